@@ -18,6 +18,9 @@ var (
 	PCAPExtension    = ".pcap"
 	HashcatExtension = ".hashcat"
 
+	// HC22000Prefix lines of the hash files imported from the FE start with it, they don't need any conversion
+	HC22000Prefix = "WPA*"
+
 	GrpcURL     = os.Getenv("GRPC_URL")
 	GrpcTimeout = os.Getenv("GRPC_TIMEOUT")
 )
@@ -71,13 +71,24 @@ Authenticate
 
 Check if client can is authorized by the user
 */
-func (c *Client) Authenticate(username, password string) (*pb.UniformResponse, error) {
+func (c *Client) Authenticate(username, password string) (*pb.AuthResponse, error) {
 	return c.PBInstance.Login(c.ClientContext, &pb.AuthRequest{
 		Username: username,
 		Password: password,
 	})
 }
 
+/*
+Refresh
+
+Exchanges the refresh token for a new JWT token and a new refresh token
+*/
+func (c *Client) Refresh(refreshToken string) (*pb.AuthResponse, error) {
+	return c.PBInstance.RefreshToken(c.ClientContext, &pb.RefreshTokenRequest{
+		RefreshToken: refreshToken,
+	})
+}
+
 // LogErrorAndSend is a helper that updates the logs with an error message and sends a failure status to the server.
 func (c *Client) LogErrorAndSend(
 	stream grpc.BidiStreamingClient[pb.ClientTaskMessageFromClient, pb.ClientTaskMessageFromServer],
@@ -12,9 +12,23 @@ import (
 	"time"
 )
 
+// minRefreshInterval the token is not renewed more often, whatever its expiration
+const minRefreshInterval = 1 * time.Minute
+
 type LoginInfo struct {
 	JWT  *string
 	Auth *entities.AuthRequest
+
+	// RefreshToken renews JWT before it expires, after ExpiresIn
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// Update stores the tokens returned by a login or by a refresh
+func (l *LoginInfo) Update(resp *pb.AuthResponse) {
+	*l.JWT = resp.GetDetails()
+	l.RefreshToken = resp.GetRefreshToken()
+	l.ExpiresIn = time.Duration(resp.GetExpiresIn()) * time.Second
 }
 
 type Client struct {
@@ -73,18 +87,23 @@ func InitClient(env *environment.Environment) (*Client, error) {
 /*
 Authenticator
 
-runs in background, and each hour using provided credentials, updates the JWT token required for performing operations
-server side
+runs in background, and shortly before the JWT token required for performing operations server side expires, renews it
+with the refresh token. The provided credentials are used when the refresh token is refused
 */
 func (c *Client) Authenticator() {
-	ticker := time.NewTicker(1 * time.Hour) // every hour
 	for {
-		<-ticker.C
-		// Every hour re-auth and re-update JWT
-		if resp, err := c.Authenticate(c.Credentials.Auth.Username, c.Credentials.Auth.Password); err == nil {
-			*c.Credentials.JWT = resp.GetDetails()
-		} else {
+		time.Sleep(max(c.Credentials.ExpiresIn*4/5, minRefreshInterval))
+
+		resp, err := c.Refresh(c.Credentials.RefreshToken)
+		if err != nil {
+			log.Warnf("[CLIENT] Cannot refresh the token, logging in again: %v", err)
+			resp, err = c.Authenticate(c.Credentials.Auth.Username, c.Credentials.Auth.Password)
+		}
+
+		if err != nil {
 			log.Fatal(err)
 		}
+
+		c.Credentials.Update(resp)
 	}
 }
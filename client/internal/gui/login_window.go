@@ -92,7 +92,7 @@ func handleLogin(client *grpcclient.Client, state *loginState, ui *loginUI) bool
 		if err == nil {
 			client.Credentials.Auth.Username = state.username
 			client.Credentials.Auth.Password = state.password
-			*client.Credentials.JWT = resp.GetDetails()
+			client.Credentials.Update(resp)
 			return true
 		} else {
 			log.Errorf("error on Authenticate rpc call: %v", err)
@@ -147,8 +147,9 @@ func (t *TaskHandler) processHandshakeTask(handshake *entities.Handshake) error
 	)
 
 	switch {
-	case handshake.BSSID != "" && handshake.SSID != "":
-		// If here, it means that input does not come from FE so we can threaten it has a handshake
+	case handshake.BSSID != "" && handshake.SSID != "" && !strings.HasPrefix(string(data), constants.HC22000Prefix):
+		// If here, it means that input does not come from FE so we can threaten it has a handshake.
+		// Hash files imported from the FE have SSID and BSSID too, but they are ready for hashcat already
 		log.Println("[CLIENT] Converting pcap...")
 
 		pcapFilePath, errFile := utils.CreateMD5RandomFile(constants.TempPCAPStorage, constants.PCAPExtension, data)
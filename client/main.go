@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"github.com/Virgula0/progetto-dp/client/internal/constants"
 	"github.com/Virgula0/progetto-dp/client/internal/entities"
 	"github.com/Virgula0/progetto-dp/client/internal/environment"
@@ -12,8 +13,12 @@ import (
 	log "github.com/sirupsen/logrus"
 	"os"
 	"runtime"
+	"time"
 )
 
+// approvalRetryDelay how often the client asks whether the user approved it
+var approvalRetryDelay = 30 * time.Second
+
 func invokeHealthCheck(client *grpcclient.Client) {
 	log.Warn("[CLIENT] Invoking healthcheck this can take a while... ")
 	// ping server with test method
@@ -55,6 +60,24 @@ func invokeGetMachineInfo() (machineID, hostname string, err error) {
 	return machineID, string(hostnameBytes), nil
 }
 
+// invokeWaitForApproval new clients wait until the user approves them from the clients page, showing the code to look for
+func invokeWaitForApproval(client *grpcclient.Client, info *pb.GetClientInfoResponse, hostname, machineID string) *pb.GetClientInfoResponse {
+	for !info.GetApproved() {
+		log.Warnf("[CLIENT] Waiting for approval, approve the client with code %s from the clients page", info.GetEnrollmentCode())
+		gui.StateUpdateCh <- &gui.StateUpdate{
+			StatusLabel: fmt.Sprintf("Waiting for approval (code %s)", info.GetEnrollmentCode()),
+		}
+
+		time.Sleep(approvalRetryDelay)
+
+		var err error
+		if info, err = client.GetClientInfo(hostname, machineID); err != nil {
+			log.Fatalf("[CLIENT] %v", err.Error())
+		}
+	}
+	return info
+}
+
 func invokeClientStructInit(client *grpcclient.Client, info *pb.GetClientInfoResponse) mygocat.TaskHandler {
 	// Fill up client info struct received from server
 	client.EntityClient = &entities.Client{
@@ -117,6 +140,8 @@ func main() {
 		log.Fatalf("[CLIENT] %v", err.Error())
 	}
 
+	info = invokeWaitForApproval(client, info, hostname, machineID)
+
 	log.Infof("[CLIENT] Enabled encryption? (%v)", info.GetEnabledEncryption())
 
 	if info.GetEnabledEncryption() && env.EmptyCerts() {
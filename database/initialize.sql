@@ -8,9 +8,18 @@ DROP DATABASE IF EXISTS dp_hashcat;
 CREATE DATABASE IF NOT EXISTS dp_hashcat;
 USE dp_hashcat;
 
+DROP TABLE IF EXISTS setting;
+DROP TABLE IF EXISTS wordlist;
+DROP TABLE IF EXISTS personal_access_token;
+DROP TABLE IF EXISTS revoked_token;
+DROP TABLE IF EXISTS refresh_token;
+DROP TABLE IF EXISTS revocation;
+DROP TABLE IF EXISTS directive;
 DROP TABLE IF EXISTS raspberry_pi;
 DROP TABLE IF EXISTS handshake;
 DROP TABLE IF EXISTS client;
+DROP TABLE IF EXISTS team_member;
+DROP TABLE IF EXISTS team;
 DROP TABLE IF EXISTS role;
 DROP TABLE IF EXISTS user;
 
@@ -18,6 +27,7 @@ CREATE TABLE IF NOT EXISTS user (
     UUID varchar(36),
     USERNAME varchar(255) UNIQUE,
     PASSWORD varchar(255),
+    DISABLED BOOLEAN DEFAULT FALSE, -- set by an administrator, the user cannot log in and its tokens are refused
 
     PRIMARY KEY(UUID)
 );
@@ -30,14 +40,42 @@ CREATE TABLE IF NOT EXISTS role (
     FOREIGN KEY (`UUID`) REFERENCES `user` (`UUID`) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS team (
+    UUID varchar(36),
+    NAME varchar(100),
+    CREATED_DATE DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY(UUID)
+);
+
+CREATE TABLE IF NOT EXISTS team_member (
+    UUID_TEAM varchar(36),
+    UUID_USER varchar(36),
+    ROLE varchar(10), -- viewer sees the resources of the team, member also uses and shares them, owner also manages the members
+
+    PRIMARY KEY(UUID_TEAM, UUID_USER),
+    FOREIGN KEY (`UUID_TEAM`) REFERENCES `team` (`UUID`) ON DELETE CASCADE,
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS raspberry_pi (
     UUID_USER varchar(36),
     UUID varchar(36),
     MACHINE_ID varchar(32) UNIQUE,
     ENCRYPTION_KEY varchar(64),
+    CREDENTIAL_HASH varchar(64) DEFAULT NULL, -- sha256 of the device credential, NULL when not enrolled or revoked
+    NAME varchar(100) DEFAULT NULL, -- given by the user, the FE shows the hostname when NULL
+    HOSTNAME varchar(255) DEFAULT NULL, -- HOSTNAME, DAEMON_VERSION and OS are reported by the daemon when logging in
+    DAEMON_VERSION varchar(50) DEFAULT NULL,
+    OS varchar(100) DEFAULT NULL,
+    LATEST_IP varchar(100) DEFAULT NULL,
+    LAST_SEEN DATETIME DEFAULT NULL, -- latest login or check-in of the daemon
+    APPROVED BOOLEAN DEFAULT FALSE, -- new devices wait for the user, the enrollment code they show is derived from CREDENTIAL_HASH
+    UUID_TEAM varchar(36) DEFAULT NULL, -- team the device is shared with, only UUID_USER manages it
 
     PRIMARY KEY(UUID),
-    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE,
+    FOREIGN KEY (`UUID_TEAM`) REFERENCES `team` (`UUID`) ON DELETE SET NULL
 );
 
 CREATE TABLE IF NOT EXISTS client (
@@ -49,9 +87,13 @@ CREATE TABLE IF NOT EXISTS client (
     LATEST_CONNECTION DATETIME,
     MACHINE_ID varchar(32) UNIQUE,
     ENABLED_ENCRYPTION BOOLEAN DEFAULT FALSE, -- not enabled, us IS operator instead of == or !=: http://mariadb.com/kb/en/sql-language-structure-boolean-literals/
+    APPROVED BOOLEAN DEFAULT FALSE, -- new clients wait for the user, no certificate is signed for them meanwhile
+    ENROLLMENT_CODE varchar(9), -- shown by the client while it waits, for recognizing it on the FE
+    UUID_TEAM varchar(36) DEFAULT NULL, -- team the client is shared with, its members can assign tasks to it
 
     PRIMARY KEY(UUID),
-    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE,
+    FOREIGN KEY (`UUID_TEAM`) REFERENCES `team` (`UUID`) ON DELETE SET NULL
 );
 
 CREATE TABLE IF NOT EXISTS handshake (
@@ -67,9 +109,101 @@ CREATE TABLE IF NOT EXISTS handshake (
     HASHCAT_LOGS LONGTEXT,
     CRACKED_HANDSHAKE varchar(1000),
     HANDSHAKE_PCAP LONGTEXT,
+    UUID_RASPBERRY_PI varchar(36) DEFAULT NULL, -- device which uploaded the capture, NULL when uploaded from the FE
+    LATITUDE DOUBLE DEFAULT NULL, -- where the device was when the handshake was captured, NULL without a GPS fix
+    LONGITUDE DOUBLE DEFAULT NULL,
+    POSITION_DATE DATETIME DEFAULT NULL, -- time of the GPS fix
+    UUID_TEAM varchar(36) DEFAULT NULL, -- team the handshake is shared with, its members can crack it
+    QUALITY varchar(20) DEFAULT NULL, -- how much of the 4-way handshake the device found in the capture, NULL when unknown
     PRIMARY KEY(UUID),
     FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE,
-    FOREIGN KEY (`UUID_ASSIGNED_CLIENT`) REFERENCES `client` (`UUID`) ON DELETE SET NULL
+    FOREIGN KEY (`UUID_TEAM`) REFERENCES `team` (`UUID`) ON DELETE SET NULL,
+    FOREIGN KEY (`UUID_ASSIGNED_CLIENT`) REFERENCES `client` (`UUID`) ON DELETE SET NULL,
+    FOREIGN KEY (`UUID_RASPBERRY_PI`) REFERENCES `raspberry_pi` (`UUID`) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS directive (
+    UUID varchar(36),
+    UUID_USER varchar(36),
+    UUID_RASPBERRY_PI varchar(36),
+    KIND varchar(30),
+    PARAMETERS text, -- JSON, its fields depend on the kind
+    STATUS varchar(20) DEFAULT 'pending',
+    RESULT LONGTEXT DEFAULT NULL, -- reported by the device when acknowledging it, e.g. its logs
+    CREATED_DATE DATETIME(6), -- directives created in the same second are delivered in order
+    ACKNOWLEDGED_DATE DATETIME DEFAULT NULL,
+
+    PRIMARY KEY(UUID),
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE,
+    FOREIGN KEY (`UUID_RASPBERRY_PI`) REFERENCES `raspberry_pi` (`UUID`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS revocation (
+    UUID varchar(36),
+    UUID_USER varchar(36),
+    KIND varchar(20), -- raspberry_pi or client
+    MACHINE_ID varchar(32),
+    UUID_ENTITY varchar(36), -- UUID of the deleted device or client, it is the subject serial number of the certificates issued to it
+    NAME varchar(255) DEFAULT NULL, -- name of the deleted device or client, shown by the FE
+    REVOKED_DATE DATETIME,
+
+    PRIMARY KEY(UUID),
+    UNIQUE(KIND, MACHINE_ID),
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS refresh_token (
+    UUID varchar(36),
+    UUID_USER varchar(36),
+    UUID_SESSION varchar(36), -- shared by the refresh tokens of a login, carried by its access tokens for logging out
+    AUDIENCE varchar(10), -- channel of the login, fe or client, the refreshed access tokens are issued for it only
+    TOKEN_HASH varchar(64) UNIQUE, -- sha256 of the token, the token itself is known by the FE or the client only
+    EXPIRES_DATE DATETIME,
+    USED_DATE DATETIME, -- first exchange of the token, it is accepted again shortly after for the concurrent refreshes
+
+    PRIMARY KEY(UUID),
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS revoked_token (
+    UUID varchar(36), -- uuid claim of the access token logged out
+    EXPIRES_DATE DATETIME, -- expiration of the token, the row is useless afterwards and pruned
+
+    PRIMARY KEY(UUID)
+);
+
+CREATE TABLE IF NOT EXISTS personal_access_token (
+    UUID varchar(36),
+    UUID_USER varchar(36),
+    NAME varchar(100),
+    TOKEN_HASH varchar(64) UNIQUE, -- sha256 of the token, the token itself is shown once when it is created
+    SCOPE varchar(10), -- read, fe or admin, see the scopes of the FE tokens
+    CREATED_DATE DATETIME DEFAULT CURRENT_TIMESTAMP,
+    EXPIRES_DATE DATETIME,
+    LAST_USED_DATE DATETIME DEFAULT NULL,
+
+    PRIMARY KEY(UUID),
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS wordlist (
+    UUID varchar(36),
+    UUID_USER varchar(36),
+    NAME varchar(100),
+    PATH varchar(255), -- relative to the directory of the clients, passed to hashcat as is
+    CREATED_DATE DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UUID_TEAM varchar(36) DEFAULT NULL,
+
+    PRIMARY KEY(UUID),
+    FOREIGN KEY (`UUID_USER`) REFERENCES `user` (`UUID`) ON DELETE CASCADE,
+    FOREIGN KEY (`UUID_TEAM`) REFERENCES `team` (`UUID`) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS setting (
+    NAME varchar(50), -- settings changed by the administrators at runtime, the environment provides the defaults
+    VALUE varchar(255),
+
+    PRIMARY KEY(NAME)
 );
 
 DROP DATABASE IF EXISTS dp_certs;
@@ -77,6 +211,7 @@ CREATE DATABASE IF NOT EXISTS dp_certs;
 USE dp_certs;
 
 DROP TABLE IF EXISTS certs;
+DROP TABLE IF EXISTS signing_key;
 
 CREATE TABLE IF NOT EXISTS certs (
     UUID varchar(36) NOT NULL PRIMARY KEY,
@@ -87,6 +222,13 @@ CREATE TABLE IF NOT EXISTS certs (
     CLIENT_KEY BLOB
 );
 
+CREATE TABLE IF NOT EXISTS signing_key (
+    KID varchar(36) NOT NULL PRIMARY KEY, -- kid header of the JWTs signed with it
+    SECRET BLOB,
+    CREATED_DATE DATETIME,
+    RETIRED_DATE DATETIME DEFAULT NULL -- set when rotated, it still verifies the tokens signed before until they expire
+);
+
 -- granting privileges
 
 GRANT SELECT, UPDATE, INSERT, DELETE ON dp_hashcat.* TO 'agent'@'%';
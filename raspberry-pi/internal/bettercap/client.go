@@ -0,0 +1,230 @@
+package bettercap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	requestTimeout = 30 * time.Second
+
+	// HandshakeEvent tag of the events sent when new key material of a network is captured
+	HandshakeEvent = "wifi.client.handshake"
+
+	handshakesFileVariable      = "wifi.handshakes.file"
+	handshakesAggregateVariable = "wifi.handshakes.aggregate"
+)
+
+// pathNameCleaner as in bettercap, for building the name of the per network captures
+var pathNameCleaner = regexp.MustCompile("[^a-zA-Z0-9]+")
+
+// Client of the REST API of bettercap (api.rest module), authenticated with the api.rest.username and api.rest.password
+type Client struct {
+	URL      *url.URL
+	Username string
+	Password string
+
+	http *http.Client
+}
+
+// AccessPoint what bettercap knows about an access point, Hostname is its SSID
+type AccessPoint struct {
+	MAC        string `json:"mac"`
+	Hostname   string `json:"hostname"`
+	Vendor     string `json:"vendor"`
+	Channel    int    `json:"channel"`
+	RSSI       int    `json:"rssi"`
+	Encryption string `json:"encryption"`
+	Handshake  bool   `json:"handshake"`
+}
+
+// Event sent by bettercap, Data depends on the Tag
+type Event struct {
+	Tag  string          `json:"tag"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Handshake data of the HandshakeEvent, File is the capture the key material has been appended to
+type Handshake struct {
+	File    string `json:"file"`
+	AP      string `json:"ap"`
+	Station string `json:"station"`
+	Full    bool   `json:"full"`
+	Half    bool   `json:"half"`
+}
+
+// NewClient returns a client of the bettercap listening at address, e.g. http://127.0.0.1:8081
+func NewClient(address, username, password string) (*Client, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("bettercap url '%s' must be http or https", address)
+	}
+
+	return &Client{
+		URL:      parsed,
+		Username: username,
+		Password: password,
+		http:     &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// endpoint returns the URL of an API route
+func (c *Client) endpoint(route string, query url.Values) *url.URL {
+	endpoint := *c.URL
+	endpoint.Path = path.Join(endpoint.Path, route)
+	endpoint.RawQuery = query.Encode()
+	return &endpoint
+}
+
+func (c *Client) get(route string, query url.Values) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, c.endpoint(route, query).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(c.Username, c.Password)
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("bettercap %s replied %s", route, response.Status)
+	}
+
+	return response, nil
+}
+
+func (c *Client) getJSON(route string, query url.Values, value any) error {
+	response, err := c.get(route, query)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// AccessPoints returns the access points seen by the wifi module
+func (c *Client) AccessPoints() ([]*AccessPoint, error) {
+	var wifi struct {
+		AccessPoints []*AccessPoint `json:"aps"`
+	}
+
+	if err := c.getJSON("/api/session/wifi", nil, &wifi); err != nil {
+		return nil, err
+	}
+
+	return wifi.AccessPoints, nil
+}
+
+// Events returns the events bettercap still keeps in memory, the oldest ones are dropped by bettercap
+func (c *Client) Events() ([]*Event, error) {
+	var events []*Event
+	if err := c.getJSON("/api/events", nil, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+/*
+HandshakeFiles
+
+Returns the captures bettercap writes the key material to. With wifi.handshakes.aggregate it is the single
+wifi.handshakes.file, otherwise wifi.handshakes.file is a directory with a capture per network, named after it.
+Paths bettercap expands itself (~) can't be resolved from here and are not returned
+*/
+func (c *Client) HandshakeFiles(accessPoints []*AccessPoint) ([]string, error) {
+	var environment struct {
+		Data map[string]string `json:"data"`
+	}
+
+	if err := c.getJSON("/api/session/env", nil, &environment); err != nil {
+		return nil, err
+	}
+
+	file := environment.Data[handshakesFileVariable]
+	if !path.IsAbs(file) {
+		return nil, nil
+	}
+
+	if environment.Data[handshakesAggregateVariable] != "false" {
+		return []string{file}, nil
+	}
+
+	files := make([]string, 0, len(accessPoints))
+	for _, accessPoint := range accessPoints {
+		if accessPoint.Handshake {
+			files = append(files, path.Join(file, accessPoint.pathFriendlyName()+".pcap"))
+		}
+	}
+
+	return files, nil
+}
+
+// pathFriendlyName the name bettercap gives to the capture of the network
+func (a *AccessPoint) pathFriendlyName() string {
+	bssid := strings.ReplaceAll(strings.ToLower(a.MAC), ":", "")
+	if a.Hostname == "" || a.Hostname == "<hidden>" {
+		return bssid
+	}
+
+	return fmt.Sprintf("%s_%s", pathNameCleaner.ReplaceAllString(a.Hostname, ""), bssid)
+}
+
+// Download writes in w the content of the file at name, a path on the bettercap host
+func (c *Client) Download(name string, w io.Writer) error {
+	response, err := c.get("/api/file", url.Values{"name": {name}})
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, err = io.Copy(w, response.Body)
+	return err
+}
+
+// Subscribe calls handle with each event streamed by bettercap, until the connection is closed
+func (c *Client) Subscribe(handle func(*Event)) error {
+	location := c.endpoint("/api/events", nil)
+	location.Scheme = strings.Replace(location.Scheme, "http", "ws", 1)
+
+	config, err := websocket.NewConfig(location.String(), c.URL.String())
+	if err != nil {
+		return err
+	}
+
+	request := http.Request{Header: http.Header{}}
+	request.SetBasicAuth(c.Username, c.Password)
+	config.Header.Set("Authorization", request.Header.Get("Authorization"))
+
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		var event Event
+		if err = websocket.JSON.Receive(conn, &event); err != nil {
+			return err
+		}
+		handle(&event)
+	}
+}
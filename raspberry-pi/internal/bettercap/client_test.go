@@ -0,0 +1,238 @@
+package bettercap
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	testUsername = "user"
+	testPassword = "pass"
+)
+
+// fakeBettercap stands in for the api.rest module, routes answer with the given status and body
+func fakeBettercap(t *testing.T, routes map[string]http.HandlerFunc) *Client {
+	mux := http.NewServeMux()
+	for route, handler := range routes {
+		mux.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok || username != testUsername || password != testPassword {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL, testUsername, testPassword)
+	require.NoError(t, err)
+	return client
+}
+
+func reply(status int, value any) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(value)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{address: "http://127.0.0.1:8081", valid: true},
+		{address: "https://bettercap.local/prefix", valid: true},
+		{address: "ws://127.0.0.1:8081"},
+		{address: "127.0.0.1:8081"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			_, err := NewClient(test.address, testUsername, testPassword)
+			require.Equal(t, test.valid, err == nil, err)
+		})
+	}
+}
+
+func TestAccessPoints(t *testing.T) {
+	client := fakeBettercap(t, map[string]http.HandlerFunc{
+		"/api/session/wifi": reply(http.StatusOK, map[string]any{
+			"aps": []map[string]any{
+				{"mac": "e4:8f:34:7d:b5:25", "hostname": "HOME", "vendor": "Vodafone", "channel": 6, "rssi": -48, "encryption": "WPA2", "handshake": true},
+				{"mac": "00:01:02:03:04:05", "hostname": "<hidden>", "channel": 11, "rssi": -80},
+			},
+		}),
+	})
+
+	accessPoints, err := client.AccessPoints()
+	require.NoError(t, err)
+	require.Equal(t, []*AccessPoint{
+		{MAC: "e4:8f:34:7d:b5:25", Hostname: "HOME", Vendor: "Vodafone", Channel: 6, RSSI: -48, Encryption: "WPA2", Handshake: true},
+		{MAC: "00:01:02:03:04:05", Hostname: "<hidden>", Channel: 11, RSSI: -80},
+	}, accessPoints)
+}
+
+func TestHandshakeFiles(t *testing.T) {
+	accessPoints := []*AccessPoint{
+		{MAC: "E4:8F:34:7D:B5:25", Hostname: "Home Wi-Fi!", Handshake: true},
+		{MAC: "00:01:02:03:04:05", Hostname: "<hidden>", Handshake: true},
+		{MAC: "00:01:02:03:04:06", Hostname: "NOHANDSHAKE"},
+	}
+
+	tests := []struct {
+		name        string
+		environment map[string]string
+		files       []string
+	}{
+		{
+			name:        "aggregated by default",
+			environment: map[string]string{handshakesFileVariable: "/root/handshakes.pcap"},
+			files:       []string{"/root/handshakes.pcap"},
+		},
+		{
+			name:        "aggregated",
+			environment: map[string]string{handshakesFileVariable: "/root/handshakes.pcap", handshakesAggregateVariable: "true"},
+			files:       []string{"/root/handshakes.pcap"},
+		},
+		{
+			name:        "a capture per network",
+			environment: map[string]string{handshakesFileVariable: "/root/handshakes", handshakesAggregateVariable: "false"},
+			files:       []string{"/root/handshakes/HomeWiFi_e48f347db525.pcap", "/root/handshakes/000102030405.pcap"},
+		},
+		{
+			name:        "path expanded by bettercap",
+			environment: map[string]string{handshakesFileVariable: "~/bettercap-wifi-handshakes.pcap"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fakeBettercap(t, map[string]http.HandlerFunc{
+				"/api/session/env": reply(http.StatusOK, map[string]any{"data": test.environment}),
+			})
+
+			files, err := client.HandshakeFiles(accessPoints)
+			require.NoError(t, err)
+			require.Equal(t, test.files, files)
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	client := fakeBettercap(t, map[string]http.HandlerFunc{
+		"/api/file": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("name") != "/root/handshakes.pcap" {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte("capture"))
+		},
+	})
+
+	var content bytes.Buffer
+	require.NoError(t, client.Download("/root/handshakes.pcap", &content))
+	require.Equal(t, "capture", content.String())
+
+	require.EqualError(t, client.Download("/root/missing.pcap", &content), "bettercap /api/file replied 404 Not Found")
+}
+
+// TestErrorStatuses checks that the replies of bettercap other than 200 are errors
+func TestErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		status   int
+		problem  string
+	}{
+		{name: "wrong credentials", username: "wrong", status: http.StatusOK, problem: "replied 401 Unauthorized"},
+		{name: "module not running", username: testUsername, status: http.StatusNotFound, problem: "replied 404 Not Found"},
+		{name: "internal error", username: testUsername, status: http.StatusInternalServerError, problem: "replied 500 Internal Server Error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fakeBettercap(t, map[string]http.HandlerFunc{
+				"/api/session/wifi": reply(test.status, map[string]any{"aps": []any{}}),
+				"/api/session/env":  reply(test.status, map[string]any{"data": map[string]string{}}),
+				"/api/events":       reply(test.status, []any{}),
+				"/api/file":         reply(test.status, "capture"),
+			})
+			client.Username = test.username
+
+			_, err := client.AccessPoints()
+			require.ErrorContains(t, err, "/api/session/wifi "+test.problem)
+
+			_, err = client.HandshakeFiles(nil)
+			require.ErrorContains(t, err, "/api/session/env "+test.problem)
+
+			_, err = client.Events()
+			require.ErrorContains(t, err, "/api/events "+test.problem)
+
+			require.ErrorContains(t, client.Download("/root/handshakes.pcap", &bytes.Buffer{}), "/api/file "+test.problem)
+		})
+	}
+
+	t.Run("bettercap not running", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		client, err := NewClient(server.URL, testUsername, testPassword)
+		require.NoError(t, err)
+
+		_, err = client.AccessPoints()
+		require.Error(t, err)
+		require.Error(t, client.Subscribe(func(*Event) {}))
+	})
+}
+
+func TestEvents(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := fakeBettercap(t, map[string]http.HandlerFunc{
+		"/api/events": reply(http.StatusOK, []map[string]any{
+			{"tag": "wifi.ap.new", "time": at, "data": map[string]any{"mac": "e4:8f:34:7d:b5:25"}},
+			{"tag": HandshakeEvent, "time": at, "data": map[string]any{"file": "/root/handshakes.pcap", "ap": "e4:8f:34:7d:b5:25", "full": true}},
+		}),
+	})
+
+	events, err := client.Events()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, HandshakeEvent, events[1].Tag)
+	require.True(t, at.Equal(events[1].Time))
+
+	var handshake Handshake
+	require.NoError(t, json.Unmarshal(events[1].Data, &handshake))
+	require.Equal(t, Handshake{File: "/root/handshakes.pcap", AP: "e4:8f:34:7d:b5:25", Full: true}, handshake)
+}
+
+func TestSubscribe(t *testing.T) {
+	client := fakeBettercap(t, map[string]http.HandlerFunc{
+		"/api/events": websocket.Handler(func(conn *websocket.Conn) {
+			_ = websocket.JSON.Send(conn, map[string]any{"tag": "wifi.ap.new", "data": map[string]any{}})
+			_ = websocket.JSON.Send(conn, map[string]any{"tag": HandshakeEvent, "data": map[string]any{"file": "/root/handshakes.pcap"}})
+		}).ServeHTTP,
+	})
+
+	var tags []string
+	err := client.Subscribe(func(event *Event) {
+		tags = append(tags, event.Tag)
+	})
+
+	// the stream ends when bettercap closes the connection
+	require.Error(t, err)
+	require.Equal(t, []string{"wifi.ap.new", HandshakeEvent}, tags)
+
+	client.Password = "wrong"
+	require.Error(t, client.Subscribe(func(*Event) {}))
+}
@@ -3,18 +3,26 @@ package cmd
 import (
 	"fmt"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/daemon"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+	"os"
+	"sync"
 )
 
 // authCmd represents the auth command
 var (
 	username string
 	password string
+	// exportDirectory where the export command writes the bundles
+	exportDirectory string
 
-	cliKey, insecureKey, runKey = "cli", "insecure-login", "run"
+	cliKey, insecureKey, runKey, dashboardKey, resultsKey, exportKey = "cli", "insecure-login", "run", "dashboard", "results", "export"
+
+	setupOnce sync.Once
+	setupErr  error
 )
 
 var cobraCommands = map[string]*cobra.Command{
@@ -42,6 +50,29 @@ var cobraCommands = map[string]*cobra.Command{
 			return runTUI(&username, &password)
 		},
 	},
+	dashboardKey: {
+		Use:   dashboardKey,
+		Short: "Authenticate with TUI and show the status dashboard while running",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTUI(&username, &password)
+		},
+	},
+	resultsKey: {
+		Use:   resultsKey,
+		Short: "Show the networks cracked among the captures uploaded",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runResultsTUI()
+		},
+	},
+	exportKey: {
+		Use:   exportKey + " DIRECTORY",
+		Short: "Write the captures as signed bundles in DIRECTORY, to be imported from the FE without a network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exportDirectory = args[0]
+			return nil
+		},
+	},
 }
 
 func runTUI(username, password *string) error {
@@ -55,6 +86,24 @@ func runTUI(username, password *string) error {
 	return nil
 }
 
+func runResultsTUI() error {
+	path, err := daemon.ResultsPath()
+	if err != nil {
+		return err
+	}
+
+	results, err := daemon.LoadResults()
+	if err != nil {
+		return err
+	}
+
+	if _, err = tea.NewProgram(tui.ResultsModel(path, results)).Run(); err != nil {
+		return fmt.Errorf("tui: could not start program -> %v", err)
+	}
+
+	return nil
+}
+
 // setupAuthFlags configures flags for authentication commands.
 func setupAuthFlags(cmd *cobra.Command, username, password *string) error {
 	cmd.Flags().StringVarP(username, "username", "u", "", "Username for authentication (required)")
@@ -67,27 +116,74 @@ func setupAuthFlags(cmd *cobra.Command, username, password *string) error {
 	return cmd.MarkFlagRequired("password")
 }
 
-// AuthCommand returns the parsed AuthRequest structure.
-func AuthCommand() (*entities.AuthRequest, error) {
-	// Root command setup
+// setupCommands adds the sub-commands to the root command, only once as AuthCommand, ResultsCommand and ExportCommand need them
+func setupCommands() (*cobra.Command, error) {
 	rootCmd := cobraCommands[cliKey]
 
-	// Sub-command for insecure login
-	unsecureLogin := cobraCommands[insecureKey]
+	setupOnce.Do(func() {
+		// Sub-command for insecure login
+		unsecureLogin := cobraCommands[insecureKey]
 
-	// Sub-command for TUI login
-	runWithTUI := cobraCommands[runKey]
+		// Common flag setup
+		if setupErr = setupAuthFlags(unsecureLogin, &username, &password); setupErr != nil {
+			return
+		}
 
-	// Common flag setup
-	if err := setupAuthFlags(unsecureLogin, &username, &password); err != nil {
-		return nil, err
+		// Add sub-commands to root
+		rootCmd.AddCommand(unsecureLogin, cobraCommands[runKey], cobraCommands[dashboardKey], cobraCommands[resultsKey],
+			cobraCommands[exportKey])
+	})
+
+	return rootCmd, setupErr
+}
+
+// requested reports whether the daemon was started with the sub-command
+func requested(key string) bool {
+	rootCmd, err := setupCommands()
+	if err != nil {
+		return false
+	}
+
+	command, _, err := rootCmd.Find(os.Args[1:])
+	return err == nil && command == cobraCommands[key]
+}
+
+// ResultsCommand shows the results when the daemon is started with the results command, reporting whether it was
+func ResultsCommand() (bool, error) {
+	if !requested(resultsKey) {
+		return false, nil
+	}
+
+	return true, cobraCommands[cliKey].Execute()
+}
+
+// ExportCommand parses the export command, reporting whether the daemon was started with it and the directory of the bundles
+func ExportCommand() (bool, string, error) {
+	if !requested(exportKey) {
+		return false, "", nil
+	}
+
+	if err := cobraCommands[cliKey].Execute(); err != nil {
+		return true, "", err
 	}
 
-	// Add sub-commands to root
-	rootCmd.AddCommand(unsecureLogin, runWithTUI)
+	return true, exportDirectory, nil
+}
+
+// DashboardRequested reports whether the daemon was started with the dashboard command
+func DashboardRequested() bool {
+	return requested(dashboardKey)
+}
+
+// AuthCommand returns the parsed AuthRequest structure.
+func AuthCommand() (*entities.AuthRequest, error) {
+	rootCmd, err := setupCommands()
+	if err != nil {
+		return nil, err
+	}
 
 	// Execute root command
-	if err := rootCmd.Execute(); err != nil {
+	if err = rootCmd.Execute(); err != nil {
 		return nil, err
 	}
 
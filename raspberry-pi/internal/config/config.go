@@ -0,0 +1,554 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+	defaultSettle   = 5 * time.Second
+	minimumInterval = 10 * time.Second
+
+	defaultGPSDAddress  = "localhost:2947"
+	defaultMaxGap       = 2 * time.Minute
+	defaultGPSRetention = 7 * 24 * time.Hour
+)
+
+// GPS sources
+const (
+	GPSDSource = "gpsd"
+	NMEASource = "nmea"
+)
+
+// Config daemon configuration. Values missing from the configuration file keep
+// the ones set through environment variables, see Default
+type Config struct {
+	Server         Server           `yaml:"server"`
+	Watch          []WatchDirectory `yaml:"watch"`
+	Upload         Upload           `yaml:"upload"`
+	HomeWIFI       string           `yaml:"home_wifi"`
+	SpoolDir       string           `yaml:"spool_dir"`
+	CredentialFile string           `yaml:"credential_file"`
+	KeyFile        string           `yaml:"key_file"`
+	ResultsFile    string           `yaml:"results_file"`
+	LogFile        string           `yaml:"log_file"`
+	RemoteFile     string           `yaml:"remote_file"`
+	GPS            GPS              `yaml:"gps"`
+	Bettercap      Bettercap        `yaml:"bettercap"`
+}
+
+// Server TCP endpoints of the server, they are tried in order until one accepts the connection.
+// When none does, requests are sent to the REST API at HTTPSURL, if set (e.g. https://hds.example.com)
+type Server struct {
+	Endpoints []Endpoint `yaml:"endpoints"`
+	TLS       bool       `yaml:"tls"`
+	CACert    string     `yaml:"ca_cert"`
+	HTTPSURL  string     `yaml:"https_url"`
+}
+
+type Endpoint struct {
+	Address string `yaml:"address"`
+	Port    int    `yaml:"port"`
+}
+
+// WatchDirectory a directory where captures are written. A file is a capture when its name matches
+// one of the include patterns and none of the exclude ones, patterns use the filepath.Match syntax
+type WatchDirectory struct {
+	Path      string   `yaml:"path"`
+	Recursive bool     `yaml:"recursive"`
+	Include   []string `yaml:"include"`
+	Exclude   []string `yaml:"exclude"`
+}
+
+// Upload when captures are uploaded.
+// Interval is the period of the full rescan, Settle how long a capture must stay untouched before being uploaded
+// and Windows the times of the day uploads are allowed in (e.g. 22:00-06:00), any time when empty
+type Upload struct {
+	Interval time.Duration `yaml:"interval"`
+	Settle   time.Duration `yaml:"settle"`
+	Windows  []string      `yaml:"windows"`
+}
+
+// GPS where positions are read from, captures are geotagged only when Source is set.
+// Source is gpsd, reading from the gpsd at Address, or nmea, reading NMEA sentences from the serial device or file at Path.
+// A capture gets the position of the closest fix, if it is less than MaxGap away from when the handshake was captured.
+// Fixes are kept in TrackFile for Retention, for the captures uploaded after the daemon is restarted
+type GPS struct {
+	Source    string        `yaml:"source"`
+	Address   string        `yaml:"address"`
+	Path      string        `yaml:"path"`
+	MaxGap    time.Duration `yaml:"max_gap"`
+	TrackFile string        `yaml:"track_file"`
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Bettercap REST API (api.rest module) the captures are read from, in place of the watch directories, when URL is set.
+// Captures are downloaded in DownloadDir, ~/.hds/bettercap by default
+type Bettercap struct {
+	URL         string `yaml:"url"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	DownloadDir string `yaml:"download_dir"`
+}
+
+// Remote settings changed by the directives of the server, they take precedence over the configuration file.
+// Nil fields have never been changed
+type Remote struct {
+	Schedule *Schedule        `yaml:"schedule,omitempty"`
+	Watch    []WatchDirectory `yaml:"watch,omitempty"`
+}
+
+// Schedule replaces Upload.Interval and Upload.Windows
+type Schedule struct {
+	Interval time.Duration `yaml:"interval"`
+	Windows  []string      `yaml:"windows"`
+}
+
+// Path returns where the configuration file is read from
+func Path() (string, error) {
+	if constants.ConfigFile != "" {
+		return constants.ConfigFile, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".hds", "config.yaml"), nil
+}
+
+/*
+Default
+
+Configuration built from environment variables, used when there is no configuration file.
+The capture directory is ~/handshakes when bettercap writes there, ./handshakes otherwise
+*/
+func Default() *Config {
+	port, _ := strconv.Atoi(constants.TCPPort)
+
+	directory := "handshakes"
+	if constants.Bettercap {
+		directory = filepath.Join("~", "handshakes")
+	}
+
+	return &Config{
+		Server: Server{
+			Endpoints: []Endpoint{{Address: constants.TCPAddress, Port: port}},
+			TLS:       constants.TCPTLS,
+			CACert:    constants.TCPCACert,
+			HTTPSURL:  constants.ServerHTTPSURL,
+		},
+		Watch: []WatchDirectory{{
+			Path:    directory,
+			Include: []string{"*" + constants.PCAPExtension},
+		}},
+		Upload: Upload{
+			Interval: defaultInterval,
+			Settle:   defaultSettle,
+		},
+		HomeWIFI:       constants.HomeWIFISSID,
+		SpoolDir:       constants.SpoolDir,
+		CredentialFile: constants.CredentialFile,
+		KeyFile:        constants.KeyFile,
+		ResultsFile:    constants.ResultsFile,
+		LogFile:        constants.LogFile,
+		RemoteFile:     constants.RemoteFile,
+		GPS: GPS{
+			Address:   defaultGPSDAddress,
+			MaxGap:    defaultMaxGap,
+			Retention: defaultGPSRetention,
+		},
+		Bettercap: Bettercap{
+			URL:      constants.BettercapAPI,
+			Username: constants.BettercapAPIUsername,
+			Password: constants.BettercapAPIPassword,
+		},
+	}
+}
+
+// Load reads and validates the configuration file. A missing file is an error only when CONFIG_FILE points to it
+func Load() (*Config, error) {
+	config := Default()
+
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && constants.ConfigFile == "":
+		if err = config.normalize(); err != nil {
+			return nil, err
+		}
+		return config.withStoredRemote()
+	case err != nil:
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %s", path, err.Error())
+	}
+
+	if err = config.normalize(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %s", path, err.Error())
+	}
+
+	return config.withStoredRemote()
+}
+
+// withStoredRemote returns the configuration with the remote settings stored in RemoteFile, if any
+func (c *Config) withStoredRemote() (*Config, error) {
+	remote, err := c.LoadRemote()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := c.WithRemote(remote)
+	if err != nil {
+		path, _ := c.RemotePath()
+		return nil, fmt.Errorf("invalid remote settings %s: %s", path, err.Error())
+	}
+
+	return config, nil
+}
+
+// RemotePath returns where the remote settings are stored
+func (c *Config) RemotePath() (string, error) {
+	if c.RemoteFile != "" {
+		return c.RemoteFile, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".hds", "remote.yaml"), nil
+}
+
+// LoadRemote reads the remote settings, they are empty until a directive changes them
+func (c *Config) LoadRemote() (*Remote, error) {
+	path, err := c.RemotePath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Remote{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var remote Remote
+	if err = yaml.Unmarshal(content, &remote); err != nil {
+		return nil, fmt.Errorf("invalid remote settings %s: %s", path, err.Error())
+	}
+
+	return &remote, nil
+}
+
+// SaveRemote replaces the remote settings
+func (c *Config) SaveRemote(remote *Remote) error {
+	path, err := c.RemotePath()
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(remote)
+	if err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+	if err = os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
+
+// WithRemote returns a copy of the configuration with the remote settings, c is left as it is
+func (c *Config) WithRemote(remote *Remote) (*Config, error) {
+	config := *c
+
+	if remote.Schedule != nil {
+		config.Upload.Interval = remote.Schedule.Interval
+		config.Upload.Windows = remote.Schedule.Windows
+	}
+
+	// the directories are normalized in place, the ones of c may be in use
+	watch := c.Watch
+	if remote.Watch != nil {
+		watch = remote.Watch
+	}
+	config.Watch = slices.Clone(watch)
+
+	return &config, config.normalize()
+}
+
+// normalize expands the paths and validates the configuration
+func (c *Config) normalize() error {
+	var err error
+	for i := range c.Watch {
+		if c.Watch[i].Path, err = expandPath(c.Watch[i].Path); err != nil {
+			return err
+		}
+		// relative directories are relative to where the daemon is started from
+		if c.Watch[i].Path != "" {
+			if c.Watch[i].Path, err = filepath.Abs(c.Watch[i].Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.SpoolDir, err = expandPath(c.SpoolDir); err != nil {
+		return err
+	}
+	if c.CredentialFile, err = expandPath(c.CredentialFile); err != nil {
+		return err
+	}
+	if c.KeyFile, err = expandPath(c.KeyFile); err != nil {
+		return err
+	}
+	if c.ResultsFile, err = expandPath(c.ResultsFile); err != nil {
+		return err
+	}
+	if c.LogFile, err = expandPath(c.LogFile); err != nil {
+		return err
+	}
+	if c.RemoteFile, err = expandPath(c.RemoteFile); err != nil {
+		return err
+	}
+	if c.Server.CACert, err = expandPath(c.Server.CACert); err != nil {
+		return err
+	}
+	if c.GPS.Path, err = expandPath(c.GPS.Path); err != nil {
+		return err
+	}
+	if c.GPS.TrackFile, err = expandPath(c.GPS.TrackFile); err != nil {
+		return err
+	}
+	if c.Bettercap.DownloadDir, err = expandPath(c.Bettercap.DownloadDir); err != nil {
+		return err
+	}
+
+	return c.Validate()
+}
+
+// Validate reports every problem of the configuration at once
+func (c *Config) Validate() error {
+	var problems []error
+
+	problems = append(problems, c.Server.validate()...)
+
+	if len(c.Watch) == 0 {
+		problems = append(problems, errors.New("watch: at least one directory is required"))
+	}
+	for i, directory := range c.Watch {
+		problems = append(problems, directory.validate(fmt.Sprintf("watch[%d]", i))...)
+	}
+
+	if c.Upload.Interval < minimumInterval {
+		problems = append(problems, fmt.Errorf("upload.interval: must be at least %s", minimumInterval))
+	}
+	if c.Upload.Settle < 0 {
+		problems = append(problems, errors.New("upload.settle: must not be negative"))
+	}
+	for i, window := range c.Upload.Windows {
+		if _, _, err := parseWindow(window); err != nil {
+			problems = append(problems, fmt.Errorf("upload.windows[%d]: %s", i, err.Error()))
+		}
+	}
+
+	problems = append(problems, c.GPS.validate()...)
+	problems = append(problems, c.Bettercap.validate()...)
+
+	return errors.Join(problems...)
+}
+
+func (s *Server) validate() []error {
+	var problems []error
+
+	if len(s.Endpoints) == 0 {
+		problems = append(problems, errors.New("server: at least one endpoint is required"))
+	}
+	for i, endpoint := range s.Endpoints {
+		if endpoint.Address == "" {
+			problems = append(problems, fmt.Errorf("server.endpoints[%d]: address is required", i))
+		}
+		if endpoint.Port <= 0 || endpoint.Port > 65535 {
+			problems = append(problems, fmt.Errorf("server.endpoints[%d]: port %d is not valid", i, endpoint.Port))
+		}
+	}
+
+	if s.HTTPSURL != "" {
+		parsed, err := url.Parse(s.HTTPSURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problems = append(problems, fmt.Errorf("server.https_url: '%s' is not a http or https URL", s.HTTPSURL))
+		}
+	}
+
+	return problems
+}
+
+func (g *GPS) validate() []error {
+	var problems []error
+
+	switch g.Source {
+	case "":
+		return nil
+	case GPSDSource:
+		if g.Address == "" {
+			problems = append(problems, errors.New("gps.address: required by the gpsd source"))
+		}
+	case NMEASource:
+		if g.Path == "" {
+			problems = append(problems, errors.New("gps.path: required by the nmea source"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("gps.source: '%s' is not one of %s, %s", g.Source, GPSDSource, NMEASource))
+	}
+
+	if g.MaxGap <= 0 {
+		problems = append(problems, errors.New("gps.max_gap: must be positive"))
+	}
+	if g.Retention <= 0 {
+		problems = append(problems, errors.New("gps.retention: must be positive"))
+	}
+
+	return problems
+}
+
+func (b *Bettercap) validate() []error {
+	if b.URL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(b.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return []error{fmt.Errorf("bettercap.url: '%s' is not a http or https URL", b.URL)}
+	}
+
+	return nil
+}
+
+func (w *WatchDirectory) validate(field string) []error {
+	var problems []error
+
+	if w.Path == "" {
+		problems = append(problems, fmt.Errorf("%s: path is required", field))
+	}
+	if len(w.Include) == 0 {
+		problems = append(problems, fmt.Errorf("%s: at least one include pattern is required", field))
+	}
+
+	for _, pattern := range append(append([]string{}, w.Include...), w.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			problems = append(problems, fmt.Errorf("%s: pattern '%s' is not valid", field, pattern))
+		}
+	}
+
+	return problems
+}
+
+// Matches reports whether the file at path is a capture of the directory
+func (w *WatchDirectory) Matches(path string) bool {
+	name := filepath.Base(path)
+
+	for _, pattern := range w.Exclude {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return false
+		}
+	}
+
+	for _, pattern := range w.Include {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allowed reports whether captures can be uploaded at the given time
+func (u *Upload) Allowed(now time.Time) bool {
+	if len(u.Windows) == 0 {
+		return true
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	for _, window := range u.Windows {
+		start, end, err := parseWindow(window)
+		if err != nil {
+			continue
+		}
+
+		// windows ending before they start cross midnight
+		if start <= end && minute >= start && minute < end ||
+			start > end && (minute >= start || minute < end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseWindow returns the minutes of the day a HH:MM-HH:MM window starts and ends at
+func parseWindow(window string) (start, end int, err error) {
+	from, to, found := strings.Cut(window, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("window '%s' must be in the HH:MM-HH:MM format", window)
+	}
+
+	startTime, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("window '%s' must be in the HH:MM-HH:MM format", window)
+	}
+
+	endTime, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, fmt.Errorf("window '%s' must be in the HH:MM-HH:MM format", window)
+	}
+
+	start, end = startTime.Hour()*60+startTime.Minute(), endTime.Hour()*60+endTime.Minute()
+	if start == end {
+		return 0, 0, fmt.Errorf("window '%s' is empty", window)
+	}
+
+	return start, end, nil
+}
+
+// expandPath replaces a leading ~ with the home directory
+func expandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
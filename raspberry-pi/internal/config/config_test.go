@@ -0,0 +1,374 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig a configuration passing Validate, the cases change one part of it
+func validConfig() *config.Config {
+	return &config.Config{
+		Server: config.Server{
+			Endpoints: []config.Endpoint{{Address: "localhost", Port: 4747}},
+		},
+		Watch: []config.WatchDirectory{{
+			Path:    "/tmp/handshakes",
+			Include: []string{"*.pcap"},
+		}},
+		Upload: config.Upload{
+			Interval: 5 * time.Minute,
+			Settle:   5 * time.Second,
+		},
+		GPS: config.GPS{
+			Address:   "localhost:2947",
+			MaxGap:    2 * time.Minute,
+			Retention: time.Hour,
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(c *config.Config)
+		problems []string
+	}{
+		{
+			name:   "valid",
+			change: func(c *config.Config) {},
+		},
+		{
+			name:     "no endpoints",
+			change:   func(c *config.Config) { c.Server.Endpoints = nil },
+			problems: []string{"server: at least one endpoint is required"},
+		},
+		{
+			name:   "endpoint without address and port",
+			change: func(c *config.Config) { c.Server.Endpoints = append(c.Server.Endpoints, config.Endpoint{}) },
+			problems: []string{
+				"server.endpoints[1]: address is required",
+				"server.endpoints[1]: port 0 is not valid",
+			},
+		},
+		{
+			name:     "port out of range",
+			change:   func(c *config.Config) { c.Server.Endpoints[0].Port = 65536 },
+			problems: []string{"server.endpoints[0]: port 65536 is not valid"},
+		},
+		{
+			name:     "https url without scheme",
+			change:   func(c *config.Config) { c.Server.HTTPSURL = "hds.example.com" },
+			problems: []string{"server.https_url: 'hds.example.com' is not a http or https URL"},
+		},
+		{
+			name:   "https url",
+			change: func(c *config.Config) { c.Server.HTTPSURL = "https://hds.example.com" },
+		},
+		{
+			name:     "no watch directories",
+			change:   func(c *config.Config) { c.Watch = nil },
+			problems: []string{"watch: at least one directory is required"},
+		},
+		{
+			name:   "watch directory without path and patterns",
+			change: func(c *config.Config) { c.Watch = append(c.Watch, config.WatchDirectory{}) },
+			problems: []string{
+				"watch[1]: path is required",
+				"watch[1]: at least one include pattern is required",
+			},
+		},
+		{
+			name:     "invalid exclude pattern",
+			change:   func(c *config.Config) { c.Watch[0].Exclude = []string{"[a-"} },
+			problems: []string{"watch[0]: pattern '[a-' is not valid"},
+		},
+		{
+			name:     "interval too short",
+			change:   func(c *config.Config) { c.Upload.Interval = time.Second },
+			problems: []string{"upload.interval: must be at least 10s"},
+		},
+		{
+			name:     "negative settle",
+			change:   func(c *config.Config) { c.Upload.Settle = -time.Second },
+			problems: []string{"upload.settle: must not be negative"},
+		},
+		{
+			name:   "windows",
+			change: func(c *config.Config) { c.Upload.Windows = []string{"22:00-06:00", " 12:00 - 13:30 "} },
+		},
+		{
+			name:   "invalid windows",
+			change: func(c *config.Config) { c.Upload.Windows = []string{"22:00", "25:00-06:00", "10:00-10:00"} },
+			problems: []string{
+				"upload.windows[0]: window '22:00' must be in the HH:MM-HH:MM format",
+				"upload.windows[1]: window '25:00-06:00' must be in the HH:MM-HH:MM format",
+				"upload.windows[2]: window '10:00-10:00' is empty",
+			},
+		},
+		{
+			name:   "gps settings are ignored without source",
+			change: func(c *config.Config) { c.GPS = config.GPS{} },
+		},
+		{
+			name:     "unknown gps source",
+			change:   func(c *config.Config) { c.GPS.Source = "serial" },
+			problems: []string{"gps.source: 'serial' is not one of gpsd, nmea"},
+		},
+		{
+			name: "gpsd source without address",
+			change: func(c *config.Config) {
+				c.GPS.Source = config.GPSDSource
+				c.GPS.Address = ""
+			},
+			problems: []string{"gps.address: required by the gpsd source"},
+		},
+		{
+			name:     "nmea source without path",
+			change:   func(c *config.Config) { c.GPS.Source = config.NMEASource },
+			problems: []string{"gps.path: required by the nmea source"},
+		},
+		{
+			name: "gps gap and retention not positive",
+			change: func(c *config.Config) {
+				c.GPS.Source = config.GPSDSource
+				c.GPS.MaxGap = 0
+				c.GPS.Retention = -time.Hour
+			},
+			problems: []string{
+				"gps.max_gap: must be positive",
+				"gps.retention: must be positive",
+			},
+		},
+		{
+			name:     "bettercap url not http",
+			change:   func(c *config.Config) { c.Bettercap.URL = "ftp://127.0.0.1:8081" },
+			problems: []string{"bettercap.url: 'ftp://127.0.0.1:8081' is not a http or https URL"},
+		},
+		{
+			name:   "bettercap url",
+			change: func(c *config.Config) { c.Bettercap.URL = "http://127.0.0.1:8081" },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := validConfig()
+			test.change(c)
+
+			err := c.Validate()
+			if len(test.problems) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			// every problem is reported at once, one per line
+			require.Error(t, err)
+			require.Equal(t, test.problems, strings.Split(err.Error(), "\n"))
+		})
+	}
+}
+
+// writeConfig writes content as the configuration file read by Load
+func writeConfig(t *testing.T, content string) string {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	previous := constants.ConfigFile
+	constants.ConfigFile = path
+	t.Cleanup(func() { constants.ConfigFile = previous })
+
+	return directory
+}
+
+func TestLoad(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	working, err := os.Getwd()
+	require.NoError(t, err)
+
+	t.Run("defaults", func(t *testing.T) {
+		writeConfig(t, `
+server:
+  endpoints:
+    - address: localhost
+      port: 4747
+remote_file: `+filepath.Join(t.TempDir(), "remote.yaml")+`
+`)
+
+		c, err := config.Load()
+		require.NoError(t, err)
+
+		require.Equal(t, 5*time.Minute, c.Upload.Interval)
+		require.Equal(t, 5*time.Second, c.Upload.Settle)
+		require.Empty(t, c.Upload.Windows)
+		require.Equal(t, "localhost:2947", c.GPS.Address)
+		require.Equal(t, 2*time.Minute, c.GPS.MaxGap)
+		require.Equal(t, 7*24*time.Hour, c.GPS.Retention)
+
+		// the default directory is relative to where the daemon is started from
+		require.Len(t, c.Watch, 1)
+		require.Equal(t, filepath.Join(working, "handshakes"), c.Watch[0].Path)
+		require.Equal(t, []string{"*.pcap"}, c.Watch[0].Include)
+	})
+
+	t.Run("paths are expanded", func(t *testing.T) {
+		writeConfig(t, `
+server:
+  endpoints:
+    - address: localhost
+      port: 4747
+watch:
+  - path: ~/captures
+    recursive: true
+    include: ["*.pcap", "*.pcapng"]
+  - path: relative
+    include: ["*.cap"]
+spool_dir: ~/spool
+remote_file: `+filepath.Join(t.TempDir(), "remote.yaml")+`
+`)
+
+		c, err := config.Load()
+		require.NoError(t, err)
+
+		require.Equal(t, filepath.Join(home, "captures"), c.Watch[0].Path)
+		require.True(t, c.Watch[0].Recursive)
+		require.Equal(t, filepath.Join(working, "relative"), c.Watch[1].Path)
+		require.Equal(t, filepath.Join(home, "spool"), c.SpoolDir)
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		problem string
+	}{
+		{
+			name:    "unknown field",
+			content: "server:\n  endpoints:\n    - address: localhost\n      port: 4747\nuploads: {}\n",
+			problem: "field uploads not found",
+		},
+		{
+			name:    "invalid duration",
+			content: "upload:\n  interval: often\n",
+			problem: "invalid configuration file",
+		},
+		{
+			name:    "invalid gps source",
+			content: "server:\n  endpoints:\n    - address: localhost\n      port: 4747\ngps:\n  source: serial\n",
+			problem: "gps.source: 'serial' is not one of gpsd, nmea",
+		},
+		{
+			name:    "missing endpoints",
+			content: "server:\n  endpoints: []\n",
+			problem: "server: at least one endpoint is required",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writeConfig(t, test.content)
+
+			_, err := config.Load()
+			require.ErrorContains(t, err, test.problem)
+		})
+	}
+
+	t.Run("missing file set through CONFIG_FILE", func(t *testing.T) {
+		directory := writeConfig(t, "")
+		constants.ConfigFile = filepath.Join(directory, "missing.yaml")
+
+		_, err := config.Load()
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestWithRemote(t *testing.T) {
+	c := validConfig()
+	c.RemoteFile = filepath.Join(t.TempDir(), "remote.yaml")
+
+	// the remote settings are empty until a directive stores them
+	remote, err := c.LoadRemote()
+	require.NoError(t, err)
+	require.Equal(t, &config.Remote{}, remote)
+
+	require.NoError(t, c.SaveRemote(&config.Remote{
+		Schedule: &config.Schedule{Interval: time.Hour, Windows: []string{"01:00-05:00"}},
+	}))
+
+	remote, err = c.LoadRemote()
+	require.NoError(t, err)
+
+	changed, err := c.WithRemote(remote)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, changed.Upload.Interval)
+	require.Equal(t, []string{"01:00-05:00"}, changed.Upload.Windows)
+	require.Equal(t, c.Watch, changed.Watch)
+	require.Equal(t, 5*time.Minute, c.Upload.Interval, "c is left as it is")
+
+	_, err = c.WithRemote(&config.Remote{Schedule: &config.Schedule{Interval: time.Second}})
+	require.ErrorContains(t, err, "upload.interval: must be at least 10s")
+}
+
+func TestMatches(t *testing.T) {
+	directory := config.WatchDirectory{
+		Include: []string{"*.pcap", "*.pcapng"},
+		Exclude: []string{"tmp-*"},
+	}
+
+	tests := []struct {
+		path    string
+		matches bool
+	}{
+		{path: "/captures/home.pcap", matches: true},
+		{path: "/captures/home.pcapng", matches: true},
+		{path: "/captures/home.cap", matches: false},
+		{path: "/captures/tmp-home.pcap", matches: false},
+		{path: "/tmp-captures/home.pcap", matches: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			require.Equal(t, test.matches, directory.Matches(test.path))
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse("15:04", clock)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	tests := []struct {
+		name    string
+		windows []string
+		clock   string
+		allowed bool
+	}{
+		{name: "any time without windows", clock: "13:00", allowed: true},
+		{name: "inside", windows: []string{"09:00-17:00"}, clock: "09:00", allowed: true},
+		{name: "end excluded", windows: []string{"09:00-17:00"}, clock: "17:00", allowed: false},
+		{name: "before", windows: []string{"09:00-17:00"}, clock: "08:59", allowed: false},
+		{name: "across midnight before", windows: []string{"22:00-06:00"}, clock: "23:30", allowed: true},
+		{name: "across midnight after", windows: []string{"22:00-06:00"}, clock: "05:59", allowed: true},
+		{name: "across midnight outside", windows: []string{"22:00-06:00"}, clock: "12:00", allowed: false},
+		{name: "second window", windows: []string{"01:00-02:00", "12:00-13:00"}, clock: "12:30", allowed: true},
+		{name: "invalid windows are skipped", windows: []string{"noon", "12:00-13:00"}, clock: "12:30", allowed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upload := config.Upload{Windows: test.windows}
+			require.Equal(t, test.allowed, upload.Allowed(at(test.clock)))
+		})
+	}
+}
@@ -7,15 +7,69 @@ import (
 const PCAPExtension = ".pcap"
 const MachineIDFile = "/etc/machine-id"
 
+// DaemonVersion reported to the server when logging in
+const DaemonVersion = "1.1.0"
+
+// CertServerName name in the certificates issued by the server CA
+const CertServerName = "HDS"
+
+// Bundles written by the export command, the server reads them in the same way
+const (
+	BundleVersion   = 1
+	BundleManifest  = "manifest.json"
+	BundleSignature = "manifest.sig"
+	// MaxBundleSize the server refuses larger bundles, captures are split in more bundles
+	MaxBundleSize = 128 << 20
+)
+
+// Kinds of the directives sent by the server
+const (
+	DirectiveSchedule       = "schedule"
+	DirectiveAddWatch       = "add_watch"
+	DirectiveRemoveWatch    = "remove_watch"
+	DirectivePurge          = "purge"
+	DirectiveReauthenticate = "reauthenticate"
+	DirectiveUploadLogs     = "upload_logs"
+)
+
+// HandshakeAlreadyPresent reason sent by the server for captures it already has
+const HandshakeAlreadyPresent = "error creating handshake: handshake already present"
+
+// PendingApproval reason sent by the server until the user approves the device
+const PendingApproval = "the raspberry pi is waiting for approval, approve it from the devices page"
+
 var (
 	ServerHost = os.Getenv("SERVER_HOST")
 	ServerPort = os.Getenv("SERVER_PORT")
 
 	TCPAddress = os.Getenv("TCP_ADDRESS")
 	TCPPort    = os.Getenv("TCP_PORT")
+	TCPTLS     = os.Getenv("TCP_TLS") == "True"
+	// TCPCACert optional path of the server CA, pins the server certificate before a device certificate is enrolled
+	TCPCACert = os.Getenv("TCP_CA_CERT")
+	// ServerHTTPSURL optional URL of the server REST API, used when the TCP server is unreachable
+	ServerHTTPSURL = os.Getenv("SERVER_HTTPS_URL")
+	// ConfigFile path of the configuration file, defaults to ~/.hds/config.yaml
+	ConfigFile = os.Getenv("CONFIG_FILE")
+	// CredentialFile where the device credential is stored, it is created when the device is enrolled
+	CredentialFile = os.Getenv("CREDENTIAL_FILE")
+	// KeyFile where the key of the last key exchange is stored, for signing the bundles written while offline
+	KeyFile = os.Getenv("KEY_FILE")
+	// ResultsFile where the networks cracked are written, defaults to ~/.hds/results.json
+	ResultsFile = os.Getenv("RESULTS_FILE")
+	// LogFile where the logs are written while the dashboard is shown, defaults to ~/.hds/daemon.log
+	LogFile = os.Getenv("LOG_FILE")
+	// RemoteFile where the settings changed by the directives of the server are stored, defaults to ~/.hds/remote.yaml
+	RemoteFile = os.Getenv("REMOTE_FILE")
+	// SpoolDir where captures ready to be uploaded are kept until the server acknowledges them
+	SpoolDir = os.Getenv("SPOOL_DIR")
 
 	Test      = os.Getenv("TEST") == "True"
 	Bettercap = os.Getenv("BETTERCAP") == "True"
+	// BettercapAPI URL of the bettercap REST API, captures are read from it instead of the watch directories when set
+	BettercapAPI         = os.Getenv("BETTERCAP_API")
+	BettercapAPIUsername = os.Getenv("BETTERCAP_API_USERNAME")
+	BettercapAPIPassword = os.Getenv("BETTERCAP_API_PASSWORD")
 
 	HomeWIFISSID = os.Getenv("HOME_WIFI")
 )
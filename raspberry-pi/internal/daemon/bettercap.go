@@ -0,0 +1,310 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/bettercap"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
+	"github.com/google/gopacket/pcap"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var bettercapRetryDelay = 10 * time.Second
+
+/*
+BettercapEnvironment
+
+Reads the captures through the REST API of bettercap, which can run on another host.
+Captures are downloaded in a local directory, so they can be parsed and uploaded as the ones of the watch directories,
+and they are named by their path on the bettercap host. Captures keeps the same role as Watcher.Captures:
+it receives the bettercap path of the captures reported by the handshake events, once they have settled
+*/
+type BettercapEnvironment struct {
+	Captures chan string
+
+	client    *bettercap.Client
+	directory string
+	settle    time.Duration
+
+	mutex        sync.RWMutex
+	accessPoints map[string]*bettercap.AccessPoint
+}
+
+// NewBettercapEnvironment returns the environment of the bettercap API configured, call Run for receiving the captures
+func NewBettercapEnvironment(cfg *config.Bettercap, settle time.Duration) (*BettercapEnvironment, error) {
+	client, err := bettercap.NewClient(cfg.URL, cfg.Username, cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	directory, err := dataPath(cfg.DownloadDir, "bettercap")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(directory, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &BettercapEnvironment{
+		Captures:     make(chan string, 64),
+		client:       client,
+		directory:    directory,
+		settle:       settle,
+		accessPoints: make(map[string]*bettercap.AccessPoint),
+	}, nil
+}
+
+// localPath returns where the capture at name on the bettercap host is downloaded
+func (b *BettercapEnvironment) localPath(name string) (string, error) {
+	local := filepath.Join(b.directory, filepath.FromSlash(name))
+	if relative, err := filepath.Rel(b.directory, local); err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+		return "", fmt.Errorf("'%s' is not a valid capture name", name)
+	}
+
+	return local, nil
+}
+
+// download copies the capture from bettercap, the local copy is replaced only when the capture has changed
+func (b *BettercapEnvironment) download(name string) (string, error) {
+	local, err := b.localPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	var content bytes.Buffer
+	if err = b.client.Download(name, &content); err != nil {
+		return "", err
+	}
+
+	if previous, errRead := os.ReadFile(local); errRead == nil && bytes.Equal(previous, content.Bytes()) {
+		return local, nil
+	}
+
+	if err = os.MkdirAll(filepath.Dir(local), 0o700); err != nil {
+		return "", err
+	}
+
+	temp := local + ".tmp"
+	if err = os.WriteFile(temp, content.Bytes(), 0o600); err != nil {
+		return "", err
+	}
+
+	return local, os.Rename(temp, local)
+}
+
+// downloadAll downloads the captures, the local copy is used when a capture can't be downloaded
+func (b *BettercapEnvironment) downloadAll(names []string) []string {
+	locals := make([]string, 0, len(names))
+	for _, name := range names {
+		local, err := b.download(name)
+		if err == nil {
+			locals = append(locals, local)
+			continue
+		}
+
+		log.Warnf("[RSP-PI] Unable to download '%s' from bettercap: %s", name, err.Error())
+		if local, errPath := b.localPath(name); errPath == nil {
+			if _, errStat := os.Stat(local); errStat == nil {
+				locals = append(locals, local)
+			}
+		}
+	}
+
+	return locals
+}
+
+// downloaded returns the bettercap path of the captures downloaded so far
+func (b *BettercapEnvironment) downloaded() ([]string, error) {
+	var names []string
+
+	err := filepath.WalkDir(b.directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return err
+		}
+
+		relative, err := filepath.Rel(b.directory, path)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, "/"+filepath.ToSlash(relative))
+		return nil
+	})
+
+	return names, err
+}
+
+// refreshAccessPoints replaces the access points known with the ones seen by bettercap
+func (b *BettercapEnvironment) refreshAccessPoints() ([]*bettercap.AccessPoint, error) {
+	accessPoints, err := b.client.AccessPoints()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	clear(b.accessPoints)
+	for _, accessPoint := range accessPoints {
+		b.accessPoints[strings.ToLower(accessPoint.MAC)] = accessPoint
+	}
+
+	return accessPoints, nil
+}
+
+/*
+captureNames
+
+Returns the bettercap path of every capture known: the handshake files of the bettercap configuration,
+the ones reported by the events bettercap still keeps and the ones downloaded before, which bettercap may have forgotten
+*/
+func (b *BettercapEnvironment) captureNames() ([]string, error) {
+	accessPoints, err := b.refreshAccessPoints()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := b.client.HandshakeFiles(accessPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := b.client.Events()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if handshake, ok := handshakeOf(event); ok {
+			names = append(names, handshake.File)
+		}
+	}
+
+	downloaded, err := b.downloaded()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(names)+len(downloaded))
+	for _, name := range append(names, downloaded...) {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	return unique, nil
+}
+
+// handshakeOf returns the data of a handshake event
+func handshakeOf(event *bettercap.Event) (*bettercap.Handshake, bool) {
+	if event.Tag != bettercap.HandshakeEvent {
+		return nil, false
+	}
+
+	var handshake bettercap.Handshake
+	if err := json.Unmarshal(event.Data, &handshake); err != nil || handshake.File == "" {
+		return nil, false
+	}
+
+	return &handshake, true
+}
+
+// LoadEnvironment downloads and opens every capture known by bettercap
+func (b *BettercapEnvironment) LoadEnvironment() (map[string]*pcap.Handle, error) {
+	names, err := b.captureNames()
+	if err != nil {
+		return nil, err
+	}
+
+	return openCaptures(b.downloadAll(names)), nil
+}
+
+// LoadCaptures downloads and opens the captures, paths are the ones on the bettercap host
+func (b *BettercapEnvironment) LoadCaptures(paths []string) map[string]*pcap.Handle {
+	// the access points which just had their handshake captured are likely to be new
+	if _, err := b.refreshAccessPoints(); err != nil {
+		log.Warnf("[RSP-PI] Unable to read the access points from bettercap: %s", err.Error())
+	}
+
+	return openCaptures(b.downloadAll(paths))
+}
+
+// Describe returns what bettercap knows about the access point, empty when it has not seen it
+func (b *BettercapEnvironment) Describe(bssid string) string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	accessPoint, found := b.accessPoints[strings.ToLower(bssid)]
+	if !found {
+		return ""
+	}
+
+	details := []string{fmt.Sprintf("ch %d", accessPoint.Channel), fmt.Sprintf("%d dBm", accessPoint.RSSI)}
+	if accessPoint.Encryption != "" {
+		details = append(details, accessPoint.Encryption)
+	}
+	if accessPoint.Vendor != "" {
+		details = append(details, accessPoint.Vendor)
+	}
+
+	return strings.Join(details, ", ")
+}
+
+func (b *BettercapEnvironment) Close() {}
+
+/*
+Run
+
+Subscribes to the bettercap events, reconnecting when the connection is lost, and delivers on Captures the
+captures of the handshake events. Bettercap appends to a capture each time new key material is received,
+so a capture is delivered only once no event has been received for it in the settle period
+*/
+func (b *BettercapEnvironment) Run() {
+	handshakes := make(chan string, 64)
+
+	go func() {
+		for {
+			err := b.client.Subscribe(func(event *bettercap.Event) {
+				if handshake, ok := handshakeOf(event); ok {
+					handshakes <- handshake.File
+				}
+			})
+			if err == nil {
+				err = errors.New("connection closed")
+			}
+
+			log.Warnf("[RSP-PI] Bettercap events stopped: %s", err.Error())
+			time.Sleep(bettercapRetryDelay)
+		}
+	}()
+
+	pending := make(map[string]time.Time)
+
+	ticker := time.NewTicker(max(b.settle/2, minimumSettleCheck))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case name := <-handshakes:
+			pending[name] = time.Now()
+		case now := <-ticker.C:
+			for name, changed := range pending {
+				if now.Sub(changed) >= b.settle {
+					delete(pending, name)
+					b.Captures <- name
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/bettercap"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// fakeBettercap a stand-in for the REST API of bettercap, the fields can be changed while the test runs
+type fakeBettercap struct {
+	mutex        sync.Mutex
+	files        map[string]string
+	accessPoints []*bettercap.AccessPoint
+	events       []*bettercap.Event
+	// streams events sent on each connection to the events websocket, the connection is closed after them
+	streams chan []*bettercap.Event
+}
+
+func (f *fakeBettercap) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/session/wifi", func(w http.ResponseWriter, _ *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"aps": f.accessPoints})
+	})
+	mux.HandleFunc("/api/session/env", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"wifi.handshakes.file": "/root/handshakes.pcap"}})
+	})
+	mux.HandleFunc("/api/file", func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		content, found := f.files[r.URL.Query().Get("name")]
+		if !found {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	})
+	mux.HandleFunc("/api/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") == "" {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			_ = json.NewEncoder(w).Encode(f.events)
+			return
+		}
+
+		websocket.Handler(func(conn *websocket.Conn) {
+			for _, event := range <-f.streams {
+				_ = websocket.JSON.Send(conn, event)
+			}
+		}).ServeHTTP(w, r)
+	})
+
+	return mux
+}
+
+func handshakeEvent(t *testing.T, file string) *bettercap.Event {
+	data, err := json.Marshal(&bettercap.Handshake{File: file, AP: "e4:8f:34:7d:b5:25", Full: true})
+	require.NoError(t, err)
+	return &bettercap.Event{Tag: bettercap.HandshakeEvent, Time: time.Now(), Data: data}
+}
+
+// startBettercap returns the environment of a fake bettercap, captures are downloaded in a temporary directory
+func startBettercap(t *testing.T, fake *fakeBettercap, settle time.Duration) *BettercapEnvironment {
+	server := httptest.NewServer(fake.handler())
+	t.Cleanup(server.Close)
+
+	environment, err := NewBettercapEnvironment(&config.Bettercap{
+		URL:         server.URL,
+		DownloadDir: t.TempDir(),
+	}, settle)
+	require.NoError(t, err)
+
+	return environment
+}
+
+func TestBettercapCaptureNames(t *testing.T) {
+	fake := &fakeBettercap{
+		files: map[string]string{
+			"/root/handshakes.pcap":      "aggregated",
+			"/root/handshakes/home.pcap": "home",
+			"/root/forgotten.pcap":       "forgotten",
+		},
+		events: []*bettercap.Event{
+			{Tag: "wifi.ap.new", Data: json.RawMessage(`{"mac":"e4:8f:34:7d:b5:25"}`)},
+			handshakeEvent(t, "/root/handshakes/home.pcap"),
+			handshakeEvent(t, "/root/handshakes.pcap"),
+			{Tag: bettercap.HandshakeEvent, Data: json.RawMessage(`{"ap":"e4:8f:34:7d:b5:25"}`)},
+		},
+	}
+	environment := startBettercap(t, fake, time.Second)
+
+	// downloaded before bettercap forgot the event
+	_, err := environment.download("/root/forgotten.pcap")
+	require.NoError(t, err)
+
+	names, err := environment.captureNames()
+	require.NoError(t, err)
+	require.Equal(t, []string{"/root/handshakes.pcap", "/root/handshakes/home.pcap", "/root/forgotten.pcap"}, names)
+}
+
+func TestBettercapDownload(t *testing.T) {
+	fake := &fakeBettercap{files: map[string]string{"/root/handshakes.pcap": "first"}}
+	environment := startBettercap(t, fake, time.Second)
+
+	local := filepath.Join(environment.directory, "root", "handshakes.pcap")
+
+	tests := []struct {
+		name    string
+		change  func()
+		names   []string
+		locals  []string
+		content string
+	}{
+		{
+			name:    "downloaded",
+			names:   []string{"/root/handshakes.pcap"},
+			locals:  []string{local},
+			content: "first",
+		},
+		{
+			name:    "changed on bettercap",
+			change:  func() { fake.files["/root/handshakes.pcap"] = "second" },
+			names:   []string{"/root/handshakes.pcap"},
+			locals:  []string{local},
+			content: "second",
+		},
+		{
+			name:    "local copy used when bettercap loses it",
+			change:  func() { delete(fake.files, "/root/handshakes.pcap") },
+			names:   []string{"/root/handshakes.pcap"},
+			locals:  []string{local},
+			content: "second",
+		},
+		{
+			name:    "never downloaded",
+			names:   []string{"/root/missing.pcap"},
+			locals:  []string{},
+			content: "second",
+		},
+		{
+			name:    "outside the download directory",
+			change:  func() { fake.files["/../escape.pcap"] = "escape" },
+			names:   []string{"/../escape.pcap"},
+			locals:  []string{},
+			content: "second",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.change != nil {
+				fake.mutex.Lock()
+				test.change()
+				fake.mutex.Unlock()
+			}
+
+			require.Equal(t, test.locals, environment.downloadAll(test.names))
+
+			content, err := os.ReadFile(local)
+			require.NoError(t, err)
+			require.Equal(t, test.content, string(content))
+		})
+	}
+}
+
+func TestBettercapDescribe(t *testing.T) {
+	fake := &fakeBettercap{
+		accessPoints: []*bettercap.AccessPoint{
+			{MAC: "E4:8F:34:7D:B5:25", Hostname: "HOME", Vendor: "Vodafone", Channel: 6, RSSI: -48, Encryption: "WPA2"},
+			{MAC: "00:01:02:03:04:05", Hostname: "OPEN", Channel: 11, RSSI: -80},
+		},
+	}
+	environment := startBettercap(t, fake, time.Second)
+
+	_, err := environment.refreshAccessPoints()
+	require.NoError(t, err)
+
+	tests := []struct {
+		bssid   string
+		details string
+	}{
+		{bssid: "e4:8f:34:7d:b5:25", details: "ch 6, -48 dBm, WPA2, Vodafone"},
+		{bssid: "00:01:02:03:04:05", details: "ch 11, -80 dBm"},
+		{bssid: "00:01:02:03:04:06", details: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.bssid, func(t *testing.T) {
+			require.Equal(t, test.details, environment.Describe(test.bssid))
+		})
+	}
+
+	// access points gone from bettercap are forgotten
+	fake.mutex.Lock()
+	fake.accessPoints = fake.accessPoints[1:]
+	fake.mutex.Unlock()
+
+	_, err = environment.refreshAccessPoints()
+	require.NoError(t, err)
+	require.Empty(t, environment.Describe("e4:8f:34:7d:b5:25"))
+}
+
+// TestBettercapRun checks that the captures of the handshake events are delivered once settled,
+// also after the connection to the events is lost
+func TestBettercapRun(t *testing.T) {
+	// Run never returns, the delay stays short for the retries following the test
+	bettercapRetryDelay = 50 * time.Millisecond
+
+	fake := &fakeBettercap{streams: make(chan []*bettercap.Event, 2)}
+	fake.streams <- []*bettercap.Event{
+		handshakeEvent(t, "/root/handshakes/home.pcap"),
+		{Tag: "wifi.ap.new", Data: json.RawMessage(`{}`)},
+		handshakeEvent(t, "/root/handshakes/home.pcap"),
+	}
+	fake.streams <- []*bettercap.Event{
+		handshakeEvent(t, "/root/handshakes/office.pcap"),
+	}
+
+	environment := startBettercap(t, fake, testSettle)
+	go environment.Run()
+
+	var captures []string
+	timeout := time.After(10 * testSettle)
+	for len(captures) < 2 {
+		select {
+		case capture := <-environment.Captures:
+			captures = append(captures, capture)
+		case <-timeout:
+			require.FailNow(t, "captures not delivered", "delivered %v", captures)
+		}
+	}
+
+	slices.Sort(captures)
+	require.Equal(t, []string{"/root/handshakes/home.pcap", "/root/handshakes/office.pcap"}, captures)
+
+	select {
+	case capture := <-environment.Captures:
+		require.FailNow(t, "capture delivered twice", capture)
+	case <-time.After(3 * testSettle):
+	}
+}
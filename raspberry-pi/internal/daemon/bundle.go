@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/utils"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/wpaparser"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// bundleCapacity captures of a bundle can't exceed it, the rest is left for the manifest and the tar headers
+const bundleCapacity = constants.MaxBundleSize - (1 << 20)
+
+// bundleWriter a bundle being written, it is renamed to its final name only once closed
+type bundleWriter struct {
+	path       string
+	file       *os.File
+	compressed *gzip.Writer
+	archive    *tar.Writer
+	manifest   entities.BundleManifest
+	size       int64
+}
+
+/*
+ExportBundles
+
+Writes the captures in directory, e.g. a USB stick, as bundles which can be imported from the devices page of the FE
+when the device can't reach the server. Captures are sealed as for the uploads and the manifest is signed
+with the key of the last key exchange, so the server can verify the bundles until the next key exchange.
+More bundles are written when the captures exceed the size accepted by the server, their paths are returned
+*/
+func ExportBundles(machineID string, captures []*wpaparser.HandshakeInfo, directory string) ([]string, error) {
+	key, err := LoadKey()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("no encryption key has been exchanged yet, start the daemon once while the server is reachable")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	var bundle *bundleWriter
+	for _, capture := range captures {
+		content, errRead := utils.ReadFileBytes(capture.FilePath)
+		if errRead != nil {
+			log.Warnf("[RSP-PI] Unable to read '%s': %s", capture.FilePath, errRead.Error())
+			continue
+		}
+
+		payload, errSeal := sealCapture(key, machineID, capture, content)
+		if errSeal != nil {
+			return paths, errSeal
+		}
+
+		if int64(len(payload)) > bundleCapacity {
+			log.Warnf("[RSP-PI] '%s' is too large for a bundle, upload it to the server", capture.FilePath)
+			continue
+		}
+
+		if bundle != nil && bundle.size+int64(len(payload)) > bundleCapacity {
+			if err = bundle.close(key); err != nil {
+				return paths, err
+			}
+			paths = append(paths, bundle.path)
+			bundle = nil
+		}
+
+		if bundle == nil {
+			if bundle, err = newBundleWriter(directory, machineID, len(paths)+1); err != nil {
+				return paths, err
+			}
+		}
+
+		if err = bundle.add(capture, payload); err != nil {
+			bundle.abort()
+			return paths, err
+		}
+	}
+
+	if bundle == nil {
+		return paths, errors.New("no capture to export")
+	}
+
+	if err = bundle.close(key); err != nil {
+		return paths, err
+	}
+
+	return append(paths, bundle.path), nil
+}
+
+func newBundleWriter(directory, machineID string, number int) (*bundleWriter, error) {
+	if err := os.MkdirAll(directory, 0o700); err != nil {
+		return nil, err
+	}
+
+	created := time.Now().UTC()
+	path := filepath.Join(directory, fmt.Sprintf("hds-%s-%s-%d.tar.gz", machineID[:8], created.Format("20060102T150405Z"), number))
+
+	file, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := gzip.NewWriter(file)
+	return &bundleWriter{
+		path:       path,
+		file:       file,
+		compressed: compressed,
+		archive:    tar.NewWriter(compressed),
+		manifest: entities.BundleManifest{
+			Version:   constants.BundleVersion,
+			MachineID: machineID,
+			Created:   created,
+		},
+	}, nil
+}
+
+func (b *bundleWriter) write(name string, content []byte) error {
+	err := b.archive.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(content)),
+		ModTime: b.manifest.Created,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = b.archive.Write(content)
+	return err
+}
+
+// add writes the sealed capture and describes it in the manifest
+func (b *bundleWriter) add(capture *wpaparser.HandshakeInfo, payload []byte) error {
+	name := fmt.Sprintf("captures/%04d.blob", len(b.manifest.Captures)+1)
+	if err := b.write(name, payload); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(payload)
+	b.manifest.Captures = append(b.manifest.Captures, &entities.BundleCapture{
+		File:     name,
+		SSID:     capture.SSID,
+		BSSID:    capture.BSSID,
+		Size:     int64(len(payload)),
+		Checksum: hex.EncodeToString(sum[:]),
+		Position: positionOf(capture),
+		Quality:  capture.Classification,
+	})
+	b.size += int64(len(payload))
+	return nil
+}
+
+// close writes the manifest with its signature, after the captures it describes
+func (b *bundleWriter) close(key string) error {
+	manifest, err := json.MarshalIndent(&b.manifest, "", "  ")
+	if err != nil {
+		b.abort()
+		return err
+	}
+
+	signature, err := utils.SignBundle(key, manifest)
+	if err != nil {
+		b.abort()
+		return err
+	}
+
+	if err = errors.Join(b.write(constants.BundleManifest, manifest), b.write(constants.BundleSignature, []byte(signature)),
+		b.archive.Close(), b.compressed.Close(), b.file.Sync(), b.file.Close()); err != nil {
+		_ = os.Remove(b.file.Name())
+		return err
+	}
+
+	return os.Rename(b.file.Name(), b.path)
+}
+
+// abort removes the bundle being written
+func (b *bundleWriter) abort() {
+	_ = b.file.Close()
+	_ = os.Remove(b.file.Name())
+}
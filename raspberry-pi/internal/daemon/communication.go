@@ -1,10 +1,10 @@
 package daemon
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/enums"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/utils"
@@ -13,229 +13,213 @@ import (
 	"time"
 )
 
+// ServerError is the reason sent by the server when it refuses a request
+type ServerError struct {
+	Reason string
+}
+
+func (e *ServerError) Error() string {
+	return e.Reason
+}
+
+// approvalRetryDelay how often the daemon tries to log in while the user has not approved it yet
+var approvalRetryDelay = 30 * time.Second
+
 /*
 Authenticator
 
-Uses provided credentials for authenticating the user via TCP each hour
+Uses the device credential for authenticating via TCP each hour, via HTTPS when the TCP server is unreachable.
+A device just enrolled waits here until the user approves it
 */
 func (r *RaspberryPiInfo) Authenticator() {
 	tickerLogin := time.NewTicker(1 * time.Hour) // every hour
 
 	for {
-		client, err := InitClientConnection()
-
-		if err != nil {
-			log.Fatalf("Failed to initialize client connection: %v", err)
-		}
-
-		// 1. Send login request
-		err = client.writeToServerCommand(enums.LOGIN)
-
-		if err != nil {
-			log.Fatalf("[RSP-PI] Failed to write command to the server: %s", err.Error())
+		response, err := r.login()
+
+		var serverErr *ServerError
+		switch {
+		case errors.As(err, &serverErr) && strings.Contains(serverErr.Reason, constants.PendingApproval):
+			log.Warnf("[RSP-PI] Waiting for approval, approve the device with code %s from the devices page", utils.EnrollmentCode(r.Credential))
+			time.Sleep(approvalRetryDelay)
+			continue
+		case errors.As(err, &serverErr):
+			path, _ := CredentialPath()
+			log.Fatalf("[RSP-PI] Device credential refused (%s), write the rotated one in %s or delete it for enrolling the device again", serverErr.Reason, path)
+		case err != nil:
+			log.Fatalf("[RSP-PI] Login failed: %s", err.Error())
 		}
 
-		if jwt, errRequest := client.writeToServerAuthRequest(r.Credentials); err == nil {
+		if jwt := string(response); utils.IsJWT(jwt) {
 			*r.JWT = jwt
 			r.FirstLogin <- true
 		} else {
-			log.Fatal(errRequest)
+			log.Fatalf("[RSP-PI] Unexpected login response: %s", jwt)
 		}
 
-		err = client.Conn.Close()
-		if err != nil {
-			log.Fatalf("[RSP-PI] Failed to close the connection: %v", err)
-		}
 		<-tickerLogin.C
 	}
 }
 
-func (c *Client) readFromServer() (string, error) {
-	// bufio.NewReader(c.Conn).ReadString('\n') this includes the \n at the end as result
-	ss, err := bufio.NewReader(c.Conn).ReadString('\n')
-
+// Reauthenticate logs in again and exchanges a new key, as when the daemon starts
+func (r *RaspberryPiInfo) Reauthenticate() error {
+	response, err := r.login()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return strings.TrimRight(ss, "\n"), nil
-}
+	jwt := string(response)
+	if !utils.IsJWT(jwt) {
+		return fmt.Errorf("unexpected login response: %s", jwt)
+	}
+	*r.JWT = jwt
 
-func (c *Client) isACKMessage(msg string) bool {
-	return msg == enums.ACK.String()
+	return ExchangeKey(r, r.MachineID)
 }
 
-func (c *Client) writeToServerCommand(command enums.Command) error {
-	cc := command.String()
-	ll := []byte(fmt.Sprintf("%v", len(cc)) + "\n")
-	_, err := c.Conn.Write(ll)
+// login sends the device credential with the metadata of the device, returning the device token
+func (r *RaspberryPiInfo) login() ([]byte, error) {
+	metadata := currentMetadata()
 
-	if err != nil {
-		return err
-	}
-
-	// accept ack from the server
-	err = c.readACKFromServer()
-	if err != nil {
-		// if here command not valid
-		return err
+	client, err := InitClientConnection()
+	if useHTTPS(err) {
+		token, errLogin := httpsLogin(r.MachineID, r.Credential, metadata)
+		return []byte(token), errLogin
 	}
 
-	// Send the actual data
-	_, err = c.Conn.Write([]byte(cc + "\n"))
-
 	if err != nil {
-		return err
+		log.Fatalf("Failed to initialize client connection: %v", err)
 	}
 
-	// accept ack from the server
-	err = c.readACKFromServer()
-	if err != nil {
-		// if here command not valid
-		return err
-	}
+	defer client.Conn.Close()
 
-	return nil
+	return client.request(enums.DEVICELOGIN, &entities.TCPDeviceLoginRequest{
+		MachineID:     r.MachineID,
+		Credential:    r.Credential,
+		Hostname:      metadata.hostname,
+		DaemonVersion: metadata.version,
+		OS:            metadata.os,
+	})
 }
 
-func (c *Client) writeToServerAuthRequest(request *entities.AuthRequest) (string, error) {
-	marshaled, err := json.Marshal(request)
-
+// request sends a command with its JSON request and waits for the answer carrying the same request ID
+func (c *Client) request(command enums.Command, request any) ([]byte, error) {
+	payload, err := json.Marshal(request)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	ll := []byte(fmt.Sprintf("%v", len(marshaled)) + "\n")
-	_, err = c.Conn.Write(ll)
-
-	if err != nil {
-		return "", err
+	// the deadline is refreshed on each request, uploads can take longer than a single timeout
+	if err = c.Conn.SetDeadline(time.Now().Add(serverTimedOutDuration)); err != nil {
+		return nil, err
 	}
 
-	// accept ack from the server
-	err = c.readACKFromServer()
-
-	if err != nil {
-		return "", err
-	}
-
-	// Send the actual data
-	_, err = c.Conn.Write(marshaled)
-
+	c.requestID++
+	err = writeFrame(c.Conn, &frame{
+		Version:   frameVersion,
+		Type:      byte(command),
+		RequestID: c.requestID,
+		Payload:   payload,
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// accept ack from the server
-	err = c.readACKFromServer()
+	for {
+		response, err := readFrame(c.reader)
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return "", err
-	}
+		if response.RequestID != c.requestID {
+			log.Warnf("[RSP-PI] Ignoring response to request %d while waiting for %d", response.RequestID, c.requestID)
+			continue
+		}
 
-	// read token or error
-	msg, err := c.readFromServer()
-	if err != nil {
-		return "", err
-	}
+		if response.Type == byte(enums.ERROR) {
+			return nil, &ServerError{Reason: string(response.Payload)}
+		}
 
-	if !utils.IsJWT(msg) {
-		return "", errors.New(msg)
+		return response.Payload, nil
 	}
-
-	return msg, nil
 }
 
-func (c *Client) writeToServerHandshake(request entities.TCPCreateRaspberryPIRequest) (int, error) {
-	marshaled, err := json.Marshal(request)
-
+// ExchangeKey agrees with the server on the key used for encrypting captures.
+// Only public keys travel on the wire, the server derives the same key and stores it for machineID
+func ExchangeKey(instance *RaspberryPiInfo, machineID string) error {
+	private, err := utils.GenerateExchangeKey()
 	if err != nil {
-		return -1, err
+		return err
 	}
 
-	ll := []byte(fmt.Sprintf("%v", len(marshaled)) + "\n")
-	_, err = c.Conn.Write(ll)
-
-	if err != nil {
-		return 0, err
-	}
+	publicKey := utils.BytesToBase64String(private.PublicKey().Bytes())
 
-	// accept ack from the server
-	err = c.readACKFromServer()
+	var serverPublicKey string
+	client, err := InitClientConnection()
+	switch {
+	case useHTTPS(err):
+		serverPublicKey, err = httpsExchangeKey(*instance.JWT, publicKey)
+	case err != nil:
+		return err
+	default:
+		defer client.Conn.Close()
 
-	if err != nil {
-		return 0, err
+		var response []byte
+		response, err = client.request(enums.KEYEXCHANGE, entities.TCPKeyExchangeRequest{
+			Jwt:       *instance.JWT,
+			MachineID: machineID,
+			PublicKey: publicKey,
+		})
+		serverPublicKey = string(response)
 	}
-
-	// Send the actual data
-	wrote, err := c.Conn.Write(marshaled)
-
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("[RSP-PI] Key exchange failed: %s", err.Error())
 	}
 
-	// accept ack from the server
-	err = c.readACKFromServer()
-
+	key, err := utils.DeriveDeviceKey(private, serverPublicKey, machineID)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("[RSP-PI] Invalid server public key: %s", err.Error())
 	}
 
-	return wrote, nil
-}
-
-func (c *Client) readACKFromServer() error {
-	// accept ack from the server
-	read, err := c.readFromServer()
-
-	if err != nil {
-		return err
-	}
+	*instance.EncryptionKey = key
 
-	if !c.isACKMessage(read) {
-		var status = enums.FAIL
-		return fmt.Errorf("error did not received an ACK %s", status)
+	// bundles written while offline are signed with the last key exchanged
+	if err = saveKey(key); err != nil {
+		log.Warnf("[RSP-PI] Unable to save the encryption key, bundles can't be exported: %s", err.Error())
 	}
-
 	return nil
 }
 
-// HandleServerCommunication handles data exchange with the server.
-func HandleServerCommunication(instance *RaspberryPiInfo, machineID string, handshakes []*entities.Handshake) error {
+// Enroll requests a certificate for the device. From now on TLS connections present it and
+// verify the server against the CA which issued it. The server regenerates its CA on every restart,
+// so the daemon enrolls each time it starts. Certificates are not used by the REST API,
+// when only it is reachable the enrollment is left to the next start
+func Enroll(instance *RaspberryPiInfo, machineID string) error {
 	client, err := InitClientConnection()
-
+	if useHTTPS(err) {
+		log.Warnf("[RSP-PI] Device certificate not enrolled, restart the daemon once the TCP server is reachable")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
 	defer client.Conn.Close()
 
-	// 1. Send hadnshake request
-	err = client.writeToServerCommand(enums.HANDSHAKE)
-
-	if err != nil {
-		return fmt.Errorf("[RSP-PI] Failed to write command to the server: %s", err.Error())
-	}
-
-	request := entities.TCPCreateRaspberryPIRequest{
-		Handshakes:    handshakes,
-		Jwt:           *instance.JWT,
-		MachineID:     machineID,
-		EncryptionKey: "",
+	request := entities.TCPCertificateRequest{
+		Jwt:       *instance.JWT,
+		MachineID: machineID,
 	}
 
-	wrote, err := client.writeToServerHandshake(request)
+	response, err := client.request(enums.CERTIFICATE, request)
 	if err != nil {
-		return fmt.Errorf("[RSP-PI] Failed to write to server: %s", err.Error())
+		return fmt.Errorf("[RSP-PI] Certificate enrollment failed: %s", err.Error())
 	}
-	log.Printf("[RSP-PI] Wrote %v bytes", wrote)
 
-	response, err := client.readFromServer()
-	if err != nil {
-		return fmt.Errorf("[RSP-PI] Failed to write to server: %s", err.Error())
+	var enrolled entities.TCPCertificateResponse
+	if err = json.Unmarshal(response, &enrolled); err != nil {
+		return err
 	}
 
-	log.Println("[RSP-PI] Response from server:", response)
-
-	return nil
+	return setEnrollment(&enrolled)
 }
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/enums"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/utils"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CredentialPath returns where the device credential is stored
+func CredentialPath() (string, error) {
+	return dataPath(settings.CredentialFile, "credential")
+}
+
+// LoadCredential reads the device credential. The error wraps os.ErrNotExist when the device has not been enrolled yet
+func LoadCredential() (string, error) {
+	path, err := CredentialPath()
+	if err != nil {
+		return "", err
+	}
+
+	return readSecret(path)
+}
+
+// KeyPath returns where the key of the last key exchange is stored
+func KeyPath() (string, error) {
+	return dataPath(settings.KeyFile, "key")
+}
+
+// LoadKey reads the key of the last key exchange. The error wraps os.ErrNotExist when no key has been exchanged yet
+func LoadKey() (string, error) {
+	path, err := KeyPath()
+	if err != nil {
+		return "", err
+	}
+
+	return readSecret(path)
+}
+
+// saveKey stores the key exchanged, the server keeps it until the next exchange
+func saveKey(key string) error {
+	path, err := KeyPath()
+	if err != nil {
+		return err
+	}
+
+	return writeSecret(path, key)
+}
+
+// readSecret reads a file holding a secret, refusing it when others can access it
+func readSecret(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	// secrets allow uploading on behalf of the user, as for ssh keys they must not be readable by others
+	if info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("file %s must be accessible only by its owner (chmod 600)", path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// saveCredential replaces the credential file, a crash can't leave the device without a credential
+func saveCredential(credential string) error {
+	path, err := CredentialPath()
+	if err != nil {
+		return err
+	}
+
+	return writeSecret(path, credential)
+}
+
+// writeSecret replaces the file at path atomically, only the owner can read it
+func writeSecret(path, secret string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, []byte(secret+"\n"), 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
+
+// EnrollDevice logs in once with the user credentials for approving the device. The credential received
+// is stored and used from now on, the user credentials are not needed anymore
+func EnrollDevice(credentials *entities.AuthRequest, machineID string) (string, error) {
+	client, err := InitClientConnection()
+	if err != nil {
+		return "", err
+	}
+
+	defer client.Conn.Close()
+
+	response, err := client.request(enums.LOGIN, credentials)
+	if err != nil {
+		return "", fmt.Errorf("[RSP-PI] Login failed: %s", err.Error())
+	}
+
+	jwt := string(response)
+	if !utils.IsJWT(jwt) {
+		return "", fmt.Errorf("[RSP-PI] Unexpected login response: %s", jwt)
+	}
+
+	response, err = client.request(enums.ENROLL, &entities.TCPEnrollRequest{
+		Jwt:       jwt,
+		MachineID: machineID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("[RSP-PI] Enrollment failed: %s", err.Error())
+	}
+
+	credential := string(response)
+	if err = saveCredential(credential); err != nil {
+		return "", err
+	}
+
+	log.Infof("[RSP-PI] Device enrolled, approve it from the devices page checking that its code is %s", utils.EnrollmentCode(credential))
+	return credential, nil
+}
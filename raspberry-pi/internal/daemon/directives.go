@@ -0,0 +1,341 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/enums"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/wpaparser"
+	log "github.com/sirupsen/logrus"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNoWatchDirectories = errors.New("captures are read from the bettercap API, there are no watch directories")
+
+// uploadedCapture the handshakes found in a capture by BSSID, true once they are on the server.
+// Modified is when the capture was last changed before being scanned
+type uploadedCapture struct {
+	modified   time.Time
+	handshakes map[string]bool
+}
+
+// uploads what is known about the captures scanned, for purging the ones uploaded
+var (
+	uploadsMutex sync.Mutex
+	uploads      = make(map[string]*uploadedCapture)
+)
+
+// scannedCaptures the captures about to be uploaded, none of their handshakes is on the server yet
+func scannedCaptures(captures []*wpaparser.HandshakeInfo) map[string]*uploadedCapture {
+	scanned := make(map[string]*uploadedCapture)
+	for _, capture := range captures {
+		entry, found := scanned[capture.FilePath]
+		if !found {
+			info, err := os.Stat(capture.FilePath)
+			if err != nil {
+				continue
+			}
+
+			entry = &uploadedCapture{modified: info.ModTime(), handshakes: make(map[string]bool)}
+			scanned[capture.FilePath] = entry
+		}
+		entry.handshakes[capture.BSSID] = false
+	}
+
+	return scanned
+}
+
+// markUploaded records that the handshake of the capture is on the server
+func markUploaded(scanned map[string]*uploadedCapture, capture *wpaparser.HandshakeInfo) {
+	if entry, found := scanned[capture.FilePath]; found {
+		entry.handshakes[capture.BSSID] = true
+	}
+}
+
+// recordUploads replaces what is known about the captures scanned, about every capture when the scan was complete
+func recordUploads(scanned map[string]*uploadedCapture, complete bool) {
+	uploadsMutex.Lock()
+	defer uploadsMutex.Unlock()
+
+	if complete {
+		uploads = scanned
+		return
+	}
+	maps.Copy(uploads, scanned)
+}
+
+/*
+SyncDirectives
+
+Applies the directives queued from the FE and acknowledges each one with its outcome.
+The server delivers a directive until it is acknowledged, so applying it twice must have the same effect of applying it once.
+watcher is nil when captures are read from bettercap
+*/
+func SyncDirectives(instance *RaspberryPiInfo, machineID string, env Environment, watcher *Watcher) error {
+	directives, err := fetchDirectives(instance, machineID)
+	if err != nil {
+		return err
+	}
+
+	for _, directive := range directives {
+		result, errApply := applyDirective(instance, env, watcher, directive)
+		if errApply != nil {
+			log.Warnf("[RSP-PI] Directive %s failed: %s", directive.Kind, errApply.Error())
+			result = errApply.Error()
+		} else {
+			log.Infof("[RSP-PI] Directive %s applied: %s", directive.Kind, firstLine(result))
+		}
+
+		// the token is the new one when the directive re-authenticated the device
+		err = acknowledge(&entities.TCPDirectiveAckRequest{
+			Jwt:           *instance.JWT,
+			MachineID:     machineID,
+			DirectiveUUID: directive.UUID,
+			Applied:       errApply == nil,
+			Result:        result,
+		})
+
+		var serverErr *ServerError
+		switch {
+		case errors.As(err, &serverErr):
+			// e.g. the directive has been cancelled meanwhile
+			log.Warnf("[RSP-PI] Directive %s not acknowledged: %s", directive.Kind, serverErr.Reason)
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// firstLine the logs uploaded would flood the logs
+func firstLine(result string) string {
+	line, _, _ := strings.Cut(result, "\n")
+	return line
+}
+
+func fetchDirectives(instance *RaspberryPiInfo, machineID string) ([]*entities.TCPDirective, error) {
+	client, err := InitClientConnection()
+	if useHTTPS(err) {
+		return httpsDirectives(*instance.JWT)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.Conn.Close()
+
+	response, err := client.request(enums.DIRECTIVES, &entities.TCPDirectivesRequest{
+		Jwt:       *instance.JWT,
+		MachineID: machineID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var directives entities.TCPDirectivesResponse
+	if err = json.Unmarshal(response, &directives); err != nil {
+		return nil, err
+	}
+
+	return directives.Directives, nil
+}
+
+func acknowledge(ack *entities.TCPDirectiveAckRequest) error {
+	client, err := InitClientConnection()
+	if useHTTPS(err) {
+		return httpsDirectiveAck(ack)
+	}
+	if err != nil {
+		return err
+	}
+
+	defer client.Conn.Close()
+
+	_, err = client.request(enums.DIRECTIVEACK, ack)
+	return err
+}
+
+// applyDirective returns what the directive did
+func applyDirective(instance *RaspberryPiInfo, env Environment, watcher *Watcher, directive *entities.TCPDirective) (string, error) {
+	parameters := directive.Parameters
+	if parameters == nil {
+		parameters = &entities.DirectiveParameters{}
+	}
+
+	switch directive.Kind {
+	case constants.DirectiveSchedule:
+		return applySchedule(parameters)
+	case constants.DirectiveAddWatch, constants.DirectiveRemoveWatch:
+		return applyWatch(env, watcher, directive.Kind, parameters)
+	case constants.DirectivePurge:
+		return purge(env)
+	case constants.DirectiveReauthenticate:
+		return "logged in and exchanged a new key", instance.Reauthenticate()
+	case constants.DirectiveUploadLogs:
+		return recordedLogs.String(), nil
+	default:
+		return "", fmt.Errorf("unknown directive '%s', the daemon may be older than the server", directive.Kind)
+	}
+}
+
+// changedSettings returns the remote settings changed by change and the configuration with them, validated.
+// Nothing is stored until the caller saves them
+func changedSettings(change func(remote *config.Remote)) (*config.Remote, *config.Config, error) {
+	remote, err := settings.LoadRemote()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	change(remote)
+
+	changed, err := settings.WithRemote(remote)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return remote, changed, nil
+}
+
+// applySchedule replaces the upload windows, any time when there are none, and the interval when it is given
+func applySchedule(parameters *entities.DirectiveParameters) (string, error) {
+	schedule := &config.Schedule{Interval: settings.Upload.Interval, Windows: parameters.Windows}
+	if parameters.Interval != "" {
+		interval, err := time.ParseDuration(parameters.Interval)
+		if err != nil {
+			return "", err
+		}
+		schedule.Interval = interval
+	}
+
+	remote, changed, err := changedSettings(func(remote *config.Remote) {
+		remote.Schedule = schedule
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err = settings.SaveRemote(remote); err != nil {
+		return "", err
+	}
+
+	// the upload loop resets its ticker after syncing the directives
+	settings.Upload.Interval, settings.Upload.Windows = changed.Upload.Interval, changed.Upload.Windows
+	return fmt.Sprintf("rescanning every %s, uploading in windows %v", schedule.Interval, schedule.Windows), nil
+}
+
+// directivePath expands the home directory of the paths sent by the server, which are absolute otherwise
+func directivePath(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path '%s' must be absolute", path)
+	}
+
+	return filepath.Clean(path), nil
+}
+
+// applyWatch adds a watch directory, replacing the one with the same path, or removes it
+func applyWatch(env Environment, watcher *Watcher, kind string, parameters *entities.DirectiveParameters) (string, error) {
+	environment, ok := env.(*WatchEnvironment)
+	if !ok || watcher == nil {
+		return "", errNoWatchDirectories
+	}
+
+	path, err := directivePath(parameters.Path)
+	if err != nil {
+		return "", err
+	}
+
+	watch := slices.DeleteFunc(slices.Clone(settings.Watch), func(directory config.WatchDirectory) bool {
+		return directory.Path == path
+	})
+
+	result := fmt.Sprintf("'%s' is not watched anymore", path)
+	switch {
+	case kind == constants.DirectiveAddWatch:
+		watch = append(watch, config.WatchDirectory{
+			Path:      path,
+			Recursive: parameters.Recursive,
+			Include:   parameters.Include,
+			Exclude:   parameters.Exclude,
+		})
+		result = fmt.Sprintf("watching '%s' %v (recursive: %t)", path, parameters.Include, parameters.Recursive)
+	case len(watch) == len(settings.Watch):
+		return fmt.Sprintf("'%s' was not watched", path), nil
+	}
+
+	remote, changed, err := changedSettings(func(remote *config.Remote) {
+		remote.Watch = watch
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if kind == constants.DirectiveAddWatch {
+		if err = os.MkdirAll(path, 0o750); err != nil {
+			return "", err
+		}
+	}
+
+	if err = watcher.SetDirectories(changed.Watch); err != nil {
+		return "", err
+	}
+
+	if err = settings.SaveRemote(remote); err != nil {
+		return "", err
+	}
+
+	settings.Watch, environment.Directories = changed.Watch, changed.Watch
+	return result, nil
+}
+
+// purge removes the captures of the watch directories whose handshakes are all on the server,
+// unless they changed after being uploaded
+func purge(env Environment) (string, error) {
+	environment, ok := env.(*WatchEnvironment)
+	if !ok {
+		return "", errNoWatchDirectories
+	}
+
+	uploadsMutex.Lock()
+	defer uploadsMutex.Unlock()
+
+	var failures []error
+	removed := 0
+	for path, capture := range uploads {
+		if slices.Contains(slices.Collect(maps.Values(capture.handshakes)), false) || directoryOf(environment.Directories, path) == nil {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(capture.modified) {
+			continue
+		}
+
+		if err = os.Remove(path); err != nil {
+			failures = append(failures, err)
+			continue
+		}
+
+		delete(uploads, path)
+		removed++
+	}
+
+	return fmt.Sprintf("%d captures removed", removed), errors.Join(failures...)
+}
@@ -1,79 +1,117 @@
 package daemon
 
 import (
-	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
-	"github.com/Virgula0/progetto-dp/raspberrypi/internal/utils"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
 	"github.com/google/gopacket/pcap"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Environment interface {
 	LoadEnvironment() (map[string]*pcap.Handle, error)
+	LoadCaptures(paths []string) map[string]*pcap.Handle
+	// Describe returns what the environment knows about the access point besides the captures, if anything
+	Describe(bssid string) string
 	Close()
 }
 
-type Env struct {
-	HandshakeDirectory string
-	files              []*pcap.Handle
-}
-
-type ProdEnvironment struct {
-	HandshakeDirectory string
-	files              []*pcap.Handle
+// WatchEnvironment reads the captures of the configured watch directories
+type WatchEnvironment struct {
+	Directories []config.WatchDirectory
+	files       []*pcap.Handle
 }
 
 /*
 ChooseEnvironment
 
-Handy function for returning a different environment based on the presence of Bettercap environment variables
-Because if it's a test, we will send test.pcap within hs directory, otherwise we get real handshakes from ~/handshakes
+Returns the environment of the configured watch directories, creating the ones missing
+so that they can be watched before bettercap writes the first capture
 */
-func ChooseEnvironment() (Environment, error) {
-	userDir, _ := os.UserHomeDir()
-	pwd, _ := os.Getwd()
-
-	switch constants.Bettercap {
-	case true:
-		dd := filepath.Join(userDir, "handshakes")
-		if exists, _ := utils.DirExists(dd); !exists {
-			err := os.MkdirAll(dd, os.ModePerm)
-			if err != nil {
-				return nil, err
-			}
+func ChooseEnvironment(directories []config.WatchDirectory) (Environment, error) {
+	for _, directory := range directories {
+		if err := os.MkdirAll(directory.Path, 0o750); err != nil {
+			return nil, err
 		}
-		return &ProdEnvironment{
-			HandshakeDirectory: filepath.Join(userDir, "hs"),
-		}, nil
-	default:
-		return &Env{
-			HandshakeDirectory: filepath.Join(pwd, "handshakes"),
-		}, nil
 	}
+
+	return &WatchEnvironment{
+		Directories: directories,
+	}, nil
+}
+
+// directoryOf returns the watch directory the capture belongs to, nil when the file is not a capture
+func directoryOf(directories []config.WatchDirectory, path string) *config.WatchDirectory {
+	for i := range directories {
+		directory := &directories[i]
+
+		relative, err := filepath.Rel(directory.Path, path)
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		// files in subdirectories belong to recursive directories only
+		if !directory.Recursive && filepath.Dir(relative) != "." {
+			continue
+		}
+
+		if directory.Matches(path) {
+			return directory
+		}
+	}
+
+	return nil
 }
 
 /*
-getPaths
+capturePaths
 
-reads pcap using gopacket/pcap
+returns the captures of a watch directory, subdirectories are visited only when it is recursive
 */
-func getPaths(root, extension string) (map[string]*pcap.Handle, error) {
-	files, err := utils.ReadFileNamesByExtension(root, extension)
+func capturePaths(directory *config.WatchDirectory) ([]string, error) {
+	var files []string
 
-	if err != nil {
-		return nil, err
-	}
+	err := filepath.WalkDir(directory.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != directory.Path && !directory.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if directory.Matches(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+/*
+openCaptures
 
+reads pcap using gopacket/pcap. Files which can't be read, i.e. still being written, are skipped:
+they are read again when they change or on the next full scan
+*/
+func openCaptures(paths []string) map[string]*pcap.Handle {
 	var pcaps = make(map[string]*pcap.Handle)
-	for _, file := range files {
+	for _, file := range paths {
 		handle, err := pcap.OpenOffline(file)
 		if err != nil {
-			return nil, err
+			log.Warnf("[RSP-PI] Skipping '%s': %s", file, err.Error())
+			continue
 		}
 		pcaps[file] = handle
 	}
 
-	return pcaps, nil
+	return pcaps
 }
 
 func closeFiles(d []*pcap.Handle) {
@@ -82,18 +120,37 @@ func closeFiles(d []*pcap.Handle) {
 	}
 }
 
-func (d *Env) LoadEnvironment() (map[string]*pcap.Handle, error) {
-	return getPaths(d.HandshakeDirectory, constants.PCAPExtension)
+// LoadEnvironment opens every capture of the watch directories
+func (d *WatchEnvironment) LoadEnvironment() (map[string]*pcap.Handle, error) {
+	var paths []string
+	for i := range d.Directories {
+		files, err := capturePaths(&d.Directories[i])
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+
+	return openCaptures(paths), nil
 }
 
-func (d *ProdEnvironment) LoadEnvironment() (map[string]*pcap.Handle, error) {
-	return getPaths(d.HandshakeDirectory, constants.PCAPExtension)
+// LoadCaptures opens the given files, the ones which are not captures of the watch directories are ignored
+func (d *WatchEnvironment) LoadCaptures(paths []string) map[string]*pcap.Handle {
+	var captures []string
+	for _, path := range paths {
+		if directoryOf(d.Directories, path) != nil {
+			captures = append(captures, path)
+		}
+	}
+
+	return openCaptures(captures)
 }
 
-func (d *Env) Close() {
-	closeFiles(d.files)
+// Describe the watch directories hold nothing but the captures
+func (d *WatchEnvironment) Describe(string) string {
+	return ""
 }
 
-func (d *ProdEnvironment) Close() {
+func (d *WatchEnvironment) Close() {
 	closeFiles(d.files)
 }
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+Frames exchanged with the server
+
+	| magic (4) | version (1) | type (1) | request ID (4) | length (4) | payload (length) |
+
+Integers are big endian. The server answers each request with a frame carrying the same request ID
+*/
+
+const (
+	frameVersion    byte   = 1
+	frameHeaderSize        = 14
+	maxFrameSize    uint32 = 64 << 20
+)
+
+var frameMagic = [4]byte{'H', 'D', 'S', 'F'}
+
+var errFrameMagic = errors.New("invalid frame magic")
+var errFrameTooLarge = errors.New("frame exceeds the maximum allowed size")
+
+type frame struct {
+	Version   byte
+	Type      byte
+	RequestID uint32
+	Payload   []byte
+}
+
+func readFrame(r io.Reader) (*frame, error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if [4]byte(header[:4]) != frameMagic {
+		return nil, errFrameMagic
+	}
+
+	f := &frame{
+		Version:   header[4],
+		Type:      header[5],
+		RequestID: binary.BigEndian.Uint32(header[6:10]),
+	}
+
+	if f.Version != frameVersion {
+		return nil, fmt.Errorf("unsupported frame version %d", f.Version)
+	}
+
+	length := binary.BigEndian.Uint32(header[10:14])
+	if length > maxFrameSize {
+		return nil, errFrameTooLarge
+	}
+
+	f.Payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.Payload); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func writeFrame(w io.Writer, f *frame) error {
+	if uint64(len(f.Payload)) > uint64(maxFrameSize) {
+		return errFrameTooLarge
+	}
+
+	buffer := make([]byte, frameHeaderSize, frameHeaderSize+len(f.Payload))
+	copy(buffer, frameMagic[:])
+	buffer[4] = f.Version
+	buffer[5] = f.Type
+	binary.BigEndian.PutUint32(buffer[6:10], f.RequestID)
+	binary.BigEndian.PutUint32(buffer[10:14], uint32(len(f.Payload))) // #nosec G115 bounded by maxFrameSize
+
+	_, err := w.Write(append(buffer, f.Payload...))
+	return err
+}
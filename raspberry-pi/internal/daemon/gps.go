@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/gps"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/wpaparser"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var gpsRetryDelay = 10 * time.Second
+
+// track positions received from the GPS, nil when captures are not geotagged
+var track *gps.Track
+
+/*
+StartGPS
+
+Follows the GPS source of the configuration, reconnecting when it stops. It does nothing when no source is configured.
+Captures are matched with the fixes by time, so the system clock must follow the GPS one (e.g. chrony fed by gpsd)
+*/
+func StartGPS(cfg *config.GPS) error {
+	if cfg.Source == "" {
+		return nil
+	}
+
+	t, err := openTrack(cfg)
+	if err != nil {
+		return err
+	}
+
+	source := fmt.Sprintf("%s %s", cfg.Source, cfg.Address)
+	if cfg.Source == config.NMEASource {
+		source = fmt.Sprintf("%s %s", cfg.Source, cfg.Path)
+	}
+
+	t.OnFix = func(fix gps.Fix) {
+		status.SetGPS(source, fix.Time, nil)
+	}
+	track = t
+	// shown as waiting for the first fix
+	status.SetGPS(source, time.Time{}, nil)
+
+	go follow(cfg, source)
+	return nil
+}
+
+// LoadTrack loads the fixes received so far without following the GPS, for geotagging the captures exported
+func LoadTrack(cfg *config.GPS) error {
+	if cfg.Source == "" {
+		return nil
+	}
+
+	t, err := openTrack(cfg)
+	if err != nil {
+		return err
+	}
+
+	track = t
+	return nil
+}
+
+func openTrack(cfg *config.GPS) (*gps.Track, error) {
+	path, err := dataPath(cfg.TrackFile, "track.jsonl")
+	if err != nil {
+		return nil, err
+	}
+
+	return gps.NewTrack(path, cfg.Retention)
+}
+
+func follow(cfg *config.GPS, source string) {
+	for {
+		var err error
+		switch cfg.Source {
+		case config.GPSDSource:
+			err = gps.FollowGPSD(cfg.Address, track)
+		case config.NMEASource:
+			err = gps.FollowNMEA(cfg.Path, track)
+		}
+
+		if err == nil {
+			err = errors.New("source closed")
+		}
+
+		log.Warnf("[RSP-PI] GPS source '%s' stopped: %s", source, err.Error())
+		status.SetGPS(source, time.Time{}, err)
+		time.Sleep(gpsRetryDelay)
+	}
+}
+
+// positionOf returns where the handshake was captured, nil when it is not known
+func positionOf(capture *wpaparser.HandshakeInfo) *entities.Position {
+	if track == nil || capture.CapturedAt.IsZero() {
+		return nil
+	}
+
+	fix, found := track.Position(capture.CapturedAt, settings.GPS.MaxGap)
+	if !found {
+		return nil
+	}
+
+	return &entities.Position{
+		Latitude:  fix.Latitude,
+		Longitude: fix.Longitude,
+		Date:      fix.Time,
+	}
+}
@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	// maxHTTPSUploadSize captures are sent in a single request, the server refuses larger ones (32 MiB)
+	maxHTTPSUploadSize = 32 << 20
+	// httpsUploadTimeout captures are sent in a single request, slow networks need more than serverTimedOutDuration
+	httpsUploadTimeout = 5 * time.Minute
+
+	deviceLoginRoute      = "/v1/device/login"
+	deviceKeyRoute        = "/v1/device/key"
+	deviceHandshakesRoute = "/v1/device/handshakes"
+	deviceResultsRoute    = "/v1/device/results"
+	deviceDirectivesRoute = "/v1/device/directives"
+)
+
+/*
+useHTTPS
+
+Reports whether a request must be sent to the REST API of the server, because connecting to the TCP server failed with err.
+Networks such as hotels and hotspots often block ports other than the HTTPS one,
+the REST API accepts the same requests of the TCP server with the device token
+*/
+func useHTTPS(err error) bool {
+	if err == nil || settings.Server.HTTPSURL == "" {
+		return false
+	}
+
+	log.Warnf("[RSP-PI] TCP server unreachable, using %s: %s", settings.Server.HTTPSURL, err.Error())
+	return true
+}
+
+// httpsRequest sends request as JSON to the REST API route and decodes the answer in response.
+// The reasons of the errors answered by the server are returned as ServerError, as for the TCP server
+func httpsRequest(method, route, token string, request, response any, timeout time.Duration) error {
+	endpoint, err := url.Parse(settings.Server.HTTPSURL)
+	if err != nil {
+		return err
+	}
+	endpoint.Path = path.Join(endpoint.Path, route)
+
+	var body io.Reader
+	if request != nil {
+		payload, errMarshal := json.Marshal(request)
+		if errMarshal != nil {
+			return errMarshal
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	httpRequest, err := http.NewRequest(method, endpoint.String(), body)
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpResponse, err := (&http.Client{Timeout: timeout}).Do(httpRequest)
+	status.SetServer(settings.Server.HTTPSURL, err)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		var refused entities.UniformResponse
+		if errDecode := json.NewDecoder(httpResponse.Body).Decode(&refused); errDecode != nil || refused.Details == "" {
+			return fmt.Errorf("server replied %s", httpResponse.Status)
+		}
+		return &ServerError{Reason: refused.Details}
+	}
+
+	return json.NewDecoder(httpResponse.Body).Decode(response)
+}
+
+// httpsLogin as DEVICELOGIN, returns the device token
+func httpsLogin(machineID, credential string, metadata *deviceMetadata) (string, error) {
+	var response entities.UniformResponse
+	err := httpsRequest(http.MethodPost, deviceLoginRoute, "", &entities.DeviceLoginRequest{
+		MachineID:     machineID,
+		Credential:    credential,
+		Hostname:      metadata.hostname,
+		DaemonVersion: metadata.version,
+		OS:            metadata.os,
+	}, &response, serverTimedOutDuration)
+
+	return response.Details, err
+}
+
+// httpsExchangeKey as KEYEXCHANGE, returns the public key of the server
+func httpsExchangeKey(token, publicKey string) (string, error) {
+	var response entities.DeviceKeyExchangeResponse
+	err := httpsRequest(http.MethodPost, deviceKeyRoute, token, &entities.DeviceKeyExchangeRequest{
+		PublicKey: publicKey,
+	}, &response, serverTimedOutDuration)
+
+	return response.PublicKey, err
+}
+
+// httpsUpload sends the spooled capture in a single request, it can't be resumed as the chunked upload
+func httpsUpload(token string, capture *spooledCapture) (string, error) {
+	if capture.Size > maxHTTPSUploadSize {
+		// the server would refuse it after receiving the whole capture
+		return "", &ServerError{Reason: fmt.Sprintf("captures larger than %d MiB are uploaded only through the TCP server", maxHTTPSUploadSize>>20)}
+	}
+
+	payload, err := os.ReadFile(capture.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var response entities.DeviceUploadResponse
+	err = httpsRequest(http.MethodPost, deviceHandshakesRoute, token, &entities.DeviceUploadRequest{
+		SSID:     capture.SSID,
+		BSSID:    capture.BSSID,
+		Payload:  payload,
+		Position: positionOf(capture.HandshakeInfo),
+		Quality:  capture.Classification,
+	}, &response, httpsUploadTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	status.Progress(capture.FilePath, capture.BSSID, capture.Size, capture.Size)
+	return response.HandshakeUUID, nil
+}
+
+// httpsResults as RESULTS
+func httpsResults(token string) ([]*entities.TCPResult, error) {
+	var response entities.DeviceResultsResponse
+	if err := httpsRequest(http.MethodGet, deviceResultsRoute, token, nil, &response, serverTimedOutDuration); err != nil {
+		return nil, err
+	}
+
+	results := make([]*entities.TCPResult, 0, len(response.Results))
+	for _, result := range response.Results {
+		results = append(results, &entities.TCPResult{
+			HandshakeUUID:    result.HandshakeUUID,
+			SSID:             result.SSID,
+			BSSID:            result.BSSID,
+			UploadedDate:     result.UploadedDate,
+			CrackedHandshake: result.CrackedHandshake,
+		})
+	}
+
+	return results, nil
+}
+
+// httpsDirectives as DIRECTIVES
+func httpsDirectives(token string) ([]*entities.TCPDirective, error) {
+	var response entities.DeviceDirectivesResponse
+	if err := httpsRequest(http.MethodGet, deviceDirectivesRoute, token, nil, &response, serverTimedOutDuration); err != nil {
+		return nil, err
+	}
+
+	return response.Directives, nil
+}
+
+// httpsDirectiveAck as DIRECTIVEACK
+func httpsDirectiveAck(ack *entities.TCPDirectiveAckRequest) error {
+	var response entities.UniformResponse
+	return httpsRequest(http.MethodPost, deviceDirectivesRoute, ack.Jwt, &entities.DeviceDirectiveAckRequest{
+		DirectiveUUID: ack.DirectiveUUID,
+		Applied:       ack.Applied,
+		Result:        ack.Result,
+	}, &response, serverTimedOutDuration)
+}
@@ -1,27 +1,110 @@
 package daemon
 
 import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 )
 
 var serverTimedOutDuration = time.Second * 30
 
+// settings configuration in use, replaced by Configure when the daemon starts
+var settings = config.Default()
+
+// Configure sets the configuration used for connecting to the server and storing files.
+// It must be called before any connection is opened
+func Configure(c *config.Config) {
+	settings = c
+}
+
 type RaspberryPiInfo struct {
-	JWT         *string
-	FirstLogin  chan bool
-	Credentials *entities.AuthRequest
+	JWT           *string
+	EncryptionKey *string
+	FirstLogin    chan bool
+	MachineID     string
+	Credential    string // see EnrollDevice
 }
 
 type Client struct {
-	Conn net.Conn
+	Conn      net.Conn
+	reader    *bufio.Reader
+	requestID uint32
+}
+
+// enrolled certificate used by every TLS connection once Enroll succeeded
+var (
+	enrollmentMutex   sync.RWMutex
+	deviceCertificate *tls.Certificate
+	serverCA          *x509.CertPool
+)
+
+// dataPath returns the configured path, or name inside ~/.hds when it is not configured
+func dataPath(configured, name string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".hds", name), nil
+}
+
+// LogPath returns where the logs are written while the dashboard is shown
+func LogPath() (string, error) {
+	return dataPath(settings.LogFile, "daemon.log")
 }
 
+// InitClientConnection connects to the first endpoint accepting the connection
 func InitClientConnection() (*Client, error) {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%s", constants.TCPAddress, constants.TCPPort))
+	var failures []error
+	var address string
+	for _, endpoint := range settings.Server.Endpoints {
+		address = net.JoinHostPort(endpoint.Address, strconv.Itoa(endpoint.Port))
+		client, err := dial(address)
+		if err == nil {
+			status.SetServer(address, nil)
+			return client, nil
+		}
+		failures = append(failures, err)
+	}
+
+	if len(failures) == 0 {
+		return nil, errors.New("no server endpoint configured")
+	}
+
+	err := fmt.Errorf("no server endpoint is reachable: %w", errors.Join(failures...))
+	status.SetServer(address, err)
+	return nil, err
+}
+
+func dial(address string) (*Client, error) {
+	var conn net.Conn
+	var err error
+	if settings.Server.TLS {
+		var config *tls.Config
+		if config, err = clientTLSConfig(); err != nil {
+			return nil, err
+		}
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: serverTimedOutDuration}, "tcp", address, config)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, serverTimedOutDuration)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +115,58 @@ func InitClientConnection() (*Client, error) {
 	}
 
 	return &Client{
-		Conn: conn,
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
 	}, nil
 }
+
+// clientTLSConfig after the enrollment, the server is verified against the CA received with the device certificate.
+// Before it, the server is verified only if TCP_CA_CERT is set, otherwise any certificate is accepted,
+// in the same way gRPC clients connect the first time
+func clientTLSConfig() (*tls.Config, error) {
+	enrollmentMutex.RLock()
+	defer enrollmentMutex.RUnlock()
+
+	config := &tls.Config{
+		ServerName: constants.CertServerName,
+		MinVersion: tls.VersionTLS13,
+	}
+
+	switch {
+	case deviceCertificate != nil:
+		config.Certificates = []tls.Certificate{*deviceCertificate}
+		config.RootCAs = serverCA
+	case settings.Server.CACert != "":
+		caCert, err := os.ReadFile(settings.Server.CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("failed to append CA certificate to pool")
+		}
+	default:
+		config.InsecureSkipVerify = true // #nosec G402 bootstrap connection, used for logging in and enrolling only
+	}
+
+	return config, nil
+}
+
+// setEnrollment stores the certificates received from the server
+func setEnrollment(response *entities.TCPCertificateResponse) error {
+	certificate, err := tls.X509KeyPair(response.ClientCert, response.ClientKey)
+	if err != nil {
+		return err
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(response.CACert) {
+		return errors.New("failed to append CA certificate to pool")
+	}
+
+	enrollmentMutex.Lock()
+	defer enrollmentMutex.Unlock()
+	deviceCertificate = &certificate
+	serverCA = certPool
+	return nil
+}
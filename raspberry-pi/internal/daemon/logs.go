@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordedLogLines how many lines are sent when the server asks for the logs
+const recordedLogLines = 1000
+
+// LogRecorder keeps the last lines logged at any level, the ones written in the log file or on the terminal
+type LogRecorder struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+var recordedLogs = &LogRecorder{}
+
+// RecordLogs starts recording the logs for the upload_logs directive
+func RecordLogs() {
+	log.AddHook(recordedLogs)
+}
+
+func (l *LogRecorder) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (l *LogRecorder) Fire(entry *log.Entry) error {
+	line := fmt.Sprintf("%s [%s] %s", entry.Time.Format(time.RFC3339), strings.ToUpper(entry.Level.String()), entry.Message)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.lines = append(l.lines, line)
+	if len(l.lines) > recordedLogLines {
+		l.lines = l.lines[len(l.lines)-recordedLogLines:]
+	}
+	return nil
+}
+
+func (l *LogRecorder) String() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return strings.Join(l.lines, "\n")
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"bufio"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
+	"os"
+	"runtime"
+	"strings"
+)
+
+const osReleaseFile = "/etc/os-release"
+
+// deviceMetadata what the daemon tells the server about the device at each login
+type deviceMetadata struct {
+	hostname string
+	version  string
+	os       string
+}
+
+// the server refuses longer values
+const (
+	maxHostnameLength = 255
+	maxOSLength       = 100
+)
+
+func currentMetadata() *deviceMetadata {
+	hostname, _ := os.Hostname()
+
+	return &deviceMetadata{
+		hostname: truncate(hostname, maxHostnameLength),
+		version:  constants.DaemonVersion,
+		os:       truncate(strings.TrimSpace(distribution()+" "+runtime.GOOS+"/"+runtime.GOARCH), maxOSLength),
+	}
+}
+
+// distribution the PRETTY_NAME of os-release, empty when it can't be read
+func distribution() string {
+	file, err := os.Open(osReleaseFile)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); found {
+			return strings.Trim(value, `"'`)
+		}
+	}
+
+	return ""
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return strings.ToValidUTF8(value[:length], "")
+}
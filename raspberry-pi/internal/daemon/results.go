@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/enums"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+)
+
+// ResultsPath returns where the networks cracked are written
+func ResultsPath() (string, error) {
+	return dataPath(settings.ResultsFile, "results.json")
+}
+
+// LoadResults reads the results file, it is empty until the first results are fetched
+func LoadResults() ([]*entities.TCPResult, error) {
+	path, err := ResultsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*entities.TCPResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*entities.TCPResult
+	if err = json.Unmarshal(content, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// saveResults replaces the results file, it holds Wi-Fi passwords so only the owner can read it
+func saveResults(results []*entities.TCPResult) error {
+	path, err := ResultsPath()
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+	if err = os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
+
+// FetchResults asks the server for the networks cracked among the captures uploaded by the device
+func FetchResults(instance *RaspberryPiInfo, machineID string) ([]*entities.TCPResult, error) {
+	client, err := InitClientConnection()
+	if useHTTPS(err) {
+		return httpsResults(*instance.JWT)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.Conn.Close()
+
+	response, err := client.request(enums.RESULTS, &entities.TCPResultsRequest{
+		Jwt:       *instance.JWT,
+		MachineID: machineID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results entities.TCPResultsResponse
+	if err = json.Unmarshal(response, &results); err != nil {
+		return nil, err
+	}
+
+	return results.Results, nil
+}
+
+// SyncResults fetches the results and writes them in the results file, reporting the networks cracked since the last time
+func SyncResults(instance *RaspberryPiInfo, machineID string) error {
+	results, err := FetchResults(instance, machineID)
+	if err != nil {
+		return err
+	}
+
+	previous, err := LoadResults()
+	if err != nil {
+		log.Warnf("[RSP-PI] Unable to read the previous results: %s", err.Error())
+	}
+
+	known := make(map[string]string, len(previous))
+	for _, result := range previous {
+		known[result.HandshakeUUID] = result.CrackedHandshake
+	}
+
+	for _, result := range results {
+		if cracked, found := known[result.HandshakeUUID]; !found || cracked != result.CrackedHandshake {
+			log.Infof("[RSP-PI] Network '%s' (%s) has been cracked", result.SSID, result.BSSID)
+		}
+	}
+
+	status.SetResults(results)
+	return saveResults(results)
+}
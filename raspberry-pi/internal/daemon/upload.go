@@ -0,0 +1,308 @@
+package daemon
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/enums"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/utils"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/wpaparser"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// chunkSize must not exceed the maximum chunk size accepted by the server (1 MiB)
+	chunkSize = 512 << 10
+	// uploadAttempts how many times an upload is resumed after a connection failure,
+	// and how many chunks in a row the server can acknowledge without the upload moving forward
+	uploadAttempts = 3
+	spoolExtension = ".blob"
+)
+
+var uploadRetryDelay = 5 * time.Second
+
+var errUploadStalled = errors.New("the server does not acknowledge the chunks sent")
+
+// spooledCapture a capture compressed and encrypted, ready to be uploaded.
+// The encryption uses a random nonce, so the same file must be sent on every attempt for the upload to be resumed
+type spooledCapture struct {
+	*wpaparser.HandshakeInfo
+	UploadID string
+	Path     string
+	Size     int64
+	Checksum string
+}
+
+// UploadCaptures uploads the captures one at a time. A capture failing is reported and skipped,
+// it is sent again on the next scan. complete tells whether captures are all the ones on disk,
+// only in that case the spool is pruned
+func UploadCaptures(instance *RaspberryPiInfo, machineID string, captures []*wpaparser.HandshakeInfo, complete bool) error {
+	dir, err := spoolDir()
+	if err != nil {
+		return err
+	}
+
+	queue := make([]*status.Upload, 0, len(captures))
+	for _, capture := range captures {
+		queue = append(queue, &status.Upload{FilePath: capture.FilePath, SSID: capture.SSID, BSSID: capture.BSSID})
+	}
+	status.Enqueue(queue)
+	// captures left in the queue by a connection failure are uploaded on the next scan
+	defer status.ClearQueue()
+
+	scanned := scannedCaptures(captures)
+	defer recordUploads(scanned, complete)
+
+	pending := make(map[string]bool)
+	for _, capture := range captures {
+		outcome := &status.Outcome{FilePath: capture.FilePath, SSID: capture.SSID, BSSID: capture.BSSID}
+
+		spooled, errSpool := spool(dir, *instance.EncryptionKey, machineID, capture)
+		if errSpool != nil {
+			log.Warnf("[RSP-PI] Unable to prepare '%s': %s", capture.FilePath, errSpool.Error())
+			outcome.Error = errSpool.Error()
+			status.Done(outcome)
+			continue
+		}
+		pending[spooled.UploadID] = true
+
+		handshakeID, errUpload := uploadWithRetry(instance, machineID, spooled)
+
+		var serverErr *ServerError
+		switch {
+		case errors.As(errUpload, &serverErr) && serverErr.Reason == constants.HandshakeAlreadyPresent:
+			log.Println("[RSP-PI] Capture already uploaded:", capture.FilePath)
+			outcome.Result = "already uploaded"
+			markUploaded(scanned, capture)
+		case errors.As(errUpload, &serverErr):
+			log.Warnf("[RSP-PI] Capture '%s' refused by the server: %s", capture.FilePath, serverErr.Reason)
+			outcome.Error = serverErr.Reason
+		case errUpload != nil:
+			outcome.Error = errUpload.Error()
+			status.Done(outcome)
+			return fmt.Errorf("[RSP-PI] Failed to upload '%s': %s", capture.FilePath, errUpload.Error())
+		default:
+			log.Println("[RSP-PI] Capture uploaded as handshake", handshakeID)
+			outcome.Result = handshakeID
+			markUploaded(scanned, capture)
+		}
+		status.Done(outcome)
+
+		// only connection failures keep the capture in the spool for being resumed
+		delete(pending, spooled.UploadID)
+		removeSpooled(spooled.Path)
+	}
+
+	if complete {
+		pruneSpool(dir, pending)
+	}
+	return nil
+}
+
+func spoolDir() (string, error) {
+	dir, err := dataPath(settings.SpoolDir, "spool")
+	if err != nil {
+		return "", err
+	}
+
+	return dir, os.MkdirAll(dir, 0o700)
+}
+
+// spool compresses and encrypts the capture, unless it is already in the spool from a previous attempt.
+// The upload ID depends on the key, so captures spooled before a new key exchange are never resumed
+func spool(dir, key, machineID string, capture *wpaparser.HandshakeInfo) (*spooledCapture, error) {
+	content, err := utils.ReadFileBytes(capture.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	contentSum := sha256.Sum256(content)
+	keySum := sha256.Sum256([]byte(key))
+	id := sha256.Sum256([]byte(strings.Join([]string{
+		hex.EncodeToString(keySum[:]), machineID, capture.BSSID, capture.SSID, hex.EncodeToString(contentSum[:]),
+	}, "|")))
+
+	spooled := &spooledCapture{
+		HandshakeInfo: capture,
+		UploadID:      hex.EncodeToString(id[:]),
+	}
+	spooled.Path = filepath.Join(dir, spooled.UploadID+spoolExtension)
+
+	payload, err := os.ReadFile(spooled.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		payload, err = sealCapture(key, machineID, capture, content)
+		if err == nil {
+			err = os.WriteFile(spooled.Path, payload, 0o600)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(payload)
+	spooled.Size = int64(len(payload))
+	spooled.Checksum = hex.EncodeToString(sum[:])
+	return spooled, nil
+}
+
+// sealCapture compresses the capture before encrypting it, ciphertext does not compress
+func sealCapture(key, machineID string, capture *wpaparser.HandshakeInfo, content []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return utils.SealAESGCM(key, compressed.Bytes(), utils.HandshakeAdditionalData(machineID, capture.BSSID, capture.SSID))
+}
+
+// uploadWithRetry reconnects and resumes the upload when the connection drops. Errors sent by the server are not retried
+func uploadWithRetry(instance *RaspberryPiInfo, machineID string, capture *spooledCapture) (string, error) {
+	var err error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		var handshakeID string
+		handshakeID, err = upload(instance, machineID, capture)
+
+		var serverErr *ServerError
+		if err == nil || errors.As(err, &serverErr) {
+			return handshakeID, err
+		}
+
+		log.Warnf("[RSP-PI] Upload of '%s' interrupted (attempt %d/%d): %s", capture.FilePath, attempt, uploadAttempts, err.Error())
+		time.Sleep(uploadRetryDelay)
+	}
+	return "", err
+}
+
+// upload sends the capture starting from the offset the server already holds, the whole capture when sent via HTTPS
+func upload(instance *RaspberryPiInfo, machineID string, capture *spooledCapture) (string, error) {
+	client, err := InitClientConnection()
+	if useHTTPS(err) {
+		return httpsUpload(*instance.JWT, capture)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer client.Conn.Close()
+
+	file, err := os.Open(capture.Path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	offset, err := client.uploadRequest(enums.UPLOADBEGIN, &entities.TCPUploadBeginRequest{
+		Jwt:       *instance.JWT,
+		MachineID: machineID,
+		UploadID:  capture.UploadID,
+		SSID:      capture.SSID,
+		BSSID:     capture.BSSID,
+		Size:      capture.Size,
+		Checksum:  capture.Checksum,
+		Position:  positionOf(capture.HandshakeInfo),
+		Quality:   capture.Classification,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if offset > 0 {
+		log.Printf("[RSP-PI] Resuming upload of '%s' from %d/%d", capture.FilePath, offset, capture.Size)
+	}
+
+	buffer := make([]byte, chunkSize)
+	stalled := 0
+	for offset < capture.Size {
+		if offset < 0 || stalled == uploadAttempts {
+			return "", errUploadStalled
+		}
+
+		read, errRead := file.ReadAt(buffer, offset)
+		if errRead != nil && !errors.Is(errRead, io.EOF) {
+			return "", errRead
+		}
+
+		// the server answers with the offset it holds, which is where the next chunk starts
+		sent := offset
+		offset, err = client.uploadRequest(enums.UPLOADCHUNK, &entities.TCPUploadChunkRequest{
+			Jwt:       *instance.JWT,
+			MachineID: machineID,
+			UploadID:  capture.UploadID,
+			Offset:    sent,
+			Data:      buffer[:read],
+		})
+		if err != nil {
+			return "", err
+		}
+
+		// a resync moves the offset back once, a server answering so every time would be asked for chunks forever
+		if offset > sent {
+			stalled = 0
+		} else {
+			stalled++
+		}
+		status.Progress(capture.FilePath, capture.BSSID, offset, capture.Size)
+	}
+
+	response, err := client.request(enums.UPLOADCOMMIT, &entities.TCPUploadCommitRequest{
+		Jwt:       *instance.JWT,
+		MachineID: machineID,
+		UploadID:  capture.UploadID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(response), nil
+}
+
+func (c *Client) uploadRequest(command enums.Command, request any) (int64, error) {
+	response, err := c.request(command, request)
+	if err != nil {
+		return 0, err
+	}
+
+	var status entities.TCPUploadResponse
+	if err = json.Unmarshal(response, &status); err != nil {
+		return 0, err
+	}
+
+	return status.Offset, nil
+}
+
+func removeSpooled(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("[RSP-PI] Unable to remove '%s' from the spool: %s", path, err.Error())
+	}
+}
+
+// pruneSpool removes the captures which are not waiting to be resumed anymore, i.e. deleted or sealed with an old key
+func pruneSpool(dir string, pending map[string]bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Warnf("[RSP-PI] Unable to read the spool: %s", err.Error())
+		return
+	}
+
+	for _, entry := range entries {
+		if name := entry.Name(); filepath.Ext(name) == spoolExtension && !pending[strings.TrimSuffix(name, spoolExtension)] {
+			removeSpooled(filepath.Join(dir, name))
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const minimumSettleCheck = 100 * time.Millisecond
+
+// Watcher notifies the captures written in the watch directories. Bettercap appends to captures
+// while it sniffs, so a capture is notified only once it has not changed for the settle period
+type Watcher struct {
+	Captures chan string
+
+	watcher *fsnotify.Watcher
+	settle  time.Duration
+
+	// directories are changed by the directives of the server while Run reads them
+	mutex       sync.Mutex
+	directories []config.WatchDirectory
+}
+
+// NewWatcher starts watching the directories, call Run for receiving the captures
+func NewWatcher(directories []config.WatchDirectory, settle time.Duration) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		Captures:    make(chan string, 64),
+		watcher:     watcher,
+		directories: directories,
+		settle:      settle,
+	}
+
+	for _, directory := range directories {
+		if _, err = w.addTree(directory.Path, directory.Recursive); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// addTree watches root and, when recursive, its subdirectories. It returns the files found in them
+func (w *Watcher) addTree(root string, recursive bool) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			files = append(files, path)
+			return nil
+		}
+
+		if path != root && !recursive {
+			return filepath.SkipDir
+		}
+
+		return w.watcher.Add(path)
+	})
+
+	return files, err
+}
+
+// below reports whether path is root or inside it
+func below(root, path string) bool {
+	relative, err := filepath.Rel(root, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// covered reports whether path is one of the directories or below a recursive one
+func covered(directories []config.WatchDirectory, path string) bool {
+	for _, directory := range directories {
+		if path == directory.Path || directory.Recursive && below(directory.Path, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetDirectories replaces the watch directories, the directories which are not part of them anymore are not watched.
+// Captures already in the new directories are found by the next full scan
+func (w *Watcher) SetDirectories(directories []config.WatchDirectory) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, path := range w.watcher.WatchList() {
+		if !covered(directories, path) {
+			if err := w.watcher.Remove(path); err != nil {
+				log.Warnf("[RSP-PI] Unable to stop watching '%s': %s", path, err.Error())
+			}
+		}
+	}
+
+	for _, directory := range directories {
+		if _, err := w.addTree(directory.Path, directory.Recursive); err != nil {
+			return err
+		}
+	}
+
+	w.directories = directories
+	return nil
+}
+
+// inRecursiveDirectory reports whether path is below a recursive watch directory
+func (w *Watcher) inRecursiveDirectory(path string) bool {
+	for _, directory := range w.directories {
+		if directory.Recursive && below(directory.Path, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Run delivers the captures on the Captures channel until Close is called
+func (w *Watcher) Run() {
+	pending := make(map[string]time.Time)
+
+	ticker := time.NewTicker(max(w.settle/2, minimumSettleCheck))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(event, pending)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warnf("[RSP-PI] Watcher error: %s", err.Error())
+		case now := <-ticker.C:
+			for path, changed := range pending {
+				if now.Sub(changed) >= w.settle {
+					delete(pending, path)
+					w.Captures <- path
+				}
+			}
+		}
+	}
+}
+
+// handle records the captures changed by the event, the settle period restarts on each change
+func (w *Watcher) handle(event fsnotify.Event, pending map[string]time.Time) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// a capture renamed is notified again with its new name
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		delete(pending, event.Name)
+		return
+	}
+
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return
+	}
+
+	changed := []string{event.Name}
+
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		if !event.Has(fsnotify.Create) || !w.inRecursiveDirectory(event.Name) {
+			return
+		}
+
+		// captures can be written in the new directory before it is watched
+		if changed, err = w.addTree(event.Name, true); err != nil {
+			log.Warnf("[RSP-PI] Unable to watch '%s': %s", event.Name, err.Error())
+		}
+	}
+
+	now := time.Now()
+	for _, path := range changed {
+		if directoryOf(w.directories, path) != nil {
+			pending[path] = now
+		}
+	}
+}
+
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
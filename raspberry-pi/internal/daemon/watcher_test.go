@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+const testSettle = 300 * time.Millisecond
+
+// startWatcher runs a watcher on directories until the test ends
+func startWatcher(t *testing.T, directories []config.WatchDirectory) *Watcher {
+	w, err := NewWatcher(directories, testSettle)
+	require.NoError(t, err)
+
+	go w.Run()
+	t.Cleanup(func() { _ = w.Close() })
+
+	return w
+}
+
+// notified returns the captures delivered by the watcher within wait
+func notified(w *Watcher, wait time.Duration) []string {
+	var captures []string
+
+	timeout := time.After(wait)
+	for {
+		select {
+		case capture := <-w.Captures:
+			captures = append(captures, capture)
+		case <-timeout:
+			slices.Sort(captures)
+			return captures
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestWatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		recursive bool
+		write     func(t *testing.T, root string)
+		captures  []string // relative to root
+	}{
+		{
+			name:     "capture",
+			write:    func(t *testing.T, root string) { writeFile(t, filepath.Join(root, "home.pcap"), "capture") },
+			captures: []string{"home.pcap"},
+		},
+		{
+			name:  "not a capture",
+			write: func(t *testing.T, root string) { writeFile(t, filepath.Join(root, "notes.txt"), "notes") },
+		},
+		{
+			name: "excluded capture",
+			write: func(t *testing.T, root string) {
+				writeFile(t, filepath.Join(root, "tmp-home.pcap"), "capture")
+			},
+		},
+		{
+			name: "capture renamed",
+			write: func(t *testing.T, root string) {
+				writeFile(t, filepath.Join(root, "home.pcap"), "capture")
+				require.NoError(t, os.Rename(filepath.Join(root, "home.pcap"), filepath.Join(root, "office.pcap")))
+			},
+			captures: []string{"office.pcap"},
+		},
+		{
+			name: "capture removed before settling",
+			write: func(t *testing.T, root string) {
+				writeFile(t, filepath.Join(root, "home.pcap"), "capture")
+				require.NoError(t, os.Remove(filepath.Join(root, "home.pcap")))
+			},
+		},
+		{
+			name:      "new subdirectory of a recursive directory",
+			recursive: true,
+			write: func(t *testing.T, root string) {
+				writeFile(t, filepath.Join(root, "2024", "01", "home.pcap"), "capture")
+			},
+			captures: []string{filepath.Join("2024", "01", "home.pcap")},
+		},
+		{
+			name: "new subdirectory of a directory",
+			write: func(t *testing.T, root string) {
+				writeFile(t, filepath.Join(root, "2024", "home.pcap"), "capture")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			w := startWatcher(t, []config.WatchDirectory{{
+				Path:      root,
+				Recursive: test.recursive,
+				Include:   []string{"*.pcap"},
+				Exclude:   []string{"tmp-*"},
+			}})
+
+			test.write(t, root)
+
+			var expected []string
+			for _, capture := range test.captures {
+				expected = append(expected, filepath.Join(root, capture))
+			}
+			require.Equal(t, expected, notified(w, 4*testSettle))
+		})
+	}
+}
+
+// TestWatcherSettle checks that a capture still being written is notified once, after the last write
+func TestWatcherSettle(t *testing.T) {
+	root := t.TempDir()
+	w := startWatcher(t, []config.WatchDirectory{{Path: root, Include: []string{"*.pcap"}}})
+
+	capture := filepath.Join(root, "home.pcap")
+	file, err := os.Create(capture)
+	require.NoError(t, err)
+	defer file.Close()
+
+	written := time.Now()
+	for range 5 {
+		_, err = file.WriteString("packet")
+		require.NoError(t, err)
+		written = time.Now()
+		require.Empty(t, notified(w, testSettle/2), "notified while being written")
+	}
+
+	require.Equal(t, []string{capture}, notified(w, 4*testSettle))
+	require.GreaterOrEqual(t, time.Since(written), testSettle)
+}
+
+func TestWatcherSetDirectories(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	w := startWatcher(t, []config.WatchDirectory{{Path: first, Include: []string{"*.pcap"}}})
+
+	require.NoError(t, w.SetDirectories([]config.WatchDirectory{{Path: second, Include: []string{"*.pcap"}}}))
+
+	writeFile(t, filepath.Join(first, "home.pcap"), "capture")
+	writeFile(t, filepath.Join(second, "office.pcap"), "capture")
+
+	require.Equal(t, []string{filepath.Join(second, "office.pcap")}, notified(w, 4*testSettle))
+}
+
+// TestCapturePaths checks the captures found by the full rescan
+func TestCapturePaths(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"home.pcap",
+		"tmp-home.pcap",
+		"notes.txt",
+		filepath.Join("2024", "office.pcap"),
+		filepath.Join("2024", "01", "shop.pcap"),
+	} {
+		writeFile(t, filepath.Join(root, name), "capture")
+	}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		captures  []string
+	}{
+		{
+			name:     "directory",
+			captures: []string{"home.pcap"},
+		},
+		{
+			name:      "recursive directory",
+			recursive: true,
+			captures: []string{
+				filepath.Join("2024", "01", "shop.pcap"),
+				filepath.Join("2024", "office.pcap"),
+				"home.pcap",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			directories := []config.WatchDirectory{{
+				Path:      root,
+				Recursive: test.recursive,
+				Include:   []string{"*.pcap"},
+				Exclude:   []string{"tmp-*"},
+			}}
+
+			files, err := capturePaths(&directories[0])
+			require.NoError(t, err)
+
+			var expected []string
+			for _, capture := range test.captures {
+				expected = append(expected, filepath.Join(root, capture))
+			}
+			require.Equal(t, expected, files)
+
+			// the watcher notifies the same files the rescan finds
+			for _, file := range files {
+				require.NotNil(t, directoryOf(directories, file), file)
+			}
+			require.Nil(t, directoryOf(directories, filepath.Join(root, "tmp-home.pcap")))
+			require.Equal(t, test.recursive, directoryOf(directories, filepath.Join(root, "2024", "office.pcap")) != nil)
+		})
+	}
+}
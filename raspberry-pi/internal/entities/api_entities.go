@@ -4,3 +4,62 @@ type AuthRequest struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
+
+// UniformResponse body of the REST API answers, Details is the reason of the errors
+type UniformResponse struct {
+	StatusCode int    `json:"status_code"`
+	Details    string `json:"details"`
+}
+
+// DeviceLoginRequest as TCPDeviceLoginRequest, for the REST API
+type DeviceLoginRequest struct {
+	MachineID     string `json:"machine_id"`
+	Credential    string `json:"credential"`
+	Hostname      string `json:"hostname,omitempty"`
+	DaemonVersion string `json:"daemon_version,omitempty"`
+	OS            string `json:"os,omitempty"`
+}
+
+type DeviceKeyExchangeRequest struct {
+	PublicKey string `json:"public_key"`
+}
+
+type DeviceKeyExchangeResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+// DeviceUploadRequest a whole capture, Payload is the spooled capture sent in chunks over TCP
+type DeviceUploadRequest struct {
+	SSID     string    `json:"ssid"`
+	BSSID    string    `json:"bssid"`
+	Payload  []byte    `json:"payload"`
+	Position *Position `json:"position,omitempty"`
+	Quality  string    `json:"quality,omitempty"`
+}
+
+type DeviceUploadResponse struct {
+	HandshakeUUID string `json:"handshake_uuid"`
+}
+
+type DeviceResult struct {
+	HandshakeUUID    string `json:"handshake_uuid"`
+	SSID             string `json:"ssid"`
+	BSSID            string `json:"bssid"`
+	UploadedDate     string `json:"uploaded_date"`
+	CrackedHandshake string `json:"cracked_handshake"`
+}
+
+type DeviceResultsResponse struct {
+	Results []*DeviceResult `json:"results"`
+}
+
+// DeviceDirectivesResponse the directives are the same sent with DIRECTIVES
+type DeviceDirectivesResponse struct {
+	Directives []*TCPDirective `json:"directives"`
+}
+
+type DeviceDirectiveAckRequest struct {
+	DirectiveUUID string `json:"directive_uuid"`
+	Applied       bool   `json:"applied"`
+	Result        string `json:"result"`
+}
@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// BundleManifest describes the captures of a bundle, it is signed with the device key
+type BundleManifest struct {
+	Version   int              `json:"version"`
+	MachineID string           `json:"machine_id"`
+	Created   time.Time        `json:"created"`
+	Captures  []*BundleCapture `json:"captures"`
+}
+
+// BundleCapture File is the bundle entry holding the capture, sealed as for the uploads. Checksum is its hex sha256
+type BundleCapture struct {
+	File     string    `json:"file"`
+	SSID     string    `json:"ssid"`
+	BSSID    string    `json:"bssid"`
+	Size     int64     `json:"size"`
+	Checksum string    `json:"checksum"`
+	Position *Position `json:"position,omitempty"`
+	Quality  string    `json:"quality,omitempty"`
+}
@@ -1,10 +1,28 @@
 package entities
 
-type TCPCreateRaspberryPIRequest struct {
-	Handshakes    []*Handshake
-	Jwt           string `validate:"required,jwt"`
+import "time"
+
+// TCPKeyExchangeRequest PublicKey is the base64 encoded X25519 public key of the daemon
+type TCPKeyExchangeRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+	PublicKey string `validate:"required,base64"`
+}
+
+// TCPEnrollRequest approves the device, Jwt is the token obtained logging in with the user credentials
+type TCPEnrollRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+// TCPDeviceLoginRequest Credential is the one received when the device was enrolled.
+// Hostname, DaemonVersion and OS are shown in the FE
+type TCPDeviceLoginRequest struct {
 	MachineID     string `validate:"required,len=32"`
-	EncryptionKey string `validate:"required,len=64"`
+	Credential    string `validate:"required,hexadecimal,len=64"`
+	Hostname      string `validate:"max=255"`
+	DaemonVersion string `validate:"max=50"`
+	OS            string `validate:"max=100"`
 }
 
 // Pointers in stracture is to deal with NULL data binding when parsing the rows while querying
@@ -22,3 +40,109 @@ type Handshake struct {
 	CrackedHandshake *string `db:"CRACKED_HANDSHAKE"`
 	HandshakePCAP    *string `db:"HANDSHAKE_PCAP"`
 }
+
+// TCPCertificateRequest enrolls a certificate for the device, the server accepts it only over TLS
+type TCPCertificateRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+// TCPCertificateResponse PEM encoded CA, device certificate and its key
+type TCPCertificateResponse struct {
+	CACert     []byte
+	ClientCert []byte
+	ClientKey  []byte
+}
+
+// TCPUploadBeginRequest declares a capture. Size and Checksum (hex sha256) refer to the compressed and encrypted payload,
+// Position is nil when there is no GPS fix for the capture, Quality is the wpaparser classification of the capture
+type TCPUploadBeginRequest struct {
+	Jwt       string    `validate:"required,jwt"`
+	MachineID string    `validate:"required,len=32"`
+	UploadID  string    `validate:"required,hexadecimal,len=64"`
+	SSID      string    `validate:"required"`
+	BSSID     string    `validate:"required"`
+	Size      int64     `validate:"required,gt=0"`
+	Checksum  string    `validate:"required,hexadecimal,len=64"`
+	Position  *Position `validate:"omitempty"`
+	Quality   string
+}
+
+// Position where a capture was taken, Date is when the GPS fix was taken
+type Position struct {
+	Latitude  float64
+	Longitude float64
+	Date      time.Time
+}
+
+type TCPUploadChunkRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+	UploadID  string `validate:"required,hexadecimal,len=64"`
+	Offset    int64  `validate:"gte=0"`
+	Data      []byte `validate:"required"`
+}
+
+type TCPUploadCommitRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+	UploadID  string `validate:"required,hexadecimal,len=64"`
+}
+
+// TCPUploadResponse Offset is the number of bytes held by the server, the next chunk starts there
+type TCPUploadResponse struct {
+	Offset int64
+}
+
+type TCPResultsRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+// TCPResult a network cracked among the captures uploaded by the device, CrackedHandshake is what hashcat found
+type TCPResult struct {
+	HandshakeUUID    string
+	SSID             string
+	BSSID            string
+	UploadedDate     string
+	CrackedHandshake string
+}
+
+type TCPResultsResponse struct {
+	Results []*TCPResult
+}
+
+type TCPDirectivesRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+// TCPDirective a change of the daemon asked by the user from the FE, Kind tells which of the parameters are set
+type TCPDirective struct {
+	UUID       string
+	Kind       string
+	Parameters *DirectiveParameters
+}
+
+// DirectiveParameters Interval and Windows are the upload schedule, the other fields a watch directory
+type DirectiveParameters struct {
+	Interval  string
+	Windows   []string
+	Path      string
+	Recursive bool
+	Include   []string
+	Exclude   []string
+}
+
+type TCPDirectivesResponse struct {
+	Directives []*TCPDirective
+}
+
+// TCPDirectiveAckRequest Result is what the directive did, the logs for upload_logs and the reason when it failed
+type TCPDirectiveAckRequest struct {
+	Jwt           string `validate:"required,jwt"`
+	MachineID     string `validate:"required,len=32"`
+	DirectiveUUID string `validate:"required"`
+	Applied       bool
+	Result        string
+}
@@ -1,27 +1,31 @@
 package enums
 
-type ServerStatus byte
+// ResponseType message types used by the server when answering a frame
+type ResponseType byte
 
 const (
-	ACK ServerStatus = iota + 1
-	FAIL
+	RESPONSE ResponseType = iota + 0x80
+	ERROR
 )
 
-func (s ServerStatus) String() string {
-	return [...]string{"ACK", "FAIL"}[s-1]
-}
-
-func (s ServerStatus) EnumIndex() int {
-	return int(s)
-}
-
 type Command byte
 
 const (
 	LOGIN Command = iota + 1
 	HANDSHAKE
+	KEYEXCHANGE
+	CERTIFICATE
+	UPLOADBEGIN
+	UPLOADCHUNK
+	UPLOADCOMMIT
+	ENROLL
+	DEVICELOGIN
+	RESULTS
+	DIRECTIVES
+	DIRECTIVEACK
 )
 
 func (c Command) String() string {
-	return [...]string{"LOGIN", "HANDSHAKE"}[c-1]
+	return [...]string{"LOGIN", "HANDSHAKE", "KEYEXCHANGE", "CERTIFICATE", "UPLOADBEGIN", "UPLOADCHUNK", "UPLOADCOMMIT", "ENROLL", "DEVICELOGIN", "RESULTS",
+		"DIRECTIVES", "DIRECTIVEACK"}[c-1]
 }
@@ -0,0 +1,252 @@
+package gps
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTrack opens a track in a temporary file
+func newTrack(t *testing.T) *Track {
+	track, err := NewTrack(filepath.Join(t.TempDir(), "track.jsonl"), 24*time.Hour)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = track.Close() })
+
+	return track
+}
+
+// sentence appends the checksum to the body of a NMEA sentence
+func sentence(body string) string {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X", body, sum)
+}
+
+func TestParseRMC(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		fix      *Fix
+		problem  string
+	}{
+		{
+			name:     "fix",
+			sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A",
+			fix:      &Fix{Latitude: 48.1173, Longitude: 11.516666666666667, Time: time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC)},
+		},
+		{
+			name:     "fraction of second and southern western hemispheres",
+			sentence: sentence("GNRMC,080000.50,A,3351.600,S,15112.600,W,0.0,0.0,010524,,"),
+			fix:      &Fix{Latitude: -33.86, Longitude: -151.21, Time: time.Date(2024, 5, 1, 8, 0, 0, 500*int(time.Millisecond), time.UTC)},
+		},
+		{
+			name:     "no fix",
+			sentence: sentence("GPRMC,123519,V,,,,,,,230394,,"),
+			problem:  "no fix",
+		},
+		{
+			name:     "other sentence",
+			sentence: sentence("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"),
+			problem:  "not a RMC sentence",
+		},
+		{
+			name:     "checksum mismatch",
+			sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6B",
+			problem:  "checksum mismatch",
+		},
+		{
+			name:     "missing checksum",
+			sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W",
+			problem:  "not a NMEA sentence",
+		},
+		{
+			name:     "invalid hemisphere",
+			sentence: sentence("GPRMC,123519,A,4807.038,X,01131.000,E,022.4,084.4,230394,003.1,W"),
+			problem:  "invalid hemisphere",
+		},
+		{
+			name:     "invalid coordinate",
+			sentence: sentence("GPRMC,123519,A,48,N,01131.000,E,022.4,084.4,230394,003.1,W"),
+			problem:  "invalid coordinate",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fix, err := parseRMC(test.sentence)
+			if test.problem != "" {
+				require.ErrorContains(t, err, test.problem)
+				return
+			}
+
+			require.NoError(t, err)
+			require.InDelta(t, test.fix.Latitude, fix.Latitude, 1e-9)
+			require.InDelta(t, test.fix.Longitude, fix.Longitude, 1e-9)
+			require.True(t, test.fix.Time.Equal(fix.Time), fix.Time.String())
+		})
+	}
+}
+
+func TestReadNMEA(t *testing.T) {
+	feed := strings.Join([]string{
+		sentence("GPGGA,120000,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"),
+		sentence("GPRMC,120000,A,4807.038,N,01131.000,E,0.0,0.0,010524,,"),
+		sentence("GPRMC,120001,V,,,,,,,010524,,"),
+		"garbage",
+		sentence("GPRMC,120001,A,4807.040,N,01131.002,E,0.0,0.0,010524,,") + "\r",
+		// read again by the source, it is not added twice
+		sentence("GPRMC,120000,A,4807.038,N,01131.000,E,0.0,0.0,010524,,"),
+	}, "\n")
+
+	track := newTrack(t)
+
+	var notified []Fix
+	track.OnFix = func(fix Fix) { notified = append(notified, fix) }
+
+	require.NoError(t, readNMEA(strings.NewReader(feed), track))
+
+	require.Len(t, track.fixes, 2)
+	require.Equal(t, track.fixes, notified)
+	require.True(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Equal(track.fixes[0].Time))
+	require.True(t, time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC).Equal(track.fixes[1].Time))
+}
+
+func TestFollowGPSD(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	watch := make(chan string, 1)
+	go func() {
+		conn, errAccept := listener.Accept()
+		if errAccept != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		watch <- line
+
+		// the connection is closed at the end of the feed, as gpsd does when it stops
+		_, _ = conn.Write([]byte(strings.Join([]string{
+			`{"class":"VERSION","release":"3.22","proto_major":3,"proto_minor":14}`,
+			`{"class":"DEVICES","devices":[{"class":"DEVICE","path":"/dev/ttyACM0"}]}`,
+			`{"class":"TPV","device":"/dev/ttyACM0","mode":1,"time":"2024-05-01T12:00:00.000Z"}`,
+			`{"class":"TPV","device":"/dev/ttyACM0","mode":3,"time":"2024-05-01T12:00:01.000Z","lat":45.4642,"lon":9.19}`,
+			`{"class":"SKY","satellites":[{"PRN":1,"used":true}]}`,
+			`not json`,
+			`{"class":"TPV","device":"/dev/ttyACM0","mode":2,"time":"2024-05-01T12:00:02.000Z","lat":45.4643}`,
+			`{"class":"TPV","device":"/dev/ttyACM0","mode":2,"lat":45.4643,"lon":9.1901}`,
+			`{"class":"TPV","device":"/dev/ttyACM0","mode":2,"time":"2024-05-01T12:00:03.000Z","lat":45.4644,"lon":9.1902}`,
+		}, "\n") + "\n"))
+	}()
+
+	track := newTrack(t)
+	require.NoError(t, FollowGPSD(listener.Addr().String(), track))
+	require.Equal(t, gpsdWatch, <-watch)
+
+	require.Equal(t, []Fix{
+		{Latitude: 45.4642, Longitude: 9.19, Time: time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)},
+		{Latitude: 45.4644, Longitude: 9.1902, Time: time.Date(2024, 5, 1, 12, 0, 3, 0, time.UTC)},
+	}, track.fixes)
+}
+
+func TestFollowGPSDUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	require.Error(t, FollowGPSD(address, newTrack(t)))
+}
+
+func TestPosition(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	track := newTrack(t)
+	for i, offset := range []time.Duration{0, time.Minute, 10 * time.Minute} {
+		require.NoError(t, track.Add(Fix{Latitude: float64(i), Longitude: float64(i), Time: start.Add(offset)}))
+	}
+
+	tests := []struct {
+		name     string
+		capture  time.Duration // after start
+		maxGap   time.Duration
+		latitude float64 // of the fix expected, -1 when none is
+	}{
+		{name: "same time", capture: time.Minute, maxGap: time.Minute, latitude: 1},
+		{name: "closer to the fix before", capture: 20 * time.Second, maxGap: time.Minute, latitude: 0},
+		{name: "closer to the fix after", capture: 40 * time.Second, maxGap: time.Minute, latitude: 1},
+		{name: "between distant fixes", capture: 6 * time.Minute, maxGap: 5 * time.Minute, latitude: 2},
+		{name: "before the first fix", capture: -30 * time.Second, maxGap: time.Minute, latitude: 0},
+		{name: "after the last fix", capture: 11 * time.Minute, maxGap: 2 * time.Minute, latitude: 2},
+		{name: "fixes too old", capture: 30 * time.Minute, maxGap: 2 * time.Minute, latitude: -1},
+		{name: "fixes too far away", capture: 5 * time.Minute, maxGap: 2 * time.Minute, latitude: -1},
+		{name: "gap is inclusive", capture: 12 * time.Minute, maxGap: 2 * time.Minute, latitude: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fix, found := track.Position(start.Add(test.capture), test.maxGap)
+			if test.latitude < 0 {
+				require.False(t, found)
+				require.Nil(t, fix)
+				return
+			}
+
+			require.True(t, found)
+			require.Equal(t, test.latitude, fix.Latitude)
+		})
+	}
+
+	t.Run("no fix", func(t *testing.T) {
+		fix, found := newTrack(t).Position(start, time.Hour)
+		require.False(t, found)
+		require.Nil(t, fix)
+	})
+}
+
+func TestTrackReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "track.jsonl")
+	now := time.Now().Truncate(time.Second)
+
+	track, err := NewTrack(path, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, track.Add(Fix{Latitude: 1, Time: now.Add(-2 * time.Hour)}))
+	require.NoError(t, track.Add(Fix{Latitude: 2, Time: now.Add(-time.Minute)}))
+	// not newer than the last fix
+	require.NoError(t, track.Add(Fix{Latitude: 3, Time: now.Add(-time.Minute)}))
+	require.NoError(t, track.Close())
+
+	// a line cut by a power loss
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"Latitude":4,"Lo`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	track, err = NewTrack(path, time.Hour)
+	require.NoError(t, err)
+	defer track.Close()
+
+	// the fix older than the retention is forgotten
+	require.Len(t, track.fixes, 1)
+	require.Equal(t, float64(2), track.fixes[0].Latitude)
+
+	fix, found := track.Position(now, 2*time.Minute)
+	require.True(t, found)
+	require.Equal(t, float64(2), fix.Latitude)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(content), "\n"), "the file is rewritten without the old and cut fixes")
+}
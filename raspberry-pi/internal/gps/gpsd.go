@@ -0,0 +1,57 @@
+package gps
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"time"
+)
+
+const (
+	gpsdDialTimeout = 10 * time.Second
+	// gpsdWatch asks gpsd to stream its reports as JSON, one per line
+	gpsdWatch = `?WATCH={"enable":true,"json":true};` + "\n"
+	// modes of a TPV report, positions are known from the 2D fix on
+	gpsdMode2D = 2
+)
+
+// gpsdReport the fields of the gpsd reports we use, only TPV reports carry a position
+type gpsdReport struct {
+	Class string    `json:"class"`
+	Mode  int       `json:"mode"`
+	Time  time.Time `json:"time"`
+	Lat   *float64  `json:"lat"`
+	Lon   *float64  `json:"lon"`
+}
+
+// FollowGPSD adds to the track the fixes streamed by the gpsd listening at address, until the connection is closed
+func FollowGPSD(address string, track *Track) error {
+	conn, err := net.DialTimeout("tcp", address, gpsdDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte(gpsdWatch)); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var report gpsdReport
+		// gpsd sends other classes of reports, they are not all decodable in our struct
+		if json.Unmarshal(scanner.Bytes(), &report) != nil {
+			continue
+		}
+
+		if report.Class != "TPV" || report.Mode < gpsdMode2D || report.Lat == nil || report.Lon == nil || report.Time.IsZero() {
+			continue
+		}
+
+		if err = track.Add(Fix{Latitude: *report.Lat, Longitude: *report.Lon, Time: report.Time}); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,143 @@
+package gps
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errNoFix = errors.New("no fix")
+
+// FollowNMEA adds to the track the fixes read from an NMEA 0183 source, a serial device or a file.
+// It returns once the source ends, serial devices never do
+func FollowNMEA(path string, track *Track) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return readNMEA(file, track)
+}
+
+// readNMEA adds to the track the fixes of the RMC sentences read from reader, the other lines are skipped
+func readNMEA(reader io.Reader, track *Track) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fix, err := parseRMC(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			continue
+		}
+
+		if err = track.Add(*fix); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+/*
+parseRMC
+
+Parses a RMC sentence, the only one carrying both the date and the position:
+
+	$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A
+
+Any talker (GP, GN, ...) is accepted, other sentences and sentences without a valid fix are refused
+*/
+func parseRMC(sentence string) (*Fix, error) {
+	body, err := checksum(sentence)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Split(body, ",")
+	if len(fields) < 10 || len(fields[0]) != 5 || fields[0][2:] != "RMC" {
+		return nil, fmt.Errorf("not a RMC sentence: %s", sentence)
+	}
+
+	if fields[2] != "A" {
+		return nil, errNoFix
+	}
+
+	at, err := time.Parse("020106 150405", fields[9]+" "+strings.SplitN(fields[1], ".", 2)[0])
+	if err != nil {
+		return nil, err
+	}
+	// fractions of seconds are kept, receivers sending several fixes per second would have them all at the same time
+	if _, fraction, found := strings.Cut(fields[1], "."); found {
+		if parsed, errFraction := strconv.ParseFloat("0."+fraction, 64); errFraction == nil {
+			at = at.Add(time.Duration(parsed * float64(time.Second)))
+		}
+	}
+
+	latitude, err := coordinate(fields[3], fields[4], "N", "S", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	longitude, err := coordinate(fields[5], fields[6], "E", "W", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Fix{Latitude: latitude, Longitude: longitude, Time: at}, nil
+}
+
+// checksum verifies the checksum of the sentence, returning what is between $ and *
+func checksum(sentence string) (string, error) {
+	body, sum, found := strings.Cut(strings.TrimPrefix(sentence, "$"), "*")
+	if !strings.HasPrefix(sentence, "$") || !found {
+		return "", fmt.Errorf("not a NMEA sentence: %s", sentence)
+	}
+
+	expected, err := strconv.ParseUint(sum, 16, 8)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksum: %s", sentence)
+	}
+
+	var computed byte
+	for i := 0; i < len(body); i++ {
+		computed ^= body[i]
+	}
+
+	if computed != byte(expected) {
+		return "", fmt.Errorf("checksum mismatch: %s", sentence)
+	}
+
+	return body, nil
+}
+
+// coordinate converts a (d)ddmm.mmmm value to degrees, negative in the south and west hemispheres
+func coordinate(value, hemisphere, positive, negative string, degreeDigits int) (float64, error) {
+	if len(value) < degreeDigits+2 {
+		return 0, fmt.Errorf("invalid coordinate: %s", value)
+	}
+
+	degrees, err := strconv.ParseFloat(value[:degreeDigits], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	minutes, err := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	degrees += minutes / 60
+
+	switch hemisphere {
+	case positive:
+		return degrees, nil
+	case negative:
+		return -degrees, nil
+	default:
+		return 0, fmt.Errorf("invalid hemisphere: %s", hemisphere)
+	}
+}
@@ -0,0 +1,169 @@
+package gps
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Fix a position reported by the GPS receiver, Time is the one of the receiver
+type Fix struct {
+	Latitude  float64
+	Longitude float64
+	Time      time.Time
+}
+
+/*
+Track
+
+Fixes received so far, ordered by time. Captures are often uploaded long after they have been taken,
+e.g. once back home, so fixes are also appended to a file and reloaded when the daemon starts.
+Fixes older than the retention are forgotten
+*/
+type Track struct {
+	// OnFix when set is called with each fix added
+	OnFix func(Fix)
+
+	mutex     sync.RWMutex
+	fixes     []Fix
+	file      *os.File
+	retention time.Duration
+}
+
+// NewTrack loads the fixes written in path, creating it if it does not exist
+func NewTrack(path string, retention time.Duration) (*Track, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	t := &Track{retention: retention}
+
+	if err := t.load(path); err != nil {
+		return nil, err
+	}
+
+	// rewriting the file drops the fixes older than the retention
+	if err := t.rewrite(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	t.file = file
+
+	return t, nil
+}
+
+func (t *Track) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	oldest := time.Now().Add(-t.retention)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var fix Fix
+		// a line cut by a power loss is skipped
+		if json.Unmarshal(scanner.Bytes(), &fix) != nil || fix.Time.Before(oldest) {
+			continue
+		}
+		t.fixes = append(t.fixes, fix)
+	}
+
+	slices.SortFunc(t.fixes, func(a, b Fix) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return scanner.Err()
+}
+
+func (t *Track) rewrite(path string) error {
+	temp := path + ".tmp"
+
+	file, err := os.OpenFile(temp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, fix := range t.fixes {
+		if err = encoder.Encode(fix); err != nil {
+			break
+		}
+	}
+
+	if err = errors.Join(err, file.Close()); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
+
+// Add records a fix. Fixes not newer than the last one are ignored, so a source read again does not duplicate them
+func (t *Track) Add(fix Fix) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.fixes) > 0 && !fix.Time.After(t.fixes[len(t.fixes)-1].Time) {
+		return nil
+	}
+
+	t.fixes = append(t.fixes, fix)
+	if t.OnFix != nil {
+		t.OnFix(fix)
+	}
+
+	return json.NewEncoder(t.file).Encode(fix)
+}
+
+// Position returns the fix closest to at, if there is one less than maxGap away from it
+func (t *Track) Position(at time.Time, maxGap time.Duration) (*Fix, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	index, _ := slices.BinarySearchFunc(t.fixes, at, func(fix Fix, at time.Time) int {
+		return fix.Time.Compare(at)
+	})
+
+	var closest *Fix
+	// the closest fix is either the first one after at or the last one before it
+	for _, i := range []int{index - 1, index} {
+		if i < 0 || i >= len(t.fixes) {
+			continue
+		}
+		if closest == nil || absolute(t.fixes[i].Time.Sub(at)) < absolute(closest.Time.Sub(at)) {
+			found := t.fixes[i]
+			closest = &found
+		}
+	}
+
+	if closest == nil || absolute(closest.Time.Sub(at)) > maxGap {
+		return nil, false
+	}
+
+	return closest, true
+}
+
+func (t *Track) Close() error {
+	return t.file.Close()
+}
+
+func absolute(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,278 @@
+package status
+
+import (
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	log "github.com/sirupsen/logrus"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	historySize  = 50
+	messagesSize = 20
+)
+
+// Request asked by the user from the dashboard
+type Request int
+
+const (
+	// ForceUpload rescans and uploads immediately, even outside the upload windows
+	ForceUpload Request = iota + 1
+	// Rescan scans every watch directory again, uploading within the upload windows only
+	Rescan
+)
+
+// Network found in a capture. Classification is one of the wpaparser handshake classifications
+type Network struct {
+	SSID           string
+	BSSID          string
+	FilePath       string
+	Classification string
+	Details        string // what the environment knows about the access point, e.g. its channel
+	Seen           time.Time
+}
+
+// Upload a capture waiting in the upload queue, Sent and Size are updated while its chunks are acknowledged
+type Upload struct {
+	FilePath string
+	SSID     string
+	BSSID    string
+	Sent     int64
+	Size     int64
+}
+
+// Outcome of an upload, Error is empty when the capture was uploaded
+type Outcome struct {
+	FilePath string
+	SSID     string
+	BSSID    string
+	Result   string
+	Error    string
+	At       time.Time
+}
+
+// Connectivity the last time something was checked, Error is empty when it succeeded
+type Connectivity struct {
+	Target    string
+	Checked   time.Time
+	Connected bool
+	Error     string
+}
+
+// Message a warning or error logged by the daemon
+type Message struct {
+	Level string
+	Text  string
+	At    time.Time
+}
+
+// Snapshot copy of the daemon status, safe to be read while the daemon keeps running
+type Snapshot struct {
+	Directories []string
+	Networks    []*Network
+	Queue       []*Upload
+	History     []*Outcome
+	HomeWIFI    *Connectivity // nil when the home network is not monitored
+	GPS         *Connectivity // nil when captures are not geotagged, Checked is the time of the last fix
+	Server      Connectivity
+	Results     []*entities.TCPResult
+	Messages    []*Message
+	LastScan    time.Time
+}
+
+var (
+	mutex    sync.RWMutex
+	current  = &Snapshot{}
+	requests = make(chan Request, 1)
+)
+
+// Current returns a copy of the status
+func Current() *Snapshot {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	snapshot := *current
+	snapshot.Directories = slices.Clone(current.Directories)
+	snapshot.Networks = cloneAll(current.Networks)
+	snapshot.Queue = cloneAll(current.Queue)
+	snapshot.History = cloneAll(current.History)
+	snapshot.Results = cloneAll(current.Results)
+	snapshot.Messages = cloneAll(current.Messages)
+	if current.HomeWIFI != nil {
+		homeWIFI := *current.HomeWIFI
+		snapshot.HomeWIFI = &homeWIFI
+	}
+	if current.GPS != nil {
+		gps := *current.GPS
+		snapshot.GPS = &gps
+	}
+	return &snapshot
+}
+
+func cloneAll[T any](items []*T) []*T {
+	cloned := make([]*T, 0, len(items))
+	for _, item := range items {
+		copied := *item
+		cloned = append(cloned, &copied)
+	}
+	return cloned
+}
+
+// update runs change holding the lock
+func update(change func(s *Snapshot)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	change(current)
+}
+
+// SetDirectories records the watch directories, described as shown to the user
+func SetDirectories(directories []string) {
+	update(func(s *Snapshot) {
+		s.Directories = slices.Clone(directories)
+	})
+}
+
+// SetNetworks records the networks found by a scan. On a full scan they replace the known ones,
+// otherwise the networks of the scanned captures are updated only
+func SetNetworks(networks []*Network, fullScan bool) {
+	update(func(s *Snapshot) {
+		if fullScan {
+			s.Networks = nil
+			s.LastScan = time.Now()
+		}
+
+		scanned := make(map[string]bool)
+		for _, network := range networks {
+			scanned[network.FilePath] = true
+		}
+
+		s.Networks = slices.DeleteFunc(s.Networks, func(network *Network) bool {
+			return scanned[network.FilePath]
+		})
+		s.Networks = append(s.Networks, cloneAll(networks)...)
+	})
+}
+
+// Enqueue adds the captures about to be uploaded to the upload queue
+func Enqueue(uploads []*Upload) {
+	update(func(s *Snapshot) {
+		s.Queue = append(s.Queue, cloneAll(uploads)...)
+	})
+}
+
+// Progress records the bytes of the capture acknowledged by the server
+func Progress(filePath, bssid string, sent, size int64) {
+	update(func(s *Snapshot) {
+		for _, upload := range s.Queue {
+			if upload.FilePath == filePath && upload.BSSID == bssid {
+				upload.Sent, upload.Size = sent, size
+			}
+		}
+	})
+}
+
+// Done removes the capture from the upload queue and adds its outcome to the history
+func Done(outcome *Outcome) {
+	update(func(s *Snapshot) {
+		s.Queue = slices.DeleteFunc(s.Queue, func(upload *Upload) bool {
+			return upload.FilePath == outcome.FilePath && upload.BSSID == outcome.BSSID
+		})
+
+		recorded := *outcome
+		recorded.At = time.Now()
+		s.History = append(s.History, &recorded)
+		if len(s.History) > historySize {
+			s.History = s.History[len(s.History)-historySize:]
+		}
+	})
+}
+
+// ClearQueue empties the upload queue, the captures left are uploaded on the next scan
+func ClearQueue() {
+	update(func(s *Snapshot) {
+		s.Queue = nil
+	})
+}
+
+// SetHomeWIFI records whether the device is connected to the home network ssid
+func SetHomeWIFI(ssid string, connected bool, err error) {
+	update(func(s *Snapshot) {
+		s.HomeWIFI = newConnectivity(ssid, connected, err)
+	})
+}
+
+// SetGPS records the last fix read from the GPS source, or why the source stopped
+func SetGPS(source string, fix time.Time, err error) {
+	update(func(s *Snapshot) {
+		s.GPS = newConnectivity(source, err == nil, err)
+		if err == nil {
+			s.GPS.Checked = fix.Local()
+		}
+	})
+}
+
+// SetServer records whether the server at address was reachable the last time the daemon connected
+func SetServer(address string, err error) {
+	update(func(s *Snapshot) {
+		s.Server = *newConnectivity(address, err == nil, err)
+	})
+}
+
+func newConnectivity(target string, connected bool, err error) *Connectivity {
+	connectivity := &Connectivity{
+		Target:    target,
+		Checked:   time.Now(),
+		Connected: connected,
+	}
+	if err != nil {
+		connectivity.Error = err.Error()
+	}
+	return connectivity
+}
+
+// SetResults replaces the networks cracked
+func SetResults(results []*entities.TCPResult) {
+	update(func(s *Snapshot) {
+		s.Results = cloneAll(results)
+	})
+}
+
+// AddMessage keeps the latest messages logged
+func AddMessage(level, text string) {
+	update(func(s *Snapshot) {
+		s.Messages = append(s.Messages, &Message{Level: level, Text: text, At: time.Now()})
+		if len(s.Messages) > messagesSize {
+			s.Messages = s.Messages[len(s.Messages)-messagesSize:]
+		}
+	})
+}
+
+// Ask sends a request to the daemon. A request is dropped when another one is still waiting
+func Ask(request Request) bool {
+	select {
+	case requests <- request:
+		return true
+	default:
+		return false
+	}
+}
+
+// Requests delivers the requests of the user
+func Requests() <-chan Request {
+	return requests
+}
+
+// LogHook records the messages logged from Level up, so that they are visible while the dashboard hides the logs
+type LogHook struct {
+	Level log.Level
+}
+
+func (h *LogHook) Levels() []log.Level {
+	return log.AllLevels[:h.Level+1]
+}
+
+func (h *LogHook) Fire(entry *log.Entry) error {
+	AddMessage(entry.Level.String(), entry.Message)
+	return nil
+}
@@ -0,0 +1,274 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// reset starts the test from an empty status
+func reset(t *testing.T) {
+	update(func(s *Snapshot) { *s = Snapshot{} })
+	t.Cleanup(func() {
+		update(func(s *Snapshot) { *s = Snapshot{} })
+	})
+}
+
+func network(path, bssid string) *Network {
+	return &Network{SSID: "TEST", BSSID: bssid, FilePath: path, Classification: "complete"}
+}
+
+func TestSetNetworks(t *testing.T) {
+	tests := []struct {
+		name     string
+		scans    [][]*Network
+		full     []bool
+		networks []string // file paths in the order they are shown
+		scanned  bool
+	}{
+		{
+			name:     "full scan",
+			scans:    [][]*Network{{network("/a.pcap", "01"), network("/b.pcap", "02")}},
+			full:     []bool{true},
+			networks: []string{"/a.pcap", "/b.pcap"},
+			scanned:  true,
+		},
+		{
+			name:     "full scan replaces the known networks",
+			scans:    [][]*Network{{network("/a.pcap", "01")}, {network("/b.pcap", "02")}},
+			full:     []bool{true, true},
+			networks: []string{"/b.pcap"},
+			scanned:  true,
+		},
+		{
+			name:     "capture changed is added",
+			scans:    [][]*Network{{network("/a.pcap", "01")}, {network("/b.pcap", "02")}},
+			full:     []bool{true, false},
+			networks: []string{"/a.pcap", "/b.pcap"},
+			scanned:  true,
+		},
+		{
+			name: "networks of the capture changed are replaced",
+			scans: [][]*Network{
+				{network("/a.pcap", "01"), network("/a.pcap", "02"), network("/b.pcap", "03")},
+				{network("/a.pcap", "04")},
+			},
+			full:     []bool{true, false},
+			networks: []string{"/b.pcap", "/a.pcap"},
+			scanned:  true,
+		},
+		{
+			name:     "no full scan yet",
+			scans:    [][]*Network{{network("/a.pcap", "01")}},
+			full:     []bool{false},
+			networks: []string{"/a.pcap"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reset(t)
+
+			for i, scan := range test.scans {
+				SetNetworks(scan, test.full[i])
+			}
+
+			snapshot := Current()
+			paths := make([]string, 0, len(snapshot.Networks))
+			for _, network := range snapshot.Networks {
+				paths = append(paths, network.FilePath)
+			}
+			require.Equal(t, test.networks, paths)
+			require.Equal(t, test.scanned, !snapshot.LastScan.IsZero())
+		})
+	}
+}
+
+func TestUploads(t *testing.T) {
+	reset(t)
+
+	Enqueue([]*Upload{
+		{FilePath: "/a.pcap", SSID: "HOME", BSSID: "01"},
+		{FilePath: "/a.pcap", SSID: "SHOP", BSSID: "02"},
+		{FilePath: "/b.pcap", SSID: "WORK", BSSID: "03"},
+	})
+	Progress("/a.pcap", "02", 512, 1024)
+
+	queue := Current().Queue
+	require.Len(t, queue, 3)
+	require.Equal(t, int64(0), queue[0].Size, "only the capture of the network is updated")
+	require.Equal(t, int64(512), queue[1].Sent)
+	require.Equal(t, int64(1024), queue[1].Size)
+
+	Done(&Outcome{FilePath: "/a.pcap", SSID: "SHOP", BSSID: "02", Result: "uploaded"})
+	Done(&Outcome{FilePath: "/b.pcap", SSID: "WORK", BSSID: "03", Error: "server unreachable"})
+
+	snapshot := Current()
+	require.Len(t, snapshot.Queue, 1)
+	require.Equal(t, "01", snapshot.Queue[0].BSSID)
+	require.Len(t, snapshot.History, 2)
+	require.Equal(t, "uploaded", snapshot.History[0].Result)
+	require.Equal(t, "server unreachable", snapshot.History[1].Error)
+	require.False(t, snapshot.History[0].At.IsZero())
+
+	ClearQueue()
+	require.Empty(t, Current().Queue)
+}
+
+// TestBounded checks that the history and the messages keep the latest entries only
+func TestBounded(t *testing.T) {
+	reset(t)
+
+	for i := range historySize + 5 {
+		Done(&Outcome{BSSID: fmt.Sprint(i)})
+	}
+	for i := range messagesSize + 5 {
+		AddMessage("warning", fmt.Sprint(i))
+	}
+
+	snapshot := Current()
+	require.Len(t, snapshot.History, historySize)
+	require.Equal(t, "5", snapshot.History[0].BSSID)
+	require.Equal(t, fmt.Sprint(historySize+4), snapshot.History[historySize-1].BSSID)
+
+	require.Len(t, snapshot.Messages, messagesSize)
+	require.Equal(t, "5", snapshot.Messages[0].Text)
+}
+
+func TestConnectivity(t *testing.T) {
+	fix := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		change    func()
+		read      func(s *Snapshot) *Connectivity
+		target    string
+		connected bool
+		err       string
+	}{
+		{
+			name:      "home wifi connected",
+			change:    func() { SetHomeWIFI("HOME", true, nil) },
+			read:      func(s *Snapshot) *Connectivity { return s.HomeWIFI },
+			target:    "HOME",
+			connected: true,
+		},
+		{
+			name:   "home wifi not connected",
+			change: func() { SetHomeWIFI("HOME", false, nil) },
+			read:   func(s *Snapshot) *Connectivity { return s.HomeWIFI },
+			target: "HOME",
+		},
+		{
+			name:   "home wifi not readable",
+			change: func() { SetHomeWIFI("HOME", false, errors.New("no wireless interface")) },
+			read:   func(s *Snapshot) *Connectivity { return s.HomeWIFI },
+			target: "HOME",
+			err:    "no wireless interface",
+		},
+		{
+			name:      "gps fix",
+			change:    func() { SetGPS("gpsd", fix, nil) },
+			read:      func(s *Snapshot) *Connectivity { return s.GPS },
+			target:    "gpsd",
+			connected: true,
+		},
+		{
+			name:   "gps stopped",
+			change: func() { SetGPS("gpsd", time.Time{}, errors.New("connection refused")) },
+			read:   func(s *Snapshot) *Connectivity { return s.GPS },
+			target: "gpsd",
+			err:    "connection refused",
+		},
+		{
+			name:      "server reachable",
+			change:    func() { SetServer("127.0.0.1:4749", nil) },
+			read:      func(s *Snapshot) *Connectivity { return &s.Server },
+			target:    "127.0.0.1:4749",
+			connected: true,
+		},
+		{
+			name:   "server unreachable",
+			change: func() { SetServer("127.0.0.1:4749", errors.New("timeout")) },
+			read:   func(s *Snapshot) *Connectivity { return &s.Server },
+			target: "127.0.0.1:4749",
+			err:    "timeout",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reset(t)
+			test.change()
+
+			connectivity := test.read(Current())
+			require.NotNil(t, connectivity)
+			require.Equal(t, test.target, connectivity.Target)
+			require.Equal(t, test.connected, connectivity.Connected)
+			require.Equal(t, test.err, connectivity.Error)
+			require.False(t, connectivity.Checked.IsZero())
+		})
+	}
+
+	t.Run("gps checked at the fix", func(t *testing.T) {
+		reset(t)
+		SetGPS("gpsd", fix, nil)
+		require.True(t, fix.Equal(Current().GPS.Checked))
+	})
+}
+
+// TestCurrent checks that the snapshot is not changed by the updates following it
+func TestCurrent(t *testing.T) {
+	reset(t)
+
+	SetDirectories([]string{"/captures"})
+	SetNetworks([]*Network{network("/a.pcap", "01")}, true)
+	Enqueue([]*Upload{{FilePath: "/a.pcap", BSSID: "01"}})
+	SetResults([]*entities.TCPResult{{SSID: "TEST", BSSID: "01", CrackedHandshake: "password"}})
+	SetHomeWIFI("HOME", true, nil)
+
+	snapshot := Current()
+	snapshot.Networks[0].SSID = "CHANGED"
+	snapshot.HomeWIFI.Connected = false
+
+	SetDirectories([]string{"/other"})
+	Progress("/a.pcap", "01", 1, 2)
+	SetResults(nil)
+
+	require.Equal(t, []string{"/captures"}, snapshot.Directories)
+	require.Equal(t, int64(0), snapshot.Queue[0].Sent)
+	require.Len(t, snapshot.Results, 1)
+
+	current := Current()
+	require.Equal(t, "TEST", current.Networks[0].SSID)
+	require.True(t, current.HomeWIFI.Connected)
+	require.Equal(t, int64(1), current.Queue[0].Sent)
+	require.Empty(t, current.Results)
+}
+
+func TestAsk(t *testing.T) {
+	require.True(t, Ask(ForceUpload))
+	require.False(t, Ask(Rescan), "a request is still waiting")
+	require.Equal(t, ForceUpload, <-Requests())
+	require.True(t, Ask(Rescan))
+	require.Equal(t, Rescan, <-Requests())
+}
+
+func TestLogHook(t *testing.T) {
+	reset(t)
+
+	hook := &LogHook{Level: log.WarnLevel}
+	require.Equal(t, []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}, hook.Levels())
+
+	require.NoError(t, hook.Fire(&log.Entry{Level: log.ErrorLevel, Message: "upload failed"}))
+
+	messages := Current().Messages
+	require.Len(t, messages, 1)
+	require.Equal(t, "error", messages[0].Level)
+	require.Equal(t, "upload failed", messages[0].Text)
+}
@@ -0,0 +1,222 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/wpaparser"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	forceUpload = "u"
+	rescan      = "r"
+
+	refreshInterval = time.Second
+	// rows shown at most in each section, the most recent ones
+	sectionRows = 6
+	timeFormat  = "15:04:05"
+)
+
+var (
+	sectionStyle = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240")).Padding(0, 1)
+	headerStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	okStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	warnStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+)
+
+type refreshMsg time.Time
+
+type dashboardModel struct {
+	machineID string
+	snapshot  *status.Snapshot
+	notice    string
+	width     int
+}
+
+// DashboardModel shows the status of the daemon, refreshed every second
+func DashboardModel(machineID string) tea.Model {
+	return dashboardModel{
+		machineID: machineID,
+		snapshot:  status.Current(),
+	}
+}
+
+func refresh() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return refreshMsg(t)
+	})
+}
+
+func (m dashboardModel) Init() tea.Cmd {
+	return refresh()
+}
+
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case refreshMsg:
+		m.snapshot = status.Current()
+		return m, refresh()
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tea.KeyMsg:
+		switch msg.String() {
+		case ctrlC, esc, quit:
+			return m, tea.Quit
+		case forceUpload:
+			m.notice = askNotice(status.Ask(status.ForceUpload), "upload")
+		case rescan:
+			m.notice = askNotice(status.Ask(status.Rescan), "rescan")
+		}
+	}
+
+	return m, nil
+}
+
+func askNotice(accepted bool, action string) string {
+	if !accepted {
+		return warnStyle.Render(fmt.Sprintf("another request is waiting, %s not requested", action))
+	}
+	return okStyle.Render(fmt.Sprintf("%s requested at %s", action, time.Now().Format(timeFormat)))
+}
+
+// section renders a bordered block with its title, as wide as the terminal when its size is known
+func (m dashboardModel) section(title string, lines []string) string {
+	if len(lines) == 0 {
+		lines = []string{blurredStyle.Render("nothing yet")}
+	}
+
+	style := sectionStyle
+	if m.width > 4 {
+		style = style.Width(m.width - 4)
+	}
+
+	return style.Render(headerStyle.Render(title) + "\n" + strings.Join(lines, "\n"))
+}
+
+// latest returns the last sectionRows items
+func latest[T any](items []T) []T {
+	return items[max(len(items)-sectionRows, 0):]
+}
+
+func connectivityLine(name string, connectivity *status.Connectivity) string {
+	switch {
+	case connectivity == nil:
+		return fmt.Sprintf("%s: %s", name, blurredStyle.Render("not monitored"))
+	case connectivity.Checked.IsZero():
+		return fmt.Sprintf("%s: %s", name, blurredStyle.Render("not checked yet"))
+	case connectivity.Connected:
+		return fmt.Sprintf("%s: %s %s (%s)", name, okStyle.Render("connected"), connectivity.Target, connectivity.Checked.Format(timeFormat))
+	case connectivity.Error != "":
+		return fmt.Sprintf("%s: %s %s (%s) %s", name, errorStyle.Render("unreachable"), connectivity.Target,
+			connectivity.Checked.Format(timeFormat), connectivity.Error)
+	default:
+		return fmt.Sprintf("%s: %s %s (%s)", name, warnStyle.Render("not connected"), connectivity.Target, connectivity.Checked.Format(timeFormat))
+	}
+}
+
+func classificationStyle(classification string) lipgloss.Style {
+	switch classification {
+	case wpaparser.HandshakeComplete:
+		return okStyle
+	case wpaparser.HandshakePartial:
+		return warnStyle
+	default:
+		return blurredStyle
+	}
+}
+
+func (m dashboardModel) networkLines() []string {
+	lines := make([]string, 0, sectionRows)
+	for _, network := range latest(m.snapshot.Networks) {
+		details := filepath.Base(network.FilePath)
+		if network.Details != "" {
+			details += " " + network.Details
+		}
+		lines = append(lines, fmt.Sprintf("%-24s %-18s %s  %s", network.SSID, network.BSSID,
+			classificationStyle(network.Classification).Render(fmt.Sprintf("%-8s", network.Classification)),
+			blurredStyle.Render(details)))
+	}
+	return lines
+}
+
+func (m dashboardModel) queueLines() []string {
+	lines := make([]string, 0, sectionRows)
+	for _, upload := range latest(m.snapshot.Queue) {
+		progress := "waiting"
+		if upload.Size > 0 {
+			progress = fmt.Sprintf("%d%%", upload.Sent*100/upload.Size)
+		}
+		lines = append(lines, fmt.Sprintf("%-24s %-18s %s", upload.SSID, upload.BSSID, progress))
+	}
+	return lines
+}
+
+func (m dashboardModel) historyLines() []string {
+	lines := make([]string, 0, sectionRows)
+	for _, outcome := range latest(m.snapshot.History) {
+		result := okStyle.Render(outcome.Result)
+		if outcome.Error != "" {
+			result = errorStyle.Render(outcome.Error)
+		}
+		lines = append(lines, fmt.Sprintf("%s %-24s %-18s %s", outcome.At.Format(timeFormat), outcome.SSID, outcome.BSSID, result))
+	}
+	return lines
+}
+
+func (m dashboardModel) resultLines() []string {
+	lines := make([]string, 0, sectionRows)
+	for _, result := range latest(m.snapshot.Results) {
+		lines = append(lines, fmt.Sprintf("%-24s %-18s %s", result.SSID, result.BSSID, okStyle.Render(result.CrackedHandshake)))
+	}
+	return lines
+}
+
+func (m dashboardModel) messageLines() []string {
+	lines := make([]string, 0, sectionRows)
+	for _, message := range latest(m.snapshot.Messages) {
+		style := warnStyle
+		if message.Level != "warning" {
+			style = errorStyle
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", message.At.Format(timeFormat), style.Render(message.Text)))
+	}
+	return lines
+}
+
+func (m dashboardModel) View() string {
+	snapshot := m.snapshot
+
+	lastScan := "never"
+	if !snapshot.LastScan.IsZero() {
+		lastScan = snapshot.LastScan.Format(timeFormat)
+	}
+
+	sections := []string{
+		titleStyle.Render("H.D.S daemon") + "  " + blurredStyle.Render(m.machineID),
+		m.section("Status", []string{
+			connectivityLine("Server", &snapshot.Server),
+			connectivityLine("Home Wi-Fi", snapshot.HomeWIFI),
+			connectivityLine("GPS", snapshot.GPS),
+			fmt.Sprintf("Last full scan: %s", lastScan),
+		}),
+		m.section("Capture sources", snapshot.Directories),
+		m.section(fmt.Sprintf("Networks (%d)", len(snapshot.Networks)), m.networkLines()),
+		m.section(fmt.Sprintf("Upload queue (%d)", len(snapshot.Queue)), m.queueLines()),
+		m.section("Upload history", m.historyLines()),
+		m.section(fmt.Sprintf("Cracked (%d)", len(snapshot.Results)), m.resultLines()),
+		m.section("Messages", m.messageLines()),
+	}
+
+	help := helpStyle.Render("u force upload • r rescan • q quit")
+	if m.notice != "" {
+		help += "  " + m.notice
+	}
+
+	return strings.Join(append(sections, help), "\n") + "\n"
+}
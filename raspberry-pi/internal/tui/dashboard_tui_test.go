@@ -0,0 +1,139 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectivityLine(t *testing.T) {
+	checked := time.Date(2024, 5, 1, 10, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name         string
+		connectivity *status.Connectivity
+		line         string
+	}{
+		{
+			name: "not monitored",
+			line: "GPS: not monitored",
+		},
+		{
+			name:         "not checked",
+			connectivity: &status.Connectivity{Target: "gpsd"},
+			line:         "GPS: not checked yet",
+		},
+		{
+			name:         "connected",
+			connectivity: &status.Connectivity{Target: "gpsd", Checked: checked, Connected: true},
+			line:         "GPS: connected gpsd (10:30:00)",
+		},
+		{
+			name:         "unreachable",
+			connectivity: &status.Connectivity{Target: "gpsd", Checked: checked, Error: "connection refused"},
+			line:         "GPS: unreachable gpsd (10:30:00) connection refused",
+		},
+		{
+			name:         "not connected",
+			connectivity: &status.Connectivity{Target: "gpsd", Checked: checked},
+			line:         "GPS: not connected gpsd (10:30:00)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.line, connectivityLine("GPS", test.connectivity))
+		})
+	}
+}
+
+func TestQueueLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		upload   *status.Upload
+		progress string
+	}{
+		{name: "waiting", upload: &status.Upload{SSID: "HOME", BSSID: "01"}, progress: "waiting"},
+		{name: "started", upload: &status.Upload{SSID: "HOME", BSSID: "01", Size: 1024}, progress: "0%"},
+		{name: "half", upload: &status.Upload{SSID: "HOME", BSSID: "01", Sent: 512, Size: 1024}, progress: "50%"},
+		{name: "sent", upload: &status.Upload{SSID: "HOME", BSSID: "01", Sent: 1024, Size: 1024}, progress: "100%"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := dashboardModel{snapshot: &status.Snapshot{Queue: []*status.Upload{test.upload}}}
+
+			lines := m.queueLines()
+			require.Len(t, lines, 1)
+			require.True(t, strings.HasSuffix(lines[0], " "+test.progress), lines[0])
+		})
+	}
+}
+
+// TestLatest checks that the sections show the most recent rows only
+func TestLatest(t *testing.T) {
+	var results []*entities.TCPResult
+	for _, ssid := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+		results = append(results, &entities.TCPResult{SSID: ssid, CrackedHandshake: "password"})
+	}
+
+	m := dashboardModel{snapshot: &status.Snapshot{Results: results}}
+
+	lines := m.resultLines()
+	require.Len(t, lines, sectionRows)
+	require.True(t, strings.HasPrefix(lines[0], "C "))
+	require.True(t, strings.HasPrefix(lines[sectionRows-1], "H "))
+}
+
+func TestView(t *testing.T) {
+	m := dashboardModel{
+		machineID: "machine",
+		snapshot: &status.Snapshot{
+			Directories: []string{"/captures"},
+			Networks:    []*status.Network{{SSID: "HOME", BSSID: "01", FilePath: "/captures/home.pcap", Classification: "complete"}},
+			Queue:       []*status.Upload{{SSID: "HOME", BSSID: "01"}},
+			Server:      status.Connectivity{Target: "127.0.0.1:4749"},
+		},
+	}
+
+	view := m.View()
+	require.Contains(t, view, "Networks (1)")
+	require.Contains(t, view, "Upload queue (1)")
+	require.Contains(t, view, "Cracked (0)")
+	require.Contains(t, view, "/captures")
+	require.Contains(t, view, "home.pcap")
+	require.Contains(t, view, "Last full scan: never")
+	require.Contains(t, view, "Home Wi-Fi: not monitored")
+	require.Contains(t, view, "nothing yet")
+}
+
+func TestDashboardRequests(t *testing.T) {
+	tests := []struct {
+		key     string
+		request status.Request
+		notice  string
+	}{
+		{key: forceUpload, request: status.ForceUpload, notice: "upload requested"},
+		{key: rescan, request: status.Rescan, notice: "rescan requested"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			model := DashboardModel("machine")
+
+			model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(test.key)})
+			require.Contains(t, model.(dashboardModel).notice, test.notice)
+
+			// the daemon has not read the first request yet
+			model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(test.key)})
+			require.Contains(t, model.(dashboardModel).notice, "another request is waiting")
+
+			require.Equal(t, test.request, <-status.Requests())
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const quit = "q"
+
+var (
+	titleStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	tableStyle = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240"))
+)
+
+type resultsModel struct {
+	source string
+	table  table.Model
+	empty  bool
+}
+
+// ResultsModel shows the networks cracked among the captures uploaded, source is the file they were read from
+func ResultsModel(source string, results []*entities.TCPResult) tea.Model {
+	columns := []table.Column{
+		{Title: "SSID", Width: 24},
+		{Title: "BSSID", Width: 18},
+		{Title: "Cracked", Width: 32},
+		{Title: "Uploaded", Width: 20},
+	}
+
+	rows := make([]table.Row, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, table.Row{result.SSID, result.BSSID, result.CrackedHandshake, result.UploadedDate})
+	}
+
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		// the height includes the header
+		table.WithHeight(min(len(rows), 15)+1),
+	)
+
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240")).BorderBottom(true).Bold(true)
+	styles.Selected = styles.Selected.Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
+	t.SetStyles(styles)
+
+	return resultsModel{
+		source: source,
+		table:  t,
+		empty:  len(rows) == 0,
+	}
+}
+
+func (m resultsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m resultsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case ctrlC, esc, quit:
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m resultsModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("Cracked networks"))
+	b.WriteString(fmt.Sprintf("\n%s\n\n", helpStyle.Render(m.source)))
+
+	if m.empty {
+		b.WriteString("No network has been cracked yet, results are fetched by the daemon while it runs.\n\n")
+	} else {
+		b.WriteString(tableStyle.Render(m.table.View()))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString(helpStyle.Render("↑/↓ to move, q to quit"))
+	b.WriteRune('\n')
+
+	return b.String()
+}
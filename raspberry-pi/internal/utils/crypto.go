@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// deviceKeyInfo must match the HKDF context used by the server
+const deviceKeyInfo = "hds-raspberrypi-pcap-v1"
+
+// bundleKeyInfo must match the HKDF context of the bundle signing key used by the server
+const bundleKeyInfo = "hds-raspberrypi-bundle-v1"
+
+// GenerateExchangeKey generates the ephemeral X25519 key pair sent to the server during KEYEXCHANGE
+func GenerateExchangeKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// DeriveDeviceKey returns the hex encoded AES-256 key shared with the server
+func DeriveDeviceKey(private *ecdh.PrivateKey, serverPublicKey, machineID string) (string, error) {
+	rawPeer, err := base64.StdEncoding.DecodeString(serverPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	peer, err := ecdh.X25519().NewPublicKey(rawPeer)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := private.ECDH(peer)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, []byte(machineID), deviceKeyInfo, 32)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+// HandshakeAdditionalData binds an encrypted capture to the device and the network it belongs to
+func HandshakeAdditionalData(machineID, bssid, ssid string) []byte {
+	return []byte(machineID + "|" + bssid + "|" + ssid)
+}
+
+// SealAESGCM encrypts plaintext with AES-GCM. The result is nonce || ciphertext || tag
+func SealAESGCM(hexKey string, plaintext, additionalData []byte) ([]byte, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// SignBundle returns the hex encoded HMAC-SHA256 of the manifest of a bundle, keyed with a key derived from the device key
+func SignBundle(hexKey string, manifest []byte) (string, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return "", err
+	}
+
+	signingKey, err := hkdf.Key(sha256.New, key, nil, bundleKeyInfo, 32)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write(manifest)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// EnrollmentCode the code shown on the devices page while the device waits for approval,
+// the server derives it in the same way from the hash of the credential it stores
+func EnrollmentCode(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	code := strings.ToUpper(hex.EncodeToString(sum[:4]))
+	return code[:4] + "-" + code[4:]
+}
@@ -3,6 +3,7 @@ package wifi
 import (
 	"errors"
 	"fmt"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
 	"github.com/mdlayher/wifi"
 	log "github.com/sirupsen/logrus"
 	"os"
@@ -57,15 +58,17 @@ func MonitorWiFiConnection(ssid string) error {
 
 	for {
 		connected, err := checkHouseConnection(ssid)
+		status.SetHomeWIFI(ssid, connected, err)
 		if err != nil {
 			return fmt.Errorf("[RSP-PI] Error checking Wi-Fi connection: %s", err.Error())
 		}
 
 		if connected {
 			log.Infof("[RSP-PI] Successfully connected to '%s'", ssid)
+		} else {
+			log.Warnf("[RSP-PI] Not connected to '%s'. Re-attempting in 5 minutes...", ssid)
 		}
 
-		log.Warnf("[RSP-PI] Not connected to '%s'. Re-attempting in 5 minutes...", ssid)
 		<-ticker.C
 	}
 }
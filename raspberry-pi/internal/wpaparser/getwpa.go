@@ -32,18 +32,22 @@ func GetWPA(handles map[string]*pcap.Handle) []*HandshakeInfo {
 		findBSSIDSSID(packets, seenBSSIDs)
 
 		// Call the function to process the WPA handshake
-		handshakeFound := processWPAHandshake(packets)
-		if handshakeFound {
+		classification := classifyHandshake(packets)
+		if classification == HandshakeComplete {
 			log.Println("WPA2 Handshake successfully detected!")
 		} else {
 			log.Println("Not enough EAPOL packets to form a WPA2 handshake.")
 		}
 
+		capturedAt := captureTime(packets)
+
 		for bssid, ssid := range seenBSSIDs {
 			validFiles = append(validFiles, &HandshakeInfo{
-				FilePath: filePath,
-				BSSID:    bssid,
-				SSID:     ssid,
+				FilePath:       filePath,
+				BSSID:          bssid,
+				SSID:           ssid,
+				Classification: classification,
+				CapturedAt:     capturedAt,
 			})
 		}
 
@@ -5,14 +5,28 @@ import (
 	"github.com/google/gopacket/layers"
 	log "github.com/sirupsen/logrus"
 	"strings"
+	"time"
 )
 
 const UnknownType = "Unknown"
 
+// Handshake classifications of a capture
+const (
+	// HandshakeComplete the 4-way handshake has been captured
+	HandshakeComplete = "complete"
+	// HandshakePartial some EAPOL messages have been captured, not enough for a 4-way handshake
+	HandshakePartial = "partial"
+	// HandshakeMissing no EAPOL message has been captured
+	HandshakeMissing = "missing"
+)
+
+// HandshakeInfo CapturedAt is when the handshake was captured according to the capture, zero for empty captures
 type HandshakeInfo struct {
-	FilePath string
-	BSSID    string
-	SSID     string
+	FilePath       string
+	BSSID          string
+	SSID           string
+	Classification string
+	CapturedAt     time.Time
 }
 
 // -----------------------------
@@ -98,6 +112,36 @@ func processWPAHandshake(packets []gopacket.Packet) bool {
 	return false
 }
 
+// classifyHandshake tells how much of the 4-way handshake has been captured
+func classifyHandshake(packets []gopacket.Packet) string {
+	if processWPAHandshake(packets) {
+		return HandshakeComplete
+	}
+
+	for _, packet := range packets {
+		if extractEAPOLLayer(packet) != nil {
+			return HandshakePartial
+		}
+	}
+
+	return HandshakeMissing
+}
+
+// captureTime returns when the first EAPOL packet was captured, or the first packet when there are none
+func captureTime(packets []gopacket.Packet) time.Time {
+	for _, packet := range packets {
+		if extractEAPOLLayer(packet) != nil {
+			return packet.Metadata().Timestamp
+		}
+	}
+
+	if len(packets) > 0 {
+		return packets[0].Metadata().Timestamp
+	}
+
+	return time.Time{}
+}
+
 // extractEAPOLLayer retrieves the EAPOL layer from a packet.
 func extractEAPOLLayer(packet gopacket.Packet) *layers.EAPOL {
 	if layer := packet.Layer(layers.LayerTypeEAPOL); layer != nil {
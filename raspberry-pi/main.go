@@ -1,106 +1,335 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/cmd"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/config"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/constants"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/daemon"
-	"github.com/Virgula0/progetto-dp/raspberrypi/internal/entities"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/status"
+	"github.com/Virgula0/progetto-dp/raspberrypi/internal/tui"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/utils"
 	internalWIFI "github.com/Virgula0/progetto-dp/raspberrypi/internal/wifi"
 	"github.com/Virgula0/progetto-dp/raspberrypi/internal/wpaparser"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/gopacket/pcap"
 	log "github.com/sirupsen/logrus"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
 
 // initializeInstance sets up the Raspberry Pi instance.
+// The user credentials are asked only the first time, for enrolling the device
 func initializeInstance() (_ *daemon.RaspberryPiInfo, machineID string) {
 	machineID, err := utils.MachineID()
 	if err != nil {
 		log.Fatalf("[RSP-PI] Failed to get machine ID: %s", err.Error())
 	}
 
-	// Parse credentials from command line arguments using cobra
-	credentials, err := cmd.AuthCommand()
+	credential, err := daemon.LoadCredential()
+	if errors.Is(err, os.ErrNotExist) {
+		// Parse credentials from command line arguments using cobra
+		credentials, errAuth := cmd.AuthCommand()
+		if errAuth != nil {
+			log.Fatalf("[RSP-PI] Failed to get credentials: %s", errAuth.Error())
+		}
+
+		credential, err = daemon.EnrollDevice(credentials, machineID)
+	}
+
 	if err != nil {
-		log.Fatalf("[RSP-PI] Failed to get credentials: %s", err.Error())
+		log.Fatalf("[RSP-PI] Failed to get the device credential: %s", err.Error())
 	}
 
 	return &daemon.RaspberryPiInfo{
-		JWT:         new(string),
-		FirstLogin:  make(chan bool, 1),
-		Credentials: credentials,
+		JWT:           new(string),
+		EncryptionKey: new(string),
+		FirstLogin:    make(chan bool, 1),
+		MachineID:     machineID,
+		Credential:    credential,
 	}, machineID
 }
 
-// processHandshakes processes Wi-Fi handshakes and prepares them for transmission.
-func processHandshakes(env daemon.Environment) []*entities.Handshake {
-	handles, err := env.LoadEnvironment()
-	if err != nil {
-		log.Fatalf("[RSP-PI] Failed to load environment: %s", err.Error())
+// processHandshakes looks for Wi-Fi handshakes in the captures changed, in every capture of the environment on a full scan
+func processHandshakes(env daemon.Environment, changed map[string]bool, fullScan bool) ([]*wpaparser.HandshakeInfo, error) {
+	var handles map[string]*pcap.Handle
+	if fullScan {
+		var err error
+		if handles, err = env.LoadEnvironment(); err != nil {
+			return nil, err
+		}
+	} else {
+		handles = env.LoadCaptures(slices.Collect(maps.Keys(changed)))
 	}
 
 	handshakes := wpaparser.GetWPA(handles)
-	toSend := make([]*entities.Handshake, 0)
 
+	networks := make([]*status.Network, 0, len(handshakes))
 	log.Println(strings.Repeat("-", 43))
 	for _, handshakeInfo := range handshakes {
-		log.Println(*handshakeInfo)
-
-		readContent, err := utils.ReadFileBytes(handshakeInfo.FilePath)
-		if err != nil {
-			log.Warnf("[RSP-PI] Unable to read '%s': %s", handshakeInfo.FilePath, err.Error())
-			continue
-		}
-
-		content := utils.BytesToBase64String(readContent)
-		toSend = append(toSend, &entities.Handshake{
-			SSID:          handshakeInfo.SSID,
-			BSSID:         handshakeInfo.BSSID,
-			HandshakePCAP: &content,
+		details := env.Describe(handshakeInfo.BSSID)
+		log.Println(*handshakeInfo, details)
+		networks = append(networks, &status.Network{
+			SSID:           handshakeInfo.SSID,
+			BSSID:          handshakeInfo.BSSID,
+			FilePath:       handshakeInfo.FilePath,
+			Classification: handshakeInfo.Classification,
+			Details:        details,
+			Seen:           time.Now(),
 		})
 	}
 	log.Println(strings.Repeat("-", 43))
-	return toSend
+
+	status.SetNetworks(networks, fullScan)
+	return handshakes, nil
 }
 
-func runWifiCheckRoutine() {
+func runWifiCheckRoutine(homeWIFI string) {
 	// If it is not a test let's check for connection.
 	// This is because we're inside a container we can skip overcomplicating
 	if !constants.Test {
 		go func() {
-			if err := internalWIFI.MonitorWiFiConnection(constants.HomeWIFISSID); err != nil {
+			if err := internalWIFI.MonitorWiFiConnection(homeWIFI); err != nil {
 				log.Fatalf("[RSP-PI] error wifi monitor %s", err.Error())
 			}
 		}()
 	}
 }
 
+/*
+runUploads
+
+Uploads the captures as soon as they are received from captures. Every interval the whole environment is scanned again,
+for the captures written while the daemon was not running and the uploads failed, and the results and directives are fetched.
+Outside the upload windows captures are kept waiting for the next window, unless the user forces the upload from the dashboard.
+Directives can change upload and the watch directories, they are applied from here only
+*/
+func runUploads(instance *daemon.RaspberryPiInfo, machineID string, env daemon.Environment, watcher *daemon.Watcher,
+	captures <-chan string, upload *config.Upload) {
+	ticker := time.NewTicker(upload.Interval)
+	defer ticker.Stop()
+
+	fullScan, force := true, false
+	changed := make(map[string]bool)
+
+	for {
+		if (fullScan || len(changed) > 0) && (force || upload.Allowed(time.Now())) {
+			handshakes, err := processHandshakes(env, changed, fullScan)
+			if err != nil {
+				log.Errorf("[RSP-PI] Failed to load environment: %s", err.Error())
+			}
+
+			// uploads interrupted are resumed on the next scan
+			if err = daemon.UploadCaptures(instance, machineID, handshakes, fullScan && err == nil); err != nil {
+				log.Errorf("error while sending handshake to the server %s", err.Error())
+			}
+
+			if fullScan {
+				if err = daemon.SyncResults(instance, machineID); err != nil {
+					log.Warnf("[RSP-PI] Failed to fetch the results: %s", err.Error())
+				}
+
+				if err = daemon.SyncDirectives(instance, machineID, env, watcher); err != nil {
+					log.Warnf("[RSP-PI] Failed to fetch the directives: %s", err.Error())
+				}
+				ticker.Reset(upload.Interval)
+			}
+
+			fullScan, force = false, false
+			clear(changed)
+		}
+
+		select {
+		case path := <-captures:
+			changed[path] = true
+		case <-ticker.C:
+			fullScan = true
+		case request := <-status.Requests():
+			fullScan, force = true, request == status.ForceUpload
+		}
+	}
+}
+
+/*
+startEnvironment
+
+Starts reading the captures from the bettercap API when it is configured, from the watch directories otherwise.
+It returns the environment, the watcher of its directories (nil for bettercap), the channel receiving the captures changed
+and a description of where captures are read from
+*/
+func startEnvironment(settings *config.Config) (daemon.Environment, *daemon.Watcher, <-chan string, []string, error) {
+	if settings.Bettercap.URL != "" {
+		env, err := daemon.NewBettercapEnvironment(&settings.Bettercap, settings.Upload.Settle)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		go env.Run()
+		return env, nil, env.Captures, []string{fmt.Sprintf("bettercap API %s", settings.Bettercap.URL)}, nil
+	}
+
+	env, err := daemon.ChooseEnvironment(settings.Watch)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	watcher, err := daemon.NewWatcher(settings.Watch, settings.Upload.Settle)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("unable to watch the capture directories: %w", err)
+	}
+
+	go watcher.Run()
+
+	directories := make([]string, 0, len(settings.Watch))
+	for _, directory := range settings.Watch {
+		directories = append(directories, fmt.Sprintf("%s %v (recursive: %t)", directory.Path, directory.Include, directory.Recursive))
+	}
+
+	return env, watcher, watcher.Captures, directories, nil
+}
+
+/*
+exportBundles
+
+Writes every capture of the environment as bundles in directory, without connecting to the server.
+Captures are geotagged with the track recorded while they were taken, as the GPS is not followed
+*/
+func exportBundles(settings *config.Config, directory string) error {
+	machineID, err := utils.MachineID()
+	if err != nil {
+		return err
+	}
+
+	if err = daemon.LoadTrack(&settings.GPS); err != nil {
+		return err
+	}
+
+	var env daemon.Environment
+	if settings.Bettercap.URL != "" {
+		env, err = daemon.NewBettercapEnvironment(&settings.Bettercap, settings.Upload.Settle)
+	} else {
+		env, err = daemon.ChooseEnvironment(settings.Watch)
+	}
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	handles, err := env.LoadEnvironment()
+	if err != nil {
+		return err
+	}
+
+	paths, err := daemon.ExportBundles(machineID, wpaparser.GetWPA(handles), directory)
+	for _, path := range paths {
+		log.Infof("[RSP-PI] Bundle written to %s", path)
+	}
+
+	return err
+}
+
+// runDashboard shows the dashboard until the user quits it. Logs would mess the screen up, they are written in the log file
+func runDashboard(machineID string) error {
+	path, err := daemon.LogPath()
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.SetOutput(file)
+	log.AddHook(&status.LogHook{Level: log.WarnLevel})
+
+	_, err = tea.NewProgram(tui.DashboardModel(machineID), tea.WithAltScreen()).Run()
+	return err
+}
+
 // main orchestrates the Raspberry Pi client application.
 func main() {
-	ticker := time.NewTicker(5 * time.Minute)
+	settings, err := config.Load()
+	if err != nil {
+		log.Fatalf("[RSP-PI] Failed to load the configuration: %s", err.Error())
+	}
+	daemon.Configure(settings)
+	daemon.RecordLogs()
 
-	runWifiCheckRoutine()
+	if shown, errResults := cmd.ResultsCommand(); shown || errResults != nil {
+		if errResults != nil {
+			log.Fatalf("[RSP-PI] Failed to show the results: %s", errResults.Error())
+		}
+		return
+	}
+
+	if export, directory, errExport := cmd.ExportCommand(); export || errExport != nil {
+		if errExport == nil {
+			errExport = exportBundles(settings, directory)
+		}
+		if errExport != nil {
+			log.Fatalf("[RSP-PI] Failed to export the bundles: %s", errExport.Error())
+		}
+		return
+	}
+
+	dashboard := cmd.DashboardRequested()
+	runWifiCheckRoutine(settings.HomeWIFI)
+
+	// captures are geotagged with the fixes received from now on, even while waiting for the login
+	if err = daemon.StartGPS(&settings.GPS); err != nil {
+		log.Errorf("[RSP-PI] Failed to start the GPS: %s", err.Error())
+		return
+	}
 
 	instance, machineID := initializeInstance()
 	go instance.Authenticator()
 
 	<-instance.FirstLogin
 
-	env, err := daemon.ChooseEnvironment()
+	if settings.Server.TLS {
+		if err = daemon.Enroll(instance, machineID); err != nil {
+			log.Errorf("[RSP-PI] Failed to enroll the device certificate: %s", err.Error())
+			return
+		}
+	}
+
+	if err = daemon.ExchangeKey(instance, machineID); err != nil {
+		log.Errorf("[RSP-PI] Failed to exchange the encryption key: %s", err.Error())
+		return
+	}
+
+	env, watcher, captures, sources, err := startEnvironment(settings)
 	if err != nil {
 		log.Errorf("[RSP-PI] Failed to choose environment: %s", err.Error())
 		return
 	}
+	defer env.Close()
 
-	for {
-		handshakes := processHandshakes(env)
-		err := daemon.HandleServerCommunication(instance, machineID, handshakes)
-		if err != nil {
-			log.Errorf("error while sending handshake to the server %s", err.Error())
-			return
-		}
+	if !dashboard {
+		runUploads(instance, machineID, env, watcher, captures, &settings.Upload)
+		return
+	}
+
+	status.SetDirectories(sources)
+
+	if results, errResults := daemon.LoadResults(); errResults == nil {
+		status.SetResults(results)
+	}
+
+	go runUploads(instance, machineID, env, watcher, captures, &settings.Upload)
 
-		<-ticker.C
+	if err = runDashboard(machineID); err != nil {
+		log.Errorf("[RSP-PI] Failed to show the dashboard: %s", err.Error())
 	}
 }
@@ -63,6 +63,20 @@ func updateClientConfigRuntime(service *handlers.ServiceHandler, storage *encryp
 	}
 }
 
+// maintainTokens rotates the JWT signing key and prunes the expired tokens every hour
+func maintainTokens(service *handlers.ServiceHandler, rotation time.Duration) {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := service.Usecase.MaintainTokens(rotation); err != nil {
+			log.Errorf("fail to maintain tokens: %s", err.Error())
+		}
+
+		<-ticker.C
+	}
+}
+
 // StartAsGRPC start the grpc_server server-grpc_server with the required business logic usecases
 func startGRPC(service *handlers.ServiceHandler) error {
 	grpc := grpcserver.New(grpcserver.NewServerContext(service.Usecase))
@@ -106,13 +120,15 @@ func startGRPC(service *handlers.ServiceHandler) error {
 		ServerCert:          serverCert,
 		ServerKey:           serverKey,
 		ClientConfigStorage: storage,
+		EnsureNotRevoked:    service.Usecase.EnsureNotRevoked,
+		AuthorizeToken:      service.Usecase.RequireScope,
 	})
 }
 
 // tcpServerInstance initialize tcp server
 func tcpServerInstance(service *handlers.ServiceHandler, host, port string) (*raspberrypi.TCPServer, error) {
 
-	tcpInstance, err := raspberrypi.NewTCPServer(service, host, port)
+	tcpInstance, err := raspberrypi.NewTCPServer(service, host, port, raspberrypi.TLSMode(constants.TCPTLSMode))
 
 	if err != nil {
 		return nil, err
@@ -124,6 +140,15 @@ func tcpServerInstance(service *handlers.ServiceHandler, host, port string) (*ra
 func RunBackend() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
+	keyRotation := constants.DefaultKeyRotation
+	if constants.JwtKeyRotation != "" {
+		parsed, err := time.ParseDuration(constants.JwtKeyRotation)
+		if err != nil {
+			log.Fatalf("Invalid JWT_KEY_ROTATION: %s", err.Error())
+		}
+		keyRotation = parsed
+	}
+
 	dbUser, err := infrastructure.NewDatabaseConnection(constants.DBUser, constants.DBPassword, constants.DBHost, constants.DBPort, constants.DBName)
 	if err != nil {
 		log.Fatalf("%s", err.Error())
@@ -144,6 +169,8 @@ func RunBackend() {
 
 	srv := createServer(gorillaMux, constants.ServerHost, constants.ServerPort)
 
+	go maintainTokens(service, keyRotation)
+
 	// Go Routine for the REST-API server
 	go func() {
 		go dbUser.StartDBPinger()
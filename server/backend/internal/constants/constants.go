@@ -4,14 +4,71 @@ import (
 	"os"
 	"strings"
 	"time"
-
-	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
 )
 
 const UserIDKey = "userID"
 
+// Claims of the tokens issued to devices, see Usecase.CreateDeviceToken
+const (
+	MachineIDKey  = "machineID"
+	CredentialKey = "credential"
+)
+
+// Claims of the user tokens, see Usecase.CreateAuthToken
+const (
+	TokenIDKey  = "uuid"
+	SessionKey  = "session"
+	AudienceKey = "aud"
+	ScopesKey   = "scopes"
+)
+
+// Audiences of the tokens, a token is accepted only on the channel it was issued for
+const (
+	AudienceFE     = "fe"
+	AudienceClient = "client"
+	AudienceDaemon = "daemon"
+)
+
+// Scopes granted to the tokens, each belongs to an audience, see Usecase.RequireScope
+const (
+	ScopeFE           = "fe"
+	ScopeRead         = "read" // granted with ScopeFE, the GET routes require only this one so read-only tokens can be issued
+	ScopeAdmin        = "admin"
+	ScopeClientTasks  = "client:tasks"
+	ScopeDaemonEnroll = "daemon:enroll"
+	ScopeDaemonUpload = "daemon:upload"
+)
+
+// PersonalAccessTokenPrefix prefix of the personal access tokens, which are not JWTs and are looked up by their hash
+const PersonalAccessTokenPrefix = "hds_"
+
+// SettingRegistrations whether new users can sign up, changed by the administrators at runtime
+const SettingRegistrations = "registrations"
+
+// KeyIDHeader header of the JWTs naming the signing key, see Usecase.LoadSigningKeys
+const KeyIDHeader = "kid"
+
+// Lifetimes of the tokens issued by the backend
+const (
+	AccessTokenDuration = 15 * time.Minute
+	// DeviceTokenDuration the daemon logs in again every hour
+	DeviceTokenDuration  = 2 * time.Hour
+	RefreshTokenDuration = 7 * 24 * time.Hour
+	// RefreshTokenReuseWindow a refresh token is accepted again for this long after its first use
+	RefreshTokenReuseWindow = 30 * time.Second
+	// DefaultKeyRotation age of the signing key when it is replaced, if JWT_KEY_ROTATION is not set
+	DefaultKeyRotation = 30 * 24 * time.Hour
+	// PersonalAccessTokenTouchInterval the last use of a personal access token is recorded at most this often
+	PersonalAccessTokenTouchInterval = time.Minute
+	// SigningKeyReloadInterval the keys are loaded again at most this often for the tokens signed with an unknown key
+	SigningKeyReloadInterval = 10 * time.Second
+)
+
 var JSONContentType = "application/json"
-var JwtSecretKey = []byte(utils.GenerateToken(128))
+var CSVContentType = "text/csv; charset=utf-8"
+var PCAPContentType = "application/vnd.tcpdump.pcap"
+var TextContentType = "text/plain; charset=utf-8"
+var GzipContentType = "application/gzip"
 
 type MyTokenKey string
 
@@ -19,6 +76,104 @@ var TokenConstant = MyTokenKey("token")
 
 const Limit = 5
 
+// Default sort of the handshakes listing, newest first
+const (
+	DefaultHandshakeSort  = "uploaded_date"
+	DefaultHandshakeOrder = "desc"
+	// NotCrackedSortValue cracked date the handshakes not cracked yet are sorted by, earlier than any other
+	NotCrackedSortValue = "1000-01-01 00:00:00"
+)
+
+// ActivityDays how many days of uploads are shown in the activity of a raspberry pi
+const ActivityDays = 30
+
+// MaxCaptureSize maximum size of a capture uploaded by a daemon, both encrypted and once decompressed
+const MaxCaptureSize int64 = 256 << 20
+
+// MaxHTTPSUploadSize maximum size of an encrypted capture uploaded through the REST API, it is sent in a single request
+const MaxHTTPSUploadSize int64 = 32 << 20
+
+// Offline bundles written by the daemons, see Usecase.ImportRaspberryPIBundle
+const (
+	BundleVersion   = 1
+	BundleManifest  = "manifest.json"
+	BundleSignature = "manifest.sig"
+	// MaxBundleSize maximum size of a bundle, it is imported in a single request
+	MaxBundleSize int64 = 128 << 20
+)
+
+// Formats of the exported captures, see Usecase.ExportHandshakes
+const (
+	PCAPFormat    = "pcap"
+	HC22000Format = "22000"
+	// ExportManifest describes the captures of an export archive, they are named after their handshake
+	ExportManifest = "manifest.json"
+)
+
+// MaxImportSize maximum size of a file imported through Usecase.ImportHandshakes, both as uploaded and once its archives are inflated
+const MaxImportSize int64 = 128 << 20
+
+// MaxPotfileEntries maximum number of potfile entries imported at once, each one matching a handshake derives its PMK
+const MaxPotfileEntries = 10000
+
+// Results of the files imported through Usecase.ImportHandshakes
+const (
+	ImportedResult  = "imported"
+	DuplicateResult = "duplicate"
+	CrackedResult   = "cracked"
+	UnmatchedResult = "unmatched"
+	FailedResult    = "failed"
+)
+
+// Kinds of the directives sent to the daemons, see Usecase.CreateRaspberryPIDirective
+const (
+	DirectiveSchedule       = "schedule"
+	DirectiveAddWatch       = "add_watch"
+	DirectiveRemoveWatch    = "remove_watch"
+	DirectivePurge          = "purge"
+	DirectiveReauthenticate = "reauthenticate"
+	DirectiveUploadLogs     = "upload_logs"
+)
+
+// Statuses of the directives
+const (
+	DirectivePending = "pending"
+	DirectiveApplied = "applied"
+	DirectiveFailed  = "failed"
+)
+
+// Kinds of the revocations, see Usecase.DeleteRaspberryPI and Usecase.DeleteClient
+const (
+	RevokedRaspberryPI = "raspberry_pi"
+	RevokedClient      = "client"
+)
+
+// Roles of the team members, each one allows what the previous ones do.
+// Viewers see the resources of the team, members also assign tasks to its clients and share their resources with it,
+// owners also manage the members and unshare resources
+const (
+	TeamViewer = "viewer"
+	TeamMember = "member"
+	TeamOwner  = "owner"
+)
+
+// Kinds of the resources shareable with a team, see Usecase.ShareResource
+const (
+	SharedClient      = "client"
+	SharedRaspberryPI = "raspberry_pi"
+	SharedHandshake   = "handshake"
+	SharedWordlist    = "wordlist"
+)
+
+// MinDirectiveInterval the daemons refuse to rescan more often
+const MinDirectiveInterval = 10 * time.Second
+
+// MaxDirectiveResultSize results longer than this, e.g. logs, keep their last part only
+const MaxDirectiveResultSize = 1 << 20
+
+// TCPTLSMutual value of TCPTLSMode requiring device certificates
+const TCPTLSMutual = "mtls"
+
 // const for DateTime Format YYYY-MM-DD HH:MM:SS
 
 const DateTimeExample = "2006-01-02 15:04:05"
@@ -42,7 +197,7 @@ var (
 	DBCertUser = os.Getenv("DB_CERT_USER")
 	DBCertPass = os.Getenv("DB_CERT_PASSWORD")
 
-	AllowRegistrations = os.Getenv("ALLOW_REGISTRATIONS")
+	AllowRegistrations = os.Getenv("ALLOW_REGISTRATIONS") // default of SettingRegistrations
 	DebugEnabled       = strings.ToLower(os.Getenv("DEBUG")) == "true"
 	WipeTables         = strings.ToLower(os.Getenv("RESET")) == "true"
 
@@ -55,6 +210,18 @@ var (
 
 	TCPAddress = os.Getenv("TCP_ADDRESS")
 	TCPPort    = os.Getenv("TCP_PORT")
+	// TCPTLSMode empty for plaintext, "tls" for encrypting the channel, "mtls" for also requiring device certificates
+	TCPTLSMode = strings.ToLower(os.Getenv("TCP_TLS"))
+	// TCPAllowPlaintext accepts the user tokens and the unencrypted captures of the daemons speaking the legacy protocol,
+	// as long as they never exchanged a key
+	TCPAllowPlaintext = strings.ToLower(os.Getenv("TCP_ALLOW_PLAINTEXT")) == "true"
+	// UploadDir where chunked uploads of the daemons are stored until committed
+	UploadDir = os.Getenv("UPLOAD_DIR")
+
+	// JWT
+
+	// JwtKeyRotation age after which the JWT signing key is replaced, DefaultKeyRotation when empty
+	JwtKeyRotation = os.Getenv("JWT_KEY_ROTATION")
 )
 
 var HashCost = 12
@@ -62,12 +229,24 @@ var HashCost = 12
 // Role Constants
 type Role string
 
-const RoleString = "role" // still not implemented
+// RoleString claim of the role of the user, the admin scope is granted to ADMIN only
+const RoleString = "role"
 
 // Declare constants of type Role for each role
 const (
 	ADMIN Role = "ADMIN"
 	USER  Role = "USER"
+	// DEVICE tokens are issued to enrolled raspberry pis, they are accepted by the TCP server only
+	DEVICE Role = "DEVICE"
+)
+
+// Qualities of the handshakes uploaded by the daemons, how much of the 4-way handshake is in the capture
+const (
+	QualityComplete = "complete"
+	QualityPartial  = "partial"
+	QualityMissing  = "missing"
+	// QualityUnknown filters the handshakes uploaded from the FE, imported or uploaded by older daemons
+	QualityUnknown = "unknown"
 )
 
 // Statuses for handshake assignments
@@ -75,4 +254,5 @@ const (
 	NothingStatus = "nothing"
 	PendingStatus = "pending"
 	WorkingStatus = "working"
+	CrackedStatus = "cracked"
 )
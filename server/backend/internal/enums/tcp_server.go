@@ -16,8 +16,26 @@ type Command byte
 const (
 	LOGIN Command = iota + 1
 	HANDSHAKE
+	KEYEXCHANGE
+	CERTIFICATE
+	UPLOADBEGIN
+	UPLOADCHUNK
+	UPLOADCOMMIT
+	ENROLL
+	DEVICELOGIN
+	RESULTS
+	DIRECTIVES
+	DIRECTIVEACK
 )
 
 func (c Command) String() string {
-	return [...]string{"LOGIN", "HANDSHAKE"}[c-1]
+	return [...]string{"LOGIN", "HANDSHAKE", "KEYEXCHANGE", "CERTIFICATE", "UPLOADBEGIN", "UPLOADCHUNK", "UPLOADCOMMIT", "ENROLL", "DEVICELOGIN", "RESULTS", "DIRECTIVES", "DIRECTIVEACK"}[c-1]
 }
+
+// ResponseType message types used by the server when answering a frame. Requests use the Command value instead
+type ResponseType byte
+
+const (
+	RESPONSE ResponseType = iota + 0x80
+	ERROR
+)
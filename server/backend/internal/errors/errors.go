@@ -19,6 +19,18 @@ var ErrNotValidClientIP = errors.New("not valid client IP")
 var ErrClientIsBusy = errors.New("client is busy")
 var ErrOldPasswordMismatch = errors.New("old password is not correct")
 var ErrPasswordConfirmationDoNotMatch = errors.New("password confirmation does not match")
+var ErrAlreadyApproved = errors.New("already approved, delete it for refusing it")
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token, login again")
+var ErrTokenRevoked = errors.New("token has been revoked")
+var ErrUnknownSigningKey = errors.New("token signed with an unknown key")
+var ErrTokenAudience = errors.New("token not issued for this channel")
+var ErrMissingScope = errors.New("token lacks the scope")
+var ErrInvalidPersonalAccessToken = errors.New("personal access token does not exist, expired or has been revoked")
+var ErrPersonalAccessTokenScope = errors.New("the scope of the token cannot exceed the role of the user")
+var ErrOwnAccount = errors.New("administrators cannot disable, delete or demote their own account")
+var ErrTeamPermission = errors.New("your role in the team does not allow the operation")
+var ErrLastTeamOwner = errors.New("the team must keep at least an owner, delete the team instead")
+var ErrUserNotFound = errors.New("user not found")
 
 var ErrCertsNotInitialized = errors.New("caCerts not initialized in repository ")
 var ErrFailToGeneratePrivateKey = errors.New("fail to generate private key ")
@@ -30,9 +42,46 @@ var ErrInvalidToken = errors.New("[GRPC]: HashcatChat -> Invalid token -> ")
 var ErrOnUpdateTask = errors.New("[GRPC]: HashcatChat -> Cannot update client task -> ")
 var ErrCannotAnswerToClient = errors.New("[GRPC]: HashcatChat -> Cannot reply to the client -> ")
 var ErrGetHandshakeStatus = errors.New("[GRPC]: HashcatChat GetHandshakesByStatus -> ")
+var ErrClientRevoked = errors.New("the client has been deleted, approve it again from the revocations page before reconnecting")
+var ErrClientPendingApproval = errors.New("the client is waiting for approval, approve it from the clients page")
 
 // Daemon
 var ErrHandshakeAlreadyPresent = errors.New("error creating handshake: handshake already present")
+var ErrRaspberryPINotEnrolled = errors.New("raspberry pi has not exchanged an encryption key yet")
+var ErrRaspberryPIOwnedByAnotherUser = errors.New("raspberry pi is registered to another user")
+var ErrHandshakeDecryption = errors.New("handshake payload failed authentication")
+var ErrUnknownCommand = errors.New("unknown command")
+var ErrTLSRequired = errors.New("certificates can be enrolled only over TLS")
+var ErrDeviceCertificateRequired = errors.New("a device certificate is required, enroll one with CERTIFICATE")
+var ErrMessageTooLarge = errors.New("message exceeds the maximum allowed size")
+var ErrUploadNotFound = errors.New("upload not found, begin it again")
+var ErrUploadTooLarge = errors.New("upload exceeds the maximum allowed size")
+var ErrUploadIncomplete = errors.New("upload is not complete")
+var ErrUploadChecksum = errors.New("upload checksum mismatch, begin it again")
+var ErrDeviceTokenNotAllowed = errors.New("device tokens can be used only for uploading captures")
+var ErrDeviceTokenMismatch = errors.New("the token was not issued for this device")
+var ErrDeviceCredentialInvalid = errors.New("device credential is not valid, enroll the device again")
+var ErrDeviceCertificateMismatch = errors.New("the certificate presented was not issued for this device")
+var ErrClientCertificateMismatch = errors.New("the certificate presented was not issued for this client")
+var ErrDeviceTokenRequired = errors.New("a device token is required, log in with the device credential")
+var ErrDeviceCertificateOnlyTCP = errors.New("the server requires device certificates, connect through the TCP server")
+var ErrBundleInvalid = errors.New("the file is not a bundle written by the daemon")
+var ErrBundleCaptureAltered = errors.New("the capture is missing from the bundle or it has been altered")
+var ErrBundleSignature = errors.New("the bundle signature does not match the key of the device, it may have been written before its last key exchange")
+var ErrDirectiveInvalid = errors.New("invalid directive")
+var ErrDirectiveNotPending = errors.New("the directive has already been acknowledged by the device or it does not exist")
+var ErrRaspberryPIRevoked = errors.New("the raspberry pi has been deleted, approve it again from the revocations page before reconnecting")
+var ErrRaspberryPIPendingApproval = errors.New("the raspberry pi is waiting for approval, approve it from the devices page")
+var ErrInvalidCursor = errors.New("invalid cursor, request the first page again")
+var ErrCaptureFormat = errors.New("the capture is neither a pcap nor a pcapng file")
+var ErrNoCrackableHandshake = errors.New("the capture holds neither a PMKID nor an EAPOL message pair hashcat can crack")
+var ErrImportInvalid = errors.New("the archive can't be read, import a tar, tar.gz, zip or a single file")
+var ErrImportFileKind = errors.New("the file is neither a pcap, a pcapng, a 22000 hash file nor a hashcat potfile")
+var ErrNoMatchingHandshake = errors.New("no handshake of the network is waiting to be cracked")
+var ErrPotfilePMKMismatch = errors.New("the password of the potfile entry doesn't derive its PMK")
+var ErrPasswordNotCracking = errors.New("the password doesn't crack the handshake")
+var ErrPasswordUnverifiable = errors.New("the capture has no hash the password can be checked against")
+var ErrTooManyPotfileEntries = errors.New("too many potfile entries in a single import, import the potfile in smaller parts")
 
 // SQL
 const (
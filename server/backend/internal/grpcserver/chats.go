@@ -0,0 +1,91 @@
+package grpcserver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// chat a HashcatTaskChat stream. Plaintext clients are known only once they send a message, the others by their certificate
+type chat struct {
+	clientUUID string
+	certified  bool // clientUUID is the subject of the verified certificate, the messages can't act for another client
+	cancel     context.CancelCauseFunc
+}
+
+// chatRegistry the HashcatTaskChat streams open, so that the ones of a revoked client can be closed
+type chatRegistry struct {
+	mu    sync.Mutex
+	chats map[*chat]struct{}
+}
+
+func newChatRegistry() *chatRegistry {
+	return &chatRegistry{
+		chats: make(map[*chat]struct{}),
+	}
+}
+
+// open registers a stream, the returned context is canceled when the client is revoked
+func (r *chatRegistry) open(ctx context.Context) (context.Context, *chat) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	c := &chat{cancel: cancel}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, isTLS := p.AuthInfo.(credentials.TLSInfo); isTLS && len(tlsInfo.State.PeerCertificates) > 0 {
+			c.clientUUID, c.certified = tlsInfo.State.PeerCertificates[0].Subject.SerialNumber, true
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chats[c] = struct{}{}
+
+	return ctx, c
+}
+
+func (r *chatRegistry) close(c *chat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.chats, c)
+	c.cancel(nil)
+}
+
+// identify records the client the stream belongs to, as declared by its messages. The streams opened with a certificate
+// belong to its client, ErrClientCertificateMismatch when a message declares another one
+func (r *chatRegistry) identify(c *chat, clientUUID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c.certified {
+		if clientUUID != c.clientUUID {
+			return customErrors.ErrClientCertificateMismatch
+		}
+		return nil
+	}
+
+	c.clientUUID = clientUUID
+	return nil
+}
+
+// dropRevoked closes the streams of a revoked client
+func (r *chatRegistry) dropRevoked(revocation *entities.Revocation) {
+	if revocation.Kind != constants.RevokedClient {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for c := range r.chats {
+		if c.clientUUID == revocation.EntityUUID {
+			log.Warnf("[GRPC]: Closing HashcatChat of revoked client %s", revocation.EntityUUID)
+			c.cancel(customErrors.ErrClientRevoked)
+		}
+	}
+}
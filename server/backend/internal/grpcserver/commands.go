@@ -29,7 +29,7 @@ func (s *ServerContext) Test(_ context.Context, _ *pb.HelloRequest) (*pb.HelloRe
 }
 
 // Login implements the behavior for Login gRPC method
-func (s *ServerContext) Login(_ context.Context, request *pb.AuthRequest) (*pb.UniformResponse, error) {
+func (s *ServerContext) Login(_ context.Context, request *pb.AuthRequest) (*pb.AuthResponse, error) {
 	user, role, err := s.Usecase.GetUserByUsername(request.GetUsername())
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "%v", err)
@@ -41,15 +41,36 @@ func (s *ServerContext) Login(_ context.Context, request *pb.AuthRequest) (*pb.U
 		return nil, status.Errorf(codes.NotFound, "%s", customErrors.ErrInvalidCredentials)
 	}
 
-	// Create the auth token
-	token, err := s.Usecase.CreateAuthToken(user.UserUUID, role.RoleString)
+	// Create the access and refresh tokens
+	session, err := s.Usecase.CreateSession(user.UserUUID, role.RoleString, constants.AudienceClient)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
 
-	return &pb.UniformResponse{
-		Status:  "logged_in",
-		Details: token,
+	return &pb.AuthResponse{
+		Status:       "logged_in",
+		Details:      session.Details,
+		RefreshToken: session.RefreshToken,
+		ExpiresIn:    session.ExpiresIn,
+	}, nil
+}
+
+// RefreshToken implements the behavior for RefreshToken gRPC method
+func (s *ServerContext) RefreshToken(_ context.Context, request *pb.RefreshTokenRequest) (*pb.AuthResponse, error) {
+	session, err := s.Usecase.RefreshSession(request.GetRefreshToken(), constants.AudienceClient)
+	if errors.Is(err, customErrors.ErrInvalidRefreshToken) {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+
+	return &pb.AuthResponse{
+		Status:       "refreshed",
+		Details:      session.Details,
+		RefreshToken: session.RefreshToken,
+		ExpiresIn:    session.ExpiresIn,
 	}, nil
 }
 
@@ -66,7 +87,7 @@ func (s *ServerContext) GetClientInfo(ctx context.Context, request *pb.GetClient
 
 	remoteIP := p.Addr.String()
 
-	data, err := s.Usecase.GetDataFromToken(jwt)
+	data, err := s.Usecase.GetDataFromToken(jwt, constants.ScopeClientTasks)
 
 	if err != nil {
 		return nil, err
@@ -75,53 +96,33 @@ func (s *ServerContext) GetClientInfo(ctx context.Context, request *pb.GetClient
 	userID := data[constants.UserIDKey].(string)
 
 	client, err := s.Usecase.GetClientInfo(userID, machineUUID)
+	isRegistered := err == nil
 
-	if err != nil {
-		// We can create a new client since it does not exist
-		if errors.Is(err, customErrors.ErrNoClientFound) {
-			newID, errClientCreation := s.Usecase.CreateClient(userID, machineUUID, remoteIP, name)
+	// We can create a new client since it does not exist, its certificate is signed once the user approves it
+	if errors.Is(err, customErrors.ErrNoClientFound) {
+		_, errClientCreation := s.Usecase.CreateClient(userID, machineUUID, remoteIP, name)
 
-			if errClientCreation != nil {
-				return nil, errClientCreation
-			}
-
-			caCert, caKey, _, _, err := s.Usecase.GetServerCerts()
-
-			if err != nil {
-				return nil, err
-			}
+		if errors.Is(errClientCreation, customErrors.ErrClientRevoked) {
+			return nil, status.Errorf(codes.PermissionDenied, "%v", errClientCreation)
+		}
 
-			// sign certs
-			clientCert, clientKey, err := s.Usecase.SignCert(caCert, caKey, newID)
-			if err != nil {
-				return nil, err
-			}
+		if errClientCreation != nil {
+			return nil, errClientCreation
+		}
 
-			_, err = s.Usecase.CreateCertForClient(userID, newID, clientCert, clientKey)
-			if err != nil {
-				return nil, err
-			}
+		client, err = s.Usecase.GetClientInfo(userID, machineUUID)
+	}
 
-			// client created
-			return &pb.GetClientInfoResponse{
-				IsRegistered:       false,
-				UserUuid:           userID,
-				ClientUuid:         newID,
-				Name:               name,
-				LatestIp:           remoteIP,
-				CreationTime:       time.Now().Format(constants.DateTimeExample),
-				LastConnectionTime: time.Now().Format(constants.DateTimeExample),
-				MachineId:          machineUUID,
-				EnabledEncryption:  false, // on creation this cannot be true
-			}, nil
-		}
-		// otherwise return the error
+	if err != nil {
 		return nil, err
 	}
 
-	// if here the client exists and no previous errors have been found from the query
+	if !client.Approved {
+		log.Infof("[GRPC]: Client %s is waiting for approval with code %s", client.ClientUUID, client.EnrollmentCode)
+	}
+
 	return &pb.GetClientInfoResponse{
-		IsRegistered:       true,
+		IsRegistered:       isRegistered,
 		UserUuid:           client.UserUUID,
 		ClientUuid:         client.ClientUUID,
 		Name:               client.Name,
@@ -130,11 +131,17 @@ func (s *ServerContext) GetClientInfo(ctx context.Context, request *pb.GetClient
 		LastConnectionTime: client.LatestConnectionTime,
 		MachineId:          client.MachineID,
 		EnabledEncryption:  client.EnabledEncryption,
+		Approved:           client.Approved,
+		EnrollmentCode:     client.EnrollmentCode,
 	}, nil
 }
 
 func (s *ServerContext) HashcatTaskChat(stream pb.HDSTemplateService_HashcatTaskChatServer) error {
-	errChannel := make(chan error, 1) // Buffered channel to avoid blocking
+	errChannel := make(chan error, 2) // Buffered channel to avoid blocking
+
+	// closed when the client is revoked
+	ctx, current := s.chats.open(stream.Context())
+	defer s.chats.close(current)
 
 	/*
 		Here is the logic for this part:
@@ -142,7 +149,7 @@ func (s *ServerContext) HashcatTaskChat(stream pb.HDSTemplateService_HashcatTask
 		- We send a message to all clients and if the uuid matches, then the client will reply to the server updating the status
 	*/
 	go func() {
-		if err := s.sendTasksToClients(stream); err != nil {
+		if err := s.sendTasksToClients(ctx, stream); err != nil {
 			errChannel <- err
 		}
 	}()
@@ -151,20 +158,32 @@ func (s *ServerContext) HashcatTaskChat(stream pb.HDSTemplateService_HashcatTask
 		- The client will start the cracking process
 		- The client will update the status and hashcat logs once cracking has started
 	*/
-	if err := s.listenToTasksFromClient(stream); err != nil {
+	go func() {
+		errChannel <- s.listenToTasksFromClient(current, stream)
+	}()
+
+	select {
+	case err := <-errChannel:
 		return err
+	case <-ctx.Done():
+		if cause := context.Cause(ctx); errors.Is(cause, customErrors.ErrClientRevoked) {
+			return status.Errorf(codes.PermissionDenied, "%v", cause)
+		}
+		return <-errChannel
 	}
-
-	return <-errChannel
 }
 
 // sendTasksToClients sends all pending tasks to clients, the client will recognize the assignment by its clientID
-func (s *ServerContext) sendTasksToClients(stream pb.HDSTemplateService_HashcatTaskChatServer) error {
+func (s *ServerContext) sendTasksToClients(ctx context.Context, stream pb.HDSTemplateService_HashcatTaskChatServer) error {
 	ticker := time.NewTicker(1 * time.Second) // Do not flood client. Update tasks every second
 	defer ticker.Stop()
 
 	for {
-		<-ticker.C // blocking channel
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
 
 		handshakes, err := s.getPendingHandshakes()
 		if err != nil {
@@ -252,8 +271,9 @@ func (s *ServerContext) updateTaskStatuses(tasks []*pb.ClientTask) error {
 	return nil
 }
 
-// listenToTasksFromClient updates dynamically the coming information from the client. Useful for fast hashcat logs transmission
-func (s *ServerContext) listenToTasksFromClient(stream pb.HDSTemplateService_HashcatTaskChatServer) error {
+// listenToTasksFromClient updates dynamically the coming information from the client. Useful for fast hashcat logs transmission.
+// Messages of revoked clients close the stream
+func (s *ServerContext) listenToTasksFromClient(current *chat, stream pb.HDSTemplateService_HashcatTaskChatServer) error {
 	for {
 		// Receive message from client
 		msg, err := stream.Recv()
@@ -262,18 +282,36 @@ func (s *ServerContext) listenToTasksFromClient(stream pb.HDSTemplateService_Has
 			if status.Code(err) == codes.Canceled {
 				return status.Errorf(codes.NotFound, "%v", customErrors.ErrGRPCClosedConnection.Error())
 			}
+			// tokens refused by the scope interceptor
+			if code := status.Code(err); code == codes.PermissionDenied || code == codes.Unauthenticated {
+				return err
+			}
 			return status.Errorf(codes.Unknown, "%v", fmt.Sprintf("%s %v", customErrors.ErrGRPCFailedToReceive, err))
 		}
 
 		log.Printf("[GRPC]: HashcatChat ->Received from client: %v", msg)
 
 		// Process the received message
-		data, err := s.Usecase.GetDataFromToken(msg.GetJwt())
+		data, err := s.Usecase.GetDataFromToken(msg.GetJwt(), constants.ScopeClientTasks)
 		if err != nil {
 			return status.Errorf(codes.Unauthenticated, "%v", fmt.Sprintf("%s %v", customErrors.ErrInvalidToken, err))
 		}
 
+		if err = s.chats.identify(current, msg.GetClientUuid()); err != nil {
+			return status.Errorf(codes.PermissionDenied, "%v", err)
+		}
+
+		if err = s.Usecase.EnsureNotRevoked(msg.GetClientUuid()); err != nil {
+			return status.Errorf(codes.PermissionDenied, "%v", err)
+		}
+
 		userID := data[constants.UserIDKey].(string)
+
+		// clients unknown to the user are reported by UpdateClientTask
+		if err = s.Usecase.EnsureClientApproved(userID, msg.GetClientUuid()); errors.Is(err, customErrors.ErrClientPendingApproval) {
+			return status.Errorf(codes.FailedPrecondition, "%v", err)
+		}
+
 		_, err = s.Usecase.UpdateClientTask(
 			userID,
 			msg.GetHandshakeUuid(),
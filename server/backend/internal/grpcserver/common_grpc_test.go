@@ -66,18 +66,19 @@ func (s *GRPCServerTestSuite) SetupSuite() {
 	// assign generated clientID
 	s.UserClientRegistered.ClientUUID = clientID
 
-	caCert, caKey, _, _, err := s.Service.Usecase.GetServerCerts()
-	s.Require().NoError(err)
-
-	// sign certs
-	clientCert, clientKey, err := s.Service.Usecase.SignCert(caCert, caKey, clientID)
-	s.Require().NoError(err)
+	// the approval signs its certs
+	s.Require().NoError(s.Service.Usecase.ApproveClient(s.UserFixture.UserUUID, clientID, true))
 
-	_, err = s.Service.Usecase.CreateCertForClient(s.UserFixture.UserUUID, clientID, clientCert, clientKey)
+	certs, _, err := s.Service.Usecase.GetClientCertsByUserID(s.UserFixture.UserUUID)
 	s.Require().NoError(err)
 
-	s.UserClientRegistered.clientCert = clientCert
-	s.UserClientRegistered.clientKey = clientKey
+	for _, cert := range certs {
+		if cert.ClientUUID == clientID {
+			s.UserClientRegistered.clientCert = []byte(cert.ClientCert)
+			s.UserClientRegistered.clientKey = []byte(cert.ClientKey)
+		}
+	}
+	s.Require().NotNil(s.UserClientRegistered.clientCert)
 
 	// this instead is a client unregistered for testing purposes
 	s.UserClientUnregistered = &entities.Client{
@@ -8,12 +8,17 @@ import (
 
 type ServerContext struct {
 	Usecase *usecaseHandler.Usecase
+	chats   *chatRegistry
 	pb.UnimplementedHDSTemplateServiceServer
 }
 
-// NewServerContext Inject context into GRPC SERVER
+// NewServerContext Inject context into GRPC SERVER, the task chats of the clients revoked from now on are closed
 func NewServerContext(usecase *usecaseHandler.Usecase) *ServerContext {
+	chats := newChatRegistry()
+	usecase.OnRevocation(chats.dropRevoked)
+
 	return &ServerContext{
 		Usecase: usecase,
+		chats:   chats,
 	}
 }
@@ -4,11 +4,22 @@ package grpcserver_test
 import (
 	"context"
 	_ "context"
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/repository"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
 	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
 	"time"
 	_ "time"
 
@@ -53,7 +64,7 @@ func (s *GRPCServerTestSuite) Test_GRPC_Login() {
 	tests := []struct {
 		testname       string
 		request        *pb.AuthRequest
-		expectedOutput func(resp *pb.UniformResponse, err error) bool
+		expectedOutput func(resp *pb.AuthResponse, err error) bool
 	}{
 		{
 			testname: "Valid username",
@@ -61,9 +72,9 @@ func (s *GRPCServerTestSuite) Test_GRPC_Login() {
 				Username: s.UserFixture.Username,
 				Password: s.UserFixture.Password,
 			},
-			expectedOutput: func(resp *pb.UniformResponse, err error) bool {
+			expectedOutput: func(resp *pb.AuthResponse, err error) bool {
 				s.Require().NoError(err, "Test RPC failed")
-				return resp.Details != "" && resp.Status == "logged_in"
+				return resp.Details != "" && resp.Status == "logged_in" && resp.RefreshToken != "" && resp.ExpiresIn > 0
 			},
 		},
 		{
@@ -72,7 +83,7 @@ func (s *GRPCServerTestSuite) Test_GRPC_Login() {
 				Username: "test",
 				Password: s.UserFixture.Password,
 			},
-			expectedOutput: func(resp *pb.UniformResponse, err error) bool {
+			expectedOutput: func(resp *pb.AuthResponse, err error) bool {
 				s.Require().Contains(err.Error(), "invalid credentials", "invalid credentials fail")
 				return true
 			},
@@ -83,7 +94,7 @@ func (s *GRPCServerTestSuite) Test_GRPC_Login() {
 				Username: s.UserFixture.Username,
 				Password: utils.GenerateToken(10),
 			},
-			expectedOutput: func(resp *pb.UniformResponse, err error) bool {
+			expectedOutput: func(resp *pb.AuthResponse, err error) bool {
 				s.Require().Contains(err.Error(), "invalid credentials", "invalid credentials fail")
 				return true
 			},
@@ -105,6 +116,331 @@ func (s *GRPCServerTestSuite) Test_GRPC_Login() {
 	}
 }
 
+func (s *GRPCServerTestSuite) Test_GRPC_RefreshToken() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	login, err := s.Client.Login(ctx, &pb.AuthRequest{
+		Username: s.UserFixture.Username,
+		Password: s.UserFixture.Password,
+	})
+	s.Require().NoError(err)
+
+	s.Run("Refresh token exchanged for new tokens", func() {
+		refreshed, err := s.Client.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+		s.Require().NoError(err)
+		s.Require().Equal("refreshed", refreshed.Status)
+		s.Require().NotEqual(login.RefreshToken, refreshed.RefreshToken)
+		s.Require().Equal(int64(constants.AccessTokenDuration.Seconds()), refreshed.ExpiresIn)
+
+		valid, err := s.Service.Usecase.ValidateToken(refreshed.Details, constants.ScopeClientTasks)
+		s.Require().NoError(err)
+		s.Require().True(valid)
+	})
+
+	s.Run("Refresh token reused within the reuse window", func() {
+		refreshed, err := s.Client.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+		s.Require().NoError(err)
+		s.Require().NotEqual(login.RefreshToken, refreshed.RefreshToken)
+	})
+
+	s.Run("Refresh token used twice", func() {
+		s.endReuseWindow(s.UserFixture.UserUUID)
+
+		_, err := s.Client.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+		s.Require().Equal(codes.Unauthenticated, status.Code(err))
+		s.Require().Contains(err.Error(), customErrors.ErrInvalidRefreshToken.Error())
+	})
+
+	s.Run("Unknown refresh token", func() {
+		_, err := s.Client.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: utils.GenerateToken(64)})
+		s.Require().Equal(codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func (s *GRPCServerTestSuite) Test_SigningKeys() {
+	token, err := s.Service.Usecase.CreateAuthToken(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceFE, "")
+	s.Require().NoError(err)
+
+	// another instance sharing the database, as the backend after a restart
+	rr, err := repository.NewRepository(s.DatabaseUser, s.DatabaseCert)
+	s.Require().NoError(err)
+	restarted := usecase.NewUsecase(rr)
+	s.Require().NoError(restarted.LoadSigningKeys())
+
+	s.Run("Tokens survive a restart", func() {
+		valid, err := restarted.ValidateToken(token, constants.ScopeFE)
+		s.Require().NoError(err)
+		s.Require().True(valid)
+	})
+
+	s.Run("Tokens signed before a rotation stay valid", func() {
+		s.Require().NoError(s.Service.Usecase.RotateSigningKey())
+
+		valid, err := s.Service.Usecase.ValidateToken(token, constants.ScopeFE)
+		s.Require().NoError(err)
+		s.Require().True(valid)
+
+		rotated, err := s.Service.Usecase.CreateAuthToken(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceFE, "")
+		s.Require().NoError(err)
+		s.Require().NotEqual(tokenKID(token), tokenKID(rotated))
+
+		// the key is not cached by the other instance yet
+		valid, err = restarted.ValidateToken(rotated, constants.ScopeFE)
+		s.Require().NoError(err)
+		s.Require().True(valid)
+	})
+
+	s.Run("Keys loaded again once per interval for unknown keys", func() {
+		s.Require().NoError(s.Service.Usecase.RotateSigningKey())
+
+		rotated, err := s.Service.Usecase.CreateAuthToken(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceFE, "")
+		s.Require().NoError(err)
+
+		// the other instance loaded its keys for the previous rotation moments ago
+		_, err = restarted.ValidateToken(rotated, constants.ScopeFE)
+		s.Require().ErrorIs(err, customErrors.ErrUnknownSigningKey)
+	})
+
+	s.Run("Token signed with an unknown key", func() {
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			constants.UserIDKey:  s.UserFixture.UserUUID,
+			constants.RoleString: string(constants.ADMIN),
+			constants.TokenIDKey: uuid.New().String(),
+			"exp":                time.Now().Add(time.Hour).Unix(),
+		})
+		forged.Header[constants.KeyIDHeader] = uuid.New().String()
+		signed, err := forged.SignedString([]byte(utils.GenerateToken(64)))
+		s.Require().NoError(err)
+
+		_, err = s.Service.Usecase.ValidateToken(signed, constants.ScopeFE)
+		s.Require().ErrorIs(err, customErrors.ErrUnknownSigningKey)
+	})
+
+	s.Run("Key rotated once older than the rotation age", func() {
+		s.Require().NoError(s.Service.Usecase.MaintainTokens(constants.DefaultKeyRotation))
+		notRotated, err := s.Service.Usecase.CreateAuthToken(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceFE, "")
+		s.Require().NoError(err)
+
+		// the creation date is rounded to the second
+		time.Sleep(time.Second)
+		s.Require().NoError(s.Service.Usecase.MaintainTokens(time.Millisecond))
+		rotated, err := s.Service.Usecase.CreateAuthToken(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceFE, "")
+		s.Require().NoError(err)
+		s.Require().NotEqual(tokenKID(notRotated), tokenKID(rotated))
+	})
+
+	s.Run("Expired revocations pruned", func() {
+		_, err := s.DatabaseUser.Exec(
+			fmt.Sprintf("INSERT INTO %s(uuid, expires_date) VALUES(?,?)", entities.RevokedTokenTableName),
+			uuid.New().String(), time.Now().Add(-time.Minute).UTC(),
+		)
+		s.Require().NoError(err)
+
+		s.Require().NoError(s.Service.Usecase.MaintainTokens(constants.DefaultKeyRotation))
+
+		var count int
+		s.Require().NoError(s.DatabaseUser.QueryRow(
+			fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE expires_date <= ?", entities.RevokedTokenTableName),
+			time.Now().UTC(),
+		).Scan(&count))
+		s.Require().Zero(count)
+	})
+
+	s.Run("Used refresh tokens pruned after the reuse window", func() {
+		session, err := s.Service.Usecase.CreateSession(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceFE)
+		s.Require().NoError(err)
+
+		_, err = s.Service.Usecase.RefreshSession(session.RefreshToken, constants.AudienceFE)
+		s.Require().NoError(err)
+		s.endReuseWindow(s.UserFixture.UserUUID)
+
+		s.Require().NoError(s.Service.Usecase.MaintainTokens(constants.DefaultKeyRotation))
+
+		var count int
+		s.Require().NoError(s.DatabaseUser.QueryRow(
+			fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE used_date IS NOT NULL", entities.RefreshTokenTableName),
+		).Scan(&count))
+		s.Require().Zero(count)
+	})
+}
+
+// endReuseWindow moves the first use of the refresh tokens used by the user before the reuse window
+func (s *GRPCServerTestSuite) endReuseWindow(userUUID string) {
+	_, err := s.DatabaseUser.Exec(
+		fmt.Sprintf("UPDATE %s SET used_date = ? WHERE uuid_user = ? AND used_date IS NOT NULL", entities.RefreshTokenTableName),
+		time.Now().Add(-constants.RefreshTokenReuseWindow-time.Second).UTC(), userUUID,
+	)
+	s.Require().NoError(err)
+}
+
+func (s *GRPCServerTestSuite) Test_RefreshAndLogout() {
+	session, err := testsuite.SessionAPI(entities.AuthRequest{
+		Username: s.NormalUserFixture.Username,
+		Password: s.NormalUserFixture.Password,
+	})
+	s.Require().NoError(err)
+	s.Require().NotEmpty(session.RefreshToken)
+	s.Require().Equal(int64(constants.AccessTokenDuration.Seconds()), session.ExpiresIn)
+
+	refresh := func(refreshToken string) (*entities.AuthResponse, error) {
+		marshaled, err := json.Marshal(&entities.RefreshTokenRequest{RefreshToken: refreshToken})
+		s.Require().NoError(err)
+
+		response, err := testsuite.HTTPRequest(http.MethodPost, testsuite.APIREFRESH, map[string]string{}, url.Values{}, marshaled, 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		var refreshed entities.AuthResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &refreshed))
+		return &refreshed, nil
+	}
+
+	s.Run("Refresh token rotated", func() {
+		refreshed, err := refresh(session.RefreshToken)
+		s.Require().NoError(err)
+		s.Require().NotEqual(session.RefreshToken, refreshed.RefreshToken)
+
+		s.endReuseWindow(s.NormalUserFixture.UserUUID)
+		_, err = refresh(session.RefreshToken)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnauthorized))
+
+		session = refreshed
+	})
+
+	// the pages requested together once the access token expired all renew the session with the same refresh token
+	s.Run("Parallel refreshes", func() {
+		type outcome struct {
+			refreshed *entities.AuthResponse
+			err       error
+		}
+
+		outcomes := make(chan outcome, 2)
+		for range 2 {
+			go func() {
+				refreshed, err := refresh(session.RefreshToken)
+				outcomes <- outcome{refreshed, err}
+			}()
+		}
+
+		var refreshed []*entities.AuthResponse
+		for range 2 {
+			result := <-outcomes
+			s.Require().NoError(result.err)
+			refreshed = append(refreshed, result.refreshed)
+		}
+		s.Require().NotEqual(refreshed[0].RefreshToken, refreshed[1].RefreshToken)
+
+		// whichever cookies the browser keeps, the session goes on
+		for _, tokens := range refreshed {
+			valid, err := s.Service.Usecase.ValidateToken(tokens.Details, constants.ScopeFE)
+			s.Require().NoError(err)
+			s.Require().True(valid)
+		}
+
+		next, err := refresh(refreshed[0].RefreshToken)
+		s.Require().NoError(err)
+		_, err = refresh(refreshed[1].RefreshToken)
+		s.Require().NoError(err)
+
+		session = next
+	})
+
+	s.Run("Logout revokes the token and its session", func() {
+		_, err := testsuite.HTTPRequest(http.MethodGet, testsuite.APILOGOUT, map[string]string{
+			"Authorization": "Bearer " + session.Details,
+		}, url.Values{}, nil, 10*time.Second)
+		s.Require().NoError(err)
+
+		_, err = s.Service.Usecase.ValidateToken(session.Details, constants.ScopeFE)
+		s.Require().ErrorIs(err, customErrors.ErrTokenRevoked)
+
+		_, err = refresh(session.RefreshToken)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnauthorized))
+
+		// other sessions of the user are still valid
+		valid, err := s.Service.Usecase.ValidateToken(s.NormalUserTokenFixture, constants.ScopeClientTasks)
+		s.Require().NoError(err)
+		s.Require().True(valid)
+	})
+}
+
+func (s *GRPCServerTestSuite) Test_TokenScopes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	feSession, err := testsuite.SessionAPI(entities.AuthRequest{
+		Username: s.NormalUserFixture.Username,
+		Password: s.NormalUserFixture.Password,
+	})
+	s.Require().NoError(err)
+
+	verify := func(token string) error {
+		_, err := testsuite.HTTPRequest(http.MethodGet, testsuite.APIVERIFY, map[string]string{
+			"Authorization": "Bearer " + token,
+		}, url.Values{}, nil, 10*time.Second)
+		return err
+	}
+
+	s.Run("FE token refused by gRPC", func() {
+		_, err := s.Client.GetClientInfo(ctx, &pb.GetClientInfoRequest{
+			Jwt:       feSession.Details,
+			MachineId: s.UserClientUnregistered.MachineID,
+			Name:      s.UserClientUnregistered.Name,
+		})
+		s.Require().Equal(codes.PermissionDenied, status.Code(err))
+		s.Require().Contains(err.Error(), customErrors.ErrTokenAudience.Error())
+	})
+
+	s.Run("Client token refused by the FE routes", func() {
+		s.Require().ErrorContains(verify(s.NormalUserTokenFixture), fmt.Sprintf("%d", http.StatusForbidden))
+		s.Require().NoError(verify(feSession.Details))
+	})
+
+	s.Run("Daemon token refused by the FE routes", func() {
+		daemonToken, err := s.Service.Usecase.CreateAuthToken(s.NormalUserFixture.UserUUID, string(constants.USER), constants.AudienceDaemon, "")
+		s.Require().NoError(err)
+		s.Require().ErrorContains(verify(daemonToken), fmt.Sprintf("%d", http.StatusForbidden))
+	})
+
+	s.Run("Client refresh token refused by the FE", func() {
+		login, err := s.Client.Login(ctx, &pb.AuthRequest{
+			Username: s.NormalUserFixture.Username,
+			Password: s.NormalUserFixture.Password,
+		})
+		s.Require().NoError(err)
+
+		marshaled, err := json.Marshal(&entities.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+		s.Require().NoError(err)
+		_, err = testsuite.HTTPRequest(http.MethodPost, testsuite.APIREFRESH, map[string]string{}, url.Values{}, marshaled, 10*time.Second)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnauthorized))
+	})
+
+	s.Run("Admin scope granted to administrators only", func() {
+		s.Require().ErrorIs(s.Service.Usecase.RequireScope(feSession.Details, constants.ScopeAdmin), customErrors.ErrMissingScope)
+
+		adminToken, err := s.Service.Usecase.CreateAuthToken(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceFE, "")
+		s.Require().NoError(err)
+		s.Require().NoError(s.Service.Usecase.RequireScope(adminToken, constants.ScopeAdmin))
+
+		clientToken, err := s.Service.Usecase.CreateAuthToken(s.UserFixture.UserUUID, string(constants.ADMIN), constants.AudienceClient, "")
+		s.Require().NoError(err)
+		s.Require().ErrorIs(s.Service.Usecase.RequireScope(clientToken, constants.ScopeAdmin), customErrors.ErrTokenAudience)
+	})
+}
+
+// tokenKID the signing key a token is signed with
+func tokenKID(token string) string {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+
+	kid, _ := parsed.Header[constants.KeyIDHeader].(string)
+	return kid
+}
+
 func (s *GRPCServerTestSuite) Test_GetClientInfo_Method() {
 
 	// Connect to the gRPC server
@@ -340,3 +676,179 @@ func (s *GRPCServerTestSuite) Test_HashcatMessageService_UpdateClientTaskSuccess
 		s.Require().NoError(err, "Failed to send request to the server")
 	})
 }
+
+func (s *GRPCServerTestSuite) Test_RevokedClient() {
+	client := s.Client
+	machineID := utils.GenerateToken(32)
+	ssid, bssid := utils.GenerateToken(10), "XX:XX:XX:XX:XX:XX"
+
+	info := func() (*pb.GetClientInfoResponse, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		return client.GetClientInfo(ctx, &pb.GetClientInfoRequest{
+			Jwt:       s.UserTokenFixture,
+			MachineId: machineID,
+			Name:      "REVOKED",
+		})
+	}
+
+	registered, err := info()
+	s.Require().NoError(err)
+	s.Require().NoError(s.Service.Usecase.ApproveClient(s.UserFixture.UserUUID, registered.GetClientUuid(), true))
+
+	handshakeID, err := s.Service.Usecase.CreateHandshake(s.UserFixture.UserUUID, ssid, bssid, constants.NothingStatus, utils.StringToBase64String("test.pcap"))
+	s.Require().NoError(err)
+	_, err = s.Service.Usecase.UpdateClientTask(s.UserFixture.UserUUID, handshakeID, registered.GetClientUuid(), constants.WorkingStatus, "", "", "")
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	stream, err := client.HashcatTaskChat(ctx)
+	s.Require().NoError(err, "Stream initialization failed")
+
+	s.Require().NoError(stream.Send(&pb.ClientTaskMessageFromClient{
+		Jwt:           s.UserTokenFixture,
+		HandshakeUuid: handshakeID,
+		ClientUuid:    registered.GetClientUuid(),
+		Status:        constants.WorkingStatus,
+		HashcatLogs:   "started",
+	}))
+
+	// the stream is known to belong to the client once its message has been processed
+	s.Require().Eventually(func() bool {
+		handshakes, _, errHandshakes := s.Service.Usecase.GetHandshakesByBSSIDAndSSID(s.UserFixture.UserUUID, bssid, ssid)
+		return errHandshakes == nil && len(handshakes) == 1 && handshakes[0].HashcatLogs != nil && *handshakes[0].HashcatLogs == "started"
+	}, 10*time.Second, 100*time.Millisecond)
+
+	deleted, err := s.Service.Usecase.DeleteClient(s.UserFixture.UserUUID, registered.GetClientUuid())
+	s.Require().NoError(err)
+	s.Require().True(deleted)
+
+	s.Run("The task chat of the revoked client is closed", func() {
+		for {
+			_, err := stream.Recv()
+			if err != nil {
+				s.Require().Equal(codes.PermissionDenied, status.Code(err), err.Error())
+				break
+			}
+		}
+	})
+
+	s.Run("Revoked clients are not registered again", func() {
+		_, err := info()
+		s.Require().Equal(codes.PermissionDenied, status.Code(err))
+		s.Require().Contains(err.Error(), customErrors.ErrClientRevoked.Error())
+
+		s.Require().Error(s.Service.Usecase.EnsureNotRevoked(registered.GetClientUuid()))
+	})
+
+	s.Run("Approved clients are registered again", func() {
+		revocations, _, err := s.Service.Usecase.GetRevocations(s.UserFixture.UserUUID, 1)
+		s.Require().NoError(err)
+		s.Require().NotEmpty(revocations)
+		s.Require().Equal(registered.GetClientUuid(), revocations[0].EntityUUID)
+		s.Require().NoError(s.Service.Usecase.ApproveRevocation(s.UserFixture.UserUUID, revocations[0].UUID))
+
+		resp, err := info()
+		s.Require().NoError(err)
+		s.Require().False(resp.GetIsRegistered())
+		s.Require().NotEqual(registered.GetClientUuid(), resp.GetClientUuid())
+	})
+}
+
+func (s *GRPCServerTestSuite) Test_PendingClient() {
+	client := s.Client
+	machineID := utils.GenerateToken(32)
+
+	info := func() (*pb.GetClientInfoResponse, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		return client.GetClientInfo(ctx, &pb.GetClientInfoRequest{
+			Jwt:       s.UserTokenFixture,
+			MachineId: machineID,
+			Name:      "PENDING",
+		})
+	}
+
+	pending, err := info()
+	s.Require().NoError(err)
+
+	s.Run("New clients wait for approval with an enrollment code", func() {
+		s.Require().False(pending.GetApproved())
+		s.Require().Regexp(`^[0-9A-F]{4}-[0-9A-F]{4}$`, pending.GetEnrollmentCode())
+		s.Require().False(s.hasCerts(pending.GetClientUuid()))
+
+		again, err := info()
+		s.Require().NoError(err)
+		s.Require().True(again.GetIsRegistered())
+		s.Require().Equal(pending.GetEnrollmentCode(), again.GetEnrollmentCode())
+	})
+
+	s.Run("Tasks and encryption are refused to pending clients", func() {
+		handshakeID, err := s.Service.Usecase.CreateHandshake(s.UserFixture.UserUUID, utils.GenerateToken(10), "XX:XX:XX:XX:XX:XX", constants.NothingStatus, utils.StringToBase64String("test.pcap"))
+		s.Require().NoError(err)
+
+		_, err = s.Service.Usecase.UpdateClientTaskRest(s.UserFixture.UserUUID, handshakeID, pending.GetClientUuid(), constants.PendingStatus, "", "", "")
+		s.Require().ErrorIs(err, customErrors.ErrClientPendingApproval)
+
+		err = s.Service.Usecase.UpdateEncryptionClientStatus(pending.GetClientUuid(), s.UserFixture.UserUUID, true)
+		s.Require().ErrorIs(err, customErrors.ErrClientPendingApproval)
+	})
+
+	s.Run("The task chat of pending clients is refused", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		stream, err := client.HashcatTaskChat(ctx)
+		s.Require().NoError(err, "Stream initialization failed")
+
+		s.Require().NoError(stream.Send(&pb.ClientTaskMessageFromClient{
+			Jwt:        s.UserTokenFixture,
+			ClientUuid: pending.GetClientUuid(),
+			Status:     constants.WorkingStatus,
+		}))
+
+		_, err = stream.Recv()
+		s.Require().Equal(codes.FailedPrecondition, status.Code(err), err)
+	})
+
+	s.Run("Approved clients get their certificates", func() {
+		s.Require().NoError(s.Service.Usecase.ApproveClient(s.UserFixture.UserUUID, pending.GetClientUuid(), true))
+		s.Require().ErrorIs(s.Service.Usecase.ApproveClient(s.UserFixture.UserUUID, pending.GetClientUuid(), true), customErrors.ErrAlreadyApproved)
+
+		approved, err := info()
+		s.Require().NoError(err)
+		s.Require().True(approved.GetApproved())
+		s.Require().True(s.hasCerts(pending.GetClientUuid()))
+	})
+
+	s.Run("Rejected clients are revoked", func() {
+		rejected, err := s.Service.Usecase.CreateClient(s.UserFixture.UserUUID, utils.GenerateToken(32), "", "REJECTED")
+		s.Require().NoError(err)
+
+		s.Require().NoError(s.Service.Usecase.ApproveClient(s.UserFixture.UserUUID, rejected, false))
+		s.Require().Error(s.Service.Usecase.EnsureNotRevoked(rejected))
+
+		revocations, _, err := s.Service.Usecase.GetRevocations(s.UserFixture.UserUUID, 1)
+		s.Require().NoError(err)
+		s.Require().NotEmpty(revocations)
+		s.Require().Equal(rejected, revocations[0].EntityUUID)
+		s.Require().NoError(s.Service.Usecase.ApproveRevocation(s.UserFixture.UserUUID, revocations[0].UUID))
+	})
+}
+
+// hasCerts whether a certificate has been signed for the client
+func (s *GRPCServerTestSuite) hasCerts(clientUUID string) bool {
+	certs, _, err := s.Service.Usecase.GetClientCertsByUserID(s.UserFixture.UserUUID)
+	s.Require().NoError(err)
+
+	for _, cert := range certs {
+		if cert.ClientUUID == clientUUID {
+			return true
+		}
+	}
+	return false
+}
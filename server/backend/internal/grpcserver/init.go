@@ -13,10 +13,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	pb "github.com/Virgula0/progetto-dp/server/protobuf/hds"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
@@ -51,8 +55,10 @@ func (s *Server) Run(ctx context.Context, opt *Option) error {
 		MinVersion:         tls.VersionTLS13,
 		InsecureSkipVerify: false,
 		GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
-			// Get the client configuration
+			// Get the client configuration. The server name is chosen by the client, so revocations are checked on the
+			// verified certificate below and on the requests, see ServerContext.HashcatTaskChat
 			log.Infof("[gRPC] Client Name (ID) %s", info.ServerName)
+
 			clientConfig, exists := opt.ClientConfigStorage.GetClientConfig(info.ServerName)
 
 			nocert := &tls.Config{
@@ -86,6 +92,9 @@ func (s *Server) Run(ctx context.Context, opt *Option) error {
 				ClientCAs:    certPool,
 				ClientAuth:   tls.RequireAndVerifyClientCert,
 				MinVersion:   tls.VersionTLS13,
+				VerifyConnection: func(state tls.ConnectionState) error {
+					return opt.ensureNotRevoked(state.PeerCertificates[0].Subject.SerialNumber)
+				},
 			}, nil
 		},
 	}
@@ -102,9 +111,14 @@ func (s *Server) Run(ctx context.Context, opt *Option) error {
 		grpc.ConnectionTimeout(opt.GrpcConnTimeout),
 	}
 
+	unaryInterceptors := []grpc.UnaryServerInterceptor{opt.scopeInterceptor}
 	if opt.Debug {
-		options = append(options, grpc.UnaryInterceptor(logInterceptor))
+		unaryInterceptors = append(unaryInterceptors, logInterceptor)
 	}
+	options = append(options,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(opt.scopeStreamInterceptor),
+	)
 
 	server := grpc.NewServer(options...)
 	pb.RegisterHDSTemplateServiceServer(server, s.GRPCCtx)
@@ -131,6 +145,67 @@ func (s *Server) Run(ctx context.Context, opt *Option) error {
 	return nil
 }
 
+// ensureNotRevoked refuses revoked clients, if revocations are checked
+func (opt *Option) ensureNotRevoked(clientUUID string) error {
+	if opt.EnsureNotRevoked == nil {
+		return nil
+	}
+
+	if err := opt.EnsureNotRevoked(clientUUID); err != nil {
+		log.Warnf("[gRPC] Connection of revoked client %s refused", clientUUID)
+		return err
+	}
+	return nil
+}
+
+// tokenCarrier the requests authenticated by the JWT they carry
+type tokenCarrier interface {
+	GetJwt() string
+}
+
+// authorize refuses the requests carrying a token not issued to the clients, i.e. the FE and daemon tokens
+func (opt *Option) authorize(req any) error {
+	carrier, ok := req.(tokenCarrier)
+	if !ok || opt.AuthorizeToken == nil {
+		return nil
+	}
+
+	err := opt.AuthorizeToken(carrier.GetJwt(), constants.ScopeClientTasks)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, customErrors.ErrTokenAudience), errors.Is(err, customErrors.ErrMissingScope):
+		return status.Errorf(codes.PermissionDenied, "%v", err)
+	default:
+		return status.Errorf(codes.Unauthenticated, "%s %v", customErrors.ErrInvalidToken, err)
+	}
+}
+
+// scopeInterceptor authorizes the token of the unary requests
+func (opt *Option) scopeInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, uHandler grpc.UnaryHandler) (any, error) {
+	if err := opt.authorize(req); err != nil {
+		return nil, err
+	}
+	return uHandler(ctx, req)
+}
+
+// scopeStreamInterceptor authorizes the token of every message received on the streams
+func (opt *Option) scopeStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, sHandler grpc.StreamHandler) error {
+	return sHandler(srv, &authorizedStream{ServerStream: ss, opt: opt})
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	opt *Option
+}
+
+func (s *authorizedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.opt.authorize(m)
+}
+
 // logInterceptor grpc debug purposes
 func logInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, uHandler grpc.UnaryHandler) (any, error) {
 	start := time.Now()
@@ -12,4 +12,10 @@ type Option struct {
 
 	CACert, CAKey, ServerCert, ServerKey []byte
 	ClientConfigStorage                  *encryption.ClientConfigStore
+
+	// EnsureNotRevoked refuses the UUID of a deleted client, it is the subject serial number of its certificate. Not checked when nil
+	EnsureNotRevoked func(clientUUID string) error
+
+	// AuthorizeToken refuses the JWT of a request when it is not granted scope. Not checked when nil
+	AuthorizeToken func(token, scope string) error
 }
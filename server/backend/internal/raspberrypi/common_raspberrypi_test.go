@@ -2,15 +2,26 @@
 package raspberrypi_test
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/enums"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/raspberrypi"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/repository"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/seed"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"github.com/stretchr/testify/suite"
+	"net"
+	"strings"
 	"testing"
+	"time"
 )
 
 type ServerTCPIPSuite struct {
@@ -19,10 +30,14 @@ type ServerTCPIPSuite struct {
 	UserClientRegistered *entities.Client
 
 	ExistingRaspberryMachineID string
-	AdminToken                 string
+	AdminToken                 string // issued to the daemons by LOGIN
+	AdminDeviceToken           string // issued by DEVICELOGIN to the existing raspberry pi
+	AdminFEToken               string
 	NormalUser                 *entities.User
 	NormalUserToken            string
+	NormalUserFEToken          string
 	RaspberryPIExistingID      string
+	RaspberryPIExistingKey     string
 	TestSSID                   string
 	TestBSSID                  string
 }
@@ -61,22 +76,167 @@ func (s *ServerTCPIPSuite) SetupSuite() {
 
 	s.ExistingRaspberryMachineID = machineID
 	s.RaspberryPIExistingID = respID
+	s.approveDevice(machineID)
 
-	s.AdminToken, err = testsuite.AuthAPI(entities.AuthRequest{
+	s.AdminFEToken, err = testsuite.AuthAPI(entities.AuthRequest{
 		Username: s.UserFixture.Username,
 		Password: s.UserFixture.Password,
 	})
 	s.Require().NoError(err)
 
-	s.NormalUserToken, err = testsuite.AuthAPI(entities.AuthRequest{
+	s.NormalUserFEToken, err = testsuite.AuthAPI(entities.AuthRequest{
 		Username: s.NormalUser.Username,
 		Password: s.NormalUser.Password,
 	})
 	s.Require().NoError(err)
 
+	s.AdminToken = s.daemonLogin(s.UserFixture)
+	s.NormalUserToken = s.daemonLogin(s.NormalUser)
+
 	s.TestSSID = "TEST"
 	s.TestBSSID = "00:01:02:03:04:05"
 
+	s.AdminDeviceToken = s.deviceToken(s.AdminToken, s.ExistingRaspberryMachineID)
+	s.RaspberryPIExistingKey = s.exchangeKey(s.AdminDeviceToken, s.ExistingRaspberryMachineID)
+}
+
+// sendCommand runs a whole legacy exchange (command, request, response) on a new connection and returns the response line
+func (s *ServerTCPIPSuite) sendCommand(command string, request any) string {
+	client := s.Client()
+	defer client.Close()
+	s.Require().NoError(client.SetDeadline(time.Now().Add(3 * time.Minute)))
+
+	reader := bufio.NewReader(client)
+	send := func(message []byte) {
+		_, err := client.Write([]byte(fmt.Sprintf("%v", len(message)) + "\n"))
+		s.Require().NoError(err, "Failed to send size")
+
+		response, err := reader.ReadString('\n')
+		s.Require().NoError(err, "Failed to read from server")
+		s.Require().Equal("ACK\n", response)
+
+		_, err = client.Write(message)
+		s.Require().NoError(err, "Failed to send data")
+
+		response, err = reader.ReadString('\n')
+		s.Require().NoError(err, "Failed to read from server")
+		s.Require().Equal("ACK\n", response)
+	}
+
+	marshaled, err := json.Marshal(request)
+	s.Require().NoError(err)
+
+	send([]byte(command))
+	send(marshaled)
+
+	response, err := reader.ReadString('\n')
+	s.Require().NoError(err, "Failed to read from server")
+	return response
+}
+
+// daemonLogin returns the token issued to the daemons of user
+func (s *ServerTCPIPSuite) daemonLogin(user *entities.User) string {
+	token := strings.TrimSpace(s.sendCommand("LOGIN", &entities.AuthRequest{
+		Username: user.Username,
+		Password: user.Password,
+	}))
+	s.Require().True(utils.IsJWT(token), "unexpected login response "+token)
+	return token
+}
+
+// deviceToken enrolls the device identified by machineID with the user token, approves it and returns the token
+// obtained logging in with its credential, as the daemon does on its first start
+func (s *ServerTCPIPSuite) deviceToken(userToken, machineID string) string {
+	client := s.Client()
+	defer client.Close()
+
+	response := s.framedRequest(client, enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: userToken, MachineID: machineID})
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	s.approveDevice(machineID)
+
+	response = s.framedRequest(client, enums.DEVICELOGIN, &raspberrypi.TCPDeviceLoginRequest{
+		MachineID:  machineID,
+		Credential: string(response.Payload),
+	})
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	return string(response.Payload)
+}
+
+// framedRequest sends a single framed request on conn and returns the response frame
+func (s *ServerTCPIPSuite) framedRequest(conn net.Conn, command enums.Command, request any) *raspberrypi.Frame {
+	s.Require().NoError(conn.SetDeadline(time.Now().Add(3 * time.Minute)))
+
+	payload, err := json.Marshal(request)
+	s.Require().NoError(err)
+
+	s.Require().NoError(raspberrypi.WriteFrame(conn, &raspberrypi.Frame{
+		Version:   raspberrypi.FrameVersion,
+		Type:      byte(command),
+		RequestID: 1,
+		Payload:   payload,
+	}))
+
+	response, err := raspberrypi.ReadFrame(bufio.NewReader(conn))
+	s.Require().NoError(err)
+	s.Require().Equal(uint32(1), response.RequestID)
+	return response
+}
+
+// exchangeKey performs the daemon side of KEYEXCHANGE and returns the derived key
+func (s *ServerTCPIPSuite) exchangeKey(token, machineID string) string {
+	private, err := utils.GenerateExchangeKey()
+	s.Require().NoError(err)
+
+	response := s.sendCommand("KEYEXCHANGE", &raspberrypi.TCPKeyExchangeRequest{
+		Jwt:       token,
+		MachineID: machineID,
+		PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+	})
+
+	key, err := utils.DeriveDeviceKey(private, strings.TrimSpace(response), machineID)
+	s.Require().NoError(err, "unexpected key exchange response "+response)
+	return key
+}
+
+// approveDevice approves the device of the admin identified by machineID, as the user does from the devices page
+func (s *ServerTCPIPSuite) approveDevice(machineID string) {
+	rsp, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+	s.Require().NoError(err)
+	s.Require().NoError(s.Service.Usecase.ApproveRaspberryPI(rsp.UserUUID, rsp.RaspberryPIUUID, true))
+}
+
+// sealPCAP encrypts a capture the way the daemon does before uploading it
+func (s *ServerTCPIPSuite) sealPCAP(key, machineID, bssid, ssid string, pcap []byte) *string {
+	sealed, err := utils.SealAESGCM(key, pcap, utils.HandshakeAdditionalData(machineID, bssid, ssid))
+	s.Require().NoError(err)
+
+	encoded := utils.BytesToBase64String(sealed)
+	return &encoded
+}
+
+// sealCapture compresses and encrypts a capture the way the daemon does before a chunked upload,
+// returning the payload with its hex sha256
+func (s *ServerTCPIPSuite) sealCapture(key, machineID, bssid, ssid string, pcap []byte) ([]byte, string) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write(pcap)
+	s.Require().NoError(err)
+	s.Require().NoError(writer.Close())
+
+	sealed, err := utils.SealAESGCM(key, compressed.Bytes(), utils.HandshakeAdditionalData(machineID, bssid, ssid))
+	s.Require().NoError(err)
+
+	sum := sha256.Sum256(sealed)
+	return sealed, hex.EncodeToString(sum[:])
+}
+
+// uploadOffset decodes the answer to UPLOADBEGIN and UPLOADCHUNK
+func (s *ServerTCPIPSuite) uploadOffset(response *raspberrypi.Frame) int64 {
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+	var upload raspberrypi.TCPUploadResponse
+	s.Require().NoError(json.Unmarshal(response.Payload, &upload))
+	return upload.Offset
 }
 
 // TearDownAllSuite implements suite.SetupTestSuite and is called after each suite
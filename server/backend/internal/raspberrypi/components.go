@@ -6,144 +6,441 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/enums"
 	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"github.com/go-sql-driver/mysql"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
-	"io"
 	"net"
 	"strconv"
 	"strings"
-	"sync"
 )
 
-var readMessageMutex sync.Mutex
-var writeMessageMutex sync.Mutex
+// MaxLegacyMessageSize legacy daemons cannot use the chunked upload, their captures are sent in a single message
+const MaxLegacyMessageSize = 64 << 20
 
-// readMessageSize the first message from the client is the length of the content will be sent so we can initialize a buffer
+// readMessageSize the first message from the client is the length of the content will be sent so we can initialize a buffer.
+// The length is trusted only up to MaxLegacyMessageSize
 func (wr *TCPServer) readMessageSize(reader *bufio.Reader) (int64, error) {
-	readMessageMutex.Lock()
-	defer readMessageMutex.Unlock()
 	lengthOfMessage, err := reader.ReadString('\n')
 	if err != nil {
 		return 0, err
 	}
 	lengthOfMessage = strings.TrimSpace(lengthOfMessage)
-	return strconv.ParseInt(lengthOfMessage, 10, 64)
+	size, err := strconv.ParseInt(lengthOfMessage, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 || size > MaxLegacyMessageSize {
+		return 0, customErrors.ErrMessageTooLarge
+	}
+	return size, nil
 }
 
 // readMessageContent read the real message from the client
 func (wr *TCPServer) readMessageContent(reader *bufio.Reader, size int64) ([]byte, error) {
-	readMessageMutex.Lock()
-	defer readMessageMutex.Unlock()
-	buffer := make([]byte, size)
-	_, err := io.ReadFull(reader, buffer)
-	return buffer, err
+	return readPayload(reader, size)
 }
 
 // writeErrorToClient refactored function to send error whenever happens to the client
 func (wr *TCPServer) writeErrorToClient(client net.Conn, message string) {
-	writeMessageMutex.Lock()
-	defer writeMessageMutex.Unlock()
 	_, err := client.Write([]byte(message + "\n"))
 	if err != nil {
 		log.Errorf("[TCP/IP] Error writing to client: %s", err.Error())
 	}
 }
 
-func (wr *TCPServer) processLoginMessage(buffer []byte, client net.Conn) error {
-	var loginRequest entities.AuthRequest
+// processCommand runs a command regardless of the protocol it was received with.
+// The returned bytes are the answer for the client, when an error is returned its message is sent instead
+func (wr *TCPServer) processCommand(info *connectionInfo, command enums.Command, buffer []byte) ([]byte, error) {
+	switch command {
+	case enums.LOGIN:
+		return wr.processLoginMessage(buffer)
+	case enums.HANDSHAKE:
+		return wr.processHandshakeMessage(info, buffer)
+	case enums.KEYEXCHANGE:
+		return wr.processKeyExchangeMessage(info, buffer)
+	case enums.CERTIFICATE:
+		return wr.processCertificateMessage(buffer)
+	case enums.ENROLL:
+		return wr.processEnrollMessage(buffer)
+	case enums.DEVICELOGIN:
+		return wr.processDeviceLoginMessage(info, buffer)
+	case enums.UPLOADBEGIN:
+		return wr.processUploadBeginMessage(info, buffer)
+	case enums.UPLOADCHUNK:
+		return wr.processUploadChunkMessage(info, buffer)
+	case enums.UPLOADCOMMIT:
+		return wr.processUploadCommitMessage(info, buffer)
+	case enums.RESULTS:
+		return wr.processResultsMessage(info, buffer)
+	case enums.DIRECTIVES:
+		return wr.processDirectivesMessage(info, buffer)
+	case enums.DIRECTIVEACK:
+		return wr.processDirectiveAckMessage(info, buffer)
+	default:
+		return nil, customErrors.ErrUnknownCommand
+	}
+}
 
-	// Unmarshal the request
-	if err := json.Unmarshal(buffer, &loginRequest); err != nil {
-		wr.writeErrorToClient(client, fmt.Sprintf("Invalid request format: %s", err.Error()))
-		return err
+// decodeRequest unmarshal and validate a request
+func decodeRequest(buffer []byte, request any) error {
+	if err := json.Unmarshal(buffer, request); err != nil {
+		return fmt.Errorf("invalid request format: %w", err)
 	}
 
-	// Validate the request
-	if err := utils.ValidateGenericStruct(loginRequest); err != nil {
-		wr.writeErrorToClient(client, fmt.Sprintf("Invalid request data: %s", err.Error()))
-		return err
+	if err := utils.ValidateGenericStruct(request); err != nil {
+		return fmt.Errorf("invalid request data: %w", err)
+	}
+
+	return nil
+}
+
+func (wr *TCPServer) processLoginMessage(buffer []byte) ([]byte, error) {
+	var loginRequest entities.AuthRequest
+
+	if err := decodeRequest(buffer, &loginRequest); err != nil {
+		return nil, err
 	}
 
 	user, role, err := wr.usecase.GetUserByUsername(loginRequest.Username)
 
 	if err != nil {
-		wr.writeErrorToClient(client, fmt.Sprintf("login failed: %s", err.Error()))
-		return err
+		return nil, fmt.Errorf("login failed: %w", err)
 	}
 
 	// Compare password
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginRequest.Password))
 	if err != nil {
-		wr.writeErrorToClient(client, fmt.Sprintf("login failed: %s", customErrors.ErrInvalidCredentials))
-		return fmt.Errorf("login failed, wrong password for user %s", user.Username)
+		log.Warnf("[TCP/IP] login failed, wrong password for user %s", user.Username)
+		return nil, fmt.Errorf("login failed: %w", customErrors.ErrInvalidCredentials)
 	}
 
 	// Create the auth token
-	token, err := wr.usecase.CreateAuthToken(user.UserUUID, role.RoleString)
+	token, err := wr.usecase.CreateAuthToken(user.UserUUID, role.RoleString, constants.AudienceDaemon, "")
 	if err != nil {
-		wr.writeErrorToClient(client, fmt.Sprintf("login failed: %s", customErrors.ErrInvalidCredentials))
-		return err
+		return nil, fmt.Errorf("login failed: %w", customErrors.ErrInvalidCredentials)
 	}
 
-	_, err = client.Write([]byte(token + "\n"))
-	return err
+	return []byte(token), nil
+}
+
+// processEnrollMessage issues the device credential. Only the user tokens issued to the daemons are accepted, the device then waits for the user
+// to approve it from the FE before its login succeeds
+func (wr *TCPServer) processEnrollMessage(buffer []byte) ([]byte, error) {
+	var enrollRequest TCPEnrollRequest
+
+	if err := decodeRequest(buffer, &enrollRequest); err != nil {
+		return nil, err
+	}
+
+	data, err := wr.usecase.GetDataFromToken(enrollRequest.Jwt, constants.ScopeDaemonEnroll)
+	if err != nil {
+		return nil, fmt.Errorf("enrollment failed: %w", err)
+	}
+
+	credential, err := wr.usecase.EnrollRaspberryPI(data[constants.UserIDKey].(string), enrollRequest.MachineID)
+	if err != nil {
+		return nil, fmt.Errorf("enrollment failed: %w", err)
+	}
+
+	log.Infof("[TCP/IP] Device %s enrolled", enrollRequest.MachineID)
+
+	return []byte(credential), nil
+}
+
+// processDeviceLoginMessage exchanges the device credential for a device token, recording what the device reports
+func (wr *TCPServer) processDeviceLoginMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var deviceLoginRequest TCPDeviceLoginRequest
+
+	if err := decodeRequest(buffer, &deviceLoginRequest); err != nil {
+		return nil, err
+	}
+
+	token, err := wr.usecase.AuthenticateRaspberryPI(deviceLoginRequest.MachineID, deviceLoginRequest.Credential)
+	if errors.Is(err, customErrors.ErrRaspberryPIPendingApproval) {
+		// what the device reports helps the user recognizing it before approving it
+		wr.checkIn(info, deviceLoginRequest.MachineID, &entities.RaspberryPIMetadata{
+			Hostname:      deviceLoginRequest.Hostname,
+			DaemonVersion: deviceLoginRequest.DaemonVersion,
+			OS:            deviceLoginRequest.OS,
+		})
+	}
+
+	if err != nil {
+		log.Warnf("[TCP/IP] Device login failed for %s", deviceLoginRequest.MachineID)
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	wr.identify(info, deviceLoginRequest.MachineID)
+	wr.checkIn(info, deviceLoginRequest.MachineID, &entities.RaspberryPIMetadata{
+		Hostname:      deviceLoginRequest.Hostname,
+		DaemonVersion: deviceLoginRequest.DaemonVersion,
+		OS:            deviceLoginRequest.OS,
+	})
+
+	return []byte(token), nil
+}
+
+// checkIn records that the device has been seen, the request is answered anyway
+func (wr *TCPServer) checkIn(info *connectionInfo, machineID string, metadata *entities.RaspberryPIMetadata) {
+	if err := wr.usecase.CheckInRaspberryPI(machineID, info.address, metadata); err != nil {
+		log.Warnf("[TCP/IP] Check-in of %s not recorded: %s", machineID, err.Error())
+	}
+}
+
+// deviceUser returns the user owning the device token issued to machineID, user tokens are refused
+func (wr *TCPServer) deviceUser(jwt, machineID string) (string, error) {
+	data, err := wr.usecase.GetDataFromDeviceToken(jwt, machineID)
+	if err != nil {
+		return "", err
+	}
+
+	return data[constants.UserIDKey].(string), nil
+}
+
+// daemonUser as deviceUser, but the user tokens obtained with LOGIN are accepted too
+func (wr *TCPServer) daemonUser(jwt, machineID string) (string, error) {
+	data, err := wr.usecase.GetDataFromDaemonToken(jwt, machineID)
+	if err != nil {
+		return "", err
+	}
+
+	return data[constants.UserIDKey].(string), nil
+}
+
+// uploaderUser returns the user uploading captures with HANDSHAKE. Legacy daemons have no device credential,
+// their user tokens are accepted only on the legacy protocol and only while their plaintext captures are, see TCPAllowPlaintext
+func (wr *TCPServer) uploaderUser(info *connectionInfo, jwt, machineID string) (string, error) {
+	if info.legacy && constants.TCPAllowPlaintext {
+		return wr.daemonUser(jwt, machineID)
+	}
+
+	return wr.deviceUser(jwt, machineID)
+}
+
+// authorizeDevice in mutual TLS mode only the connection holding the certificate enrolled for machineID
+// can act on behalf of the device
+func (wr *TCPServer) authorizeDevice(info *connectionInfo, userID, machineID string) error {
+	wr.identify(info, machineID)
+
+	if wr.tlsMode != TLSMutual {
+		return nil
+	}
+
+	if info.certificate == nil {
+		return customErrors.ErrDeviceCertificateRequired
+	}
+
+	rsp, err := wr.usecase.GetRaspberryPIByMachineID(machineID)
+	if err != nil || rsp.UserUUID != userID || rsp.RaspberryPIUUID != info.certificate.Subject.SerialNumber {
+		log.Warnf("[TCP/IP] Certificate %s refused for device %s", info.certificate.Subject.SerialNumber, machineID)
+		return customErrors.ErrDeviceCertificateMismatch
+	}
+
+	return nil
+}
+
+// processCertificateMessage signs a certificate for the device. The private key is sent back, so TLS is mandatory
+func (wr *TCPServer) processCertificateMessage(buffer []byte) ([]byte, error) {
+	if wr.tlsMode == TLSDisabled {
+		return nil, customErrors.ErrTLSRequired
+	}
+
+	var certificateRequest TCPCertificateRequest
+
+	if err := decodeRequest(buffer, &certificateRequest); err != nil {
+		return nil, err
+	}
+
+	userID, err := wr.daemonUser(certificateRequest.Jwt, certificateRequest.MachineID)
+	if err != nil {
+		return nil, fmt.Errorf("certificate enrollment failed: %w", err)
+	}
+
+	caCert, deviceCert, deviceKey, err := wr.usecase.EnrollRaspberryPICertificate(userID, certificateRequest.MachineID)
+	if err != nil {
+		return nil, fmt.Errorf("certificate enrollment failed: %w", err)
+	}
+
+	log.Infof("[TCP/IP] Certificate enrolled for device %s", certificateRequest.MachineID)
+
+	return json.Marshal(&TCPCertificateResponse{
+		CACert:     caCert,
+		ClientCert: deviceCert,
+		ClientKey:  deviceKey,
+	})
+}
+
+// processKeyExchangeMessage derives the encryption key of the device and answers with the server public key
+func (wr *TCPServer) processKeyExchangeMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var keyExchangeRequest TCPKeyExchangeRequest
+
+	if err := decodeRequest(buffer, &keyExchangeRequest); err != nil {
+		return nil, err
+	}
+
+	userID, err := wr.deviceUser(keyExchangeRequest.Jwt, keyExchangeRequest.MachineID)
+	if err != nil {
+		return nil, fmt.Errorf("key exchange failed: %w", err)
+	}
+
+	if err = wr.authorizeDevice(info, userID, keyExchangeRequest.MachineID); err != nil {
+		return nil, err
+	}
+
+	serverPublicKey, err := wr.usecase.ExchangeRaspberryPIKey(userID, keyExchangeRequest.MachineID, keyExchangeRequest.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("key exchange failed: %w", err)
+	}
+
+	return []byte(serverPublicKey), nil
+}
+
+// processResultsMessage answers with the handshakes uploaded by the device which have been cracked
+func (wr *TCPServer) processResultsMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var resultsRequest TCPResultsRequest
+
+	if err := decodeRequest(buffer, &resultsRequest); err != nil {
+		return nil, err
+	}
+
+	userID, err := wr.deviceUser(resultsRequest.Jwt, resultsRequest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = wr.authorizeDevice(info, userID, resultsRequest.MachineID); err != nil {
+		return nil, err
+	}
+
+	handshakes, err := wr.usecase.GetRaspberryPIResults(userID, resultsRequest.MachineID)
+	if err != nil {
+		return nil, fmt.Errorf("results not available: %w", err)
+	}
+
+	response := TCPResultsResponse{Results: make([]*TCPResult, 0, len(handshakes))}
+	for _, handshake := range handshakes {
+		result := &TCPResult{
+			HandshakeUUID: handshake.UUID,
+			SSID:          handshake.SSID,
+			BSSID:         handshake.BSSID,
+			UploadedDate:  handshake.UploadedDate,
+		}
+		if handshake.CrackedHandshake != nil {
+			result.CrackedHandshake = *handshake.CrackedHandshake
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return json.Marshal(&response)
+}
+
+// processDirectivesMessage answers with the directives queued for the device, they are delivered until acknowledged
+func (wr *TCPServer) processDirectivesMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var directivesRequest TCPDirectivesRequest
+
+	if err := decodeRequest(buffer, &directivesRequest); err != nil {
+		return nil, err
+	}
+
+	userID, err := wr.deviceUser(directivesRequest.Jwt, directivesRequest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = wr.authorizeDevice(info, userID, directivesRequest.MachineID); err != nil {
+		return nil, err
+	}
+
+	directives, err := wr.usecase.GetPendingRaspberryPIDirectives(userID, directivesRequest.MachineID)
+	if err != nil {
+		return nil, fmt.Errorf("directives not available: %w", err)
+	}
+
+	wr.checkIn(info, directivesRequest.MachineID, nil)
+
+	return json.Marshal(&TCPDirectivesResponse{Directives: directives})
+}
+
+// processDirectiveAckMessage records the outcome of a directive applied by the device
+func (wr *TCPServer) processDirectiveAckMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var ackRequest TCPDirectiveAckRequest
+
+	if err := decodeRequest(buffer, &ackRequest); err != nil {
+		return nil, err
+	}
+
+	userID, err := wr.deviceUser(ackRequest.Jwt, ackRequest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = wr.authorizeDevice(info, userID, ackRequest.MachineID); err != nil {
+		return nil, err
+	}
+
+	err = wr.usecase.AcknowledgeRaspberryPIDirective(userID, ackRequest.MachineID, ackRequest.DirectiveUUID, ackRequest.Applied, ackRequest.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[TCP/IP] Directive %s acknowledged by %s (applied: %t)", ackRequest.DirectiveUUID, ackRequest.MachineID, ackRequest.Applied)
+
+	return []byte(ackRequest.DirectiveUUID), nil
 }
 
 // processHandshakeMessage performs main tcp server actions
-func (wr *TCPServer) processHandshakeMessage(buffer []byte, client net.Conn) error {
+func (wr *TCPServer) processHandshakeMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
 	var createRequest TCPCreateRaspberryPIRequest
 
-	// Unmarshal the request
-	if err := json.Unmarshal(buffer, &createRequest); err != nil {
-		wr.writeErrorToClient(client, fmt.Sprintf("Invalid request format: %s", err.Error()))
-		return err
+	if err := decodeRequest(buffer, &createRequest); err != nil {
+		return nil, err
 	}
 
-	// Validate the request
-	if err := utils.ValidateGenericStruct(createRequest); err != nil {
-		wr.writeErrorToClient(client, fmt.Sprintf("Invalid request data: %s", err.Error()))
-		return err
+	userID, err := wr.uploaderUser(info, createRequest.Jwt, createRequest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = wr.authorizeDevice(info, userID, createRequest.MachineID); err != nil {
+		return nil, err
 	}
 
 	// Create Raspberry PI
-	_, err := wr.createRaspberryPI(&createRequest)
-	if err != nil {
-		errParsed := wr.handleCreationError(err, client)
-		if errParsed != nil {
-			wr.writeErrorToClient(client, errParsed.Error())
+	if _, err := wr.createRaspberryPI(info, &createRequest); err != nil {
+		if errParsed := wr.handleCreationError(err); errParsed != nil {
+			return nil, errParsed
 		}
 	}
 
 	// Process Handshakes
-	handshakeSavedIDs, err := wr.processHandshakes(createRequest)
+	handshakeSavedIDs, err := wr.processHandshakes(info, createRequest)
 	if err != nil {
-		wr.writeErrorToClient(client, err.Error())
-		return err
+		return nil, err
 	}
 
-	// Send response
-	response := strings.Join(handshakeSavedIDs, ";") + "\n"
-	_, err = client.Write([]byte(response))
-	return err
+	return []byte(strings.Join(handshakeSavedIDs, ";")), nil
 }
 
 // processHandshakes read handshake data from request and saves it into the database
-func (wr *TCPServer) processHandshakes(request TCPCreateRaspberryPIRequest) ([]string, error) {
+func (wr *TCPServer) processHandshakes(info *connectionInfo, request TCPCreateRaspberryPIRequest) ([]string, error) {
 	handshakeSavedIDs := make([]string, 0)
 	for _, handshake := range request.Handshakes {
 		if handshake.BSSID == "" || handshake.SSID == "" {
 			continue
 		}
-		handshakeID, err := wr.createHandshake(request.Jwt, handshake)
+		handshakeID, err := wr.createHandshake(info, request.Jwt, request.MachineID, handshake)
 		if err != nil {
-			if errors.Is(err, customErrors.ErrHandshakeAlreadyPresent) {
+			if errors.Is(err, customErrors.ErrHandshakeAlreadyPresent) ||
+				errors.Is(err, customErrors.ErrRaspberryPINotEnrolled) ||
+				errors.Is(err, customErrors.ErrRaspberryPIPendingApproval) ||
+				errors.Is(err, customErrors.ErrHandshakeDecryption) {
 				return nil, err
 			}
-			return nil, fmt.Errorf("error creating handshake: %l", err)
+			return nil, fmt.Errorf("error creating handshake: %w", err)
 		}
 		handshakeSavedIDs = append(handshakeSavedIDs, handshakeID)
 	}
@@ -155,7 +452,7 @@ func (wr *TCPServer) processHandshakes(request TCPCreateRaspberryPIRequest) ([]s
 
 // handleCreationError useful function for handling the error returned from createRaspberryPI. If the error is a duplicate error
 // we can ignore it, as we assume the device already exists
-func (wr *TCPServer) handleCreationError(err error, client net.Conn) error {
+func (wr *TCPServer) handleCreationError(err error) error {
 	var mysqlErr *mysql.MySQLError
 	switch {
 	case errors.As(err, &mysqlErr) && customErrors.ErrCodeDuplicateEntry == mysqlErr.Number:
@@ -163,53 +460,54 @@ func (wr *TCPServer) handleCreationError(err error, client net.Conn) error {
 		err = nil
 	default:
 		log.Errorf("[TCP/IP] Error creating RaspberryPI: %s", err.Error())
-		_, errWrite := client.Write([]byte(err.Error() + "\n"))
-		if errWrite != nil {
-			wr.writeErrorToClient(client, errWrite.Error())
-		}
 	}
 	return err
 }
 
 // createRaspberryPI create a raspberrypi entity in the database if it does not exist
-func (wr *TCPServer) createRaspberryPI(request *TCPCreateRaspberryPIRequest) (result []byte, err error) {
+func (wr *TCPServer) createRaspberryPI(info *connectionInfo, request *TCPCreateRaspberryPIRequest) (result []byte, err error) {
 
-	data, err := wr.usecase.GetDataFromToken(request.Jwt)
+	userID, err := wr.uploaderUser(info, request.Jwt, request.MachineID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	userID := data[constants.UserIDKey].(string)
-
-	raspID, err := wr.usecase.CreateRaspberryPI(userID, request.MachineID, request.EncryptionKey)
+	// the encryption key is agreed later through KEYEXCHANGE, it is never sent in clear
+	raspID, err := wr.usecase.CreateRaspberryPI(userID, request.MachineID, "")
 
 	return []byte(raspID), err
 }
 
 // createHandshake create a new handshake if it does not exist
-func (wr *TCPServer) createHandshake(jwt string, handshake *entities.Handshake) (result string, err error) {
+func (wr *TCPServer) createHandshake(info *connectionInfo, jwt, machineID string, handshake *entities.Handshake) (result string, err error) {
 
-	data, err := wr.usecase.GetDataFromToken(jwt)
+	userID, err := wr.uploaderUser(info, jwt, machineID)
 
 	if err != nil {
 		return "", err
 	}
 
-	userID := data[constants.UserIDKey].(string)
+	if err = wr.usecase.EnsureHandshakeNotPresent(userID, handshake.BSSID, handshake.SSID); err != nil {
+		return "", err
+	}
 
-	_, saved, err := wr.usecase.GetHandshakesByBSSIDAndSSID(userID, handshake.BSSID, handshake.SSID)
+	if handshake.HandshakePCAP == nil {
+		return "", customErrors.ErrHandshakeDecryption
+	}
 
+	// payloads failing the AES-GCM authentication are rejected
+	pcap, err := wr.usecase.OpenRaspberryPIHandshake(userID, machineID, handshake.BSSID, handshake.SSID, *handshake.HandshakePCAP)
+	if errors.Is(err, customErrors.ErrRaspberryPINotEnrolled) && info.legacy && constants.TCPAllowPlaintext {
+		// daemons older than the key exchange send the capture as it is
+		return wr.usecase.CreatePlaintextRaspberryPIHandshake(userID, machineID, handshake.SSID, handshake.BSSID, *handshake.HandshakePCAP)
+	}
 	if err != nil {
+		log.Warnf("[TCP/IP] Rejected handshake %s from %s: %s", handshake.BSSID, machineID, err.Error())
 		return "", err
 	}
 
-	if saved > 0 { // we don't save the handshake if already saved
-		return "", customErrors.ErrHandshakeAlreadyPresent
-	}
-
-	// TODO: use encryption key of the raspberryPI for exchanging handshakes bytes securely
-	handshakeID, err := wr.usecase.CreateHandshake(userID, handshake.SSID, handshake.BSSID, constants.NothingStatus, *handshake.HandshakePCAP)
+	handshakeID, err := wr.usecase.CreateRaspberryPIHandshake(userID, machineID, handshake.SSID, handshake.BSSID, constants.NothingStatus, pcap, nil, "")
 
 	if err != nil {
 		return "", err
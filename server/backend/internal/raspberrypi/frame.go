@@ -0,0 +1,109 @@
+package raspberrypi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+Framed protocol
+
+Every message, in both directions, is a frame made of a fixed size header followed by the payload
+
+	| magic (4) | version (1) | type (1) | request ID (4) | length (4) | payload (length) |
+
+Integers are big endian. Requests carry the command as type, responses carry enums.RESPONSE or enums.ERROR
+and the request ID of the frame they answer. Legacy clients never start with the magic value, so both protocols
+can be served by the same listener.
+*/
+
+const (
+	FrameVersion    byte = 1
+	FrameHeaderSize      = 14
+	// MaxFrameSize fits an UPLOADCHUNK request, a chunk of MaxChunkSize base64 encoded in JSON with the other fields.
+	// Frames are read before the request is authenticated, bigger captures must be sent with the chunked upload
+	MaxFrameSize uint32 = (MaxChunkSize+2)/3*4 + 64<<10
+)
+
+var FrameMagic = [4]byte{'H', 'D', 'S', 'F'}
+
+var ErrFrameMagic = errors.New("invalid frame magic")
+var ErrFrameVersion = errors.New("unsupported frame version")
+var ErrFrameTooLarge = errors.New("frame exceeds the maximum allowed size")
+
+type Frame struct {
+	Version   byte
+	Type      byte
+	RequestID uint32
+	Payload   []byte
+}
+
+// ReadFrame reads a whole frame. The payload is not read when the header is not valid,
+// in that case the stream is not in a known state anymore and the connection should be dropped
+func ReadFrame(r io.Reader) (*Frame, error) {
+	header := make([]byte, FrameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if [4]byte(header[:4]) != FrameMagic {
+		return nil, ErrFrameMagic
+	}
+
+	frame := &Frame{
+		Version:   header[4],
+		Type:      header[5],
+		RequestID: binary.BigEndian.Uint32(header[6:10]),
+	}
+
+	if frame.Version != FrameVersion {
+		return frame, fmt.Errorf("%w: %d", ErrFrameVersion, frame.Version)
+	}
+
+	length := binary.BigEndian.Uint32(header[10:14])
+	if length > MaxFrameSize {
+		return frame, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, length)
+	}
+
+	payload, err := readPayload(r, int64(length))
+	if err != nil {
+		return nil, err
+	}
+
+	frame.Payload = payload
+	return frame, nil
+}
+
+// readPayload reads size bytes growing the buffer as they arrive, so that a peer announcing a large payload
+// without sending it does not get it allocated
+func readPayload(r io.Reader, size int64) ([]byte, error) {
+	var payload bytes.Buffer
+	if _, err := io.CopyN(&payload, r, size); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return payload.Bytes(), nil
+}
+
+// WriteFrame writes header and payload with a single Write call
+func WriteFrame(w io.Writer, frame *Frame) error {
+	if uint64(len(frame.Payload)) > uint64(MaxFrameSize) {
+		return ErrFrameTooLarge
+	}
+
+	buffer := make([]byte, FrameHeaderSize, FrameHeaderSize+len(frame.Payload))
+	copy(buffer, FrameMagic[:])
+	buffer[4] = frame.Version
+	buffer[5] = frame.Type
+	binary.BigEndian.PutUint32(buffer[6:10], frame.RequestID)
+	binary.BigEndian.PutUint32(buffer[10:14], uint32(len(frame.Payload))) // #nosec G115 bounded by MaxFrameSize
+
+	_, err := w.Write(append(buffer, frame.Payload...))
+	return err
+}
@@ -1,33 +1,116 @@
 package raspberrypi
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
 	handlers "github.com/Virgula0/progetto-dp/server/backend/internal/restapi"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 	"net"
+	"sync"
 	"time"
 )
 
+type TLSMode string
+
+const (
+	TLSDisabled TLSMode = ""
+	TLSEnabled  TLSMode = "tls"
+	// TLSMutual in addition to TLSEnabled, key exchanges and uploads are accepted only from
+	// connections presenting the certificate enrolled by the device
+	TLSMutual TLSMode = constants.TCPTLSMutual
+)
+
 type TCPServer struct {
 	l         net.Listener
 	timeout   time.Duration
 	sleepTime time.Duration
 	usecase   *usecase.Usecase
+	tlsMode   TLSMode
+	uploads   *uploadStore
 	TCPHandler
+
+	connectionsMutex sync.Mutex
+	connections      map[*connectionInfo]net.Conn
 }
 
 // NewTCPServer creates and returns a new TCPServer instance, initializing it with the provided TCP connection and usecase.
-func NewTCPServer(service *handlers.ServiceHandler, address, port string) (*TCPServer, error) {
-	conn, err := net.Listen("tcp", fmt.Sprintf("%s:%s", address, port))
+// When TLS is enabled, the server certificate created by Usecase.CreateServerCerts is used, so it must be called before.
+// Connections of the devices revoked from now on are dropped
+func NewTCPServer(service *handlers.ServiceHandler, address, port string, tlsMode TLSMode) (*TCPServer, error) {
+	if tlsMode != TLSDisabled && tlsMode != TLSEnabled && tlsMode != TLSMutual {
+		return nil, fmt.Errorf("unknown TLS mode %q", tlsMode)
+	}
+
+	uploads, err := newUploadStore(constants.UploadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Listen("tcp", net.JoinHostPort(address, port))
+
+	if err != nil {
+		return nil, err
+	}
 
+	if tlsMode != TLSDisabled {
+		config, errConfig := serverTLSConfig(service.Usecase)
+		if errConfig != nil {
+			return nil, errors.Join(errConfig, conn.Close())
+		}
+		conn = tls.NewListener(conn, config)
+	}
+
+	server := &TCPServer{
+		l:           conn,
+		usecase:     service.Usecase,
+		timeout:     30 * time.Second,
+		sleepTime:   200 * time.Millisecond,
+		tlsMode:     tlsMode,
+		uploads:     uploads,
+		connections: make(map[*connectionInfo]net.Conn),
+	}
+
+	service.Usecase.OnRevocation(server.dropRevoked)
+
+	return server, nil
+}
+
+// Addr returns the address the server is listening on
+func (wr *TCPServer) Addr() net.Addr {
+	return wr.l.Addr()
+}
+
+// serverTLSConfig daemons can always connect without a certificate for logging in and enrolling one,
+// when a certificate is presented it must be signed by our CA and issued to a device which has not been revoked
+func serverTLSConfig(uc *usecase.Usecase) (*tls.Config, error) {
+	caCert, _, serverCert, serverKey, err := uc.GetServerCerts()
 	if err != nil {
 		return nil, err
 	}
 
-	return &TCPServer{
-		l:         conn,
-		usecase:   service.Usecase,
-		timeout:   30 * time.Second,
-		sleepTime: 200 * time.Millisecond,
+	keyPair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate and key: %v", err)
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("failed to append CA certificate to pool")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		ClientCAs:    certPool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS13,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return nil
+			}
+			return uc.EnsureNotRevoked(state.PeerCertificates[0].Subject.SerialNumber)
+		},
 	}, nil
 }
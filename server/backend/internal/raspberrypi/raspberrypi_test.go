@@ -1,14 +1,28 @@
 package raspberrypi_test
 
 import (
+	"archive/tar"
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/enums"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/raspberrypi"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
 	"github.com/Virgula0/progetto-dp/server/entities"
 	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -173,9 +187,8 @@ func (s *ServerTCPIPSuite) Test_TCPServer_CreateRaspberryPIValidation() {
 		{
 			testname: "JWT not valid",
 			request: &raspberrypi.TCPCreateRaspberryPIRequest{
-				Jwt:           "test",
-				MachineID:     utils.GenerateToken(32),
-				EncryptionKey: utils.GenerateToken(64),
+				Jwt:       "test",
+				MachineID: utils.GenerateToken(32),
 			},
 			expectedOutput: func(response string) bool {
 				log.Println(response)
@@ -185,53 +198,28 @@ func (s *ServerTCPIPSuite) Test_TCPServer_CreateRaspberryPIValidation() {
 		{
 			testname: "Error on machineID not valid",
 			request: &raspberrypi.TCPCreateRaspberryPIRequest{
-				Jwt:           s.AdminToken,
-				MachineID:     utils.GenerateToken(64),
-				EncryptionKey: utils.GenerateToken(64),
+				Jwt:       s.AdminToken,
+				MachineID: utils.GenerateToken(64),
 			},
 			expectedOutput: func(response string) bool {
 				return strings.Contains(response, "Error:Field validation for 'MachineID'")
 			},
 		},
 		{
-			testname: "Error on machineID not valid",
-			request: &raspberrypi.TCPCreateRaspberryPIRequest{
-				Jwt:           s.AdminToken,
-				MachineID:     utils.GenerateToken(32),
-				EncryptionKey: utils.GenerateToken(32),
-			},
-			expectedOutput: func(response string) bool {
-				return strings.Contains(response, "Error:Field validation for 'EncryptionKey'")
-			},
-		},
-		{
-			testname: "RaspberryPI created",
+			testname: "User tokens are refused",
 			request: &raspberrypi.TCPCreateRaspberryPIRequest{
-				Jwt:           s.AdminToken,
-				MachineID:     utils.GenerateToken(32),
-				EncryptionKey: utils.GenerateToken(64),
+				Jwt:       s.AdminToken,
+				MachineID: utils.GenerateToken(32),
 			},
 			expectedOutput: func(response string) bool {
-				return strings.Contains(response, "no valid handshakes provided")
+				return strings.Contains(response, customErrors.ErrDeviceTokenRequired.Error())
 			},
 		},
 		{
 			testname: "Raspberrypi already present in the table for the correct user (IGNORE THE CREATION)",
 			request: &raspberrypi.TCPCreateRaspberryPIRequest{
-				Jwt:           s.AdminToken,
-				MachineID:     s.ExistingRaspberryMachineID,
-				EncryptionKey: utils.GenerateToken(64),
-			},
-			expectedOutput: func(response string) bool {
-				return strings.Contains(response, "no valid handshakes provided")
-			},
-		},
-		{
-			testname: "Raspberrypi already present also for another user (IGNORE THE CREATION)",
-			request: &raspberrypi.TCPCreateRaspberryPIRequest{
-				Jwt:           s.NormalUserToken,
-				MachineID:     s.ExistingRaspberryMachineID,
-				EncryptionKey: utils.GenerateToken(64),
+				Jwt:       s.AdminDeviceToken,
+				MachineID: s.ExistingRaspberryMachineID,
 			},
 			expectedOutput: func(response string) bool {
 				return strings.Contains(response, "no valid handshakes provided")
@@ -308,7 +296,9 @@ func (s *ServerTCPIPSuite) Test_TCPServer_CreateRaspberryPIValidation() {
 }
 
 func (s *ServerTCPIPSuite) Test_TCPServer_TestOnHandshakeCreation() {
-	var pcapTest = utils.StringToBase64String("test.pcap") // Optional: Add a delay to simulate network conditions
+	var pcapTest = []byte("test.pcap")
+	var firstSSID, firstBSSID = utils.GenerateToken(10), utils.GenerateToken(10)
+	var secondSSID, secondBSSID = utils.GenerateToken(10), utils.GenerateToken(10)
 	tests := []struct {
 		testname       string
 		request        *raspberrypi.TCPCreateRaspberryPIRequest
@@ -329,12 +319,11 @@ func (s *ServerTCPIPSuite) Test_TCPServer_TestOnHandshakeCreation() {
 						HashcatOptions:   nil,
 						HashcatLogs:      nil,
 						CrackedHandshake: nil,
-						HandshakePCAP:    &pcapTest,
+						HandshakePCAP:    s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, s.TestBSSID, s.TestSSID, pcapTest),
 					},
 				},
-				Jwt:           s.AdminToken,
-				MachineID:     s.ExistingRaspberryMachineID,
-				EncryptionKey: utils.GenerateToken(64),
+				Jwt:       s.AdminDeviceToken,
+				MachineID: s.ExistingRaspberryMachineID,
 			},
 			expectedOutput: func(response string) bool {
 				regex := "[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}"
@@ -350,33 +339,32 @@ func (s *ServerTCPIPSuite) Test_TCPServer_TestOnHandshakeCreation() {
 					{
 						ClientUUID:       nil,
 						UUID:             "",
-						SSID:             utils.GenerateToken(10),
-						BSSID:            utils.GenerateToken(10),
+						SSID:             firstSSID,
+						BSSID:            firstBSSID,
 						UploadedDate:     "",
 						Status:           constants.NothingStatus,
 						CrackedDate:      nil,
 						HashcatOptions:   nil,
 						HashcatLogs:      nil,
 						CrackedHandshake: nil,
-						HandshakePCAP:    &pcapTest,
+						HandshakePCAP:    s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, firstBSSID, firstSSID, pcapTest),
 					},
 					{
 						ClientUUID:       nil,
 						UUID:             "",
-						SSID:             utils.GenerateToken(10),
-						BSSID:            utils.GenerateToken(10),
+						SSID:             secondSSID,
+						BSSID:            secondBSSID,
 						UploadedDate:     "",
 						Status:           constants.NothingStatus,
 						CrackedDate:      nil,
 						HashcatOptions:   nil,
 						HashcatLogs:      nil,
 						CrackedHandshake: nil,
-						HandshakePCAP:    &pcapTest,
+						HandshakePCAP:    s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, secondBSSID, secondSSID, pcapTest),
 					},
 				},
-				Jwt:           s.AdminToken,
-				MachineID:     s.ExistingRaspberryMachineID,
-				EncryptionKey: utils.GenerateToken(64),
+				Jwt:       s.AdminDeviceToken,
+				MachineID: s.ExistingRaspberryMachineID,
 			},
 			expectedOutput: func(response string) bool {
 				regex := "[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12};"
@@ -472,3 +460,1606 @@ func (s *ServerTCPIPSuite) Test_TCPServer_TestOnHandshakeCreation() {
 		})
 	}
 }
+
+func (s *ServerTCPIPSuite) Test_TCPServer_KeyExchange() {
+	private, err := utils.GenerateExchangeKey()
+	s.Require().NoError(err)
+	publicKey := utils.BytesToBase64String(private.PublicKey().Bytes())
+
+	newMachineID := utils.GenerateToken(32)
+	newDeviceToken := s.deviceToken(s.NormalUserToken, newMachineID)
+
+	tests := []struct {
+		testname       string
+		request        *raspberrypi.TCPKeyExchangeRequest
+		expectedOutput func(response string) bool
+	}{
+		{
+			testname: "Public key not valid",
+			request: &raspberrypi.TCPKeyExchangeRequest{
+				Jwt:       s.AdminToken,
+				MachineID: utils.GenerateToken(32),
+				PublicKey: "not a key",
+			},
+			expectedOutput: func(response string) bool {
+				return strings.Contains(response, "Error:Field validation for 'PublicKey'")
+			},
+		},
+		{
+			testname: "Public key of the wrong size",
+			request: &raspberrypi.TCPKeyExchangeRequest{
+				Jwt:       newDeviceToken,
+				MachineID: newMachineID,
+				PublicKey: utils.StringToBase64String("short"),
+			},
+			expectedOutput: func(response string) bool {
+				return strings.Contains(response, "key exchange failed")
+			},
+		},
+		{
+			testname: "User tokens are refused",
+			request: &raspberrypi.TCPKeyExchangeRequest{
+				Jwt:       s.AdminToken,
+				MachineID: s.ExistingRaspberryMachineID,
+				PublicKey: publicKey,
+			},
+			expectedOutput: func(response string) bool {
+				return strings.Contains(response, customErrors.ErrDeviceTokenRequired.Error())
+			},
+		},
+		{
+			testname: "Device token issued to another device",
+			request: &raspberrypi.TCPKeyExchangeRequest{
+				Jwt:       newDeviceToken,
+				MachineID: s.ExistingRaspberryMachineID,
+				PublicKey: publicKey,
+			},
+			expectedOutput: func(response string) bool {
+				return strings.Contains(response, customErrors.ErrDeviceTokenMismatch.Error())
+			},
+		},
+		{
+			testname: "Key exchanged for a new device",
+			request: &raspberrypi.TCPKeyExchangeRequest{
+				Jwt:       newDeviceToken,
+				MachineID: newMachineID,
+				PublicKey: publicKey,
+			},
+			expectedOutput: func(response string) bool {
+				_, err := utils.DeriveDeviceKey(private, strings.TrimSpace(response), utils.GenerateToken(32))
+				return err == nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.testname, func() {
+			response := s.sendCommand("KEYEXCHANGE", tt.request)
+			s.Require().True(tt.expectedOutput(response), "Condition not matched for "+tt.testname+": "+response)
+		})
+	}
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_RejectsUnauthenticatedHandshake() {
+	var pcapTest = []byte("test.pcap")
+	var ssid, bssid = utils.GenerateToken(10), utils.GenerateToken(10)
+
+	tampered := s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, pcapTest)
+	raw := []byte(*tampered)
+	raw[len(raw)-3] ^= 0x01
+	*tampered = string(raw)
+
+	plainPCAP := utils.StringToBase64String("test.pcap")
+
+	notEnrolledMachineID := utils.GenerateToken(32)
+	_, err := s.Service.Usecase.CreateRaspberryPI(s.UserFixture.UserUUID, notEnrolledMachineID, "")
+	s.Require().NoError(err)
+	notEnrolledToken := s.deviceToken(s.AdminToken, notEnrolledMachineID)
+
+	tests := []struct {
+		testname string
+		request  *raspberrypi.TCPCreateRaspberryPIRequest
+		expected string
+	}{
+		{
+			testname: "Payload tampered",
+			request: &raspberrypi.TCPCreateRaspberryPIRequest{
+				Handshakes: []*entities.Handshake{{SSID: ssid, BSSID: bssid, HandshakePCAP: tampered}},
+				Jwt:        s.AdminDeviceToken,
+				MachineID:  s.ExistingRaspberryMachineID,
+			},
+			expected: "handshake payload failed authentication",
+		},
+		{
+			testname: "Payload encrypted for another network",
+			request: &raspberrypi.TCPCreateRaspberryPIRequest{
+				Handshakes: []*entities.Handshake{{
+					SSID:          ssid,
+					BSSID:         bssid,
+					HandshakePCAP: s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, utils.GenerateToken(10), pcapTest),
+				}},
+				Jwt:       s.AdminDeviceToken,
+				MachineID: s.ExistingRaspberryMachineID,
+			},
+			expected: "handshake payload failed authentication",
+		},
+		{
+			testname: "Payload not encrypted",
+			request: &raspberrypi.TCPCreateRaspberryPIRequest{
+				Handshakes: []*entities.Handshake{{SSID: ssid, BSSID: bssid, HandshakePCAP: &plainPCAP}},
+				Jwt:        s.AdminDeviceToken,
+				MachineID:  s.ExistingRaspberryMachineID,
+			},
+			expected: "handshake payload failed authentication",
+		},
+		{
+			testname: "Device without a key",
+			request: &raspberrypi.TCPCreateRaspberryPIRequest{
+				Handshakes: []*entities.Handshake{{
+					SSID:          ssid,
+					BSSID:         bssid,
+					HandshakePCAP: s.sealPCAP(s.RaspberryPIExistingKey, notEnrolledMachineID, bssid, ssid, pcapTest),
+				}},
+				Jwt:       notEnrolledToken,
+				MachineID: notEnrolledMachineID,
+			},
+			expected: "raspberry pi has not exchanged an encryption key yet",
+		},
+		{
+			testname: "User token",
+			request: &raspberrypi.TCPCreateRaspberryPIRequest{
+				Handshakes: []*entities.Handshake{{
+					SSID:          ssid,
+					BSSID:         bssid,
+					HandshakePCAP: s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, pcapTest),
+				}},
+				Jwt:       s.NormalUserToken,
+				MachineID: s.ExistingRaspberryMachineID,
+			},
+			expected: customErrors.ErrDeviceTokenRequired.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.testname, func() {
+			response := s.sendCommand("HANDSHAKE", tt.request)
+			s.Require().Contains(response, tt.expected)
+		})
+	}
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_LegacyPlaintextUpload() {
+	defer func(allowed bool) { constants.TCPAllowPlaintext = allowed }(constants.TCPAllowPlaintext)
+
+	plainPCAP := utils.StringToBase64String("test.pcap")
+	request := func(machineID string) *raspberrypi.TCPCreateRaspberryPIRequest {
+		return &raspberrypi.TCPCreateRaspberryPIRequest{
+			Handshakes: []*entities.Handshake{{SSID: utils.GenerateToken(10), BSSID: utils.GenerateToken(10), HandshakePCAP: &plainPCAP}},
+			Jwt:        s.AdminToken,
+			MachineID:  machineID,
+		}
+	}
+
+	legacyMachineID := utils.GenerateToken(32)
+	_, err := s.Service.Usecase.CreateRaspberryPI(s.UserFixture.UserUUID, legacyMachineID, "")
+	s.Require().NoError(err)
+	s.approveDevice(legacyMachineID)
+
+	pendingMachineID := utils.GenerateToken(32)
+	_, err = s.Service.Usecase.CreateRaspberryPI(s.UserFixture.UserUUID, pendingMachineID, "")
+	s.Require().NoError(err)
+
+	s.Run("User tokens refused unless allowed", func() {
+		constants.TCPAllowPlaintext = false
+		s.Require().Contains(s.sendCommand("HANDSHAKE", request(legacyMachineID)), customErrors.ErrDeviceTokenRequired.Error())
+	})
+
+	constants.TCPAllowPlaintext = true
+
+	s.Run("Accepted from a legacy daemon", func() {
+		response := strings.TrimSpace(s.sendCommand("HANDSHAKE", request(legacyMachineID)))
+		s.Require().Regexp("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", response)
+
+		handshake, err := s.Service.Usecase.GetHandshake(s.UserFixture.UserUUID, response)
+		s.Require().NoError(err)
+
+		capture, err := s.Service.Usecase.GetHandshakeCapture(s.UserFixture.UserUUID, handshake.UUID)
+		s.Require().NoError(err)
+		s.Require().Equal("test.pcap", string(capture))
+	})
+
+	s.Run("Refused once the device exchanged a key", func() {
+		s.Require().Contains(s.sendCommand("HANDSHAKE", request(s.ExistingRaspberryMachineID)), "handshake payload failed authentication")
+	})
+
+	s.Run("Refused until the device is approved", func() {
+		s.Require().Contains(s.sendCommand("HANDSHAKE", request(pendingMachineID)), "waiting for approval")
+	})
+
+	s.Run("Refused over the framed protocol", func() {
+		client := s.Client()
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.HANDSHAKE, request(legacyMachineID))
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrDeviceTokenRequired.Error())
+	})
+
+	s.Run("User tokens cannot exchange a key", func() {
+		private, err := utils.GenerateExchangeKey()
+		s.Require().NoError(err)
+
+		response := s.sendCommand("KEYEXCHANGE", &raspberrypi.TCPKeyExchangeRequest{
+			Jwt:       s.AdminToken,
+			MachineID: legacyMachineID,
+			PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+		})
+		s.Require().Contains(response, customErrors.ErrDeviceTokenRequired.Error())
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_FramedProtocol() {
+	login, err := json.Marshal(&entities.AuthRequest{
+		Username: s.UserFixture.Username,
+		Password: s.UserFixture.Password,
+	})
+	s.Require().NoError(err)
+
+	wrongLogin, err := json.Marshal(&entities.AuthRequest{
+		Username: s.UserFixture.Username,
+		Password: utils.GenerateToken(32),
+	})
+	s.Require().NoError(err)
+
+	ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+	handshake, err := json.Marshal(&raspberrypi.TCPCreateRaspberryPIRequest{
+		Handshakes: []*entities.Handshake{{
+			SSID:          ssid,
+			BSSID:         bssid,
+			HandshakePCAP: s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, []byte("test.pcap")),
+		}},
+		Jwt:       s.AdminDeviceToken,
+		MachineID: s.ExistingRaspberryMachineID,
+	})
+	s.Require().NoError(err)
+
+	tests := []struct {
+		testname       string
+		request        *raspberrypi.Frame
+		expectedType   enums.ResponseType
+		expectedOutput func(payload string) bool
+	}{
+		{
+			testname:     "Login",
+			request:      &raspberrypi.Frame{Version: raspberrypi.FrameVersion, Type: byte(enums.LOGIN), RequestID: 10, Payload: login},
+			expectedType: enums.RESPONSE,
+			expectedOutput: func(payload string) bool {
+				return utils.IsJWT(payload)
+			},
+		},
+		{
+			testname:     "Login with wrong password does not close the connection",
+			request:      &raspberrypi.Frame{Version: raspberrypi.FrameVersion, Type: byte(enums.LOGIN), RequestID: 11, Payload: wrongLogin},
+			expectedType: enums.ERROR,
+			expectedOutput: func(payload string) bool {
+				return strings.Contains(payload, "invalid credentials")
+			},
+		},
+		{
+			testname:     "Handshake",
+			request:      &raspberrypi.Frame{Version: raspberrypi.FrameVersion, Type: byte(enums.HANDSHAKE), RequestID: 12, Payload: handshake},
+			expectedType: enums.RESPONSE,
+			expectedOutput: func(payload string) bool {
+				return regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$").MatchString(payload)
+			},
+		},
+		{
+			testname:     "Unknown command",
+			request:      &raspberrypi.Frame{Version: raspberrypi.FrameVersion, Type: 0x7f, RequestID: 13},
+			expectedType: enums.ERROR,
+			expectedOutput: func(payload string) bool {
+				return payload == "unknown command"
+			},
+		},
+	}
+
+	// all the requests are pipelined on the same connection, responses are matched by request ID
+	client := s.Client()
+	defer client.Close()
+	s.Require().NoError(client.SetDeadline(time.Now().Add(3 * time.Minute)))
+
+	for _, tt := range tests {
+		s.Require().NoError(raspberrypi.WriteFrame(client, tt.request))
+	}
+
+	reader := bufio.NewReader(client)
+	for _, tt := range tests {
+		s.Run(tt.testname, func() {
+			response, err := raspberrypi.ReadFrame(reader)
+			s.Require().NoError(err)
+			s.Require().Equal(tt.request.RequestID, response.RequestID)
+			s.Require().Equal(byte(tt.expectedType), response.Type)
+			s.Require().True(tt.expectedOutput(string(response.Payload)), "Condition not matched for "+tt.testname+": "+string(response.Payload))
+		})
+	}
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_FramedProtocolMalformedFrames() {
+	tests := []struct {
+		testname string
+		header   []byte
+		expected string
+	}{
+		{
+			testname: "Unsupported version",
+			header:   []byte{'H', 'D', 'S', 'F', 99, byte(enums.LOGIN), 0, 0, 0, 1, 0, 0, 0, 0},
+			expected: "unsupported frame version",
+		},
+		{
+			testname: "Frame too large",
+			header:   []byte{'H', 'D', 'S', 'F', raspberrypi.FrameVersion, byte(enums.LOGIN), 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff},
+			expected: "frame exceeds the maximum allowed size",
+		},
+		{
+			testname: "Frame larger than a chunk upload",
+			header:   binary.BigEndian.AppendUint32([]byte{'H', 'D', 'S', 'F', raspberrypi.FrameVersion, byte(enums.UPLOADCHUNK), 0, 0, 0, 1}, raspberrypi.MaxFrameSize+1),
+			expected: "frame exceeds the maximum allowed size",
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.testname, func() {
+			client := s.Client()
+			defer client.Close()
+			s.Require().NoError(client.SetDeadline(time.Now().Add(3 * time.Minute)))
+
+			_, err := client.Write(tt.header)
+			s.Require().NoError(err)
+
+			reader := bufio.NewReader(client)
+			response, err := raspberrypi.ReadFrame(reader)
+			s.Require().NoError(err)
+			s.Require().Equal(uint32(1), response.RequestID)
+			s.Require().Equal(byte(enums.ERROR), response.Type)
+			s.Require().Contains(string(response.Payload), tt.expected)
+
+			// the stream is not trusted anymore, the server drops the connection
+			_, err = raspberrypi.ReadFrame(reader)
+			s.Require().ErrorIs(err, io.EOF)
+		})
+	}
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_MutualTLS() {
+	machineID := utils.GenerateToken(32)
+	var certificate tls.Certificate
+	var key string
+
+	s.Run("Enrollment refused without TLS", func() {
+		client := s.Client()
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.CERTIFICATE, &raspberrypi.TCPCertificateRequest{
+			Jwt:       s.NormalUserToken,
+			MachineID: machineID,
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "certificates can be enrolled only over TLS")
+	})
+
+	s.Run("Enrollment refused for a device of another user", func() {
+		client := s.TLSClient(nil)
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.CERTIFICATE, &raspberrypi.TCPCertificateRequest{
+			Jwt:       s.NormalUserToken,
+			MachineID: s.ExistingRaspberryMachineID,
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "raspberry pi is registered to another user")
+	})
+
+	s.Run("Certificate withheld until the device is approved", func() {
+		client := s.TLSClient(nil)
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.CERTIFICATE, &raspberrypi.TCPCertificateRequest{
+			Jwt:       s.NormalUserToken,
+			MachineID: machineID,
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrRaspberryPIPendingApproval.Error())
+
+		s.approveDevice(machineID)
+	})
+
+	s.Run("Certificate enrolled", func() {
+		client := s.TLSClient(nil)
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.CERTIFICATE, &raspberrypi.TCPCertificateRequest{
+			Jwt:       s.NormalUserToken,
+			MachineID: machineID,
+		})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		var enrolled raspberrypi.TCPCertificateResponse
+		s.Require().NoError(json.Unmarshal(response.Payload, &enrolled))
+
+		var err error
+		certificate, err = tls.X509KeyPair(enrolled.ClientCert, enrolled.ClientKey)
+		s.Require().NoError(err)
+
+		rsp, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+		s.Require().NoError(err)
+		s.Require().Equal(rsp.RaspberryPIUUID, certificate.Leaf.Subject.SerialNumber)
+	})
+
+	deviceToken := s.deviceToken(s.NormalUserToken, machineID)
+
+	s.Run("Key exchange refused without a device certificate", func() {
+		client := s.TLSClient(nil)
+		defer client.Close()
+
+		private, err := utils.GenerateExchangeKey()
+		s.Require().NoError(err)
+
+		response := s.framedRequest(client, enums.KEYEXCHANGE, &raspberrypi.TCPKeyExchangeRequest{
+			Jwt:       deviceToken,
+			MachineID: machineID,
+			PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "a device certificate is required")
+	})
+
+	s.Run("Key exchange with the device certificate", func() {
+		client := s.TLSClient(&certificate)
+		defer client.Close()
+
+		private, err := utils.GenerateExchangeKey()
+		s.Require().NoError(err)
+
+		response := s.framedRequest(client, enums.KEYEXCHANGE, &raspberrypi.TCPKeyExchangeRequest{
+			Jwt:       deviceToken,
+			MachineID: machineID,
+			PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+		})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		key, err = utils.DeriveDeviceKey(private, string(response.Payload), machineID)
+		s.Require().NoError(err)
+	})
+
+	s.Run("Handshake uploaded with the device certificate", func() {
+		client := s.TLSClient(&certificate)
+		defer client.Close()
+
+		ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+		response := s.framedRequest(client, enums.HANDSHAKE, &raspberrypi.TCPCreateRaspberryPIRequest{
+			Handshakes: []*entities.Handshake{{
+				SSID:          ssid,
+				BSSID:         bssid,
+				HandshakePCAP: s.sealPCAP(key, machineID, bssid, ssid, []byte("test.pcap")),
+			}},
+			Jwt:       deviceToken,
+			MachineID: machineID,
+		})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	})
+
+	s.Run("Certificate of another device refused", func() {
+		client := s.TLSClient(&certificate)
+		defer client.Close()
+
+		ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+		response := s.framedRequest(client, enums.HANDSHAKE, &raspberrypi.TCPCreateRaspberryPIRequest{
+			Handshakes: []*entities.Handshake{{
+				SSID:          ssid,
+				BSSID:         bssid,
+				HandshakePCAP: s.sealPCAP(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, []byte("test.pcap")),
+			}},
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "the certificate presented was not issued for this device")
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_ChunkedUpload() {
+	ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+	payload, checksum := s.sealCapture(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, []byte(strings.Repeat("test.pcap", 1000)))
+
+	begin := &raspberrypi.TCPUploadBeginRequest{
+		Jwt:       s.AdminDeviceToken,
+		MachineID: s.ExistingRaspberryMachineID,
+		UploadID:  strings.Repeat("a1", 32),
+		SSID:      ssid,
+		BSSID:     bssid,
+		Size:      int64(len(payload)),
+		Checksum:  checksum,
+	}
+	chunk := func(offset, end int64) *raspberrypi.TCPUploadChunkRequest {
+		return &raspberrypi.TCPUploadChunkRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  begin.UploadID,
+			Offset:    offset,
+			Data:      payload[offset:end],
+		}
+	}
+	commit := &raspberrypi.TCPUploadCommitRequest{
+		Jwt:       s.AdminDeviceToken,
+		MachineID: s.ExistingRaspberryMachineID,
+		UploadID:  begin.UploadID,
+	}
+	half := int64(len(payload) / 2)
+
+	s.Run("Upload is interrupted after the first half", func() {
+		client := s.Client()
+		defer client.Close()
+
+		s.Require().Equal(int64(0), s.uploadOffset(s.framedRequest(client, enums.UPLOADBEGIN, begin)))
+		s.Require().Equal(half, s.uploadOffset(s.framedRequest(client, enums.UPLOADCHUNK, chunk(0, half))))
+
+		response := s.framedRequest(client, enums.UPLOADCOMMIT, commit)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Equal("upload is not complete", string(response.Payload))
+	})
+
+	s.Run("Upload resumes from the acknowledged offset", func() {
+		client := s.Client()
+		defer client.Close()
+
+		s.Require().Equal(half, s.uploadOffset(s.framedRequest(client, enums.UPLOADBEGIN, begin)))
+
+		// a chunk sent again is not appended twice
+		s.Require().Equal(half, s.uploadOffset(s.framedRequest(client, enums.UPLOADCHUNK, chunk(0, half))))
+		s.Require().Equal(int64(len(payload)), s.uploadOffset(s.framedRequest(client, enums.UPLOADCHUNK, chunk(half, int64(len(payload))))))
+
+		response := s.framedRequest(client, enums.UPLOADCOMMIT, commit)
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+		s.Require().Regexp("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", string(response.Payload))
+
+		handshakes, _, err := s.Service.Usecase.GetHandshakesByBSSIDAndSSID(s.UserFixture.UserUUID, bssid, ssid)
+		s.Require().NoError(err)
+		s.Require().Len(handshakes, 1)
+		s.Require().Equal(utils.BytesToBase64String([]byte(strings.Repeat("test.pcap", 1000))), *handshakes[0].HandshakePCAP)
+	})
+
+	s.Run("Capture already uploaded is refused before sending data", func() {
+		client := s.Client()
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.UPLOADBEGIN, begin)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Equal("error creating handshake: handshake already present", string(response.Payload))
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_ChunkedUploadFailures() {
+	ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+	payload, checksum := s.sealCapture(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, []byte("test.pcap"))
+
+	upload := func(uploadID, checksum string, data []byte) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+
+		s.Require().Equal(int64(0), s.uploadOffset(s.framedRequest(client, enums.UPLOADBEGIN, &raspberrypi.TCPUploadBeginRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  uploadID,
+			SSID:      ssid,
+			BSSID:     bssid,
+			Size:      int64(len(data)),
+			Checksum:  checksum,
+		})))
+
+		response := s.framedRequest(client, enums.UPLOADCHUNK, &raspberrypi.TCPUploadChunkRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  uploadID,
+			Data:      data,
+		})
+		if response.Type == byte(enums.ERROR) {
+			return response
+		}
+
+		return s.framedRequest(client, enums.UPLOADCOMMIT, &raspberrypi.TCPUploadCommitRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  uploadID,
+		})
+	}
+
+	s.Run("Checksum mismatch", func() {
+		response := upload(strings.Repeat("b2", 32), strings.Repeat("00", 32), payload)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Equal("upload checksum mismatch, begin it again", string(response.Payload))
+	})
+
+	s.Run("Payload sealed for another network", func() {
+		other, otherChecksum := s.sealCapture(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, utils.GenerateToken(10), []byte("test.pcap"))
+		response := upload(strings.Repeat("c3", 32), otherChecksum, other)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Equal("handshake payload failed authentication", string(response.Payload))
+	})
+
+	s.Run("Chunk over the limit", func() {
+		big := make([]byte, raspberrypi.MaxChunkSize+1)
+		sum := sha256.Sum256(big)
+		response := upload(strings.Repeat("d4", 32), hex.EncodeToString(sum[:]), big)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Equal("message exceeds the maximum allowed size", string(response.Payload))
+	})
+
+	s.Run("Unknown upload", func() {
+		client := s.Client()
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.UPLOADCOMMIT, &raspberrypi.TCPUploadCommitRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  strings.Repeat("e5", 32),
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Equal("upload not found, begin it again", string(response.Payload))
+	})
+
+	s.Run("Upload too large", func() {
+		client := s.Client()
+		defer client.Close()
+
+		response := s.framedRequest(client, enums.UPLOADBEGIN, &raspberrypi.TCPUploadBeginRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  strings.Repeat("f6", 32),
+			SSID:      ssid,
+			BSSID:     bssid,
+			Size:      raspberrypi.MaxUploadSize + 1,
+			Checksum:  checksum,
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Equal("upload exceeds the maximum allowed size", string(response.Payload))
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_LegacyMessageTooLarge() {
+	client := s.Client()
+	defer client.Close()
+	s.Require().NoError(client.SetDeadline(time.Now().Add(3 * time.Minute)))
+
+	// the server must not allocate what the client claims
+	_, err := client.Write([]byte("99999999999\n"))
+	s.Require().NoError(err)
+
+	response, err := bufio.NewReader(client).ReadString('\n')
+	s.Require().NoError(err)
+	s.Require().Equal("Invalid message size\n", response)
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_DeviceCredential() {
+	machineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+
+	request := func(command enums.Command, request any) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+		return s.framedRequest(client, command, request)
+	}
+	deviceLogin := func(credential string) *raspberrypi.Frame {
+		return request(enums.DEVICELOGIN, &raspberrypi.TCPDeviceLoginRequest{
+			MachineID:  machineID,
+			Credential: credential,
+		})
+	}
+	keyExchange := func(token, machineID string) *raspberrypi.Frame {
+		private, err := utils.GenerateExchangeKey()
+		s.Require().NoError(err)
+		return request(enums.KEYEXCHANGE, &raspberrypi.TCPKeyExchangeRequest{
+			Jwt:       token,
+			MachineID: machineID,
+			PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+		})
+	}
+
+	response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.AdminToken, MachineID: machineID})
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	credential := string(response.Payload)
+	s.Require().Regexp("^[0-9a-f]{64}$", credential)
+	s.approveDevice(machineID)
+
+	response = deviceLogin(credential)
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	deviceToken := string(response.Payload)
+	s.Require().True(utils.IsJWT(deviceToken))
+
+	s.Run("Device token is accepted for its own machine", func() {
+		response := keyExchange(deviceToken, machineID)
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	})
+
+	s.Run("Device token is refused for another machine", func() {
+		response := keyExchange(deviceToken, s.ExistingRaspberryMachineID)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "the token was not issued for this device")
+	})
+
+	s.Run("Device token cannot enroll or be used outside the TCP server", func() {
+		response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: deviceToken, MachineID: machineID})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "device tokens can be used only for uploading captures")
+
+		valid, _ := s.Service.Usecase.ValidateToken(deviceToken, constants.ScopeFE)
+		s.Require().False(valid)
+	})
+
+	s.Run("FE token refused by the TCP server", func() {
+		response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.AdminFEToken, MachineID: machineID})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrTokenAudience.Error())
+
+		response = keyExchange(s.AdminFEToken, s.ExistingRaspberryMachineID)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+	})
+
+	rsp, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+	s.Require().NoError(err)
+
+	s.Run("Rotation invalidates the previous credential and its tokens", func() {
+		rotated, err := s.Service.Usecase.RotateRaspberryPICredential(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+
+		response := deviceLogin(credential)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "device credential is not valid")
+
+		response = keyExchange(deviceToken, machineID)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "device credential is not valid")
+
+		response = deviceLogin(rotated)
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+		credential = rotated
+	})
+
+	s.Run("Enrolling an approved device again waits for a new approval", func() {
+		response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.AdminToken, MachineID: machineID})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+		enrolled := string(response.Payload)
+
+		response = deviceLogin(credential)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "device credential is not valid")
+
+		response = deviceLogin(enrolled)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrRaspberryPIPendingApproval.Error())
+
+		s.approveDevice(machineID)
+		response = deviceLogin(enrolled)
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+		credential = enrolled
+	})
+
+	s.Run("Revoked credential is refused", func() {
+		s.Require().NoError(s.Service.Usecase.RevokeRaspberryPICredential(s.UserFixture.UserUUID, rsp.RaspberryPIUUID))
+
+		response := deviceLogin(credential)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "device credential is not valid")
+	})
+
+	s.Run("Another user cannot rotate the credential", func() {
+		_, err := s.Service.Usecase.RotateRaspberryPICredential(s.NormalUser.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().ErrorIs(err, customErrors.ErrElementNotFound)
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_Results() {
+	machineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+	token := s.deviceToken(s.AdminToken, machineID)
+	key := s.exchangeKey(token, machineID)
+	ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+
+	results := func(token, machineID string) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+		return s.framedRequest(client, enums.RESULTS, &raspberrypi.TCPResultsRequest{
+			Jwt:       token,
+			MachineID: machineID,
+		})
+	}
+	decode := func(response *raspberrypi.Frame) []*raspberrypi.TCPResult {
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		var decoded raspberrypi.TCPResultsResponse
+		s.Require().NoError(json.Unmarshal(response.Payload, &decoded))
+		return decoded.Results
+	}
+
+	response := s.sendCommand("HANDSHAKE", &raspberrypi.TCPCreateRaspberryPIRequest{
+		Handshakes: []*entities.Handshake{{
+			SSID:          ssid,
+			BSSID:         bssid,
+			HandshakePCAP: s.sealPCAP(key, machineID, bssid, ssid, []byte("test.pcap")),
+		}},
+		Jwt:       token,
+		MachineID: machineID,
+	})
+	handshakeID := strings.TrimSpace(response)
+	s.Require().Regexp("^[0-9a-f-]{36}$", handshakeID)
+
+	s.Run("Handshakes not cracked yet are not returned", func() {
+		s.Require().Empty(decode(results(token, machineID)))
+	})
+
+	_, err := s.Service.Usecase.UpdateClientTask(s.UserFixture.UserUUID, handshakeID, s.UserClientRegistered.ClientUUID,
+		constants.CrackedStatus, "-m 22000", "logs", "[password]")
+	s.Require().NoError(err)
+
+	s.Run("Cracked handshakes are returned to the device which uploaded them", func() {
+		found := decode(results(token, machineID))
+		s.Require().Len(found, 1)
+		s.Require().Equal(handshakeID, found[0].HandshakeUUID)
+		s.Require().Equal(ssid, found[0].SSID)
+		s.Require().Equal(bssid, found[0].BSSID)
+		s.Require().Equal("[password]", found[0].CrackedHandshake)
+	})
+
+	s.Run("Other devices do not receive them", func() {
+		for _, result := range decode(results(s.AdminDeviceToken, s.ExistingRaspberryMachineID)) {
+			s.Require().NotEqual(handshakeID, result.HandshakeUUID)
+		}
+	})
+
+	s.Run("User tokens cannot fetch the results of the device", func() {
+		response := results(s.AdminToken, machineID)
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrDeviceTokenRequired.Error())
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_GeotaggedUpload() {
+	ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+	payload, checksum := s.sealCapture(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, []byte("test.pcap"))
+	position := &entities.Position{
+		Latitude:  43.7228,
+		Longitude: 10.4017,
+		Date:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	upload := func(uploadID string, position *entities.Position, quality string) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+
+		return s.framedRequest(client, enums.UPLOADBEGIN, &raspberrypi.TCPUploadBeginRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  uploadID,
+			SSID:      ssid,
+			BSSID:     bssid,
+			Size:      int64(len(payload)),
+			Checksum:  checksum,
+			Position:  position,
+			Quality:   quality,
+		})
+	}
+
+	s.Run("Positions out of range are refused", func() {
+		response := upload(strings.Repeat("c3", 32), &entities.Position{Latitude: 91, Longitude: 0, Date: position.Date}, "")
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "Latitude")
+	})
+
+	s.Run("Unknown qualities are refused", func() {
+		response := upload(strings.Repeat("c5", 32), position, "perfect")
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "Quality")
+	})
+
+	s.Run("Position and quality are stored with the handshake", func() {
+		uploadID := strings.Repeat("c4", 32)
+		s.Require().Equal(int64(0), s.uploadOffset(upload(uploadID, position, constants.QualityPartial)))
+
+		client := s.Client()
+		defer client.Close()
+
+		s.Require().Equal(int64(len(payload)), s.uploadOffset(s.framedRequest(client, enums.UPLOADCHUNK, &raspberrypi.TCPUploadChunkRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  uploadID,
+			Data:      payload,
+		})))
+
+		response := s.framedRequest(client, enums.UPLOADCOMMIT, &raspberrypi.TCPUploadCommitRequest{
+			Jwt:       s.AdminDeviceToken,
+			MachineID: s.ExistingRaspberryMachineID,
+			UploadID:  uploadID,
+		})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		handshakes, err := s.Service.Usecase.GetGeotaggedHandshakes(s.UserFixture.UserUUID)
+		s.Require().NoError(err)
+
+		var found *entities.Handshake
+		for _, handshake := range handshakes {
+			if handshake.UUID == string(response.Payload) {
+				found = handshake
+			}
+		}
+		s.Require().NotNil(found)
+		s.Require().InDelta(position.Latitude, *found.Latitude, 1e-9)
+		s.Require().InDelta(position.Longitude, *found.Longitude, 1e-9)
+		s.Require().Equal("2025-01-02 03:04:05", *found.PositionDate)
+		s.Require().Equal(constants.QualityPartial, *found.Quality)
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_HTTPSUpload() {
+	machineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+	ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+
+	request := func(method, route, token string, body any) (int, []byte) {
+		marshaled, err := json.Marshal(body)
+		s.Require().NoError(err)
+
+		req, err := http.NewRequest(method, fmt.Sprintf("http://%s:%s/v1%s", constants.ServerHost, constants.ServerPort, route), bytes.NewReader(marshaled))
+		s.Require().NoError(err)
+		req.Header.Set("Content-Type", constants.JSONContentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		response, err := http.DefaultClient.Do(req)
+		s.Require().NoError(err)
+		defer response.Body.Close()
+
+		content, err := io.ReadAll(response.Body)
+		s.Require().NoError(err)
+		return response.StatusCode, content
+	}
+
+	client := s.Client()
+	response := s.framedRequest(client, enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.AdminToken, MachineID: machineID})
+	client.Close()
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	credential := string(response.Payload)
+	s.approveDevice(machineID)
+
+	status, content := request(http.MethodPost, "/device/login", "", &entities.RaspberryPILoginRequest{
+		MachineID:  machineID,
+		Credential: credential,
+	})
+	s.Require().Equal(http.StatusOK, status, string(content))
+
+	var login entities.UniformResponse
+	s.Require().NoError(json.Unmarshal(content, &login))
+	deviceToken := login.Details
+	s.Require().True(utils.IsJWT(deviceToken))
+
+	private, err := utils.GenerateExchangeKey()
+	s.Require().NoError(err)
+
+	status, content = request(http.MethodPost, "/device/key", deviceToken, &entities.RaspberryPIKeyExchangeRequest{
+		PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+	})
+	s.Require().Equal(http.StatusOK, status, string(content))
+
+	var exchange entities.RaspberryPIKeyExchangeResponse
+	s.Require().NoError(json.Unmarshal(content, &exchange))
+	key, err := utils.DeriveDeviceKey(private, exchange.PublicKey, machineID)
+	s.Require().NoError(err)
+
+	payload, checksum := s.sealCapture(key, machineID, bssid, ssid, []byte("test.pcap"))
+	upload := &entities.RaspberryPIUploadRequest{SSID: ssid, BSSID: bssid, Payload: payload, Quality: constants.QualityComplete}
+
+	s.Run("Wrong credential is refused", func() {
+		status, content := request(http.MethodPost, "/device/login", "", &entities.RaspberryPILoginRequest{
+			MachineID:  machineID,
+			Credential: strings.Repeat("0", 64),
+		})
+		s.Require().Equal(http.StatusUnauthorized, status)
+		s.Require().Contains(string(content), "device credential is not valid")
+	})
+
+	s.Run("User tokens are refused", func() {
+		status, content := request(http.MethodPost, "/device/handshakes", s.AdminToken, upload)
+		s.Require().Equal(http.StatusUnauthorized, status)
+		s.Require().Contains(string(content), "a device token is required")
+	})
+
+	s.Run("Captures sealed with another key are refused", func() {
+		sealed, _ := s.sealCapture(s.RaspberryPIExistingKey, machineID, bssid, ssid, []byte("test.pcap"))
+		status, _ := request(http.MethodPost, "/device/handshakes", deviceToken, &entities.RaspberryPIUploadRequest{
+			SSID:    ssid,
+			BSSID:   bssid,
+			Payload: sealed,
+		})
+		s.Require().Equal(http.StatusBadRequest, status)
+	})
+
+	var handshakeID string
+	s.Run("Capture is saved", func() {
+		status, content := request(http.MethodPost, "/device/handshakes", deviceToken, upload)
+		s.Require().Equal(http.StatusCreated, status, string(content))
+
+		var uploaded entities.RaspberryPIUploadResponse
+		s.Require().NoError(json.Unmarshal(content, &uploaded))
+		s.Require().NotEmpty(uploaded.HandshakeUUID)
+		handshakeID = uploaded.HandshakeUUID
+
+		handshakes, _, err := s.Service.Usecase.GetHandshakesByBSSIDAndSSID(s.UserFixture.UserUUID, bssid, ssid)
+		s.Require().NoError(err)
+		s.Require().Len(handshakes, 1)
+		s.Require().Equal(constants.QualityComplete, *handshakes[0].Quality)
+	})
+
+	s.Run("Duplicates are refused on both transports", func() {
+		status, content := request(http.MethodPost, "/device/handshakes", deviceToken, upload)
+		s.Require().Equal(http.StatusConflict, status)
+		s.Require().Contains(string(content), "handshake already present")
+
+		client := s.Client()
+		defer client.Close()
+		response := s.framedRequest(client, enums.UPLOADBEGIN, &raspberrypi.TCPUploadBeginRequest{
+			Jwt:       deviceToken,
+			MachineID: machineID,
+			UploadID:  strings.Repeat("d5", 32),
+			SSID:      ssid,
+			BSSID:     bssid,
+			Size:      int64(len(payload)),
+			Checksum:  checksum,
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), "handshake already present")
+	})
+
+	s.Run("Results are returned", func() {
+		s.Require().NotEmpty(handshakeID)
+
+		status, content := request(http.MethodGet, "/device/results", deviceToken, nil)
+		s.Require().Equal(http.StatusOK, status, string(content))
+
+		var results entities.RaspberryPIResultsResponse
+		s.Require().NoError(json.Unmarshal(content, &results))
+		s.Require().NotNil(results.Results)
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_BundleImport() {
+	ssid, bssid := utils.GenerateToken(10), utils.GenerateToken(10)
+	payload, checksum := s.sealCapture(s.RaspberryPIExistingKey, s.ExistingRaspberryMachineID, bssid, ssid, []byte("test.pcap"))
+
+	bundle := func(key string, files map[string][]byte, captures ...*entities.RaspberryPIBundleCapture) []byte {
+		manifest, err := json.Marshal(&entities.RaspberryPIBundleManifest{
+			Version:   constants.BundleVersion,
+			MachineID: s.ExistingRaspberryMachineID,
+			Created:   time.Now(),
+			Captures:  captures,
+		})
+		s.Require().NoError(err)
+
+		signature, err := utils.SignBundle(key, manifest)
+		s.Require().NoError(err)
+
+		var buffer bytes.Buffer
+		compressed := gzip.NewWriter(&buffer)
+		archive := tar.NewWriter(compressed)
+		write := func(name string, content []byte) {
+			s.Require().NoError(archive.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content))}))
+			_, err := archive.Write(content)
+			s.Require().NoError(err)
+		}
+
+		write(constants.BundleManifest, manifest)
+		write(constants.BundleSignature, []byte(signature))
+		for name, content := range files {
+			write(name, content)
+		}
+		s.Require().NoError(archive.Close())
+		s.Require().NoError(compressed.Close())
+		return buffer.Bytes()
+	}
+
+	capture := &entities.RaspberryPIBundleCapture{
+		File:     "captures/0001.blob",
+		SSID:     ssid,
+		BSSID:    bssid,
+		Size:     int64(len(payload)),
+		Checksum: checksum,
+	}
+	valid := bundle(s.RaspberryPIExistingKey, map[string][]byte{capture.File: payload}, capture)
+
+	importBundle := func(token string, content []byte) (int, []byte) {
+		marshaled, err := json.Marshal(&entities.ImportRaspberryPIBundleRequest{Bundle: content})
+		s.Require().NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%s/v1/devices/bundle", constants.ServerHost, constants.ServerPort), bytes.NewReader(marshaled))
+		s.Require().NoError(err)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		response, err := http.DefaultClient.Do(req)
+		s.Require().NoError(err)
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		s.Require().NoError(err)
+		return response.StatusCode, body
+	}
+
+	s.Run("Bundles signed with another key are refused", func() {
+		forged := bundle(strings.Repeat("ab", 32), map[string][]byte{capture.File: payload}, capture)
+		status, body := importBundle(s.AdminFEToken, forged)
+		s.Require().Equal(http.StatusBadRequest, status)
+		s.Require().Contains(string(body), "the bundle signature does not match")
+	})
+
+	s.Run("Files which are not bundles are refused", func() {
+		status, body := importBundle(s.AdminFEToken, []byte("test.pcap"))
+		s.Require().Equal(http.StatusBadRequest, status)
+		s.Require().Contains(string(body), customErrors.ErrBundleInvalid.Error())
+	})
+
+	s.Run("Bundles of devices of other users are refused", func() {
+		status, _ := importBundle(s.NormalUserFEToken, valid)
+		s.Require().Equal(http.StatusNotFound, status)
+	})
+
+	s.Run("Captures are imported once", func() {
+		status, body := importBundle(s.AdminFEToken, valid)
+		s.Require().Equal(http.StatusOK, status, string(body))
+
+		var imported entities.ImportRaspberryPIBundleResponse
+		s.Require().NoError(json.Unmarshal(body, &imported))
+		s.Require().Equal(1, imported.Imported)
+		s.Require().Len(imported.Outcomes, 1)
+		s.Require().NotEmpty(imported.Outcomes[0].HandshakeUUID)
+
+		status, body = importBundle(s.AdminFEToken, valid)
+		s.Require().Equal(http.StatusOK, status, string(body))
+		s.Require().NoError(json.Unmarshal(body, &imported))
+		s.Require().Equal(0, imported.Imported)
+		s.Require().Equal(customErrors.ErrHandshakeAlreadyPresent.Error(), imported.Outcomes[0].Error)
+	})
+
+	s.Run("Altered captures are reported", func() {
+		other := *capture
+		other.SSID = utils.GenerateToken(10)
+		altered := bundle(s.RaspberryPIExistingKey, map[string][]byte{other.File: append([]byte{0}, payload...)}, &other)
+
+		imported, err := s.Service.Usecase.ImportRaspberryPIBundle(s.UserFixture.UserUUID, altered)
+		s.Require().NoError(err)
+		s.Require().Equal(0, imported.Imported)
+		s.Require().Equal(customErrors.ErrBundleCaptureAltered.Error(), imported.Outcomes[0].Error)
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_Directives() {
+	machineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+	token := s.deviceToken(s.AdminToken, machineID)
+	s.exchangeKey(token, machineID)
+
+	rsp, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+	s.Require().NoError(err)
+
+	request := func(command enums.Command, request any) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+		return s.framedRequest(client, command, request)
+	}
+	pending := func(token string) []*entities.RaspberryPIDirective {
+		response := request(enums.DIRECTIVES, &raspberrypi.TCPDirectivesRequest{Jwt: token, MachineID: machineID})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		var decoded raspberrypi.TCPDirectivesResponse
+		s.Require().NoError(json.Unmarshal(response.Payload, &decoded))
+		return decoded.Directives
+	}
+	ack := func(token, directiveID string, applied bool, result string) *raspberrypi.Frame {
+		return request(enums.DIRECTIVEACK, &raspberrypi.TCPDirectiveAckRequest{
+			Jwt:           token,
+			MachineID:     machineID,
+			DirectiveUUID: directiveID,
+			Applied:       applied,
+			Result:        result,
+		})
+	}
+
+	scheduleID, err := s.Service.Usecase.CreateRaspberryPIDirective(s.UserFixture.UserUUID, rsp.RaspberryPIUUID,
+		constants.DirectiveSchedule, &entities.DirectiveParameters{Interval: "15m", Windows: []string{"22:00-06:00"}})
+	s.Require().NoError(err)
+
+	logsID, err := s.Service.Usecase.CreateRaspberryPIDirective(s.UserFixture.UserUUID, rsp.RaspberryPIUUID,
+		constants.DirectiveUploadLogs, &entities.DirectiveParameters{Path: "ignored"})
+	s.Require().NoError(err)
+
+	s.Run("Pending directives are delivered in the order they were created", func() {
+		directives := pending(token)
+		s.Require().Len(directives, 2)
+
+		s.Require().Equal(scheduleID, directives[0].UUID)
+		s.Require().Equal(constants.DirectiveSchedule, directives[0].Kind)
+		s.Require().Equal("15m", directives[0].Parameters.Interval)
+		s.Require().Equal([]string{"22:00-06:00"}, directives[0].Parameters.Windows)
+
+		s.Require().Equal(logsID, directives[1].UUID)
+		s.Require().Empty(directives[1].Parameters.Path)
+	})
+
+	s.Run("Acknowledged directives are not delivered again", func() {
+		response := ack(token, scheduleID, true, "")
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		response = ack(token, logsID, true, "first line\nlast line")
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		s.Require().Empty(pending(token))
+
+		directives, err := s.Service.Usecase.GetRaspberryPIDirectives(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Len(directives, 2)
+		s.Require().Equal(logsID, directives[0].UUID)
+		s.Require().Equal(constants.DirectiveApplied, directives[0].Status)
+		s.Require().Equal("first line\nlast line", *directives[0].Result)
+		s.Require().NotNil(directives[0].AcknowledgedDate)
+	})
+
+	s.Run("A directive is acknowledged only once", func() {
+		response := ack(token, scheduleID, false, "too late")
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrDirectiveNotPending.Error())
+	})
+
+	s.Run("Failed directives are recorded as such", func() {
+		purgeID, err := s.Service.Usecase.CreateRaspberryPIDirective(s.UserFixture.UserUUID, rsp.RaspberryPIUUID,
+			constants.DirectivePurge, &entities.DirectiveParameters{})
+		s.Require().NoError(err)
+
+		response := ack(token, purgeID, false, "captures are managed by bettercap")
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		directives, err := s.Service.Usecase.GetRaspberryPIDirectives(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Equal(purgeID, directives[0].UUID)
+		s.Require().Equal(constants.DirectiveFailed, directives[0].Status)
+	})
+
+	s.Run("Pending directives can be cancelled", func() {
+		directiveID, err := s.Service.Usecase.CreateRaspberryPIDirective(s.UserFixture.UserUUID, rsp.RaspberryPIUUID,
+			constants.DirectiveReauthenticate, &entities.DirectiveParameters{})
+		s.Require().NoError(err)
+
+		s.Require().ErrorIs(s.Service.Usecase.CancelRaspberryPIDirective(s.NormalUser.UserUUID, directiveID),
+			customErrors.ErrDirectiveNotPending)
+		s.Require().NoError(s.Service.Usecase.CancelRaspberryPIDirective(s.UserFixture.UserUUID, directiveID))
+		s.Require().Empty(pending(token))
+
+		s.Require().ErrorIs(s.Service.Usecase.CancelRaspberryPIDirective(s.UserFixture.UserUUID, scheduleID),
+			customErrors.ErrDirectiveNotPending)
+	})
+
+	s.Run("Invalid directives are refused", func() {
+		for _, tc := range []struct {
+			kind       string
+			parameters *entities.DirectiveParameters
+		}{
+			{constants.DirectiveSchedule, &entities.DirectiveParameters{}},
+			{constants.DirectiveSchedule, &entities.DirectiveParameters{Interval: "1s"}},
+			{constants.DirectiveSchedule, &entities.DirectiveParameters{Windows: []string{"25:00-06:00"}}},
+			{constants.DirectiveAddWatch, &entities.DirectiveParameters{Path: "captures", Include: []string{"*.pcap"}}},
+			{constants.DirectiveAddWatch, &entities.DirectiveParameters{Path: "/captures"}},
+			{constants.DirectiveAddWatch, &entities.DirectiveParameters{Path: "/captures", Include: []string{"[*.pcap"}}},
+			{constants.DirectiveRemoveWatch, &entities.DirectiveParameters{}},
+			{"shutdown", &entities.DirectiveParameters{}},
+		} {
+			_, err := s.Service.Usecase.CreateRaspberryPIDirective(s.UserFixture.UserUUID, rsp.RaspberryPIUUID, tc.kind, tc.parameters)
+			s.Require().ErrorIs(err, customErrors.ErrDirectiveInvalid, "%s %+v", tc.kind, tc.parameters)
+		}
+	})
+
+	s.Run("Other users cannot manage or fetch the directives of the device", func() {
+		_, err := s.Service.Usecase.CreateRaspberryPIDirective(s.NormalUser.UserUUID, rsp.RaspberryPIUUID,
+			constants.DirectivePurge, &entities.DirectiveParameters{})
+		s.Require().ErrorIs(err, customErrors.ErrElementNotFound)
+
+		response := request(enums.DIRECTIVES, &raspberrypi.TCPDirectivesRequest{Jwt: s.NormalUserToken, MachineID: machineID})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrDeviceTokenRequired.Error())
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_DeviceMetadata() {
+	machineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+
+	request := func(command enums.Command, request any) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+		return s.framedRequest(client, command, request)
+	}
+
+	response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.AdminToken, MachineID: machineID})
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	credential := string(response.Payload)
+	s.approveDevice(machineID)
+
+	rsp, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+	s.Require().NoError(err)
+	s.Require().Nil(rsp.LastSeen)
+	var token string
+
+	s.Run("Metadata is recorded at login", func() {
+		response := request(enums.DEVICELOGIN, &raspberrypi.TCPDeviceLoginRequest{
+			MachineID:     machineID,
+			Credential:    credential,
+			Hostname:      "pwnagotchi",
+			DaemonVersion: "1.4.0",
+			OS:            "Raspbian GNU/Linux 11 (bullseye) linux/arm",
+		})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+		token = string(response.Payload)
+
+		rsp, err := s.Service.Usecase.GetRaspberryPIByUUID(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Equal("pwnagotchi", *rsp.Hostname)
+		s.Require().Equal("1.4.0", *rsp.DaemonVersion)
+		s.Require().Equal("Raspbian GNU/Linux 11 (bullseye) linux/arm", *rsp.OS)
+		s.Require().Equal("127.0.0.1", *rsp.LatestIP)
+		s.Require().NotNil(rsp.LastSeen)
+	})
+
+	s.Run("Check-ins keep the metadata reported at login", func() {
+		response := request(enums.DIRECTIVES, &raspberrypi.TCPDirectivesRequest{Jwt: token, MachineID: machineID})
+		s.Require().Equal(byte(enums.ERROR), response.Type) // no key exchanged yet
+
+		s.exchangeKey(token, machineID)
+		response = request(enums.DIRECTIVES, &raspberrypi.TCPDirectivesRequest{Jwt: token, MachineID: machineID})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		rsp, err := s.Service.Usecase.GetRaspberryPIByUUID(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Equal("pwnagotchi", *rsp.Hostname)
+		s.Require().NotNil(rsp.LastSeen)
+	})
+
+	s.Run("Devices can be renamed by their owner only", func() {
+		s.Require().ErrorIs(s.Service.Usecase.RenameRaspberryPI(s.NormalUser.UserUUID, rsp.RaspberryPIUUID, "stolen"),
+			customErrors.ErrElementNotFound)
+
+		s.Require().NoError(s.Service.Usecase.RenameRaspberryPI(s.UserFixture.UserUUID, rsp.RaspberryPIUUID, " backpack "))
+		renamed, err := s.Service.Usecase.GetRaspberryPIByUUID(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Equal("backpack", *renamed.Name)
+
+		s.Require().NoError(s.Service.Usecase.RenameRaspberryPI(s.UserFixture.UserUUID, rsp.RaspberryPIUUID, ""))
+		renamed, err = s.Service.Usecase.GetRaspberryPIByUUID(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Nil(renamed.Name)
+	})
+
+	s.Run("Captures and activity of the device", func() {
+		for _, status := range []string{constants.NothingStatus, constants.CrackedStatus} {
+			_, err := s.Service.Usecase.CreateRaspberryPIHandshake(s.UserFixture.UserUUID, machineID, "ssid-"+status,
+				"aa:bb:cc:dd:ee:ff", status, utils.StringToBase64String("test.pcap"), nil, "")
+			s.Require().NoError(err)
+		}
+
+		handshakes, length, err := s.Service.Usecase.GetRaspberryPIHandshakes(s.UserFixture.UserUUID, rsp.RaspberryPIUUID, 1)
+		s.Require().NoError(err)
+		s.Require().Equal(2, length)
+		s.Require().Len(handshakes, 2)
+		for _, handshake := range handshakes {
+			s.Require().Equal(rsp.RaspberryPIUUID, *handshake.RaspberryPIUUID)
+			s.Require().Nil(handshake.HandshakePCAP)
+		}
+
+		activity, err := s.Service.Usecase.GetRaspberryPIActivity(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Len(activity, 1)
+		s.Require().Equal(2, activity[0].Uploaded)
+		s.Require().Equal(1, activity[0].Cracked)
+
+		_, length, err = s.Service.Usecase.GetRaspberryPIHandshakes(s.NormalUser.UserUUID, rsp.RaspberryPIUUID, 1)
+		s.Require().NoError(err)
+		s.Require().Zero(length)
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_Revocation() {
+	machineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+
+	request := func(command enums.Command, request any) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+		return s.framedRequest(client, command, request)
+	}
+
+	_, err := s.Service.Usecase.CreateRaspberryPI(s.NormalUser.UserUUID, machineID, "")
+	s.Require().NoError(err)
+	s.approveDevice(machineID)
+
+	client := s.TLSClient(nil)
+	defer client.Close()
+
+	response := s.framedRequest(client, enums.CERTIFICATE, &raspberrypi.TCPCertificateRequest{
+		Jwt:       s.NormalUserToken,
+		MachineID: machineID,
+	})
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+	var enrolled raspberrypi.TCPCertificateResponse
+	s.Require().NoError(json.Unmarshal(response.Payload, &enrolled))
+	certificate, err := tls.X509KeyPair(enrolled.ClientCert, enrolled.ClientKey)
+	s.Require().NoError(err)
+
+	rsp, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+	s.Require().NoError(err)
+	token := s.deviceToken(s.NormalUserToken, machineID)
+
+	// a connection which already acted for the device
+	connected := s.TLSClient(&certificate)
+	defer connected.Close()
+	private, err := utils.GenerateExchangeKey()
+	s.Require().NoError(err)
+	response = s.framedRequest(connected, enums.KEYEXCHANGE, &raspberrypi.TCPKeyExchangeRequest{
+		Jwt:       token,
+		MachineID: machineID,
+		PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+	})
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+	s.Run("Devices can be deleted by their owner only", func() {
+		_, err := s.Service.Usecase.DeleteRaspberryPI(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().ErrorIs(err, customErrors.ErrElementNotFound)
+
+		deleted, err := s.Service.Usecase.DeleteRaspberryPI(s.NormalUser.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().True(deleted)
+	})
+
+	s.Run("Connections of the revoked device are dropped", func() {
+		s.Require().NoError(connected.SetDeadline(time.Now().Add(5 * time.Second)))
+		_, err := connected.Read(make([]byte, 1))
+		s.Require().Error(err)
+		s.Require().NotErrorIs(err, os.ErrDeadlineExceeded)
+	})
+
+	s.Run("Revoked devices are not created again", func() {
+		response := request(enums.KEYEXCHANGE, &raspberrypi.TCPKeyExchangeRequest{
+			Jwt:       token,
+			MachineID: machineID,
+			PublicKey: utils.BytesToBase64String(private.PublicKey().Bytes()),
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrRaspberryPIRevoked.Error())
+
+		response = request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.NormalUserToken, MachineID: machineID})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrRaspberryPIRevoked.Error())
+
+		_, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+		s.Require().ErrorIs(err, customErrors.ErrElementNotFound)
+	})
+
+	s.Run("Certificates of the revoked device are refused", func() {
+		revoked := s.TLSClient(&certificate)
+		defer revoked.Close()
+
+		s.Require().NoError(raspberrypi.WriteFrame(revoked, &raspberrypi.Frame{
+			Version: raspberrypi.FrameVersion,
+			Type:    byte(enums.RESULTS),
+			Payload: []byte("{}"),
+		}))
+		s.Require().NoError(revoked.SetDeadline(time.Now().Add(5 * time.Second)))
+		_, err := raspberrypi.ReadFrame(bufio.NewReader(revoked))
+		s.Require().Error(err)
+	})
+
+	s.Run("Approved devices can connect again", func() {
+		revocations, length, err := s.Service.Usecase.GetRevocations(s.NormalUser.UserUUID, 1)
+		s.Require().NoError(err)
+		s.Require().Equal(1, length)
+		s.Require().Equal(constants.RevokedRaspberryPI, revocations[0].Kind)
+		s.Require().Equal(machineID, revocations[0].MachineID)
+		s.Require().Equal(rsp.RaspberryPIUUID, revocations[0].EntityUUID)
+
+		s.Require().ErrorIs(s.Service.Usecase.ApproveRevocation(s.UserFixture.UserUUID, revocations[0].UUID), customErrors.ErrElementNotFound)
+		s.Require().NoError(s.Service.Usecase.ApproveRevocation(s.NormalUser.UserUUID, revocations[0].UUID))
+
+		response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.NormalUserToken, MachineID: machineID})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		recreated, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+		s.Require().NoError(err)
+		s.Require().NotEqual(rsp.RaspberryPIUUID, recreated.RaspberryPIUUID)
+		s.Require().False(recreated.Approved)
+	})
+}
+
+func (s *ServerTCPIPSuite) Test_TCPServer_Approval() {
+	machineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+
+	request := func(command enums.Command, request any) *raspberrypi.Frame {
+		client := s.Client()
+		defer client.Close()
+		return s.framedRequest(client, command, request)
+	}
+	approval := func(token, rspUUID string, approve bool) int {
+		marshaled, err := json.Marshal(&entities.ApproveRaspberryPIRequest{RaspberryPIUUID: rspUUID, Approve: &approve})
+		s.Require().NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%s/v1/devices/approval", constants.ServerHost, constants.ServerPort), bytes.NewReader(marshaled))
+		s.Require().NoError(err)
+		req.Header.Set("Content-Type", constants.JSONContentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		response, err := http.DefaultClient.Do(req)
+		s.Require().NoError(err)
+		defer response.Body.Close()
+		return response.StatusCode
+	}
+
+	response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.AdminToken, MachineID: machineID})
+	s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	credential := string(response.Payload)
+
+	rsp, err := s.Service.Usecase.GetRaspberryPIByMachineID(machineID)
+	s.Require().NoError(err)
+	s.Require().False(rsp.Approved)
+
+	s.Run("The enrollment code is the one computed by the daemon", func() {
+		sum := sha256.Sum256([]byte(credential))
+		code := usecase.RaspberryPIEnrollmentCode(rsp)
+		s.Require().Regexp(`^[0-9A-F]{4}-[0-9A-F]{4}$`, code)
+		s.Require().Equal(utils.EnrollmentCode(hex.EncodeToString(sum[:])), code)
+	})
+
+	s.Run("Devices waiting for approval cannot log in", func() {
+		response := request(enums.DEVICELOGIN, &raspberrypi.TCPDeviceLoginRequest{
+			MachineID:  machineID,
+			Credential: credential,
+			Hostname:   "pending",
+		})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrRaspberryPIPendingApproval.Error())
+
+		// the user can still recognize the device
+		pending, err := s.Service.Usecase.GetRaspberryPIByUUID(s.UserFixture.UserUUID, rsp.RaspberryPIUUID)
+		s.Require().NoError(err)
+		s.Require().Equal("pending", *pending.Hostname)
+	})
+
+	s.Run("Devices are approved by their owner only", func() {
+		s.Require().Equal(http.StatusNotFound, approval(s.NormalUserFEToken, rsp.RaspberryPIUUID, true))
+		s.Require().Equal(http.StatusOK, approval(s.AdminFEToken, rsp.RaspberryPIUUID, true))
+		s.Require().Equal(http.StatusConflict, approval(s.AdminFEToken, rsp.RaspberryPIUUID, true))
+
+		response := request(enums.DEVICELOGIN, &raspberrypi.TCPDeviceLoginRequest{MachineID: machineID, Credential: credential})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+		token := string(response.Payload)
+
+		s.exchangeKey(token, machineID)
+		response = request(enums.RESULTS, &raspberrypi.TCPResultsRequest{Jwt: token, MachineID: machineID})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+	})
+
+	s.Run("Rejected devices are revoked", func() {
+		rejectedMachineID := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10))))
+		response := request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.NormalUserToken, MachineID: rejectedMachineID})
+		s.Require().Equal(byte(enums.RESPONSE), response.Type, string(response.Payload))
+
+		rejected, err := s.Service.Usecase.GetRaspberryPIByMachineID(rejectedMachineID)
+		s.Require().NoError(err)
+		s.Require().Equal(http.StatusOK, approval(s.NormalUserFEToken, rejected.RaspberryPIUUID, false))
+
+		response = request(enums.ENROLL, &raspberrypi.TCPEnrollRequest{Jwt: s.NormalUserToken, MachineID: rejectedMachineID})
+		s.Require().Equal(byte(enums.ERROR), response.Type)
+		s.Require().Contains(string(response.Payload), customErrors.ErrRaspberryPIRevoked.Error())
+
+		revocations, _, err := s.Service.Usecase.GetRevocations(s.NormalUser.UserUUID, 1)
+		s.Require().NoError(err)
+		s.Require().NotEmpty(revocations)
+		s.Require().Equal(rejected.RaspberryPIUUID, revocations[0].EntityUUID)
+		s.Require().NoError(s.Service.Usecase.ApproveRevocation(s.NormalUser.UserUUID, revocations[0].UUID))
+	})
+}
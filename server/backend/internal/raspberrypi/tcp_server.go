@@ -2,7 +2,10 @@ package raspberrypi
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/enums"
 	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	"github.com/Virgula0/progetto-dp/server/entities"
@@ -16,11 +19,93 @@ type TCPHandler interface {
 	RunTCPServer()
 }
 
+// TCPCreateRaspberryPIRequest HandshakePCAP of each handshake must be encrypted with the key agreed through KEYEXCHANGE
 type TCPCreateRaspberryPIRequest struct {
-	Handshakes    []*entities.Handshake
+	Handshakes []*entities.Handshake
+	Jwt        string `validate:"required,jwt"`
+	MachineID  string `validate:"required,len=32"`
+}
+
+// TCPKeyExchangeRequest PublicKey is the base64 encoded X25519 public key of the daemon
+type TCPKeyExchangeRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+	PublicKey string `validate:"required,base64"`
+}
+
+// TCPCertificateRequest enrolls a certificate for the device, it can be sent only over TLS
+type TCPCertificateRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+// TCPCertificateResponse PEM encoded certificates. CACert is the CA which signed both the server and the device certificate
+type TCPCertificateResponse struct {
+	CACert     []byte
+	ClientCert []byte
+	ClientKey  []byte
+}
+
+// TCPEnrollRequest approves the device on behalf of the user, Jwt must be obtained with LOGIN
+type TCPEnrollRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+// TCPDeviceLoginRequest Credential is the one returned by ENROLL. Hostname, DaemonVersion and OS are recorded as
+// the device metadata, older daemons do not send them
+type TCPDeviceLoginRequest struct {
+	MachineID     string `validate:"required,len=32"`
+	Credential    string `validate:"required,hexadecimal,len=64"`
+	Hostname      string `validate:"max=255"`
+	DaemonVersion string `validate:"max=50"`
+	OS            string `validate:"max=100"`
+}
+
+// TCPResultsRequest asks for the cracked handshakes among the ones uploaded by the device
+type TCPResultsRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+// TCPResult CrackedHandshake is the value found by hashcat, as reported by the client
+type TCPResult struct {
+	HandshakeUUID    string
+	SSID             string
+	BSSID            string
+	UploadedDate     string
+	CrackedHandshake string
+}
+
+type TCPResultsResponse struct {
+	Results []*TCPResult
+}
+
+// TCPDirectivesRequest asks for the directives the device has not acknowledged yet, it is sent on each check-in
+type TCPDirectivesRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+}
+
+type TCPDirectivesResponse struct {
+	Directives []*entities.RaspberryPIDirective
+}
+
+// TCPDirectiveAckRequest Applied tells whether the device applied the directive, Result what happened or why it failed
+type TCPDirectiveAckRequest struct {
 	Jwt           string `validate:"required,jwt"`
 	MachineID     string `validate:"required,len=32"`
-	EncryptionKey string `validate:"omitempty,len=64"`
+	DirectiveUUID string `validate:"required"`
+	Applied       bool
+	Result        string
+}
+
+// connectionInfo what is known about the peer of a connection
+type connectionInfo struct {
+	certificate *x509.Certificate // verified against our CA, nil when the peer did not present one
+	address     string
+	machineID   string // the device the connection acted for, guarded by TCPServer.connectionsMutex
+	legacy      bool   // the peer speaks the length-line/ACK protocol
 }
 
 var statusACK = enums.ACK.String()
@@ -40,27 +125,15 @@ func (wr *TCPServer) RunTCPServer() error {
 		go wr.handleClientConnection(client)
 	}
 }
-func (wr *TCPServer) processClientRequestManager(processClientRequestErrChann chan error, client net.Conn) {
-	defer func() {
-		if _, closed := <-processClientRequestErrChann; closed {
-			return
-		}
-		close(processClientRequestErrChann)
-	}()
-
-	// for loop needed for sending both handshake and login using a single connection
-	for {
-		if err := wr.processClientRequest(client); err != nil && !errors.Is(err, customErrors.ErrHandshakeAlreadyPresent) {
-			processClientRequestErrChann <- err
-			return
-		}
-	}
-}
 
-// handleClientConnection accept request from client
+// handleClientConnection accept request from client.
+// Each connection owns its reader, the protocol is chosen looking at the first byte sent by the client
 func (wr *TCPServer) handleClientConnection(client net.Conn) {
 	defer client.Close()
-	processClientRequestErrChann := make(chan error, 1)
+
+	info := peerInfo(client)
+	wr.track(info, client)
+	defer wr.untrack(info)
 
 	err := client.SetDeadline(time.Now().Add(wr.timeout))
 	if err != nil {
@@ -68,80 +141,201 @@ func (wr *TCPServer) handleClientConnection(client net.Conn) {
 		return
 	}
 
-	// Start processing the client request in a separate goroutine
-	go wr.processClientRequestManager(processClientRequestErrChann, client)
+	reader := bufio.NewReader(client)
+
+	// with TLS, the handshake happens on the first read
+	first, err := reader.Peek(1)
+	wr.connectionsMutex.Lock()
+	info.certificate = peerCertificate(client)
+	wr.connectionsMutex.Unlock()
+	switch {
+	case err != nil: // nothing has been sent
+	case first[0] == FrameMagic[0]:
+		err = wr.serveFramed(client, reader, info)
+	default:
+		err = wr.serveLegacy(client, reader, info)
+	}
 
-	if err := <-processClientRequestErrChann; err != nil {
-		if err == io.EOF {
+	if err != nil {
+		if errors.Is(err, io.EOF) {
 			log.Warnf("[TCP/IP] Connection closed by client: %s", err.Error())
 			return
 		}
 		log.Errorf("[TCP/IP] Error processing client request: %s", err.Error())
-		return
 	}
 }
 
-func (wr *TCPServer) sendACKToTheClient(client net.Conn) error {
-	if _, err := client.Write([]byte(statusACK)); err != nil {
-		return err
+func peerInfo(client net.Conn) *connectionInfo {
+	return &connectionInfo{address: client.RemoteAddr().String()}
+}
+
+// peerCertificate the certificate presented by the peer, the TLS handshake must have happened already
+func peerCertificate(client net.Conn) *x509.Certificate {
+	if tlsConn, ok := client.(*tls.Conn); ok {
+		if state := tlsConn.ConnectionState(); len(state.PeerCertificates) > 0 {
+			return state.PeerCertificates[0]
+		}
 	}
-	/*
-		for some reason, it can happen that ACKs are read after error messages even if they're sent first. this may need further investigations
-		The Nagle's Algorithm in TCP stack is not the problem, as by default is disabled in go
-		there is no way to avoid this sleep, I tried everything, mutex, channels and so on...
-	*/
-	time.Sleep(wr.sleepTime)
 	return nil
 }
 
+func (wr *TCPServer) track(info *connectionInfo, client net.Conn) {
+	wr.connectionsMutex.Lock()
+	defer wr.connectionsMutex.Unlock()
+	wr.connections[info] = client
+}
+
+func (wr *TCPServer) untrack(info *connectionInfo) {
+	wr.connectionsMutex.Lock()
+	defer wr.connectionsMutex.Unlock()
+	delete(wr.connections, info)
+}
+
+// identify records the device the connection acts for, so that it can be dropped when the device is revoked
+func (wr *TCPServer) identify(info *connectionInfo, machineID string) {
+	wr.connectionsMutex.Lock()
+	defer wr.connectionsMutex.Unlock()
+	info.machineID = machineID
+}
+
+// dropRevoked closes the connections of a revoked device, recognized by its machine ID or by its certificate
+func (wr *TCPServer) dropRevoked(revocation *entities.Revocation) {
+	if revocation.Kind != constants.RevokedRaspberryPI {
+		return
+	}
+
+	wr.connectionsMutex.Lock()
+	defer wr.connectionsMutex.Unlock()
+
+	for info, client := range wr.connections {
+		if info.machineID != revocation.MachineID &&
+			(info.certificate == nil || info.certificate.Subject.SerialNumber != revocation.EntityUUID) {
+			continue
+		}
+
+		log.Warnf("[TCP/IP] Dropping the connection of revoked device %s from %s", revocation.MachineID, info.address)
+		if err := client.Close(); err != nil {
+			log.Errorf("[TCP/IP] Error closing the connection of %s: %s", info.address, err.Error())
+		}
+	}
+}
+
+// serveFramed answers frames until the client closes the connection. Errors of a single request are sent back as
+// an ERROR frame and do not close the connection, only malformed frames do
+func (wr *TCPServer) serveFramed(client net.Conn, reader *bufio.Reader, info *connectionInfo) error {
+	for {
+		frame, err := ReadFrame(reader)
+		if err != nil {
+			if frame != nil { // the header was readable, tell the client why it is being dropped
+				_ = wr.writeFrame(client, enums.ERROR, frame.RequestID, []byte(err.Error()))
+			}
+			return err
+		}
+
+		// the deadline is an idle timeout, refreshed on each request
+		if err = client.SetDeadline(time.Now().Add(wr.timeout)); err != nil {
+			return err
+		}
+
+		log.Infof("[TCP/IP] Received framed request %d of type %d", frame.RequestID, frame.Type)
+
+		responseType := enums.RESPONSE
+		response, err := wr.processCommand(info, enums.Command(frame.Type), frame.Payload)
+		if err != nil {
+			log.Warnf("[TCP/IP] Request %d failed: %s", frame.RequestID, err.Error())
+			responseType, response = enums.ERROR, []byte(err.Error())
+		}
+
+		if err = wr.writeFrame(client, responseType, frame.RequestID, response); err != nil {
+			return err
+		}
+	}
+}
+
+func (wr *TCPServer) writeFrame(client net.Conn, responseType enums.ResponseType, requestID uint32, payload []byte) error {
+	err := WriteFrame(client, &Frame{
+		Version:   FrameVersion,
+		Type:      byte(responseType),
+		RequestID: requestID,
+		Payload:   payload,
+	})
+	if err != nil {
+		log.Errorf("[TCP/IP] Error writing to client: %s", err.Error())
+	}
+	return err
+}
+
+// serveLegacy speaks the original length-line/ACK protocol.
+// for loop needed for sending both handshake and login using a single connection
+func (wr *TCPServer) serveLegacy(client net.Conn, reader *bufio.Reader, info *connectionInfo) error {
+	info.legacy = true
+	for {
+		if err := wr.processClientRequest(client, reader, info); err != nil && !errors.Is(err, customErrors.ErrHandshakeAlreadyPresent) {
+			return err
+		}
+	}
+}
+
+func (wr *TCPServer) sendACKToTheClient(client net.Conn) error {
+	return wr.sendLegacyStatus(client, statusACK)
+}
+
 func (wr *TCPServer) sendACKFailedToTheClient(client net.Conn) error {
-	if _, err := client.Write([]byte(statusErrorACK)); err != nil {
+	return wr.sendLegacyStatus(client, statusErrorACK)
+}
+
+func (wr *TCPServer) sendLegacyStatus(client net.Conn, status string) error {
+	if _, err := client.Write([]byte(status)); err != nil {
 		return err
 	}
 	/*
-		for some reason, it can happen that ACKs are read after error messages even if they're sent first. this may need further investigations
-		The Nagle's Algorithm in TCP stack is not the problem, as by default is disabled in go
-		there is no way to avoid this sleep, I tried everything, mutex, channels and so on...
+		Legacy daemons create a new bufio.Reader for every read, so an ACK immediately followed by another message
+		can end up buffered by a reader which is then discarded. Give them the time to consume the ACK alone.
+		Framed clients are not affected.
 	*/
 	time.Sleep(wr.sleepTime)
 	return nil
 }
 
-func (wr *TCPServer) handshake(client net.Conn) error {
-	reader := bufio.NewReader(client)
-
+// readLegacyMessage reads a length line and the following content, acknowledging both
+func (wr *TCPServer) readLegacyMessage(client net.Conn, reader *bufio.Reader) ([]byte, error) {
 	// Step 1: Read message size
-	messageSize, errReadMsg := wr.readMessageSize(reader)
-	if errReadMsg != nil {
+	messageSize, err := wr.readMessageSize(reader)
+	if err != nil {
 		wr.writeErrorToClient(client, "Invalid message size")
-		return errReadMsg
+		return nil, err
 	}
 
 	// Step 2: Send ACK to the client for the length
 	if errFirstAckClient := wr.sendACKToTheClient(client); errFirstAckClient != nil {
-		return errFirstAckClient
+		return nil, errFirstAckClient
 	}
 
 	// Step 3: Read the actual message content
 	buffer, err := wr.readMessageContent(reader, messageSize)
 	if err != nil {
 		wr.writeErrorToClient(client, "Error reading message content")
-		return err
+		return nil, err
 	}
 
 	// Step 4: Send ACK to the client for the message
 	if errSecondAckClient := wr.sendACKToTheClient(client); errSecondAckClient != nil {
-		return errSecondAckClient
+		return nil, errSecondAckClient
 	}
 
-	log.Printf("[TCP/IP] Received message: %s", string(buffer))
+	return buffer, nil
+}
 
-	// Step 5: Process the message type
-	return wr.processHandshakeMessage(buffer, client)
+// legacyCommands maps the command names of the legacy protocol
+var legacyCommands = map[string]enums.Command{
+	enums.LOGIN.String():       enums.LOGIN,
+	enums.HANDSHAKE.String():   enums.HANDSHAKE,
+	enums.KEYEXCHANGE.String(): enums.KEYEXCHANGE,
+	enums.CERTIFICATE.String(): enums.CERTIFICATE,
 }
 
-func (wr *TCPServer) login(client net.Conn) error {
-	reader := bufio.NewReader(client)
+// processClientRequest parses the client request
+func (wr *TCPServer) processClientRequest(client net.Conn, reader *bufio.Reader, info *connectionInfo) error {
 	// 1. Read message size
 	messageSize, err := wr.readMessageSize(reader)
 	if err != nil {
@@ -149,7 +343,7 @@ func (wr *TCPServer) login(client net.Conn) error {
 		return err
 	}
 
-	// Step 2: Send ACK to the client for the length
+	// Step 2: Send ACK of the message length to the client
 	if errFirstAckClient := wr.sendACKToTheClient(client); errFirstAckClient != nil {
 		return errFirstAckClient
 	}
@@ -161,59 +355,32 @@ func (wr *TCPServer) login(client net.Conn) error {
 		return err
 	}
 
-	// Step 4: Send ACK to the client for the message
+	command, found := legacyCommands[string(buffer)]
+	if !found {
+		// Step 4: Send ACK FAIL of command to the client
+		log.Errorf("[TCP/IP] Received invalid command request: %s", string(buffer))
+		return wr.sendACKFailedToTheClient(client)
+	}
+
+	// Step 4: Send ACK of the command to the client
+	log.Infof("[TCP/IP] Received %s message", command)
 	if errSecondAckClient := wr.sendACKToTheClient(client); errSecondAckClient != nil {
 		return errSecondAckClient
 	}
 
-	// Step 5: process login
-	return wr.processLoginMessage(buffer, client)
-}
-
-// processClientRequest parses the client request
-func (wr *TCPServer) processClientRequest(client net.Conn) error {
-	reader := bufio.NewReader(client)
-	// 1. Read message size
-	messageSize, err := wr.readMessageSize(reader)
+	// Step 5: Read the request of the command
+	request, err := wr.readLegacyMessage(client, reader)
 	if err != nil {
-		wr.writeErrorToClient(client, "Invalid message size")
 		return err
 	}
 
-	// Step 3: Send ACK of the message length to the client
-	if errFirstAckClient := wr.sendACKToTheClient(client); errFirstAckClient != nil {
-		return errFirstAckClient
-	}
-
-	// Step 4: Read the actual message content
-	buffer, err := wr.readMessageContent(reader, messageSize)
+	// Step 6: Process it and answer with a single line
+	response, err := wr.processCommand(info, command, request)
 	if err != nil {
-		wr.writeErrorToClient(client, "Error reading message content")
+		wr.writeErrorToClient(client, err.Error())
 		return err
 	}
 
-	// Step 3. Process login or command action
-	switch string(buffer) {
-	case enums.LOGIN.String():
-		// Step 4: Send ACK of the message length to the client
-		log.Infof("[TCP/IP] Received login message")
-		if errSecondAckClient := wr.sendACKToTheClient(client); errSecondAckClient != nil {
-			return errSecondAckClient
-		}
-		return wr.login(client)
-	case enums.HANDSHAKE.String():
-		// Step 4: Send ACK of the message length to the client
-		log.Infof("[TCP/IP] Received handshake message")
-		if errSecondAckClient := wr.sendACKToTheClient(client); errSecondAckClient != nil {
-			return errSecondAckClient
-		}
-		return wr.handshake(client)
-	default:
-		// Step 4: Send ACK FAIL of command to the client
-		log.Errorf("[TCP/IP] Received invalid command request: %s", string(buffer))
-		if errSecondAckFailClient := wr.sendACKFailedToTheClient(client); errSecondAckFailClient != nil {
-			return errSecondAckFailClient
-		}
-		return err
-	}
+	_, err = client.Write(append(response, '\n'))
+	return err
 }
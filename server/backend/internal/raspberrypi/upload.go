@@ -0,0 +1,399 @@
+package raspberrypi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxUploadSize maximum size of an encrypted capture, and of the capture once decompressed
+	MaxUploadSize = constants.MaxCaptureSize
+	// MaxChunkSize maximum size of the data carried by a single UPLOADCHUNK
+	MaxChunkSize = 1 << 20
+	// uploadExpiration uploads not completed within this time are deleted
+	uploadExpiration = 24 * time.Hour
+)
+
+/*
+A capture is uploaded with three commands:
+
+ 1. UPLOADBEGIN declares the capture, the answer contains the offset the upload must continue from
+ 2. UPLOADCHUNK appends data at Offset, each chunk is acknowledged with the new offset
+ 3. UPLOADCOMMIT verifies the checksum, decrypts and decompresses the capture and saves the handshake
+
+The payload is gzip(pcap) sealed with the device key, UploadID is chosen by the daemon and must stay the same
+across reconnections for the upload to be resumed.
+*/
+
+// TCPUploadBeginRequest Size and Checksum (hex sha256) refer to the encrypted payload.
+// Position is where the capture was taken, nil when the daemon had no GPS fix. Quality is the classification of the capture
+// made by the daemon, older daemons do not send it
+type TCPUploadBeginRequest struct {
+	Jwt       string             `validate:"required,jwt"`
+	MachineID string             `validate:"required,len=32"`
+	UploadID  string             `validate:"required,hexadecimal,len=64"`
+	SSID      string             `validate:"required"`
+	BSSID     string             `validate:"required"`
+	Size      int64              `validate:"required,gt=0"`
+	Checksum  string             `validate:"required,hexadecimal,len=64"`
+	Position  *entities.Position `validate:"omitempty"`
+	Quality   string             `validate:"omitempty,oneof=complete partial missing"`
+}
+
+type TCPUploadChunkRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+	UploadID  string `validate:"required,hexadecimal,len=64"`
+	Offset    int64  `validate:"gte=0"`
+	Data      []byte `validate:"required"`
+}
+
+type TCPUploadCommitRequest struct {
+	Jwt       string `validate:"required,jwt"`
+	MachineID string `validate:"required,len=32"`
+	UploadID  string `validate:"required,hexadecimal,len=64"`
+}
+
+// TCPUploadResponse Offset is the number of bytes the server holds, the next chunk must start there
+type TCPUploadResponse struct {
+	Offset int64
+}
+
+// uploadMetadata stored next to the partial upload
+type uploadMetadata struct {
+	SSID     string
+	BSSID    string
+	Size     int64
+	Checksum string
+	Position *entities.Position
+	Quality  string
+}
+
+// sameCapture tells whether the metadata declare the same payload, the position does not change what is uploaded
+func (m *uploadMetadata) sameCapture(other *uploadMetadata) bool {
+	return m.SSID == other.SSID && m.BSSID == other.BSSID && m.Size == other.Size && m.Checksum == other.Checksum
+}
+
+// uploadLock serializes the requests on an upload, holders counts the requests holding or waiting for it
+type uploadLock struct {
+	sync.Mutex
+	holders int
+}
+
+// uploadStore keeps partial uploads on disk, so that memory usage does not depend on the capture size
+type uploadStore struct {
+	dir   string
+	mutex sync.Mutex
+	locks map[string]*uploadLock
+}
+
+func newUploadStore(dir string) (*uploadStore, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "hds-uploads")
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &uploadStore{
+		dir:   dir,
+		locks: make(map[string]*uploadLock),
+	}, nil
+}
+
+// name upload IDs are chosen by the client, the owner is part of the file name so that they can't collide
+func (s *uploadStore) name(userID, machineID, uploadID string) string {
+	sum := sha256.Sum256([]byte(userID + "|" + machineID + "|" + uploadID))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *uploadStore) dataPath(name string) string {
+	return filepath.Join(s.dir, name+".part")
+}
+
+func (s *uploadStore) metadataPath(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// lock serializes the requests on the same upload, returning the function releasing it.
+// The lock is forgotten once released by the last request, so only the uploads in progress have one
+func (s *uploadStore) lock(name string) func() {
+	s.mutex.Lock()
+	lock, found := s.locks[name]
+	if !found {
+		lock = &uploadLock{}
+		s.locks[name] = lock
+	}
+	lock.holders++
+	s.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if lock.holders--; lock.holders == 0 {
+			delete(s.locks, name)
+		}
+	}
+}
+
+func (s *uploadStore) readMetadata(name string) (*uploadMetadata, error) {
+	content, err := os.ReadFile(s.metadataPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, customErrors.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata uploadMetadata
+	if err = json.Unmarshal(content, &metadata); err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+// partSize size of the data received so far
+func (s *uploadStore) partSize(name string) (int64, error) {
+	info, err := os.Stat(s.dataPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, customErrors.ErrUploadNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// begin creates the upload or, if the same capture was already declared, returns how much of it has been received
+func (s *uploadStore) begin(name string, metadata *uploadMetadata) (int64, error) {
+	s.sweep()
+
+	defer s.lock(name)()
+
+	previous, err := s.readMetadata(name)
+	if err == nil && previous.sameCapture(metadata) {
+		if offset, errSize := s.partSize(name); errSize == nil && offset <= metadata.Size {
+			return offset, nil
+		}
+	}
+
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = os.WriteFile(s.dataPath(name), nil, 0o600); err != nil {
+		return 0, err
+	}
+
+	return 0, os.WriteFile(s.metadataPath(name), content, 0o600)
+}
+
+// write appends data if offset is where the upload stopped. Whatever happens, the current offset is returned
+func (s *uploadStore) write(name string, offset int64, data []byte) (int64, error) {
+	defer s.lock(name)()
+
+	metadata, err := s.readMetadata(name)
+	if err != nil {
+		return 0, err
+	}
+
+	current, err := s.partSize(name)
+	if err != nil {
+		return 0, err
+	}
+
+	// chunk already received or sent ahead of time, the client resyncs with the offset we answer
+	if offset != current {
+		return current, nil
+	}
+
+	if current+int64(len(data)) > metadata.Size {
+		return current, customErrors.ErrUploadTooLarge
+	}
+
+	file, err := os.OpenFile(s.dataPath(name), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return current, err
+	}
+
+	written, err := file.Write(data)
+	return current + int64(written), errors.Join(err, file.Close())
+}
+
+// read returns the upload once complete. When its checksum does not match the upload is deleted
+func (s *uploadStore) read(name string) (*uploadMetadata, []byte, error) {
+	defer s.lock(name)()
+
+	metadata, err := s.readMetadata(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload, err := os.ReadFile(s.dataPath(name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if int64(len(payload)) != metadata.Size {
+		return nil, nil, customErrors.ErrUploadIncomplete
+	}
+
+	if sum := sha256.Sum256(payload); hex.EncodeToString(sum[:]) != metadata.Checksum {
+		s.remove(name)
+		return nil, nil, customErrors.ErrUploadChecksum
+	}
+
+	return metadata, payload, nil
+}
+
+func (s *uploadStore) remove(name string) {
+	for _, path := range []string{s.dataPath(name), s.metadataPath(name)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Errorf("[TCP/IP] Error removing upload %s: %s", path, err.Error())
+		}
+	}
+}
+
+// sweep deletes the uploads abandoned by the clients
+func (s *uploadStore) sweep() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Errorf("[TCP/IP] Error reading upload directory: %s", err.Error())
+		return
+	}
+
+	for _, entry := range entries {
+		info, errInfo := entry.Info()
+		if errInfo != nil || time.Since(info.ModTime()) < uploadExpiration {
+			continue
+		}
+
+		if err = os.Remove(filepath.Join(s.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Errorf("[TCP/IP] Error removing expired upload %s: %s", entry.Name(), err.Error())
+		}
+	}
+}
+
+// authorizeUpload returns the user owning the token, if it is allowed to upload on behalf of machineID
+func (wr *TCPServer) authorizeUpload(info *connectionInfo, jwt, machineID string) (string, error) {
+	userID, err := wr.deviceUser(jwt, machineID)
+	if err != nil {
+		return "", err
+	}
+
+	return userID, wr.authorizeDevice(info, userID, machineID)
+}
+
+// processUploadBeginMessage refuses captures we already have before any data is sent
+func (wr *TCPServer) processUploadBeginMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var beginRequest TCPUploadBeginRequest
+
+	if err := decodeRequest(buffer, &beginRequest); err != nil {
+		return nil, err
+	}
+
+	if beginRequest.Size > MaxUploadSize {
+		return nil, customErrors.ErrUploadTooLarge
+	}
+
+	userID, err := wr.authorizeUpload(info, beginRequest.Jwt, beginRequest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = wr.usecase.GetEnrolledRaspberryPI(userID, beginRequest.MachineID); err != nil {
+		return nil, err
+	}
+
+	if err = wr.usecase.EnsureHandshakeNotPresent(userID, beginRequest.BSSID, beginRequest.SSID); err != nil {
+		return nil, err
+	}
+
+	offset, err := wr.uploads.begin(wr.uploads.name(userID, beginRequest.MachineID, beginRequest.UploadID), &uploadMetadata{
+		SSID:     beginRequest.SSID,
+		BSSID:    beginRequest.BSSID,
+		Size:     beginRequest.Size,
+		Checksum: beginRequest.Checksum,
+		Position: beginRequest.Position,
+		Quality:  beginRequest.Quality,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[TCP/IP] Upload %s of %s starting from %d/%d", beginRequest.UploadID, beginRequest.MachineID, offset, beginRequest.Size)
+
+	return json.Marshal(&TCPUploadResponse{Offset: offset})
+}
+
+func (wr *TCPServer) processUploadChunkMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var chunkRequest TCPUploadChunkRequest
+
+	if err := decodeRequest(buffer, &chunkRequest); err != nil {
+		return nil, err
+	}
+
+	if len(chunkRequest.Data) > MaxChunkSize {
+		return nil, customErrors.ErrMessageTooLarge
+	}
+
+	userID, err := wr.authorizeUpload(info, chunkRequest.Jwt, chunkRequest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := wr.uploads.write(wr.uploads.name(userID, chunkRequest.MachineID, chunkRequest.UploadID), chunkRequest.Offset, chunkRequest.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&TCPUploadResponse{Offset: offset})
+}
+
+// processUploadCommitMessage saves the uploaded capture, answering with the ID of the new handshake
+func (wr *TCPServer) processUploadCommitMessage(info *connectionInfo, buffer []byte) ([]byte, error) {
+	var commitRequest TCPUploadCommitRequest
+
+	if err := decodeRequest(buffer, &commitRequest); err != nil {
+		return nil, err
+	}
+
+	userID, err := wr.authorizeUpload(info, commitRequest.Jwt, commitRequest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := wr.uploads.name(userID, commitRequest.MachineID, commitRequest.UploadID)
+
+	metadata, payload, err := wr.uploads.read(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// the payload matches what the client declared, sending it again would not give a different result
+	defer wr.uploads.remove(name)
+
+	handshakeID, err := wr.usecase.IngestRaspberryPICapture(userID, commitRequest.MachineID, metadata.SSID, metadata.BSSID, payload, metadata.Position, metadata.Quality)
+	if err != nil {
+		log.Warnf("[TCP/IP] Rejected upload %s from %s: %s", commitRequest.UploadID, commitRequest.MachineID, err.Error())
+		return nil, err
+	}
+
+	return []byte(handshakeID), nil
+}
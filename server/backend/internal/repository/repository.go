@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
@@ -130,16 +131,16 @@ func (repo *Repository) CreateUser(userEntity *entities.User, role constants.Rol
 	return nil
 }
 
-// GetUserByUsername retrieves user and role information by username
+// GetUserByUsername retrieves user and role information by username, disabled users are not found
 func (repo *Repository) GetUserByUsername(username string) (*entities.User, *entities.Role, error) {
 	var user entities.User
 	var role entities.Role
 
-	query := fmt.Sprintf("SELECT * FROM %s AS u NATURAL JOIN %s WHERE u.username = ? LIMIT 1",
+	query := fmt.Sprintf("SELECT * FROM %s AS u NATURAL JOIN %s WHERE u.username = ? AND u.disabled = FALSE LIMIT 1",
 		entities.UserTableName, entities.RoleTableName)
 
 	row := repo.dbUser.QueryRow(query, username)
-	err := row.Scan(&user.UserUUID, &user.Username, &user.Password, &role.RoleString)
+	err := row.Scan(&user.UserUUID, &user.Username, &user.Password, &user.Disabled, &role.RoleString)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil, customErrors.ErrInvalidCredentials
@@ -158,27 +159,161 @@ func (repo *Repository) GetUserByUserID(userUUID string) (*entities.User, error)
 	query := fmt.Sprintf("SELECT * FROM %s WHERE uuid = ?", entities.UserTableName)
 
 	row := repo.dbUser.QueryRow(query, userUUID)
-	err := row.Scan(&user.UserUUID, &user.Username, &user.Password)
+	err := row.Scan(&user.UserUUID, &user.Username, &user.Password, &user.Disabled)
 
 	return &user, err
 }
 
-// GetClientsInstalled returns all installed clients
-func (repo *Repository) GetClientsInstalled() (clients []*entities.Client, length int, e error) {
-	clientBuilder := func() (any, []any) {
-		c := &entities.Client{}
-		return c, []any{
-			&c.UserUUID,
-			&c.ClientUUID,
-			&c.Name,
-			&c.LatestIP,
-			&c.CreationTime,
-			&c.LatestConnectionTime,
-			&c.MachineID,
-			&c.EnabledEncryption,
+// GetUserRole returns the current role of the user, ErrElementNotFound when the user does not exist
+func (repo *Repository) GetUserRole(userUUID string) (*entities.Role, error) {
+	var role entities.Role
+
+	row := repo.dbUser.QueryRow(
+		fmt.Sprintf("SELECT role_string FROM %s WHERE uuid = ?", entities.RoleTableName),
+		userUUID,
+	)
+	err := row.Scan(&role.RoleString)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, customErrors.ErrElementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetUsers returns a page of all the users, counting what each of them owns
+func (repo *Repository) GetUsers(offset uint) (users []*entities.UserSummary, length int, e error) {
+	userBuilder := func() (any, []any) {
+		u := &entities.UserSummary{}
+		return u, []any{
+			&u.UserUUID,
+			&u.Username,
+			&u.Disabled,
+			&u.Role,
+			&u.Clients,
+			&u.Devices,
+			&u.Handshakes,
 		}
 	}
 
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT u.uuid, u.username, u.disabled, r.role_string, "+
+			"(SELECT COUNT(*) FROM %s AS c WHERE c.uuid_user = u.uuid), "+
+			"(SELECT COUNT(*) FROM %s AS p WHERE p.uuid_user = u.uuid), "+
+			"(SELECT COUNT(*) FROM %s AS h WHERE h.uuid_user = u.uuid) "+
+			"FROM %s AS u JOIN %s AS r ON r.uuid = u.uuid ORDER BY u.username LIMIT %v OFFSET ?",
+			entities.ClientTableName, entities.RaspberryPiTableName, entities.HandshakeTableName,
+			entities.UserTableName, entities.RoleTableName, constants.Limit),
+		userBuilder,
+		(offset-1)*constants.Limit,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		users = append(users, item.(*entities.UserSummary))
+	}
+
+	count, err := qq.countQueryResults(fmt.Sprintf("SELECT COUNT(*) FROM %s", entities.UserTableName))
+	return users, count, err
+}
+
+// UpdateUserDisabled disables or enables again the user
+func (repo *Repository) UpdateUserDisabled(userUUID string, disabled bool) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET disabled = ? WHERE uuid = ?", entities.UserTableName),
+		disabled, userUUID,
+	)
+	return err
+}
+
+// UpdateUserRole changes the role of the user
+func (repo *Repository) UpdateUserRole(userUUID string, role constants.Role) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET role_string = ? WHERE uuid = ?", entities.RoleTableName),
+		role, userUUID,
+	)
+	return err
+}
+
+// DeleteUser deletes the user with everything it owns, the certificates of its clients are in the other database
+func (repo *Repository) DeleteUser(userUUID string) error {
+	result, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid = ?", entities.UserTableName),
+		userUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = ensureAffected(result, customErrors.ErrElementNotFound); err != nil {
+		return err
+	}
+
+	_, err = repo.dbCerts.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid_user = ?", entities.CertTableName),
+		userUUID,
+	)
+	return err
+}
+
+// DeleteUserSessions deletes the refresh tokens of every session of the user
+func (repo *Repository) DeleteUserSessions(userUUID string) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid_user = ?", entities.RefreshTokenTableName),
+		userUUID,
+	)
+	return err
+}
+
+// GetSetting returns the value of a setting, nil when it has never been changed
+func (repo *Repository) GetSetting(name string) (*string, error) {
+	var value string
+
+	row := repo.dbUser.QueryRow(fmt.Sprintf("SELECT value FROM %s WHERE name = ?", entities.SettingTableName), name)
+	err := row.Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// SetSetting stores the value of a setting
+func (repo *Repository) SetSetting(name, value string) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(name, value) VALUES(?,?) ON DUPLICATE KEY UPDATE value = VALUES(value)",
+			entities.SettingTableName),
+		name, value,
+	)
+	return err
+}
+
+// clientBuilder maps a client row, columns follow the table definition order
+func clientBuilder() (any, []any) {
+	c := &entities.Client{}
+	return c, []any{
+		&c.UserUUID,
+		&c.ClientUUID,
+		&c.Name,
+		&c.LatestIP,
+		&c.CreationTime,
+		&c.LatestConnectionTime,
+		&c.MachineID,
+		&c.EnabledEncryption,
+		&c.Approved,
+		&c.EnrollmentCode,
+		&c.TeamUUID,
+	}
+}
+
+// GetClientsInstalled returns all installed clients
+func (repo *Repository) GetClientsInstalled() (clients []*entities.Client, length int, e error) {
 	qq := queryHandler{repo.dbUser}
 	results, err := qq.queryEntities(
 		fmt.Sprintf("SELECT * FROM %s", entities.ClientTableName),
@@ -194,6 +329,27 @@ func (repo *Repository) GetClientsInstalled() (clients []*entities.Client, lengt
 	return clients, len(clients), nil
 }
 
+// GetAllClients returns a page of the clients of every user
+func (repo *Repository) GetAllClients(offset uint) (clients []*entities.Client, length int, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s ORDER BY creation_datetime DESC LIMIT %v OFFSET ?",
+			entities.ClientTableName, constants.Limit),
+		clientBuilder,
+		(offset-1)*constants.Limit,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		clients = append(clients, item.(*entities.Client))
+	}
+
+	count, err := qq.countQueryResults(fmt.Sprintf("SELECT COUNT(*) FROM %s", entities.ClientTableName))
+	return clients, count, err
+}
+
 // GetClientCertsByUserID returns client certificates for a user
 func (repo *Repository) GetClientCertsByUserID(userUUID string) (certs []*entities.Cert, length int, e error) {
 	certBuilder := func() (any, []any) {
@@ -224,28 +380,15 @@ func (repo *Repository) GetClientCertsByUserID(userUUID string) (certs []*entiti
 	return certs, len(certs), nil
 }
 
-// GetClientsInstalledByUserID returns paginated clients for a user
-func (repo *Repository) GetClientsInstalledByUserID(userUUID string, offset uint) (clients []*entities.Client, length int, e error) {
-	clientBuilder := func() (any, []any) {
-		c := &entities.Client{}
-		return c, []any{
-			&c.UserUUID,
-			&c.ClientUUID,
-			&c.Name,
-			&c.LatestIP,
-			&c.CreationTime,
-			&c.LatestConnectionTime,
-			&c.MachineID,
-			&c.EnabledEncryption,
-		}
-	}
-
+// GetClientsInstalledByUserID returns a page of the clients of the user, including the ones shared with its teams
+func (repo *Repository) GetClientsInstalledByUserID(userUUID string, offset, pageSize uint) (clients []*entities.Client, length int, e error) {
 	qq := queryHandler{repo.dbUser}
+	condition, args := ownedOrShared(userUUID)
 	results, err := qq.queryEntities(
-		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? LIMIT %v OFFSET ?",
-			entities.ClientTableName, constants.Limit),
+		fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT ? OFFSET ?",
+			entities.ClientTableName, condition),
 		clientBuilder,
-		userUUID, (offset-1)*constants.Limit,
+		append(args, pageSize, (offset-1)*pageSize)...,
 	)
 	if err != nil {
 		return nil, -1, err
@@ -257,8 +400,8 @@ func (repo *Repository) GetClientsInstalledByUserID(userUUID string, offset uint
 	}
 
 	count, err := qq.countQueryResults(
-		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE uuid_user = ?", entities.ClientTableName),
-		userUUID,
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", entities.ClientTableName, condition),
+		args...,
 	)
 	return clients, count, err
 }
@@ -273,42 +416,74 @@ func (repo *Repository) UpdateEncryptionClientStatus(clientUUID, userUUID string
 	return err
 }
 
-// CreateClient creates a new client record
-func (repo *Repository) CreateClient(userUUID, machineID, latestIP, name string) (string, error) {
-	query := fmt.Sprintf("INSERT INTO %s(uuid_user, uuid, name, latest_ip, creation_datetime, latest_connection, machine_id) VALUES(?,?,?,?,?,?,?)",
+// CreateClient creates a new client record, waiting for approval
+func (repo *Repository) CreateClient(userUUID, machineID, latestIP, name, enrollmentCode string) (string, error) {
+	query := fmt.Sprintf("INSERT INTO %s(uuid_user, uuid, name, latest_ip, creation_datetime, latest_connection, machine_id, enrollment_code) VALUES(?,?,?,?,?,?,?,?)",
 		entities.ClientTableName)
 
 	formattedDateTime := time.Now().Format(constants.DateTimeExample)
 	clientNewID := uuid.New().String()
-	_, err := repo.dbUser.Exec(query, userUUID, clientNewID, name, latestIP, formattedDateTime, formattedDateTime, machineID)
+	_, err := repo.dbUser.Exec(query, userUUID, clientNewID, name, latestIP, formattedDateTime, formattedDateTime, machineID, enrollmentCode)
 	return clientNewID, err
 }
 
-// UpdateClientTaskCommon contains shared logic for updating client tasks
+// ApproveClient marks the client of the user as approved
+func (repo *Repository) ApproveClient(userUUID, clientUUID string) error {
+	result, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET approved = TRUE WHERE uuid_user = ? AND uuid = ?", entities.ClientTableName),
+		userUUID, clientUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(result, customErrors.ErrElementNotFound)
+}
+
+func handshakeBuilder() (any, []any) {
+	h := &entities.Handshake{}
+	return h, []any{
+		&h.UserUUID,
+		&h.ClientUUID,
+		&h.UUID,
+		&h.SSID,
+		&h.BSSID,
+		&h.UploadedDate,
+		&h.Status,
+		&h.CrackedDate,
+		&h.HashcatOptions,
+		&h.HashcatLogs,
+		&h.CrackedHandshake,
+		&h.HandshakePCAP,
+		&h.RaspberryPIUUID,
+		&h.Latitude,
+		&h.Longitude,
+		&h.PositionDate,
+		&h.TeamUUID,
+		&h.Quality,
+	}
+}
+
+// handshakeSummaryColumns the columns of handshakeBuilder with the logs and the capture left NULL,
+// the listings do not carry them since they can be megabytes each
+var handshakeSummaryColumns = "uuid_user, uuid_assigned_client, uuid, ssid, bssid, uploaded_date, status, cracked_date, " +
+	"hashcat_options, NULL, cracked_handshake, NULL, uuid_raspberry_pi, latitude, longitude, position_date, uuid_team, quality"
+
+// UpdateClientTaskCommon contains shared logic for updating client tasks.
+// Outside of REST mode the user is the owner of the client, which can be working on a handshake shared by a team
 func (repo *Repository) updateClientTaskCommon(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake string, restMode bool) (*entities.Handshake, error) {
-	handshakeBuilder := func() (any, []any) {
-		h := &entities.Handshake{}
-		return h, []any{
-			&h.UserUUID,
-			&h.ClientUUID,
-			&h.UUID,
-			&h.SSID,
-			&h.BSSID,
-			&h.UploadedDate,
-			&h.Status,
-			&h.CrackedDate,
-			&h.HashcatOptions,
-			&h.HashcatLogs,
-			&h.CrackedHandshake,
-			&h.HandshakePCAP,
-		}
+	condition, args := "uuid_user = ?", []any{userUUID}
+	if !restMode {
+		condition = fmt.Sprintf("(uuid_user = ? OR uuid_assigned_client IN (SELECT uuid FROM %s WHERE uuid_user = ?))",
+			entities.ClientTableName)
+		args = append(args, userUUID)
 	}
 
 	qq := queryHandler{repo.dbUser}
 	results, err := qq.queryEntities(
-		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND uuid = ?", entities.HandshakeTableName),
+		fmt.Sprintf("SELECT * FROM %s WHERE %s AND uuid = ?", entities.HandshakeTableName, condition),
 		handshakeBuilder,
-		userUUID, handshakeUUID,
+		append(args, handshakeUUID)...,
 	)
 	if err != nil {
 		return nil, err
@@ -330,12 +505,14 @@ func (repo *Repository) updateClientTaskCommon(userUUID, handshakeUUID, assigned
 		}
 	}
 
+	// cracked_date keeps the first time the handshake has been cracked and is cleared when it is queued again
 	updateQuery := fmt.Sprintf(
-		"UPDATE %s SET uuid_assigned_client = ?, status = ?, hashcat_options = ?, hashcat_logs = ?, cracked_handshake = ? WHERE uuid_user = ? AND uuid = ?",
+		"UPDATE %s SET uuid_assigned_client = ?, status = ?, hashcat_options = ?, hashcat_logs = ?, cracked_handshake = ?, "+
+			"cracked_date = CASE WHEN ? = ? THEN COALESCE(cracked_date, NOW()) ELSE NULL END WHERE uuid_user = ? AND uuid = ?",
 		entities.HandshakeTableName,
 	)
 	if _, err = repo.dbUser.Exec(updateQuery,
-		assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake, userUUID, handshakeUUID,
+		assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake, status, constants.CrackedStatus, handshake.UserUUID, handshakeUUID,
 	); err != nil {
 		return nil, err
 	}
@@ -344,7 +521,7 @@ func (repo *Repository) updateClientTaskCommon(userUUID, handshakeUUID, assigned
 	results, err = qq.queryEntities(
 		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND uuid = ?", entities.HandshakeTableName),
 		handshakeBuilder,
-		userUUID, handshakeUUID,
+		handshake.UserUUID, handshakeUUID,
 	)
 	if err != nil {
 		return nil, err
@@ -372,24 +549,42 @@ func (repo *Repository) DeleteClient(userUUID, clientUUID string) (bool, error)
 	return err == nil, err
 }
 
-// GetRaspberryPiByUserID returns paginated raspberry pi devices for a user
-func (repo *Repository) GetRaspberryPiByUserID(userUUID string, offset uint) (rsps []*entities.RaspberryPI, length int, e error) {
-	rspBuilder := func() (any, []any) {
-		r := &entities.RaspberryPI{}
-		return r, []any{
-			&r.UserUUID,
-			&r.RaspberryPIUUID,
-			&r.MachineID,
-			&r.EncryptionKey,
-		}
+// GetClientByUUID returns the client of the user
+func (repo *Repository) GetClientByUUID(userUUID, clientUUID string) (*entities.Client, error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND uuid = ?", entities.ClientTableName),
+		clientBuilder,
+		userUUID, clientUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, customErrors.ErrElementNotFound
 	}
+	return results[0].(*entities.Client), nil
+}
+
+// DeleteCertOfClient deletes the certificate issued to a client
+func (repo *Repository) DeleteCertOfClient(clientUUID string) error {
+	_, err := repo.dbCerts.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE client_uuid = ?", entities.CertTableName),
+		clientUUID,
+	)
+	return err
+}
 
+// GetRaspberryPiByUserID returns a page of the raspberry pi devices of the user, including the ones shared with its teams
+func (repo *Repository) GetRaspberryPiByUserID(userUUID string, offset, pageSize uint) (rsps []*entities.RaspberryPI, length int, e error) {
 	qq := queryHandler{repo.dbUser}
+	condition, args := ownedOrShared(userUUID)
 	results, err := qq.queryEntities(
-		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? LIMIT %v OFFSET ?",
-			entities.RaspberryPiTableName, constants.Limit),
-		rspBuilder,
-		userUUID, (offset-1)*constants.Limit,
+		fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT ? OFFSET ?",
+			entities.RaspberryPiTableName, condition),
+		raspberryPIBuilder,
+		append(args, pageSize, (offset-1)*pageSize)...,
 	)
 	if err != nil {
 		return nil, -1, err
@@ -400,38 +595,213 @@ func (repo *Repository) GetRaspberryPiByUserID(userUUID string, offset uint) (rs
 	}
 
 	count, err := qq.countQueryResults(
-		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE uuid_user = ?", entities.RaspberryPiTableName),
-		userUUID,
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", entities.RaspberryPiTableName, condition),
+		args...,
+	)
+	return rsps, count, err
+}
+
+// GetAllRaspberryPIs returns a page of the raspberry pi devices of every user
+func (repo *Repository) GetAllRaspberryPIs(offset uint) (rsps []*entities.RaspberryPI, length int, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s ORDER BY uuid_user, machine_id LIMIT %v OFFSET ?",
+			entities.RaspberryPiTableName, constants.Limit),
+		raspberryPIBuilder,
+		(offset-1)*constants.Limit,
 	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		rsps = append(rsps, item.(*entities.RaspberryPI))
+	}
+
+	count, err := qq.countQueryResults(fmt.Sprintf("SELECT COUNT(*) FROM %s", entities.RaspberryPiTableName))
 	return rsps, count, err
 }
 
-// GetHandshakesByUserID returns paginated handshakes for a user
-func (repo *Repository) GetHandshakesByUserID(userUUID string, offset uint) (handshakes []*entities.Handshake, length int, e error) {
-	handshakeBuilder := func() (any, []any) {
-		h := &entities.Handshake{}
-		return h, []any{
-			&h.UserUUID,
-			&h.ClientUUID,
-			&h.UUID,
-			&h.SSID,
-			&h.BSSID,
-			&h.UploadedDate,
-			&h.Status,
-			&h.CrackedDate,
-			&h.HashcatOptions,
-			&h.HashcatLogs,
-			&h.CrackedHandshake,
-			&h.HandshakePCAP,
+// raspberryPIBuilder maps a raspberry_pi row, columns follow the table definition order
+func raspberryPIBuilder() (any, []any) {
+	r := &entities.RaspberryPI{}
+	return r, []any{
+		&r.UserUUID,
+		&r.RaspberryPIUUID,
+		&r.MachineID,
+		&r.EncryptionKey,
+		&r.CredentialHash,
+		&r.Name,
+		&r.Hostname,
+		&r.DaemonVersion,
+		&r.OS,
+		&r.LatestIP,
+		&r.LastSeen,
+		&r.Approved,
+		&r.TeamUUID,
+	}
+}
+
+// GetRaspberryPIByMachineID returns the raspberry pi registered with the given machine ID, regardless of its owner
+func (repo *Repository) GetRaspberryPIByMachineID(machineID string) (*entities.RaspberryPI, error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE machine_id = ?", entities.RaspberryPiTableName),
+		raspberryPIBuilder,
+		machineID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, customErrors.ErrElementNotFound
+	}
+	return results[0].(*entities.RaspberryPI), nil
+}
+
+// UpdateRaspberryPIEncryptionKey replaces the key used by a raspberry pi for encrypting its captures
+func (repo *Repository) UpdateRaspberryPIEncryptionKey(userUUID, rspUUID, encryptionKey string) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET encryption_key = ? WHERE uuid_user = ? AND uuid = ?", entities.RaspberryPiTableName),
+		encryptionKey, userUUID, rspUUID,
+	)
+	return err
+}
+
+// UpdateRaspberryPICredential sets the hash of the device credential, a nil hash revokes it.
+// Returns ErrElementNotFound when the raspberry pi does not belong to the user or nothing changed
+func (repo *Repository) UpdateRaspberryPICredential(userUUID, rspUUID string, credentialHash *string) error {
+	result, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET credential_hash = ? WHERE uuid_user = ? AND uuid = ?", entities.RaspberryPiTableName),
+		credentialHash, userUUID, rspUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return customErrors.ErrElementNotFound
+	}
+	return nil
+}
+
+// EnrollRaspberryPICredential sets the hash of the credential of a device enrolling again and puts it back
+// waiting for approval, so that the new credential is refused until the user approves it again
+func (repo *Repository) EnrollRaspberryPICredential(userUUID, rspUUID, credentialHash string) error {
+	result, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET credential_hash = ?, approved = FALSE WHERE uuid_user = ? AND uuid = ?", entities.RaspberryPiTableName),
+		credentialHash, userUUID, rspUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(result, customErrors.ErrElementNotFound)
+}
+
+// handshakeSortKeys expressions the handshakes are sorted by, cracked_date is NULL until the handshake is cracked
+var handshakeSortKeys = map[string]string{
+	"uploaded_date": "uploaded_date",
+	"cracked_date":  "COALESCE(cracked_date, '" + constants.NotCrackedSortValue + "')",
+	"ssid":          "ssid",
+	"bssid":         "bssid",
+	"status":        "status",
+}
+
+// handshakeFilterCondition the WHERE clause of the handshakes of the user, including the ones shared with its teams, matching the filter
+func handshakeFilterCondition(userUUID string, filter *entities.HandshakeFilter) (string, []any) {
+	condition, args := ownedOrShared(userUUID)
+	conditions := []string{condition}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		conditions = append(conditions, "(ssid LIKE ? OR bssid LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+
+	if filter.ClientUUID != "" {
+		conditions = append(conditions, "uuid_assigned_client = ?")
+		args = append(args, filter.ClientUUID)
+	}
+
+	if filter.RaspberryPIUUID != "" {
+		conditions = append(conditions, "uuid_raspberry_pi = ?")
+		args = append(args, filter.RaspberryPIUUID)
+	}
+
+	switch filter.Quality {
+	case "":
+	case constants.QualityUnknown:
+		conditions = append(conditions, "quality IS NULL")
+	default:
+		conditions = append(conditions, "quality = ?")
+		args = append(args, filter.Quality)
+	}
+
+	for _, bound := range []struct {
+		condition string
+		value     string
+	}{
+		{"DATE(uploaded_date) >= ?", filter.UploadedFrom},
+		{"DATE(uploaded_date) <= ?", filter.UploadedTo},
+		{"DATE(cracked_date) >= ?", filter.CrackedFrom},
+		{"DATE(cracked_date) <= ?", filter.CrackedTo},
+	} {
+		if bound.value != "" {
+			conditions = append(conditions, bound.condition)
+			args = append(args, bound.value)
 		}
 	}
 
+	return strings.Join(conditions, " AND "), args
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// GetHandshakesByUserID returns the handshakes of the user, including the ones shared with its teams, matching the filter.
+// The page starts after the cursor when one is given, the returned length counts every handshake matching the filter.
+// The filter must be complete, with its sort, order and page size
+func (repo *Repository) GetHandshakesByUserID(userUUID string, filter *entities.HandshakeFilter, after *entities.HandshakeCursor, limit uint) (handshakes []*entities.Handshake, length int, e error) {
 	qq := queryHandler{repo.dbUser}
+	condition, args := handshakeFilterCondition(userUUID, filter)
+
+	sortKey, ok := handshakeSortKeys[filter.Sort]
+	if !ok {
+		return nil, -1, customErrors.ErrInvalidCursor
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Order == "desc" {
+		direction, comparison = "DESC", "<"
+	}
+
+	pageCondition, pageArgs := condition, args
+	offset := (filter.Page - 1) * filter.PageSize
+	if after != nil {
+		pageCondition = fmt.Sprintf("%s AND (%s %s ? OR (%s = ? AND uuid %s ?))", condition, sortKey, comparison, sortKey, comparison)
+		pageArgs = append(append([]any{}, args...), after.Value, after.Value, after.UUID)
+		offset = 0
+	}
+
 	results, err := qq.queryEntities(
-		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? LIMIT %v OFFSET ?",
-			entities.HandshakeTableName, constants.Limit),
+		fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s %s, uuid %s LIMIT ? OFFSET ?",
+			handshakeSummaryColumns, entities.HandshakeTableName, pageCondition, sortKey, direction, direction),
 		handshakeBuilder,
-		userUUID, (offset-1)*constants.Limit,
+		append(pageArgs, limit, offset)...,
 	)
 	if err != nil {
 		return nil, -1, err
@@ -442,32 +812,14 @@ func (repo *Repository) GetHandshakesByUserID(userUUID string, offset uint) (han
 	}
 
 	count, err := qq.countQueryResults(
-		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE uuid_user = ?", entities.HandshakeTableName),
-		userUUID,
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", entities.HandshakeTableName, condition),
+		args...,
 	)
 	return handshakes, count, err
 }
 
 // GetHandshakesByStatus returns handshakes filtered by status
 func (repo *Repository) GetHandshakesByStatus(filterStatus string) (handshakes []*entities.Handshake, length int, e error) {
-	handshakeBuilder := func() (any, []any) {
-		h := &entities.Handshake{}
-		return h, []any{
-			&h.UserUUID,
-			&h.ClientUUID,
-			&h.UUID,
-			&h.SSID,
-			&h.BSSID,
-			&h.UploadedDate,
-			&h.Status,
-			&h.CrackedDate,
-			&h.HashcatOptions,
-			&h.HashcatLogs,
-			&h.CrackedHandshake,
-			&h.HandshakePCAP,
-		}
-	}
-
 	qq := queryHandler{repo.dbUser}
 	results, err := qq.queryEntities(
 		fmt.Sprintf("SELECT * FROM %s WHERE status = ?", entities.HandshakeTableName),
@@ -484,26 +836,32 @@ func (repo *Repository) GetHandshakesByStatus(filterStatus string) (handshakes [
 	return handshakes, len(results), nil
 }
 
-// GetHandshakesByBSSIDAndSSID checks for existing handshake records
-func (repo *Repository) GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid string) (handshakes []*entities.Handshake, length int, e error) {
-	handshakeBuilder := func() (any, []any) {
-		h := &entities.Handshake{}
-		return h, []any{
-			&h.UserUUID,
-			&h.ClientUUID,
-			&h.UUID,
-			&h.SSID,
-			&h.BSSID,
-			&h.UploadedDate,
-			&h.Status,
-			&h.CrackedDate,
-			&h.HashcatOptions,
-			&h.HashcatLogs,
-			&h.CrackedHandshake,
-			&h.HandshakePCAP,
-		}
+// GetTaskQueue returns a page of the handshakes of every user assigned to a client and not cracked yet, oldest first
+func (repo *Repository) GetTaskQueue(offset uint) (handshakes []*entities.Handshake, length int, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT %s FROM %s WHERE status IN (?, ?) ORDER BY uploaded_date LIMIT %v OFFSET ?",
+			handshakeSummaryColumns, entities.HandshakeTableName, constants.Limit),
+		handshakeBuilder,
+		constants.PendingStatus, constants.WorkingStatus, (offset-1)*constants.Limit,
+	)
+	if err != nil {
+		return nil, -1, err
 	}
 
+	for _, item := range results {
+		handshakes = append(handshakes, item.(*entities.Handshake))
+	}
+
+	count, err := qq.countQueryResults(
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE status IN (?, ?)", entities.HandshakeTableName),
+		constants.PendingStatus, constants.WorkingStatus,
+	)
+	return handshakes, count, err
+}
+
+// GetHandshakesByBSSIDAndSSID checks for existing handshake records
+func (repo *Repository) GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid string) (handshakes []*entities.Handshake, length int, e error) {
 	qq := queryHandler{repo.dbUser}
 	results, err := qq.queryEntities(
 		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND bssid = ? AND ssid = ?", entities.HandshakeTableName),
@@ -520,15 +878,52 @@ func (repo *Repository) GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid string
 	return handshakes, len(results), nil
 }
 
-// CreateCertForClient generates and stores client certificates
-func (repo *Repository) CreateCertForClient(userUUID, clientUUID string, clientCert, clientKey []byte) (string, error) {
-	if repo.certs.caCert == nil || repo.certs.caKey == nil {
-		return "", customErrors.ErrCertsNotInitialized
+// GetHandshakesByRaspberryPI returns the handshakes uploaded by a raspberry pi having the given status
+func (repo *Repository) GetHandshakesByRaspberryPI(userUUID, rspUUID, status string) (handshakes []*entities.Handshake, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND uuid_raspberry_pi = ? AND status = ?", entities.HandshakeTableName),
+		handshakeBuilder,
+		userUUID, rspUUID, status,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	certID := uuid.New().String()
-	_, err := repo.dbCerts.Exec(
-		fmt.Sprintf("INSERT INTO %s(uuid, uuid_user, client_uuid, ca_cert, client_cert, client_key) VALUES(?,?,?,?,?,?)", entities.CertTableName),
+	for _, item := range results {
+		handshakes = append(handshakes, item.(*entities.Handshake))
+	}
+	return handshakes, nil
+}
+
+// GetGeotaggedHandshakes returns the handshakes of the user having a position
+func (repo *Repository) GetGeotaggedHandshakes(userUUID string) (handshakes []*entities.Handshake, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT %s FROM %s WHERE uuid_user = ? AND latitude IS NOT NULL AND longitude IS NOT NULL ORDER BY position_date",
+			handshakeSummaryColumns, entities.HandshakeTableName),
+		handshakeBuilder,
+		userUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range results {
+		handshakes = append(handshakes, item.(*entities.Handshake))
+	}
+	return handshakes, nil
+}
+
+// CreateCertForClient generates and stores client certificates
+func (repo *Repository) CreateCertForClient(userUUID, clientUUID string, clientCert, clientKey []byte) (string, error) {
+	if repo.certs.caCert == nil || repo.certs.caKey == nil {
+		return "", customErrors.ErrCertsNotInitialized
+	}
+
+	certID := uuid.New().String()
+	_, err := repo.dbCerts.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, uuid_user, client_uuid, ca_cert, client_cert, client_key) VALUES(?,?,?,?,?,?)", entities.CertTableName),
 		certID, userUUID, clientUUID, repo.certs.caCert, clientCert, clientKey,
 	)
 	return certID, err
@@ -566,6 +961,57 @@ func (repo *Repository) CreateHandshake(userUUID, ssid, bssid, status, handshake
 	return handshakeID, err
 }
 
+// GetHandshakesBySSID returns the handshakes of the user captured from the network named ssid, whatever their BSSID
+func (repo *Repository) GetHandshakesBySSID(userUUID, ssid string) (handshakes []*entities.Handshake, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT %s FROM %s WHERE uuid_user = ? AND ssid = ? ORDER BY uploaded_date, uuid",
+			handshakeSummaryColumns, entities.HandshakeTableName),
+		handshakeBuilder,
+		userUUID, ssid,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range results {
+		handshakes = append(handshakes, item.(*entities.Handshake))
+	}
+	return handshakes, nil
+}
+
+// CrackHandshake marks a handshake of the user as cracked with a password recovered elsewhere, no client has worked on it
+func (repo *Repository) CrackHandshake(userUUID, handshakeUUID, crackedHandshake string) error {
+	result, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET status = ?, cracked_handshake = ?, cracked_date = COALESCE(cracked_date, NOW()) "+
+			"WHERE uuid_user = ? AND uuid = ?", entities.HandshakeTableName),
+		constants.CrackedStatus, crackedHandshake, userUUID, handshakeUUID,
+	)
+	if err != nil {
+		return err
+	}
+	return ensureAffected(result, customErrors.ErrElementNotFound)
+}
+
+// CreateRaspberryPIHandshake creates a new handshake record uploaded by a raspberry pi, position is nil and quality empty when unknown
+func (repo *Repository) CreateRaspberryPIHandshake(userUUID, rspUUID, ssid, bssid, status, handshakePcap string, position *entities.Position, quality string) (string, error) {
+	var latitude, longitude, positionDate, qualityValue any
+	if position != nil {
+		latitude, longitude, positionDate = position.Latitude, position.Longitude, position.Date.UTC()
+	}
+	if quality != "" {
+		qualityValue = quality
+	}
+
+	handshakeID := uuid.New().String()
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid_user, uuid, ssid, bssid, status, handshake_pcap, uuid_raspberry_pi, latitude, longitude, position_date, quality) VALUES(?,?,?,?,?,?,?,?,?,?,?)",
+			entities.HandshakeTableName),
+		userUUID, handshakeID, ssid, bssid, status, handshakePcap, rspUUID, latitude, longitude, positionDate, qualityValue,
+	)
+	return handshakeID, err
+}
+
 // CreateRaspberryPI creates a new raspberry pi device entry
 func (repo *Repository) CreateRaspberryPI(userUUID, machineID, encryptionKey string) (string, error) {
 	rspID := uuid.New().String()
@@ -576,6 +1022,19 @@ func (repo *Repository) CreateRaspberryPI(userUUID, machineID, encryptionKey str
 	return rspID, err
 }
 
+// ApproveRaspberryPI marks the raspberry pi of the user as approved
+func (repo *Repository) ApproveRaspberryPI(userUUID, rspUUID string) error {
+	result, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET approved = TRUE WHERE uuid_user = ? AND uuid = ?", entities.RaspberryPiTableName),
+		userUUID, rspUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(result, customErrors.ErrElementNotFound)
+}
+
 // DeleteRaspberryPI deletes a raspberry pi record
 func (repo *Repository) DeleteRaspberryPI(userUUID, rspUUID string) (bool, error) {
 	_, err := repo.dbUser.Exec(
@@ -596,20 +1055,6 @@ func (repo *Repository) DeleteHandshake(userUUID, handshakeUUID string) (bool, e
 
 // GetClientInfo retrieves client information by machine ID
 func (repo *Repository) GetClientInfo(userUUID, machineID string) (*entities.Client, error) {
-	clientBuilder := func() (any, []any) {
-		c := &entities.Client{}
-		return c, []any{
-			&c.UserUUID,
-			&c.ClientUUID,
-			&c.Name,
-			&c.LatestIP,
-			&c.CreationTime,
-			&c.LatestConnectionTime,
-			&c.MachineID,
-			&c.EnabledEncryption,
-		}
-	}
-
 	qq := queryHandler{repo.dbUser}
 	results, err := qq.queryEntities(
 		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND machine_id = ?", entities.ClientTableName),
@@ -625,3 +1070,888 @@ func (repo *Repository) GetClientInfo(userUUID, machineID string) (*entities.Cli
 	}
 	return results[0].(*entities.Client), nil
 }
+
+// GetRaspberryPIByUUID returns the raspberry pi of the user
+func (repo *Repository) GetRaspberryPIByUUID(userUUID, rspUUID string) (*entities.RaspberryPI, error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND uuid = ?", entities.RaspberryPiTableName),
+		raspberryPIBuilder,
+		userUUID, rspUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, customErrors.ErrElementNotFound
+	}
+	return results[0].(*entities.RaspberryPI), nil
+}
+
+// UpdateRaspberryPIName names a raspberry pi of the user, a nil name removes it
+func (repo *Repository) UpdateRaspberryPIName(userUUID, rspUUID string, name *string) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET name = ? WHERE uuid_user = ? AND uuid = ?", entities.RaspberryPiTableName),
+		name, userUUID, rspUUID,
+	)
+	return err
+}
+
+// UpdateRaspberryPICheckIn records when and from where a raspberry pi has been seen.
+// The metadata is replaced only when given, it is reported at login only
+func (repo *Repository) UpdateRaspberryPICheckIn(rspUUID, latestIP string, metadata *entities.RaspberryPIMetadata) error {
+	if metadata == nil {
+		_, err := repo.dbUser.Exec(
+			fmt.Sprintf("UPDATE %s SET latest_ip = ?, last_seen = ? WHERE uuid = ?", entities.RaspberryPiTableName),
+			latestIP, time.Now().UTC(), rspUUID,
+		)
+		return err
+	}
+
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET latest_ip = ?, last_seen = ?, hostname = ?, daemon_version = ?, os = ? WHERE uuid = ?",
+			entities.RaspberryPiTableName),
+		latestIP, time.Now().UTC(), metadata.Hostname, metadata.DaemonVersion, metadata.OS, rspUUID,
+	)
+	return err
+}
+
+// GetRaspberryPIHandshakes returns paginated handshakes uploaded by a raspberry pi, newest first
+func (repo *Repository) GetRaspberryPIHandshakes(userUUID, rspUUID string, offset uint) (handshakes []*entities.Handshake, length int, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT %s FROM %s WHERE uuid_user = ? AND uuid_raspberry_pi = ? ORDER BY uploaded_date DESC LIMIT %v OFFSET ?",
+			handshakeSummaryColumns, entities.HandshakeTableName, constants.Limit),
+		handshakeBuilder,
+		userUUID, rspUUID, (offset-1)*constants.Limit,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		handshakes = append(handshakes, item.(*entities.Handshake))
+	}
+
+	count, err := qq.countQueryResults(
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE uuid_user = ? AND uuid_raspberry_pi = ?", entities.HandshakeTableName),
+		userUUID, rspUUID,
+	)
+	return handshakes, count, err
+}
+
+// GetRaspberryPIActivity returns how many handshakes a raspberry pi uploaded per day and how many of them are cracked,
+// for the latest days it uploaded something
+func (repo *Repository) GetRaspberryPIActivity(userUUID, rspUUID string) (activity []*entities.RaspberryPIActivity, e error) {
+	activityBuilder := func() (any, []any) {
+		a := &entities.RaspberryPIActivity{}
+		return a, []any{
+			&a.Day,
+			&a.Uploaded,
+			&a.Cracked,
+		}
+	}
+
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT DATE(uploaded_date) AS day, COUNT(*), SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) FROM %s "+
+			"WHERE uuid_user = ? AND uuid_raspberry_pi = ? GROUP BY DATE(uploaded_date) ORDER BY day DESC LIMIT %v",
+			entities.HandshakeTableName, constants.ActivityDays),
+		activityBuilder,
+		constants.CrackedStatus, userUUID, rspUUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range results {
+		activity = append(activity, item.(*entities.RaspberryPIActivity))
+	}
+	return activity, nil
+}
+
+// directiveBuilder maps a directive row, columns follow the table definition order
+func directiveBuilder() (any, []any) {
+	d := &entities.Directive{}
+	return d, []any{
+		&d.UUID,
+		&d.UserUUID,
+		&d.RaspberryPIUUID,
+		&d.Kind,
+		&d.Parameters,
+		&d.Status,
+		&d.Result,
+		&d.CreatedDate,
+		&d.AcknowledgedDate,
+	}
+}
+
+// CreateDirective queues a directive for a raspberry pi, parameters is JSON
+func (repo *Repository) CreateDirective(userUUID, rspUUID, kind, parameters string) (string, error) {
+	directiveID := uuid.New().String()
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, uuid_user, uuid_raspberry_pi, kind, parameters, status, created_date) VALUES(?,?,?,?,?,?,?)",
+			entities.DirectiveTableName),
+		directiveID, userUUID, rspUUID, kind, parameters, constants.DirectivePending, time.Now().UTC(),
+	)
+	return directiveID, err
+}
+
+// GetDirectivesByRaspberryPI returns the directives of a raspberry pi having one of the statuses, oldest first
+func (repo *Repository) GetDirectivesByRaspberryPI(userUUID, rspUUID string, statuses ...string) (directives []*entities.Directive, e error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(statuses)), ",")
+	args := []any{userUUID, rspUUID}
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? AND uuid_raspberry_pi = ? AND status IN (%s) ORDER BY created_date",
+			entities.DirectiveTableName, placeholders),
+		directiveBuilder,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range results {
+		directives = append(directives, item.(*entities.Directive))
+	}
+	return directives, nil
+}
+
+// AcknowledgeDirective records the outcome of a pending directive.
+// Returns ErrDirectiveNotPending when it does not belong to the raspberry pi or it has already been acknowledged
+func (repo *Repository) AcknowledgeDirective(userUUID, rspUUID, directiveUUID, status, result string) error {
+	updated, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET status = ?, result = ?, acknowledged_date = ? WHERE uuid_user = ? AND uuid_raspberry_pi = ? AND uuid = ? AND status = ?",
+			entities.DirectiveTableName),
+		status, result, time.Now().UTC(), userUUID, rspUUID, directiveUUID, constants.DirectivePending,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(updated, customErrors.ErrDirectiveNotPending)
+}
+
+// DeletePendingDirective removes a directive not delivered yet.
+// Returns ErrDirectiveNotPending when it does not belong to the user or it has already been acknowledged
+func (repo *Repository) DeletePendingDirective(userUUID, directiveUUID string) error {
+	deleted, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid_user = ? AND uuid = ? AND status = ?", entities.DirectiveTableName),
+		userUUID, directiveUUID, constants.DirectivePending,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(deleted, customErrors.ErrDirectiveNotPending)
+}
+
+// ensureAffected returns notFound when the statement changed no rows
+func ensureAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// revocationBuilder maps a revocation row, columns follow the table definition order
+func revocationBuilder() (any, []any) {
+	r := &entities.Revocation{}
+	return r, []any{
+		&r.UUID,
+		&r.UserUUID,
+		&r.Kind,
+		&r.MachineID,
+		&r.EntityUUID,
+		&r.Name,
+		&r.RevokedDate,
+	}
+}
+
+// CreateRevocation revokes a machine ID, replacing the revocation it already had for kind
+func (repo *Repository) CreateRevocation(userUUID, kind, machineID, entityUUID string, name *string) (string, error) {
+	revocationID := uuid.New().String()
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, uuid_user, kind, machine_id, uuid_entity, name, revoked_date) VALUES(?,?,?,?,?,?,?) "+
+			"ON DUPLICATE KEY UPDATE uuid = VALUES(uuid), uuid_user = VALUES(uuid_user), uuid_entity = VALUES(uuid_entity), "+
+			"name = VALUES(name), revoked_date = VALUES(revoked_date)",
+			entities.RevocationTableName),
+		revocationID, userUUID, kind, machineID, entityUUID, name, time.Now().UTC(),
+	)
+	return revocationID, err
+}
+
+// getRevocation returns the first revocation matching condition, ErrElementNotFound when there is none
+func (repo *Repository) getRevocation(condition string, args ...any) (*entities.Revocation, error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE %s", entities.RevocationTableName, condition),
+		revocationBuilder,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, customErrors.ErrElementNotFound
+	}
+	return results[0].(*entities.Revocation), nil
+}
+
+// GetRevocation returns the revocation of a machine ID for kind
+func (repo *Repository) GetRevocation(kind, machineID string) (*entities.Revocation, error) {
+	return repo.getRevocation("kind = ? AND machine_id = ?", kind, machineID)
+}
+
+// GetRevocationByEntity returns the revocation of the deleted device or client
+func (repo *Repository) GetRevocationByEntity(entityUUID string) (*entities.Revocation, error) {
+	return repo.getRevocation("uuid_entity = ?", entityUUID)
+}
+
+// GetRevocationByUUID returns a revocation of the user
+func (repo *Repository) GetRevocationByUUID(userUUID, revocationUUID string) (*entities.Revocation, error) {
+	return repo.getRevocation("uuid_user = ? AND uuid = ?", userUUID, revocationUUID)
+}
+
+// GetRevocationsByUserID returns paginated revocations of a user, newest first
+func (repo *Repository) GetRevocationsByUserID(userUUID string, offset uint) (revocations []*entities.Revocation, length int, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? ORDER BY revoked_date DESC LIMIT %v OFFSET ?",
+			entities.RevocationTableName, constants.Limit),
+		revocationBuilder,
+		userUUID, (offset-1)*constants.Limit,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		revocations = append(revocations, item.(*entities.Revocation))
+	}
+
+	count, err := qq.countQueryResults(
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE uuid_user = ?", entities.RevocationTableName),
+		userUUID,
+	)
+	return revocations, count, err
+}
+
+// DeleteRevocation removes a revocation of the user, ErrElementNotFound when it does not exist
+func (repo *Repository) DeleteRevocation(userUUID, revocationUUID string) error {
+	deleted, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid_user = ? AND uuid = ?", entities.RevocationTableName),
+		userUUID, revocationUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(deleted, customErrors.ErrElementNotFound)
+}
+
+// signingKeyBuilder maps a signing key row, columns follow the table definition order
+func signingKeyBuilder() (any, []any) {
+	k := &entities.SigningKey{}
+	return k, []any{
+		&k.KID,
+		&k.Secret,
+		&k.CreatedDate,
+		&k.RetiredDate,
+	}
+}
+
+// GetSigningKeys returns the JWT signing keys, newest first
+func (repo *Repository) GetSigningKeys() (keys []*entities.SigningKey, e error) {
+	qq := queryHandler{repo.dbCerts}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s ORDER BY created_date DESC", entities.SigningKeyTableName),
+		signingKeyBuilder,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range results {
+		keys = append(keys, item.(*entities.SigningKey))
+	}
+	return keys, nil
+}
+
+// CreateSigningKey stores a new signing key and retires the previous ones
+func (repo *Repository) CreateSigningKey(kid string, secret []byte) error {
+	now := time.Now().UTC()
+	_, err := repo.dbCerts.Exec(
+		fmt.Sprintf("INSERT INTO %s(kid, secret, created_date) VALUES(?,?,?)", entities.SigningKeyTableName),
+		kid, secret, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.dbCerts.Exec(
+		fmt.Sprintf("UPDATE %s SET retired_date = ? WHERE kid != ? AND retired_date IS NULL", entities.SigningKeyTableName),
+		now, kid,
+	)
+	return err
+}
+
+// DeleteSigningKeysRetiredBefore removes the retired keys no unexpired token can be signed with
+func (repo *Repository) DeleteSigningKeysRetiredBefore(date time.Time) error {
+	_, err := repo.dbCerts.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE retired_date IS NOT NULL AND retired_date < ?", entities.SigningKeyTableName),
+		date.UTC(),
+	)
+	return err
+}
+
+// CreateRefreshToken stores the hash of a refresh token of the session opened on the channel of audience
+func (repo *Repository) CreateRefreshToken(userUUID, sessionUUID, audience, tokenHash string, expires time.Time) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, uuid_user, uuid_session, audience, token_hash, expires_date) VALUES(?,?,?,?,?,?)",
+			entities.RefreshTokenTableName),
+		uuid.New().String(), userUUID, sessionUUID, audience, tokenHash, expires.UTC(),
+	)
+	return err
+}
+
+// ConsumeRefreshToken marks as used an unexpired refresh token issued for audience returning it with the role of its user.
+// A used token is still accepted until usedAfter is past its first use, so that the concurrent requests renewing the
+// same session all succeed. ErrInvalidRefreshToken when it does not exist or it was used before usedAfter
+func (repo *Repository) ConsumeRefreshToken(tokenHash, audience string, usedAfter time.Time) (*entities.RefreshToken, *entities.Role, error) {
+	var token entities.RefreshToken
+	var role entities.Role
+
+	query := fmt.Sprintf("SELECT t.*, r.role_string FROM %s AS t JOIN %s AS r ON r.uuid = t.uuid_user "+
+		"WHERE t.token_hash = ? AND t.audience = ? AND t.expires_date > ? AND (t.used_date IS NULL OR t.used_date > ?) LIMIT 1",
+		entities.RefreshTokenTableName, entities.RoleTableName)
+
+	now := time.Now().UTC()
+	row := repo.dbUser.QueryRow(query, tokenHash, audience, now, usedAfter.UTC())
+	err := row.Scan(&token.UUID, &token.UserUUID, &token.SessionUUID, &token.Audience, &token.TokenHash, &token.ExpiresDate,
+		&token.UsedDate, &role.RoleString)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, customErrors.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the first use is kept, the reuse window starts from it
+	_, err = repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET used_date = ? WHERE uuid = ? AND used_date IS NULL", entities.RefreshTokenTableName),
+		now, token.UUID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &token, &role, nil
+}
+
+// DeleteSession deletes the refresh tokens of a session of the user
+func (repo *Repository) DeleteSession(userUUID, sessionUUID string) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid_user = ? AND uuid_session = ?", entities.RefreshTokenTableName),
+		userUUID, sessionUUID,
+	)
+	return err
+}
+
+// RevokeToken refuses the token with the given uuid claim until it expires
+func (repo *Repository) RevokeToken(tokenUUID string, expires time.Time) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, expires_date) VALUES(?,?) ON DUPLICATE KEY UPDATE expires_date = VALUES(expires_date)",
+			entities.RevokedTokenTableName),
+		tokenUUID, expires.UTC(),
+	)
+	return err
+}
+
+// IsTokenRevoked whether the token with the given uuid claim has been revoked, the tokens of the users disabled
+// or deleted are revoked as well
+func (repo *Repository) IsTokenRevoked(tokenUUID, userUUID string) (bool, error) {
+	var revoked bool
+
+	row := repo.dbUser.QueryRow(
+		fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE uuid = ?) OR NOT EXISTS(SELECT 1 FROM %s WHERE uuid = ? AND disabled = FALSE)",
+			entities.RevokedTokenTableName, entities.UserTableName),
+		tokenUUID, userUUID,
+	)
+	err := row.Scan(&revoked)
+	return revoked, err
+}
+
+// personalAccessTokenBuilder maps a personal access token row, columns follow the table definition order
+func personalAccessTokenBuilder() (any, []any) {
+	t := &entities.PersonalAccessToken{}
+	return t, []any{
+		&t.UUID,
+		&t.UserUUID,
+		&t.Name,
+		&t.TokenHash,
+		&t.Scope,
+		&t.CreatedDate,
+		&t.ExpiresDate,
+		&t.LastUsedDate,
+	}
+}
+
+func (repo *Repository) CreatePersonalAccessToken(userUUID, name, scope, tokenHash string, expires time.Time) (string, error) {
+	tokenUUID := uuid.New().String()
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, uuid_user, name, token_hash, scope, expires_date) VALUES(?,?,?,?,?,?)",
+			entities.PersonalAccessTokenTableName),
+		tokenUUID, userUUID, name, tokenHash, scope, expires.UTC(),
+	)
+	return tokenUUID, err
+}
+
+// GetPersonalAccessTokensByUserID returns paginated personal access tokens of a user, newest first
+func (repo *Repository) GetPersonalAccessTokensByUserID(userUUID string, offset uint) (tokens []*entities.PersonalAccessToken, length int, e error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE uuid_user = ? ORDER BY created_date DESC LIMIT %v OFFSET ?",
+			entities.PersonalAccessTokenTableName, constants.Limit),
+		personalAccessTokenBuilder,
+		userUUID, (offset-1)*constants.Limit,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		tokens = append(tokens, item.(*entities.PersonalAccessToken))
+	}
+
+	count, err := qq.countQueryResults(
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE uuid_user = ?", entities.PersonalAccessTokenTableName),
+		userUUID,
+	)
+	return tokens, count, err
+}
+
+// GetPersonalAccessToken returns the unexpired personal access token with the role of its user, the tokens of the
+// disabled users are not returned. ErrInvalidPersonalAccessToken when there is none
+func (repo *Repository) GetPersonalAccessToken(tokenHash string) (*entities.PersonalAccessToken, *entities.Role, error) {
+	var role entities.Role
+
+	query := fmt.Sprintf("SELECT t.*, r.role_string FROM %s AS t JOIN %s AS r ON r.uuid = t.uuid_user "+
+		"JOIN %s AS u ON u.uuid = t.uuid_user WHERE t.token_hash = ? AND t.expires_date > ? AND u.disabled = FALSE LIMIT 1",
+		entities.PersonalAccessTokenTableName, entities.RoleTableName, entities.UserTableName)
+
+	entity, fields := personalAccessTokenBuilder()
+	row := repo.dbUser.QueryRow(query, tokenHash, time.Now().UTC())
+	err := row.Scan(append(fields, &role.RoleString)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, customErrors.ErrInvalidPersonalAccessToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entity.(*entities.PersonalAccessToken), &role, nil
+}
+
+// TouchPersonalAccessToken records that the token has just been used
+func (repo *Repository) TouchPersonalAccessToken(tokenUUID string) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET last_used_date = ? WHERE uuid = ?", entities.PersonalAccessTokenTableName),
+		time.Now().UTC(), tokenUUID,
+	)
+	return err
+}
+
+// DeletePersonalAccessToken revokes a personal access token of the user, ErrElementNotFound when it does not exist
+func (repo *Repository) DeletePersonalAccessToken(userUUID, tokenUUID string) error {
+	deleted, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid_user = ? AND uuid = ?", entities.PersonalAccessTokenTableName),
+		userUUID, tokenUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(deleted, customErrors.ErrElementNotFound)
+}
+
+// DeleteExpiredTokens prunes the revoked tokens, the refresh tokens and the personal access tokens that expired,
+// and the refresh tokens used before usedBefore
+func (repo *Repository) DeleteExpiredTokens(usedBefore time.Time) error {
+	now := time.Now().UTC()
+	for _, table := range []string{entities.RevokedTokenTableName, entities.RefreshTokenTableName, entities.PersonalAccessTokenTableName} {
+		if _, err := repo.dbUser.Exec(fmt.Sprintf("DELETE FROM %s WHERE expires_date <= ?", table), now); err != nil {
+			return err
+		}
+	}
+
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE used_date <= ?", entities.RefreshTokenTableName),
+		usedBefore.UTC(),
+	)
+	return err
+}
+
+// ownedOrShared returns the condition matching the rows of the user and the ones shared with its teams, with its args.
+// When roles are given only the teams where the user has one of them are considered
+func ownedOrShared(userUUID string, roles ...string) (string, []any) {
+	args := []any{userUUID, userUUID}
+	roleCondition := ""
+	if len(roles) > 0 {
+		roleCondition = fmt.Sprintf(" AND role IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(roles)), ","))
+		for _, role := range roles {
+			args = append(args, role)
+		}
+	}
+
+	return fmt.Sprintf("(uuid_user = ? OR uuid_team IN (SELECT uuid_team FROM %s WHERE uuid_user = ?%s))",
+		entities.TeamMemberTableName, roleCondition), args
+}
+
+// CreateTeam creates a team with the user as its owner
+func (repo *Repository) CreateTeam(userUUID, name string) (string, error) {
+	teamUUID := uuid.New().String()
+	if _, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, name) VALUES(?,?)", entities.TeamTableName),
+		teamUUID, name,
+	); err != nil {
+		return "", err
+	}
+
+	return teamUUID, repo.SetTeamMember(teamUUID, userUUID, constants.TeamOwner)
+}
+
+// GetTeamsByUserID returns the teams of the user with its role in each of them
+func (repo *Repository) GetTeamsByUserID(userUUID string) (teams []*entities.UserTeam, length int, e error) {
+	userTeamBuilder := func() (any, []any) {
+		t := &entities.UserTeam{}
+		return t, []any{
+			&t.TeamUUID,
+			&t.Name,
+			&t.CreatedDate,
+			&t.Role,
+		}
+	}
+
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT t.uuid, t.name, t.created_date, m.role FROM %s AS t JOIN %s AS m ON m.uuid_team = t.uuid "+
+			"WHERE m.uuid_user = ? ORDER BY t.name",
+			entities.TeamTableName, entities.TeamMemberTableName),
+		userTeamBuilder,
+		userUUID,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		teams = append(teams, item.(*entities.UserTeam))
+	}
+	return teams, len(teams), nil
+}
+
+// GetTeamRole returns the role of the user in the team, ErrElementNotFound when it is not a member
+func (repo *Repository) GetTeamRole(userUUID, teamUUID string) (string, error) {
+	var role string
+
+	row := repo.dbUser.QueryRow(
+		fmt.Sprintf("SELECT role FROM %s WHERE uuid_team = ? AND uuid_user = ?", entities.TeamMemberTableName),
+		teamUUID, userUUID,
+	)
+	err := row.Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", customErrors.ErrElementNotFound
+	}
+	return role, err
+}
+
+// GetTeamMembers returns the members of the team, owners first
+func (repo *Repository) GetTeamMembers(teamUUID string) (members []*entities.TeamMember, length int, e error) {
+	teamMemberBuilder := func() (any, []any) {
+		m := &entities.TeamMember{}
+		return m, []any{
+			&m.UserUUID,
+			&m.Username,
+			&m.Role,
+		}
+	}
+
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT u.uuid, u.username, m.role FROM %s AS m JOIN %s AS u ON u.uuid = m.uuid_user "+
+			"WHERE m.uuid_team = ? ORDER BY FIELD(m.role, ?, ?, ?), u.username",
+			entities.TeamMemberTableName, entities.UserTableName),
+		teamMemberBuilder,
+		teamUUID, constants.TeamOwner, constants.TeamMember, constants.TeamViewer,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		members = append(members, item.(*entities.TeamMember))
+	}
+	return members, len(members), nil
+}
+
+// SetTeamMember adds the user to the team, or changes its role when it is a member already
+func (repo *Repository) SetTeamMember(teamUUID, userUUID, role string) error {
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid_team, uuid_user, role) VALUES(?,?,?) ON DUPLICATE KEY UPDATE role = VALUES(role)",
+			entities.TeamMemberTableName),
+		teamUUID, userUUID, role,
+	)
+	return err
+}
+
+// CountTeamOwners returns how many owners the team has
+func (repo *Repository) CountTeamOwners(teamUUID string) (int, error) {
+	qq := queryHandler{repo.dbUser}
+	return qq.countQueryResults(
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE uuid_team = ? AND role = ?", entities.TeamMemberTableName),
+		teamUUID, constants.TeamOwner,
+	)
+}
+
+// DeleteTeamMember removes the user from the team, the resources it shared stay with the team
+func (repo *Repository) DeleteTeamMember(teamUUID, userUUID string) error {
+	deleted, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid_team = ? AND uuid_user = ?", entities.TeamMemberTableName),
+		teamUUID, userUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(deleted, customErrors.ErrElementNotFound)
+}
+
+// DeleteTeam deletes the team, the resources shared with it go back to their owners only
+func (repo *Repository) DeleteTeam(teamUUID string) error {
+	deleted, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid = ?", entities.TeamTableName),
+		teamUUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(deleted, customErrors.ErrElementNotFound)
+}
+
+// updateResourceTeam sets the team of the rows of table matching condition, ErrElementNotFound when there are none.
+// Rows are counted first since rows already shared with the team would not be affected by the update
+func (repo *Repository) updateResourceTeam(table, condition string, teamUUID *string, args ...any) error {
+	qq := queryHandler{repo.dbUser}
+	count, err := qq.countQueryResults(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, condition), args...)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return customErrors.ErrElementNotFound
+	}
+
+	_, err = repo.dbUser.Exec(
+		fmt.Sprintf("UPDATE %s SET uuid_team = ? WHERE %s", table, condition),
+		append([]any{teamUUID}, args...)...,
+	)
+	return err
+}
+
+// ShareResource shares a resource of the user with a team, a nil team makes it personal again
+func (repo *Repository) ShareResource(table, userUUID, resourceUUID string, teamUUID *string) error {
+	return repo.updateResourceTeam(table, "uuid_user = ? AND uuid = ?", teamUUID, userUUID, resourceUUID)
+}
+
+// UnshareTeamResource removes a resource from its team, as long as the user owns that team
+func (repo *Repository) UnshareTeamResource(table, userUUID, resourceUUID string) error {
+	return repo.updateResourceTeam(table,
+		fmt.Sprintf("uuid = ? AND uuid_team IN (SELECT uuid_team FROM %s WHERE uuid_user = ? AND role = ?)",
+			entities.TeamMemberTableName),
+		nil, resourceUUID, userUUID, constants.TeamOwner,
+	)
+}
+
+// GetSharedClient returns the client of the user or shared with a team where the user has one of the roles
+func (repo *Repository) GetSharedClient(userUUID, clientUUID string, roles ...string) (*entities.Client, error) {
+	condition, args := ownedOrShared(userUUID, roles...)
+
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE %s AND uuid = ?", entities.ClientTableName, condition),
+		clientBuilder,
+		append(args, clientUUID)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, customErrors.ErrElementNotFound
+	}
+	return results[0].(*entities.Client), nil
+}
+
+// GetSharedHandshake returns the handshake of the user or shared with a team where the user has one of the roles,
+// without its logs and capture
+func (repo *Repository) GetSharedHandshake(userUUID, handshakeUUID string, roles ...string) (*entities.Handshake, error) {
+	condition, args := ownedOrShared(userUUID, roles...)
+
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT %s FROM %s WHERE %s AND uuid = ?", handshakeSummaryColumns, entities.HandshakeTableName, condition),
+		handshakeBuilder,
+		append(args, handshakeUUID)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, customErrors.ErrElementNotFound
+	}
+	return results[0].(*entities.Handshake), nil
+}
+
+// GetHandshakeCapture returns the base64 capture of a handshake of the user or shared with one of its teams, nil if there is none
+func (repo *Repository) GetHandshakeCapture(userUUID, handshakeUUID string) (*string, error) {
+	return repo.getHandshakeColumn(userUUID, handshakeUUID, "handshake_pcap")
+}
+
+// GetHandshakeLogs returns the hashcat logs of a handshake of the user or shared with one of its teams, nil if it has never been cracked
+func (repo *Repository) GetHandshakeLogs(userUUID, handshakeUUID string) (*string, error) {
+	return repo.getHandshakeColumn(userUUID, handshakeUUID, "hashcat_logs")
+}
+
+func (repo *Repository) getHandshakeColumn(userUUID, handshakeUUID, column string) (*string, error) {
+	condition, args := ownedOrShared(userUUID)
+
+	var value *string
+	err := repo.dbUser.QueryRow(
+		fmt.Sprintf("SELECT %s FROM %s WHERE %s AND uuid = ?", column, entities.HandshakeTableName, condition),
+		append(args, handshakeUUID)...,
+	).Scan(&value)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, customErrors.ErrElementNotFound
+	}
+	return value, err
+}
+
+// exportedHandshakeBuilder maps a handshake row followed by the name of its assigned client
+func exportedHandshakeBuilder() (any, []any) {
+	h, fields := handshakeBuilder()
+	e := &entities.ExportedHandshake{Handshake: h.(*entities.Handshake)}
+	return e, append(fields, &e.ClientName)
+}
+
+// GetExportedHandshakes returns the handshakes of the user or shared with its teams, with their captures,
+// restricted to handshakeUUIDs when any is given
+func (repo *Repository) GetExportedHandshakes(userUUID string, handshakeUUIDs []string) ([]*entities.ExportedHandshake, error) {
+	condition, args := ownedOrShared(userUUID)
+
+	if len(handshakeUUIDs) > 0 {
+		condition += fmt.Sprintf(" AND uuid IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(handshakeUUIDs)), ","))
+		for _, handshakeUUID := range handshakeUUIDs {
+			args = append(args, handshakeUUID)
+		}
+	}
+
+	return repo.getExportedHandshakes("*", condition, args)
+}
+
+// GetCrackedHandshakes returns the cracked handshakes of the user or shared with its teams, without their logs and captures
+func (repo *Repository) GetCrackedHandshakes(userUUID string) ([]*entities.ExportedHandshake, error) {
+	condition, args := ownedOrShared(userUUID)
+	return repo.getExportedHandshakes(handshakeSummaryColumns, condition+" AND status = ?", append(args, constants.CrackedStatus))
+}
+
+func (repo *Repository) getExportedHandshakes(columns, condition string, args []any) ([]*entities.ExportedHandshake, error) {
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT %s, (SELECT c.name FROM %s c WHERE c.uuid = h.uuid_assigned_client) FROM %s h WHERE %s ORDER BY uploaded_date, uuid",
+			columns, entities.ClientTableName, entities.HandshakeTableName, condition),
+		exportedHandshakeBuilder,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	handshakes := make([]*entities.ExportedHandshake, 0, len(results))
+	for _, item := range results {
+		handshakes = append(handshakes, item.(*entities.ExportedHandshake))
+	}
+	return handshakes, nil
+}
+
+// wordlistBuilder maps a wordlist row, columns follow the table definition order
+func wordlistBuilder() (any, []any) {
+	w := &entities.Wordlist{}
+	return w, []any{
+		&w.UUID,
+		&w.UserUUID,
+		&w.Name,
+		&w.Path,
+		&w.CreatedDate,
+		&w.TeamUUID,
+	}
+}
+
+func (repo *Repository) CreateWordlist(userUUID, name, path string, teamUUID *string) (string, error) {
+	wordlistUUID := uuid.New().String()
+	_, err := repo.dbUser.Exec(
+		fmt.Sprintf("INSERT INTO %s(uuid, uuid_user, name, path, uuid_team) VALUES(?,?,?,?,?)", entities.WordlistTableName),
+		wordlistUUID, userUUID, name, path, teamUUID,
+	)
+	return wordlistUUID, err
+}
+
+// GetWordlistsByUserID returns the wordlists of the user and the ones shared with its teams
+func (repo *Repository) GetWordlistsByUserID(userUUID string) (wordlists []*entities.Wordlist, length int, e error) {
+	condition, args := ownedOrShared(userUUID)
+
+	qq := queryHandler{repo.dbUser}
+	results, err := qq.queryEntities(
+		fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY name", entities.WordlistTableName, condition),
+		wordlistBuilder,
+		args...,
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for _, item := range results {
+		wordlists = append(wordlists, item.(*entities.Wordlist))
+	}
+	return wordlists, len(wordlists), nil
+}
+
+// DeleteWordlist deletes a wordlist of the user, or shared with a team the user owns
+func (repo *Repository) DeleteWordlist(userUUID, wordlistUUID string) error {
+	deleted, err := repo.dbUser.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE uuid = ? AND (uuid_user = ? OR uuid_team IN "+
+			"(SELECT uuid_team FROM %s WHERE uuid_user = ? AND role = ?))",
+			entities.WordlistTableName, entities.TeamMemberTableName),
+		wordlistUUID, userUUID, userUUID, constants.TeamOwner,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ensureAffected(deleted, customErrors.ErrElementNotFound)
+}
@@ -0,0 +1,372 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/raspberrypi"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/google/uuid"
+)
+
+// Handler the routes of the administrators, they are granted the admin scope only
+type Handler struct {
+	Usecase *usecase.Usecase
+}
+
+type PageRequest struct {
+	Page uint `query:"page" validate:"required,min=1"`
+}
+
+// GetUsers handles logic for listing every user
+func (u Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request PageRequest
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	users, counted, err := u.Usecase.GetUsers(request.Page)
+
+	if counted == 0 {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    customErrors.ErrElementNotFound.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnUsersResponse{
+		Length: counted,
+		Users:  users,
+	})
+}
+
+// CreateUser handles logic for creating a user, registrations do not need to be enabled
+func (u Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request entities.CreateUserRequest
+
+	if err := utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err := validateCredentials(request.Username, request.Password); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	userEntity := &entities.User{
+		UserUUID: uuid.New().String(),
+		Username: request.Username,
+		Password: request.Password,
+	}
+
+	if err := u.Usecase.CreateUser(userEntity, constants.Role(request.Role)); err != nil {
+		c.JSON(http.StatusConflict, entities.UniformResponse{
+			StatusCode: http.StatusConflict,
+			Details:    customErrors.ErrUsernameAlreadyTaken.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.UniformResponse{
+		StatusCode: http.StatusOK,
+		Details:    userEntity.UserUUID,
+	})
+}
+
+// validateCredentials the criteria of the registrations
+func validateCredentials(username, password string) error {
+	if !utils.IsValidUsername(username) {
+		return customErrors.ErrBadPUsernameCriteria
+	}
+
+	if !utils.IsValidPassword(password) {
+		return customErrors.ErrBadPasswordCriteria
+	}
+	return nil
+}
+
+// DeleteUser handles logic for deleting a user with everything it owns
+func (u Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	var request entities.DeleteUserRequest
+
+	u.userOperation(w, r, &request, func(adminID string) error {
+		return u.Usecase.DeleteUser(adminID, request.UserUUID)
+	})
+}
+
+// DisableUser handles logic for disabling a user or enabling it again
+func (u Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	var request entities.DisableUserRequest
+
+	u.userOperation(w, r, &request, func(adminID string) error {
+		return u.Usecase.DisableUser(adminID, request.UserUUID, *request.Disabled)
+	})
+}
+
+// ResetUserPassword handles logic for setting the password of a user
+func (u Handler) ResetUserPassword(w http.ResponseWriter, r *http.Request) {
+	var request entities.ResetUserPasswordRequest
+
+	u.userOperation(w, r, &request, func(_ string) error {
+		if !utils.IsValidPassword(request.Password) {
+			return customErrors.ErrBadPasswordCriteria
+		}
+		return u.Usecase.ResetUserPassword(request.UserUUID, request.Password)
+	})
+}
+
+// UpdateUserRole handles logic for promoting or demoting a user
+func (u Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var request entities.UpdateUserRoleRequest
+
+	u.userOperation(w, r, &request, func(adminID string) error {
+		return u.Usecase.UpdateUserRole(adminID, request.UserUUID, constants.Role(request.Role))
+	})
+}
+
+// userOperation decodes the request of an operation on a user and answers with its outcome
+func (u Handler) userOperation(w http.ResponseWriter, r *http.Request, request any, operation func(adminID string) error) {
+	c := response.Initializer{ResponseWriter: w}
+
+	adminID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err = utils.ValidateJSON(request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	err = operation(adminID.String())
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, entities.UserOperationResponse{
+			Status: true,
+		})
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+	case errors.Is(err, customErrors.ErrOwnAccount):
+		c.JSON(http.StatusConflict, entities.UniformResponse{
+			StatusCode: http.StatusConflict,
+			Details:    err.Error(),
+		})
+	case errors.Is(err, customErrors.ErrBadPasswordCriteria):
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+	}
+}
+
+// GetClients handles logic for listing the clients of every user
+func (u Handler) GetClients(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request PageRequest
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	clients, counted, err := u.Usecase.GetAllClients(request.Page)
+
+	if counted == 0 {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    customErrors.ErrElementNotFound.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnClientsInstalledResponse{
+		Length:  counted,
+		Clients: clients,
+	})
+}
+
+// GetDevices handles logic for listing the raspberry pi devices of every user
+func (u Handler) GetDevices(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request PageRequest
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	rspDevices, counted, err := u.Usecase.GetAllRaspberryPIs(request.Page)
+
+	if counted == 0 {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    customErrors.ErrElementNotFound.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	devices := make([]*entities.CustomRaspberryPIResponse, 0, len(rspDevices))
+	for _, dev := range rspDevices {
+		devices = append(devices, raspberrypi.DeviceResponse(dev))
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnRaspberryPiDevicesResponse{
+		Length:  counted,
+		Devices: devices,
+	})
+}
+
+// GetTasks handles logic for listing the handshakes of every user waiting for or being cracked by a client
+func (u Handler) GetTasks(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request PageRequest
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	handshakes, counted, err := u.Usecase.GetTaskQueue(request.Page)
+
+	if counted == 0 {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    customErrors.ErrElementNotFound.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.GetHandshakeResponse{
+		Length:     counted,
+		Handshakes: handshakes,
+	})
+}
+
+// GetRegistrations handles logic for telling whether new users can sign up
+func (u Handler) GetRegistrations(w http.ResponseWriter, _ *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	enabled, err := u.Usecase.RegistrationsAllowed()
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.RegistrationsResponse{
+		Enabled: enabled,
+	})
+}
+
+// UpdateRegistrations handles logic for enabling or disabling the registrations
+func (u Handler) UpdateRegistrations(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request entities.RegistrationsRequest
+
+	if err := utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err := u.Usecase.SetRegistrationsAllowed(*request.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.RegistrationsResponse{
+		Enabled: *request.Enabled,
+	})
+}
@@ -0,0 +1,202 @@
+// nolint all
+package admin_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type AdminAPITestSuite struct {
+	testsuite.RESTTestSuite
+}
+
+// Run All tests
+func TestAdminAPI(t *testing.T) {
+	suite.Run(t, new(AdminAPITestSuite))
+}
+
+func (s *AdminAPITestSuite) Test_AdminAPI() {
+	adminToken := s.Login(s.UserFixture)
+	userToken := s.Login(s.NormalUserFixture)
+
+	adminRequest := func(method, route, token string, query url.Values, body any) (string, error) {
+		return testsuite.APIRequest(method, testsuite.APIADMIN+route, token, query, body)
+	}
+	page := url.Values{"page": {"1"}}
+	yes, no := true, false
+	login := func(username, password string) (string, error) {
+		return testsuite.AuthAPI(entities.AuthRequest{Username: username, Password: password})
+	}
+
+	created := &entities.CreateUserRequest{
+		Username: "managed" + utils.GenerateToken(6),
+		Password: "Test1234!",
+		Role:     string(constants.USER),
+	}
+	var createdUUID string
+
+	s.Run("Admin routes refused to users", func() {
+		_, err := adminRequest(http.MethodGet, "/users", userToken, page, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusForbidden))
+	})
+
+	s.Run("Users listed", func() {
+		response, err := adminRequest(http.MethodGet, "/users", adminToken, page, nil)
+		s.Require().NoError(err)
+
+		var users entities.ReturnUsersResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &users))
+		s.Require().GreaterOrEqual(users.Length, 2)
+	})
+
+	s.Run("User created", func() {
+		response, err := adminRequest(http.MethodPost, "/users", adminToken, nil, created)
+		s.Require().NoError(err)
+
+		var uniform entities.UniformResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &uniform))
+		createdUUID = uniform.Details
+
+		_, err = adminRequest(http.MethodPost, "/users", adminToken, nil, created)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusConflict))
+
+		_, err = login(created.Username, created.Password)
+		s.Require().NoError(err)
+	})
+
+	s.Run("Disabled user cannot log in and its tokens are refused", func() {
+		token, err := login(created.Username, created.Password)
+		s.Require().NoError(err)
+
+		_, err = adminRequest(http.MethodPost, "/users/disable", adminToken, nil, &entities.DisableUserRequest{UserUUID: createdUUID, Disabled: &yes})
+		s.Require().NoError(err)
+
+		_, err = login(created.Username, created.Password)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnauthorized))
+
+		_, err = s.Service.Usecase.ValidateToken(token, constants.ScopeFE)
+		s.Require().ErrorIs(err, customErrors.ErrTokenRevoked)
+
+		_, err = adminRequest(http.MethodPost, "/users/disable", adminToken, nil, &entities.DisableUserRequest{UserUUID: createdUUID, Disabled: &no})
+		s.Require().NoError(err)
+
+		_, err = login(created.Username, created.Password)
+		s.Require().NoError(err)
+	})
+
+	s.Run("Password reset", func() {
+		_, err := adminRequest(http.MethodPost, "/users/password", adminToken, nil, &entities.ResetUserPasswordRequest{UserUUID: createdUUID, Password: "weak"})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusBadRequest))
+
+		_, err = adminRequest(http.MethodPost, "/users/password", adminToken, nil, &entities.ResetUserPasswordRequest{UserUUID: createdUUID, Password: "Reset1234!"})
+		s.Require().NoError(err)
+
+		_, err = login(created.Username, created.Password)
+		s.Require().Error(err)
+		_, err = login(created.Username, "Reset1234!")
+		s.Require().NoError(err)
+	})
+
+	s.Run("Role changed", func() {
+		_, err := adminRequest(http.MethodPost, "/users/role", adminToken, nil, &entities.UpdateUserRoleRequest{UserUUID: createdUUID, Role: string(constants.ADMIN)})
+		s.Require().NoError(err)
+
+		promoted, err := login(created.Username, "Reset1234!")
+		s.Require().NoError(err)
+		_, err = adminRequest(http.MethodGet, "/users", promoted, page, nil)
+		s.Require().NoError(err)
+
+		// the admin scope of the token is not enough once demoted
+		_, err = adminRequest(http.MethodPost, "/users/role", adminToken, nil, &entities.UpdateUserRoleRequest{UserUUID: createdUUID, Role: string(constants.USER)})
+		s.Require().NoError(err)
+		_, err = adminRequest(http.MethodGet, "/users", promoted, page, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusForbidden))
+	})
+
+	s.Run("Administrators cannot act on their own account", func() {
+		_, err := adminRequest(http.MethodPost, "/users/disable", adminToken, nil, &entities.DisableUserRequest{UserUUID: s.UserFixture.UserUUID, Disabled: &yes})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusConflict))
+
+		_, err = adminRequest(http.MethodDelete, "/users", adminToken, nil, &entities.DeleteUserRequest{UserUUID: s.UserFixture.UserUUID})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusConflict))
+	})
+
+	s.Run("System-wide resources listed", func() {
+		// a task of another user
+		clientUUID, err := s.Service.Usecase.CreateClient(s.NormalUserFixture.UserUUID, utils.GenerateToken(32), "", "admin client")
+		s.Require().NoError(err)
+
+		handshakeUUID, err := s.Service.Usecase.CreateHandshake(s.NormalUserFixture.UserUUID, "admin", "XX:XX:XX:XX:XX:XX", constants.NothingStatus, utils.StringToBase64String("admin.pcap"))
+		s.Require().NoError(err)
+
+		_, err = s.Service.Usecase.UpdateClientTask(s.NormalUserFixture.UserUUID, handshakeUUID, clientUUID, constants.PendingStatus, "", "", "")
+		s.Require().NoError(err)
+
+		response, err := adminRequest(http.MethodGet, "/clients", adminToken, page, nil)
+		s.Require().NoError(err)
+
+		var clients entities.ReturnClientsInstalledResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &clients))
+		s.Require().GreaterOrEqual(clients.Length, 1)
+
+		response, err = adminRequest(http.MethodGet, "/tasks", adminToken, page, nil)
+		s.Require().NoError(err)
+
+		var tasks entities.GetHandshakeResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &tasks))
+		s.Require().GreaterOrEqual(tasks.Length, 1)
+		for _, task := range tasks.Handshakes {
+			s.Require().Contains([]string{constants.PendingStatus, constants.WorkingStatus}, task.Status)
+			s.Require().Nil(task.HandshakePCAP)
+		}
+	})
+
+	s.Run("Registrations toggled at runtime", func() {
+		register := func() error {
+			password := "Test1234!"
+			marshaled, err := json.Marshal(map[string]string{
+				"username":     "signup" + utils.GenerateToken(6),
+				"password":     password,
+				"confirmation": password,
+			})
+			s.Require().NoError(err)
+			_, err = testsuite.HTTPRequest(http.MethodPost, testsuite.APIREGISTER, map[string]string{}, url.Values{}, marshaled, 10*time.Second)
+			return err
+		}
+
+		allowed, err := s.Service.Usecase.RegistrationsAllowed()
+		s.Require().NoError(err)
+		defer func() {
+			s.Require().NoError(s.Service.Usecase.SetRegistrationsAllowed(allowed))
+		}()
+
+		_, err = adminRequest(http.MethodPost, "/registrations", adminToken, nil, &entities.RegistrationsRequest{Enabled: &no})
+		s.Require().NoError(err)
+		s.Require().ErrorContains(register(), fmt.Sprintf("%d", http.StatusUnauthorized))
+
+		_, err = adminRequest(http.MethodPost, "/registrations", adminToken, nil, &entities.RegistrationsRequest{Enabled: &yes})
+		s.Require().NoError(err)
+		s.Require().NoError(register())
+	})
+
+	s.Run("User deleted", func() {
+		_, err := adminRequest(http.MethodDelete, "/users", adminToken, nil, &entities.DeleteUserRequest{UserUUID: createdUUID})
+		s.Require().NoError(err)
+
+		_, err = login(created.Username, "Reset1234!")
+		s.Require().Error(err)
+
+		_, err = adminRequest(http.MethodDelete, "/users", adminToken, nil, &entities.DeleteUserRequest{UserUUID: createdUUID})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+	})
+}
@@ -1,10 +1,12 @@
 package authenticate
 
 import (
+	"errors"
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"net/http"
 
-	"github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	rr "github.com/Virgula0/progetto-dp/server/backend/internal/response"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
@@ -48,13 +50,13 @@ func (u Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		statusCode := http.StatusUnauthorized
 		c.JSON(statusCode, entities.UniformResponse{
 			StatusCode: statusCode,
-			Details:    errors.ErrInvalidCredentials.Error(),
+			Details:    customErrors.ErrInvalidCredentials.Error(),
 		})
 		return
 	}
 
-	// Create the auth token
-	token, err := u.Usecase.CreateAuthToken(user.UserUUID, role.RoleString)
+	// Create the access and refresh tokens
+	session, err := u.Usecase.CreateSession(user.UserUUID, role.RoleString, constants.AudienceFE)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 
@@ -65,9 +67,45 @@ func (u Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send the token in response
-	c.JSON(http.StatusOK, entities.UniformResponse{
-		StatusCode: http.StatusOK,
-		Details:    token,
-	})
+	// Send the tokens in response
+	session.StatusCode = http.StatusOK
+	c.JSON(http.StatusOK, session)
+}
+
+// RefreshHandler exchanges a refresh token for a new access token and a new refresh token
+func (u Handler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	c := rr.Initializer{ResponseWriter: w}
+
+	var request entities.RefreshTokenRequest
+
+	if err := utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	session, err := u.Usecase.RefreshSession(request.RefreshToken, constants.AudienceFE)
+
+	if errors.Is(err, customErrors.ErrInvalidRefreshToken) {
+		statusCode := http.StatusUnauthorized
+		c.JSON(statusCode, entities.UniformResponse{
+			StatusCode: statusCode,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		c.JSON(statusCode, entities.UniformResponse{
+			StatusCode: statusCode,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	session.StatusCode = http.StatusOK
+	c.JSON(http.StatusOK, session)
 }
@@ -1,10 +1,11 @@
 package client
 
 import (
+	"errors"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
 	"net/http"
 
-	"github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 	"github.com/Virgula0/progetto-dp/server/entities"
@@ -14,7 +15,8 @@ type Handler struct {
 	Usecase *usecase.Usecase
 }
 type ReturnClientDevicesRequest struct {
-	Page uint `query:"page" validate:"required,min=1"`
+	Page     uint `query:"page" validate:"required,min=1"`
+	PageSize uint `query:"page_size" validate:"omitempty,min=1,max=100"`
 }
 
 // ReturnClientsInstalled handles logic for returning installed clients
@@ -41,12 +43,12 @@ func (u Handler) ReturnClientsInstalled(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	clientsInstalled, counted, err := u.Usecase.GetClientsInstalledByUserID(userID.String(), request.Page)
+	clientsInstalled, counted, err := u.Usecase.GetClientsInstalledByUserID(userID.String(), request.Page, request.PageSize)
 
 	if counted == 0 {
 		c.JSON(http.StatusNotFound, entities.UniformResponse{
 			StatusCode: http.StatusNotFound,
-			Details:    errors.ErrElementNotFound.Error(),
+			Details:    customErrors.ErrElementNotFound.Error(),
 		})
 		return
 	}
@@ -77,7 +79,7 @@ func (u Handler) ReturnClientsInstalled(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// DeleteClient handles logic for deleting a existing client
+// DeleteClient handles logic for deleting a existing client, the client is revoked until approved again
 func (u Handler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 	c := response.Initializer{ResponseWriter: w}
 
@@ -103,6 +105,14 @@ func (u Handler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 
 	deleted, err := u.Usecase.DeleteClient(userID.String(), request.ClientUUID)
 
+	if errors.Is(err, customErrors.ErrElementNotFound) {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	}
+
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -116,6 +126,58 @@ func (u Handler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ApproveClient handles logic for approving or rejecting a client waiting for approval
+func (u Handler) ApproveClient(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ApproveClientRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	err = u.Usecase.ApproveClient(userID.String(), request.ClientUUID, *request.Approve)
+
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	case errors.Is(err, customErrors.ErrAlreadyApproved):
+		c.JSON(http.StatusConflict, entities.UniformResponse{
+			StatusCode: http.StatusConflict,
+			Details:    err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ApproveClientResponse{
+		Status: true,
+	})
+}
+
 // UpdateEncryptionClientStatus update encryption client status
 func (u Handler) UpdateEncryptionClientStatus(w http.ResponseWriter, r *http.Request) {
 	c := response.Initializer{ResponseWriter: w}
@@ -149,6 +211,14 @@ func (u Handler) UpdateEncryptionClientStatus(w http.ResponseWriter, r *http.Req
 	}
 
 	err = u.Usecase.UpdateEncryptionClientStatus(request.ClientUUID, userID.String(), *request.Status)
+	if errors.Is(err, customErrors.ErrClientPendingApproval) {
+		c.JSON(http.StatusConflict, entities.UniformResponse{
+			StatusCode: http.StatusConflict,
+			Details:    err.Error(),
+		})
+		return
+	}
+
 	if err != nil {
 		c.JSON(http.StatusNotFound, entities.UniformResponse{
 			StatusCode: http.StatusNotFound,
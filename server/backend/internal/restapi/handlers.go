@@ -29,7 +29,7 @@ func NewServiceHandler(dbUser, dbCerts *infrastructure.Database) (ServiceHandler
 	if constants.WipeTables {
 
 		// Delete data from DB
-		err = dbUser.CleanDB([]string{entities.UserTableName})
+		err = dbUser.CleanDB([]string{entities.UserTableName, entities.TeamTableName})
 
 		if err != nil {
 			return ServiceHandler{}, err
@@ -50,6 +50,12 @@ func NewServiceHandler(dbUser, dbCerts *infrastructure.Database) (ServiceHandler
 	}
 
 	uc := usecase.NewUsecase(repo)
+	if err = uc.LoadSigningKeys(); err != nil {
+		e := fmt.Errorf("fail LoadSigningKeys: %s", err.Error())
+		log.Println(e)
+		return ServiceHandler{}, e
+	}
+
 	return ServiceHandler{
 		Usecase: uc,
 	}, nil
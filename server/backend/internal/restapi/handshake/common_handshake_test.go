@@ -0,0 +1,17 @@
+// nolint all
+package handshake_test
+
+import (
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type HandshakeTestSuite struct {
+	testsuite.RESTTestSuite
+}
+
+// Run All tests
+func TestHandshakeAPI(t *testing.T) {
+	suite.Run(t, new(HandshakeTestSuite))
+}
@@ -0,0 +1,116 @@
+package handshake
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetHandshake returns a handshake of the user or shared with one of its teams, without its logs and capture
+func (u Handler) GetHandshake(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	handshake, ok := handshakeResource(u, c, r, u.Usecase.GetHandshake)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, handshake)
+}
+
+// GetHandshakeCapture downloads the capture of a handshake, as uploaded or converted to the hashcat 22000 format
+func (u Handler) GetHandshakeCapture(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request entities.CaptureRequest
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	lookup, contentType, extension := u.Usecase.GetHandshakeCapture, constants.PCAPContentType, constants.PCAPFormat
+	if request.Format == constants.HC22000Format {
+		lookup, contentType, extension = u.Usecase.GetHandshakeHashes, constants.TextContentType, constants.HC22000Format
+	}
+
+	capture, ok := handshakeResource(u, c, r, lookup)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, mux.Vars(r)["uuid"], extension))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(capture); err != nil {
+		log.Errorf("[ERROR] While writing capture -> %s", err.Error())
+	}
+}
+
+// GetHandshakeLogs returns the hashcat logs of a handshake as text, empty until a client works on it
+func (u Handler) GetHandshakeLogs(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	logs, ok := handshakeResource(u, c, r, u.Usecase.GetHandshakeLogs)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.TextContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write([]byte(logs)); err != nil {
+		log.Errorf("[ERROR] While writing logs -> %s", err.Error())
+	}
+}
+
+// handshakeResource looks up the handshake in the path for the user of the token, the error is answered when it fails
+func handshakeResource[T any](u Handler, c response.Initializer, r *http.Request, lookup func(userUUID, handshakeUUID string) (T, error)) (T, bool) {
+	var resource T
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return resource, false
+	}
+
+	resource, err = lookup(userID.String(), mux.Vars(r)["uuid"])
+
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return resource, false
+	case errors.Is(err, customErrors.ErrNoCrackableHandshake), errors.Is(err, customErrors.ErrCaptureFormat):
+		c.JSON(http.StatusUnprocessableEntity, entities.UniformResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Details:    err.Error(),
+		})
+		return resource, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return resource, false
+	}
+
+	return resource, true
+}
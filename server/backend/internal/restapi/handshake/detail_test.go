@@ -0,0 +1,60 @@
+// nolint all
+package handshake_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"net/http"
+	"net/url"
+)
+
+func (s *HandshakeTestSuite) Test_HandshakeDetail() {
+	ownerToken := s.Login(s.UserFixture)
+	otherToken := s.Login(s.NormalUserFixture)
+
+	ssid := "detail-" + utils.GenerateToken(6)
+	handshakeUUID, err := s.Service.Usecase.CreateHandshake(s.UserFixture.UserUUID, ssid, "XX:XX:XX:XX:XX:XX", constants.NothingStatus, utils.StringToBase64String("detail capture"))
+	s.Require().NoError(err)
+
+	clientUUID, err := s.Service.Usecase.CreateClient(s.UserFixture.UserUUID, utils.GenerateToken(32), "", "detail client")
+	s.Require().NoError(err)
+
+	_, err = s.Service.Usecase.UpdateClientTask(s.UserFixture.UserUUID, handshakeUUID, clientUUID, constants.WorkingStatus, "-a 0", "hashcat logs", "")
+	s.Require().NoError(err)
+
+	s.Run("Listings leave the capture and the logs out", func() {
+		response, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES, ownerToken, url.Values{"search": {ssid}}, nil)
+		s.Require().NoError(err)
+		s.Require().Contains(response, handshakeUUID)
+		s.Require().NotContains(response, "HandshakePCAP")
+		s.Require().NotContains(response, "HashcatLogs")
+
+		response, err = testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/"+handshakeUUID, ownerToken, nil, nil)
+		s.Require().NoError(err)
+		var handshake entities.Handshake
+		s.Require().NoError(json.Unmarshal([]byte(response), &handshake))
+		s.Require().Equal(ssid, handshake.SSID)
+		s.Require().Nil(handshake.HandshakePCAP)
+	})
+
+	s.Run("Capture and logs downloaded apart", func() {
+		capture, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/"+handshakeUUID+"/capture", ownerToken, nil, nil)
+		s.Require().NoError(err)
+		s.Require().Equal("detail capture", capture)
+
+		logs, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/"+handshakeUUID+"/logs", ownerToken, nil, nil)
+		s.Require().NoError(err)
+		s.Require().Equal("hashcat logs", logs)
+	})
+
+	s.Run("Handshakes of others not found", func() {
+		for _, suffix := range []string{"", "/capture", "/logs"} {
+			_, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/"+handshakeUUID+suffix, otherToken, nil, nil)
+			s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package handshake
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/wpaparser"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	JSONFormat    = "json"
+	PotfileFormat = "potfile"
+)
+
+var crackedCSVHeader = []string{"handshake_uuid", "ssid", "bssid", "password", "cracked_date", "client_uuid", "client_name"}
+
+// ExportHandshakes downloads a tar.gz archive with the captures of the handshakes asked, or of all of them, and their manifest
+func (u Handler) ExportHandshakes(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.HandshakeExportRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if request.Format == "" {
+		request.Format = constants.PCAPFormat
+	}
+
+	var handshakeUUIDs []string
+	if request.UUIDs != "" {
+		handshakeUUIDs = strings.Split(request.UUIDs, ",")
+	}
+
+	archive, err := u.Usecase.ExportHandshakes(userID.String(), handshakeUUIDs, request.Format)
+
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.GzipContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="handshakes-%s.tar.gz"`, request.Format))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(archive); err != nil {
+		log.Errorf("[ERROR] While writing export -> %s", err.Error())
+	}
+}
+
+// GetCrackedResults exports the passwords recovered from the handshakes of the user as JSON, CSV or hashcat potfile
+func (u Handler) GetCrackedResults(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.CrackedResultsRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	results, err := u.Usecase.GetCrackedResults(userID.String())
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var write func(io.Writer, []*entities.CrackedResult) error
+
+	switch request.Format {
+	case CSVFormat:
+		w.Header().Set("Content-Type", constants.CSVContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="cracked.csv"`)
+		write = writeCrackedCSV
+	case PotfileFormat:
+		w.Header().Set("Content-Type", constants.TextContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="hashcat.potfile"`)
+		write = writePotfile
+	default:
+		c.JSON(http.StatusOK, entities.CrackedResultsResponse{
+			Length:  len(results),
+			Results: results,
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if err = write(w, results); err != nil {
+		log.Errorf("[ERROR] While writing cracked results -> %s", err.Error())
+	}
+}
+
+// writeCrackedCSV passwords come from wordlists and potfiles, they are escaped like the SSIDs.
+// The JSON and potfile formats give them as they are
+func writeCrackedCSV(w io.Writer, results []*entities.CrackedResult) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(crackedCSVHeader); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if err := writer.Write([]string{
+			result.HandshakeUUID,
+			csvSafe(result.SSID),
+			csvSafe(result.BSSID),
+			csvSafe(result.Password),
+			valueOrEmpty(result.CrackedDate),
+			valueOrEmpty(result.ClientUUID),
+			csvSafe(valueOrEmpty(result.ClientName)),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writePotfile writes an entry for each network only once, hashcat keys its entries by ESSID and password
+func writePotfile(w io.Writer, results []*entities.CrackedResult) error {
+	seen := make(map[string]bool)
+
+	for _, result := range results {
+		entry, err := wpaparser.PotfileEntry(result.SSID, result.Password)
+		if err != nil {
+			return err
+		}
+
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+
+		if _, err = fmt.Fprintln(w, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
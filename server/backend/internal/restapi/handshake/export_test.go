@@ -0,0 +1,130 @@
+// nolint all
+package handshake_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (s *HandshakeTestSuite) Test_HandshakeExport() {
+	ownerToken := s.Login(s.UserFixture)
+	otherToken := s.Login(s.NormalUserFixture)
+
+	// IEEE and password are the PMK test vector of 802.11i
+	handshakeUUID, err := s.Service.Usecase.CreateHandshake(s.UserFixture.UserUUID, "IEEE", "0A:"+strings.ToUpper(utils.GenerateToken(2))+":00:00:00:01", constants.NothingStatus, utils.StringToBase64String("export capture"))
+	s.Require().NoError(err)
+
+	clientUUID, err := s.Service.Usecase.CreateClient(s.UserFixture.UserUUID, utils.GenerateToken(32), "", "export client")
+	s.Require().NoError(err)
+
+	cracked, err := s.Service.Usecase.UpdateClientTask(s.UserFixture.UserUUID, handshakeUUID, clientUUID, constants.CrackedStatus, "-a 0", "hashcat logs", "[password],[another password]")
+	s.Require().NoError(err)
+	s.Require().NotNil(cracked.CrackedDate)
+
+	formulaUUID, err := s.Service.Usecase.CreateHandshake(s.UserFixture.UserUUID, "=FORMULA", "0B:"+strings.ToUpper(utils.GenerateToken(2))+":00:00:00:01", constants.NothingStatus, utils.StringToBase64String("export capture"))
+	s.Require().NoError(err)
+
+	_, err = s.Service.Usecase.UpdateClientTask(s.UserFixture.UserUUID, formulaUUID, clientUUID, constants.CrackedStatus, "-a 0", "hashcat logs", "[@SUM(1+1)]")
+	s.Require().NoError(err)
+
+	s.Run("Cracked results as JSON, CSV and potfile", func() {
+		response, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/cracked", ownerToken, nil, nil)
+		s.Require().NoError(err)
+
+		var results entities.CrackedResultsResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &results))
+
+		passwords := make([]string, 0)
+		for _, result := range results.Results {
+			if result.HandshakeUUID == handshakeUUID {
+				passwords = append(passwords, result.Password)
+				s.Require().Equal(*cracked.CrackedDate, *result.CrackedDate)
+				s.Require().Equal("export client", *result.ClientName)
+			}
+		}
+		s.Require().Equal([]string{"password", "another password"}, passwords)
+
+		response, err = testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/cracked", ownerToken, url.Values{"format": {"csv"}}, nil)
+		s.Require().NoError(err)
+		s.Require().Contains(response, fmt.Sprintf("%s,IEEE,%s,password,%s,%s,export client", handshakeUUID, cracked.BSSID, *cracked.CrackedDate, clientUUID))
+		// spreadsheets must not evaluate the passwords either
+		s.Require().Contains(response, fmt.Sprintf("%s,'=FORMULA,", formulaUUID))
+		s.Require().Contains(response, ",'@SUM(1+1),")
+
+		response, err = testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/cracked", ownerToken, url.Values{"format": {"potfile"}}, nil)
+		s.Require().NoError(err)
+		s.Require().Contains(response, "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e*49454545:password\n")
+
+		response, err = testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/cracked", otherToken, nil, nil)
+		s.Require().NoError(err)
+		s.Require().NotContains(response, handshakeUUID)
+	})
+
+	s.Run("Archive of the selected captures", func() {
+		response, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/export", ownerToken, url.Values{"uuids": {handshakeUUID}}, nil)
+		s.Require().NoError(err)
+
+		files := s.readArchive([]byte(response))
+		s.Require().Equal("export capture", string(files[handshakeUUID+".pcap"]))
+
+		var manifest entities.HandshakeExportManifest
+		s.Require().NoError(json.Unmarshal(files[constants.ExportManifest], &manifest))
+		s.Require().Len(manifest.Handshakes, 1)
+		s.Require().Equal([]string{"password", "another password"}, manifest.Handshakes[0].Passwords)
+		s.Require().Equal("export client", *manifest.Handshakes[0].ClientName)
+	})
+
+	s.Run("Captures which are not pcaps can't be converted to 22000", func() {
+		_, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/"+handshakeUUID+"/capture", ownerToken, url.Values{"format": {"22000"}}, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnprocessableEntity))
+
+		response, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/export", ownerToken, url.Values{"uuids": {handshakeUUID}, "format": {"22000"}}, nil)
+		s.Require().NoError(err)
+
+		files := s.readArchive([]byte(response))
+		s.Require().NotContains(files, handshakeUUID+".22000")
+
+		var manifest entities.HandshakeExportManifest
+		s.Require().NoError(json.Unmarshal(files[constants.ExportManifest], &manifest))
+		s.Require().Empty(manifest.Handshakes[0].File)
+		s.Require().Contains(manifest.Handshakes[0].Error, customErrors.ErrCaptureFormat.Error())
+	})
+
+	s.Run("Handshakes of others are not exported", func() {
+		_, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES+"/export", otherToken, url.Values{"uuids": {handshakeUUID}}, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+	})
+}
+
+// readArchive returns the files of a tar.gz archive by name
+func (s *HandshakeTestSuite) readArchive(archive []byte) map[string][]byte {
+	compressed, err := gzip.NewReader(bytes.NewReader(archive))
+	s.Require().NoError(err)
+
+	reader := tar.NewReader(compressed)
+	files := make(map[string][]byte)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		s.Require().NoError(err)
+
+		content, err := io.ReadAll(reader)
+		s.Require().NoError(err)
+		files[header.Name] = content
+	}
+	return files
+}
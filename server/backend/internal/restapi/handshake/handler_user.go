@@ -3,6 +3,7 @@ package handshake
 import (
 	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	log "github.com/sirupsen/logrus"
 	"net/http"
 
 	"github.com/Virgula0/progetto-dp/server/backend/internal/errors"
@@ -15,11 +16,7 @@ type Handler struct {
 	Usecase *usecase.Usecase
 }
 
-type GetHandshakesRequest struct {
-	Page uint `query:"page" validate:"required,min=1"`
-}
-
-// GetHandshakes handles logic for getting user's handshakes
+// GetHandshakes handles logic for getting user's handshakes, see entities.HandshakeFilter for the query parameters
 func (u Handler) GetHandshakes(w http.ResponseWriter, r *http.Request) {
 	c := response.Initializer{ResponseWriter: w}
 
@@ -33,7 +30,7 @@ func (u Handler) GetHandshakes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var request GetHandshakesRequest
+	var request entities.HandshakeFilter
 
 	if err = utils.ValidateQueryParameters(&request, r); err != nil {
 		c.JSON(http.StatusBadRequest, entities.UniformResponse{
@@ -43,7 +40,15 @@ func (u Handler) GetHandshakes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	handshakes, counted, err := u.Usecase.GetHandshakes(userID.String(), request.Page)
+	handshakes, counted, nextCursor, err := u.Usecase.GetHandshakes(userID.String(), &request)
+
+	if err == errors.ErrInvalidCursor {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
 
 	if counted == 0 {
 		c.JSON(http.StatusNotFound, entities.UniformResponse{
@@ -64,9 +69,62 @@ func (u Handler) GetHandshakes(w http.ResponseWriter, r *http.Request) {
 	c.JSON(http.StatusOK, entities.GetHandshakeResponse{
 		Length:     counted,
 		Handshakes: handshakes,
+		NextCursor: nextCursor,
 	})
 }
 
+type GetHandshakeLocationsRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=geojson csv"`
+}
+
+// GetHandshakeLocations exports where the handshakes of the user have been captured, as GeoJSON or CSV
+func (u Handler) GetHandshakeLocations(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request GetHandshakeLocationsRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	handshakes, err := u.Usecase.GetGeotaggedHandshakes(userID.String())
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if request.Format != CSVFormat {
+		c.JSON(http.StatusOK, locationsToGeoJSON(handshakes))
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.CSVContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="handshake-locations.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	if err = writeLocationsCSV(w, handshakes); err != nil {
+		log.Errorf("[ERROR] While writing locations -> %s", err.Error())
+	}
+}
+
 // UpdateClientTask handles logic for updating handshake status
 func (u Handler) UpdateClientTask(w http.ResponseWriter, r *http.Request) {
 	c := response.Initializer{ResponseWriter: w}
@@ -0,0 +1,129 @@
+// nolint all
+package handshake_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+func (s *HandshakeTestSuite) Test_HandshakeListing() {
+	token := s.Login(s.UserFixture)
+
+	list := func(query url.Values) (*entities.GetHandshakeResponse, error) {
+		response, err := testsuite.APIRequest(http.MethodGet, testsuite.APIHANDSHAKES, token, query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var handshakes entities.GetHandshakeResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &handshakes))
+		return &handshakes, nil
+	}
+
+	// SSIDs not used by the other tests, some cracked and some classified as if uploaded by a daemon
+	prefix := "list-" + utils.GenerateToken(6)
+	ssids := make([]string, 0)
+	for i := 0; i < 7; i++ {
+		status := constants.NothingStatus
+		if i%3 == 0 {
+			status = constants.CrackedStatus
+		}
+
+		ssid := fmt.Sprintf("%s-%d", prefix, 6-i)
+		handshakeUUID, err := s.Service.Usecase.CreateHandshake(s.UserFixture.UserUUID, ssid, "XX:XX:XX:XX:XX:XX", status, utils.StringToBase64String("list.pcap"))
+		s.Require().NoError(err)
+		ssids = append(ssids, ssid)
+
+		if i%2 == 0 {
+			_, err = s.DatabaseUser.Exec(fmt.Sprintf("UPDATE %s SET quality = ? WHERE uuid = ?", entities.HandshakeTableName), constants.QualityComplete, handshakeUUID)
+			s.Require().NoError(err)
+		}
+	}
+	slices.Sort(ssids)
+
+	s.Run("Filters", func() {
+		handshakes, err := list(url.Values{"search": {prefix}})
+		s.Require().NoError(err)
+		s.Require().Equal(7, handshakes.Length)
+		s.Require().Len(handshakes.Handshakes, constants.Limit)
+
+		handshakes, err = list(url.Values{"search": {prefix}, "status": {constants.CrackedStatus}, "page_size": {"10"}})
+		s.Require().NoError(err)
+		s.Require().Equal(3, handshakes.Length)
+		for _, handshake := range handshakes.Handshakes {
+			s.Require().Equal(constants.CrackedStatus, handshake.Status)
+		}
+
+		handshakes, err = list(url.Values{"search": {prefix}, "quality": {constants.QualityComplete}, "page_size": {"10"}})
+		s.Require().NoError(err)
+		s.Require().Equal(4, handshakes.Length)
+		for _, handshake := range handshakes.Handshakes {
+			s.Require().Equal(constants.QualityComplete, *handshake.Quality)
+		}
+
+		handshakes, err = list(url.Values{"search": {prefix}, "quality": {constants.QualityUnknown}, "page_size": {"10"}})
+		s.Require().NoError(err)
+		s.Require().Equal(3, handshakes.Length)
+		for _, handshake := range handshakes.Handshakes {
+			s.Require().Nil(handshake.Quality)
+		}
+
+		_, err = list(url.Values{"search": {prefix}, "quality": {constants.QualityPartial}})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+
+		_, err = list(url.Values{"search": {prefix}, "uploaded_from": {time.Now().AddDate(0, 0, 2).Format(time.DateOnly)}})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+
+		// wildcards are matched literally
+		_, err = list(url.Values{"search": {"list-%"}})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+	})
+
+	s.Run("Pages by cursor", func() {
+		collect := func(query url.Values) []string {
+			listed := make([]string, 0)
+			for {
+				handshakes, err := list(query)
+				s.Require().NoError(err)
+				for _, handshake := range handshakes.Handshakes {
+					listed = append(listed, handshake.SSID)
+				}
+
+				if handshakes.NextCursor == "" {
+					return listed
+				}
+				// the cursor carries the sort
+				query = url.Values{"search": {prefix}, "page_size": {query.Get("page_size")}, "cursor": {handshakes.NextCursor}}
+			}
+		}
+
+		s.Require().Equal(ssids, collect(url.Values{"search": {prefix}, "sort": {"ssid"}, "order": {"asc"}, "page_size": {"3"}}))
+
+		// handshakes uploaded in the same second are told apart by their UUID
+		listed := collect(url.Values{"search": {prefix}, "page_size": {"2"}})
+		slices.Sort(listed)
+		s.Require().Equal(ssids, listed)
+	})
+
+	s.Run("Invalid parameters", func() {
+		_, err := list(url.Values{"cursor": {"invalid"}})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusBadRequest))
+
+		_, err = list(url.Values{"page_size": {"101"}})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusBadRequest))
+
+		_, err = list(url.Values{"sort": {"hashcat_logs"}})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusBadRequest))
+
+		_, err = list(url.Values{"quality": {"perfect"}})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusBadRequest))
+	})
+}
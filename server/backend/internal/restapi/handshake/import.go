@@ -0,0 +1,65 @@
+package handshake
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+)
+
+// ImportHandshakes imports captures, 22000 hash files and potfiles, alone or archived, and reports the outcome of each network
+func (u Handler) ImportHandshakes(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	// the file travels base64 encoded inside the JSON body
+	r.Body = http.MaxBytesReader(w, r.Body, constants.MaxImportSize/3*4+(64<<10))
+
+	var request entities.ImportHandshakesRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	imported, err := u.Usecase.ImportHandshakes(userID.String(), request.Name, request.File)
+
+	switch {
+	case errors.Is(err, customErrors.ErrImportInvalid):
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	case errors.Is(err, customErrors.ErrUploadTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, entities.UniformResponse{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Details:    err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, imported)
+}
@@ -0,0 +1,165 @@
+// nolint all
+package handshake_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/wpaparser"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"net"
+	"net/http"
+	"strings"
+)
+
+func (s *HandshakeTestSuite) Test_HandshakeImport() {
+	token := s.Login(s.UserFixture)
+
+	post := func(request entities.ImportHandshakesRequest) (string, error) {
+		return testsuite.APIRequest(http.MethodPost, testsuite.APIHANDSHAKES+"/import", token, nil, request)
+	}
+
+	// two access points share the ESSID, the potfile has the password of the first one only
+	essid := "IMPORT" + utils.GenerateToken(8)
+	ap := "0b" + utils.GenerateToken(10)
+	otherAP := "0d" + utils.GenerateToken(10)
+	line := s.pmkidLine(essid, "password", ap)
+	hashes := line + "\n" + s.pmkidLine(essid, "another password", otherAP) + "\nnot a hash\n"
+
+	potfileEntry, err := wpaparser.PotfileEntry(essid, "password")
+	s.Require().NoError(err)
+
+	// an entry whose password is not the one of its PMK
+	otherEntry, err := wpaparser.PotfileEntry(essid, "another password")
+	s.Require().NoError(err)
+	forgedEntry := strings.SplitN(otherEntry, ":", 2)[0] + ":password"
+
+	potfile := forgedEntry + "\n" + potfileEntry + "\n" + strings.Repeat("cd", 16) + ":0c0000000001:0a0000000001:unknown network:password\n"
+
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	archive := tar.NewWriter(compressed)
+	for _, file := range []struct{ name, content string }{
+		{"hashcat.potfile", potfile},
+		{"network.22000", hashes},
+		{"again.22000", hashes},
+		{"notes.txt", "not a capture"},
+	} {
+		s.Require().NoError(archive.WriteHeader(&tar.Header{Name: file.name, Mode: 0o600, Size: int64(len(file.content))}))
+		_, err = archive.Write([]byte(file.content))
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(archive.Close())
+	s.Require().NoError(compressed.Close())
+
+	response, err := post(entities.ImportHandshakesRequest{Name: "import.tar.gz", File: buffer.Bytes()})
+	s.Require().NoError(err)
+
+	var imported entities.ImportHandshakesResponse
+	s.Require().NoError(json.Unmarshal([]byte(response), &imported))
+	s.Require().Equal(2, imported.Imported)
+	s.Require().Equal(2, imported.Duplicates)
+	s.Require().Equal(1, imported.Cracked)
+	s.Require().Equal(1, imported.Unmatched)
+	s.Require().Equal(3, imported.Failed)
+
+	var handshakeUUID, otherUUID string
+	var potfileErrors []string
+	for _, outcome := range imported.Outcomes {
+		switch {
+		case outcome.File == "network.22000" && outcome.BSSID == formatMAC(ap):
+			handshakeUUID = outcome.HandshakeUUID
+			s.Require().Equal(essid, outcome.SSID)
+		case outcome.File == "network.22000" && outcome.BSSID == formatMAC(otherAP):
+			otherUUID = outcome.HandshakeUUID
+			s.Require().Equal(essid, outcome.SSID)
+		case outcome.File == "hashcat.potfile" && outcome.Result == constants.FailedResult:
+			potfileErrors = append(potfileErrors, outcome.Error)
+		}
+	}
+	s.Require().ElementsMatch([]string{customErrors.ErrPotfilePMKMismatch.Error(), customErrors.ErrPasswordNotCracking.Error()}, potfileErrors)
+
+	handshake, err := s.Service.Usecase.GetHandshake(s.UserFixture.UserUUID, handshakeUUID)
+	s.Require().NoError(err)
+	s.Require().Equal(constants.CrackedStatus, handshake.Status)
+	s.Require().Equal("[password]", *handshake.CrackedHandshake)
+	s.Require().NotNil(handshake.CrackedDate)
+
+	other, err := s.Service.Usecase.GetHandshake(s.UserFixture.UserUUID, otherUUID)
+	s.Require().NoError(err)
+	s.Require().Equal(constants.NothingStatus, other.Status)
+	s.Require().Nil(other.CrackedHandshake)
+
+	capture, err := s.Service.Usecase.GetHandshakeCapture(s.UserFixture.UserUUID, handshakeUUID)
+	s.Require().NoError(err)
+	s.Require().Equal(line+"\n", string(capture))
+
+	// a capture without a hash to check the password against is not cracked
+	unverifiableESSID := "IMPORT" + utils.GenerateToken(8)
+	unverifiableUUID, err := s.Service.Usecase.CreateHandshake(s.UserFixture.UserUUID, unverifiableESSID, "0c:00:00:00:00:02",
+		constants.NothingStatus, utils.StringToBase64String("not a capture"))
+	s.Require().NoError(err)
+
+	unverifiableEntry, err := wpaparser.PotfileEntry(unverifiableESSID, "password")
+	s.Require().NoError(err)
+
+	response, err = post(entities.ImportHandshakesRequest{Name: "unverifiable.potfile", File: []byte(unverifiableEntry + "\n")})
+	s.Require().NoError(err)
+
+	imported = entities.ImportHandshakesResponse{}
+	s.Require().NoError(json.Unmarshal([]byte(response), &imported))
+	s.Require().Equal(1, imported.Failed)
+	s.Require().Len(imported.Outcomes, 1)
+	s.Require().Equal(customErrors.ErrPasswordUnverifiable.Error(), imported.Outcomes[0].Error)
+
+	unverifiable, err := s.Service.Usecase.GetHandshake(s.UserFixture.UserUUID, unverifiableUUID)
+	s.Require().NoError(err)
+	s.Require().Equal(constants.NothingStatus, unverifiable.Status)
+
+	// refused before any entry is looked up
+	response, err = post(entities.ImportHandshakesRequest{
+		Name: "huge.potfile",
+		File: []byte(strings.Repeat(potfileEntry+"\n", constants.MaxPotfileEntries+1)),
+	})
+	s.Require().NoError(err)
+
+	imported = entities.ImportHandshakesResponse{}
+	s.Require().NoError(json.Unmarshal([]byte(response), &imported))
+	s.Require().Equal(1, imported.Failed)
+	s.Require().Len(imported.Outcomes, 1)
+	s.Require().Equal(customErrors.ErrTooManyPotfileEntries.Error(), imported.Outcomes[0].Error)
+
+	_, err = post(entities.ImportHandshakesRequest{Name: "broken.tar.gz", File: []byte{0x1f, 0x8b, 0x00}})
+	s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusBadRequest))
+}
+
+// pmkidLine returns the 22000 line of the PMKID the access point would send to the station 0a:00:00:00:00:01
+func (s *HandshakeTestSuite) pmkidLine(essid, password, ap string) string {
+	pmk, err := wpaparser.PMK(essid, password)
+	s.Require().NoError(err)
+
+	apMAC, err := hex.DecodeString(ap)
+	s.Require().NoError(err)
+	station := []byte{0x0a, 0x00, 0x00, 0x00, 0x00, 0x01}
+
+	mac := hmac.New(sha1.New, pmk)
+	mac.Write([]byte("PMK Name"))
+	mac.Write(apMAC)
+	mac.Write(station)
+
+	return fmt.Sprintf("WPA*01*%x*%s*%x*%x***", mac.Sum(nil)[:16], ap, station, essid)
+}
+
+func formatMAC(hexMAC string) string {
+	mac, _ := hex.DecodeString(hexMAC)
+	return net.HardwareAddr(mac).String()
+}
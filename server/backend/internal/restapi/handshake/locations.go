@@ -0,0 +1,86 @@
+package handshake
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Virgula0/progetto-dp/server/entities"
+)
+
+const (
+	GeoJSONFormat = "geojson"
+	CSVFormat     = "csv"
+)
+
+var locationsCSVHeader = []string{"uuid", "ssid", "bssid", "status", "latitude", "longitude", "position_date", "uploaded_date"}
+
+// geotagged handshakes are returned by the repository having latitude and longitude
+func locationsToGeoJSON(handshakes []*entities.Handshake) *entities.HandshakeLocations {
+	collection := &entities.HandshakeLocations{
+		Type:     "FeatureCollection",
+		Features: make([]*entities.HandshakeLocation, 0, len(handshakes)),
+	}
+
+	for _, h := range handshakes {
+		collection.Features = append(collection.Features, &entities.HandshakeLocation{
+			Type: "Feature",
+			Geometry: entities.GeoJSONPoint{
+				Type:        "Point",
+				Coordinates: [2]float64{*h.Longitude, *h.Latitude},
+			},
+			Properties: entities.HandshakeLocationProperties{
+				UUID:         h.UUID,
+				SSID:         h.SSID,
+				BSSID:        h.BSSID,
+				Status:       h.Status,
+				UploadedDate: h.UploadedDate,
+				PositionDate: valueOrEmpty(h.PositionDate),
+			},
+		})
+	}
+
+	return collection
+}
+
+func writeLocationsCSV(w io.Writer, handshakes []*entities.Handshake) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(locationsCSVHeader); err != nil {
+		return err
+	}
+
+	for _, h := range handshakes {
+		if err := writer.Write([]string{
+			h.UUID,
+			csvSafe(h.SSID),
+			csvSafe(h.BSSID),
+			h.Status,
+			strconv.FormatFloat(*h.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(*h.Longitude, 'f', -1, 64),
+			valueOrEmpty(h.PositionDate),
+			h.UploadedDate,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvSafe SSIDs are chosen by whoever runs the access point, spreadsheets must not evaluate them as formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
@@ -35,8 +36,8 @@ func (u Handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
 	// Extract the token
 	token := parts[1]
 
-	// Check if the token is a valid JWT
-	if !utils.IsJWT(token) {
+	// Check if the token is a valid JWT or a personal access token
+	if !utils.IsJWT(token) && !strings.HasPrefix(token, constants.PersonalAccessTokenPrefix) {
 		statusCode := http.StatusUnauthorized
 		c.JSON(statusCode, entities.UniformResponse{
 			StatusCode: statusCode,
@@ -46,7 +47,7 @@ func (u Handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate the token
-	isValid, err := u.Usecase.ValidateToken(token)
+	isValid, err := u.Usecase.ValidateToken(token, constants.ScopeFE)
 	if err != nil || !isValid {
 		statusCode := http.StatusUnauthorized
 		c.JSON(statusCode, entities.UniformResponse{
@@ -56,8 +57,15 @@ func (u Handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Invalidate the token
-	u.Usecase.InvalidateToken(token)
+	// Invalidate the token and its refresh token
+	if err = u.Usecase.InvalidateToken(token); err != nil {
+		statusCode := http.StatusInternalServerError
+		c.JSON(statusCode, entities.UniformResponse{
+			StatusCode: statusCode,
+			Details:    err.Error(),
+		})
+		return
+	}
 
 	// Respond with success
 	c.JSON(http.StatusOK, entities.UniformResponse{
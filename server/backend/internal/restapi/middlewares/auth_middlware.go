@@ -5,22 +5,59 @@ import (
 	"net/http"
 
 	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/gorilla/mux"
 )
 
-// EnsureTokenIsValid Middleware function to ensure the token is valid
+// EnsureTokenIsValid Middleware function to ensure the token is valid and issued for the FE. Reading requires the
+// read scope only, so read-only personal access tokens are accepted by the GET routes
 func (u *TokenAuth) EnsureTokenIsValid(next http.Handler) http.Handler {
+	readOnly := u.EnsureScope(constants.ScopeRead)(next)
+	readWrite := u.EnsureScope(constants.ScopeFE)(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			readOnly.ServeHTTP(w, r)
+			return
+		}
+		readWrite.ServeHTTP(w, r)
+	})
+}
+
+// EnsureScope Middleware function to ensure the token is valid and granted scope
+func (u *TokenAuth) EnsureScope(scope string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := u.TokenValidation(r, w, scope)
+			if token == "" {
+				// Not authorized, ResponseWriter already written, no need to call c.JSON again
+				return
+			}
+
+			// Store the token in the request context
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, constants.TokenConstant, token)
+
+			// Call the next handler with the updated context
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// EnsureDeviceTokenIsValid Middleware function for the routes used by the daemons, only valid device tokens are accepted
+func (u *TokenAuth) EnsureDeviceTokenIsValid(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := u.TokenValidation(r, w)
+		token := bearerToken(r, w)
 		if token == "" {
-			// Not authorized, ResponseWriter already written, no need to call c.JSON again
 			return
 		}
 
-		// Store the token in the request context
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, constants.TokenConstant, token)
+		r = r.WithContext(context.WithValue(r.Context(), constants.TokenConstant, token))
+
+		if _, _, err := u.Usecase.GetDeviceFromToken(r); err != nil {
+			ResponseWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
 
-		// Call the next handler with the updated context
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
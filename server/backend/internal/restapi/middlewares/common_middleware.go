@@ -1,10 +1,13 @@
 package middlewares
 
 import (
+	"errors"
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"net/http"
 	"strings"
 
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
@@ -14,8 +17,30 @@ type TokenAuth struct {
 	Usecase *usecase.Usecase
 }
 
-// TokenValidation a refactored function used by auth_middleware
-func (u *TokenAuth) TokenValidation(r *http.Request, w http.ResponseWriter) string {
+// TokenValidation a refactored function used by auth_middleware, the token must be granted scope
+func (u *TokenAuth) TokenValidation(r *http.Request, w http.ResponseWriter, scope string) string {
+	token := bearerToken(r, w)
+	if token == "" {
+		return ""
+	}
+
+	// Validate the token using the Usecase
+	isValid, err := u.Usecase.ValidateToken(token, scope)
+	if errors.Is(err, customErrors.ErrTokenAudience) || errors.Is(err, customErrors.ErrMissingScope) {
+		ResponseWithError(w, http.StatusForbidden, err.Error())
+		return ""
+	}
+	if err != nil || !isValid {
+		ResponseWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return ""
+	}
+
+	// If the token is valid, return it
+	return token
+}
+
+// bearerToken returns the JWT or the personal access token of the Authorization header, answering the request when it is missing or malformed
+func bearerToken(r *http.Request, w http.ResponseWriter) string {
 	// Extract the Authorization header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -32,20 +57,12 @@ func (u *TokenAuth) TokenValidation(r *http.Request, w http.ResponseWriter) stri
 
 	token := parts[1]
 
-	// Check if the token is a valid JWT
-	if !utils.IsJWT(token) {
+	// Check if the token is a valid JWT, personal access tokens are not
+	if !utils.IsJWT(token) && !strings.HasPrefix(token, constants.PersonalAccessTokenPrefix) {
 		ResponseWithError(w, http.StatusUnauthorized, "Invalid token: token is not in valid JWT format")
 		return ""
 	}
 
-	// Validate the token using the Usecase
-	isValid, err := u.Usecase.ValidateToken(token)
-	if err != nil || !isValid {
-		ResponseWithError(w, http.StatusUnauthorized, "Invalid or expired token")
-		return ""
-	}
-
-	// If the token is valid, return it
 	return token
 }
 
@@ -0,0 +1,344 @@
+package raspberrypi
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"time"
+
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+)
+
+/*
+Routes used by the daemons when the TCP server can't be reached, e.g. from networks blocking its port.
+They have the same semantics as the DEVICELOGIN, KEYEXCHANGE, UPLOADCOMMIT, RESULTS, DIRECTIVES and DIRECTIVEACK commands
+and share their code.
+Device certificates can't be verified here, so when the TCP server requires them these routes are refused
+*/
+
+// uploadTimeout captures are sent in a single request, slow networks need more than the default timeouts
+const uploadTimeout = 5 * time.Minute
+
+// deviceErrorStatus the status code answered to the daemon for err
+func deviceErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, customErrors.ErrHandshakeAlreadyPresent), errors.Is(err, customErrors.ErrDirectiveNotPending):
+		return http.StatusConflict
+	case errors.Is(err, customErrors.ErrRaspberryPINotEnrolled), errors.Is(err, customErrors.ErrRaspberryPIOwnedByAnotherUser),
+		errors.Is(err, customErrors.ErrDeviceCertificateOnlyTCP), errors.Is(err, customErrors.ErrRaspberryPIRevoked),
+		errors.Is(err, customErrors.ErrRaspberryPIPendingApproval):
+		return http.StatusForbidden
+	case errors.Is(err, customErrors.ErrDeviceCredentialInvalid), errors.Is(err, customErrors.ErrDeviceTokenMismatch):
+		return http.StatusUnauthorized
+	case errors.As(err, &tooLarge), errors.Is(err, customErrors.ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, customErrors.ErrHandshakeDecryption), errors.Is(err, gzip.ErrHeader), errors.Is(err, gzip.ErrChecksum),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ensureCertificatesNotRequired answers the request when device certificates are required
+func ensureCertificatesNotRequired(c response.Initializer) bool {
+	if constants.TCPTLSMode != constants.TCPTLSMutual {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, entities.UniformResponse{
+		StatusCode: http.StatusForbidden,
+		Details:    customErrors.ErrDeviceCertificateOnlyTCP.Error(),
+	})
+	return false
+}
+
+// DeviceLogin exchanges the device credential for a device token, as DEVICELOGIN
+func (u Handler) DeviceLogin(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	if !ensureCertificatesNotRequired(c) {
+		return
+	}
+
+	var request entities.RaspberryPILoginRequest
+
+	if err := utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	token, err := u.Usecase.AuthenticateRaspberryPI(request.MachineID, request.Credential)
+	if errors.Is(err, customErrors.ErrRaspberryPIPendingApproval) {
+		u.checkIn(r, request.MachineID, &entities.RaspberryPIMetadata{
+			Hostname:      request.Hostname,
+			DaemonVersion: request.DaemonVersion,
+			OS:            request.OS,
+		})
+	}
+
+	if err != nil {
+		log.Warnf("[REST-API] Device login failed for %s", request.MachineID)
+		c.JSON(deviceErrorStatus(err), entities.UniformResponse{
+			StatusCode: deviceErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	u.checkIn(r, request.MachineID, &entities.RaspberryPIMetadata{
+		Hostname:      request.Hostname,
+		DaemonVersion: request.DaemonVersion,
+		OS:            request.OS,
+	})
+
+	c.JSON(http.StatusOK, entities.UniformResponse{
+		StatusCode: http.StatusOK,
+		Details:    token,
+	})
+}
+
+// checkIn records that the device has been seen, as the TCP server does
+func (u Handler) checkIn(r *http.Request, machineID string, metadata *entities.RaspberryPIMetadata) {
+	if err := u.Usecase.CheckInRaspberryPI(machineID, r.RemoteAddr, metadata); err != nil {
+		log.Warnf("[REST-API] Check-in of %s not recorded: %s", machineID, err.Error())
+	}
+}
+
+// DeviceKeyExchange derives the encryption key of the device, as KEYEXCHANGE
+func (u Handler) DeviceKeyExchange(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	if !ensureCertificatesNotRequired(c) {
+		return
+	}
+
+	userID, machineID, err := u.Usecase.GetDeviceFromToken(r)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, entities.UniformResponse{
+			StatusCode: http.StatusUnauthorized,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.RaspberryPIKeyExchangeRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	serverPublicKey, err := u.Usecase.ExchangeRaspberryPIKey(userID, machineID, request.PublicKey)
+	if err != nil {
+		c.JSON(deviceErrorStatus(err), entities.UniformResponse{
+			StatusCode: deviceErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.RaspberryPIKeyExchangeResponse{
+		PublicKey: serverPublicKey,
+	})
+}
+
+// DeviceUpload saves a capture sent in a single request, as a TCP chunked upload once committed
+func (u Handler) DeviceUpload(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	if !ensureCertificatesNotRequired(c) {
+		return
+	}
+
+	userID, machineID, err := u.Usecase.GetDeviceFromToken(r)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, entities.UniformResponse{
+			StatusCode: http.StatusUnauthorized,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	if err = errors.Join(controller.SetReadDeadline(time.Now().Add(uploadTimeout)),
+		controller.SetWriteDeadline(time.Now().Add(uploadTimeout))); err != nil {
+		log.Warnf("[REST-API] Unable to extend the upload deadline: %s", err.Error())
+	}
+
+	// the payload travels base64 encoded inside the JSON body
+	r.Body = http.MaxBytesReader(w, r.Body, constants.MaxHTTPSUploadSize/3*4+(64<<10))
+
+	var request entities.RaspberryPIUploadRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		status := deviceErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, entities.UniformResponse{
+			StatusCode: status,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if int64(len(request.Payload)) > constants.MaxHTTPSUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, entities.UniformResponse{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Details:    customErrors.ErrUploadTooLarge.Error(),
+		})
+		return
+	}
+
+	handshakeID, err := u.Usecase.IngestRaspberryPICapture(userID, machineID, request.SSID, request.BSSID, request.Payload, request.Position, request.Quality)
+	if err != nil {
+		log.Warnf("[REST-API] Rejected upload of %s from %s: %s", request.BSSID, machineID, err.Error())
+		c.JSON(deviceErrorStatus(err), entities.UniformResponse{
+			StatusCode: deviceErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entities.RaspberryPIUploadResponse{
+		HandshakeUUID: handshakeID,
+	})
+}
+
+// DeviceResults returns the handshakes uploaded by the device which have been cracked, as RESULTS
+func (u Handler) DeviceResults(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	if !ensureCertificatesNotRequired(c) {
+		return
+	}
+
+	userID, machineID, err := u.Usecase.GetDeviceFromToken(r)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, entities.UniformResponse{
+			StatusCode: http.StatusUnauthorized,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	handshakes, err := u.Usecase.GetRaspberryPIResults(userID, machineID)
+	if err != nil {
+		c.JSON(deviceErrorStatus(err), entities.UniformResponse{
+			StatusCode: deviceErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	results := make([]*entities.RaspberryPIResult, 0, len(handshakes))
+	for _, handshake := range handshakes {
+		result := &entities.RaspberryPIResult{
+			HandshakeUUID: handshake.UUID,
+			SSID:          handshake.SSID,
+			BSSID:         handshake.BSSID,
+			UploadedDate:  handshake.UploadedDate,
+		}
+		if handshake.CrackedHandshake != nil {
+			result.CrackedHandshake = *handshake.CrackedHandshake
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, entities.RaspberryPIResultsResponse{
+		Results: results,
+	})
+}
+
+// DeviceDirectives returns the directives the device has not acknowledged yet, as DIRECTIVES
+func (u Handler) DeviceDirectives(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	if !ensureCertificatesNotRequired(c) {
+		return
+	}
+
+	userID, machineID, err := u.Usecase.GetDeviceFromToken(r)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, entities.UniformResponse{
+			StatusCode: http.StatusUnauthorized,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	directives, err := u.Usecase.GetPendingRaspberryPIDirectives(userID, machineID)
+	if err != nil {
+		c.JSON(deviceErrorStatus(err), entities.UniformResponse{
+			StatusCode: deviceErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	u.checkIn(r, machineID, nil)
+
+	c.JSON(http.StatusOK, entities.RaspberryPIDirectivesResponse{
+		Directives: directives,
+	})
+}
+
+// DeviceDirectiveAck records the outcome of a directive applied by the device, as DIRECTIVEACK
+func (u Handler) DeviceDirectiveAck(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	if !ensureCertificatesNotRequired(c) {
+		return
+	}
+
+	userID, machineID, err := u.Usecase.GetDeviceFromToken(r)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, entities.UniformResponse{
+			StatusCode: http.StatusUnauthorized,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	// results hold the logs of the device, they are cut by the usecase
+	r.Body = http.MaxBytesReader(w, r.Body, 2*constants.MaxDirectiveResultSize)
+
+	var request entities.RaspberryPIDirectiveAckRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	err = u.Usecase.AcknowledgeRaspberryPIDirective(userID, machineID, request.DirectiveUUID, request.Applied, request.Result)
+	if err != nil {
+		c.JSON(deviceErrorStatus(err), entities.UniformResponse{
+			StatusCode: deviceErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.UniformResponse{
+		StatusCode: http.StatusOK,
+		Details:    request.DirectiveUUID,
+	})
+}
@@ -1,11 +1,13 @@
 package raspberrypi
 
 import (
+	"errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"net/http"
 
-	"github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
 )
@@ -15,7 +17,8 @@ type Handler struct {
 }
 
 type ReturnRaspberryPiDevicesRequest struct {
-	Page uint `query:"page" validate:"required,min=0"`
+	Page     uint `query:"page" validate:"required,min=0"`
+	PageSize uint `query:"page_size" validate:"omitempty,min=1,max=100"`
 }
 
 // GetRaspberryPIDevices handles logic for getting user's raspberrypi devices
@@ -42,12 +45,12 @@ func (u Handler) GetRaspberryPIDevices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rspDevices, counted, err := u.Usecase.GetRaspberryPI(userID.String(), request.Page)
+	rspDevices, counted, err := u.Usecase.GetRaspberryPI(userID.String(), request.Page, request.PageSize)
 
 	if counted == 0 {
 		c.JSON(http.StatusNotFound, entities.UniformResponse{
 			StatusCode: http.StatusNotFound,
-			Details:    errors.ErrElementNotFound.Error(),
+			Details:    customErrors.ErrElementNotFound.Error(),
 		})
 		return
 	}
@@ -63,13 +66,7 @@ func (u Handler) GetRaspberryPIDevices(w http.ResponseWriter, r *http.Request) {
 	temp := make([]*entities.CustomRaspberryPIResponse, 0)
 
 	for _, dev := range rspDevices {
-		tt := entities.CustomRaspberryPIResponse{
-			UserUUID:        dev.UserUUID,
-			RaspberryPIUUID: dev.RaspberryPIUUID,
-			MachineID:       dev.MachineID,
-		}
-
-		temp = append(temp, &tt)
+		temp = append(temp, DeviceResponse(dev))
 	}
 
 	c.JSON(http.StatusOK, entities.ReturnRaspberryPiDevicesResponse{
@@ -78,7 +75,132 @@ func (u Handler) GetRaspberryPIDevices(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// DeleteRaspberryPI handles logic for deleting a raspberrypi device
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// DeviceResponse what the user can see of a device
+func DeviceResponse(dev *entities.RaspberryPI) *entities.CustomRaspberryPIResponse {
+	return &entities.CustomRaspberryPIResponse{
+		UserUUID:        dev.UserUUID,
+		RaspberryPIUUID: dev.RaspberryPIUUID,
+		MachineID:       dev.MachineID,
+		Enrolled:        dev.CredentialHash != nil,
+		Approved:        dev.Approved,
+		EnrollmentCode:  usecase.RaspberryPIEnrollmentCode(dev),
+		Name:            valueOrEmpty(dev.Name),
+		Hostname:        valueOrEmpty(dev.Hostname),
+		DaemonVersion:   valueOrEmpty(dev.DaemonVersion),
+		OS:              valueOrEmpty(dev.OS),
+		LatestIP:        valueOrEmpty(dev.LatestIP),
+		LastSeen:        valueOrEmpty(dev.LastSeen),
+		TeamUUID:        valueOrEmpty(dev.TeamUUID),
+	}
+}
+
+// GetRaspberryPIDevice returns a raspberrypi device with a page of the captures it uploaded and its activity
+func (u Handler) GetRaspberryPIDevice(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ReturnRaspberryPIRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	dev, err := u.Usecase.GetRaspberryPIByUUID(userID.String(), request.RaspberryPIUUID)
+
+	if err != nil {
+		c.JSON(userErrorStatus(err), entities.UniformResponse{
+			StatusCode: userErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	handshakes, counted, err := u.Usecase.GetRaspberryPIHandshakes(userID.String(), request.RaspberryPIUUID, request.Page)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	activity, err := u.Usecase.GetRaspberryPIActivity(userID.String(), request.RaspberryPIUUID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnRaspberryPIResponse{
+		Device:     DeviceResponse(dev),
+		Length:     counted,
+		Handshakes: handshakes,
+		Activity:   activity,
+	})
+}
+
+// RenameRaspberryPI gives a name to a raspberrypi device
+func (u Handler) RenameRaspberryPI(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.RenameRaspberryPIRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err = u.Usecase.RenameRaspberryPI(userID.String(), request.RaspberryPIUUID, request.Name); err != nil {
+		c.JSON(userErrorStatus(err), entities.UniformResponse{
+			StatusCode: userErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.RenameRaspberryPIResponse{
+		Status: true,
+	})
+}
+
+// DeleteRaspberryPI handles logic for deleting a raspberrypi device, the device is revoked until approved again
 func (u Handler) DeleteRaspberryPI(w http.ResponseWriter, r *http.Request) {
 	c := response.Initializer{ResponseWriter: w}
 
@@ -105,8 +227,8 @@ func (u Handler) DeleteRaspberryPI(w http.ResponseWriter, r *http.Request) {
 	deleted, err := u.Usecase.DeleteRaspberryPI(userID.String(), request.RaspberryPIUUID)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
-			StatusCode: http.StatusInternalServerError,
+		c.JSON(userErrorStatus(err), entities.UniformResponse{
+			StatusCode: userErrorStatus(err),
 			Details:    err.Error(),
 		})
 		return
@@ -116,3 +238,313 @@ func (u Handler) DeleteRaspberryPI(w http.ResponseWriter, r *http.Request) {
 		Status: deleted,
 	})
 }
+
+// ApproveRaspberryPI handles logic for approving or rejecting a raspberrypi device waiting for approval
+func (u Handler) ApproveRaspberryPI(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ApproveRaspberryPIRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err = u.Usecase.ApproveRaspberryPI(userID.String(), request.RaspberryPIUUID, *request.Approve); err != nil {
+		c.JSON(userErrorStatus(err), entities.UniformResponse{
+			StatusCode: userErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ApproveRaspberryPIResponse{
+		Status: true,
+	})
+}
+
+// RotateRaspberryPICredential handles logic for replacing the credential of a raspberrypi device
+func (u Handler) RotateRaspberryPICredential(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.RaspberryPICredentialRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	credential, err := u.Usecase.RotateRaspberryPICredential(userID.String(), request.RaspberryPIUUID)
+
+	if errors.Is(err, customErrors.ErrElementNotFound) {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.RotateRaspberryPICredentialResponse{
+		Credential: credential,
+	})
+}
+
+// RevokeRaspberryPICredential handles logic for revoking the credential of a raspberrypi device
+func (u Handler) RevokeRaspberryPICredential(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.RaspberryPICredentialRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err = u.Usecase.RevokeRaspberryPICredential(userID.String(), request.RaspberryPIUUID); err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.RevokeRaspberryPICredentialResponse{
+		Status: true,
+	})
+}
+
+// ImportRaspberryPIBundle imports the captures of an offline bundle written by a daemon of the user
+func (u Handler) ImportRaspberryPIBundle(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	// the bundle travels base64 encoded inside the JSON body
+	r.Body = http.MaxBytesReader(w, r.Body, constants.MaxBundleSize/3*4+(64<<10))
+
+	var request entities.ImportRaspberryPIBundleRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	imported, err := u.Usecase.ImportRaspberryPIBundle(userID.String(), request.Bundle)
+
+	switch {
+	case errors.Is(err, customErrors.ErrBundleInvalid), errors.Is(err, customErrors.ErrBundleSignature):
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	case errors.Is(err, customErrors.ErrRaspberryPINotEnrolled):
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	case errors.Is(err, customErrors.ErrRaspberryPIPendingApproval):
+		c.JSON(http.StatusConflict, entities.UniformResponse{
+			StatusCode: http.StatusConflict,
+			Details:    err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, imported)
+}
+
+// userErrorStatus the status code answered to the user for err
+func userErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, customErrors.ErrDirectiveInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, customErrors.ErrDirectiveNotPending), errors.Is(err, customErrors.ErrAlreadyApproved):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetRaspberryPIDirectives returns the directives of a raspberrypi device, with what the device reported
+func (u Handler) GetRaspberryPIDirectives(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ReturnRaspberryPIDirectivesRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	directives, err := u.Usecase.GetRaspberryPIDirectives(userID.String(), request.RaspberryPIUUID)
+
+	if err != nil {
+		c.JSON(userErrorStatus(err), entities.UniformResponse{
+			StatusCode: userErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnRaspberryPIDirectivesResponse{
+		Length:     len(directives),
+		Directives: directives,
+	})
+}
+
+// CreateRaspberryPIDirective queues a directive for a raspberrypi device
+func (u Handler) CreateRaspberryPIDirective(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.CreateRaspberryPIDirectiveRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	directiveID, err := u.Usecase.CreateRaspberryPIDirective(userID.String(), request.RaspberryPIUUID, request.Kind, &request.Parameters)
+
+	if err != nil {
+		c.JSON(userErrorStatus(err), entities.UniformResponse{
+			StatusCode: userErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entities.CreateRaspberryPIDirectiveResponse{
+		DirectiveUUID: directiveID,
+	})
+}
+
+// CancelRaspberryPIDirective removes a directive the device has not acknowledged yet
+func (u Handler) CancelRaspberryPIDirective(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.CancelRaspberryPIDirectiveRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err = u.Usecase.CancelRaspberryPIDirective(userID.String(), request.DirectiveUUID); err != nil {
+		c.JSON(userErrorStatus(err), entities.UniformResponse{
+			StatusCode: userErrorStatus(err),
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.CancelRaspberryPIDirectiveResponse{
+		Status: true,
+	})
+}
@@ -27,7 +27,17 @@ func (u Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	c := response.Initializer{ResponseWriter: w}
 	var request Request
 
-	if constants.AllowRegistrations == "" {
+	allowed, err := u.Usecase.RegistrationsAllowed()
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		c.JSON(statusCode, entities.UniformResponse{
+			StatusCode: statusCode,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if !allowed {
 		statusCode := http.StatusUnauthorized
 		c.JSON(statusCode, entities.UniformResponse{
 			StatusCode: statusCode,
@@ -37,7 +47,7 @@ func (u Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate the request
-	if err := utils.ValidateJSON(&request, r); err != nil {
+	if err = utils.ValidateJSON(&request, r); err != nil {
 		statusCode := http.StatusBadRequest
 		c.JSON(statusCode, entities.UniformResponse{
 			StatusCode: statusCode,
@@ -83,7 +93,7 @@ func (u Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call the usecase to create the user
-	err := u.Usecase.CreateUser(userEntity, constants.USER)
+	err = u.Usecase.CreateUser(userEntity, constants.USER)
 	if err != nil {
 		statusCode := http.StatusBadRequest
 		c.JSON(statusCode, entities.UniformResponse{
@@ -0,0 +1,111 @@
+package revocation
+
+import (
+	"errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"net/http"
+
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/entities"
+)
+
+type Handler struct {
+	Usecase *usecase.Usecase
+}
+
+// GetRevocations handles logic for returning the devices and clients deleted by the user
+func (u Handler) GetRevocations(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ReturnRevocationsRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	revocations, counted, err := u.Usecase.GetRevocations(userID.String(), request.Page)
+
+	if counted == 0 {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    customErrors.ErrElementNotFound.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnRevocationsResponse{
+		Length:      counted,
+		Revocations: revocations,
+	})
+}
+
+// ApproveRevocation handles logic for approving again a device or a client deleted by the user
+func (u Handler) ApproveRevocation(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ApproveRevocationRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	err = u.Usecase.ApproveRevocation(userID.String(), request.RevocationUUID)
+
+	if errors.Is(err, customErrors.ErrElementNotFound) {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ApproveRevocationResponse{
+		Status: true,
+	})
+}
@@ -3,6 +3,8 @@ package restapi
 import (
 	"github.com/gorilla/mux"
 
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/admin"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/authenticate"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/client"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/handshake"
@@ -10,23 +12,65 @@ import (
 	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/middlewares"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/raspberrypi"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/register"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/revocation"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/team"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/token"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi/wordlist"
 )
 
 const RouteIndex = "/v1"
 const RouteAuthenticate = "/auth"
+const RouteRefresh = "/auth/refresh"
 const RouteTokenVerifier = "/verify"
 const RouteRegister = "/register"
 const RouteLogout = "/logout"
 const GetClients = "/clients"
 const GetDevices = "/devices"
 const GetHandshakes = "/handshakes"
+const HandshakeLocations = "/handshakes/locations"
+const HandshakeExport = "/handshakes/export"
+const CrackedResults = "/handshakes/cracked"
+const HandshakeImport = "/handshakes/import"
+const HandshakeDetail = "/handshakes/{uuid:[0-9a-f-]{36}}"
+const HandshakeCapture = HandshakeDetail + "/capture"
+const HandshakeLogs = HandshakeDetail + "/logs"
 const UpdateClientTask = "/assign"
 const DeleteClient = "/delete/client"
 const DeleteRaspberryPI = "/delete/raspberrypi"
+const ClientApproval = "/clients/approval"
+const RaspberryPIApproval = "/devices/approval"
+const RaspberryPICredential = "/devices/credential"
+const RaspberryPIBundle = "/devices/bundle"
+const RaspberryPIDirectives = "/devices/directives"
+const RaspberryPIDevice = "/devices/device"
+const RaspberryPIName = "/devices/name"
 const ManageHandshake = "/manage/handshake"
+const Revocations = "/revocations"
+const PersonalAccessTokens = "/tokens"
+const Teams = "/teams"
+const TeamMembers = "/teams/members"
+const TeamShare = "/teams/share"
+const Wordlists = "/wordlists"
 const UpdateClientEncryptionStatus = "/encryption-status"
 const UpdateUserPassword = "/user/password"
 
+// routes of the administrators
+const AdminUsers = "/admin/users"
+const AdminUserDisable = "/admin/users/disable"
+const AdminUserPassword = "/admin/users/password"
+const AdminUserRole = "/admin/users/role"
+const AdminClients = "/admin/clients"
+const AdminDevices = "/admin/devices"
+const AdminTasks = "/admin/tasks"
+const AdminRegistrations = "/admin/registrations"
+
+// routes of the daemons, used when the TCP server is not reachable
+const DeviceLogin = "/device/login"
+const DeviceKeyExchange = "/device/key"
+const DeviceHandshakes = "/device/handshakes"
+const DeviceResults = "/device/results"
+const DeviceDirectives = "/device/directives"
+
 //nolint:funlen // this function can be huge, it does not contain logic, only route directives
 func (h ServiceHandler) InitRoutes(router *mux.Router) {
 
@@ -37,6 +81,11 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 	installedClientsHandler := client.Handler{Usecase: h.Usecase}
 	installedDevicesHandler := raspberrypi.Handler{Usecase: h.Usecase}
 	handshakesHandler := handshake.Handler{Usecase: h.Usecase}
+	revocationsHandler := revocation.Handler{Usecase: h.Usecase}
+	adminHandler := admin.Handler{Usecase: h.Usecase}
+	tokensHandler := token.Handler{Usecase: h.Usecase}
+	teamsHandler := team.Handler{Usecase: h.Usecase}
+	wordlistsHandler := wordlist.Handler{Usecase: h.Usecase}
 
 	// Global middleware for loggin requests
 	router.Use(middlewares.LoggingMiddleware)
@@ -46,6 +95,9 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 	loginRouter.
 		HandleFunc(RouteAuthenticate, authenticateHandler.LoginHandler).
 		Methods("POST")
+	loginRouter.
+		HandleFunc(RouteRefresh, authenticateHandler.RefreshHandler).
+		Methods("POST")
 
 	updatePasswordRouter := router.PathPrefix(RouteIndex).Subrouter()
 	updatePasswordRouter.
@@ -76,6 +128,9 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 	installedClientsRouter.HandleFunc(DeleteClient, installedClientsHandler.DeleteClient).Methods("DELETE")
 	installedClientsRouter.Use(authMiddleware.EnsureTokenIsValid)
 
+	installedClientsRouter.HandleFunc(ClientApproval, installedClientsHandler.ApproveClient).Methods("POST")
+	installedClientsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
 	// Update client encryption status
 	updateEncryptionStatusRouter := router.PathPrefix(RouteIndex).Subrouter()
 	updateEncryptionStatusRouter.HandleFunc(UpdateClientEncryptionStatus, installedClientsHandler.UpdateEncryptionClientStatus).
@@ -87,14 +142,128 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 	installedDevicesRouter.HandleFunc(GetDevices, installedDevicesHandler.GetRaspberryPIDevices).Methods("GET")
 	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
 
+	installedDevicesRouter.HandleFunc(RaspberryPIDevice, installedDevicesHandler.GetRaspberryPIDevice).Methods("GET")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	installedDevicesRouter.HandleFunc(RaspberryPIName, installedDevicesHandler.RenameRaspberryPI).Methods("POST")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
 	installedDevicesRouter.HandleFunc(DeleteRaspberryPI, installedDevicesHandler.DeleteRaspberryPI).Methods("DELETE")
 	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
 
+	installedDevicesRouter.HandleFunc(RaspberryPIApproval, installedDevicesHandler.ApproveRaspberryPI).Methods("POST")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	installedDevicesRouter.HandleFunc(RaspberryPICredential, installedDevicesHandler.RotateRaspberryPICredential).Methods("POST")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	installedDevicesRouter.HandleFunc(RaspberryPICredential, installedDevicesHandler.RevokeRaspberryPICredential).Methods("DELETE")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	installedDevicesRouter.HandleFunc(RaspberryPIBundle, installedDevicesHandler.ImportRaspberryPIBundle).Methods("POST")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	installedDevicesRouter.HandleFunc(RaspberryPIDirectives, installedDevicesHandler.GetRaspberryPIDirectives).Methods("GET")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	installedDevicesRouter.HandleFunc(RaspberryPIDirectives, installedDevicesHandler.CreateRaspberryPIDirective).Methods("POST")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	installedDevicesRouter.HandleFunc(RaspberryPIDirectives, installedDevicesHandler.CancelRaspberryPIDirective).Methods("DELETE")
+	installedDevicesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	// Devices and clients deleted by the user -- AUTHENTICATED --
+	revocationsRouter := router.PathPrefix(RouteIndex).Subrouter()
+	revocationsRouter.HandleFunc(Revocations, revocationsHandler.GetRevocations).Methods("GET")
+	revocationsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	revocationsRouter.HandleFunc(Revocations, revocationsHandler.ApproveRevocation).Methods("DELETE")
+	revocationsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	// Personal access tokens of the user -- AUTHENTICATED --
+	tokensRouter := router.PathPrefix(RouteIndex).Subrouter()
+	tokensRouter.HandleFunc(PersonalAccessTokens, tokensHandler.GetPersonalAccessTokens).Methods("GET")
+	tokensRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	tokensRouter.HandleFunc(PersonalAccessTokens, tokensHandler.CreatePersonalAccessToken).Methods("POST")
+	tokensRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	tokensRouter.HandleFunc(PersonalAccessTokens, tokensHandler.RevokePersonalAccessToken).Methods("DELETE")
+	tokensRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	// Teams of the user and the resources shared with them -- AUTHENTICATED --
+	teamsRouter := router.PathPrefix(RouteIndex).Subrouter()
+	teamsRouter.HandleFunc(Teams, teamsHandler.GetTeams).Methods("GET")
+	teamsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	teamsRouter.HandleFunc(Teams, teamsHandler.CreateTeam).Methods("POST")
+	teamsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	teamsRouter.HandleFunc(Teams, teamsHandler.DeleteTeam).Methods("DELETE")
+	teamsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	teamsRouter.HandleFunc(TeamMembers, teamsHandler.GetTeamMembers).Methods("GET")
+	teamsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	teamsRouter.HandleFunc(TeamMembers, teamsHandler.AddTeamMember).Methods("POST")
+	teamsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	teamsRouter.HandleFunc(TeamMembers, teamsHandler.RemoveTeamMember).Methods("DELETE")
+	teamsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	teamsRouter.HandleFunc(TeamShare, teamsHandler.ShareResource).Methods("POST")
+	teamsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	// Wordlists offered when assigning tasks -- AUTHENTICATED --
+	wordlistsRouter := router.PathPrefix(RouteIndex).Subrouter()
+	wordlistsRouter.HandleFunc(Wordlists, wordlistsHandler.GetWordlists).Methods("GET")
+	wordlistsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	wordlistsRouter.HandleFunc(Wordlists, wordlistsHandler.CreateWordlist).Methods("POST")
+	wordlistsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	wordlistsRouter.HandleFunc(Wordlists, wordlistsHandler.DeleteWordlist).Methods("DELETE")
+	wordlistsRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	// Device login -- NOT AUTHENTICATED, the device credential is in the body --
+	deviceLoginRouter := router.PathPrefix(RouteIndex).Subrouter()
+	deviceLoginRouter.HandleFunc(DeviceLogin, installedDevicesHandler.DeviceLogin).Methods("POST")
+
+	// Daemon uploads -- AUTHENTICATED WITH DEVICE TOKENS --
+	deviceRouter := router.PathPrefix(RouteIndex).Subrouter()
+	deviceRouter.HandleFunc(DeviceKeyExchange, installedDevicesHandler.DeviceKeyExchange).Methods("POST")
+	deviceRouter.HandleFunc(DeviceHandshakes, installedDevicesHandler.DeviceUpload).Methods("POST")
+	deviceRouter.HandleFunc(DeviceResults, installedDevicesHandler.DeviceResults).Methods("GET")
+	deviceRouter.HandleFunc(DeviceDirectives, installedDevicesHandler.DeviceDirectives).Methods("GET")
+	deviceRouter.HandleFunc(DeviceDirectives, installedDevicesHandler.DeviceDirectiveAck).Methods("POST")
+	deviceRouter.Use(authMiddleware.EnsureDeviceTokenIsValid)
+
 	// Get handshake by user -- AUTHENTICATED --
 	handshakesRouter := router.PathPrefix(RouteIndex).Subrouter()
 	handshakesRouter.HandleFunc(GetHandshakes, handshakesHandler.GetHandshakes).Methods("GET")
 	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
 
+	handshakesRouter.HandleFunc(HandshakeLocations, handshakesHandler.GetHandshakeLocations).Methods("GET")
+	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	handshakesRouter.HandleFunc(HandshakeExport, handshakesHandler.ExportHandshakes).Methods("GET")
+	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	handshakesRouter.HandleFunc(CrackedResults, handshakesHandler.GetCrackedResults).Methods("GET")
+	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	handshakesRouter.HandleFunc(HandshakeImport, handshakesHandler.ImportHandshakes).Methods("POST")
+	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	handshakesRouter.HandleFunc(HandshakeDetail, handshakesHandler.GetHandshake).Methods("GET")
+	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	handshakesRouter.HandleFunc(HandshakeCapture, handshakesHandler.GetHandshakeCapture).Methods("GET")
+	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	handshakesRouter.HandleFunc(HandshakeLogs, handshakesHandler.GetHandshakeLogs).Methods("GET")
+	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
 	handshakesRouter.HandleFunc(UpdateClientTask, handshakesHandler.UpdateClientTask).Methods("POST")
 	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
 
@@ -103,4 +272,19 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 
 	handshakesRouter.HandleFunc(ManageHandshake, handshakesHandler.CreateHandshake).Methods("PUT")
 	handshakesRouter.Use(authMiddleware.EnsureTokenIsValid)
+
+	// Users and system-wide resources -- ADMINISTRATORS ONLY --
+	adminRouter := router.PathPrefix(RouteIndex).Subrouter()
+	adminRouter.HandleFunc(AdminUsers, adminHandler.GetUsers).Methods("GET")
+	adminRouter.HandleFunc(AdminUsers, adminHandler.CreateUser).Methods("POST")
+	adminRouter.HandleFunc(AdminUsers, adminHandler.DeleteUser).Methods("DELETE")
+	adminRouter.HandleFunc(AdminUserDisable, adminHandler.DisableUser).Methods("POST")
+	adminRouter.HandleFunc(AdminUserPassword, adminHandler.ResetUserPassword).Methods("POST")
+	adminRouter.HandleFunc(AdminUserRole, adminHandler.UpdateUserRole).Methods("POST")
+	adminRouter.HandleFunc(AdminClients, adminHandler.GetClients).Methods("GET")
+	adminRouter.HandleFunc(AdminDevices, adminHandler.GetDevices).Methods("GET")
+	adminRouter.HandleFunc(AdminTasks, adminHandler.GetTasks).Methods("GET")
+	adminRouter.HandleFunc(AdminRegistrations, adminHandler.GetRegistrations).Methods("GET")
+	adminRouter.HandleFunc(AdminRegistrations, adminHandler.UpdateRegistrations).Methods("POST")
+	adminRouter.Use(authMiddleware.EnsureScope(constants.ScopeAdmin))
 }
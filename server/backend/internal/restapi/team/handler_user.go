@@ -0,0 +1,229 @@
+package team
+
+import (
+	"errors"
+	"net/http"
+
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+)
+
+type Handler struct {
+	Usecase *usecase.Usecase
+}
+
+// GetTeams handles logic for returning the teams of the user with its role in each of them
+func (u Handler) GetTeams(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	teams, counted, err := u.Usecase.GetTeams(userID.String())
+
+	if counted == 0 {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    customErrors.ErrElementNotFound.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnTeamsResponse{
+		Length: counted,
+		Teams:  teams,
+	})
+}
+
+// CreateTeam handles logic for creating a team owned by the user
+func (u Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.CreateTeamRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	teamUUID, err := u.Usecase.CreateTeam(userID.String(), request.Name)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.CreateTeamResponse{
+		TeamUUID: teamUUID,
+	})
+}
+
+// DeleteTeam handles logic for deleting a team, owners only
+func (u Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	var request entities.DeleteTeamRequest
+
+	u.teamOperation(w, r, &request, func(userID string) error {
+		return u.Usecase.DeleteTeam(userID, request.TeamUUID)
+	})
+}
+
+// GetTeamMembers handles logic for returning the members of a team of the user
+func (u Handler) GetTeamMembers(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ReturnTeamMembersRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	members, counted, err := u.Usecase.GetTeamMembers(userID.String(), request.TeamUUID)
+
+	if errors.Is(err, customErrors.ErrElementNotFound) {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnTeamMembersResponse{
+		Length:  counted,
+		Members: members,
+	})
+}
+
+// AddTeamMember handles logic for adding a user to a team or changing its role, owners only
+func (u Handler) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	var request entities.AddTeamMemberRequest
+
+	u.teamOperation(w, r, &request, func(userID string) error {
+		return u.Usecase.AddTeamMember(userID, request.TeamUUID, request.Username, request.Role)
+	})
+}
+
+// RemoveTeamMember handles logic for removing a member from a team, or for leaving it
+func (u Handler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var request entities.RemoveTeamMemberRequest
+
+	u.teamOperation(w, r, &request, func(userID string) error {
+		return u.Usecase.RemoveTeamMember(userID, request.TeamUUID, request.UserUUID)
+	})
+}
+
+// ShareResource handles logic for sharing a resource of the user with a team, or making it personal again
+func (u Handler) ShareResource(w http.ResponseWriter, r *http.Request) {
+	var request entities.ShareResourceRequest
+
+	u.teamOperation(w, r, &request, func(userID string) error {
+		return u.Usecase.ShareResource(userID, request.Kind, request.ResourceUUID, request.TeamUUID)
+	})
+}
+
+// teamOperation decodes the request of an operation on a team and answers with its outcome
+func (u Handler) teamOperation(w http.ResponseWriter, r *http.Request, request any, operation func(userID string) error) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err = utils.ValidateJSON(request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	err = operation(userID.String())
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, entities.TeamOperationResponse{
+			Status: true,
+		})
+	case errors.Is(err, customErrors.ErrElementNotFound), errors.Is(err, customErrors.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+	case errors.Is(err, customErrors.ErrTeamPermission):
+		c.JSON(http.StatusForbidden, entities.UniformResponse{
+			StatusCode: http.StatusForbidden,
+			Details:    err.Error(),
+		})
+	case errors.Is(err, customErrors.ErrLastTeamOwner):
+		c.JSON(http.StatusConflict, entities.UniformResponse{
+			StatusCode: http.StatusConflict,
+			Details:    err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// nolint all
+package team_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+type TeamsTestSuite struct {
+	testsuite.RESTTestSuite
+}
+
+// Run All tests
+func TestTeams(t *testing.T) {
+	suite.Run(t, new(TeamsTestSuite))
+}
+
+func (s *TeamsTestSuite) Test_Teams() {
+	ownerToken := s.Login(s.UserFixture)
+	memberToken := s.Login(s.NormalUserFixture)
+
+	response, err := testsuite.APIRequest(http.MethodPost, testsuite.APITEAMS, ownerToken, nil, &entities.CreateTeamRequest{Name: "crackers"})
+	s.Require().NoError(err)
+	var team entities.CreateTeamResponse
+	s.Require().NoError(json.Unmarshal([]byte(response), &team))
+
+	addMember := func(role string) error {
+		_, err := testsuite.APIRequest(http.MethodPost, testsuite.APITEAMS+"/members", ownerToken, nil, &entities.AddTeamMemberRequest{
+			TeamUUID: team.TeamUUID, Username: s.NormalUserFixture.Username, Role: role,
+		})
+		return err
+	}
+	share := func(token, kind, resourceUUID, teamUUID string) error {
+		_, err := testsuite.APIRequest(http.MethodPost, testsuite.APITEAMS+"/share", token, nil, &entities.ShareResourceRequest{
+			Kind: kind, ResourceUUID: resourceUUID, TeamUUID: teamUUID,
+		})
+		return err
+	}
+	assign := func(handshakeUUID, clientUUID string) *entities.UpdateHandshakeTaskViaAPIResponse {
+		response, err := testsuite.APIRequest(http.MethodPost, testsuite.APIASSIGN, memberToken, nil, &entities.UpdateHandshakeTaskViaAPIRequest{
+			HandshakeUUID: handshakeUUID, AssignedClientUUID: clientUUID, HashcatOptions: "-a 0 wordlists/team.txt",
+		})
+		s.Require().NoError(err)
+
+		var assigned entities.UpdateHandshakeTaskViaAPIResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &assigned))
+		return &assigned
+	}
+
+	machineID := utils.GenerateToken(32)
+	clientUUID, err := s.Service.Usecase.CreateClient(s.UserFixture.UserUUID, machineID, "", "team client")
+	s.Require().NoError(err)
+	s.Require().NoError(s.Service.Usecase.ApproveClient(s.UserFixture.UserUUID, clientUUID, true))
+
+	handshakeUUID, err := s.Service.Usecase.CreateHandshake(s.NormalUserFixture.UserUUID, "team", "XX:XX:XX:XX:XX:XX", constants.NothingStatus, utils.StringToBase64String("team.pcap"))
+	s.Require().NoError(err)
+
+	s.Run("Teams of others not disclosed", func() {
+		_, err := testsuite.APIRequest(http.MethodGet, testsuite.APITEAMS+"/members", memberToken, url.Values{"uuid": {team.TeamUUID}}, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+
+		s.Require().ErrorContains(share(memberToken, constants.SharedHandshake, handshakeUUID, team.TeamUUID), fmt.Sprintf("%d", http.StatusNotFound))
+	})
+
+	s.Run("Viewers see the team resources without using them", func() {
+		s.Require().NoError(addMember(constants.TeamViewer))
+		s.Require().NoError(share(ownerToken, constants.SharedClient, clientUUID, team.TeamUUID))
+
+		response, err := testsuite.APIRequest(http.MethodGet, testsuite.APITEAMS, memberToken, nil, nil)
+		s.Require().NoError(err)
+		var teams entities.ReturnTeamsResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &teams))
+		s.Require().Equal(1, teams.Length)
+		s.Require().Equal(constants.TeamViewer, teams.Teams[0].Role)
+
+		response, err = testsuite.APIRequest(http.MethodGet, testsuite.APICLIENTS, memberToken, url.Values{"page": {"1"}}, nil)
+		s.Require().NoError(err)
+		s.Require().Contains(response, clientUUID)
+
+		s.Require().ErrorContains(share(memberToken, constants.SharedHandshake, handshakeUUID, team.TeamUUID), fmt.Sprintf("%d", http.StatusForbidden))
+		s.Require().False(assign(handshakeUUID, clientUUID).Success)
+
+		// only the owners manage the members
+		_, err = testsuite.APIRequest(http.MethodDelete, testsuite.APITEAMS+"/members", memberToken, nil, &entities.RemoveTeamMemberRequest{
+			TeamUUID: team.TeamUUID, UserUUID: s.UserFixture.UserUUID,
+		})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusForbidden))
+	})
+
+	s.Run("Members assign tasks to the team clients", func() {
+		s.Require().NoError(addMember(constants.TeamMember))
+		s.Require().NoError(share(memberToken, constants.SharedHandshake, handshakeUUID, team.TeamUUID))
+
+		assigned := assign(handshakeUUID, clientUUID)
+		s.Require().True(assigned.Success, assigned.Reason)
+		s.Require().Equal(s.NormalUserFixture.UserUUID, assigned.Handshake.UserUUID)
+
+		// the owner of the client reports the progress of the task
+		_, err := s.Service.Usecase.UpdateClientTask(s.UserFixture.UserUUID, handshakeUUID, clientUUID, constants.WorkingStatus, "-a 0 wordlists/team.txt", "", "")
+		s.Require().NoError(err)
+	})
+
+	s.Run("Wordlists shared with the team", func() {
+		response, err := testsuite.APIRequest(http.MethodPost, testsuite.APIWORDLISTS, memberToken, nil, &entities.CreateWordlistRequest{
+			Name: "team", Path: "wordlists/team.txt", TeamUUID: team.TeamUUID,
+		})
+		s.Require().NoError(err)
+		var created entities.CreateWordlistResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &created))
+
+		response, err = testsuite.APIRequest(http.MethodGet, testsuite.APIWORDLISTS, ownerToken, nil, nil)
+		s.Require().NoError(err)
+		s.Require().Contains(response, created.WordlistUUID)
+
+		// owners of the team delete them too
+		_, err = testsuite.APIRequest(http.MethodDelete, testsuite.APIWORDLISTS, ownerToken, nil, &entities.DeleteWordlistRequest{WordlistUUID: created.WordlistUUID})
+		s.Require().NoError(err)
+	})
+
+	s.Run("Team keeps an owner", func() {
+		_, err := testsuite.APIRequest(http.MethodDelete, testsuite.APITEAMS+"/members", ownerToken, nil, &entities.RemoveTeamMemberRequest{
+			TeamUUID: team.TeamUUID, UserUUID: s.UserFixture.UserUUID,
+		})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusConflict))
+	})
+
+	s.Run("Resources back to their owners when the team is deleted", func() {
+		_, err := testsuite.APIRequest(http.MethodDelete, testsuite.APITEAMS, memberToken, nil, &entities.DeleteTeamRequest{TeamUUID: team.TeamUUID})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusForbidden))
+
+		// members leave without knowing their UUID
+		_, err = testsuite.APIRequest(http.MethodDelete, testsuite.APITEAMS+"/members", memberToken, nil, &entities.RemoveTeamMemberRequest{TeamUUID: team.TeamUUID})
+		s.Require().NoError(err)
+
+		_, err = testsuite.APIRequest(http.MethodDelete, testsuite.APITEAMS, ownerToken, nil, &entities.DeleteTeamRequest{TeamUUID: team.TeamUUID})
+		s.Require().NoError(err)
+
+		client, err := s.Service.Usecase.GetClientInfo(s.UserFixture.UserUUID, machineID)
+		s.Require().NoError(err)
+		s.Require().Nil(client.TeamUUID)
+	})
+}
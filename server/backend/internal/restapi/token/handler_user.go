@@ -0,0 +1,156 @@
+package token
+
+import (
+	"errors"
+	"net/http"
+
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+)
+
+type Handler struct {
+	Usecase *usecase.Usecase
+}
+
+// GetPersonalAccessTokens handles logic for returning the personal access tokens of the user, without the tokens themselves
+func (u Handler) GetPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.ReturnPersonalAccessTokensRequest
+
+	if err = utils.ValidateQueryParameters(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	tokens, counted, err := u.Usecase.GetPersonalAccessTokens(userID.String(), request.Page)
+
+	if counted == 0 {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    customErrors.ErrElementNotFound.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.ReturnPersonalAccessTokensResponse{
+		Length: counted,
+		Tokens: tokens,
+	})
+}
+
+// CreatePersonalAccessToken handles logic for creating a personal access token, the token is in the response only
+func (u Handler) CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.CreatePersonalAccessTokenRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	created, err := u.Usecase.CreatePersonalAccessToken(userID.String(), request.Name, request.Scope, request.ExpiresInDays)
+
+	if errors.Is(err, customErrors.ErrPersonalAccessTokenScope) {
+		c.JSON(http.StatusForbidden, entities.UniformResponse{
+			StatusCode: http.StatusForbidden,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, created)
+}
+
+// RevokePersonalAccessToken handles logic for revoking a personal access token of the user, it is refused at once
+func (u Handler) RevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	userID, err := u.Usecase.GetUserIDFromToken(r)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	var request entities.RevokePersonalAccessTokenRequest
+
+	if err = utils.ValidateJSON(&request, r); err != nil {
+		c.JSON(http.StatusBadRequest, entities.UniformResponse{
+			StatusCode: http.StatusBadRequest,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	err = u.Usecase.RevokePersonalAccessToken(userID.String(), request.TokenUUID)
+
+	if errors.Is(err, customErrors.ErrElementNotFound) {
+		c.JSON(http.StatusNotFound, entities.UniformResponse{
+			StatusCode: http.StatusNotFound,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, entities.UniformResponse{
+			StatusCode: http.StatusInternalServerError,
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entities.RevokePersonalAccessTokenResponse{
+		Status: true,
+	})
+}
@@ -0,0 +1,151 @@
+// nolint all
+package token_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/testsuite"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type PersonalAccessTokensTestSuite struct {
+	testsuite.RESTTestSuite
+}
+
+// Run All tests
+func TestPersonalAccessTokens(t *testing.T) {
+	suite.Run(t, new(PersonalAccessTokensTestSuite))
+}
+
+func (s *PersonalAccessTokensTestSuite) Test_PersonalAccessTokens() {
+	adminToken := s.Login(s.UserFixture)
+	userToken := s.Login(s.NormalUserFixture)
+
+	create := func(token, scope string) (*entities.CreatePersonalAccessTokenResponse, error) {
+		response, err := testsuite.APIRequest(http.MethodPost, testsuite.APITOKENS, token, nil, &entities.CreatePersonalAccessTokenRequest{
+			Name:          "script " + scope,
+			Scope:         scope,
+			ExpiresInDays: 30,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var created entities.CreatePersonalAccessTokenResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &created))
+		return &created, nil
+	}
+	page := url.Values{"page": {"1"}}
+
+	// listed returns the token with tokenUUID as listed on the tokens page
+	listed := func(token, tokenUUID string) *entities.PersonalAccessToken {
+		response, err := testsuite.APIRequest(http.MethodGet, testsuite.APITOKENS, token, page, nil)
+		s.Require().NoError(err)
+		s.Require().NotContains(response, "TokenHash")
+
+		var tokens entities.ReturnPersonalAccessTokensResponse
+		s.Require().NoError(json.Unmarshal([]byte(response), &tokens))
+
+		for _, item := range tokens.Tokens {
+			if item.UUID == tokenUUID {
+				return item
+			}
+		}
+		s.FailNow("token not listed")
+		return nil
+	}
+
+	s.Run("Read token accepted by the GET routes only", func() {
+		created, err := create(userToken, constants.ScopeRead)
+		s.Require().NoError(err)
+		s.Require().True(strings.HasPrefix(created.Token, constants.PersonalAccessTokenPrefix))
+
+		_, err = testsuite.APIRequest(http.MethodGet, testsuite.APIVERIFY, created.Token, nil, nil)
+		s.Require().NoError(err)
+
+		_, err = create(created.Token, constants.ScopeRead)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusForbidden))
+	})
+
+	s.Run("Token listed without its hash and with the time it was last used", func() {
+		created, err := create(userToken, constants.ScopeFE)
+		s.Require().NoError(err)
+
+		_, err = testsuite.APIRequest(http.MethodGet, testsuite.APIVERIFY, created.Token, nil, nil)
+		s.Require().NoError(err)
+
+		used := listed(created.Token, created.TokenUUID)
+		s.Require().Equal(constants.ScopeFE, used.Scope)
+		s.Require().NotNil(used.LastUsedDate)
+
+		// the time is rounded to the second, the same use would be recorded a second later
+		time.Sleep(time.Second)
+		_, err = testsuite.APIRequest(http.MethodGet, testsuite.APIVERIFY, created.Token, nil, nil)
+		s.Require().NoError(err)
+		s.Require().Equal(*used.LastUsedDate, *listed(created.Token, created.TokenUUID).LastUsedDate, "uses recorded once a minute at most")
+	})
+
+	s.Run("Logout revokes the token", func() {
+		created, err := create(userToken, constants.ScopeFE)
+		s.Require().NoError(err)
+
+		_, err = testsuite.HTTPRequest(http.MethodGet, testsuite.APILOGOUT, map[string]string{
+			"Authorization": "Bearer " + created.Token,
+		}, url.Values{}, nil, 10*time.Second)
+		s.Require().NoError(err)
+
+		_, err = testsuite.APIRequest(http.MethodGet, testsuite.APIVERIFY, created.Token, nil, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnauthorized))
+	})
+
+	s.Run("Admin tokens for administrators only", func() {
+		_, err := create(userToken, constants.ScopeAdmin)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusForbidden))
+
+		feToken, err := create(adminToken, constants.ScopeFE)
+		s.Require().NoError(err)
+		_, err = testsuite.APIRequest(http.MethodGet, testsuite.APIADMIN+"/users", feToken.Token, page, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusForbidden))
+
+		adminPAT, err := create(adminToken, constants.ScopeAdmin)
+		s.Require().NoError(err)
+		_, err = testsuite.APIRequest(http.MethodGet, testsuite.APIADMIN+"/users", adminPAT.Token, page, nil)
+		s.Require().NoError(err)
+	})
+
+	s.Run("Token refused by the other channels", func() {
+		created, err := create(userToken, constants.ScopeFE)
+		s.Require().NoError(err)
+
+		s.Require().ErrorIs(s.Service.Usecase.RequireScope(created.Token, constants.ScopeClientTasks), customErrors.ErrTokenAudience)
+	})
+
+	s.Run("Revoked token refused", func() {
+		created, err := create(userToken, constants.ScopeFE)
+		s.Require().NoError(err)
+
+		// tokens of other users cannot be revoked
+		_, err = testsuite.APIRequest(http.MethodDelete, testsuite.APITOKENS, adminToken, nil, &entities.RevokePersonalAccessTokenRequest{TokenUUID: created.TokenUUID})
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusNotFound))
+
+		_, err = testsuite.APIRequest(http.MethodDelete, testsuite.APITOKENS, userToken, nil, &entities.RevokePersonalAccessTokenRequest{TokenUUID: created.TokenUUID})
+		s.Require().NoError(err)
+
+		_, err = testsuite.APIRequest(http.MethodGet, testsuite.APIVERIFY, created.Token, nil, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnauthorized))
+	})
+
+	s.Run("Unknown token refused", func() {
+		_, err := testsuite.APIRequest(http.MethodGet, testsuite.APIVERIFY, constants.PersonalAccessTokenPrefix+utils.GenerateToken(64), nil, nil)
+		s.Require().ErrorContains(err, fmt.Sprintf("%d", http.StatusUnauthorized))
+	})
+}
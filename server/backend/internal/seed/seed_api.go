@@ -52,7 +52,7 @@ func loadUsers(repo *repository.Repository) error {
 
 			randomHash := fmt.Sprintf("%x", md5.Sum([]byte(utils.GenerateToken(10)))) // #nosec G401 disable weak hash alert, it is not used for crypto stuff
 
-			_, err = repo.CreateClient(user.User.UserUUID, randomHash, "127.0.0.1", "TestAdmin")
+			_, err = repo.CreateClient(user.User.UserUUID, randomHash, "127.0.0.1", "TestAdmin", utils.EnrollmentCode(utils.GenerateToken(8)))
 
 			if err != nil {
 				e := fmt.Errorf("failed to seed client table: %v", err)
@@ -14,6 +14,17 @@ import (
 )
 
 var APILOGIN = fmt.Sprintf("http://%s:%s/v1/auth", constants.ServerHost, constants.ServerPort)
+var APIREFRESH = fmt.Sprintf("http://%s:%s/v1/auth/refresh", constants.ServerHost, constants.ServerPort)
+var APILOGOUT = fmt.Sprintf("http://%s:%s/v1/logout", constants.ServerHost, constants.ServerPort)
+var APIVERIFY = fmt.Sprintf("http://%s:%s/v1/verify", constants.ServerHost, constants.ServerPort)
+var APIREGISTER = fmt.Sprintf("http://%s:%s/v1/register", constants.ServerHost, constants.ServerPort)
+var APITOKENS = fmt.Sprintf("http://%s:%s/v1/tokens", constants.ServerHost, constants.ServerPort)
+var APIADMIN = fmt.Sprintf("http://%s:%s/v1/admin", constants.ServerHost, constants.ServerPort)
+var APITEAMS = fmt.Sprintf("http://%s:%s/v1/teams", constants.ServerHost, constants.ServerPort)
+var APIWORDLISTS = fmt.Sprintf("http://%s:%s/v1/wordlists", constants.ServerHost, constants.ServerPort)
+var APICLIENTS = fmt.Sprintf("http://%s:%s/v1/clients", constants.ServerHost, constants.ServerPort)
+var APIHANDSHAKES = fmt.Sprintf("http://%s:%s/v1/handshakes", constants.ServerHost, constants.ServerPort)
+var APIASSIGN = fmt.Sprintf("http://%s:%s/v1/assign", constants.ServerHost, constants.ServerPort)
 
 // HTTPRequest performs an HTTP request with the specified method, URL, headers, query parameters, and body.
 // It returns the response body as a string and an error if any.
@@ -65,26 +76,52 @@ func HTTPRequest(method, urlStr string, headers map[string]string, queryParams u
 	return string(bodyBytes), nil
 }
 
+// APIRequest calls the API authenticated by token, body is sent as JSON when not nil
+func APIRequest(method, urlStr, token string, queryParams url.Values, body any) (string, error) {
+	var marshaled []byte
+	if body != nil {
+		var err error
+		if marshaled, err = json.Marshal(body); err != nil {
+			return "", err
+		}
+	}
+
+	return HTTPRequest(method, urlStr, map[string]string{
+		"Authorization": "Bearer " + token,
+	}, queryParams, marshaled, 10*time.Second)
+}
+
 func AuthAPI(auth entities.AuthRequest) (string, error) {
-	marshaled, err := json.Marshal(&auth)
+	session, err := SessionAPI(auth)
 
 	if err != nil {
 		return "", err
 	}
 
+	return session.Details, nil
+}
+
+// SessionAPI logs in returning the access token with the refresh token
+func SessionAPI(auth entities.AuthRequest) (*entities.AuthResponse, error) {
+	marshaled, err := json.Marshal(&auth)
+
+	if err != nil {
+		return nil, err
+	}
+
 	response, err := HTTPRequest("POST", APILOGIN, map[string]string{}, url.Values{}, marshaled, 10*time.Second)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var authResponse entities.UniformResponse
+	var authResponse entities.AuthResponse
 
 	err = json.Unmarshal([]byte(response), &authResponse)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return authResponse.Details, nil
+	return &authResponse, nil
 }
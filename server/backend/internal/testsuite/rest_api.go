@@ -0,0 +1,112 @@
+package testsuite
+
+import (
+	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/infrastructure"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/repository"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/restapi"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/seed"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RESTTestSuite runs the REST API alone, for the tests of its handlers
+type RESTTestSuite struct {
+	suite.Suite
+	Service      *restapi.ServiceHandler // contains Usecase as well
+	DatabaseUser *infrastructure.Database
+	DatabaseCert *infrastructure.Database
+
+	UserFixture       *entities.User // administrator
+	NormalUserFixture *entities.User
+}
+
+func (s *RESTTestSuite) SetupSuite() {
+	dbConnUser, err := infrastructure.NewDatabaseConnection(constants.DBUser, constants.DBPassword, constants.DBHost, constants.DBPort, constants.DBName)
+	s.Require().NoError(err)
+	s.DatabaseUser = dbConnUser
+
+	dbConnCerts, err := infrastructure.NewDatabaseConnection(constants.DBCertUser, constants.DBCertPass, constants.DBHost, constants.DBPort, constants.DBCert)
+	s.Require().NoError(err)
+	s.DatabaseCert = dbConnCerts
+
+	service, err := restapi.NewServiceHandler(dbConnUser, dbConnCerts) // run seeds internally
+	s.Require().NoError(err)
+	s.Service = &service
+
+	// the approval of the clients signs their certs
+	s.Require().NoError(s.Service.Usecase.CreateServerCerts())
+
+	s.Require().NotNil(seed.AdminSeed.User)
+	s.UserFixture = seed.AdminSeed.User
+
+	s.Require().NotNil(seed.NormalUserSeed.User)
+	s.NormalUserFixture = seed.NormalUserSeed.User
+
+	gorillaMux := mux.NewRouter()
+	service.InitRoutes(gorillaMux)
+
+	srv := &http.Server{
+		Addr:              constants.ServerHost + ":" + constants.ServerPort,
+		Handler:           gorillaMux,
+		ReadHeaderTimeout: 3 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+
+	go func() {
+		log.Printf("[REST-API] Server running on %s:%s", constants.ServerHost, constants.ServerPort)
+		restErr := srv.ListenAndServe()
+		s.Require().NoError(restErr)
+	}()
+
+	// wait for the server to listen
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", srv.Addr, time.Second)
+		if err == nil {
+			s.Require().NoError(conn.Close())
+			return
+		}
+		s.Require().True(time.Now().Before(deadline), "REST API didn't start within 10 seconds")
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Login returns an access token of the FE for user
+func (s *RESTTestSuite) Login(user *entities.User) string {
+	token, err := AuthAPI(entities.AuthRequest{
+		Username: user.Username,
+		Password: user.Password,
+	})
+	s.Require().NoError(err)
+
+	return token
+}
+
+// TearDownAllSuite implements suite.SetupTestSuite and is called after each suite
+func (s *RESTTestSuite) TearDownSuite() {
+	// restore DB as its original state
+	err := s.DatabaseUser.CleanDB([]string{entities.UserTableName})
+	s.Require().NoError(err)
+
+	err = s.DatabaseCert.CleanDB([]string{entities.CertTableName})
+	s.Require().NoError(err)
+
+	rr, err := repository.NewRepository(s.DatabaseUser, s.DatabaseCert)
+	s.Require().NoError(err)
+
+	err = seed.LoadUsers(rr)
+	s.Require().NoError(err)
+
+	err = s.DatabaseUser.CloseDatabase()
+	s.Require().NoError(err)
+
+	err = s.DatabaseCert.CloseDatabase()
+	s.Require().NoError(err)
+}
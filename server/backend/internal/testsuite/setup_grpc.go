@@ -102,6 +102,8 @@ func (s *GRPCTestSuite) startServer() {
 			ServerCert:          serverCert,
 			ServerKey:           serverKey,
 			ClientConfigStorage: encryption.NewClientCertStore(),
+			EnsureNotRevoked:    s.Service.Usecase.EnsureNotRevoked,
+			AuthorizeToken:      s.Service.Usecase.RequireScope,
 		})
 	}()
 
@@ -1,6 +1,8 @@
 package testsuite
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/infrastructure"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/raspberrypi"
@@ -18,6 +20,8 @@ type TCPServerSuite struct {
 	Service      *restapi.ServiceHandler // contains Usecase as well for mocking
 	DatabaseUser *infrastructure.Database
 	DatabaseCert *infrastructure.Database
+
+	MutualTLSAddress string // a second server requiring device certificates, listening on a random port
 }
 
 func (s *TCPServerSuite) SetupSuite() {
@@ -56,7 +60,7 @@ func (s *TCPServerSuite) SetupSuite() {
 }
 
 func (s *TCPServerSuite) startServer(service *restapi.ServiceHandler) {
-	server, err := raspberrypi.NewTCPServer(service, constants.TCPAddress, constants.TCPPort)
+	server, err := raspberrypi.NewTCPServer(service, constants.TCPAddress, constants.TCPPort, raspberrypi.TLSDisabled)
 	s.Require().NoError(err)
 
 	go func() {
@@ -64,6 +68,17 @@ func (s *TCPServerSuite) startServer(service *restapi.ServiceHandler) {
 		s.Require().NoError(err)
 	}()
 
+	s.Require().NoError(service.Usecase.CreateServerCerts())
+
+	tlsServer, err := raspberrypi.NewTCPServer(service, constants.TCPAddress, "0", raspberrypi.TLSMutual)
+	s.Require().NoError(err)
+	s.MutualTLSAddress = tlsServer.Addr().String()
+
+	go func() {
+		errTLS := tlsServer.RunTCPServer()
+		s.Require().NoError(errTLS)
+	}()
+
 	// Init client
 	time.Sleep(3 * time.Second) // give the time to start the server
 }
@@ -74,3 +89,27 @@ func (s *TCPServerSuite) Client() net.Conn {
 
 	return conn
 }
+
+// TLSClient connects to the mutual TLS server verifying its certificate, certificate can be nil
+func (s *TCPServerSuite) TLSClient(certificate *tls.Certificate) *tls.Conn {
+	caCert, _, _, _, err := s.Service.Usecase.GetServerCerts()
+	s.Require().NoError(err)
+
+	certPool := x509.NewCertPool()
+	s.Require().True(certPool.AppendCertsFromPEM(caCert))
+
+	config := &tls.Config{
+		RootCAs:    certPool,
+		ServerName: constants.CertCommonName,
+		MinVersion: tls.VersionTLS13,
+	}
+
+	if certificate != nil {
+		config.Certificates = []tls.Certificate{*certificate}
+	}
+
+	conn, err := tls.Dial("tcp", s.MutualTLSAddress, config)
+	s.Require().NoError(err)
+
+	return conn
+}
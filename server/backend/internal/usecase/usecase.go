@@ -1,31 +1,57 @@
 package usecase
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/utils"
+	"io"
 	"math/big"
 	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Virgula0/progetto-dp/server/backend/internal/constants"
 	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
 	"github.com/Virgula0/progetto-dp/server/backend/internal/repository"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/wpaparser"
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 )
 
 type Usecase struct {
 	repo *repository.Repository
-}
 
-var blacklistedTokens = make(map[string]bool)
+	revocationMutex     sync.RWMutex
+	revocationListeners []func(revocation *entities.Revocation)
+
+	// signingKeys secrets of the JWT signing keys by kid, the retired ones included. currentKey signs the new tokens.
+	// signingKeysReloadDate when the keys were last loaded again for a token signed with an unknown key
+	signingKeysMutex      sync.RWMutex
+	signingKeys           map[string][]byte
+	currentKey            *entities.SigningKey
+	signingKeysReloadDate time.Time
+}
 
 // NewUsecase Dep. injection for usecase. Injecting db -> repo -> usecase
 func NewUsecase(repo *repository.Repository) *Usecase {
@@ -161,20 +187,108 @@ func (uc *Usecase) SignCert(caCertPEM, caKeyPEM []byte, commonNameClientUUID str
 	return certPEM, keyPEM, nil
 }
 
-func (uc *Usecase) GetDataFromToken(tokenInput string) (jwt.MapClaims, error) {
-	claims := jwt.MapClaims{}
+// GetDataFromToken returns the claims of a user token granted scope, device tokens are refused
+func (uc *Usecase) GetDataFromToken(tokenInput, scope string) (jwt.MapClaims, error) {
+	claims, err := uc.parseToken(tokenInput)
+	if err != nil {
+		return nil, err
+	}
 
-	token, err := jwt.ParseWithClaims(tokenInput, claims, func(token *jwt.Token) (any, error) {
-		return constants.JwtSecretKey, nil
-	})
+	if claims[constants.RoleString] == string(constants.DEVICE) {
+		return nil, customErrors.ErrDeviceTokenNotAllowed
+	}
+
+	if err = uc.authorizeScope(claims, scope); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// RequireScope refuses tokens not issued for the audience of scope or not granted it
+func (uc *Usecase) RequireScope(tokenInput, scope string) error {
+	claims, err := uc.parseToken(tokenInput)
+	if err != nil {
+		return err
+	}
+
+	return uc.authorizeScope(claims, scope)
+}
+
+// GetDataFromDeviceToken returns the claims of a device token issued to the daemon of machineID, user tokens are refused.
+// A device token is valid only while the credential used for obtaining it has not been rotated or revoked
+func (uc *Usecase) GetDataFromDeviceToken(tokenInput, machineID string) (jwt.MapClaims, error) {
+	claims, err := uc.GetDataFromDaemonToken(tokenInput, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims[constants.RoleString] != string(constants.DEVICE) {
+		return nil, customErrors.ErrDeviceTokenRequired
+	}
+
+	return claims, nil
+}
+
+// GetDataFromDaemonToken as GetDataFromDeviceToken, but the user tokens obtained by the daemons with LOGIN are accepted too.
+// Only enrolling a certificate and the uploads of the legacy daemons take them, see TCPAllowPlaintext
+func (uc *Usecase) GetDataFromDaemonToken(tokenInput, machineID string) (jwt.MapClaims, error) {
+	claims, err := uc.parseToken(tokenInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = uc.authorizeScope(claims, constants.ScopeDaemonUpload); err != nil {
+		return nil, err
+	}
+
+	// device tokens act for the device they were issued to only, user tokens for the device named by the request
+	device := claims[constants.RoleString] == string(constants.DEVICE)
+	if device && claims[constants.MachineIDKey] != machineID {
+		return nil, customErrors.ErrDeviceTokenMismatch
+	}
 
-	if err != nil || !token.Valid {
+	if err = uc.ensureNotRevoked(constants.RevokedRaspberryPI, machineID); err != nil {
 		return nil, err
 	}
 
+	if !device {
+		return claims, nil
+	}
+
+	rsp, err := uc.repo.GetRaspberryPIByMachineID(machineID)
+	if err != nil || rsp.UserUUID != claims[constants.UserIDKey] || rsp.CredentialHash == nil ||
+		claims[constants.CredentialKey] != credentialFingerprint(*rsp.CredentialHash) {
+		return nil, customErrors.ErrDeviceCredentialInvalid
+	}
+
 	return claims, nil
 }
 
+// GetDeviceFromToken returns the user and the machine ID of the device token of the request, user tokens are refused
+func (uc *Usecase) GetDeviceFromToken(r *http.Request) (userUUID, machineID string, err error) {
+	tokenInput, ok := r.Context().Value(constants.TokenConstant).(string)
+	if !ok {
+		return "", "", customErrors.ErrUnableToGetDataFromToken
+	}
+
+	claims, err := uc.parseToken(tokenInput)
+	if err != nil {
+		return "", "", customErrors.ErrUnableToGetDataFromToken
+	}
+
+	machineID, _ = claims[constants.MachineIDKey].(string)
+	if claims[constants.RoleString] != string(constants.DEVICE) || machineID == "" {
+		return "", "", customErrors.ErrDeviceTokenRequired
+	}
+
+	if claims, err = uc.GetDataFromDeviceToken(tokenInput, machineID); err != nil {
+		return "", "", err
+	}
+
+	return claims[constants.UserIDKey].(string), machineID, nil
+}
+
 func (uc *Usecase) GetUserIDFromToken(r *http.Request) (uuid.UUID, error) {
 	ctx := r.Context()
 	token, ok := ctx.Value(constants.TokenConstant).(string)
@@ -183,7 +297,8 @@ func (uc *Usecase) GetUserIDFromToken(r *http.Request) (uuid.UUID, error) {
 		return uuid.UUID{}, customErrors.ErrUnableToGetDataFromToken
 	}
 
-	data, err := uc.GetDataFromToken(token)
+	// the middleware already required the scope of the route, every FE token is granted read
+	data, err := uc.GetDataFromToken(token, constants.ScopeRead)
 
 	if err != nil {
 		return uuid.UUID{}, err
@@ -192,138 +307,2282 @@ func (uc *Usecase) GetUserIDFromToken(r *http.Request) (uuid.UUID, error) {
 	return uuid.Parse(data[constants.UserIDKey].(string))
 }
 
-func (uc *Usecase) InvalidateToken(token string) {
-	blacklistedTokens[token] = true
-}
+// InvalidateToken revokes the access token until it expires and ends its session, so that its refresh token is refused too.
+// Personal access tokens have neither an expiration nor a session, they are revoked as from the tokens page
+func (uc *Usecase) InvalidateToken(tokenInput string) error {
+	claims, err := uc.parseToken(tokenInput)
+	if err != nil {
+		return err
+	}
+
+	tokenID, _ := claims[constants.TokenIDKey].(string)
+	userID, _ := claims[constants.UserIDKey].(string)
 
-func (uc *Usecase) ValidateToken(tokenInput string) (bool, error) {
-	if _, ok := blacklistedTokens[tokenInput]; ok {
-		return false, nil
+	if strings.HasPrefix(tokenInput, constants.PersonalAccessTokenPrefix) {
+		return uc.repo.DeletePersonalAccessToken(userID, tokenID)
 	}
 
-	token, err := jwt.ParseWithClaims(tokenInput, jwt.MapClaims{}, func(token *jwt.Token) (any, error) {
-		return constants.JwtSecretKey, nil
-	})
+	expires, err := claims.GetExpirationTime()
+	if err != nil || expires == nil {
+		return customErrors.ErrUnableToGetDataFromToken
+	}
+
+	if err = uc.repo.RevokeToken(tokenID, expires.Time); err != nil {
+		return err
+	}
+
+	sessionID, _ := claims[constants.SessionKey].(string)
+	if sessionID == "" {
+		return nil
+	}
+
+	return uc.repo.DeleteSession(userID, sessionID)
+}
 
-	if err != nil || !token.Valid {
+// ValidateToken whether the user token is granted scope
+func (uc *Usecase) ValidateToken(tokenInput, scope string) (bool, error) {
+	// device tokens are valid only for the daemon routes, see GetDeviceFromToken
+	if _, err := uc.GetDataFromToken(tokenInput, scope); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
-func (uc *Usecase) CreateAuthToken(userID, role string) (string, error) {
-	// Create the JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID": userID,
-		"role":   role,
-		"uuid":   (uuid.New()).String(),                 // jwtToken uuid
-		"exp":    time.Now().Add(time.Hour * 12).Unix(), // Token expires after 12 hours
+// CreateAuthToken creates a short-lived access token of the session for audience, see CreateSession. sessionID is
+// empty for the tokens without a refresh token, as the ones used by the daemons for enrolling
+func (uc *Usecase) CreateAuthToken(userID, role, audience, sessionID string) (string, error) {
+	return uc.signToken(jwt.MapClaims{
+		constants.UserIDKey:   userID,
+		constants.RoleString:  role,
+		constants.SessionKey:  sessionID,
+		constants.AudienceKey: audience,
+		constants.ScopesKey:   grantedScopes(audience, role),
+		constants.TokenIDKey:  (uuid.New()).String(),
+		"exp":                 time.Now().Add(constants.AccessTokenDuration).Unix(),
 	})
-
-	// Sign the token with a secret key
-	return token.SignedString(constants.JwtSecretKey)
 }
 
-func (uc *Usecase) GetUserByUsername(username string) (*entities.User, *entities.Role, error) {
-	return uc.repo.GetUserByUsername(username)
+// CreateDeviceToken creates a short-lived token for an enrolled raspberry pi. The token carries a fingerprint
+// of the credential hash, so rotating or revoking the credential invalidates it immediately
+func (uc *Usecase) CreateDeviceToken(userID, machineID, credentialHash string) (string, error) {
+	return uc.signToken(jwt.MapClaims{
+		constants.UserIDKey:     userID,
+		constants.RoleString:    string(constants.DEVICE),
+		constants.MachineIDKey:  machineID,
+		constants.CredentialKey: credentialFingerprint(credentialHash),
+		constants.AudienceKey:   constants.AudienceDaemon,
+		constants.ScopesKey:     grantedScopes(constants.AudienceDaemon, string(constants.DEVICE)),
+		constants.TokenIDKey:    (uuid.New()).String(),
+		"exp":                   time.Now().Add(constants.DeviceTokenDuration).Unix(),
+	})
 }
 
-func (uc *Usecase) GetUserByUserID(userUUID string) (*entities.User, error) {
-	return uc.repo.GetUserByUserID(userUUID)
+// CreateSession logs the user in on the channel of audience, returning an access token and the refresh token for renewing it
+func (uc *Usecase) CreateSession(userID, role, audience string) (*entities.AuthResponse, error) {
+	return uc.issueSessionTokens(userID, role, audience, uuid.New().String())
 }
 
-func (uc *Usecase) CreateUser(userEntity *entities.User, role constants.Role) error {
-	return uc.repo.CreateUser(userEntity, role)
+// RefreshSession exchanges a refresh token issued for audience for a new access token. The refresh token can be used
+// once, a new one of the same session is returned with the access token. The requests sent together with the expired
+// access token race for the refresh, so the token is accepted again for RefreshTokenReuseWindow after its first use.
+// The scopes follow the current role of the user
+func (uc *Usecase) RefreshSession(refreshToken, audience string) (*entities.AuthResponse, error) {
+	token, role, err := uc.repo.ConsumeRefreshToken(hashCredential(refreshToken), audience,
+		time.Now().Add(-constants.RefreshTokenReuseWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.issueSessionTokens(token.UserUUID, role.RoleString, audience, token.SessionUUID)
 }
 
-func (uc *Usecase) UpdateUserPassword(userUUID, password string) error {
-	return uc.repo.UpdateUserPassword(userUUID, password)
+func (uc *Usecase) issueSessionTokens(userID, role, audience, sessionID string) (*entities.AuthResponse, error) {
+	accessToken, err := uc.CreateAuthToken(userID, role, audience, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := utils.GenerateToken(64)
+	err = uc.repo.CreateRefreshToken(userID, sessionID, audience, hashCredential(refreshToken), time.Now().Add(constants.RefreshTokenDuration))
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.AuthResponse{
+		Details:      accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(constants.AccessTokenDuration.Seconds()),
+	}, nil
 }
 
-func (uc *Usecase) GetClientsInstalledByUserID(userUUID string, offset uint) ([]*entities.Client, int, error) {
-	return uc.repo.GetClientsInstalledByUserID(userUUID, offset)
+// scopeAudiences the audience each scope belongs to
+var scopeAudiences = map[string]string{
+	constants.ScopeFE:           constants.AudienceFE,
+	constants.ScopeRead:         constants.AudienceFE,
+	constants.ScopeAdmin:        constants.AudienceFE,
+	constants.ScopeClientTasks:  constants.AudienceClient,
+	constants.ScopeDaemonEnroll: constants.AudienceDaemon,
+	constants.ScopeDaemonUpload: constants.AudienceDaemon,
 }
 
-func (uc *Usecase) GetClientsInstalled() (clients []*entities.Client, length int, e error) {
-	return uc.repo.GetClientsInstalled()
+// grantedScopes the scopes of the tokens issued for audience. Only administrators are granted admin, device tokens
+// can only upload
+func grantedScopes(audience, role string) []string {
+	switch audience {
+	case constants.AudienceFE:
+		if role == string(constants.ADMIN) {
+			return []string{constants.ScopeFE, constants.ScopeRead, constants.ScopeAdmin}
+		}
+		return []string{constants.ScopeFE, constants.ScopeRead}
+	case constants.AudienceClient:
+		return []string{constants.ScopeClientTasks}
+	case constants.AudienceDaemon:
+		if role == string(constants.DEVICE) {
+			return []string{constants.ScopeDaemonUpload}
+		}
+		return []string{constants.ScopeDaemonEnroll, constants.ScopeDaemonUpload}
+	}
+	return nil
 }
 
-func (uc *Usecase) UpdateCerts(client *entities.Client) error {
-	caCert, caKey, _, _, err := uc.GetServerCerts()
+// authorizeScope refuses the claims of a token issued for another channel or not granted scope. The admin scope
+// also requires the user to be an administrator still, the role can change before the token expires
+func (uc *Usecase) authorizeScope(claims jwt.MapClaims, scope string) error {
+	audiences, err := claims.GetAudience()
+	if err != nil || !slices.Contains(audiences, scopeAudiences[scope]) {
+		return customErrors.ErrTokenAudience
+	}
+
+	granted, _ := claims[constants.ScopesKey].([]any)
+	if !slices.Contains(granted, any(scope)) {
+		return fmt.Errorf("%w %s", customErrors.ErrMissingScope, scope)
+	}
 
+	if scope != constants.ScopeAdmin {
+		return nil
+	}
+
+	userID, _ := claims[constants.UserIDKey].(string)
+	role, err := uc.repo.GetUserRole(userID)
 	if err != nil {
 		return err
 	}
 
-	clientCert, clientKey, err := uc.SignCert(caCert, caKey, client.ClientUUID)
+	if role.RoleString != string(constants.ADMIN) {
+		return fmt.Errorf("%w %s", customErrors.ErrMissingScope, scope)
+	}
+	return nil
+}
+
+// parseToken verifies a JWT with the signing key named by its kid header, revoked tokens are refused. Personal access
+// tokens are looked up instead, see parsePersonalAccessToken
+func (uc *Usecase) parseToken(tokenInput string) (jwt.MapClaims, error) {
+	if strings.HasPrefix(tokenInput, constants.PersonalAccessTokenPrefix) {
+		return uc.parsePersonalAccessToken(tokenInput)
+	}
+
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenInput, claims, uc.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, _ := claims[constants.TokenIDKey].(string)
+	userID, _ := claims[constants.UserIDKey].(string)
+	revoked, err := uc.repo.IsTokenRevoked(tokenID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, customErrors.ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// personalAccessTokenScopes the scopes granted by each scope a personal access token can be created with
+var personalAccessTokenScopes = map[string][]any{
+	constants.ScopeRead:  {constants.ScopeRead},
+	constants.ScopeFE:    {constants.ScopeFE, constants.ScopeRead},
+	constants.ScopeAdmin: {constants.ScopeFE, constants.ScopeRead, constants.ScopeAdmin},
+}
+
+// parsePersonalAccessToken returns the claims of a personal access token as if it was an access token issued for the FE,
+// so the routes accept it the same way. The time it is used at is recorded, at most once every PersonalAccessTokenTouchInterval:
+// the middlewares and the handler of a request all parse the token
+func (uc *Usecase) parsePersonalAccessToken(tokenInput string) (jwt.MapClaims, error) {
+	token, role, err := uc.repo.GetPersonalAccessToken(hashCredential(tokenInput))
+	if err != nil {
+		return nil, err
+	}
+
+	if touchDue(token) {
+		if err = uc.repo.TouchPersonalAccessToken(token.UUID); err != nil {
+			return nil, err
+		}
+	}
+
+	return jwt.MapClaims{
+		constants.UserIDKey:   token.UserUUID,
+		constants.RoleString:  role.RoleString,
+		constants.AudienceKey: constants.AudienceFE,
+		constants.ScopesKey:   personalAccessTokenScopes[token.Scope],
+		constants.TokenIDKey:  token.UUID,
+	}, nil
+}
+
+// touchDue whether the last use recorded for the token is older than PersonalAccessTokenTouchInterval
+func touchDue(token *entities.PersonalAccessToken) bool {
+	if token.LastUsedDate == nil {
+		return true
+	}
+
+	lastUsed, err := time.Parse(constants.DateTimeExample, *token.LastUsedDate)
+	return err != nil || time.Since(lastUsed) >= constants.PersonalAccessTokenTouchInterval
+}
+
+// CreatePersonalAccessToken creates a token for the scripts of the user expiring in days, only administrators can
+// create admin tokens. The token is returned once, only its hash is stored
+func (uc *Usecase) CreatePersonalAccessToken(userUUID, name, scope string, days int) (*entities.CreatePersonalAccessTokenResponse, error) {
+	if scope == constants.ScopeAdmin {
+		role, err := uc.repo.GetUserRole(userUUID)
+		if err != nil {
+			return nil, err
+		}
+
+		if role.RoleString != string(constants.ADMIN) {
+			return nil, customErrors.ErrPersonalAccessTokenScope
+		}
+	}
+
+	token := constants.PersonalAccessTokenPrefix + utils.GenerateToken(64)
+	expires := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+
+	tokenUUID, err := uc.repo.CreatePersonalAccessToken(userUUID, name, scope, hashCredential(token), expires)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.CreatePersonalAccessTokenResponse{
+		TokenUUID:   tokenUUID,
+		Token:       token,
+		ExpiresDate: expires.UTC().Format(time.DateTime),
+	}, nil
+}
+
+func (uc *Usecase) GetPersonalAccessTokens(userUUID string, offset uint) ([]*entities.PersonalAccessToken, int, error) {
+	return uc.repo.GetPersonalAccessTokensByUserID(userUUID, offset)
+}
+
+func (uc *Usecase) RevokePersonalAccessToken(userUUID, tokenUUID string) error {
+	return uc.repo.DeletePersonalAccessToken(userUUID, tokenUUID)
+}
+
+// verificationKey jwt.Keyfunc returning the secret of the key that signed the token. Keys created by another
+// instance sharing the database are not cached yet, so they are loaded again before refusing the token.
+// They are loaded at most once every SigningKeyReloadInterval, tokens with made up kids can't query the database each
+func (uc *Usecase) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header[constants.KeyIDHeader].(string)
+
+	lookup := func() ([]byte, bool) {
+		uc.signingKeysMutex.RLock()
+		defer uc.signingKeysMutex.RUnlock()
+
+		secret, ok := uc.signingKeys[kid]
+		return secret, ok
+	}
+
+	if secret, ok := lookup(); ok {
+		return secret, nil
+	}
+
+	if err := uc.reloadSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	if secret, ok := lookup(); ok {
+		return secret, nil
+	}
+
+	return nil, customErrors.ErrUnknownSigningKey
+}
+
+// reloadSigningKeys loads the signing keys again, unless they were reloaded less than SigningKeyReloadInterval ago
+func (uc *Usecase) reloadSigningKeys() error {
+	uc.signingKeysMutex.Lock()
+	if time.Since(uc.signingKeysReloadDate) < constants.SigningKeyReloadInterval {
+		uc.signingKeysMutex.Unlock()
+		return nil
+	}
+	uc.signingKeysReloadDate = time.Now()
+	uc.signingKeysMutex.Unlock()
+
+	return uc.LoadSigningKeys()
+}
+
+// signToken signs the claims with the current signing key
+func (uc *Usecase) signToken(claims jwt.MapClaims) (string, error) {
+	uc.signingKeysMutex.RLock()
+	defer uc.signingKeysMutex.RUnlock()
 
+	if uc.currentKey == nil {
+		return "", customErrors.ErrUnknownSigningKey
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header[constants.KeyIDHeader] = uc.currentKey.KID
+
+	return token.SignedString(uc.currentKey.Secret)
+}
+
+// LoadSigningKeys reads the JWT signing keys from the database, the first one is created at the first start
+func (uc *Usecase) LoadSigningKeys() error {
+	keys, err := uc.repo.GetSigningKeys()
 	if err != nil {
 		return err
 	}
 
-	return uc.repo.UpdateCerts(client, caCert, clientCert, clientKey)
+	secrets := make(map[string][]byte, len(keys))
+	var current *entities.SigningKey
+
+	for _, key := range keys {
+		secrets[key.KID] = key.Secret
+		if key.RetiredDate == nil && current == nil {
+			current = key
+		}
+	}
+
+	if current == nil {
+		return uc.RotateSigningKey()
+	}
+
+	uc.signingKeysMutex.Lock()
+	defer uc.signingKeysMutex.Unlock()
+
+	uc.signingKeys = secrets
+	uc.currentKey = current
+
+	return nil
 }
 
-func (uc *Usecase) UpdateEncryptionClientStatus(clientUUID, userUUID string, status bool) error {
-	return uc.repo.UpdateEncryptionClientStatus(clientUUID, userUUID, status)
+// RotateSigningKey signs the next tokens with a new key. The previous key is retired, it keeps verifying the
+// tokens signed with it until MaintainTokens removes it
+func (uc *Usecase) RotateSigningKey() error {
+	secret := make([]byte, 64)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	if err := uc.repo.CreateSigningKey(uuid.New().String(), secret); err != nil {
+		return err
+	}
+
+	return uc.LoadSigningKeys()
 }
 
-func (uc *Usecase) GetClientCertsByUserID(userUUID string) (certs []*entities.Cert, length int, e error) {
-	return uc.repo.GetClientCertsByUserID(userUUID)
+// MaintainTokens rotates the signing key once older than rotation, then prunes what expired: the retired keys no
+// valid token can be signed with, the revoked tokens and the refresh tokens, also the used ones
+func (uc *Usecase) MaintainTokens(rotation time.Duration) error {
+	uc.signingKeysMutex.RLock()
+	created, err := time.Parse(constants.DateTimeExample, uc.currentKey.CreatedDate)
+	uc.signingKeysMutex.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	if time.Since(created) >= rotation {
+		log.Infof("[JWT] Rotating the signing key created on %s", created.Format(constants.DateTimeExample))
+		if err = uc.RotateSigningKey(); err != nil {
+			return err
+		}
+	}
+
+	// device tokens are the longest-lived ones
+	if err = uc.repo.DeleteSigningKeysRetiredBefore(time.Now().Add(-constants.DeviceTokenDuration)); err != nil {
+		return err
+	}
+
+	return uc.repo.DeleteExpiredTokens(time.Now().Add(-constants.RefreshTokenReuseWindow))
 }
 
-func (uc *Usecase) CreateClient(userUUID, machineID, latestIP, name string) (string, error) {
-	return uc.repo.CreateClient(userUUID, machineID, latestIP, name)
+func (uc *Usecase) GetUserByUsername(username string) (*entities.User, *entities.Role, error) {
+	return uc.repo.GetUserByUsername(username)
 }
 
-func (uc *Usecase) CreateCertForClient(userUUID, clientUUID string, clientCert, clientKey []byte) (string, error) {
-	return uc.repo.CreateCertForClient(userUUID, clientUUID, clientCert, clientKey)
+func (uc *Usecase) GetUserByUserID(userUUID string) (*entities.User, error) {
+	return uc.repo.GetUserByUserID(userUUID)
 }
 
-func (uc *Usecase) CreateHandshake(userUUID, ssid, bssid, status, handshakePcap string) (string, error) {
-	return uc.repo.CreateHandshake(userUUID, ssid, bssid, status, handshakePcap)
+func (uc *Usecase) CreateUser(userEntity *entities.User, role constants.Role) error {
+	return uc.repo.CreateUser(userEntity, role)
 }
 
-func (uc *Usecase) GetRaspberryPI(userUUID string, offset uint) ([]*entities.RaspberryPI, int, error) {
-	return uc.repo.GetRaspberryPiByUserID(userUUID, offset)
+func (uc *Usecase) UpdateUserPassword(userUUID, password string) error {
+	return uc.repo.UpdateUserPassword(userUUID, password)
 }
 
-func (uc *Usecase) CreateRaspberryPI(userUUID, machineID, encryptionKey string) (string, error) {
-	return uc.repo.CreateRaspberryPI(userUUID, machineID, encryptionKey)
+// RegistrationsAllowed whether new users can sign up, as set by the administrators or by ALLOW_REGISTRATIONS until then
+func (uc *Usecase) RegistrationsAllowed() (bool, error) {
+	value, err := uc.repo.GetSetting(constants.SettingRegistrations)
+	if err != nil {
+		return false, err
+	}
+
+	if value == nil {
+		return constants.AllowRegistrations != "", nil
+	}
+	return strconv.ParseBool(*value)
 }
 
-func (uc *Usecase) GetHandshakes(userUUID string, offset uint) ([]*entities.Handshake, int, error) {
-	return uc.repo.GetHandshakesByUserID(userUUID, offset)
+func (uc *Usecase) SetRegistrationsAllowed(enabled bool) error {
+	return uc.repo.SetSetting(constants.SettingRegistrations, strconv.FormatBool(enabled))
 }
 
-func (uc *Usecase) GetClientInfo(userUUID, machineID string) (*entities.Client, error) {
-	return uc.repo.GetClientInfo(userUUID, machineID)
+func (uc *Usecase) GetUsers(offset uint) ([]*entities.UserSummary, int, error) {
+	return uc.repo.GetUsers(offset)
 }
 
-func (uc *Usecase) GetHandshakesByStatus(filterStatus string) (handshakes []*entities.Handshake, length int, e error) {
-	return uc.repo.GetHandshakesByStatus(filterStatus)
+// ensureOtherUser refuses administrators acting on their own account, so at least one administrator is left
+func (uc *Usecase) ensureOtherUser(adminUUID, userUUID string) error {
+	if adminUUID == userUUID {
+		return customErrors.ErrOwnAccount
+	}
+
+	_, err := uc.repo.GetUserRole(userUUID)
+	return err
 }
-func (uc *Usecase) UpdateClientTask(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake string) (*entities.Handshake, error) {
-	return uc.repo.UpdateClientTask(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake)
+
+// DisableUser disables or enables again a user. Disabled users cannot log in, their tokens are refused and their
+// refresh tokens deleted
+func (uc *Usecase) DisableUser(adminUUID, userUUID string, disabled bool) error {
+	if err := uc.ensureOtherUser(adminUUID, userUUID); err != nil {
+		return err
+	}
+
+	if err := uc.repo.UpdateUserDisabled(userUUID, disabled); err != nil {
+		return err
+	}
+
+	if !disabled {
+		return nil
+	}
+	return uc.repo.DeleteUserSessions(userUUID)
 }
 
-func (uc *Usecase) UpdateClientTaskRest(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake string) (*entities.Handshake, error) {
-	return uc.repo.UpdateClientTaskRest(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake)
+// DeleteUser deletes a user with its clients, devices and handshakes
+func (uc *Usecase) DeleteUser(adminUUID, userUUID string) error {
+	if err := uc.ensureOtherUser(adminUUID, userUUID); err != nil {
+		return err
+	}
+
+	return uc.repo.DeleteUser(userUUID)
 }
 
-func (uc *Usecase) GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid string) (handshakes []*entities.Handshake, length int, e error) {
-	return uc.repo.GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid)
+// ResetUserPassword sets the password of a user, logging it out of its sessions
+func (uc *Usecase) ResetUserPassword(userUUID, password string) error {
+	if _, err := uc.repo.GetUserRole(userUUID); err != nil {
+		return err
+	}
+
+	if err := uc.repo.UpdateUserPassword(userUUID, password); err != nil {
+		return err
+	}
+	return uc.repo.DeleteUserSessions(userUUID)
 }
 
-func (uc *Usecase) DeleteClient(userUUID, clientUUID string) (bool, error) {
-	return uc.repo.DeleteClient(userUUID, clientUUID)
+// UpdateUserRole changes the role of a user. The scopes of the new role are granted when its tokens are refreshed
+func (uc *Usecase) UpdateUserRole(adminUUID, userUUID string, role constants.Role) error {
+	if err := uc.ensureOtherUser(adminUUID, userUUID); err != nil {
+		return err
+	}
+
+	return uc.repo.UpdateUserRole(userUUID, role)
 }
 
-func (uc *Usecase) DeleteRaspberryPI(userUUID, rspUUID string) (bool, error) {
-	return uc.repo.DeleteRaspberryPI(userUUID, rspUUID)
+func (uc *Usecase) GetAllClients(offset uint) ([]*entities.Client, int, error) {
+	return uc.repo.GetAllClients(offset)
 }
 
-func (uc *Usecase) DeleteHandshake(userUUID, handshakeUUID string) (bool, error) {
+func (uc *Usecase) GetAllRaspberryPIs(offset uint) ([]*entities.RaspberryPI, int, error) {
+	return uc.repo.GetAllRaspberryPIs(offset)
+}
+
+func (uc *Usecase) GetTaskQueue(offset uint) ([]*entities.Handshake, int, error) {
+	return uc.repo.GetTaskQueue(offset)
+}
+
+// GetClientsInstalledByUserID a page size of 0 means the default one
+func (uc *Usecase) GetClientsInstalledByUserID(userUUID string, offset, pageSize uint) ([]*entities.Client, int, error) {
+	return uc.repo.GetClientsInstalledByUserID(userUUID, offset, defaultPageSize(pageSize))
+}
+
+// defaultPageSize constants.Limit unless another page size is requested
+func defaultPageSize(pageSize uint) uint {
+	if pageSize == 0 {
+		return constants.Limit
+	}
+	return pageSize
+}
+
+func (uc *Usecase) GetClientsInstalled() (clients []*entities.Client, length int, e error) {
+	return uc.repo.GetClientsInstalled()
+}
+
+func (uc *Usecase) UpdateCerts(client *entities.Client) error {
+	caCert, caKey, _, _, err := uc.GetServerCerts()
+
+	if err != nil {
+		return err
+	}
+
+	clientCert, clientKey, err := uc.SignCert(caCert, caKey, client.ClientUUID)
+
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.UpdateCerts(client, caCert, clientCert, clientKey)
+}
+
+// UpdateEncryptionClientStatus encryption can't be enabled before the client is approved, it has no certificate yet
+func (uc *Usecase) UpdateEncryptionClientStatus(clientUUID, userUUID string, status bool) error {
+	if status {
+		if err := uc.EnsureClientApproved(userUUID, clientUUID); err != nil {
+			return err
+		}
+	}
+	return uc.repo.UpdateEncryptionClientStatus(clientUUID, userUUID, status)
+}
+
+func (uc *Usecase) GetClientCertsByUserID(userUUID string) (certs []*entities.Cert, length int, e error) {
+	return uc.repo.GetClientCertsByUserID(userUUID)
+}
+
+// CreateClient refuses machine IDs of clients deleted by the user and not approved again.
+// The client waits for approval with a random enrollment code, see ApproveClient
+func (uc *Usecase) CreateClient(userUUID, machineID, latestIP, name string) (string, error) {
+	if err := uc.ensureNotRevoked(constants.RevokedClient, machineID); err != nil {
+		return "", err
+	}
+	return uc.repo.CreateClient(userUUID, machineID, latestIP, name, utils.EnrollmentCode(utils.GenerateToken(8)))
+}
+
+// ApproveClient signs the certificate of a client waiting for approval, from now on tasks can be assigned to it.
+// A client rejected is deleted, so it is refused until the user approves it again from the revocations
+func (uc *Usecase) ApproveClient(userUUID, clientUUID string, approve bool) error {
+	client, err := uc.repo.GetClientByUUID(userUUID, clientUUID)
+	if err != nil {
+		return err
+	}
+
+	if client.Approved {
+		return customErrors.ErrAlreadyApproved
+	}
+
+	if !approve {
+		_, err = uc.DeleteClient(userUUID, clientUUID)
+		return err
+	}
+
+	caCert, caKey, _, _, err := uc.GetServerCerts()
+	if err != nil {
+		return err
+	}
+
+	clientCert, clientKey, err := uc.SignCert(caCert, caKey, clientUUID)
+	if err != nil {
+		return err
+	}
+
+	if _, err = uc.repo.CreateCertForClient(userUUID, clientUUID, clientCert, clientKey); err != nil {
+		return err
+	}
+
+	if err = uc.repo.ApproveClient(userUUID, clientUUID); err != nil {
+		return err
+	}
+
+	log.Infof("[APPROVAL] client %s with machine ID %s approved by user %s", clientUUID, client.MachineID, userUUID)
+	return nil
+}
+
+// EnsureClientApproved refuses the clients of the user waiting for approval
+func (uc *Usecase) EnsureClientApproved(userUUID, clientUUID string) error {
+	client, err := uc.repo.GetClientByUUID(userUUID, clientUUID)
+	if err != nil {
+		return err
+	}
+
+	if !client.Approved {
+		return customErrors.ErrClientPendingApproval
+	}
+	return nil
+}
+
+func (uc *Usecase) CreateCertForClient(userUUID, clientUUID string, clientCert, clientKey []byte) (string, error) {
+	return uc.repo.CreateCertForClient(userUUID, clientUUID, clientCert, clientKey)
+}
+
+func (uc *Usecase) CreateHandshake(userUUID, ssid, bssid, status, handshakePcap string) (string, error) {
+	return uc.repo.CreateHandshake(userUUID, ssid, bssid, status, handshakePcap)
+}
+
+// GetRaspberryPI a page size of 0 means the default one
+func (uc *Usecase) GetRaspberryPI(userUUID string, offset, pageSize uint) ([]*entities.RaspberryPI, int, error) {
+	return uc.repo.GetRaspberryPiByUserID(userUUID, offset, defaultPageSize(pageSize))
+}
+
+// CreateRaspberryPI refuses machine IDs of devices deleted by the user and not approved again.
+// The device waits for approval, see ApproveRaspberryPI
+func (uc *Usecase) CreateRaspberryPI(userUUID, machineID, encryptionKey string) (string, error) {
+	if err := uc.ensureNotRevoked(constants.RevokedRaspberryPI, machineID); err != nil {
+		return "", err
+	}
+	return uc.repo.CreateRaspberryPI(userUUID, machineID, encryptionKey)
+}
+
+func (uc *Usecase) GetRaspberryPIByMachineID(machineID string) (*entities.RaspberryPI, error) {
+	return uc.repo.GetRaspberryPIByMachineID(machineID)
+}
+
+func (uc *Usecase) GetRaspberryPIByUUID(userUUID, rspUUID string) (*entities.RaspberryPI, error) {
+	return uc.repo.GetRaspberryPIByUUID(userUUID, rspUUID)
+}
+
+// ApproveRaspberryPI lets a device waiting for approval log in, upload captures and enroll a certificate.
+// A device rejected is deleted, so it is refused until the user approves it again from the revocations
+func (uc *Usecase) ApproveRaspberryPI(userUUID, rspUUID string, approve bool) error {
+	rsp, err := uc.repo.GetRaspberryPIByUUID(userUUID, rspUUID)
+	if err != nil {
+		return err
+	}
+
+	if rsp.Approved {
+		return customErrors.ErrAlreadyApproved
+	}
+
+	if !approve {
+		_, err = uc.DeleteRaspberryPI(userUUID, rspUUID)
+		return err
+	}
+
+	if err = uc.repo.ApproveRaspberryPI(userUUID, rspUUID); err != nil {
+		return err
+	}
+
+	log.Infof("[APPROVAL] raspberry pi %s with machine ID %s approved by user %s", rspUUID, rsp.MachineID, userUUID)
+	return nil
+}
+
+// RenameRaspberryPI an empty name removes the one given before
+func (uc *Usecase) RenameRaspberryPI(userUUID, rspUUID, name string) error {
+	if _, err := uc.repo.GetRaspberryPIByUUID(userUUID, rspUUID); err != nil {
+		return err
+	}
+
+	var newName *string
+	if name = strings.TrimSpace(name); name != "" {
+		newName = &name
+	}
+
+	return uc.repo.UpdateRaspberryPIName(userUUID, rspUUID, newName)
+}
+
+// CheckInRaspberryPI records that the device identified by machineID has been seen from address,
+// metadata is nil when the device did not report it
+func (uc *Usecase) CheckInRaspberryPI(machineID, address string, metadata *entities.RaspberryPIMetadata) error {
+	rsp, err := uc.repo.GetRaspberryPIByMachineID(machineID)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.UpdateRaspberryPICheckIn(rsp.RaspberryPIUUID, utils.RemoteIP(address), metadata)
+}
+
+// GetRaspberryPIHandshakes returns a page of the handshakes uploaded by a device, without their pcap
+func (uc *Usecase) GetRaspberryPIHandshakes(userUUID, rspUUID string, offset uint) ([]*entities.Handshake, int, error) {
+	return uc.repo.GetRaspberryPIHandshakes(userUUID, rspUUID, offset)
+}
+
+func (uc *Usecase) GetRaspberryPIActivity(userUUID, rspUUID string) ([]*entities.RaspberryPIActivity, error) {
+	return uc.repo.GetRaspberryPIActivity(userUUID, rspUUID)
+}
+
+// ExchangeRaspberryPIKey completes an X25519 key exchange started by a daemon.
+// The derived key is stored for the machine ID, enrolling the device if it does not exist yet,
+// and the server public key is returned base64 encoded so that the daemon can derive the same key
+func (uc *Usecase) ExchangeRaspberryPIKey(userUUID, machineID, devicePublicKey string) (string, error) {
+	private, err := utils.GenerateExchangeKey()
+	if err != nil {
+		return "", err
+	}
+
+	key, err := utils.DeriveDeviceKey(private, devicePublicKey, machineID)
+	if err != nil {
+		return "", err
+	}
+
+	rsp, err := uc.repo.GetRaspberryPIByMachineID(machineID)
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		_, err = uc.CreateRaspberryPI(userUUID, machineID, key)
+	case err != nil:
+		return "", err
+	case rsp.UserUUID != userUUID:
+		return "", customErrors.ErrRaspberryPIOwnedByAnotherUser
+	default:
+		err = uc.repo.UpdateRaspberryPIEncryptionKey(userUUID, rsp.RaspberryPIUUID, key)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return utils.BytesToBase64String(private.PublicKey().Bytes()), nil
+}
+
+// raspberryPIForEnrollment returns the UUID of the device identified by machineID, creating it when it does not exist yet
+func (uc *Usecase) raspberryPIForEnrollment(userUUID, machineID string) (string, error) {
+	rsp, err := uc.repo.GetRaspberryPIByMachineID(machineID)
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		return uc.CreateRaspberryPI(userUUID, machineID, "")
+	case err != nil:
+		return "", err
+	case rsp.UserUUID != userUUID:
+		return "", customErrors.ErrRaspberryPIOwnedByAnotherUser
+	default:
+		return rsp.RaspberryPIUUID, nil
+	}
+}
+
+// EnrollRaspberryPICertificate signs a certificate for the device identified by machineID, the raspberry pi UUID
+// is stored in the subject serial number as it happens for gRPC clients. The device is created when it does not exist yet,
+// the certificate is signed only once the user approved it
+func (uc *Usecase) EnrollRaspberryPICertificate(userUUID, machineID string) (caCert, deviceCert, deviceKey []byte, err error) {
+	rspID, err := uc.raspberryPIForEnrollment(userUUID, machineID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	rsp, err := uc.repo.GetRaspberryPIByUUID(userUUID, rspID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if !rsp.Approved {
+		return nil, nil, nil, customErrors.ErrRaspberryPIPendingApproval
+	}
+
+	caCert, caKey, _, _, err := uc.GetServerCerts()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	deviceCert, deviceKey, err = uc.SignCert(caCert, caKey, rspID)
+	return caCert, deviceCert, deviceKey, err
+}
+
+// EnrollRaspberryPI issues the credential the device uses for logging in from now on, replacing the previous one.
+// The device is created when it does not exist yet, a device enrolling again waits for the approval of the user
+// as a new one does, otherwise whoever knows its machine ID would take over an approved device
+func (uc *Usecase) EnrollRaspberryPI(userUUID, machineID string) (string, error) {
+	rspID, err := uc.raspberryPIForEnrollment(userUUID, machineID)
+	if err != nil {
+		return "", err
+	}
+
+	credential := utils.GenerateToken(64)
+	if err = uc.repo.EnrollRaspberryPICredential(userUUID, rspID, hashCredential(credential)); err != nil {
+		return "", err
+	}
+
+	return credential, nil
+}
+
+// RotateRaspberryPICredential replaces the credential of a device. Only its hash is stored, the credential
+// is returned to be handed to the device and cannot be retrieved anymore
+func (uc *Usecase) RotateRaspberryPICredential(userUUID, rspUUID string) (string, error) {
+	credential := utils.GenerateToken(64)
+	credentialHash := hashCredential(credential)
+
+	if err := uc.repo.UpdateRaspberryPICredential(userUUID, rspUUID, &credentialHash); err != nil {
+		return "", err
+	}
+
+	return credential, nil
+}
+
+// RevokeRaspberryPICredential the device and the tokens it obtained are refused until it is enrolled again
+func (uc *Usecase) RevokeRaspberryPICredential(userUUID, rspUUID string) error {
+	err := uc.repo.UpdateRaspberryPICredential(userUUID, rspUUID, nil)
+	if errors.Is(err, customErrors.ErrElementNotFound) { // nothing changed, it was already revoked
+		return nil
+	}
+	return err
+}
+
+// AuthenticateRaspberryPI exchanges the credential of a device for a device token.
+// Devices waiting for approval hold a valid credential, but they get ErrRaspberryPIPendingApproval instead of a token
+func (uc *Usecase) AuthenticateRaspberryPI(machineID, credential string) (string, error) {
+	if err := uc.ensureNotRevoked(constants.RevokedRaspberryPI, machineID); err != nil {
+		return "", err
+	}
+
+	rsp, err := uc.repo.GetRaspberryPIByMachineID(machineID)
+	if err != nil || rsp.CredentialHash == nil ||
+		subtle.ConstantTimeCompare([]byte(*rsp.CredentialHash), []byte(hashCredential(credential))) != 1 {
+		return "", customErrors.ErrDeviceCredentialInvalid
+	}
+
+	if !rsp.Approved {
+		log.Infof("[APPROVAL] raspberry pi with machine ID %s is waiting for approval with code %s", machineID, RaspberryPIEnrollmentCode(rsp))
+		return "", customErrors.ErrRaspberryPIPendingApproval
+	}
+
+	return uc.CreateDeviceToken(rsp.UserUUID, machineID, *rsp.CredentialHash)
+}
+
+// hashCredential credentials are random, a plain SHA-256 is enough for not storing them in clear
+func hashCredential(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:])
+}
+
+func credentialFingerprint(credentialHash string) string {
+	return credentialHash[:16]
+}
+
+// RaspberryPIEnrollmentCode the code shown by the daemon while it waits for approval. It is derived from the hash of the
+// credential, which the daemon computes as well, so it does not travel on the wire. Devices without a credential have none
+func RaspberryPIEnrollmentCode(rsp *entities.RaspberryPI) string {
+	if rsp.CredentialHash == nil {
+		return ""
+	}
+	return utils.EnrollmentCode(*rsp.CredentialHash)
+}
+
+// OpenRaspberryPIHandshake decrypts a base64 AES-GCM capture uploaded by a daemon using the key stored for its machine ID.
+// The plaintext pcap is returned base64 encoded, as it is stored in the database
+func (uc *Usecase) OpenRaspberryPIHandshake(userUUID, machineID, bssid, ssid, encryptedPCAP string) (string, error) {
+	payload, err := base64.StdEncoding.DecodeString(encryptedPCAP)
+	if err != nil {
+		return "", customErrors.ErrHandshakeDecryption
+	}
+
+	plaintext, err := uc.OpenRaspberryPICapture(userUUID, machineID, bssid, ssid, payload)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.BytesToBase64String(plaintext), nil
+}
+
+// CreatePlaintextRaspberryPIHandshake saves a capture uploaded unencrypted by a legacy daemon, see TCPAllowPlaintext.
+// Only the approved devices of userUUID which never agreed on an encryption key can upload them
+func (uc *Usecase) CreatePlaintextRaspberryPIHandshake(userUUID, machineID, ssid, bssid, pcap string) (string, error) {
+	rsp, err := uc.repo.GetRaspberryPIByMachineID(machineID)
+	if err != nil || rsp.UserUUID != userUUID || rsp.EncryptionKey != "" {
+		return "", customErrors.ErrRaspberryPINotEnrolled
+	}
+
+	if !rsp.Approved {
+		return "", customErrors.ErrRaspberryPIPendingApproval
+	}
+
+	if _, err = base64.StdEncoding.DecodeString(pcap); err != nil {
+		return "", fmt.Errorf("invalid capture encoding: %w", err)
+	}
+
+	return uc.repo.CreateRaspberryPIHandshake(userUUID, rsp.RaspberryPIUUID, ssid, bssid, constants.NothingStatus, pcap, nil, "")
+}
+
+// GetEnrolledRaspberryPI returns the device of userUUID identified by machineID, only if it already agreed on an encryption key
+// and the user approved it
+func (uc *Usecase) GetEnrolledRaspberryPI(userUUID, machineID string) (*entities.RaspberryPI, error) {
+	rsp, err := uc.repo.GetRaspberryPIByMachineID(machineID)
+	if err != nil || rsp.UserUUID != userUUID || rsp.EncryptionKey == "" {
+		return nil, customErrors.ErrRaspberryPINotEnrolled
+	}
+
+	if !rsp.Approved {
+		return nil, customErrors.ErrRaspberryPIPendingApproval
+	}
+	return rsp, nil
+}
+
+// OpenRaspberryPICapture decrypts a raw AES-GCM payload uploaded by a daemon using the key stored for its machine ID
+func (uc *Usecase) OpenRaspberryPICapture(userUUID, machineID, bssid, ssid string, payload []byte) ([]byte, error) {
+	rsp, err := uc.GetEnrolledRaspberryPI(userUUID, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := utils.OpenAESGCM(rsp.EncryptionKey, payload, utils.HandshakeAdditionalData(machineID, bssid, ssid))
+	if err != nil {
+		return nil, customErrors.ErrHandshakeDecryption
+	}
+
+	return plaintext, nil
+}
+
+// CreateRaspberryPIHandshake saves a capture uploaded by the device of userUUID identified by machineID,
+// keeping track of the device so that it can fetch the results later. position is nil when the device had no GPS fix,
+// quality is the classification of the capture made by the device, empty when it did not send one
+func (uc *Usecase) CreateRaspberryPIHandshake(userUUID, machineID, ssid, bssid, status, handshakePcap string, position *entities.Position, quality string) (string, error) {
+	rsp, err := uc.GetEnrolledRaspberryPI(userUUID, machineID)
+	if err != nil {
+		return "", err
+	}
+
+	return uc.repo.CreateRaspberryPIHandshake(userUUID, rsp.RaspberryPIUUID, ssid, bssid, status, handshakePcap, position, quality)
+}
+
+/*
+IngestRaspberryPICapture
+
+Saves a capture uploaded by a daemon, whatever the transport it was received with (TCP chunked upload or HTTPS).
+payload is gzip(pcap) sealed with the device key, the handshake is saved only if the network has not been uploaded yet
+*/
+func (uc *Usecase) IngestRaspberryPICapture(userUUID, machineID, ssid, bssid string, payload []byte, position *entities.Position, quality string) (string, error) {
+	compressed, err := uc.OpenRaspberryPICapture(userUUID, machineID, bssid, ssid, payload)
+	if err != nil {
+		return "", err
+	}
+
+	pcap, err := decompressCapture(compressed)
+	if err != nil {
+		return "", err
+	}
+
+	if err = uc.EnsureHandshakeNotPresent(userUUID, bssid, ssid); err != nil {
+		return "", err
+	}
+
+	return uc.CreateRaspberryPIHandshake(userUUID, machineID, ssid, bssid, constants.NothingStatus, utils.BytesToBase64String(pcap), position, quality)
+}
+
+// decompressCapture gunzip the capture, refusing to inflate it beyond MaxCaptureSize
+func decompressCapture(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	pcap, err := io.ReadAll(io.LimitReader(reader, constants.MaxCaptureSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(pcap)) > constants.MaxCaptureSize {
+		return nil, customErrors.ErrUploadTooLarge
+	}
+
+	return pcap, nil
+}
+
+// EnsureHandshakeNotPresent we don't save the handshake if already saved
+func (uc *Usecase) EnsureHandshakeNotPresent(userUUID, bssid, ssid string) error {
+	_, saved, err := uc.repo.GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid)
+	if err != nil {
+		return err
+	}
+
+	if saved > 0 {
+		return customErrors.ErrHandshakeAlreadyPresent
+	}
+
+	return nil
+}
+
+/*
+ImportRaspberryPIBundle
+
+Imports an offline bundle written by a daemon of userUUID: a tar.gz holding the manifest, its signature and the captures.
+The signature is verified with the key of the device, then each capture is saved as if it had been uploaded over TCP.
+A capture refused doesn't stop the others, the outcome of each one is returned
+*/
+func (uc *Usecase) ImportRaspberryPIBundle(userUUID string, bundle []byte) (*entities.ImportRaspberryPIBundleResponse, error) {
+	manifestContent, signature, files, err := readBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest entities.RaspberryPIBundleManifest
+	if err = json.Unmarshal(manifestContent, &manifest); err != nil || manifest.Version != constants.BundleVersion {
+		return nil, customErrors.ErrBundleInvalid
+	}
+
+	if err = utils.ValidateGenericStruct(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s", customErrors.ErrBundleInvalid, err.Error())
+	}
+
+	rsp, err := uc.GetEnrolledRaspberryPI(userUUID, manifest.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.VerifyBundle(rsp.EncryptionKey, manifestContent, signature) {
+		return nil, customErrors.ErrBundleSignature
+	}
+
+	response := &entities.ImportRaspberryPIBundleResponse{
+		MachineID: manifest.MachineID,
+		Outcomes:  make([]*entities.RaspberryPIBundleOutcome, 0, len(manifest.Captures)),
+	}
+
+	for _, capture := range manifest.Captures {
+		outcome := &entities.RaspberryPIBundleOutcome{SSID: capture.SSID, BSSID: capture.BSSID}
+
+		outcome.HandshakeUUID, err = uc.importBundleCapture(userUUID, manifest.MachineID, capture, files[capture.File])
+		if err != nil {
+			outcome.Error = err.Error()
+		} else {
+			response.Imported++
+		}
+
+		response.Outcomes = append(response.Outcomes, outcome)
+	}
+
+	return response, nil
+}
+
+// importBundleCapture saves a capture of a bundle, once checked it is the one described by the manifest
+func (uc *Usecase) importBundleCapture(userUUID, machineID string, capture *entities.RaspberryPIBundleCapture, payload []byte) (string, error) {
+	sum := sha256.Sum256(payload)
+	if payload == nil || int64(len(payload)) != capture.Size || hex.EncodeToString(sum[:]) != capture.Checksum {
+		return "", customErrors.ErrBundleCaptureAltered
+	}
+
+	return uc.IngestRaspberryPICapture(userUUID, machineID, capture.SSID, capture.BSSID, payload, capture.Position, capture.Quality)
+}
+
+// readBundle returns the manifest, its signature and the other files of the bundle by name,
+// refusing to inflate it beyond MaxBundleSize
+func readBundle(bundle []byte) (manifest []byte, signature string, files map[string][]byte, err error) {
+	compressed, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		return nil, "", nil, customErrors.ErrBundleInvalid
+	}
+	defer compressed.Close()
+
+	reader := tar.NewReader(io.LimitReader(compressed, constants.MaxBundleSize))
+	files = make(map[string][]byte)
+
+	for {
+		header, errNext := reader.Next()
+		if errors.Is(errNext, io.EOF) {
+			break
+		}
+		if errNext != nil {
+			return nil, "", nil, customErrors.ErrBundleInvalid
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content, errRead := io.ReadAll(reader)
+		if errRead != nil {
+			return nil, "", nil, customErrors.ErrBundleInvalid
+		}
+
+		switch header.Name {
+		case constants.BundleManifest:
+			manifest = content
+		case constants.BundleSignature:
+			signature = strings.TrimSpace(string(content))
+		default:
+			files[header.Name] = content
+		}
+	}
+
+	if manifest == nil || signature == "" {
+		return nil, "", nil, customErrors.ErrBundleInvalid
+	}
+
+	return manifest, signature, files, nil
+}
+
+// GetRaspberryPIResults returns the handshakes uploaded by the device of userUUID identified by machineID which have been cracked
+func (uc *Usecase) GetRaspberryPIResults(userUUID, machineID string) ([]*entities.Handshake, error) {
+	rsp, err := uc.GetEnrolledRaspberryPI(userUUID, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.repo.GetHandshakesByRaspberryPI(userUUID, rsp.RaspberryPIUUID, constants.CrackedStatus)
+}
+
+// CreateRaspberryPIDirective queues a directive for a device of the user, it is delivered the next time the device checks in
+func (uc *Usecase) CreateRaspberryPIDirective(userUUID, rspUUID, kind string, parameters *entities.DirectiveParameters) (string, error) {
+	if _, err := uc.repo.GetRaspberryPIByUUID(userUUID, rspUUID); err != nil {
+		return "", err
+	}
+
+	normalized, err := directiveParameters(kind, parameters)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+
+	return uc.repo.CreateDirective(userUUID, rspUUID, kind, string(encoded))
+}
+
+// directiveParameters validates the parameters of kind, returning only the ones it uses
+func directiveParameters(kind string, parameters *entities.DirectiveParameters) (*entities.DirectiveParameters, error) {
+	switch kind {
+	case constants.DirectiveSchedule:
+		if parameters.Interval == "" && len(parameters.Windows) == 0 {
+			return nil, fmt.Errorf("%w: a schedule needs an interval or upload windows", customErrors.ErrDirectiveInvalid)
+		}
+		if interval, err := time.ParseDuration(parameters.Interval); parameters.Interval != "" && (err != nil || interval < constants.MinDirectiveInterval) {
+			return nil, fmt.Errorf("%w: interval must be a duration of at least %s, e.g. 10m", customErrors.ErrDirectiveInvalid, constants.MinDirectiveInterval)
+		}
+		for _, window := range parameters.Windows {
+			if !validWindow(window) {
+				return nil, fmt.Errorf("%w: window '%s' must be in the HH:MM-HH:MM format", customErrors.ErrDirectiveInvalid, window)
+			}
+		}
+		return &entities.DirectiveParameters{Interval: parameters.Interval, Windows: parameters.Windows}, nil
+	case constants.DirectiveAddWatch:
+		if !strings.HasPrefix(parameters.Path, "/") && !strings.HasPrefix(parameters.Path, "~/") {
+			return nil, fmt.Errorf("%w: path must be absolute", customErrors.ErrDirectiveInvalid)
+		}
+		if len(parameters.Include) == 0 {
+			return nil, fmt.Errorf("%w: at least one include pattern is required", customErrors.ErrDirectiveInvalid)
+		}
+		for _, pattern := range append(append([]string{}, parameters.Include...), parameters.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%w: pattern '%s' is not valid", customErrors.ErrDirectiveInvalid, pattern)
+			}
+		}
+		return &entities.DirectiveParameters{
+			Path:      parameters.Path,
+			Recursive: parameters.Recursive,
+			Include:   parameters.Include,
+			Exclude:   parameters.Exclude,
+		}, nil
+	case constants.DirectiveRemoveWatch:
+		if parameters.Path == "" {
+			return nil, fmt.Errorf("%w: path is required", customErrors.ErrDirectiveInvalid)
+		}
+		return &entities.DirectiveParameters{Path: parameters.Path}, nil
+	case constants.DirectivePurge, constants.DirectiveReauthenticate, constants.DirectiveUploadLogs:
+		return &entities.DirectiveParameters{}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown kind '%s'", customErrors.ErrDirectiveInvalid, kind)
+	}
+}
+
+// validWindow reports whether window is in the HH:MM-HH:MM format understood by the daemon
+func validWindow(window string) bool {
+	from, to, found := strings.Cut(window, "-")
+	if !found {
+		return false
+	}
+
+	start, errStart := time.Parse("15:04", strings.TrimSpace(from))
+	end, errEnd := time.Parse("15:04", strings.TrimSpace(to))
+	return errStart == nil && errEnd == nil && !start.Equal(end)
+}
+
+// GetRaspberryPIDirectives returns every directive of a device of the user, newest first
+func (uc *Usecase) GetRaspberryPIDirectives(userUUID, rspUUID string) ([]*entities.Directive, error) {
+	if _, err := uc.repo.GetRaspberryPIByUUID(userUUID, rspUUID); err != nil {
+		return nil, err
+	}
+
+	directives, err := uc.repo.GetDirectivesByRaspberryPI(userUUID, rspUUID,
+		constants.DirectivePending, constants.DirectiveApplied, constants.DirectiveFailed)
+	slices.Reverse(directives)
+	return directives, err
+}
+
+// CancelRaspberryPIDirective removes a directive the device has not acknowledged yet
+func (uc *Usecase) CancelRaspberryPIDirective(userUUID, directiveUUID string) error {
+	return uc.repo.DeletePendingDirective(userUUID, directiveUUID)
+}
+
+// GetPendingRaspberryPIDirectives returns the directives the device identified by machineID has to apply, in the order they were created
+func (uc *Usecase) GetPendingRaspberryPIDirectives(userUUID, machineID string) ([]*entities.RaspberryPIDirective, error) {
+	rsp, err := uc.GetEnrolledRaspberryPI(userUUID, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	directives, err := uc.repo.GetDirectivesByRaspberryPI(userUUID, rsp.RaspberryPIUUID, constants.DirectivePending)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]*entities.RaspberryPIDirective, 0, len(directives))
+	for _, directive := range directives {
+		var parameters entities.DirectiveParameters
+		if err = json.Unmarshal([]byte(directive.Parameters), &parameters); err != nil {
+			return nil, err
+		}
+
+		pending = append(pending, &entities.RaspberryPIDirective{
+			UUID:       directive.UUID,
+			Kind:       directive.Kind,
+			Parameters: &parameters,
+		})
+	}
+
+	return pending, nil
+}
+
+// AcknowledgeRaspberryPIDirective records whether the device identified by machineID applied the directive.
+// Results longer than MaxDirectiveResultSize keep their end, the most recent part of logs
+func (uc *Usecase) AcknowledgeRaspberryPIDirective(userUUID, machineID, directiveUUID string, applied bool, result string) error {
+	rsp, err := uc.GetEnrolledRaspberryPI(userUUID, machineID)
+	if err != nil {
+		return err
+	}
+
+	status := constants.DirectiveApplied
+	if !applied {
+		status = constants.DirectiveFailed
+	}
+
+	if len(result) > constants.MaxDirectiveResultSize {
+		// the cut can split a character
+		result = strings.ToValidUTF8(result[len(result)-constants.MaxDirectiveResultSize:], "")
+	}
+
+	return uc.repo.AcknowledgeDirective(userUUID, rsp.RaspberryPIUUID, directiveUUID, status, result)
+}
+
+// GetHandshakes returns a page of the handshakes visible to the user matching the filter,
+// with the cursor of the next page when there is one. A cursor replaces the sort and the order of the filter
+func (uc *Usecase) GetHandshakes(userUUID string, filter *entities.HandshakeFilter) (handshakes []*entities.Handshake, length int, nextCursor string, e error) {
+	var after *entities.HandshakeCursor
+
+	if filter.Cursor != "" {
+		cursor, err := decodeHandshakeCursor(filter.Cursor)
+		if err != nil {
+			return nil, -1, "", err
+		}
+		after, filter.Sort, filter.Order = cursor, cursor.Sort, cursor.Order
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = constants.DefaultHandshakeSort
+	}
+	if filter.Order == "" {
+		filter.Order = constants.DefaultHandshakeOrder
+	}
+	filter.PageSize = defaultPageSize(filter.PageSize)
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+
+	// one more handshake than the page tells whether there is a next one
+	handshakes, length, err := uc.repo.GetHandshakesByUserID(userUUID, filter, after, filter.PageSize+1)
+	if err != nil || uint(len(handshakes)) <= filter.PageSize {
+		return handshakes, length, "", err
+	}
+
+	handshakes = handshakes[:filter.PageSize]
+	last := handshakes[len(handshakes)-1]
+
+	nextCursor, err = encodeHandshakeCursor(&entities.HandshakeCursor{
+		Sort:  filter.Sort,
+		Order: filter.Order,
+		Value: handshakeSortValue(last, filter.Sort),
+		UUID:  last.UUID,
+	})
+	return handshakes, length, nextCursor, err
+}
+
+// handshakeSortValue the value of the handshake for the sort key, as compared by the database
+func handshakeSortValue(handshake *entities.Handshake, sort string) string {
+	switch sort {
+	case "cracked_date":
+		if handshake.CrackedDate == nil {
+			return constants.NotCrackedSortValue
+		}
+		return *handshake.CrackedDate
+	case "ssid":
+		return handshake.SSID
+	case "bssid":
+		return handshake.BSSID
+	case "status":
+		return handshake.Status
+	default:
+		return handshake.UploadedDate
+	}
+}
+
+// encodeHandshakeCursor cursors are opaque to the callers, they are the base64 of the JSON
+func encodeHandshakeCursor(cursor *entities.HandshakeCursor) (string, error) {
+	marshalled, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(marshalled), nil
+}
+
+func decodeHandshakeCursor(encoded string) (*entities.HandshakeCursor, error) {
+	var cursor entities.HandshakeCursor
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(decoded, &cursor) != nil || cursor.UUID == "" {
+		return nil, customErrors.ErrInvalidCursor
+	}
+
+	// the sort is checked by the repository
+	if cursor.Order != "asc" && cursor.Order != "desc" {
+		return nil, customErrors.ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// GetHandshake returns a handshake of the user or shared with one of its teams, without its logs and capture
+func (uc *Usecase) GetHandshake(userUUID, handshakeUUID string) (*entities.Handshake, error) {
+	return uc.repo.GetSharedHandshake(userUUID, handshakeUUID)
+}
+
+// GetHandshakeCapture returns the decoded capture of a handshake of the user or shared with one of its teams
+func (uc *Usecase) GetHandshakeCapture(userUUID, handshakeUUID string) ([]byte, error) {
+	capture, err := uc.repo.GetHandshakeCapture(userUUID, handshakeUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if capture == nil {
+		return nil, customErrors.ErrElementNotFound
+	}
+	return base64.StdEncoding.DecodeString(*capture)
+}
+
+// GetHandshakeHashes returns the capture of a handshake of the user or shared with one of its teams in the hashcat 22000 format
+func (uc *Usecase) GetHandshakeHashes(userUUID, handshakeUUID string) ([]byte, error) {
+	handshake, err := uc.repo.GetSharedHandshake(userUUID, handshakeUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	capture, err := uc.GetHandshakeCapture(userUUID, handshakeUUID)
+	if err != nil {
+		return nil, err
+	}
+	return wpaparser.ToHC22000(capture, handshake.SSID)
+}
+
+/*
+ExportHandshakes
+
+Returns a tar.gz archive with the captures of the handshakes of the user or shared with its teams, all of them when
+handshakeUUIDs is empty, and a manifest describing them with their passwords. The captures are converted to format,
+the ones which can't be are listed in the manifest without a file
+*/
+func (uc *Usecase) ExportHandshakes(userUUID string, handshakeUUIDs []string, format string) ([]byte, error) {
+	handshakes, err := uc.repo.GetExportedHandshakes(userUUID, handshakeUUIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(handshakes) == 0 {
+		return nil, customErrors.ErrElementNotFound
+	}
+
+	manifest := entities.HandshakeExportManifest{
+		Format:     format,
+		ExportedAt: time.Now().UTC(),
+		Handshakes: make([]*entities.HandshakeExportManifestEntry, 0, len(handshakes)),
+	}
+
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	archive := tar.NewWriter(compressed)
+
+	for _, handshake := range handshakes {
+		entry := &entities.HandshakeExportManifestEntry{
+			UUID:         handshake.UUID,
+			SSID:         handshake.SSID,
+			BSSID:        handshake.BSSID,
+			Status:       handshake.Status,
+			UploadedDate: handshake.UploadedDate,
+			CrackedDate:  handshake.CrackedDate,
+			Passwords:    crackedPasswords(handshake.CrackedHandshake),
+			ClientUUID:   handshake.ClientUUID,
+			ClientName:   handshake.ClientName,
+		}
+		manifest.Handshakes = append(manifest.Handshakes, entry)
+
+		content, errExport := exportCapture(handshake.Handshake, format)
+		if errExport != nil {
+			entry.Error = errExport.Error()
+			continue
+		}
+
+		entry.File = handshake.UUID + "." + format
+		if err = writeArchiveFile(archive, entry.File, content, manifest.ExportedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeArchiveFile(archive, constants.ExportManifest, content, manifest.ExportedAt); err != nil {
+		return nil, err
+	}
+
+	if err = archive.Close(); err != nil {
+		return nil, err
+	}
+
+	if err = compressed.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// exportCapture decodes the capture of the handshake, converting it unless the format is pcap
+func exportCapture(handshake *entities.Handshake, format string) ([]byte, error) {
+	if handshake.HandshakePCAP == nil {
+		return nil, customErrors.ErrElementNotFound
+	}
+
+	capture, err := base64.StdEncoding.DecodeString(*handshake.HandshakePCAP)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == constants.HC22000Format {
+		return wpaparser.ToHC22000(capture, handshake.SSID)
+	}
+	return capture, nil
+}
+
+func writeArchiveFile(archive *tar.Writer, name string, content []byte, modTime time.Time) error {
+	err := archive.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = archive.Write(content)
+	return err
+}
+
+// GetCrackedResults returns a result for every password recovered from the handshakes of the user or shared with its teams
+func (uc *Usecase) GetCrackedResults(userUUID string) ([]*entities.CrackedResult, error) {
+	handshakes, err := uc.repo.GetCrackedHandshakes(userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*entities.CrackedResult, 0, len(handshakes))
+	for _, handshake := range handshakes {
+		for _, password := range crackedPasswords(handshake.CrackedHandshake) {
+			results = append(results, &entities.CrackedResult{
+				HandshakeUUID: handshake.UUID,
+				SSID:          handshake.SSID,
+				BSSID:         handshake.BSSID,
+				Password:      password,
+				CrackedDate:   handshake.CrackedDate,
+				ClientUUID:    handshake.ClientUUID,
+				ClientName:    handshake.ClientName,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// crackedPasswords splits the passwords hashcat found for a handshake, the clients send them as [password],[password]
+func crackedPasswords(cracked *string) []string {
+	if cracked == nil || *cracked == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(strings.TrimPrefix(*cracked, "["), "]"), "],[")
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+	// tarMagic is found at tarMagicOffset of the first header of a POSIX or GNU tar
+	tarMagic       = []byte("ustar")
+	tarMagicOffset = 257
+)
+
+// importFile the file uploaded to ImportHandshakes or one found in its archive
+type importFile struct {
+	name    string
+	content []byte
+}
+
+// importedNetwork a network found in an imported capture or hash file, capture is what its handshake is saved with
+type importedNetwork struct {
+	ssid    string
+	bssid   string
+	hashes  []*wpaparser.Hash
+	capture []byte
+}
+
+/*
+ImportHandshakes
+
+Imports captures, 22000 hash files and hashcat potfiles, alone or in a tar, tar.gz or zip archive. A handshake is saved
+for every network found in the captures and hash files, unless the user has one with the same SSID and BSSID already.
+The plaintexts of the potfiles then crack the handshakes of the user captured from their network, the ones imported
+along with them included. The potfiles taking the entries imported past MaxPotfileEntries are refused.
+A file refused doesn't stop the others, the outcome of each one is returned
+*/
+func (uc *Usecase) ImportHandshakes(userUUID, name string, content []byte) (*entities.ImportHandshakesResponse, error) {
+	files, err := readImportFiles(name, content)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &entities.ImportHandshakesResponse{Outcomes: make([]*entities.HandshakeImportOutcome, 0, len(files))}
+	var potfiles []*importFile
+
+	for _, file := range files {
+		kind := wpaparser.FileKind(file.content)
+		if kind == wpaparser.PotFile {
+			potfiles = append(potfiles, file)
+			continue
+		}
+
+		networks, errNetworks := importNetworks(kind, file.content)
+		if errNetworks != nil {
+			addImportOutcome(response, &entities.HandshakeImportOutcome{
+				File:   file.name,
+				Kind:   kind,
+				Result: constants.FailedResult,
+				Error:  errNetworks.Error(),
+			})
+			continue
+		}
+
+		for _, network := range networks {
+			outcome := uc.importNetwork(userUUID, network)
+			outcome.File, outcome.Kind = file.name, kind
+			addImportOutcome(response, outcome)
+		}
+	}
+
+	entries := 0
+	for _, file := range potfiles {
+		plaintexts := wpaparser.ParsePotfile(file.content)
+		if entries+len(plaintexts) > constants.MaxPotfileEntries {
+			addImportOutcome(response, &entities.HandshakeImportOutcome{
+				File:   file.name,
+				Kind:   wpaparser.PotFile,
+				Result: constants.FailedResult,
+				Error:  customErrors.ErrTooManyPotfileEntries.Error(),
+			})
+			continue
+		}
+		entries += len(plaintexts)
+
+		for _, plaintext := range plaintexts {
+			for _, outcome := range uc.importPlaintext(userUUID, plaintext) {
+				outcome.File, outcome.Kind = file.name, wpaparser.PotFile
+				addImportOutcome(response, outcome)
+			}
+		}
+	}
+
+	return response, nil
+}
+
+func addImportOutcome(response *entities.ImportHandshakesResponse, outcome *entities.HandshakeImportOutcome) {
+	switch outcome.Result {
+	case constants.ImportedResult:
+		response.Imported++
+	case constants.DuplicateResult:
+		response.Duplicates++
+	case constants.CrackedResult:
+		response.Cracked++
+	case constants.UnmatchedResult:
+		response.Unmatched++
+	default:
+		response.Failed++
+	}
+	response.Outcomes = append(response.Outcomes, outcome)
+}
+
+// importNetworks groups the hashes of a capture or hash file by network. A capture of a single network is saved whole,
+// the other files are split so that each network is saved with its own 22000 lines
+func importNetworks(kind string, content []byte) ([]*importedNetwork, error) {
+	var hashes []*wpaparser.Hash
+	var err error
+
+	switch kind {
+	case wpaparser.CaptureFile:
+		hashes, err = wpaparser.CaptureHashes(content, "")
+	case wpaparser.HashFile:
+		hashes, err = wpaparser.ParseHashes(content)
+	default:
+		return nil, customErrors.ErrImportFileKind
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	networks := make([]*importedNetwork, 0)
+	seen := make(map[string]*importedNetwork)
+
+	for _, hash := range hashes {
+		key := hash.BSSID + "*" + hash.ESSID
+		network, ok := seen[key]
+		if !ok {
+			network = &importedNetwork{ssid: hash.ESSID, bssid: hash.BSSID}
+			seen[key] = network
+			networks = append(networks, network)
+		}
+		network.hashes = append(network.hashes, hash)
+	}
+
+	for _, network := range networks {
+		network.capture = content
+		if kind == wpaparser.HashFile || len(networks) > 1 {
+			network.capture = wpaparser.JoinHashes(network.hashes)
+		}
+	}
+
+	return networks, nil
+}
+
+func (uc *Usecase) importNetwork(userUUID string, network *importedNetwork) *entities.HandshakeImportOutcome {
+	outcome := &entities.HandshakeImportOutcome{SSID: network.ssid, BSSID: network.bssid}
+
+	err := uc.EnsureHandshakeNotPresent(userUUID, network.bssid, network.ssid)
+	if err == nil {
+		outcome.HandshakeUUID, err = uc.repo.CreateHandshake(userUUID, network.ssid, network.bssid,
+			constants.NothingStatus, utils.BytesToBase64String(network.capture))
+	}
+
+	switch {
+	case errors.Is(err, customErrors.ErrHandshakeAlreadyPresent):
+		outcome.Result = constants.DuplicateResult
+	case err != nil:
+		outcome.Result, outcome.Error = constants.FailedResult, err.Error()
+	default:
+		outcome.Result = constants.ImportedResult
+	}
+
+	return outcome
+}
+
+// importPlaintext cracks the handshakes of the user captured from the network of the plaintext, an outcome for each.
+// Handshakes cracked already are left as they are, as are the ones a client is working on and the ones the password
+// doesn't crack or can't be checked against. The PMK is derived only once a handshake is left to check, a potfile entry whose password doesn't
+// derive its PMK cracks none
+func (uc *Usecase) importPlaintext(userUUID string, plaintext *wpaparser.Plaintext) []*entities.HandshakeImportOutcome {
+	failed := func(err error) []*entities.HandshakeImportOutcome {
+		return []*entities.HandshakeImportOutcome{{
+			SSID:   plaintext.ESSID,
+			BSSID:  plaintext.BSSID,
+			Result: constants.FailedResult,
+			Error:  err.Error(),
+		}}
+	}
+
+	handshakes, err := uc.repo.GetHandshakesBySSID(userUUID, plaintext.ESSID)
+	if err != nil {
+		return failed(err)
+	}
+
+	var pmk []byte
+	outcomes := make([]*entities.HandshakeImportOutcome, 0)
+
+	for _, handshake := range handshakes {
+		if plaintext.BSSID != "" && !strings.EqualFold(handshake.BSSID, plaintext.BSSID) {
+			continue
+		}
+
+		outcome := &entities.HandshakeImportOutcome{SSID: handshake.SSID, BSSID: handshake.BSSID, HandshakeUUID: handshake.UUID}
+		outcomes = append(outcomes, outcome)
+
+		switch handshake.Status {
+		case constants.CrackedStatus:
+			outcome.Result = constants.DuplicateResult
+			continue
+		case constants.PendingStatus, constants.WorkingStatus:
+			outcome.Result, outcome.Error = constants.FailedResult, customErrors.ErrClientIsBusy.Error()
+			continue
+		}
+
+		if pmk == nil {
+			if pmk, err = wpaparser.PMK(plaintext.ESSID, plaintext.Password); err != nil {
+				return failed(err)
+			}
+			if plaintext.PMK != nil && !bytes.Equal(pmk, plaintext.PMK) {
+				return failed(customErrors.ErrPotfilePMKMismatch)
+			}
+		}
+
+		cracked, errCracked := uc.crackedBy(userUUID, handshake, pmk)
+		switch {
+		case errCracked != nil:
+			outcome.Result, outcome.Error = constants.FailedResult, errCracked.Error()
+			continue
+		case !cracked:
+			outcome.Result, outcome.Error = constants.FailedResult, customErrors.ErrPasswordNotCracking.Error()
+			continue
+		}
+
+		if err = uc.repo.CrackHandshake(userUUID, handshake.UUID, "["+plaintext.Password+"]"); err != nil {
+			outcome.Result, outcome.Error = constants.FailedResult, err.Error()
+			continue
+		}
+		outcome.Result = constants.CrackedResult
+	}
+
+	if len(outcomes) == 0 {
+		outcomes = append(outcomes, &entities.HandshakeImportOutcome{
+			SSID:   plaintext.ESSID,
+			BSSID:  plaintext.BSSID,
+			Result: constants.UnmatchedResult,
+			Error:  customErrors.ErrNoMatchingHandshake.Error(),
+		})
+	}
+
+	return outcomes
+}
+
+// crackedBy tells whether the PMK cracks the handshake, checking it against the hashes of its capture for the BSSID.
+// ErrPasswordUnverifiable when the capture has no hash that can be checked, a password is never saved unverified
+func (uc *Usecase) crackedBy(userUUID string, handshake *entities.Handshake, pmk []byte) (bool, error) {
+	capture, err := uc.GetHandshakeCapture(userUUID, handshake.UUID)
+	if err != nil {
+		return false, err
+	}
+
+	content, err := wpaparser.ToHC22000(capture, handshake.SSID)
+	if err != nil {
+		return false, customErrors.ErrPasswordUnverifiable
+	}
+
+	hashes, err := wpaparser.ParseHashes(content)
+	if err != nil {
+		return false, customErrors.ErrPasswordUnverifiable
+	}
+
+	checked := false
+	for _, hash := range hashes {
+		if !strings.EqualFold(hash.BSSID, handshake.BSSID) || hash.ESSID != handshake.SSID {
+			continue
+		}
+
+		cracked, ok := hash.Cracks(pmk)
+		if cracked {
+			return true, nil
+		}
+		checked = checked || ok
+	}
+
+	if !checked {
+		return false, customErrors.ErrPasswordUnverifiable
+	}
+	return false, nil
+}
+
+// readImportFiles unpacks the file uploaded to ImportHandshakes, refusing to inflate it beyond MaxImportSize.
+// A file which is not an archive is returned as it is, the archives found inside an archive are not unpacked
+func readImportFiles(name string, content []byte) ([]*importFile, error) {
+	if bytes.HasPrefix(content, gzipMagic) {
+		inflated, err := inflateImport(content)
+		if err != nil {
+			return nil, err
+		}
+		content, name = inflated, strings.TrimSuffix(name, ".gz")
+	}
+
+	var files []*importFile
+	var err error
+
+	switch {
+	case len(content) >= tarMagicOffset+len(tarMagic) && bytes.Equal(content[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		files, err = readImportTar(content)
+	case bytes.HasPrefix(content, zipMagic):
+		files, err = readImportZip(content)
+	default:
+		return []*importFile{{name: path.Base(name), content: content}}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, customErrors.ErrImportInvalid
+	}
+
+	return files, nil
+}
+
+func inflateImport(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, customErrors.ErrImportInvalid
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, constants.MaxImportSize+1))
+	if err != nil {
+		return nil, customErrors.ErrImportInvalid
+	}
+
+	if int64(len(content)) > constants.MaxImportSize {
+		return nil, customErrors.ErrUploadTooLarge
+	}
+
+	return content, nil
+}
+
+// readImportTar the archive is in memory already and within MaxImportSize, so are its files
+func readImportTar(content []byte) ([]*importFile, error) {
+	reader := tar.NewReader(bytes.NewReader(content))
+	files := make([]*importFile, 0)
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, customErrors.ErrImportInvalid
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, customErrors.ErrImportInvalid
+		}
+
+		files = append(files, &importFile{name: header.Name, content: data})
+	}
+
+	return files, nil
+}
+
+// readImportZip the files of a zip are compressed one by one, MaxImportSize bounds their total once inflated
+func readImportZip(content []byte) ([]*importFile, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, customErrors.ErrImportInvalid
+	}
+
+	files := make([]*importFile, 0, len(reader.File))
+	var total int64
+
+	for _, entry := range reader.File {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+
+		opened, errOpen := entry.Open()
+		if errOpen != nil {
+			return nil, customErrors.ErrImportInvalid
+		}
+
+		data, errRead := io.ReadAll(io.LimitReader(opened, constants.MaxImportSize-total+1))
+		_ = opened.Close()
+		if errRead != nil {
+			return nil, customErrors.ErrImportInvalid
+		}
+
+		total += int64(len(data))
+		if total > constants.MaxImportSize {
+			return nil, customErrors.ErrUploadTooLarge
+		}
+
+		files = append(files, &importFile{name: entry.Name, content: data})
+	}
+
+	return files, nil
+}
+
+// GetHandshakeLogs returns the hashcat logs of a handshake of the user or shared with one of its teams, empty until it is cracked
+func (uc *Usecase) GetHandshakeLogs(userUUID, handshakeUUID string) (string, error) {
+	logs, err := uc.repo.GetHandshakeLogs(userUUID, handshakeUUID)
+	if err != nil || logs == nil {
+		return "", err
+	}
+	return *logs, nil
+}
+
+func (uc *Usecase) GetGeotaggedHandshakes(userUUID string) ([]*entities.Handshake, error) {
+	return uc.repo.GetGeotaggedHandshakes(userUUID)
+}
+
+func (uc *Usecase) GetClientInfo(userUUID, machineID string) (*entities.Client, error) {
+	return uc.repo.GetClientInfo(userUUID, machineID)
+}
+
+func (uc *Usecase) GetHandshakesByStatus(filterStatus string) (handshakes []*entities.Handshake, length int, e error) {
+	return uc.repo.GetHandshakesByStatus(filterStatus)
+}
+func (uc *Usecase) UpdateClientTask(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake string) (*entities.Handshake, error) {
+	return uc.repo.UpdateClientTask(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake)
+}
+
+// UpdateClientTaskRest tasks are assigned only to approved clients.
+// The clients and the handshakes shared with a team can be used by its members, viewers excluded
+func (uc *Usecase) UpdateClientTaskRest(userUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake string) (*entities.Handshake, error) {
+	roles := teamRolesFrom(constants.TeamMember)
+
+	client, err := uc.repo.GetSharedClient(userUUID, assignedClientUUID, roles...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Approved {
+		return nil, customErrors.ErrClientPendingApproval
+	}
+
+	handshake, err := uc.repo.GetSharedHandshake(userUUID, handshakeUUID, roles...)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.repo.UpdateClientTaskRest(handshake.UserUUID, handshakeUUID, assignedClientUUID, status, hashcatOptions, hashcatLogs, crackedHandshake)
+}
+
+func (uc *Usecase) GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid string) (handshakes []*entities.Handshake, length int, e error) {
+	return uc.repo.GetHandshakesByBSSIDAndSSID(userUUID, bssid, ssid)
+}
+
+// DeleteClient revokes the client before deleting it with its certificate,
+// otherwise it would be registered again the next time it connects
+func (uc *Usecase) DeleteClient(userUUID, clientUUID string) (bool, error) {
+	client, err := uc.repo.GetClientByUUID(userUUID, clientUUID)
+	if err != nil {
+		return false, err
+	}
+
+	if err = uc.revoke(userUUID, constants.RevokedClient, client.MachineID, client.ClientUUID, &client.Name); err != nil {
+		return false, err
+	}
+
+	if _, err = uc.repo.DeleteClient(userUUID, clientUUID); err != nil {
+		return false, err
+	}
+
+	return true, uc.repo.DeleteCertOfClient(clientUUID)
+}
+
+// DeleteRaspberryPI revokes the device before deleting it, otherwise its next upload would create it again
+func (uc *Usecase) DeleteRaspberryPI(userUUID, rspUUID string) (bool, error) {
+	rsp, err := uc.repo.GetRaspberryPIByUUID(userUUID, rspUUID)
+	if err != nil {
+		return false, err
+	}
+
+	name := rsp.Name
+	if name == nil {
+		name = rsp.Hostname
+	}
+
+	if err = uc.revoke(userUUID, constants.RevokedRaspberryPI, rsp.MachineID, rsp.RaspberryPIUUID, name); err != nil {
+		return false, err
+	}
+
+	return uc.repo.DeleteRaspberryPI(userUUID, rspUUID)
+}
+
+// OnRevocation listener is called each time a device or a client is revoked, the servers use it for dropping its connections
+func (uc *Usecase) OnRevocation(listener func(revocation *entities.Revocation)) {
+	uc.revocationMutex.Lock()
+	defer uc.revocationMutex.Unlock()
+	uc.revocationListeners = append(uc.revocationListeners, listener)
+}
+
+// revoke records the revocation of a device or a client, then notifies the listeners
+func (uc *Usecase) revoke(userUUID, kind, machineID, entityUUID string, name *string) error {
+	revocationID, err := uc.repo.CreateRevocation(userUUID, kind, machineID, entityUUID, name)
+	if err != nil {
+		return err
+	}
+
+	log.Warnf("[REVOCATION] %s %s with machine ID %s revoked by user %s", kind, entityUUID, machineID, userUUID)
+
+	revocation := &entities.Revocation{
+		UUID:       revocationID,
+		UserUUID:   userUUID,
+		Kind:       kind,
+		MachineID:  machineID,
+		EntityUUID: entityUUID,
+		Name:       name,
+	}
+
+	uc.revocationMutex.RLock()
+	listeners := slices.Clone(uc.revocationListeners)
+	uc.revocationMutex.RUnlock()
+
+	for _, listener := range listeners {
+		listener(revocation)
+	}
+	return nil
+}
+
+// revokedError the error returned to a revoked device or client
+func revokedError(kind string) error {
+	if kind == constants.RevokedClient {
+		return customErrors.ErrClientRevoked
+	}
+	return customErrors.ErrRaspberryPIRevoked
+}
+
+// ensureNotRevoked refuses a machine ID revoked for kind
+func (uc *Usecase) ensureNotRevoked(kind, machineID string) error {
+	_, err := uc.repo.GetRevocation(kind, machineID)
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	log.Warnf("[REVOCATION] Refused revoked %s with machine ID %s", kind, machineID)
+	return revokedError(kind)
+}
+
+// EnsureNotRevoked refuses the UUID of a deleted device or client, i.e. the subject serial number of the certificates issued to it
+func (uc *Usecase) EnsureNotRevoked(entityUUID string) error {
+	revocation, err := uc.repo.GetRevocationByEntity(entityUUID)
+	switch {
+	case errors.Is(err, customErrors.ErrElementNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	log.Warnf("[REVOCATION] Refused revoked %s %s", revocation.Kind, entityUUID)
+	return revokedError(revocation.Kind)
+}
+
+func (uc *Usecase) GetRevocations(userUUID string, offset uint) ([]*entities.Revocation, int, error) {
+	return uc.repo.GetRevocationsByUserID(userUUID, offset)
+}
+
+// ApproveRevocation the device or the client can connect again, it is registered anew when it does
+func (uc *Usecase) ApproveRevocation(userUUID, revocationUUID string) error {
+	revocation, err := uc.repo.GetRevocationByUUID(userUUID, revocationUUID)
+	if err != nil {
+		return err
+	}
+
+	if err = uc.repo.DeleteRevocation(userUUID, revocationUUID); err != nil {
+		return err
+	}
+
+	log.Infof("[REVOCATION] %s with machine ID %s approved again by user %s", revocation.Kind, revocation.MachineID, userUUID)
+	return nil
+}
+
+func (uc *Usecase) DeleteHandshake(userUUID, handshakeUUID string) (bool, error) {
 	return uc.repo.DeleteHandshake(userUUID, handshakeUUID)
 }
+
+// teamRoles the roles of the team members, each one allows what the previous ones do
+var teamRoles = []string{constants.TeamViewer, constants.TeamMember, constants.TeamOwner}
+
+// teamRolesFrom returns role and the roles allowing more
+func teamRolesFrom(role string) []string {
+	return teamRoles[slices.Index(teamRoles, role):]
+}
+
+// ensureTeamRole refuses the members of the team with a role allowing less than role.
+// ErrElementNotFound when the user is not a member, so teams of others are not disclosed
+func (uc *Usecase) ensureTeamRole(userUUID, teamUUID, role string) error {
+	current, err := uc.repo.GetTeamRole(userUUID, teamUUID)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(teamRolesFrom(role), current) {
+		return customErrors.ErrTeamPermission
+	}
+	return nil
+}
+
+// ensureAnotherOwner refuses to leave the team without owners
+func (uc *Usecase) ensureAnotherOwner(teamUUID string) error {
+	owners, err := uc.repo.CountTeamOwners(teamUUID)
+	if err != nil {
+		return err
+	}
+
+	if owners <= 1 {
+		return customErrors.ErrLastTeamOwner
+	}
+	return nil
+}
+
+// CreateTeam the user creating the team becomes its owner
+func (uc *Usecase) CreateTeam(userUUID, name string) (string, error) {
+	return uc.repo.CreateTeam(userUUID, name)
+}
+
+func (uc *Usecase) GetTeams(userUUID string) ([]*entities.UserTeam, int, error) {
+	return uc.repo.GetTeamsByUserID(userUUID)
+}
+
+// GetTeamMembers the members are visible to every member of the team
+func (uc *Usecase) GetTeamMembers(userUUID, teamUUID string) ([]*entities.TeamMember, int, error) {
+	if err := uc.ensureTeamRole(userUUID, teamUUID, constants.TeamViewer); err != nil {
+		return nil, -1, err
+	}
+	return uc.repo.GetTeamMembers(teamUUID)
+}
+
+// AddTeamMember adds the user with username to the team or changes its role, owners only.
+// Owners can demote themselves as long as the team keeps another owner
+func (uc *Usecase) AddTeamMember(userUUID, teamUUID, username, role string) error {
+	if err := uc.ensureTeamRole(userUUID, teamUUID, constants.TeamOwner); err != nil {
+		return err
+	}
+
+	member, _, err := uc.repo.GetUserByUsername(username)
+	if errors.Is(err, customErrors.ErrInvalidCredentials) {
+		return customErrors.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if member.UserUUID == userUUID && role != constants.TeamOwner {
+		if err = uc.ensureAnotherOwner(teamUUID); err != nil {
+			return err
+		}
+	}
+
+	if err = uc.repo.SetTeamMember(teamUUID, member.UserUUID, role); err != nil {
+		return err
+	}
+
+	log.Infof("[TEAM] user %s added %s to team %s as %s", userUUID, member.UserUUID, teamUUID, role)
+	return nil
+}
+
+// RemoveTeamMember owners remove any member, the other members can only leave the team, memberUUID empty.
+// The team keeps at least an owner, it must be deleted otherwise
+func (uc *Usecase) RemoveTeamMember(userUUID, teamUUID, memberUUID string) error {
+	if memberUUID == "" {
+		memberUUID = userUUID
+	}
+
+	role, err := uc.repo.GetTeamRole(userUUID, teamUUID)
+	if err != nil {
+		return err
+	}
+
+	if memberUUID != userUUID && role != constants.TeamOwner {
+		return customErrors.ErrTeamPermission
+	}
+
+	memberRole, err := uc.repo.GetTeamRole(memberUUID, teamUUID)
+	if err != nil {
+		return err
+	}
+
+	if memberRole == constants.TeamOwner {
+		if err = uc.ensureAnotherOwner(teamUUID); err != nil {
+			return err
+		}
+	}
+
+	if err = uc.repo.DeleteTeamMember(teamUUID, memberUUID); err != nil {
+		return err
+	}
+
+	log.Infof("[TEAM] user %s removed %s from team %s", userUUID, memberUUID, teamUUID)
+	return nil
+}
+
+// DeleteTeam owners only, the resources shared with the team are kept by their owners
+func (uc *Usecase) DeleteTeam(userUUID, teamUUID string) error {
+	if err := uc.ensureTeamRole(userUUID, teamUUID, constants.TeamOwner); err != nil {
+		return err
+	}
+
+	if err := uc.repo.DeleteTeam(teamUUID); err != nil {
+		return err
+	}
+
+	log.Infof("[TEAM] team %s deleted by user %s", teamUUID, userUUID)
+	return nil
+}
+
+// sharedTables the tables of the kinds of resources shareable with a team
+var sharedTables = map[string]string{
+	constants.SharedClient:      entities.ClientTableName,
+	constants.SharedRaspberryPI: entities.RaspberryPiTableName,
+	constants.SharedHandshake:   entities.HandshakeTableName,
+	constants.SharedWordlist:    entities.WordlistTableName,
+}
+
+// ShareResource shares a resource of the user with a team where the user is a member, viewers excluded.
+// An empty teamUUID makes the resource personal again, the owners of its team can do it too
+func (uc *Usecase) ShareResource(userUUID, kind, resourceUUID, teamUUID string) error {
+	table, ok := sharedTables[kind]
+	if !ok {
+		return customErrors.ErrElementNotFound
+	}
+
+	if teamUUID == "" {
+		err := uc.repo.ShareResource(table, userUUID, resourceUUID, nil)
+		if errors.Is(err, customErrors.ErrElementNotFound) {
+			return uc.repo.UnshareTeamResource(table, userUUID, resourceUUID)
+		}
+		return err
+	}
+
+	if err := uc.ensureTeamRole(userUUID, teamUUID, constants.TeamMember); err != nil {
+		return err
+	}
+	return uc.repo.ShareResource(table, userUUID, resourceUUID, &teamUUID)
+}
+
+// CreateWordlist the wordlist can be shared at once with a team where the user is a member, viewers excluded
+func (uc *Usecase) CreateWordlist(userUUID, name, wordlistPath, teamUUID string) (string, error) {
+	var team *string
+	if teamUUID != "" {
+		if err := uc.ensureTeamRole(userUUID, teamUUID, constants.TeamMember); err != nil {
+			return "", err
+		}
+		team = &teamUUID
+	}
+	return uc.repo.CreateWordlist(userUUID, name, wordlistPath, team)
+}
+
+func (uc *Usecase) GetWordlists(userUUID string) ([]*entities.Wordlist, int, error) {
+	return uc.repo.GetWordlistsByUserID(userUUID)
+}
+
+// DeleteWordlist the wordlists shared with a team can be deleted by its owners too
+func (uc *Usecase) DeleteWordlist(userUUID, wordlistUUID string) error {
+	return uc.repo.DeleteWordlist(userUUID, wordlistUUID)
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+// DeviceKeyInfo is the HKDF context used when deriving the pcap encryption key of a raspberry pi.
+// The daemon uses the very same value, changing it breaks every enrolled device.
+const DeviceKeyInfo = "hds-raspberrypi-pcap-v1"
+
+// BundleKeyInfo is the HKDF context of the key signing the offline bundles written by a raspberry pi,
+// derived from its device key. The daemon uses the very same value.
+const BundleKeyInfo = "hds-raspberrypi-bundle-v1"
+
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// GenerateExchangeKey generates an ephemeral X25519 key pair used for the device key exchange
+func GenerateExchangeKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// DeriveDeviceKey computes the shared secret between our private key and the base64 encoded peer public key.
+// The secret is expanded with HKDF-SHA256 (machineID as salt) into a 32 bytes AES-256 key, returned hex encoded.
+func DeriveDeviceKey(private *ecdh.PrivateKey, peerPublicKey, machineID string) (string, error) {
+	rawPeer, err := base64.StdEncoding.DecodeString(peerPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	peer, err := ecdh.X25519().NewPublicKey(rawPeer)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := private.ECDH(peer)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, []byte(machineID), DeviceKeyInfo, 32)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+// HandshakeAdditionalData binds an encrypted payload to the device and network it was captured for,
+// so that a ciphertext cannot be replayed as the capture of another network
+func HandshakeAdditionalData(machineID, bssid, ssid string) []byte {
+	return []byte(machineID + "|" + bssid + "|" + ssid)
+}
+
+// OpenAESGCM decrypts a payload produced by SealAESGCM. The payload layout is nonce || ciphertext || tag
+func OpenAESGCM(hexKey string, payload, additionalData []byte) ([]byte, error) {
+	aead, err := newAESGCM(hexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+
+	nonce, ciphertext := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// SealAESGCM encrypts plaintext with a random nonce, which is prepended to the result
+func SealAESGCM(hexKey string, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAESGCM(hexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// SignBundle returns the hex encoded HMAC-SHA256 of the manifest of an offline bundle.
+// The HMAC key is derived from the device key, so that it is never used both for encrypting and signing
+func SignBundle(hexKey string, manifest []byte) (string, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return "", err
+	}
+
+	signingKey, err := hkdf.Key(sha256.New, key, nil, BundleKeyInfo, 32)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write(manifest)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyBundle reports whether signature is the one SignBundle returns for the manifest
+func VerifyBundle(hexKey string, manifest []byte, signature string) bool {
+	expected, err := SignBundle(hexKey, manifest)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func newAESGCM(hexKey string) (cipher.AEAD, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"strings"
 	"unicode"
 )
@@ -83,3 +84,18 @@ func GenerateToken(length int) string {
 
 	return token
 }
+
+// RemoteIP the IP of a remote address, the address itself when it has no port
+func RemoteIP(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
+
+// EnrollmentCode formats the first 8 hexadecimal digits of digest as XXXX-XXXX, the code shown by the machines waiting for approval
+func EnrollmentCode(digest string) string {
+	code := strings.ToUpper(digest[:8])
+	return code[:4] + "-" + code[4:]
+}
@@ -0,0 +1,568 @@
+package wpaparser
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net"
+	"regexp"
+	"strings"
+
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// HC22000Prefix every line of a hashcat 22000 hash file starts with it
+const HC22000Prefix = "WPA*"
+
+const (
+	pmkidHash = "01"
+	eapolHash = "02"
+
+	// messagePairM1M2 the ANonce is taken from M1, messagePairM2M3 from M3. The EAPOL is always the one of M2
+	messagePairM1M2 = "00"
+	messagePairM2M3 = "02"
+
+	// offsets in the EAPOL frame, 802.1X header included
+	eapolHeaderLength = 4
+	keyInfoOffset     = eapolHeaderLength + 1
+	nonceOffset       = eapolHeaderLength + 13
+	nonceLength       = 32
+	micOffset         = eapolHeaderLength + 77
+	micLength         = 16
+	keyDataOffset     = eapolHeaderLength + 95
+	pmkidLength       = 16
+
+	// the PMK is derived with PBKDF2-HMAC-SHA1 from the password, salted with the ESSID
+	pmkIterations = 4096
+	pmkLength     = 32
+
+	// key descriptor versions of the EAPOL-Key frames, the MIC of version 3 is an AES-CMAC
+	keyDescriptorVersionMask = 0x0007
+	keyDescriptorMD5         = 1
+	keyDescriptorSHA1        = 2
+)
+
+// Kinds of the files told apart by FileKind
+const (
+	CaptureFile = "capture"
+	HashFile    = "hashes"
+	PotFile     = "potfile"
+)
+
+var pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+// pcapMagics pcap in both byte orders, with microsecond and nanosecond timestamps, and pcapng
+var pcapMagics = [][]byte{
+	{0xd4, 0xc3, 0xb2, 0xa1},
+	{0xa1, 0xb2, 0xc3, 0xd4},
+	{0x4d, 0x3c, 0xb2, 0xa1},
+	{0xa1, 0xb2, 0x3c, 0x4d},
+	pcapngMagic,
+}
+
+var (
+	potfileEntry = regexp.MustCompile(`^([0-9a-fA-F]{64})\*([0-9a-fA-F]+):(.*)$`)
+	showEntry    = regexp.MustCompile(`^[0-9a-fA-F]{32}:([0-9a-fA-F]{12}):[0-9a-fA-F]{12}:(.*)$`)
+)
+
+// pmkidKDE precedes the PMKID in the key data of M1
+var pmkidKDE = []byte{0xdd, 0x14, 0x00, 0x0f, 0xac, 0x04}
+
+// keyMessage a message of the 4-way handshake, MACs are hex encoded without separators as hashcat wants them
+type keyMessage struct {
+	ap            string
+	station       string
+	replayCounter uint64
+	nonce         []byte
+	mic           []byte
+	frame         []byte
+	pmkid         []byte
+}
+
+type captureReader interface {
+	gopacket.PacketDataSource
+	LinkType() layers.LinkType
+}
+
+// Hash a line of a hashcat 22000 hash file, BSSID is the MAC of the access point formatted as aa:bb:cc:dd:ee:ff
+type Hash struct {
+	Line  string
+	BSSID string
+	ESSID string
+}
+
+/*
+ToHC22000
+
+Converts a pcap or pcapng capture to the hashcat 22000 format, a line for every PMKID and every EAPOL message pair found.
+Captures already in the 22000 format are returned as they are
+*/
+func ToHC22000(capture []byte, ssid string) ([]byte, error) {
+	if bytes.HasPrefix(capture, []byte(HC22000Prefix)) {
+		return capture, nil
+	}
+
+	hashes, err := CaptureHashes(capture, ssid)
+	if err != nil {
+		return nil, err
+	}
+
+	return JoinHashes(hashes), nil
+}
+
+// JoinHashes returns the content of the 22000 hash file holding the hashes
+func JoinHashes(hashes []*Hash) []byte {
+	var content bytes.Buffer
+	for _, hash := range hashes {
+		content.WriteString(hash.Line + "\n")
+	}
+	return content.Bytes()
+}
+
+// CaptureHashes returns the 22000 hashes of a pcap or pcapng capture. The ESSID is read from the beacons of the capture,
+// ssid is used for the access points that never sent one
+func CaptureHashes(capture []byte, ssid string) ([]*Hash, error) {
+	reader, err := openCapture(capture)
+	if err != nil {
+		return nil, err
+	}
+
+	essids := make(map[string]string)
+	var m1s, m2s, m3s []*keyMessage
+
+	for {
+		data, _, err := reader.ReadPacketData()
+		if err != nil {
+			break // io.EOF or a truncated capture, what has been read so far is kept
+		}
+
+		if reader.LinkType() == layers.LinkTypeIEEE802_11 {
+			data = withFCS(data)
+		}
+		packet := gopacket.NewPacket(data, reader.LinkType(), gopacket.Default)
+
+		dot11, ok := packet.Layer(layers.LayerTypeDot11).(*layers.Dot11)
+		if !ok {
+			continue
+		}
+
+		if dot11.Type == layers.Dot11TypeMgmtBeacon || dot11.Type == layers.Dot11TypeMgmtProbeResp {
+			if name := packetSSID(packet); name != "" {
+				essids[hexMAC(dot11.Address3)] = name
+			}
+			continue
+		}
+
+		msg, key := keyMessageOf(packet, dot11)
+		if msg == nil {
+			continue
+		}
+
+		switch {
+		case key.KeyACK && !key.KeyMIC:
+			msg.pmkid = findPMKID(msg.frame[keyDataOffset:])
+			m1s = append(m1s, msg)
+		case key.KeyACK && key.Install:
+			m3s = append(m3s, msg)
+		case !key.KeyACK && key.KeyMIC && !isZero(msg.nonce):
+			// M4 carries no nonce, it can't be paired
+			m2s = append(m2s, msg)
+		}
+	}
+
+	hashes := make([]*Hash, 0)
+	seen := make(map[string]bool)
+	addHash := func(ap, essid string, fields ...string) {
+		line := strings.Join(append([]string{"WPA"}, fields...), "*")
+		if essid == "" || seen[line] {
+			return
+		}
+		seen[line] = true
+		hashes = append(hashes, &Hash{Line: line, BSSID: formatMAC(ap), ESSID: essid})
+	}
+
+	essidOf := func(ap string) string {
+		if name, ok := essids[ap]; ok {
+			return name
+		}
+		return ssid
+	}
+
+	for _, m1 := range m1s {
+		if m1.pmkid == nil {
+			continue
+		}
+		essid := essidOf(m1.ap)
+		addHash(m1.ap, essid, pmkidHash, hex.EncodeToString(m1.pmkid), m1.ap, m1.station, hex.EncodeToString([]byte(essid)), "", "", "")
+	}
+
+	for _, m2 := range m2s {
+		anonce, messagePair := pairM2(m2, m1s, m3s)
+		if anonce == nil {
+			continue
+		}
+		essid := essidOf(m2.ap)
+		addHash(m2.ap, essid, eapolHash, hex.EncodeToString(m2.mic), m2.ap, m2.station, hex.EncodeToString([]byte(essid)),
+			hex.EncodeToString(anonce), hex.EncodeToString(m2.frame), messagePair)
+	}
+
+	if len(hashes) == 0 {
+		return nil, customErrors.ErrNoCrackableHandshake
+	}
+
+	return hashes, nil
+}
+
+// ParseHashes returns the hashes of a 22000 hash file, the lines which are not 22000 hashes are skipped
+func ParseHashes(content []byte) ([]*Hash, error) {
+	hashes := make([]*Hash, 0)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		fields := strings.Split(line, "*")
+		if len(fields) != 9 || fields[0] != "WPA" || (fields[1] != pmkidHash && fields[1] != eapolHash) {
+			continue
+		}
+
+		ap, errAP := hex.DecodeString(fields[3])
+		essid, errESSID := hex.DecodeString(fields[5])
+		if errAP != nil || errESSID != nil || len(ap) != 6 || len(essid) == 0 {
+			continue
+		}
+
+		hashes = append(hashes, &Hash{Line: line, BSSID: formatMAC(fields[3]), ESSID: string(essid)})
+	}
+
+	if len(hashes) == 0 {
+		return nil, customErrors.ErrNoCrackableHandshake
+	}
+
+	return hashes, nil
+}
+
+func openCapture(capture []byte) (captureReader, error) {
+	var reader captureReader
+	var err error
+
+	if bytes.HasPrefix(capture, pcapngMagic) {
+		reader, err = pcapgo.NewNgReader(bytes.NewReader(capture), pcapgo.DefaultNgReaderOptions)
+	} else {
+		reader, err = pcapgo.NewReader(bytes.NewReader(capture))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", customErrors.ErrCaptureFormat, err.Error())
+	}
+	return reader, nil
+}
+
+// keyMessageOf returns the pairwise EAPOL-Key message carried by the packet, if any
+func keyMessageOf(packet gopacket.Packet, dot11 *layers.Dot11) (*keyMessage, *layers.EAPOLKey) {
+	eapol, ok := packet.Layer(layers.LayerTypeEAPOL).(*layers.EAPOL)
+	if !ok {
+		return nil, nil
+	}
+
+	key, ok := packet.Layer(layers.LayerTypeEAPOLKey).(*layers.EAPOLKey)
+	if !ok || key.KeyType != layers.EAPOLKeyTypePairwise {
+		return nil, nil
+	}
+
+	frame := append(append([]byte{}, eapol.Contents...), eapol.Payload...)
+	length := eapolHeaderLength + int(eapol.Length)
+	if length < keyDataOffset || len(frame) < length {
+		return nil, nil
+	}
+	frame = frame[:length]
+
+	// hashcat computes the MIC over the frame with the MIC field zeroed
+	mic := append([]byte{}, frame[micOffset:micOffset+micLength]...)
+	copy(frame[micOffset:micOffset+micLength], make([]byte, micLength))
+
+	msg := &keyMessage{
+		replayCounter: key.ReplayCounter,
+		nonce:         key.Nonce,
+		mic:           mic,
+		frame:         frame,
+	}
+
+	// Address1 is the receiver and Address2 the transmitter, the access point sends the messages with the ACK bit
+	if key.KeyACK {
+		msg.ap, msg.station = hexMAC(dot11.Address2), hexMAC(dot11.Address1)
+	} else {
+		msg.ap, msg.station = hexMAC(dot11.Address1), hexMAC(dot11.Address2)
+	}
+
+	return msg, key
+}
+
+// pairM2 finds the ANonce for M2, from the M1 with the same replay counter or else from the M3 following it
+func pairM2(m2 *keyMessage, m1s, m3s []*keyMessage) ([]byte, string) {
+	for _, m1 := range m1s {
+		if m1.ap == m2.ap && m1.station == m2.station && m1.replayCounter == m2.replayCounter {
+			return m1.nonce, messagePairM1M2
+		}
+	}
+
+	for _, m3 := range m3s {
+		if m3.ap == m2.ap && m3.station == m2.station && m3.replayCounter == m2.replayCounter+1 {
+			return m3.nonce, messagePairM2M3
+		}
+	}
+
+	return nil, ""
+}
+
+// withFCS gopacket takes the last 4 bytes of an 802.11 frame as its FCS, airodump-ng writes the frames without it
+func withFCS(frame []byte) []byte {
+	if len(frame) > 4 && crc32.ChecksumIEEE(frame[:len(frame)-4]) == binary.LittleEndian.Uint32(frame[len(frame)-4:]) {
+		return frame
+	}
+	return binary.LittleEndian.AppendUint32(append([]byte{}, frame...), crc32.ChecksumIEEE(frame))
+}
+
+func findPMKID(keyData []byte) []byte {
+	index := bytes.Index(keyData, pmkidKDE)
+	if index < 0 || len(keyData) < index+len(pmkidKDE)+pmkidLength {
+		return nil
+	}
+
+	pmkid := keyData[index+len(pmkidKDE) : index+len(pmkidKDE)+pmkidLength]
+	if isZero(pmkid) {
+		return nil
+	}
+	return pmkid
+}
+
+// packetSSID hidden networks advertise an empty or zeroed SSID, those are skipped
+func packetSSID(packet gopacket.Packet) string {
+	for _, layer := range packet.Layers() {
+		element, ok := layer.(*layers.Dot11InformationElement)
+		if !ok || element.ID != layers.Dot11InformationElementIDSSID {
+			continue
+		}
+		if isZero(element.Info) {
+			return ""
+		}
+		return string(element.Info)
+	}
+	return ""
+}
+
+func hexMAC(mac []byte) string {
+	return hex.EncodeToString(mac)
+}
+
+// formatMAC formats a MAC encoded as hashcat does, as it is stored for the handshakes
+func formatMAC(hexMAC string) string {
+	mac, err := hex.DecodeString(hexMAC)
+	if err != nil {
+		return ""
+	}
+	return net.HardwareAddr(mac).String()
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Cracks
+
+Tells whether the PMK is the one of the network the hash was captured from, checking the PMKID or the MIC of M2 as hashcat does.
+checked is false when the hash can't be checked, the EAPOL frames signed with an AES-CMAC are not supported
+*/
+func (h *Hash) Cracks(pmk []byte) (cracked, checked bool) {
+	fields := strings.Split(h.Line, "*")
+	if len(fields) != 9 {
+		return false, false
+	}
+
+	expected, errHash := hex.DecodeString(fields[2])
+	ap, errAP := hex.DecodeString(fields[3])
+	station, errStation := hex.DecodeString(fields[4])
+	if errHash != nil || errAP != nil || errStation != nil {
+		return false, false
+	}
+
+	if fields[1] == pmkidHash {
+		mac := hmac.New(sha1.New, pmk)
+		mac.Write([]byte("PMK Name"))
+		mac.Write(ap)
+		mac.Write(station)
+		return hmac.Equal(mac.Sum(nil)[:pmkidLength], expected), true
+	}
+
+	anonce, errANonce := hex.DecodeString(fields[6])
+	eapol, errEAPOL := hex.DecodeString(fields[7])
+	if errANonce != nil || errEAPOL != nil || len(eapol) < keyDataOffset {
+		return false, false
+	}
+
+	kck := pairwiseKey(pmk, ap, station, anonce, eapol[nonceOffset:nonceOffset+nonceLength])[:16]
+
+	var mac hash.Hash
+	switch binary.BigEndian.Uint16(eapol[keyInfoOffset:]) & keyDescriptorVersionMask {
+	case keyDescriptorMD5:
+		mac = hmac.New(md5.New, kck)
+	case keyDescriptorSHA1:
+		mac = hmac.New(sha1.New, kck)
+	default:
+		return false, false
+	}
+
+	mac.Write(eapol)
+	return hmac.Equal(mac.Sum(nil)[:micLength], expected), true
+}
+
+// pairwiseKey derives the PTK with the PRF-512 of 802.11i, the first 16 bytes are the key the MIC is signed with
+func pairwiseKey(pmk, ap, station, anonce, snonce []byte) []byte {
+	data := append(sortedPair(ap, station), sortedPair(anonce, snonce)...)
+
+	var ptk []byte
+	for i := byte(0); len(ptk) < 64; i++ {
+		mac := hmac.New(sha1.New, pmk)
+		mac.Write([]byte("Pairwise key expansion"))
+		mac.Write([]byte{0})
+		mac.Write(data)
+		mac.Write([]byte{i})
+		ptk = mac.Sum(ptk)
+	}
+	return ptk[:64]
+}
+
+func sortedPair(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return append(append([]byte{}, a...), b...)
+}
+
+// PMK derives the PMK of the network from the password
+func PMK(essid, password string) ([]byte, error) {
+	return pbkdf2.Key(sha1.New, password, []byte(essid), pmkIterations, pmkLength)
+}
+
+// PotfileEntry returns the line hashcat writes to its potfile once a 22000 hash of the network is cracked,
+// hashcat keys them by PMK and ESSID so that the entry matches every capture of the network
+func PotfileEntry(essid, password string) (string, error) {
+	pmk, err := PMK(essid, password)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s*%s:%s", hex.EncodeToString(pmk), hex.EncodeToString([]byte(essid)), potfilePlain(password)), nil
+}
+
+// potfilePlain hashcat writes the passwords having bytes which are not printable as $HEX[...]
+func potfilePlain(password string) string {
+	for _, b := range []byte(password) {
+		if b < 0x20 || b > 0x7e {
+			return "$HEX[" + hex.EncodeToString([]byte(password)) + "]"
+		}
+	}
+	return password
+}
+
+// Plaintext a password recovered by hashcat for a network, BSSID is known only for the entries of hashcat --show
+// and PMK only for the ones of the potfile
+type Plaintext struct {
+	ESSID    string
+	BSSID    string
+	Password string
+	PMK      []byte
+}
+
+/*
+ParsePotfile
+
+Returns the plaintexts of the 22000 entries of a hashcat potfile, PMK*ESSID:password as hashcat stores them,
+or MIC:AP:STA:ESSID:password as hashcat --show prints them. The entries of other hash modes are skipped
+*/
+func ParsePotfile(content []byte) []*Plaintext {
+	plaintexts := make([]*Plaintext, 0)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if match := potfileEntry.FindStringSubmatch(line); match != nil {
+			pmk, errPMK := hex.DecodeString(match[1])
+			essid, err := hex.DecodeString(match[2])
+			if errPMK != nil || err != nil || len(essid) == 0 {
+				continue
+			}
+			plaintexts = append(plaintexts, &Plaintext{ESSID: string(essid), Password: potfileDecode(match[3]), PMK: pmk})
+			continue
+		}
+
+		// the ESSID is printed as it is, it is taken up to the first colon since the password may have some
+		if match := showEntry.FindStringSubmatch(line); match != nil {
+			essid, password, ok := strings.Cut(match[2], ":")
+			if !ok || essid == "" {
+				continue
+			}
+			plaintexts = append(plaintexts, &Plaintext{
+				ESSID:    potfileDecode(essid),
+				BSSID:    formatMAC(match[1]),
+				Password: potfileDecode(password),
+			})
+		}
+	}
+
+	return plaintexts
+}
+
+// potfileDecode decodes the $HEX[...] notation of potfilePlain
+func potfileDecode(plain string) string {
+	if !strings.HasPrefix(plain, "$HEX[") || !strings.HasSuffix(plain, "]") {
+		return plain
+	}
+
+	decoded, err := hex.DecodeString(plain[len("$HEX[") : len(plain)-1])
+	if err != nil {
+		return plain
+	}
+	return string(decoded)
+}
+
+/*
+FileKind
+
+Tells whether the content is a capture, a 22000 hash file or a potfile with 22000 entries from its first bytes.
+An empty string is returned for anything else
+*/
+func FileKind(content []byte) string {
+	for _, magic := range pcapMagics {
+		if bytes.HasPrefix(content, magic) {
+			return CaptureFile
+		}
+	}
+
+	if bytes.HasPrefix(content, []byte(HC22000Prefix)) {
+		return HashFile
+	}
+
+	firstLine, _, _ := bytes.Cut(content, []byte("\n"))
+	firstLine = bytes.TrimRight(firstLine, "\r")
+	if potfileEntry.Match(firstLine) || showEntry.Match(firstLine) {
+		return PotFile
+	}
+
+	return ""
+}
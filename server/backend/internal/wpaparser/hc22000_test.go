@@ -0,0 +1,427 @@
+// nolint all
+package wpaparser_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	customErrors "github.com/Virgula0/progetto-dp/server/backend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/backend/internal/wpaparser"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	keyInfoM1 = 0x008a // pairwise, ACK
+	keyInfoM2 = 0x010a // pairwise, MIC
+	keyInfoM3 = 0x13ca // pairwise, install, ACK, MIC, secure, encrypted key data
+	keyInfoM4 = 0x030a // pairwise, MIC, secure
+)
+
+// network a 4-way handshake between an access point and a station, computed as they would from the password
+type network struct {
+	essid    string
+	password string
+	ap       []byte
+	station  []byte
+	anonce   []byte
+	snonce   []byte
+}
+
+func (n *network) pmk() []byte {
+	pmk, err := pbkdf2.Key(sha1.New, n.password, []byte(n.essid), 4096, 32)
+	if err != nil {
+		panic(err)
+	}
+	return pmk
+}
+
+// kck the first 16 bytes of the PTK, derived with the PRF-512 of 802.11i
+func (n *network) kck() []byte {
+	data := append(minMax(n.ap, n.station), minMax(n.anonce, n.snonce)...)
+
+	var ptk []byte
+	for i := byte(0); len(ptk) < 64; i++ {
+		mac := hmac.New(sha1.New, n.pmk())
+		mac.Write([]byte("Pairwise key expansion"))
+		mac.Write([]byte{0})
+		mac.Write(data)
+		mac.Write([]byte{i})
+		ptk = mac.Sum(ptk)
+	}
+	return ptk[:16]
+}
+
+func (n *network) pmkid() []byte {
+	mac := hmac.New(sha1.New, n.pmk())
+	mac.Write([]byte("PMK Name"))
+	mac.Write(n.ap)
+	mac.Write(n.station)
+	return mac.Sum(nil)[:16]
+}
+
+// mic signs the EAPOL frame, given with the MIC zeroed, as key descriptor version 2 does
+func (n *network) mic(eapol []byte) []byte {
+	mac := hmac.New(sha1.New, n.kck())
+	mac.Write(eapol)
+	return mac.Sum(nil)[:16]
+}
+
+func minMax(a, b []byte) []byte {
+	if bytes.Compare(a, b) < 0 {
+		return append(append([]byte{}, a...), b...)
+	}
+	return append(append([]byte{}, b...), a...)
+}
+
+func (n *network) beacon() []byte {
+	frame := []byte{0x80, 0x00, 0x00, 0x00}
+	frame = append(frame, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	frame = append(frame, n.ap...)
+	frame = append(frame, n.ap...)
+	frame = append(frame, 0x00, 0x00)
+
+	frame = append(frame, make([]byte, 8)...)       // timestamp
+	frame = append(frame, 0x64, 0x00, 0x11, 0x04)   // beacon interval, capabilities
+	frame = append(frame, 0x00, byte(len(n.essid))) // SSID element
+	frame = append(frame, []byte(n.essid)...)
+	return frame
+}
+
+// eapolKey returns the EAPOL-Key frame with the MIC zeroed
+func eapolKey(keyInfo uint16, replayCounter uint64, nonce, keyData []byte) []byte {
+	body := []byte{0x02} // RSN key descriptor
+	body = binary.BigEndian.AppendUint16(body, keyInfo)
+	body = binary.BigEndian.AppendUint16(body, 16)
+	body = binary.BigEndian.AppendUint64(body, replayCounter)
+	body = append(body, nonce...)
+	body = append(body, make([]byte, 16+8+8+16)...) // IV, RSC, reserved, MIC
+	body = binary.BigEndian.AppendUint16(body, uint16(len(keyData)))
+	body = append(body, keyData...)
+
+	frame := []byte{0x02, 0x03}
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(body)))
+	return append(frame, body...)
+}
+
+// signed returns the EAPOL-Key frame with the MIC set
+func (n *network) signed(eapol []byte) []byte {
+	frame := append([]byte{}, eapol...)
+	copy(frame[81:97], n.mic(eapol))
+	return frame
+}
+
+// dataFrame wraps the EAPOL frame in an 802.11 data frame, from the access point when fromAP or else to it
+func (n *network) dataFrame(fromAP bool, eapol []byte) []byte {
+	var frame []byte
+	if fromAP {
+		frame = append([]byte{0x08, 0x02, 0x00, 0x00}, n.station...)
+		frame = append(frame, n.ap...)
+	} else {
+		frame = append([]byte{0x08, 0x01, 0x00, 0x00}, n.ap...)
+		frame = append(frame, n.station...)
+	}
+	frame = append(frame, n.ap...)
+	frame = append(frame, 0x00, 0x00)
+
+	frame = append(frame, 0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00, 0x88, 0x8e) // LLC/SNAP
+	return append(frame, eapol...)
+}
+
+func (n *network) m1(withPMKID bool) []byte {
+	var keyData []byte
+	if withPMKID {
+		keyData = append([]byte{0xdd, 0x14, 0x00, 0x0f, 0xac, 0x04}, n.pmkid()...)
+	}
+	return n.dataFrame(true, eapolKey(keyInfoM1, 1, n.anonce, keyData))
+}
+
+// m2Key the EAPOL frame of M2 with the MIC zeroed, the one hashcat wants in the 22000 line
+func (n *network) m2Key() []byte {
+	rsn := []byte{0x30, 0x14, 0x01, 0x00, 0x00, 0x0f, 0xac, 0x04, 0x01, 0x00, 0x00, 0x0f, 0xac, 0x04, 0x01, 0x00, 0x00, 0x0f, 0xac, 0x02, 0x00, 0x00}
+	return eapolKey(keyInfoM2, 1, n.snonce, rsn)
+}
+
+func (n *network) m2() []byte {
+	return n.dataFrame(false, n.signed(n.m2Key()))
+}
+
+func (n *network) m3() []byte {
+	return n.dataFrame(true, n.signed(eapolKey(keyInfoM3, 2, n.anonce, bytes.Repeat([]byte{0x5a}, 56))))
+}
+
+func (n *network) m4() []byte {
+	return n.dataFrame(false, n.signed(eapolKey(keyInfoM4, 2, make([]byte, 32), nil)))
+}
+
+func (n *network) pmkidLine() string {
+	return fmt.Sprintf("WPA*01*%x*%x*%x*%x***", n.pmkid(), n.ap, n.station, n.essid)
+}
+
+func (n *network) eapolLine(messagePair string) string {
+	return fmt.Sprintf("WPA*02*%x*%x*%x*%x*%x*%x*%s", n.mic(n.m2Key()), n.ap, n.station, n.essid, n.anonce, n.m2Key(), messagePair)
+}
+
+// cracks tells whether the password is the one of the 22000 line, checking it as hashcat does
+func cracks(line, password string) bool {
+	fields := strings.Split(line, "*")
+	hash, _ := hex.DecodeString(fields[2])
+	ap, _ := hex.DecodeString(fields[3])
+	station, _ := hex.DecodeString(fields[4])
+	essid, _ := hex.DecodeString(fields[5])
+	n := &network{essid: string(essid), password: password, ap: ap, station: station}
+
+	if fields[1] == "01" {
+		return hmac.Equal(n.pmkid(), hash)
+	}
+
+	eapol, _ := hex.DecodeString(fields[7])
+	n.anonce, _ = hex.DecodeString(fields[6])
+	n.snonce = eapol[17:49]
+	return hmac.Equal(n.mic(eapol), hash)
+}
+
+// pcapCapture writes the frames without FCS, as airodump-ng does
+func pcapCapture(frames ...[]byte) []byte {
+	var capture bytes.Buffer
+	writer := pcapgo.NewWriter(&capture)
+	if err := writer.WriteFileHeader(65535, layers.LinkTypeIEEE802_11); err != nil {
+		panic(err)
+	}
+
+	for _, frame := range frames {
+		err := writer.WritePacket(gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(frame), Length: len(frame)}, frame)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return capture.Bytes()
+}
+
+// pcapngCapture writes the frames with their FCS
+func pcapngCapture(frames ...[]byte) []byte {
+	var capture bytes.Buffer
+	writer, err := pcapgo.NewNgWriter(&capture, layers.LinkTypeIEEE802_11)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, frame := range frames {
+		frame = binary.LittleEndian.AppendUint32(append([]byte{}, frame...), crc32.ChecksumIEEE(frame))
+		err = writer.WritePacket(gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(frame), Length: len(frame)}, frame)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		panic(err)
+	}
+	return capture.Bytes()
+}
+
+type HC22000TestSuite struct {
+	suite.Suite
+	network *network
+}
+
+// Run All tests
+func TestHC22000(t *testing.T) {
+	suite.Run(t, new(HC22000TestSuite))
+}
+
+func (s *HC22000TestSuite) SetupTest() {
+	s.network = &network{
+		essid:    "hc22000 test",
+		password: "password",
+		ap:       []byte{0x0a, 0x00, 0x00, 0x00, 0x00, 0x01},
+		station:  []byte{0x0a, 0x00, 0x00, 0x00, 0x00, 0x02},
+		anonce:   bytes.Repeat([]byte{0xa1}, 32),
+		snonce:   bytes.Repeat([]byte{0x5b}, 32),
+	}
+}
+
+func (s *HC22000TestSuite) lines(hashes []*wpaparser.Hash) []string {
+	lines := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		s.Require().Equal("0a:00:00:00:00:01", hash.BSSID)
+		s.Require().Equal(s.network.essid, hash.ESSID)
+		lines = append(lines, hash.Line)
+	}
+	return lines
+}
+
+func (s *HC22000TestSuite) Test_CaptureHashes() {
+	n := s.network
+
+	s.Run("PMKID of M1", func() {
+		hashes, err := wpaparser.CaptureHashes(pcapCapture(n.beacon(), n.m1(true)), "")
+		s.Require().NoError(err)
+		s.Require().Equal([]string{n.pmkidLine()}, s.lines(hashes))
+		s.Require().True(cracks(hashes[0].Line, n.password))
+		s.Require().False(cracks(hashes[0].Line, "wrong password"))
+	})
+
+	s.Run("M1 and M2 are paired", func() {
+		hashes, err := wpaparser.CaptureHashes(pcapCapture(n.beacon(), n.m1(false), n.m2()), "")
+		s.Require().NoError(err)
+		s.Require().Equal([]string{n.eapolLine("00")}, s.lines(hashes))
+		s.Require().True(cracks(hashes[0].Line, n.password))
+		s.Require().False(cracks(hashes[0].Line, "wrong password"))
+	})
+
+	s.Run("M2 and M3 are paired when M1 is missing", func() {
+		hashes, err := wpaparser.CaptureHashes(pcapCapture(n.beacon(), n.m2(), n.m3(), n.m4()), "")
+		s.Require().NoError(err)
+		s.Require().Equal([]string{n.eapolLine("02")}, s.lines(hashes))
+		s.Require().True(cracks(hashes[0].Line, n.password))
+	})
+
+	s.Run("Whole handshake gives the PMKID and the EAPOL", func() {
+		frames := [][]byte{n.beacon(), n.m1(true), n.m2(), n.m3(), n.m4()}
+
+		hashes, err := wpaparser.CaptureHashes(pcapCapture(frames...), "")
+		s.Require().NoError(err)
+		s.Require().Equal([]string{n.pmkidLine(), n.eapolLine("00")}, s.lines(hashes))
+
+		ngHashes, err := wpaparser.CaptureHashes(pcapngCapture(frames...), "")
+		s.Require().NoError(err)
+		s.Require().Equal(s.lines(hashes), s.lines(ngHashes))
+	})
+
+	s.Run("ESSID is taken from the argument without beacons", func() {
+		hashes, err := wpaparser.CaptureHashes(pcapngCapture(n.m1(false), n.m2()), n.essid)
+		s.Require().NoError(err)
+		s.Require().Equal([]string{n.eapolLine("00")}, s.lines(hashes))
+
+		_, err = wpaparser.CaptureHashes(pcapngCapture(n.m1(false), n.m2()), "")
+		s.Require().ErrorIs(err, customErrors.ErrNoCrackableHandshake)
+	})
+
+	s.Run("Unpaired messages are not crackable", func() {
+		_, err := wpaparser.CaptureHashes(pcapCapture(n.beacon(), n.m1(false), n.m4()), "")
+		s.Require().ErrorIs(err, customErrors.ErrNoCrackableHandshake)
+	})
+
+	s.Run("Captures of unknown formats are refused", func() {
+		_, err := wpaparser.CaptureHashes([]byte("not a capture"), "")
+		s.Require().ErrorIs(err, customErrors.ErrCaptureFormat)
+	})
+}
+
+func (s *HC22000TestSuite) Test_ToHC22000() {
+	n := s.network
+
+	s.Run("Capture is converted", func() {
+		content, err := wpaparser.ToHC22000(pcapngCapture(n.beacon(), n.m1(true), n.m2()), "")
+		s.Require().NoError(err)
+		s.Require().Equal(n.pmkidLine()+"\n"+n.eapolLine("00")+"\n", string(content))
+
+		hashes, err := wpaparser.ParseHashes(content)
+		s.Require().NoError(err)
+		s.Require().Equal([]string{n.pmkidLine(), n.eapolLine("00")}, s.lines(hashes))
+	})
+
+	s.Run("Hash files are returned as they are", func() {
+		content := []byte(n.eapolLine("02") + "\n")
+		converted, err := wpaparser.ToHC22000(content, "")
+		s.Require().NoError(err)
+		s.Require().Equal(content, converted)
+	})
+
+	// a real capture, radiotap headers included, of a handshake whose M1 has no PMKID
+	s.Run("Capture of a device", func() {
+		capture, err := os.ReadFile("testdata/test.pcap")
+		s.Require().NoError(err)
+
+		hashes, err := wpaparser.CaptureHashes(capture, "")
+		s.Require().NoError(err)
+
+		expected := []string{
+			"WPA*02*d31bf162210b5b67164026270528d855*e48f347db525*ea2e5fc390fe*566f6461666f6e652d413630383138383033*d598a8f7cb4fb60b8d031a5b82aabd1b984b6f115dead435c3efabaadaaf8bca*0203007502010a00100000000000000007772b635d36ef5c0b847f248c2df66a1133c357f0e4cc1da61fd6df2ecddcc230000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001630140100000fac040100000fac040100000fac020c00*00",
+			"WPA*02*2af2345671af120660920cd60cd7fa22*e48f347db525*ea2e5fc390fe*566f6461666f6e652d413630383138383033*000b122ef47bbc69b27956a935fa73e8ffc0132fe5a5719d3c294677b147fbe2*0203007502010a001000000000000000000c24dc72b480530dd5509a848919d417467a2dc9e781440fbdc70520d7ad2fcd000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001630140100000fac040100000fac040100000fac020c00*00",
+		}
+		for i, hash := range hashes {
+			s.Require().Equal(expected[i], hash.Line)
+			s.Require().Equal("e4:8f:34:7d:b5:25", hash.BSSID)
+			s.Require().Equal("Vodafone-A60818803", hash.ESSID)
+		}
+		s.Require().Len(hashes, len(expected))
+	})
+}
+
+func (s *HC22000TestSuite) Test_Cracks() {
+	n := s.network
+	pmk, err := wpaparser.PMK(n.essid, n.password)
+	s.Require().NoError(err)
+
+	wrong, err := wpaparser.PMK(n.essid, "wrong password")
+	s.Require().NoError(err)
+
+	hashes, err := wpaparser.CaptureHashes(pcapCapture(n.beacon(), n.m1(true), n.m2(), n.m3()), "")
+	s.Require().NoError(err)
+	s.Require().Len(hashes, 2)
+
+	for _, hash := range hashes {
+		cracked, checked := hash.Cracks(pmk)
+		s.Require().True(checked)
+		s.Require().True(cracked)
+
+		cracked, checked = hash.Cracks(wrong)
+		s.Require().True(checked)
+		s.Require().False(cracked)
+	}
+
+	s.Run("Another access point of the network", func() {
+		other := *n
+		other.ap = []byte{0x0a, 0x00, 0x00, 0x00, 0x00, 0x03}
+		other.password = "another password"
+
+		hashes, err := wpaparser.CaptureHashes(pcapCapture(other.beacon(), other.m1(true), other.m2()), "")
+		s.Require().NoError(err)
+
+		for _, hash := range hashes {
+			cracked, checked := hash.Cracks(pmk)
+			s.Require().True(checked)
+			s.Require().False(cracked)
+		}
+	})
+
+	s.Run("AES-CMAC MICs can't be checked", func() {
+		line := strings.Replace(n.eapolLine("00"), "*0203007502010a", "*0203007502010b", 1)
+		hashes, err := wpaparser.ParseHashes([]byte(line))
+		s.Require().NoError(err)
+
+		_, checked := hashes[0].Cracks(pmk)
+		s.Require().False(checked)
+	})
+}
+
+func (s *HC22000TestSuite) Test_ParsePotfile() {
+	n := s.network
+	pmk, err := wpaparser.PMK(n.essid, n.password)
+	s.Require().NoError(err)
+
+	entry, err := wpaparser.PotfileEntry(n.essid, n.password)
+	s.Require().NoError(err)
+	s.Require().Equal(fmt.Sprintf("%x*%x:%s", pmk, n.essid, n.password), entry)
+
+	show := fmt.Sprintf("%x:%x:%x:%s:%s", n.mic(n.m2Key()), n.ap, n.station, n.essid, n.password)
+
+	plaintexts := wpaparser.ParsePotfile([]byte(entry + "\n" + show + "\n"))
+	s.Require().Equal([]*wpaparser.Plaintext{
+		{ESSID: n.essid, Password: n.password, PMK: pmk},
+		{ESSID: n.essid, BSSID: "0a:00:00:00:00:01", Password: n.password},
+	}, plaintexts)
+}
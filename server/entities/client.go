@@ -3,14 +3,17 @@ package entities
 const ClientTableName = "client"
 
 type Client struct {
-	UserUUID             string `db:"UUID_USER"`
-	ClientUUID           string `db:"UUID"`
-	Name                 string `db:"NAME"`
-	LatestIP             string `db:"LATEST_IP"`
-	CreationTime         string `db:"CREATION_DATETIME"`
-	LatestConnectionTime string `db:"LATEST_CONNECTION"`
-	MachineID            string `db:"MACHINE_ID"`
-	EnabledEncryption    bool   `db:"ENABLED_ENCRYPTION"`
+	UserUUID             string  `db:"UUID_USER"`
+	ClientUUID           string  `db:"UUID"`
+	Name                 string  `db:"NAME"`
+	LatestIP             string  `db:"LATEST_IP"`
+	CreationTime         string  `db:"CREATION_DATETIME"`
+	LatestConnectionTime string  `db:"LATEST_CONNECTION"`
+	MachineID            string  `db:"MACHINE_ID"`
+	EnabledEncryption    bool    `db:"ENABLED_ENCRYPTION"`
+	Approved             bool    `db:"APPROVED"`
+	EnrollmentCode       string  `db:"ENROLLMENT_CODE"`
+	TeamUUID             *string `db:"UUID_TEAM"`
 }
 
 type ReturnClientsInstalledResponse struct {
@@ -27,6 +30,16 @@ type DeleteClientResponse struct {
 	Status bool `json:"status"`
 }
 
+// ApproveClientRequest a client rejected is deleted and refused as the ones deleted by the user
+type ApproveClientRequest struct {
+	ClientUUID string `json:"client_id" validate:"required"`
+	Approve    *bool  `json:"approve" validate:"required"`
+}
+
+type ApproveClientResponse struct {
+	Status bool `json:"status"`
+}
+
 type UpdateEncryptionClientStatusRequest struct {
 	ClientUUID string `json:"clientUUID" validate:"required,uuid4"`
 	Status     *bool  `json:"status" validate:"required"`
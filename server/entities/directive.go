@@ -0,0 +1,73 @@
+package entities
+
+const DirectiveTableName = "directive"
+
+// Directive a change requested to a raspberry pi, it is delivered each time the device checks in until acknowledged.
+// Parameters is DirectiveParameters as JSON, Result is what the device reported when acknowledging it
+type Directive struct {
+	UUID             string  `db:"UUID"`
+	UserUUID         string  `db:"UUID_USER"`
+	RaspberryPIUUID  string  `db:"UUID_RASPBERRY_PI"`
+	Kind             string  `db:"KIND"`
+	Parameters       string  `db:"PARAMETERS"`
+	Status           string  `db:"STATUS"`
+	Result           *string `db:"RESULT"`
+	CreatedDate      string  `db:"CREATED_DATE"`
+	AcknowledgedDate *string `db:"ACKNOWLEDGED_DATE"`
+}
+
+// DirectiveParameters schedule uses Interval (e.g. 10m) and Windows (e.g. 22:00-06:00), add_watch uses Path, Recursive,
+// Include and Exclude as the watch directories of the daemon configuration, remove_watch uses Path. The others have none
+type DirectiveParameters struct {
+	Interval  string   `json:"interval,omitempty"`
+	Windows   []string `json:"windows,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	Recursive bool     `json:"recursive,omitempty"`
+	Include   []string `json:"include,omitempty"`
+	Exclude   []string `json:"exclude,omitempty"`
+}
+
+type ReturnRaspberryPIDirectivesRequest struct {
+	RaspberryPIUUID string `query:"uuid" validate:"required"`
+}
+
+type ReturnRaspberryPIDirectivesResponse struct {
+	Length     int          `json:"length"`
+	Directives []*Directive `json:"directives"`
+}
+
+type CreateRaspberryPIDirectiveRequest struct {
+	RaspberryPIUUID string              `json:"raspberry_piuuid" validate:"required"`
+	Kind            string              `json:"kind" validate:"required,oneof=schedule add_watch remove_watch purge reauthenticate upload_logs"`
+	Parameters      DirectiveParameters `json:"parameters"`
+}
+
+type CreateRaspberryPIDirectiveResponse struct {
+	DirectiveUUID string `json:"directive_uuid"`
+}
+
+type CancelRaspberryPIDirectiveRequest struct {
+	DirectiveUUID string `json:"directive_uuid" validate:"required"`
+}
+
+type CancelRaspberryPIDirectiveResponse struct {
+	Status bool `json:"status"`
+}
+
+// RaspberryPIDirective a directive as delivered to the device
+type RaspberryPIDirective struct {
+	UUID       string               `json:"uuid"`
+	Kind       string               `json:"kind"`
+	Parameters *DirectiveParameters `json:"parameters"`
+}
+
+type RaspberryPIDirectivesResponse struct {
+	Directives []*RaspberryPIDirective `json:"directives"`
+}
+
+// RaspberryPIDirectiveAckRequest Applied tells whether the device applied the directive, Result what happened or why it failed
+type RaspberryPIDirectiveAckRequest struct {
+	DirectiveUUID string `json:"directive_uuid" validate:"required"`
+	Applied       bool   `json:"applied"`
+	Result        string `json:"result"`
+}
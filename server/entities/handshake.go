@@ -1,26 +1,71 @@
 package entities
 
+import "time"
+
 const HandshakeTableName = "handshake"
 
-// Pointers in stracture is to deal with NULL data binding when parsing the rows while querying
+// Pointers in stracture is to deal with NULL data binding when parsing the rows while querying.
+// The listings leave HashcatLogs and HandshakePCAP out, they are downloaded from the endpoints of the handshake
 type Handshake struct {
-	UserUUID         string  `db:"UUID_USER"`
-	ClientUUID       *string `db:"UUID_ASSIGNED_CLIENT"`
-	UUID             string  `db:"UUID"`
-	SSID             string  `db:"SSID"`
-	BSSID            string  `db:"BSSID"`
-	UploadedDate     string  `db:"UPLOADED_DATE"`
-	Status           string  `db:"STATUS"`
-	CrackedDate      *string `db:"CRACKED_DATE"`
-	HashcatOptions   *string `db:"HASHCAT_OPTIONS"`
-	HashcatLogs      *string `db:"HASHCAT_LOGS"`
-	CrackedHandshake *string `db:"CRACKED_HANDSHAKE"`
-	HandshakePCAP    *string `db:"HANDSHAKE_PCAP"`
+	UserUUID         string   `db:"UUID_USER"`
+	ClientUUID       *string  `db:"UUID_ASSIGNED_CLIENT"`
+	UUID             string   `db:"UUID"`
+	SSID             string   `db:"SSID"`
+	BSSID            string   `db:"BSSID"`
+	UploadedDate     string   `db:"UPLOADED_DATE"`
+	Status           string   `db:"STATUS"`
+	CrackedDate      *string  `db:"CRACKED_DATE"`
+	HashcatOptions   *string  `db:"HASHCAT_OPTIONS"`
+	HashcatLogs      *string  `db:"HASHCAT_LOGS" json:",omitempty"`
+	CrackedHandshake *string  `db:"CRACKED_HANDSHAKE"`
+	HandshakePCAP    *string  `db:"HANDSHAKE_PCAP" json:",omitempty"`
+	RaspberryPIUUID  *string  `db:"UUID_RASPBERRY_PI"`
+	Latitude         *float64 `db:"LATITUDE"`
+	Longitude        *float64 `db:"LONGITUDE"`
+	PositionDate     *string  `db:"POSITION_DATE"`
+	TeamUUID         *string  `db:"UUID_TEAM"`
+	Quality          *string  `db:"QUALITY"` // classification of the capture made by the daemon, nil when unknown
+}
+
+// Position where a handshake was captured, Date is when the GPS fix was taken
+type Position struct {
+	Latitude  float64   `validate:"gte=-90,lte=90"`
+	Longitude float64   `validate:"gte=-180,lte=180"`
+	Date      time.Time `validate:"required"`
+}
+
+// HandshakeFilter query parameters of the handshakes listing, the dates are inclusive.
+// Pages are requested either by number or by the cursor returned with the previous page,
+// the latter keeps its place while handshakes are uploaded or deleted. A cursor carries its sort and order
+type HandshakeFilter struct {
+	Page            uint   `query:"page" validate:"omitempty,min=1"`
+	Cursor          string `query:"cursor"`
+	PageSize        uint   `query:"page_size" validate:"omitempty,min=1,max=100"`
+	Status          string `query:"status" validate:"omitempty,oneof=nothing pending working cracked exhausted error"`
+	Search          string `query:"search" validate:"max=300"` // substring of the SSID or of the BSSID
+	ClientUUID      string `query:"client"`
+	RaspberryPIUUID string `query:"device"`
+	Quality         string `query:"quality" validate:"omitempty,oneof=complete partial missing unknown"`
+	UploadedFrom    string `query:"uploaded_from" validate:"omitempty,datetime=2006-01-02"`
+	UploadedTo      string `query:"uploaded_to" validate:"omitempty,datetime=2006-01-02"`
+	CrackedFrom     string `query:"cracked_from" validate:"omitempty,datetime=2006-01-02"`
+	CrackedTo       string `query:"cracked_to" validate:"omitempty,datetime=2006-01-02"`
+	Sort            string `query:"sort" validate:"omitempty,oneof=uploaded_date cracked_date ssid bssid status"`
+	Order           string `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+// HandshakeCursor position of the last handshake of a page, Value is its sort key
+type HandshakeCursor struct {
+	Sort  string `json:"sort"`
+	Order string `json:"order"`
+	Value string `json:"value"`
+	UUID  string `json:"uuid"`
 }
 
 type GetHandshakeResponse struct {
 	Length     int `json:"length"`
 	Handshakes []*Handshake
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type UpdateHandshakeTaskViaAPIResponse struct {
@@ -50,3 +95,118 @@ type CreateHandshakeRequest struct {
 type CreateHandshakeResponse struct {
 	HandshakeID string `json:"handshake_id"`
 }
+
+// HandshakeLocations GeoJSON FeatureCollection of the handshakes captured with a position
+type HandshakeLocations struct {
+	Type     string               `json:"type"`
+	Features []*HandshakeLocation `json:"features"`
+}
+
+// HandshakeLocation GeoJSON Feature, coordinates are longitude and latitude in this order
+type HandshakeLocation struct {
+	Type       string                      `json:"type"`
+	Geometry   GeoJSONPoint                `json:"geometry"`
+	Properties HandshakeLocationProperties `json:"properties"`
+}
+
+type GeoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type HandshakeLocationProperties struct {
+	UUID         string `json:"uuid"`
+	SSID         string `json:"ssid"`
+	BSSID        string `json:"bssid"`
+	Status       string `json:"status"`
+	UploadedDate string `json:"uploaded_date"`
+	PositionDate string `json:"position_date"`
+}
+
+// ExportedHandshake a handshake with the name of the client assigned to it, ClientName is nil when there is none
+type ExportedHandshake struct {
+	*Handshake
+	ClientName *string
+}
+
+// HandshakeExportRequest UUIDs is a comma separated list of the handshakes to export, all of them when empty
+type HandshakeExportRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=pcap 22000"`
+	UUIDs  string `query:"uuids" validate:"max=3700"`
+}
+
+// CaptureRequest format of a capture download, pcap returns the capture as it was uploaded
+type CaptureRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=pcap 22000"`
+}
+
+// CrackedResultsRequest the results are returned as JSON unless another format is asked
+type CrackedResultsRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=json csv potfile"`
+}
+
+// CrackedResult a password recovered from a handshake, a handshake has a result for every password hashcat found
+type CrackedResult struct {
+	HandshakeUUID string  `json:"handshake_uuid"`
+	SSID          string  `json:"ssid"`
+	BSSID         string  `json:"bssid"`
+	Password      string  `json:"password"`
+	CrackedDate   *string `json:"cracked_date"`
+	ClientUUID    *string `json:"client_uuid"`
+	ClientName    *string `json:"client_name"`
+}
+
+type CrackedResultsResponse struct {
+	Length  int              `json:"length"`
+	Results []*CrackedResult `json:"results"`
+}
+
+// HandshakeExportManifest manifest.json of an export archive
+type HandshakeExportManifest struct {
+	Format     string                          `json:"format"`
+	ExportedAt time.Time                       `json:"exported_at"`
+	Handshakes []*HandshakeExportManifestEntry `json:"handshakes"`
+}
+
+// HandshakeExportManifestEntry File is empty when the capture could not be converted to the format of the export
+type HandshakeExportManifestEntry struct {
+	UUID         string   `json:"uuid"`
+	SSID         string   `json:"ssid"`
+	BSSID        string   `json:"bssid"`
+	Status       string   `json:"status"`
+	UploadedDate string   `json:"uploaded_date"`
+	CrackedDate  *string  `json:"cracked_date"`
+	Passwords    []string `json:"passwords"`
+	ClientUUID   *string  `json:"client_uuid"`
+	ClientName   *string  `json:"client_name"`
+	File         string   `json:"file"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// ImportHandshakesRequest File is a capture, a 22000 hash file, a potfile or a tar, tar.gz or zip archive of them,
+// Name is the name of the file uploaded
+type ImportHandshakesRequest struct {
+	Name string `json:"name" validate:"max=255"`
+	File []byte `json:"file" validate:"required"`
+}
+
+// HandshakeImportOutcome the outcome of a network found in an imported file, or of the file itself when none has been.
+// Result is one of imported, duplicate, cracked, unmatched and failed
+type HandshakeImportOutcome struct {
+	File          string `json:"file"`
+	Kind          string `json:"kind"`
+	SSID          string `json:"ssid"`
+	BSSID         string `json:"bssid"`
+	HandshakeUUID string `json:"handshake_uuid,omitempty"`
+	Result        string `json:"result"`
+	Error         string `json:"error,omitempty"`
+}
+
+type ImportHandshakesResponse struct {
+	Imported   int                       `json:"imported"`
+	Duplicates int                       `json:"duplicates"`
+	Cracked    int                       `json:"cracked"`
+	Unmatched  int                       `json:"unmatched"`
+	Failed     int                       `json:"failed"`
+	Outcomes   []*HandshakeImportOutcome `json:"outcomes"`
+}
@@ -0,0 +1,48 @@
+package entities
+
+const PersonalAccessTokenTableName = "personal_access_token"
+
+// PersonalAccessToken a long-lived token created by the user for its scripts, accepted by the REST API in place of
+// the access tokens. Only the sha256 of the token is stored
+type PersonalAccessToken struct {
+	UUID         string  `db:"UUID"`
+	UserUUID     string  `db:"UUID_USER"`
+	Name         string  `db:"NAME"`
+	TokenHash    string  `db:"TOKEN_HASH" json:"-"`
+	Scope        string  `db:"SCOPE"`
+	CreatedDate  string  `db:"CREATED_DATE"`
+	ExpiresDate  string  `db:"EXPIRES_DATE"`
+	LastUsedDate *string `db:"LAST_USED_DATE"`
+}
+
+type ReturnPersonalAccessTokensRequest struct {
+	Page uint `query:"page" validate:"required,min=1"`
+}
+
+type ReturnPersonalAccessTokensResponse struct {
+	Length int                    `json:"length"`
+	Tokens []*PersonalAccessToken `json:"tokens"`
+}
+
+// CreatePersonalAccessTokenRequest a read token is accepted by the GET routes only, an admin token by the
+// routes of the administrators too as long as the user is one
+type CreatePersonalAccessTokenRequest struct {
+	Name          string `json:"name" validate:"required,max=100"`
+	Scope         string `json:"scope" validate:"required,oneof=read fe admin"`
+	ExpiresInDays int    `json:"expires_in_days" validate:"required,min=1,max=365"`
+}
+
+// CreatePersonalAccessTokenResponse Token is returned once, it cannot be recovered afterwards
+type CreatePersonalAccessTokenResponse struct {
+	TokenUUID   string `json:"token_uuid"`
+	Token       string `json:"token"`
+	ExpiresDate string `json:"expires_date"`
+}
+
+type RevokePersonalAccessTokenRequest struct {
+	TokenUUID string `json:"token_uuid" validate:"required"`
+}
+
+type RevokePersonalAccessTokenResponse struct {
+	Status bool `json:"status"`
+}
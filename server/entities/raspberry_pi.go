@@ -1,12 +1,30 @@
 package entities
 
+import "time"
+
 const RaspberryPiTableName = "raspberry_pi"
 
 type RaspberryPI struct {
-	UserUUID        string `db:"UUID_USER"`
-	RaspberryPIUUID string `db:"UUID"`
-	MachineID       string `db:"MACHINE_ID"`
-	EncryptionKey   string `db:"ENCRYPTION_KEY"`
+	UserUUID        string  `db:"UUID_USER"`
+	RaspberryPIUUID string  `db:"UUID"`
+	MachineID       string  `db:"MACHINE_ID"`
+	EncryptionKey   string  `db:"ENCRYPTION_KEY"`
+	CredentialHash  *string `db:"CREDENTIAL_HASH"`
+	Name            *string `db:"NAME"`
+	Hostname        *string `db:"HOSTNAME"`
+	DaemonVersion   *string `db:"DAEMON_VERSION"`
+	OS              *string `db:"OS"`
+	LatestIP        *string `db:"LATEST_IP"`
+	LastSeen        *string `db:"LAST_SEEN"`
+	Approved        bool    `db:"APPROVED"`
+	TeamUUID        *string `db:"UUID_TEAM"`
+}
+
+// RaspberryPIMetadata what the daemon reports about itself when logging in, empty fields are not known by older daemons
+type RaspberryPIMetadata struct {
+	Hostname      string
+	DaemonVersion string
+	OS            string
 }
 
 type ReturnRaspberryPiDevicesResponse struct {
@@ -14,11 +32,62 @@ type ReturnRaspberryPiDevicesResponse struct {
 	Devices []*CustomRaspberryPIResponse `json:"devices"`
 }
 
-// Needed to avoid to display encryption key
+// Needed to avoid to display encryption key. Fields reported by the daemon are empty until it logs in
 type CustomRaspberryPIResponse struct {
 	UserUUID        string
 	RaspberryPIUUID string
 	MachineID       string
+	Enrolled        bool   // the device holds a valid credential
+	Approved        bool   // uploads and certificates are refused until the user approves the device
+	EnrollmentCode  string // shown by the daemon, empty when the device has no credential
+	Name            string
+	Hostname        string
+	DaemonVersion   string
+	OS              string
+	LatestIP        string
+	LastSeen        string
+	TeamUUID        string // team the device is shared with, empty when it is not
+}
+
+type ReturnRaspberryPIRequest struct {
+	RaspberryPIUUID string `query:"uuid" validate:"required"`
+	Page            uint   `query:"page" validate:"required,min=0"`
+}
+
+// RaspberryPIActivity what a device uploaded in a day, Cracked is how many of those captures have been cracked since
+type RaspberryPIActivity struct {
+	Day      string
+	Uploaded int
+	Cracked  int
+}
+
+// ReturnRaspberryPIResponse Handshakes are a page of the captures uploaded by the device without their pcap,
+// Length counts all of them. Activity covers the latest days the device uploaded something, newest first
+type ReturnRaspberryPIResponse struct {
+	Device     *CustomRaspberryPIResponse `json:"device"`
+	Length     int                        `json:"length"`
+	Handshakes []*Handshake               `json:"handshakes"`
+	Activity   []*RaspberryPIActivity     `json:"activity"`
+}
+
+// RenameRaspberryPIRequest an empty name removes the one given before
+type RenameRaspberryPIRequest struct {
+	RaspberryPIUUID string `json:"raspberry_piuuid" validate:"required"`
+	Name            string `json:"name" validate:"max=100"`
+}
+
+type RenameRaspberryPIResponse struct {
+	Status bool `json:"status"`
+}
+
+// ApproveRaspberryPIRequest a device rejected is deleted and refused as the ones deleted by the user
+type ApproveRaspberryPIRequest struct {
+	RaspberryPIUUID string `json:"raspberry_piuuid" validate:"required"`
+	Approve         *bool  `json:"approve" validate:"required"`
+}
+
+type ApproveRaspberryPIResponse struct {
+	Status bool `json:"status"`
 }
 
 type DeleteRaspberryPIRequest struct {
@@ -28,3 +97,100 @@ type DeleteRaspberryPIRequest struct {
 type DeleteRaspberryPIResponse struct {
 	Status bool `json:"status"`
 }
+
+type RaspberryPICredentialRequest struct {
+	RaspberryPIUUID string `json:"raspberry_piuuid" validate:"required"`
+}
+
+// RotateRaspberryPICredentialResponse the credential is shown only once, the server stores its hash
+type RotateRaspberryPICredentialResponse struct {
+	Credential string `json:"credential"`
+}
+
+type RevokeRaspberryPICredentialResponse struct {
+	Status bool `json:"status"`
+}
+
+// RaspberryPILoginRequest Credential is the one returned by ENROLL, as for the DEVICELOGIN command.
+// Hostname, DaemonVersion and OS are recorded as the device metadata
+type RaspberryPILoginRequest struct {
+	MachineID     string `json:"machine_id" validate:"required,len=32"`
+	Credential    string `json:"credential" validate:"required,hexadecimal,len=64"`
+	Hostname      string `json:"hostname,omitempty" validate:"max=255"`
+	DaemonVersion string `json:"daemon_version,omitempty" validate:"max=50"`
+	OS            string `json:"os,omitempty" validate:"max=100"`
+}
+
+// RaspberryPIKeyExchangeRequest PublicKey is the base64 encoded X25519 public key of the daemon
+type RaspberryPIKeyExchangeRequest struct {
+	PublicKey string `json:"public_key" validate:"required,base64"`
+}
+
+type RaspberryPIKeyExchangeResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+// RaspberryPIUploadRequest Payload is gzip(pcap) sealed with the device key, as sent with the TCP chunked upload.
+// Position is where the capture was taken, nil when the daemon had no GPS fix. Quality is how much of the handshake it found
+type RaspberryPIUploadRequest struct {
+	SSID     string    `json:"ssid" validate:"required"`
+	BSSID    string    `json:"bssid" validate:"required"`
+	Payload  []byte    `json:"payload" validate:"required"`
+	Position *Position `json:"position,omitempty" validate:"omitempty"`
+	Quality  string    `json:"quality,omitempty" validate:"omitempty,oneof=complete partial missing"`
+}
+
+type RaspberryPIUploadResponse struct {
+	HandshakeUUID string `json:"handshake_uuid"`
+}
+
+// RaspberryPIResult CrackedHandshake is the value found by hashcat, as reported by the client
+type RaspberryPIResult struct {
+	HandshakeUUID    string `json:"handshake_uuid"`
+	SSID             string `json:"ssid"`
+	BSSID            string `json:"bssid"`
+	UploadedDate     string `json:"uploaded_date"`
+	CrackedHandshake string `json:"cracked_handshake"`
+}
+
+type RaspberryPIResultsResponse struct {
+	Results []*RaspberryPIResult `json:"results"`
+}
+
+// RaspberryPIBundleManifest describes the captures of an offline bundle written by a daemon, it is signed with the device key
+type RaspberryPIBundleManifest struct {
+	Version   int                         `json:"version"`
+	MachineID string                      `json:"machine_id" validate:"required,len=32"`
+	Created   time.Time                   `json:"created"`
+	Captures  []*RaspberryPIBundleCapture `json:"captures" validate:"dive"`
+}
+
+// RaspberryPIBundleCapture File is the bundle entry holding the capture, sealed as for the uploads. Checksum is its hex sha256
+type RaspberryPIBundleCapture struct {
+	File     string    `json:"file" validate:"required"`
+	SSID     string    `json:"ssid" validate:"required"`
+	BSSID    string    `json:"bssid" validate:"required"`
+	Size     int64     `json:"size" validate:"gt=0"`
+	Checksum string    `json:"checksum" validate:"required,hexadecimal,len=64"`
+	Position *Position `json:"position,omitempty" validate:"omitempty"`
+	Quality  string    `json:"quality,omitempty" validate:"omitempty,oneof=complete partial missing"`
+}
+
+// ImportRaspberryPIBundleRequest Bundle is the content of the file written by the daemon export command
+type ImportRaspberryPIBundleRequest struct {
+	Bundle []byte `json:"bundle" validate:"required"`
+}
+
+// RaspberryPIBundleOutcome HandshakeUUID is set when the capture has been imported, Error tells why it has not otherwise
+type RaspberryPIBundleOutcome struct {
+	SSID          string `json:"ssid"`
+	BSSID         string `json:"bssid"`
+	HandshakeUUID string `json:"handshake_uuid,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type ImportRaspberryPIBundleResponse struct {
+	MachineID string                      `json:"machine_id"`
+	Imported  int                         `json:"imported"`
+	Outcomes  []*RaspberryPIBundleOutcome `json:"outcomes"`
+}
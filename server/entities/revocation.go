@@ -0,0 +1,33 @@
+package entities
+
+const RevocationTableName = "revocation"
+
+// Revocation a raspberry pi or a client deleted by the user. Its machine ID and the certificates issued to it,
+// whose subject serial number is EntityUUID, are refused until the user approves it again
+type Revocation struct {
+	UUID        string  `db:"UUID"`
+	UserUUID    string  `db:"UUID_USER"`
+	Kind        string  `db:"KIND"`
+	MachineID   string  `db:"MACHINE_ID"`
+	EntityUUID  string  `db:"UUID_ENTITY"`
+	Name        *string `db:"NAME"`
+	RevokedDate string  `db:"REVOKED_DATE"`
+}
+
+type ReturnRevocationsRequest struct {
+	Page uint `query:"page" validate:"required,min=0"`
+}
+
+type ReturnRevocationsResponse struct {
+	Length      int           `json:"length"`
+	Revocations []*Revocation `json:"revocations"`
+}
+
+// ApproveRevocationRequest the device or client can connect again, it is created anew on its next connection
+type ApproveRevocationRequest struct {
+	RevocationUUID string `json:"revocation_uuid" validate:"required"`
+}
+
+type ApproveRevocationResponse struct {
+	Status bool `json:"status"`
+}
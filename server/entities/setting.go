@@ -0,0 +1,12 @@
+package entities
+
+const SettingTableName = "setting"
+
+// RegistrationsRequest enables or disables the registrations at runtime, overriding ALLOW_REGISTRATIONS
+type RegistrationsRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+type RegistrationsResponse struct {
+	Enabled bool `json:"enabled"`
+}
@@ -0,0 +1,79 @@
+package entities
+
+const (
+	TeamTableName       = "team"
+	TeamMemberTableName = "team_member"
+)
+
+// Team a group of users sharing clients, devices, handshakes and wordlists. The resources shared are still managed
+// by the users owning them, the members can see them and, unless viewers, use them
+type Team struct {
+	UUID        string `db:"UUID"`
+	Name        string `db:"NAME"`
+	CreatedDate string `db:"CREATED_DATE"`
+}
+
+// UserTeam a team as listed to one of its members, Role is the role of the member
+type UserTeam struct {
+	TeamUUID    string `json:"team_uuid"`
+	Name        string `json:"name"`
+	CreatedDate string `json:"created_date"`
+	Role        string `json:"role"`
+}
+
+type ReturnTeamsResponse struct {
+	Length int         `json:"length"`
+	Teams  []*UserTeam `json:"teams"`
+}
+
+type TeamMember struct {
+	UserUUID string `json:"user_uuid"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type ReturnTeamMembersRequest struct {
+	TeamUUID string `query:"uuid" validate:"required"`
+}
+
+type ReturnTeamMembersResponse struct {
+	Length  int           `json:"length"`
+	Members []*TeamMember `json:"members"`
+}
+
+// CreateTeamRequest the user creating the team is its first owner
+type CreateTeamRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type CreateTeamResponse struct {
+	TeamUUID string `json:"team_uuid"`
+}
+
+type DeleteTeamRequest struct {
+	TeamUUID string `json:"team_uuid" validate:"required"`
+}
+
+// AddTeamMemberRequest adds the user to the team, or changes its role when it is a member already
+type AddTeamMemberRequest struct {
+	TeamUUID string `json:"team_uuid" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	Role     string `json:"role" validate:"required,oneof=owner member viewer"`
+}
+
+// RemoveTeamMemberRequest removes a member, an empty UserUUID removes the user sending the request from the team
+type RemoveTeamMemberRequest struct {
+	TeamUUID string `json:"team_uuid" validate:"required"`
+	UserUUID string `json:"user_uuid"`
+}
+
+// ShareResourceRequest shares a resource of the user with a team, an empty TeamUUID makes it personal again
+type ShareResourceRequest struct {
+	Kind         string `json:"kind" validate:"required,oneof=client raspberry_pi handshake wordlist"`
+	ResourceUUID string `json:"resource_uuid" validate:"required"`
+	TeamUUID     string `json:"team_uuid"`
+}
+
+type TeamOperationResponse struct {
+	Status bool `json:"status"`
+}
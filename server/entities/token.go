@@ -0,0 +1,39 @@
+package entities
+
+const (
+	SigningKeyTableName   = "signing_key"
+	RefreshTokenTableName = "refresh_token"
+	RevokedTokenTableName = "revoked_token"
+)
+
+// SigningKey a secret the JWTs are signed with, the tokens name it by KID in their header
+type SigningKey struct {
+	KID         string  `db:"KID"`
+	Secret      []byte  `db:"SECRET"`
+	CreatedDate string  `db:"CREATED_DATE"`
+	RetiredDate *string `db:"RETIRED_DATE"`
+}
+
+// RefreshToken exchanged for a new access token, it is replaced at each use. Only the sha256 of the token is stored
+type RefreshToken struct {
+	UUID        string  `db:"UUID"`
+	UserUUID    string  `db:"UUID_USER"`
+	SessionUUID string  `db:"UUID_SESSION"`
+	Audience    string  `db:"AUDIENCE"`
+	TokenHash   string  `db:"TOKEN_HASH"`
+	ExpiresDate string  `db:"EXPIRES_DATE"`
+	UsedDate    *string `db:"USED_DATE"`
+}
+
+// AuthResponse returned by the login and by the refresh, Details is the access token as in UniformResponse
+type AuthResponse struct {
+	StatusCode   int    `json:"status_code"`
+	Details      string `json:"details"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn seconds before the access token expires
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -6,6 +6,7 @@ type User struct {
 	UserUUID string `db:"UUID"`
 	Username string `db:"USERNAME"`
 	Password string `db:"PASSWORD"`
+	Disabled bool   `db:"DISABLED"`
 }
 
 type UpdateUserPasswordRequest struct {
@@ -17,3 +18,49 @@ type UpdateUserPasswordRequest struct {
 type UpdateUserPasswordResponse struct {
 	Status string `json:"status"`
 }
+
+// UserSummary a user as listed to the administrators, with how many clients, devices and handshakes it owns
+type UserSummary struct {
+	UserUUID   string `json:"user_uuid"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	Disabled   bool   `json:"disabled"`
+	Clients    int    `json:"clients"`
+	Devices    int    `json:"devices"`
+	Handshakes int    `json:"handshakes"`
+}
+
+type ReturnUsersResponse struct {
+	Length int            `json:"length"`
+	Users  []*UserSummary `json:"users"`
+}
+
+// CreateUserRequest a user created by an administrator, registrations do not need to be enabled
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required,max=250"`
+	Password string `json:"password" validate:"required,max=250"`
+	Role     string `json:"role" validate:"required,oneof=ADMIN USER"`
+}
+
+type DeleteUserRequest struct {
+	UserUUID string `json:"user_uuid" validate:"required"`
+}
+
+type DisableUserRequest struct {
+	UserUUID string `json:"user_uuid" validate:"required"`
+	Disabled *bool  `json:"disabled" validate:"required"`
+}
+
+type ResetUserPasswordRequest struct {
+	UserUUID string `json:"user_uuid" validate:"required"`
+	Password string `json:"password" validate:"required,max=250"`
+}
+
+type UpdateUserRoleRequest struct {
+	UserUUID string `json:"user_uuid" validate:"required"`
+	Role     string `json:"role" validate:"required,oneof=ADMIN USER"`
+}
+
+type UserOperationResponse struct {
+	Status bool `json:"status"`
+}
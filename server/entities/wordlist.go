@@ -0,0 +1,37 @@
+package entities
+
+const WordlistTableName = "wordlist"
+
+// Wordlist a wordlist available on the clients, offered when submitting a task. Path is relative to the directory
+// the clients run hashcat from
+type Wordlist struct {
+	UUID        string  `db:"UUID"`
+	UserUUID    string  `db:"UUID_USER"`
+	Name        string  `db:"NAME"`
+	Path        string  `db:"PATH"`
+	CreatedDate string  `db:"CREATED_DATE"`
+	TeamUUID    *string `db:"UUID_TEAM"`
+}
+
+type ReturnWordlistsResponse struct {
+	Length    int         `json:"length"`
+	Wordlists []*Wordlist `json:"wordlists"`
+}
+
+type CreateWordlistRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Path     string `json:"path" validate:"required,max=255"`
+	TeamUUID string `json:"team_uuid"`
+}
+
+type CreateWordlistResponse struct {
+	WordlistUUID string `json:"wordlist_uuid"`
+}
+
+type DeleteWordlistRequest struct {
+	WordlistUUID string `json:"wordlist_uuid" validate:"required"`
+}
+
+type DeleteWordlistResponse struct {
+	Status bool `json:"status"`
+}
@@ -3,6 +3,7 @@ package constants
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 const TimeOut = 10
@@ -11,49 +12,133 @@ var BackendBaseURL = fmt.Sprintf("http://%s:%s/v1/", os.Getenv("BACKEND_HOST"),
 
 const SessionTokenName = "session_token"
 
+// RefreshTokenName cookie of the refresh token, exchanged for a new session_token once it expires
+const RefreshTokenName = "refresh_token"
+
+// SessionCookieDuration the cookies last as long as the refresh tokens issued by the backend
+const SessionCookieDuration = 7 * 24 * time.Hour
+
 type CustomType string
 
 var AuthToken = CustomType("token")
 
 const JSONContentType = "application/json"
 const HTMLContentType = "text/html;charset=UTF-8"
+const GeoJSONContentType = "application/geo+json"
+const CSVContentType = "text/csv;charset=UTF-8"
 const FileToCrackString = "FILE_TO_CRACK"
 
 const MaxUploadSize = 10 << 28 // 2,68435456 GB
 
+// MaxBundleSize maximum size of the bundles written by the daemon, as accepted by the backend
+const MaxBundleSize = 128 << 20
+
+// MaxImportSize maximum size of the captures, hash files, potfiles and archives of them imported at once
+const MaxImportSize = 128 << 20
+
+// PageSize rows shown in each page of the lists
+const PageSize = 5
+
+// MaxPageSize largest page the backend returns, the dropdowns listing every client or device use it
+const MaxPageSize = 100
+
+// PCAPContentType the captures are downloaded as uploaded, generic hash files included
+const PCAPContentType = "application/vnd.tcpdump.pcap"
+const TextContentType = "text/plain;charset=UTF-8"
+const GzipContentType = "application/gzip"
+
+// Choices of the filters of the handshakes page, as accepted by the backend
+var (
+	HandshakeStatuses = []string{"nothing", "pending", "working", "cracked", "exhausted", "error"}
+	// HandshakeQualities unknown are the handshakes without a classification of the daemon
+	HandshakeQualities = []string{"complete", "partial", "missing", "unknown"}
+	HandshakeSorts     = []string{"uploaded_date", "cracked_date", "ssid", "bssid", "status"}
+	PageSizes          = []int{PageSize, 10, 25, 50, MaxPageSize}
+)
+
 // Views
 
 const (
-	LoginView     = "login.html"
-	RegisterView  = "register.html"
-	HandshakeView = "handshake.html"
-	ClientView    = "clients.html"
-	DeviceView    = "raspberrypi.html"
-	WelcomeView   = "welcome.html"
+	LoginView        = "login.html"
+	RegisterView     = "register.html"
+	HandshakeView    = "handshake.html"
+	ClientView       = "clients.html"
+	DeviceView       = "raspberrypi.html"
+	DirectiveView    = "directives.html"
+	DeviceDetailView = "device.html"
+	RevocationView   = "revocations.html"
+	AdminView        = "admin.html"
+	TokensView       = "tokens.html"
+	TeamsView        = "teams.html"
+	WordlistsView    = "wordlists.html"
+	ImportView       = "import.html"
+	WelcomeView      = "welcome.html"
 )
 
 // Endpoints FE
 const (
-	RouteIndex       = "/"
-	Login            = "/login"
-	HandshakePage    = "/handshakes"
-	ClientPage       = "/clients"
-	RaspberryPIPage  = "/raspberrypi"
-	Register         = "/register"
-	Logout           = "/logout"
-	SubmitTask       = "/submit-task"
-	DeleteClient     = "/delete-client"
-	DeleteRaspberry  = "/delete-raspberrypi"
-	DeleteHandshake  = "/delete-handshake"
-	CreateHandshake  = "/create-handshake"
-	UpdateEncryption = "/update-encryption"
-	UpdatePassword   = "/update-password"
+	RouteIndex        = "/"
+	Login             = "/login"
+	HandshakePage     = "/handshakes"
+	ClientPage        = "/clients"
+	RaspberryPIPage   = "/raspberrypi"
+	Register          = "/register"
+	Logout            = "/logout"
+	SubmitTask        = "/submit-task"
+	DeleteClient      = "/delete-client"
+	DeleteRaspberry   = "/delete-raspberrypi"
+	ApproveClient     = "/approve-client"
+	ApproveDevice     = "/approve-raspberrypi"
+	DeleteHandshake   = "/delete-handshake"
+	CreateHandshake   = "/create-handshake"
+	UpdateEncryption  = "/update-encryption"
+	UpdatePassword    = "/update-password"
+	RotateCredential  = "/rotate-credential"
+	RevokeCredential  = "/revoke-credential"
+	ExportLocations   = "/handshake-locations"
+	HandshakeCapture  = "/handshake-capture"
+	HandshakeLogs     = "/handshake-logs"
+	ExportHandshakes  = "/handshake-export"
+	ExportCracked     = "/handshake-cracked"
+	ImportBundle      = "/import-bundle"
+	ImportPage        = "/import"
+	ImportHandshakes  = "/import-handshakes"
+	DirectivesPage    = "/directives"
+	CreateDirective   = "/create-directive"
+	CancelDirective   = "/cancel-directive"
+	DevicePage        = "/device"
+	RenameDevice      = "/rename-device"
+	RevocationsPage   = "/revocations"
+	ApproveRevoked    = "/approve-revocation"
+	TokensPage        = "/tokens"
+	CreateToken       = "/create-token"
+	RevokeToken       = "/revoke-token"
+	TeamsPage         = "/teams"
+	CreateTeam        = "/create-team"
+	DeleteTeam        = "/delete-team"
+	AddTeamMember     = "/add-team-member"
+	RemoveTeamMember  = "/remove-team-member"
+	ShareResource     = "/share-resource"
+	WordlistsPage     = "/wordlists"
+	CreateWordlist    = "/create-wordlist"
+	DeleteWordlist    = "/delete-wordlist"
+	AdminPage         = "/admin"
+	AdminClientsPage  = "/admin-clients"
+	AdminDevicesPage  = "/admin-devices"
+	AdminTasksPage    = "/admin-tasks"
+	AdminCreateUser   = "/admin-create-user"
+	AdminDeleteUser   = "/admin-delete-user"
+	AdminDisableUser  = "/admin-disable-user"
+	AdminResetUser    = "/admin-reset-password"
+	AdminUpdateRole   = "/admin-update-role"
+	AdminToggleSignUp = "/admin-registrations"
 )
 
 // Endpoints BE
 const (
 	BackendVerifyEndpoint    = "verify"
 	BackendAuthEndpoint      = "auth"
+	BackendRefreshEndpoint   = "auth/refresh"
 	BackendLogoutEndpoint    = "logout"
 	BackendRegisterEndpoint  = "register"
 	BackendGetHandshakes     = "handshakes"
@@ -63,6 +148,33 @@ const (
 	BackendDeleteClient      = "delete/client"
 	BackendHandshake         = "manage/handshake"
 	BackendDeleteRaspberryPI = "delete/raspberrypi"
+	ClientApproval           = "clients/approval"
+	RaspberryPIApproval      = "devices/approval"
 	UpdateClientEncryption   = "encryption-status"
 	UpdateUserPassword       = "user/password"
+	RaspberryPICredential    = "devices/credential"
+	HandshakeLocations       = "handshakes/locations"
+	BackendHandshakeCapture  = "handshakes/%s/capture"
+	BackendHandshakeLogs     = "handshakes/%s/logs"
+	BackendHandshakeExport   = "handshakes/export"
+	BackendCrackedResults    = "handshakes/cracked"
+	BackendHandshakeImport   = "handshakes/import"
+	RaspberryPIBundle        = "devices/bundle"
+	RaspberryPIDirectives    = "devices/directives"
+	RaspberryPIDevice        = "devices/device"
+	RaspberryPIName          = "devices/name"
+	BackendRevocations       = "revocations"
+	PersonalAccessTokens     = "tokens"
+	BackendTeams             = "teams"
+	BackendTeamMembers       = "teams/members"
+	BackendTeamShare         = "teams/share"
+	BackendWordlists         = "wordlists"
+	AdminUsers               = "admin/users"
+	AdminUserDisable         = "admin/users/disable"
+	AdminUserPassword        = "admin/users/password"
+	AdminUserRole            = "admin/users/role"
+	AdminClients             = "admin/clients"
+	AdminDevices             = "admin/devices"
+	AdminTasks               = "admin/tasks"
+	AdminRegistrations       = "admin/registrations"
 )
@@ -5,3 +5,8 @@ import "errors"
 var ErrLogout = errors.New("unable to logout")
 var ErrNotAuthenticated = errors.New("login first")
 var ErrInvalidCredentials = errors.New("invalid credentials")
+var ErrCredentialNotRotated = errors.New("unable to rotate the device credential")
+var ErrDirectiveNotCreated = errors.New("unable to create the directive")
+var ErrUserNotFound = errors.New("the user does not exist anymore")
+var ErrTeamOperation = errors.New("the team, the user or the resource does not exist, or it is not visible to you")
+var ErrNoHandshakeSelected = errors.New("select the handshakes to export first")
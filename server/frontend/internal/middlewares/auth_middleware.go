@@ -19,6 +19,11 @@ func (u TokenAuth) TokenValidation(next http.Handler) http.Handler {
 		// Validate token
 		sessionToken, err := u.Usecase.IsTokenValid(r)
 
+		// access tokens are short-lived, they are renewed with the refresh token
+		if err != nil {
+			sessionToken, err = u.Usecase.RefreshSession(w, r)
+		}
+
 		if err != nil {
 			http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.Login, err.Error()), http.StatusFound)
 			return
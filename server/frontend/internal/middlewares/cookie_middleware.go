@@ -2,7 +2,6 @@ package middlewares
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
 )
@@ -20,20 +19,18 @@ func (u TokenAuth) CheckCookieExistence(next http.Handler) http.Handler {
 			// If the cookie exists, validate the token
 			_, errValidation := u.Usecase.IsTokenValid(r)
 
+			if errValidation != nil {
+				_, errValidation = u.Usecase.RefreshSession(w, r)
+			}
+
 			if errValidation == nil {
 				// If token is valid, redirect to posts page
 				http.Redirect(w, r, constants.HandshakePage, http.StatusFound)
 				return
 			}
 
-			// If token is invalid, delete the cookie
-			http.SetCookie(w, &http.Cookie{
-				Name:     constants.SessionTokenName,
-				Value:    "",
-				Expires:  time.Unix(0, 0),
-				Path:     "/",
-				HttpOnly: true,
-			})
+			// If the session can't be renewed, delete the cookies
+			u.Usecase.ClearSessionCookies(w)
 		}
 
 		// Continue to the next handler
@@ -0,0 +1,307 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+)
+
+type Page struct {
+	Usecase *usecase.Usecase
+}
+
+type AdminTemplate struct {
+	Page int `query:"page"`
+}
+
+// ListUsers renders every user with the registrations toggle
+func (u Page) ListUsers(w http.ResponseWriter, r *http.Request) {
+	u.renderSection(w, r, constants.AdminPage, "users", func(token string, page int) (map[string]any, int, error) {
+		users, err := u.Usecase.GetUsers(token, page)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		registrations, err := u.Usecase.GetRegistrations(token)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return map[string]any{
+			"Users":         users.Users,
+			"Registrations": registrations.Enabled,
+		}, users.Length, nil
+	})
+}
+
+// ListClients renders the clients of every user
+func (u Page) ListClients(w http.ResponseWriter, r *http.Request) {
+	u.renderSection(w, r, constants.AdminClientsPage, "clients", func(token string, page int) (map[string]any, int, error) {
+		clients, err := u.Usecase.GetAllClients(token, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		return map[string]any{"Clients": clients.Clients}, clients.Length, nil
+	})
+}
+
+// ListDevices renders the raspberry pi devices of every user
+func (u Page) ListDevices(w http.ResponseWriter, r *http.Request) {
+	u.renderSection(w, r, constants.AdminDevicesPage, "devices", func(token string, page int) (map[string]any, int, error) {
+		devices, err := u.Usecase.GetAllDevices(token, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		return map[string]any{"Devices": devices.Devices}, devices.Length, nil
+	})
+}
+
+// ListTasks renders the handshakes waiting for or being cracked by a client
+func (u Page) ListTasks(w http.ResponseWriter, r *http.Request) {
+	u.renderSection(w, r, constants.AdminTasksPage, "tasks", func(token string, page int) (map[string]any, int, error) {
+		tasks, err := u.Usecase.GetTaskQueue(token, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		return map[string]any{"Tasks": tasks.Handshakes}, tasks.Length, nil
+	})
+}
+
+// renderSection renders a section of the administration page, users who are not administrators get the error of the backend
+func (u Page) renderSection(w http.ResponseWriter, r *http.Request, route, section string, fetch func(token string, page int) (map[string]any, int, error)) {
+	errorMessage := r.URL.Query().Get("error")
+
+	var request AdminTemplate
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1", route), http.StatusFound)
+		return
+	}
+
+	var page = 1
+
+	if request.Page != 0 {
+		page = request.Page
+	}
+
+	data, length, err := fetch(token, page)
+	if err != nil {
+		u.Usecase.RenderTemplate(w, constants.AdminView, map[string]any{
+			"Section": section,
+			"Error":   err.Error(),
+		})
+		return
+	}
+
+	postsPerPage := 5
+	data["Section"] = section
+	data["CurrentPage"] = page
+	data["TotalPages"] = (length + postsPerPage - 1) / postsPerPage
+	data["Error"] = errorMessage
+
+	u.Usecase.RenderTemplate(w, constants.AdminView, data)
+}
+
+// redirectToUsers goes back to the list of the users, showing the error when the operation failed
+func redirectToUsers(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.AdminPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.AdminPage), http.StatusFound)
+}
+
+// userOperationResult the backend answers 404 with a status false when the user has been deleted in the meantime
+func userOperationResult(result *entities.UserOperationResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if !result.Status {
+		return customErrors.ErrUserNotFound
+	}
+	return nil
+}
+
+// authToken the token of the session, the user is sent to the login page when it is missing
+func authToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := r.Context().Value(constants.AuthToken)
+
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return "", false
+	}
+	return token.(string), true
+}
+
+type CreateUserRequest struct {
+	Username string `form:"username" validate:"required"`
+	Password string `form:"password" validate:"required"`
+	Role     string `form:"role" validate:"required,oneof=ADMIN USER"`
+}
+
+// CreateUser Accept post request for creating a user, registrations do not need to be enabled
+func (u Page) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var request CreateUserRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToUsers(w, r, err)
+		return
+	}
+
+	result, err := u.Usecase.CreateUser(token, &entities.CreateUserRequest{
+		Username: request.Username,
+		Password: request.Password,
+		Role:     request.Role,
+	})
+
+	if err != nil {
+		redirectToUsers(w, r, err)
+		return
+	}
+
+	if result.StatusCode != http.StatusOK {
+		redirectToUsers(w, r, fmt.Errorf("%s", result.Details))
+		return
+	}
+
+	redirectToUsers(w, r, nil)
+}
+
+type UserRequest struct {
+	UUID string `form:"uuid" validate:"required"`
+}
+
+// DeleteUser Accept post request for deleting a user with everything it owns
+func (u Page) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	var request UserRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToUsers(w, r, err)
+		return
+	}
+
+	redirectToUsers(w, r, userOperationResult(u.Usecase.DeleteUser(token, &entities.DeleteUserRequest{
+		UserUUID: request.UUID,
+	})))
+}
+
+type DisableUserRequest struct {
+	UUID     string `form:"uuid" validate:"required"`
+	Disabled *bool  `form:"disabled" validate:"required"`
+}
+
+// DisableUser Accept post request for disabling a user or enabling it again
+func (u Page) DisableUser(w http.ResponseWriter, r *http.Request) {
+	var request DisableUserRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToUsers(w, r, err)
+		return
+	}
+
+	redirectToUsers(w, r, userOperationResult(u.Usecase.DisableUser(token, &entities.DisableUserRequest{
+		UserUUID: request.UUID,
+		Disabled: request.Disabled,
+	})))
+}
+
+type ResetPasswordRequest struct {
+	UUID     string `form:"uuid" validate:"required"`
+	Password string `form:"password" validate:"required"`
+}
+
+// ResetUserPassword Accept post request for setting the password of a user, its sessions are terminated
+func (u Page) ResetUserPassword(w http.ResponseWriter, r *http.Request) {
+	var request ResetPasswordRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToUsers(w, r, err)
+		return
+	}
+
+	redirectToUsers(w, r, userOperationResult(u.Usecase.ResetUserPassword(token, &entities.ResetUserPasswordRequest{
+		UserUUID: request.UUID,
+		Password: request.Password,
+	})))
+}
+
+type UpdateRoleRequest struct {
+	UUID string `form:"uuid" validate:"required"`
+	Role string `form:"role" validate:"required,oneof=ADMIN USER"`
+}
+
+// UpdateUserRole Accept post request for promoting or demoting a user
+func (u Page) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var request UpdateRoleRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToUsers(w, r, err)
+		return
+	}
+
+	redirectToUsers(w, r, userOperationResult(u.Usecase.UpdateUserRole(token, &entities.UpdateUserRoleRequest{
+		UserUUID: request.UUID,
+		Role:     request.Role,
+	})))
+}
+
+type RegistrationsRequest struct {
+	Enabled *bool `form:"enabled" validate:"required"`
+}
+
+// UpdateRegistrations Accept post request for enabling or disabling the registrations
+func (u Page) UpdateRegistrations(w http.ResponseWriter, r *http.Request) {
+	var request RegistrationsRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToUsers(w, r, err)
+		return
+	}
+
+	_, err := u.Usecase.UpdateRegistrations(token, &entities.RegistrationsRequest{
+		Enabled: request.Enabled,
+	})
+
+	redirectToUsers(w, r, err)
+}
@@ -54,7 +54,15 @@ func (u Page) ListClients(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postsPerPage := 5
+	teams, err := u.Usecase.GetTeams(token.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	postsPerPage := constants.PageSize
 	totalPages := (clients.Length + postsPerPage - 1) / postsPerPage
 
 	// Get Clients installed by user
@@ -63,6 +71,7 @@ func (u Page) ListClients(w http.ResponseWriter, r *http.Request) {
 	u.Usecase.RenderTemplate(w, constants.ClientView, map[string]any{
 		"Clients":     clients.Clients,
 		"Certs":       clients.Certs,
+		"Teams":       teams.Teams,
 		"CurrentPage": page,
 		"TotalPages":  totalPages,
 		"Error":       errorMessage,
@@ -101,6 +110,40 @@ func (u Page) DeleteClient(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.ClientPage), http.StatusFound)
 }
 
+type ApproveClientRequest struct {
+	UUID    string `form:"uuid" validate:"required"`
+	Approve *bool  `form:"approve" validate:"required"`
+}
+
+// ApproveClient Accept post request for approving or rejecting a client waiting for approval
+func (u Page) ApproveClient(w http.ResponseWriter, r *http.Request) {
+	var request ApproveClientRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.ClientPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	result, err := u.Usecase.ApproveClient(token.(string), &entities.ApproveClientRequest{
+		ClientUUID: request.UUID,
+		Approve:    request.Approve,
+	})
+
+	if err != nil && result != nil && !result.Status {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.ClientPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.ClientPage), http.StatusFound)
+}
+
 type UpdateClientEncryptionStatusRequest struct {
 	ClientUUID string `form:"clientUUID" validate:"required"`
 	Encryption *bool  `form:"enabled" validate:"required"`
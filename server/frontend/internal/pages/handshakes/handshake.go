@@ -5,6 +5,7 @@ import (
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/response"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+	"html/template"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -20,18 +21,14 @@ type Page struct {
 	Usecase *usecase.Usecase
 }
 
-type HandshakeTemplate struct {
-	Page int `query:"page"`
-}
-
-// TemplateHandshake renders the login page template with an error message (if any)
+// TemplateHandshake renders the handshakes matching the filters in the query, see entities.HandshakeFilter
 func (u Page) TemplateHandshake(w http.ResponseWriter, r *http.Request) {
 	c := response.Initializer{ResponseWriter: w}
 
 	errorMessage := r.URL.Query().Get("error")
 	successMessage := r.URL.Query().Get("success")
 
-	var request HandshakeTemplate
+	var filter entities.HandshakeFilter
 	token := r.Context().Value(constants.AuthToken)
 
 	// Check if the token exists
@@ -40,18 +37,21 @@ func (u Page) TemplateHandshake(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := utils.ValidateQueryParameters(&request, r); err != nil {
-		http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.HandshakePage), http.StatusFound)
+	if err := utils.ValidateQueryParameters(&filter, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
 		return
 	}
 
-	var page = 1
-
-	if request.Page != 0 {
-		page = request.Page
+	// the pages are numbered, cursors are for the API
+	filter.Cursor = ""
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = constants.PageSize
 	}
 
-	handshakes, err := u.Usecase.GetUserHandshakes(token.(string), page)
+	handshakes, err := u.Usecase.GetUserHandshakes(token.(string), &filter)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
@@ -60,22 +60,12 @@ func (u Page) TemplateHandshake(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Iterate until data available, we need all clients installed by user
-	var clientPage = 1
-	available := 1
-	var clients = make([]*entities.Client, 0)
-
-	for available > 0 {
-		cc, err := u.Usecase.GetUserClients(token.(string), clientPage)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
-				Error: err.Error(),
-			})
-			return
-		}
-		clientPage++
-		available = len(cc.Clients)
-		clients = append(clients, cc.Clients...)
+	clients, err := u.Usecase.GetAllUserClients(token.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
 	}
 
 	availableClients := make([]string, 0)
@@ -83,19 +73,55 @@ func (u Page) TemplateHandshake(w http.ResponseWriter, r *http.Request) {
 		availableClients = append(availableClients, fmt.Sprintf("%s:%s", client.Name, client.ClientUUID))
 	}
 
-	postsPerPage := 5
-	totalPages := (handshakes.Length + postsPerPage - 1) / postsPerPage
+	devices, err := u.Usecase.GetAllUserDevices(token.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
 
-	// Get Clients installed by user
+	teams, err := u.Usecase.GetTeams(token.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	wordlists, err := u.Usecase.GetWordlists(token.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	totalPages := (handshakes.Length + int(filter.PageSize) - 1) / int(filter.PageSize)
+
+	// the page links keep the filters
+	pageQuery := utils.EncodeQueryParameters(&filter)
+	pageQuery.Del("page")
 
 	// RenderTemplate the login template
 	u.Usecase.RenderTemplate(w, constants.HandshakeView, map[string]any{
 		"Handshakes":       handshakes.Handshakes,
-		"CurrentPage":      page,
+		"CurrentPage":      int(filter.Page),
 		"TotalPages":       totalPages,
+		"PageQuery":        template.URL(pageQuery.Encode()), // #nosec G203 -- encoded by url.Values
+		"Filter":           filter,
+		"PageSize":         int(filter.PageSize),
+		"PageSizes":        constants.PageSizes,
+		"Statuses":         constants.HandshakeStatuses,
+		"Qualities":        constants.HandshakeQualities,
+		"Sorts":            constants.HandshakeSorts,
+		"Clients":          clients,
+		"Devices":          devices,
 		"Error":            errorMessage,
 		"Success":          successMessage,
 		"InstalledClients": strings.Join(availableClients, ";"),
+		"Teams":            teams.Teams,
+		"Wordlists":        wordlists.Wordlists,
 	})
 }
 
@@ -235,3 +261,190 @@ func (u Page) CreateHandshake(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, fmt.Sprintf("%s?page=1&success=%s", constants.HandshakePage, url.QueryEscape(fmt.Sprintf("hadnshake %s created", id.HandshakeID))), http.StatusFound)
 }
+
+type ExportLocationsRequest struct {
+	Format string `query:"format" validate:"required,oneof=geojson csv"`
+}
+
+// ExportLocations downloads where the handshakes have been captured
+func (u Page) ExportLocations(w http.ResponseWriter, r *http.Request) {
+	var request ExportLocationsRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	exported, err := u.Usecase.ExportHandshakeLocations(token.(string), request.Format)
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	contentType, extension := constants.GeoJSONContentType, "geojson"
+	if request.Format == "csv" {
+		contentType, extension = constants.CSVContentType, "csv"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="handshake-locations.%s"`, extension))
+	_, _ = w.Write(exported)
+}
+
+type HandshakeFileRequest struct {
+	HandshakeUUID string `query:"uuid" validate:"required,uuid"`
+}
+
+type DownloadCaptureRequest struct {
+	HandshakeUUID string `query:"uuid" validate:"required,uuid"`
+	Format        string `query:"format" validate:"omitempty,oneof=pcap 22000"`
+}
+
+// DownloadCapture downloads the capture of a handshake, as uploaded or in the hashcat 22000 format.
+// The handshakes page does not load them
+func (u Page) DownloadCapture(w http.ResponseWriter, r *http.Request) {
+	var request DownloadCaptureRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	capture, err := u.Usecase.GetHandshakeCapture(token.(string), request.HandshakeUUID, &entities.CaptureRequest{Format: request.Format})
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	contentType, extension := constants.PCAPContentType, "pcap"
+	if request.Format == "22000" {
+		contentType, extension = constants.TextContentType, "22000"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, request.HandshakeUUID, extension))
+	_, _ = w.Write(capture)
+}
+
+// ExportHandshakesRequest the selected handshakes are sent as uuids, one parameter for each of them
+type ExportHandshakesRequest struct {
+	Format string `query:"format" validate:"required,oneof=pcap 22000"`
+	Scope  string `query:"scope" validate:"required,oneof=selected all"`
+}
+
+// ExportHandshakes downloads the archive of the selected handshakes, or of all of them
+func (u Page) ExportHandshakes(w http.ResponseWriter, r *http.Request) {
+	var request ExportHandshakesRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	export := &entities.HandshakeExportRequest{Format: request.Format}
+
+	if request.Scope == "selected" {
+		selected := r.URL.Query()["uuids"]
+		if len(selected) == 0 {
+			http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(customErrors.ErrNoHandshakeSelected.Error())), http.StatusFound)
+			return
+		}
+		export.UUIDs = strings.Join(selected, ",")
+	}
+
+	archive, err := u.Usecase.ExportHandshakes(token.(string), export)
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.GzipContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="handshakes-%s.tar.gz"`, request.Format))
+	_, _ = w.Write(archive)
+}
+
+type ExportCrackedRequest struct {
+	Format string `query:"format" validate:"required,oneof=json csv potfile"`
+}
+
+// ExportCracked downloads the passwords recovered by the clients
+func (u Page) ExportCracked(w http.ResponseWriter, r *http.Request) {
+	var request ExportCrackedRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	exported, err := u.Usecase.ExportCrackedResults(token.(string), &entities.CrackedResultsRequest{Format: request.Format})
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.HandshakePage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	contentType, filename := constants.JSONContentType, "cracked.json"
+	switch request.Format {
+	case "csv":
+		contentType, filename = constants.CSVContentType, "cracked.csv"
+	case "potfile":
+		contentType, filename = constants.TextContentType, "hashcat.potfile"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	_, _ = w.Write(exported)
+}
+
+// HandshakeLogs returns the hashcat logs of a handshake as text, fetched by the logs modal
+func (u Page) HandshakeLogs(w http.ResponseWriter, r *http.Request) {
+	var request HandshakeFileRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Error(w, customErrors.ErrNotAuthenticated.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logs, err := u.Usecase.GetHandshakeLogs(token.(string), request.HandshakeUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.TextContentType)
+	_, _ = w.Write(logs)
+}
@@ -0,0 +1,75 @@
+package imports
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/usecase"
+)
+
+type Page struct {
+	Usecase *usecase.Usecase
+}
+
+// ImportPage renders the form for importing captures, hash files and potfiles in bulk
+func (u Page) ImportPage(w http.ResponseWriter, r *http.Request) {
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	u.Usecase.RenderTemplate(w, constants.ImportView, map[string]any{
+		"Error": r.URL.Query().Get("error"),
+	})
+}
+
+// ImportHandshakes imports the file uploaded, the outcome of each network found in it is rendered
+func (u Page) ImportHandshakes(w http.ResponseWriter, r *http.Request) {
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := r.ParseMultipartForm(constants.MaxImportSize); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.ImportPage, url.QueryEscape("failed to parse multipart form data")), http.StatusFound)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.ImportPage, url.QueryEscape("file is required")), http.StatusFound)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, constants.MaxImportSize))
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.ImportPage, url.QueryEscape("failed to read file")), http.StatusFound)
+		return
+	}
+
+	imported, err := u.Usecase.ImportHandshakes(token.(string), &entities.ImportHandshakesRequest{
+		Name: header.Filename,
+		File: content,
+	})
+
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.ImportPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	u.Usecase.RenderTemplate(w, constants.ImportView, map[string]any{
+		"Imported": imported,
+	})
+}
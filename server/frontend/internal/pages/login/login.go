@@ -6,7 +6,6 @@ import (
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
 	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
@@ -55,14 +54,8 @@ func (u Page) PerformLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set the session token cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     constants.SessionTokenName,
-		Value:    loginResponse.Details,
-		Expires:  time.Now().Add(3 * time.Hour),
-		Path:     "/",
-		HttpOnly: true,
-	})
+	// Set the session token and the refresh token cookies
+	u.Usecase.SetSessionCookies(w, loginResponse)
 
 	// Redirect to posts
 	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.HandshakePage), http.StatusFound)
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
@@ -38,15 +37,7 @@ func (u Page) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cookie := http.Cookie{
-		Name:     constants.SessionTokenName,
-		Value:    "",
-		Expires:  time.Unix(0, 0),
-		Path:     "/",
-		HttpOnly: true,
-	}
-
-	http.SetCookie(w, &cookie)
+	u.Usecase.ClearSessionCookies(w)
 
 	http.Redirect(w, r, constants.Login, http.StatusFound)
 }
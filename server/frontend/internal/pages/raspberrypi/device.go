@@ -0,0 +1,99 @@
+package raspberrypi
+
+import (
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+	"net/http"
+	"net/url"
+)
+
+type DeviceTemplate struct {
+	UUID string `query:"uuid" validate:"required"`
+	Page int    `query:"page"`
+}
+
+// ShowDevice shows what the device reported, the captures it uploaded and its activity
+func (u Page) ShowDevice(w http.ResponseWriter, r *http.Request) {
+	var request DeviceTemplate
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	var page = 1
+
+	if request.Page != 0 {
+		page = request.Page
+	}
+
+	device, err := u.Usecase.GetRaspberryPIDevice(token.(string), request.UUID, page)
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	postsPerPage := 5
+	totalPages := (device.Length + postsPerPage - 1) / postsPerPage
+
+	u.Usecase.RenderTemplate(w, constants.DeviceDetailView, map[string]any{
+		"Device":      device.Device,
+		"Handshakes":  device.Handshakes,
+		"Activity":    device.Activity,
+		"CurrentPage": page,
+		"TotalPages":  totalPages,
+		"Error":       r.URL.Query().Get("error"),
+	})
+}
+
+type RenameDeviceRequest struct {
+	UUID string `form:"uuid" validate:"required"`
+	Name string `form:"name"`
+}
+
+// deviceURL the page of the device, showing errorMessage when not empty
+func deviceURL(rspUUID, errorMessage string) string {
+	if errorMessage == "" {
+		return fmt.Sprintf("%s?uuid=%s&page=1", constants.DevicePage, url.QueryEscape(rspUUID))
+	}
+	return fmt.Sprintf("%s?uuid=%s&page=1&error=%s", constants.DevicePage, url.QueryEscape(rspUUID), url.QueryEscape(errorMessage))
+}
+
+// RenameDevice an empty name brings back the hostname reported by the device
+func (u Page) RenameDevice(w http.ResponseWriter, r *http.Request) {
+	var request RenameDeviceRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, deviceURL(r.PostForm.Get("uuid"), err.Error()), http.StatusFound)
+		return
+	}
+
+	_, err := u.Usecase.RenameRaspberryPI(token.(string), &entities.RenameRaspberryPIRequest{
+		RaspberryPIUUID: request.UUID,
+		Name:            request.Name,
+	})
+
+	if err != nil {
+		http.Redirect(w, r, deviceURL(request.UUID, err.Error()), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, deviceURL(request.UUID, ""), http.StatusFound)
+}
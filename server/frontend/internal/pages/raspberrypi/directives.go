@@ -0,0 +1,148 @@
+package raspberrypi
+
+import (
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type DirectivesTemplate struct {
+	UUID string `query:"uuid" validate:"required"`
+}
+
+// ListDirectives shows the directives of a device, with the logs it uploaded
+func (u Page) ListDirectives(w http.ResponseWriter, r *http.Request) {
+	var request DirectivesTemplate
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	directives, err := u.Usecase.GetRaspberryPIDirectives(token.(string), request.UUID)
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	u.Usecase.RenderTemplate(w, constants.DirectiveView, map[string]any{
+		"RaspberryPIUUID": request.UUID,
+		"Directives":      directives.Directives,
+		"Error":           r.URL.Query().Get("error"),
+	})
+}
+
+// CreateDirectiveRequest lists are comma separated, fields not needed by the kind are ignored by the backend
+type CreateDirectiveRequest struct {
+	UUID      string `form:"uuid" validate:"required"`
+	Kind      string `form:"kind" validate:"required"`
+	Interval  string `form:"interval"`
+	Windows   string `form:"windows"`
+	Path      string `form:"path"`
+	Recursive bool   `form:"recursive"`
+	Include   string `form:"include"`
+	Exclude   string `form:"exclude"`
+}
+
+// splitList splits a comma separated list, ignoring empty items
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// directivesURL the page of the directives of the device, showing errorMessage when not empty
+func directivesURL(rspUUID, errorMessage string) string {
+	if errorMessage == "" {
+		return fmt.Sprintf("%s?uuid=%s", constants.DirectivesPage, url.QueryEscape(rspUUID))
+	}
+	return fmt.Sprintf("%s?uuid=%s&error=%s", constants.DirectivesPage, url.QueryEscape(rspUUID), url.QueryEscape(errorMessage))
+}
+
+func (u Page) CreateDirective(w http.ResponseWriter, r *http.Request) {
+	var request CreateDirectiveRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, directivesURL(r.PostForm.Get("uuid"), err.Error()), http.StatusFound)
+		return
+	}
+
+	result, err := u.Usecase.CreateRaspberryPIDirective(token.(string), &entities.CreateRaspberryPIDirectiveRequest{
+		RaspberryPIUUID: request.UUID,
+		Kind:            request.Kind,
+		Parameters: entities.DirectiveParameters{
+			Interval:  strings.TrimSpace(request.Interval),
+			Windows:   splitList(request.Windows),
+			Path:      strings.TrimSpace(request.Path),
+			Recursive: request.Recursive,
+			Include:   splitList(request.Include),
+			Exclude:   splitList(request.Exclude),
+		},
+	})
+
+	if err == nil && result.DirectiveUUID == "" {
+		err = customErrors.ErrDirectiveNotCreated
+	}
+
+	if err != nil {
+		http.Redirect(w, r, directivesURL(request.UUID, err.Error()), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, directivesURL(request.UUID, ""), http.StatusFound)
+}
+
+type CancelDirectiveRequest struct {
+	UUID          string `form:"uuid" validate:"required"`
+	DirectiveUUID string `form:"directive_uuid" validate:"required"`
+}
+
+func (u Page) CancelDirective(w http.ResponseWriter, r *http.Request) {
+	var request CancelDirectiveRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, directivesURL(r.PostForm.Get("uuid"), err.Error()), http.StatusFound)
+		return
+	}
+
+	_, err := u.Usecase.CancelRaspberryPIDirective(token.(string), &entities.CancelRaspberryPIDirectiveRequest{
+		DirectiveUUID: request.DirectiveUUID,
+	})
+
+	if err != nil {
+		http.Redirect(w, r, directivesURL(request.UUID, err.Error()), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, directivesURL(request.UUID, ""), http.StatusFound)
+}
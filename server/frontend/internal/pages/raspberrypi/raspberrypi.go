@@ -8,6 +8,7 @@ import (
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/response"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/usecase"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+	"io"
 	"net/http"
 	"net/url"
 )
@@ -22,8 +23,6 @@ type ClientTemplate struct {
 
 // ListRaspberryPI list raspberry pi instealled by the user
 func (u Page) ListRaspberryPI(w http.ResponseWriter, r *http.Request) {
-	c := response.Initializer{ResponseWriter: w}
-
 	errorMessage := r.URL.Query().Get("error")
 
 	var request ClientTemplate
@@ -46,7 +45,16 @@ func (u Page) ListRaspberryPI(w http.ResponseWriter, r *http.Request) {
 		page = request.Page
 	}
 
-	devices, err := u.Usecase.GetUserDevices(token.(string), page)
+	u.renderDevices(w, token.(string), page, errorMessage, "", nil)
+}
+
+// renderDevices renders the devices page, credential is displayed when it has just been issued
+// and imported when a bundle has just been imported
+func (u Page) renderDevices(w http.ResponseWriter, token string, page int, errorMessage, credential string,
+	imported *entities.ImportRaspberryPIBundleResponse) {
+	c := response.Initializer{ResponseWriter: w}
+
+	devices, err := u.Usecase.GetUserDevices(token, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Error: err.Error(),
@@ -54,15 +62,26 @@ func (u Page) ListRaspberryPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postsPerPage := 5
+	teams, err := u.Usecase.GetTeams(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	postsPerPage := constants.PageSize
 	totalPages := (devices.Length + postsPerPage - 1) / postsPerPage
 
 	// RenderTemplate the login template
 	u.Usecase.RenderTemplate(w, constants.DeviceView, map[string]any{
 		"RaspberryPis": devices.Devices,
+		"Teams":        teams.Teams,
 		"CurrentPage":  page,
 		"TotalPages":   totalPages,
 		"Error":        errorMessage,
+		"Credential":   credential,
+		"Imported":     imported,
 	})
 }
 
@@ -96,3 +115,137 @@ func (u Page) DeleteRaspberryPI(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.RaspberryPIPage), http.StatusFound)
 }
+
+type ApproveRaspberryPIRequest struct {
+	UUID    string `form:"uuid" validate:"required"`
+	Approve *bool  `form:"approve" validate:"required"`
+}
+
+// ApproveRaspberryPI Accept post request for approving or rejecting a device waiting for approval
+func (u Page) ApproveRaspberryPI(w http.ResponseWriter, r *http.Request) {
+	var request ApproveRaspberryPIRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	result, err := u.Usecase.ApproveRaspberryPI(token.(string), &entities.ApproveRaspberryPIRequest{
+		RaspberryPIUUID: request.UUID,
+		Approve:         request.Approve,
+	})
+
+	if err != nil && result != nil && !result.Status {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.RaspberryPIPage), http.StatusFound)
+}
+
+type RaspberryPICredentialRequest struct {
+	UUID string `form:"uuid" validate:"required"`
+}
+
+// RotateCredential issues a new credential for the device. It is rendered instead of redirecting,
+// so that it never ends up in a URL
+func (u Page) RotateCredential(w http.ResponseWriter, r *http.Request) {
+	var request RaspberryPICredentialRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	result, err := u.Usecase.RotateRaspberryPICredential(token.(string), &entities.RaspberryPICredentialRequest{
+		RaspberryPIUUID: request.UUID,
+	})
+
+	if err != nil || result.Credential == "" {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(customErrors.ErrCredentialNotRotated.Error())), http.StatusFound)
+		return
+	}
+
+	u.renderDevices(w, token.(string), 1, "", result.Credential, nil)
+}
+
+func (u Page) RevokeCredential(w http.ResponseWriter, r *http.Request) {
+	var request RaspberryPICredentialRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	_, err := u.Usecase.RevokeRaspberryPICredential(token.(string), &entities.RaspberryPICredentialRequest{
+		RaspberryPIUUID: request.UUID,
+	})
+
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.RaspberryPIPage), http.StatusFound)
+}
+
+// ImportBundle imports a bundle written by the daemon export command, the outcome of each capture is rendered
+func (u Page) ImportBundle(w http.ResponseWriter, r *http.Request) {
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := r.ParseMultipartForm(constants.MaxBundleSize); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape("failed to parse multipart form data")), http.StatusFound)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape("file is required")), http.StatusFound)
+		return
+	}
+	defer file.Close()
+
+	bundle, err := io.ReadAll(io.LimitReader(file, constants.MaxBundleSize))
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape("failed to read file")), http.StatusFound)
+		return
+	}
+
+	imported, err := u.Usecase.ImportRaspberryPIBundle(token.(string), &entities.ImportRaspberryPIBundleRequest{
+		Bundle: bundle,
+	})
+
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RaspberryPIPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	u.renderDevices(w, token.(string), 1, "", "", imported)
+}
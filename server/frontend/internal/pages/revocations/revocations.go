@@ -0,0 +1,98 @@
+package revocations
+
+import (
+	"fmt"
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+	"net/http"
+	"net/url"
+)
+
+type Page struct {
+	Usecase *usecase.Usecase
+}
+
+type RevocationTemplate struct {
+	Page int `query:"page"`
+}
+
+// ListRevocations renders the devices and clients deleted by users
+func (u Page) ListRevocations(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	errorMessage := r.URL.Query().Get("error")
+
+	var request RevocationTemplate
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.RevocationsPage), http.StatusFound)
+		return
+	}
+
+	var page = 1
+
+	if request.Page != 0 {
+		page = request.Page
+	}
+
+	revocations, err := u.Usecase.GetUserRevocations(token.(string), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	postsPerPage := 5
+	totalPages := (revocations.Length + postsPerPage - 1) / postsPerPage
+
+	u.Usecase.RenderTemplate(w, constants.RevocationView, map[string]any{
+		"Revocations": revocations.Revocations,
+		"CurrentPage": page,
+		"TotalPages":  totalPages,
+		"Error":       errorMessage,
+	})
+}
+
+type ApproveRevocationRequest struct {
+	UUID string `form:"uuid" validate:"required"`
+}
+
+// ApproveRevocation Accept post request for allowing a deleted device or client to connect again
+func (u Page) ApproveRevocation(w http.ResponseWriter, r *http.Request) {
+	var request ApproveRevocationRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RevocationsPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	result, err := u.Usecase.ApproveRevocation(token.(string), &entities.ApproveRevocationRequest{
+		RevocationUUID: request.UUID,
+	})
+
+	if err != nil && result != nil && !result.Status {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.RevocationsPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.RevocationsPage), http.StatusFound)
+}
@@ -2,9 +2,15 @@ package pages
 
 import (
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/middlewares"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/admin"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/clients"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/imports"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/raspberrypi"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/revocations"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/teams"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/tokens"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/welcome"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/pages/wordlists"
 	"github.com/gorilla/mux"
 
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
@@ -25,10 +31,51 @@ const Devices = constants.RaspberryPIPage
 const HandshakeSubmission = constants.SubmitTask
 const DeleteRaspberryPI = constants.DeleteRaspberry
 const DeleteClient = constants.DeleteClient
+const ApproveClient = constants.ApproveClient
+const ApproveRaspberryPI = constants.ApproveDevice
 const DeleteHandshake = constants.DeleteHandshake
 const CreateHandshake = constants.CreateHandshake
 const UpdateClientEncryptionStatus = constants.UpdateEncryption
 const UpdateUserPassword = constants.UpdatePassword
+const RotateCredential = constants.RotateCredential
+const RevokeCredential = constants.RevokeCredential
+const ExportLocations = constants.ExportLocations
+const HandshakeCapture = constants.HandshakeCapture
+const HandshakeLogs = constants.HandshakeLogs
+const ExportHandshakes = constants.ExportHandshakes
+const ExportCracked = constants.ExportCracked
+const ImportBundle = constants.ImportBundle
+const Directives = constants.DirectivesPage
+const CreateDirective = constants.CreateDirective
+const CancelDirective = constants.CancelDirective
+const Device = constants.DevicePage
+const RenameDevice = constants.RenameDevice
+const Revocations = constants.RevocationsPage
+const ApproveRevocation = constants.ApproveRevoked
+const Tokens = constants.TokensPage
+const CreateToken = constants.CreateToken
+const RevokeToken = constants.RevokeToken
+const Teams = constants.TeamsPage
+const CreateTeam = constants.CreateTeam
+const DeleteTeam = constants.DeleteTeam
+const AddTeamMember = constants.AddTeamMember
+const RemoveTeamMember = constants.RemoveTeamMember
+const ShareResource = constants.ShareResource
+const Wordlists = constants.WordlistsPage
+const CreateWordlist = constants.CreateWordlist
+const DeleteWordlist = constants.DeleteWordlist
+const Import = constants.ImportPage
+const ImportHandshakes = constants.ImportHandshakes
+const Admin = constants.AdminPage
+const AdminClients = constants.AdminClientsPage
+const AdminDevices = constants.AdminDevicesPage
+const AdminTasks = constants.AdminTasksPage
+const AdminCreateUser = constants.AdminCreateUser
+const AdminDeleteUser = constants.AdminDeleteUser
+const AdminDisableUser = constants.AdminDisableUser
+const AdminResetPassword = constants.AdminResetUser
+const AdminUpdateRole = constants.AdminUpdateRole
+const AdminRegistrations = constants.AdminToggleSignUp
 
 // InitRoutes
 //
@@ -42,6 +89,12 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 	handshakeInstance := handshakes.Page{Usecase: h.Usecase}
 	clientsInstance := clients.Page{Usecase: h.Usecase}
 	devicesInstance := raspberrypi.Page{Usecase: h.Usecase}
+	revocationsInstance := revocations.Page{Usecase: h.Usecase}
+	tokensInstance := tokens.Page{Usecase: h.Usecase}
+	teamsInstance := teams.Page{Usecase: h.Usecase}
+	wordlistsInstance := wordlists.Page{Usecase: h.Usecase}
+	importsInstance := imports.Page{Usecase: h.Usecase}
+	adminInstance := admin.Page{Usecase: h.Usecase}
 	welcomeInstance := welcome.Page{Usecase: h.Usecase}
 	authenticated := middlewares.TokenAuth{Usecase: h.Usecase}
 
@@ -108,6 +161,31 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 		Methods("POST")
 	handshakeRouter.Use(authenticated.TokenValidation)
 
+	handshakeRouter.
+		HandleFunc(ExportLocations, handshakeInstance.ExportLocations).
+		Methods("GET")
+	handshakeRouter.Use(authenticated.TokenValidation)
+
+	handshakeRouter.
+		HandleFunc(HandshakeCapture, handshakeInstance.DownloadCapture).
+		Methods("GET")
+	handshakeRouter.Use(authenticated.TokenValidation)
+
+	handshakeRouter.
+		HandleFunc(HandshakeLogs, handshakeInstance.HandshakeLogs).
+		Methods("GET")
+	handshakeRouter.Use(authenticated.TokenValidation)
+
+	handshakeRouter.
+		HandleFunc(ExportHandshakes, handshakeInstance.ExportHandshakes).
+		Methods("GET")
+	handshakeRouter.Use(authenticated.TokenValidation)
+
+	handshakeRouter.
+		HandleFunc(ExportCracked, handshakeInstance.ExportCracked).
+		Methods("GET")
+	handshakeRouter.Use(authenticated.TokenValidation)
+
 	// Clients
 	clientsRouterTemplate := router.PathPrefix(RouteIndex).Subrouter()
 	clientsRouterTemplate.
@@ -120,6 +198,11 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 		Methods("POST")
 	clientsRouterTemplate.Use(authenticated.TokenValidation)
 
+	clientsRouterTemplate.
+		HandleFunc(ApproveClient, clientsInstance.ApproveClient).
+		Methods("POST")
+	clientsRouterTemplate.Use(authenticated.TokenValidation)
+
 	clientsRouterTemplate.
 		HandleFunc(UpdateClientEncryptionStatus, clientsInstance.UpdateClientEncryptionStatus).
 		Methods("POST")
@@ -137,6 +220,193 @@ func (h ServiceHandler) InitRoutes(router *mux.Router) {
 		Methods("POST")
 	devicesRouterTemplate.Use(authenticated.TokenValidation)
 
+	devicesRouterTemplate.
+		HandleFunc(ApproveRaspberryPI, devicesInstance.ApproveRaspberryPI).
+		Methods("POST")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(RotateCredential, devicesInstance.RotateCredential).
+		Methods("POST")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(RevokeCredential, devicesInstance.RevokeCredential).
+		Methods("POST")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(ImportBundle, devicesInstance.ImportBundle).
+		Methods("POST")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(Device, devicesInstance.ShowDevice).
+		Methods("GET")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(RenameDevice, devicesInstance.RenameDevice).
+		Methods("POST")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(Directives, devicesInstance.ListDirectives).
+		Methods("GET")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(CreateDirective, devicesInstance.CreateDirective).
+		Methods("POST")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	devicesRouterTemplate.
+		HandleFunc(CancelDirective, devicesInstance.CancelDirective).
+		Methods("POST")
+	devicesRouterTemplate.Use(authenticated.TokenValidation)
+
+	// Revocations
+	revocationsRouterTemplate := router.PathPrefix(RouteIndex).Subrouter()
+	revocationsRouterTemplate.
+		HandleFunc(Revocations, revocationsInstance.ListRevocations).
+		Methods("GET")
+	revocationsRouterTemplate.Use(authenticated.TokenValidation)
+
+	revocationsRouterTemplate.
+		HandleFunc(ApproveRevocation, revocationsInstance.ApproveRevocation).
+		Methods("POST")
+	revocationsRouterTemplate.Use(authenticated.TokenValidation)
+
+	// Personal access tokens
+	tokensRouterTemplate := router.PathPrefix(RouteIndex).Subrouter()
+	tokensRouterTemplate.
+		HandleFunc(Tokens, tokensInstance.ListTokens).
+		Methods("GET")
+	tokensRouterTemplate.Use(authenticated.TokenValidation)
+
+	tokensRouterTemplate.
+		HandleFunc(CreateToken, tokensInstance.CreateToken).
+		Methods("POST")
+	tokensRouterTemplate.Use(authenticated.TokenValidation)
+
+	tokensRouterTemplate.
+		HandleFunc(RevokeToken, tokensInstance.RevokeToken).
+		Methods("POST")
+	tokensRouterTemplate.Use(authenticated.TokenValidation)
+
+	// Teams of the user and the resources shared with them
+	teamsRouterTemplate := router.PathPrefix(RouteIndex).Subrouter()
+	teamsRouterTemplate.
+		HandleFunc(Teams, teamsInstance.ListTeams).
+		Methods("GET")
+	teamsRouterTemplate.Use(authenticated.TokenValidation)
+
+	teamsRouterTemplate.
+		HandleFunc(CreateTeam, teamsInstance.CreateTeam).
+		Methods("POST")
+	teamsRouterTemplate.Use(authenticated.TokenValidation)
+
+	teamsRouterTemplate.
+		HandleFunc(DeleteTeam, teamsInstance.DeleteTeam).
+		Methods("POST")
+	teamsRouterTemplate.Use(authenticated.TokenValidation)
+
+	teamsRouterTemplate.
+		HandleFunc(AddTeamMember, teamsInstance.AddTeamMember).
+		Methods("POST")
+	teamsRouterTemplate.Use(authenticated.TokenValidation)
+
+	teamsRouterTemplate.
+		HandleFunc(RemoveTeamMember, teamsInstance.RemoveTeamMember).
+		Methods("POST")
+	teamsRouterTemplate.Use(authenticated.TokenValidation)
+
+	teamsRouterTemplate.
+		HandleFunc(ShareResource, teamsInstance.ShareResource).
+		Methods("POST")
+	teamsRouterTemplate.Use(authenticated.TokenValidation)
+
+	// Wordlists offered when submitting a task
+	wordlistsRouterTemplate := router.PathPrefix(RouteIndex).Subrouter()
+	wordlistsRouterTemplate.
+		HandleFunc(Wordlists, wordlistsInstance.ListWordlists).
+		Methods("GET")
+	wordlistsRouterTemplate.Use(authenticated.TokenValidation)
+
+	wordlistsRouterTemplate.
+		HandleFunc(CreateWordlist, wordlistsInstance.CreateWordlist).
+		Methods("POST")
+	wordlistsRouterTemplate.Use(authenticated.TokenValidation)
+
+	wordlistsRouterTemplate.
+		HandleFunc(DeleteWordlist, wordlistsInstance.DeleteWordlist).
+		Methods("POST")
+	wordlistsRouterTemplate.Use(authenticated.TokenValidation)
+
+	// Bulk import of captures, hash files and potfiles
+	importsRouterTemplate := router.PathPrefix(RouteIndex).Subrouter()
+	importsRouterTemplate.
+		HandleFunc(Import, importsInstance.ImportPage).
+		Methods("GET")
+	importsRouterTemplate.Use(authenticated.TokenValidation)
+
+	importsRouterTemplate.
+		HandleFunc(ImportHandshakes, importsInstance.ImportHandshakes).
+		Methods("POST")
+	importsRouterTemplate.Use(authenticated.TokenValidation)
+
+	// Administration, the backend refuses the requests of the users who are not administrators
+	adminRouterTemplate := router.PathPrefix(RouteIndex).Subrouter()
+	adminRouterTemplate.
+		HandleFunc(Admin, adminInstance.ListUsers).
+		Methods("GET")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminClients, adminInstance.ListClients).
+		Methods("GET")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminDevices, adminInstance.ListDevices).
+		Methods("GET")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminTasks, adminInstance.ListTasks).
+		Methods("GET")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminCreateUser, adminInstance.CreateUser).
+		Methods("POST")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminDeleteUser, adminInstance.DeleteUser).
+		Methods("POST")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminDisableUser, adminInstance.DisableUser).
+		Methods("POST")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminResetPassword, adminInstance.ResetUserPassword).
+		Methods("POST")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminUpdateRole, adminInstance.UpdateUserRole).
+		Methods("POST")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
+	adminRouterTemplate.
+		HandleFunc(AdminRegistrations, adminInstance.UpdateRegistrations).
+		Methods("POST")
+	adminRouterTemplate.Use(authenticated.TokenValidation)
+
 	// Welcome page
 	welcomeTemplate := router
 	welcomeTemplate.
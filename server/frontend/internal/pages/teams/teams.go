@@ -0,0 +1,260 @@
+package teams
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+)
+
+type Page struct {
+	Usecase *usecase.Usecase
+}
+
+type TeamsTemplate struct {
+	TeamUUID string `query:"uuid"`
+}
+
+// ListTeams renders the teams of the user, with the members of the team selected
+func (u Page) ListTeams(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+
+	var request TeamsTemplate
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, constants.TeamsPage, http.StatusFound)
+		return
+	}
+
+	teams, err := u.Usecase.GetTeams(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Teams": teams.Teams,
+		"Error": r.URL.Query().Get("error"),
+	}
+
+	for _, team := range teams.Teams {
+		if team.TeamUUID != request.TeamUUID {
+			continue
+		}
+
+		members, err := u.Usecase.GetTeamMembers(token, team.TeamUUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+
+		data["Selected"] = team
+		data["Members"] = members.Members
+	}
+
+	u.Usecase.RenderTemplate(w, constants.TeamsView, data)
+}
+
+// authToken the token of the session, the user is sent to the login page when it is missing
+func authToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := r.Context().Value(constants.AuthToken)
+
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return "", false
+	}
+	return token.(string), true
+}
+
+// redirectToTeam goes back to the team, or to the list of the teams when teamUUID is empty, showing the error when the
+// operation failed
+func redirectToTeam(w http.ResponseWriter, r *http.Request, teamUUID string, err error) {
+	query := url.Values{}
+	if teamUUID != "" {
+		query.Set("uuid", teamUUID)
+	}
+	if err != nil {
+		query.Set("error", err.Error())
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s?%s", constants.TeamsPage, query.Encode()), http.StatusFound)
+}
+
+// teamOperationResult the backend answers 404 with a status false when the team, the user or the resource is unknown
+func teamOperationResult(result *entities.TeamOperationResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if !result.Status {
+		return customErrors.ErrTeamOperation
+	}
+	return nil
+}
+
+type CreateTeamRequest struct {
+	Name string `form:"name" validate:"required,max=100"`
+}
+
+// CreateTeam Accept post request for creating a team owned by the user
+func (u Page) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var request CreateTeamRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToTeam(w, r, "", err)
+		return
+	}
+
+	created, err := u.Usecase.CreateTeam(token, &entities.CreateTeamRequest{
+		Name: request.Name,
+	})
+	if err != nil {
+		redirectToTeam(w, r, "", err)
+		return
+	}
+
+	redirectToTeam(w, r, created.TeamUUID, nil)
+}
+
+type TeamRequest struct {
+	TeamUUID string `form:"team" validate:"required"`
+}
+
+// DeleteTeam Accept post request for deleting a team, the resources shared with it are kept by their owners
+func (u Page) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	var request TeamRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToTeam(w, r, "", err)
+		return
+	}
+
+	redirectToTeam(w, r, "", teamOperationResult(u.Usecase.DeleteTeam(token, &entities.DeleteTeamRequest{
+		TeamUUID: request.TeamUUID,
+	})))
+}
+
+type AddTeamMemberRequest struct {
+	TeamUUID string `form:"team" validate:"required"`
+	Username string `form:"username" validate:"required"`
+	Role     string `form:"role" validate:"required,oneof=owner member viewer"`
+}
+
+// AddTeamMember Accept post request for adding a user to the team, or changing its role
+func (u Page) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	var request AddTeamMemberRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToTeam(w, r, r.FormValue("team"), err)
+		return
+	}
+
+	redirectToTeam(w, r, request.TeamUUID, teamOperationResult(u.Usecase.AddTeamMember(token, &entities.AddTeamMemberRequest{
+		TeamUUID: request.TeamUUID,
+		Username: request.Username,
+		Role:     request.Role,
+	})))
+}
+
+type RemoveTeamMemberRequest struct {
+	TeamUUID string `form:"team" validate:"required"`
+	UserUUID string `form:"user"`
+}
+
+// RemoveTeamMember Accept post request for removing a member from the team, without a user the user leaves the team
+func (u Page) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var request RemoveTeamMemberRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToTeam(w, r, "", err)
+		return
+	}
+
+	err := teamOperationResult(u.Usecase.RemoveTeamMember(token, &entities.RemoveTeamMemberRequest{
+		TeamUUID: request.TeamUUID,
+		UserUUID: request.UserUUID,
+	}))
+
+	if request.UserUUID == "" && err == nil {
+		redirectToTeam(w, r, "", nil)
+		return
+	}
+	redirectToTeam(w, r, request.TeamUUID, err)
+}
+
+type ShareResourceRequest struct {
+	Kind         string `form:"kind" validate:"required,oneof=client raspberry_pi handshake wordlist"`
+	ResourceUUID string `form:"uuid" validate:"required"`
+	TeamUUID     string `form:"team"`
+}
+
+// sharePages the page listing each kind of resource
+var sharePages = map[string]string{
+	"client":       constants.ClientPage,
+	"raspberry_pi": constants.RaspberryPIPage,
+	"handshake":    constants.HandshakePage,
+	"wordlist":     constants.WordlistsPage,
+}
+
+// ShareResource Accept post request for sharing a resource with a team or making it personal again, it goes back to
+// the page listing the resource
+func (u Page) ShareResource(w http.ResponseWriter, r *http.Request) {
+	var request ShareResourceRequest
+
+	token, ok := authToken(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		redirectToTeam(w, r, "", err)
+		return
+	}
+
+	back := sharePages[request.Kind]
+
+	err := teamOperationResult(u.Usecase.ShareResource(token, &entities.ShareResourceRequest{
+		Kind:         request.Kind,
+		ResourceUUID: request.ResourceUUID,
+		TeamUUID:     request.TeamUUID,
+	}))
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", back, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s?page=1", back), http.StatusFound)
+}
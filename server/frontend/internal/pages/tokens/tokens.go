@@ -0,0 +1,140 @@
+package tokens
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+)
+
+type Page struct {
+	Usecase *usecase.Usecase
+}
+
+type TokensTemplate struct {
+	Page int `query:"page"`
+}
+
+// ListTokens renders the personal access tokens of the user
+func (u Page) ListTokens(w http.ResponseWriter, r *http.Request) {
+	var request TokensTemplate
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidateQueryParameters(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.TokensPage), http.StatusFound)
+		return
+	}
+
+	var page = 1
+
+	if request.Page != 0 {
+		page = request.Page
+	}
+
+	u.renderTokens(w, token.(string), page, r.URL.Query().Get("error"), nil)
+}
+
+// renderTokens renders a page of the tokens, created is shown once right after its creation
+func (u Page) renderTokens(w http.ResponseWriter, token string, page int, errorMessage string, created *entities.CreatePersonalAccessTokenResponse) {
+	c := response.Initializer{ResponseWriter: w}
+
+	tokens, err := u.Usecase.GetPersonalAccessTokens(token, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	postsPerPage := 5
+	totalPages := (tokens.Length + postsPerPage - 1) / postsPerPage
+
+	u.Usecase.RenderTemplate(w, constants.TokensView, map[string]any{
+		"Tokens":      tokens.Tokens,
+		"Created":     created,
+		"CurrentPage": page,
+		"TotalPages":  totalPages,
+		"Error":       errorMessage,
+	})
+}
+
+type CreateTokenRequest struct {
+	Name          string `form:"name" validate:"required,max=100"`
+	Scope         string `form:"scope" validate:"required,oneof=read fe admin"`
+	ExpiresInDays int    `form:"expiresInDays" validate:"required,min=1,max=365"`
+}
+
+// CreateToken Accept post request for creating a personal access token, the page is rendered with the token instead
+// of redirecting so that it does not end up in the URL
+func (u Page) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var request CreateTokenRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.TokensPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	created, err := u.Usecase.CreatePersonalAccessToken(token.(string), &entities.CreatePersonalAccessTokenRequest{
+		Name:          request.Name,
+		Scope:         request.Scope,
+		ExpiresInDays: request.ExpiresInDays,
+	})
+
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.TokensPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	u.renderTokens(w, token.(string), 1, "", created)
+}
+
+type RevokeTokenRequest struct {
+	UUID string `form:"uuid" validate:"required"`
+}
+
+// RevokeToken Accept post request for revoking a personal access token
+func (u Page) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var request RevokeTokenRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.TokensPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	result, err := u.Usecase.RevokePersonalAccessToken(token.(string), &entities.RevokePersonalAccessTokenRequest{
+		TokenUUID: request.UUID,
+	})
+
+	if err != nil && result != nil && !result.Status {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.TokensPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s?page=1", constants.TokensPage), http.StatusFound)
+}
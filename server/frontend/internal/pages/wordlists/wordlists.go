@@ -0,0 +1,128 @@
+package wordlists
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Virgula0/progetto-dp/server/entities"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	customErrors "github.com/Virgula0/progetto-dp/server/frontend/internal/errors"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/response"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/usecase"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
+)
+
+type Page struct {
+	Usecase *usecase.Usecase
+}
+
+// ListWordlists renders the wordlists of the user and of its teams
+func (u Page) ListWordlists(w http.ResponseWriter, r *http.Request) {
+	c := response.Initializer{ResponseWriter: w}
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	wordlists, err := u.Usecase.GetWordlists(token.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	teams, err := u.Usecase.GetTeams(token.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	u.Usecase.RenderTemplate(w, constants.WordlistsView, map[string]any{
+		"Wordlists": wordlists.Wordlists,
+		"Teams":     teams.Teams,
+		"Error":     r.URL.Query().Get("error"),
+	})
+}
+
+type CreateWordlistRequest struct {
+	Name     string `form:"name" validate:"required,max=100"`
+	Path     string `form:"path" validate:"required,max=255"`
+	TeamUUID string `form:"team"`
+}
+
+// CreateWordlist Accept post request for adding a wordlist, shared at once with the team selected
+func (u Page) CreateWordlist(w http.ResponseWriter, r *http.Request) {
+	var request CreateWordlistRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.WordlistsPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	created, err := u.Usecase.CreateWordlist(token.(string), &entities.CreateWordlistRequest{
+		Name:     request.Name,
+		Path:     request.Path,
+		TeamUUID: request.TeamUUID,
+	})
+
+	if err == nil && created.WordlistUUID == "" {
+		err = customErrors.ErrTeamOperation
+	}
+
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.WordlistsPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, constants.WordlistsPage, http.StatusFound)
+}
+
+type DeleteWordlistRequest struct {
+	UUID string `form:"uuid" validate:"required"`
+}
+
+// DeleteWordlist Accept post request for deleting a wordlist of the user or of a team it owns
+func (u Page) DeleteWordlist(w http.ResponseWriter, r *http.Request) {
+	var request DeleteWordlistRequest
+	token := r.Context().Value(constants.AuthToken)
+
+	// Check if the token exists
+	if token == nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?page=1&error=%s", constants.Login, url.QueryEscape(customErrors.ErrNotAuthenticated.Error())), http.StatusFound)
+		return
+	}
+
+	if err := utils.ValidatePOSTFormRequest(&request, r); err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.WordlistsPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	result, err := u.Usecase.DeleteWordlist(token.(string), &entities.DeleteWordlistRequest{
+		WordlistUUID: request.UUID,
+	})
+
+	if err == nil && !result.Status {
+		err = customErrors.ErrTeamOperation
+	}
+
+	if err != nil {
+		http.Redirect(w, r, fmt.Sprintf("%s?error=%s", constants.WordlistsPage, url.QueryEscape(err.Error())), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, constants.WordlistsPage, http.StatusFound)
+}
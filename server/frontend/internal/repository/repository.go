@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/Virgula0/progetto-dp/server/entities"
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/constants"
+	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
 )
 
 type Repository struct {
@@ -93,13 +95,38 @@ func (repo *Repository) uniformResponseRefactored(requestData any, endpoint, met
 	return &backendResponse, nil
 }
 
+// sessionResponse handler for the endpoints returning the access and the refresh token
+func (repo *Repository) sessionResponse(requestData any, endpoint string) (*entities.AuthResponse, error) {
+	jsonData, err := json.Marshal(requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := repo.GenericHTTPRequestToBackend(http.MethodPost, endpoint, nil, jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	var session entities.AuthResponse
+	if err := json.Unmarshal(responseBody, &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
 // Authentication handlers
-func (repo *Repository) PerformLogin(username, password string) (*entities.UniformResponse, error) {
-	return repo.uniformResponseRefactored(
+func (repo *Repository) PerformLogin(username, password string) (*entities.AuthResponse, error) {
+	return repo.sessionResponse(
 		map[string]string{"username": username, "password": password},
 		constants.BackendAuthEndpoint,
-		http.MethodPost,
-		nil,
+	)
+}
+
+func (repo *Repository) RefreshSession(refreshToken string) (*entities.AuthResponse, error) {
+	return repo.sessionResponse(
+		&entities.RefreshTokenRequest{RefreshToken: refreshToken},
+		constants.BackendRefreshEndpoint,
 	)
 }
 
@@ -123,10 +150,14 @@ func (repo *Repository) PerformRegister(username, password, confirmation string)
 
 // Data retrieval handlers with pagination
 func (repo *Repository) getPaginatedResource(token, endpoint string, page int, target interface{}) error {
+	return repo.getResource(token, fmt.Sprintf("%s?page=%d", endpoint, page), target)
+}
+
+// getResource the backend answers 404 when there is nothing to return, target is left empty then
+func (repo *Repository) getResource(token, endpoint string, target interface{}) error {
 	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", token)}
-	endpointWithPage := fmt.Sprintf("%s?page=%d", endpoint, page)
 
-	responseBytes, err := repo.GenericHTTPRequestToBackend(http.MethodGet, endpointWithPage, headers, nil)
+	responseBytes, err := repo.GenericHTTPRequestToBackend(http.MethodGet, endpoint, headers, nil)
 	if err != nil {
 		return err
 	}
@@ -138,21 +169,34 @@ func (repo *Repository) getPaginatedResource(token, endpoint string, page int, t
 	return json.Unmarshal(responseBytes, target)
 }
 
-func (repo *Repository) GetUserHandshakes(token string, page int) (*entities.GetHandshakeResponse, error) {
+// GetUserHandshakes the filter is forwarded as is, the backend validates it
+func (repo *Repository) GetUserHandshakes(token string, filter *entities.HandshakeFilter) (*entities.GetHandshakeResponse, error) {
 	var response entities.GetHandshakeResponse
-	err := repo.getPaginatedResource(token, constants.BackendGetHandshakes, page, &response)
+	err := repo.getResource(token, fmt.Sprintf("%s?%s", constants.BackendGetHandshakes, utils.EncodeQueryParameters(filter).Encode()), &response)
 	return &response, err
 }
 
-func (repo *Repository) GetUserClients(token string, page int) (*entities.ReturnClientsInstalledResponse, error) {
+func (repo *Repository) GetUserClients(token string, page, pageSize int) (*entities.ReturnClientsInstalledResponse, error) {
 	var response entities.ReturnClientsInstalledResponse
-	err := repo.getPaginatedResource(token, constants.BackendGetClients, page, &response)
+	err := repo.getResource(token, fmt.Sprintf("%s?page=%d&page_size=%d", constants.BackendGetClients, page, pageSize), &response)
 	return &response, err
 }
 
-func (repo *Repository) GetUserDevices(token string, page int) (*entities.ReturnRaspberryPiDevicesResponse, error) {
+func (repo *Repository) GetUserDevices(token string, page, pageSize int) (*entities.ReturnRaspberryPiDevicesResponse, error) {
 	var response entities.ReturnRaspberryPiDevicesResponse
-	err := repo.getPaginatedResource(token, constants.BackendGetRaspberryPi, page, &response)
+	err := repo.getResource(token, fmt.Sprintf("%s?page=%d&page_size=%d", constants.BackendGetRaspberryPi, page, pageSize), &response)
+	return &response, err
+}
+
+func (repo *Repository) GetUserRevocations(token string, page int) (*entities.ReturnRevocationsResponse, error) {
+	var response entities.ReturnRevocationsResponse
+	err := repo.getPaginatedResource(token, constants.BackendRevocations, page, &response)
+	return &response, err
+}
+
+func (repo *Repository) GetPersonalAccessTokens(token string, page int) (*entities.ReturnPersonalAccessTokensResponse, error) {
+	var response entities.ReturnPersonalAccessTokensResponse
+	err := repo.getPaginatedResource(token, constants.PersonalAccessTokens, page, &response)
 	return &response, err
 }
 
@@ -191,12 +235,119 @@ func (repo *Repository) DeleteClient(token string, request *entities.DeleteClien
 	return &response, err
 }
 
+// ApproveClient approves or rejects a client waiting for approval, a client rejected is deleted
+func (repo *Repository) ApproveClient(token string, request *entities.ApproveClientRequest) (*entities.ApproveClientResponse, error) {
+	var response entities.ApproveClientResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.ClientApproval, token, request, &response)
+	return &response, err
+}
+
+// ApproveRaspberryPI approves or rejects a device waiting for approval, a device rejected is deleted
+func (repo *Repository) ApproveRaspberryPI(token string, request *entities.ApproveRaspberryPIRequest) (*entities.ApproveRaspberryPIResponse, error) {
+	var response entities.ApproveRaspberryPIResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.RaspberryPIApproval, token, request, &response)
+	return &response, err
+}
+
 func (repo *Repository) DeleteRaspberryPI(token string, request *entities.DeleteRaspberryPIRequest) (*entities.DeleteRaspberryPIResponse, error) {
 	var response entities.DeleteRaspberryPIResponse
 	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.BackendDeleteRaspberryPI, token, request, &response)
 	return &response, err
 }
 
+// Device credential operations
+func (repo *Repository) RotateRaspberryPICredential(token string, request *entities.RaspberryPICredentialRequest) (*entities.RotateRaspberryPICredentialResponse, error) {
+	var response entities.RotateRaspberryPICredentialResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.RaspberryPICredential, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) RevokeRaspberryPICredential(token string, request *entities.RaspberryPICredentialRequest) (*entities.RevokeRaspberryPICredentialResponse, error) {
+	var response entities.RevokeRaspberryPICredentialResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.RaspberryPICredential, token, request, &response)
+	return &response, err
+}
+
+// ImportRaspberryPIBundle sends an offline bundle, the outcome of each capture is returned
+func (repo *Repository) ImportRaspberryPIBundle(token string, request *entities.ImportRaspberryPIBundleRequest) (*entities.ImportRaspberryPIBundleResponse, error) {
+	var response entities.ImportRaspberryPIBundleResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.RaspberryPIBundle, token, request, &response)
+	return &response, err
+}
+
+// GetRaspberryPIDirectives returns the directives of the device, with what the device reported acknowledging them
+func (repo *Repository) GetRaspberryPIDirectives(token, rspUUID string) (*entities.ReturnRaspberryPIDirectivesResponse, error) {
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", token)}
+
+	responseBytes, err := repo.GenericHTTPRequestToBackend(http.MethodGet, fmt.Sprintf("%s?uuid=%s", constants.RaspberryPIDirectives, url.QueryEscape(rspUUID)), headers, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = repo.checkUniformError(responseBytes); err != nil {
+		return nil, err
+	}
+
+	var response entities.ReturnRaspberryPIDirectivesResponse
+	err = json.Unmarshal(responseBytes, &response)
+	return &response, err
+}
+
+// GetRaspberryPIDevice returns the device with a page of the captures it uploaded and its activity
+func (repo *Repository) GetRaspberryPIDevice(token, rspUUID string, page int) (*entities.ReturnRaspberryPIResponse, error) {
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", token)}
+
+	responseBytes, err := repo.GenericHTTPRequestToBackend(http.MethodGet, fmt.Sprintf("%s?uuid=%s&page=%d", constants.RaspberryPIDevice, url.QueryEscape(rspUUID), page), headers, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = repo.checkUniformError(responseBytes); err != nil {
+		return nil, err
+	}
+
+	var response entities.ReturnRaspberryPIResponse
+	err = json.Unmarshal(responseBytes, &response)
+	return &response, err
+}
+
+func (repo *Repository) RenameRaspberryPI(token string, request *entities.RenameRaspberryPIRequest) (*entities.RenameRaspberryPIResponse, error) {
+	var response entities.RenameRaspberryPIResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.RaspberryPIName, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) CreateRaspberryPIDirective(token string, request *entities.CreateRaspberryPIDirectiveRequest) (*entities.CreateRaspberryPIDirectiveResponse, error) {
+	var response entities.CreateRaspberryPIDirectiveResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.RaspberryPIDirectives, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) CancelRaspberryPIDirective(token string, request *entities.CancelRaspberryPIDirectiveRequest) (*entities.CancelRaspberryPIDirectiveResponse, error) {
+	var response entities.CancelRaspberryPIDirectiveResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.RaspberryPIDirectives, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) ApproveRevocation(token string, request *entities.ApproveRevocationRequest) (*entities.ApproveRevocationResponse, error) {
+	var response entities.ApproveRevocationResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.BackendRevocations, token, request, &response)
+	return &response, err
+}
+
+// CreatePersonalAccessToken the token is in the response only, the backend stores its hash
+func (repo *Repository) CreatePersonalAccessToken(token string, request *entities.CreatePersonalAccessTokenRequest) (*entities.CreatePersonalAccessTokenResponse, error) {
+	var response entities.CreatePersonalAccessTokenResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.PersonalAccessTokens, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) RevokePersonalAccessToken(token string, request *entities.RevokePersonalAccessTokenRequest) (*entities.RevokePersonalAccessTokenResponse, error) {
+	var response entities.RevokePersonalAccessTokenResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.PersonalAccessTokens, token, request, &response)
+	return &response, err
+}
+
 func (repo *Repository) DeleteHandshake(token string, request *entities.DeleteHandshakesRequest) (*entities.DeleteHandshakesResponse, error) {
 	var response entities.DeleteHandshakesResponse
 	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.BackendHandshake, token, request, &response)
@@ -222,3 +373,183 @@ func (repo *Repository) UpdateUserPassword(token string, request *entities.Updat
 	err := repo.executeAuthorizedRequest(http.MethodPost, constants.UpdateUserPassword, token, request, &response)
 	return &response, err
 }
+
+// ExportHandshakeLocations returns the file exported by the backend, the export is not a UniformResponse unless it failed
+func (repo *Repository) ExportHandshakeLocations(token, format string) ([]byte, error) {
+	return repo.getRawResource(token, fmt.Sprintf("%s?format=%s", constants.HandshakeLocations, url.QueryEscape(format)))
+}
+
+func (repo *Repository) GetHandshakeCapture(token, handshakeUUID string, request *entities.CaptureRequest) ([]byte, error) {
+	return repo.getRawResource(token, fmt.Sprintf("%s?%s",
+		fmt.Sprintf(constants.BackendHandshakeCapture, url.PathEscape(handshakeUUID)), utils.EncodeQueryParameters(request).Encode()))
+}
+
+// ExportHandshakes returns the tar.gz archive of the handshakes, all of them unless the request lists some
+func (repo *Repository) ExportHandshakes(token string, request *entities.HandshakeExportRequest) ([]byte, error) {
+	return repo.getRawResource(token, fmt.Sprintf("%s?%s", constants.BackendHandshakeExport, utils.EncodeQueryParameters(request).Encode()))
+}
+
+func (repo *Repository) ExportCrackedResults(token string, request *entities.CrackedResultsRequest) ([]byte, error) {
+	return repo.getRawResource(token, fmt.Sprintf("%s?%s", constants.BackendCrackedResults, utils.EncodeQueryParameters(request).Encode()))
+}
+
+func (repo *Repository) GetHandshakeLogs(token, handshakeUUID string) ([]byte, error) {
+	return repo.getRawResource(token, fmt.Sprintf(constants.BackendHandshakeLogs, url.PathEscape(handshakeUUID)))
+}
+
+// ImportHandshakes sends a capture, hash file, potfile or archive of them, the outcome of each network is returned
+func (repo *Repository) ImportHandshakes(token string, request *entities.ImportHandshakesRequest) (*entities.ImportHandshakesResponse, error) {
+	var response entities.ImportHandshakesResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.BackendHandshakeImport, token, request, &response)
+	return &response, err
+}
+
+// getRawResource for the endpoints answering with a file, their errors are still a UniformResponse
+func (repo *Repository) getRawResource(token, endpoint string) ([]byte, error) {
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", token)}
+
+	responseBytes, err := repo.GenericHTTPRequestToBackend(http.MethodGet, endpoint, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var uniformResponse entities.UniformResponse
+	if json.Unmarshal(responseBytes, &uniformResponse) == nil && uniformResponse.Details != "" {
+		return nil, errors.New(uniformResponse.Details)
+	}
+
+	return responseBytes, nil
+}
+
+// Administration, the backend refuses these requests unless the token belongs to an administrator
+func (repo *Repository) GetUsers(token string, page int) (*entities.ReturnUsersResponse, error) {
+	var response entities.ReturnUsersResponse
+	err := repo.getPaginatedResource(token, constants.AdminUsers, page, &response)
+	return &response, err
+}
+
+func (repo *Repository) GetAllClients(token string, page int) (*entities.ReturnClientsInstalledResponse, error) {
+	var response entities.ReturnClientsInstalledResponse
+	err := repo.getPaginatedResource(token, constants.AdminClients, page, &response)
+	return &response, err
+}
+
+func (repo *Repository) GetAllDevices(token string, page int) (*entities.ReturnRaspberryPiDevicesResponse, error) {
+	var response entities.ReturnRaspberryPiDevicesResponse
+	err := repo.getPaginatedResource(token, constants.AdminDevices, page, &response)
+	return &response, err
+}
+
+func (repo *Repository) GetTaskQueue(token string, page int) (*entities.GetHandshakeResponse, error) {
+	var response entities.GetHandshakeResponse
+	err := repo.getPaginatedResource(token, constants.AdminTasks, page, &response)
+	return &response, err
+}
+
+// CreateUser the backend answers with a UniformResponse holding the UUID of the user created
+func (repo *Repository) CreateUser(token string, request *entities.CreateUserRequest) (*entities.UniformResponse, error) {
+	return repo.uniformResponseRefactored(
+		request,
+		constants.AdminUsers,
+		http.MethodPost,
+		map[string]string{"Authorization": fmt.Sprintf("Bearer %s", token)},
+	)
+}
+
+func (repo *Repository) DeleteUser(token string, request *entities.DeleteUserRequest) (*entities.UserOperationResponse, error) {
+	var response entities.UserOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.AdminUsers, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) DisableUser(token string, request *entities.DisableUserRequest) (*entities.UserOperationResponse, error) {
+	var response entities.UserOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.AdminUserDisable, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) ResetUserPassword(token string, request *entities.ResetUserPasswordRequest) (*entities.UserOperationResponse, error) {
+	var response entities.UserOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.AdminUserPassword, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) UpdateUserRole(token string, request *entities.UpdateUserRoleRequest) (*entities.UserOperationResponse, error) {
+	var response entities.UserOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.AdminUserRole, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) GetRegistrations(token string) (*entities.RegistrationsResponse, error) {
+	var response entities.RegistrationsResponse
+	err := repo.executeAuthorizedRequest(http.MethodGet, constants.AdminRegistrations, token, nil, &response)
+	return &response, err
+}
+
+func (repo *Repository) UpdateRegistrations(token string, request *entities.RegistrationsRequest) (*entities.RegistrationsResponse, error) {
+	var response entities.RegistrationsResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.AdminRegistrations, token, request, &response)
+	return &response, err
+}
+
+// Teams of the user, the backend checks the role of the user in the team for each operation
+func (repo *Repository) GetTeams(token string) (*entities.ReturnTeamsResponse, error) {
+	var response entities.ReturnTeamsResponse
+	err := repo.getResource(token, constants.BackendTeams, &response)
+	return &response, err
+}
+
+func (repo *Repository) GetTeamMembers(token, teamUUID string) (*entities.ReturnTeamMembersResponse, error) {
+	var response entities.ReturnTeamMembersResponse
+	err := repo.getResource(token, fmt.Sprintf("%s?uuid=%s", constants.BackendTeamMembers, url.QueryEscape(teamUUID)), &response)
+	return &response, err
+}
+
+func (repo *Repository) CreateTeam(token string, request *entities.CreateTeamRequest) (*entities.CreateTeamResponse, error) {
+	var response entities.CreateTeamResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.BackendTeams, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) DeleteTeam(token string, request *entities.DeleteTeamRequest) (*entities.TeamOperationResponse, error) {
+	var response entities.TeamOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.BackendTeams, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) AddTeamMember(token string, request *entities.AddTeamMemberRequest) (*entities.TeamOperationResponse, error) {
+	var response entities.TeamOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.BackendTeamMembers, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) RemoveTeamMember(token string, request *entities.RemoveTeamMemberRequest) (*entities.TeamOperationResponse, error) {
+	var response entities.TeamOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.BackendTeamMembers, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) ShareResource(token string, request *entities.ShareResourceRequest) (*entities.TeamOperationResponse, error) {
+	var response entities.TeamOperationResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.BackendTeamShare, token, request, &response)
+	return &response, err
+}
+
+// Wordlists of the user and of its teams
+func (repo *Repository) GetWordlists(token string) (*entities.ReturnWordlistsResponse, error) {
+	var response entities.ReturnWordlistsResponse
+	err := repo.getResource(token, constants.BackendWordlists, &response)
+	return &response, err
+}
+
+func (repo *Repository) CreateWordlist(token string, request *entities.CreateWordlistRequest) (*entities.CreateWordlistResponse, error) {
+	var response entities.CreateWordlistResponse
+	err := repo.executeAuthorizedRequest(http.MethodPost, constants.BackendWordlists, token, request, &response)
+	return &response, err
+}
+
+func (repo *Repository) DeleteWordlist(token string, request *entities.DeleteWordlistRequest) (*entities.DeleteWordlistResponse, error) {
+	var response entities.DeleteWordlistResponse
+	err := repo.executeAuthorizedRequest(http.MethodDelete, constants.BackendWordlists, token, request, &response)
+	return &response, err
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/Virgula0/progetto-dp/server/frontend/internal/utils"
 	"html/template"
 	"net/http"
+	"time"
 )
 
 const genericErrorMessage = "Token+not+valid+or+expired"
@@ -112,10 +113,67 @@ func (uc Usecase) GenericHTTPRequest(method, url string, headers map[string]stri
 	return uc.repo.GenericHTTPRequestToBackend(method, url, headers, requestData)
 }
 
-func (uc Usecase) PerformLogin(username, password string) (*entities.UniformResponse, error) {
+func (uc Usecase) PerformLogin(username, password string) (*entities.AuthResponse, error) {
 	return uc.repo.PerformLogin(username, password)
 }
 
+// RefreshSession exchanges the refresh token cookie for a new access token, once the previous one expired.
+// Both cookies are replaced. The refresh token can be used once, BE accepts it again shortly after for the other
+// requests of the page racing for the refresh
+func (uc Usecase) RefreshSession(w http.ResponseWriter, r *http.Request) (string, error) {
+	refreshToken, err := r.Cookie(constants.RefreshTokenName)
+	if err != nil {
+		return "", fmt.Errorf("cookie %s not found", constants.RefreshTokenName)
+	}
+
+	session, err := uc.repo.RefreshSession(refreshToken.Value)
+	if err != nil {
+		return "", err
+	}
+
+	if session.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", genericErrorMessage)
+	}
+
+	uc.SetSessionCookies(w, session)
+
+	return session.Details, nil
+}
+
+// SetSessionCookies stores the access and the refresh token of the session
+func (uc Usecase) SetSessionCookies(w http.ResponseWriter, session *entities.AuthResponse) {
+	expires := time.Now().Add(constants.SessionCookieDuration)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.SessionTokenName,
+		Value:    session.Details,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: true,
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.RefreshTokenName,
+		Value:    session.RefreshToken,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: true,
+	})
+}
+
+// ClearSessionCookies deletes the cookies of the session
+func (uc Usecase) ClearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{constants.SessionTokenName, constants.RefreshTokenName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Expires:  time.Unix(0, 0),
+			Path:     "/",
+			HttpOnly: true,
+		})
+	}
+}
+
 func (uc Usecase) PerformLogout(token string) (*entities.UniformResponse, error) {
 	return uc.repo.PerformLogout(token)
 }
@@ -124,15 +182,47 @@ func (uc Usecase) PerformRegistration(username, password, confirmation string) (
 	return uc.repo.PerformRegister(username, password, confirmation)
 }
 
-func (uc Usecase) GetUserHandshakes(token string, page int) (*entities.GetHandshakeResponse, error) {
-	return uc.repo.GetUserHandshakes(token, page)
+func (uc Usecase) GetUserHandshakes(token string, filter *entities.HandshakeFilter) (*entities.GetHandshakeResponse, error) {
+	return uc.repo.GetUserHandshakes(token, filter)
 }
 
 func (uc Usecase) GetUserClients(token string, page int) (*entities.ReturnClientsInstalledResponse, error) {
-	return uc.repo.GetUserClients(token, page)
+	return uc.repo.GetUserClients(token, page, constants.PageSize)
 }
 func (uc Usecase) GetUserDevices(token string, page int) (*entities.ReturnRaspberryPiDevicesResponse, error) {
-	return uc.repo.GetUserDevices(token, page)
+	return uc.repo.GetUserDevices(token, page, constants.PageSize)
+}
+
+// GetAllUserClients every client of the user, fetched in pages as large as the backend allows
+func (uc Usecase) GetAllUserClients(token string) ([]*entities.Client, error) {
+	clients := make([]*entities.Client, 0)
+	for page := 1; ; page++ {
+		response, err := uc.repo.GetUserClients(token, page, constants.MaxPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		clients = append(clients, response.Clients...)
+		if len(response.Clients) < constants.MaxPageSize || len(clients) >= response.Length {
+			return clients, nil
+		}
+	}
+}
+
+// GetAllUserDevices every raspberry pi of the user, fetched in pages as large as the backend allows
+func (uc Usecase) GetAllUserDevices(token string) ([]*entities.CustomRaspberryPIResponse, error) {
+	devices := make([]*entities.CustomRaspberryPIResponse, 0)
+	for page := 1; ; page++ {
+		response, err := uc.repo.GetUserDevices(token, page, constants.MaxPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, response.Devices...)
+		if len(response.Devices) < constants.MaxPageSize || len(devices) >= response.Length {
+			return devices, nil
+		}
+	}
 }
 
 func (uc Usecase) UpdateClientEncryptionStatus(token string, request *entities.UpdateEncryptionClientStatusRequest) (*entities.UpdateEncryptionClientStatusResponse, error) {
@@ -151,10 +241,70 @@ func (uc Usecase) DeleteClientRequest(token string, request *entities.DeleteClie
 	return uc.repo.DeleteClient(token, request)
 }
 
+func (uc Usecase) ApproveClient(token string, request *entities.ApproveClientRequest) (*entities.ApproveClientResponse, error) {
+	return uc.repo.ApproveClient(token, request)
+}
+
+func (uc Usecase) ApproveRaspberryPI(token string, request *entities.ApproveRaspberryPIRequest) (*entities.ApproveRaspberryPIResponse, error) {
+	return uc.repo.ApproveRaspberryPI(token, request)
+}
+
 func (uc Usecase) DeleteRaspberryPIRequest(token string, request *entities.DeleteRaspberryPIRequest) (*entities.DeleteRaspberryPIResponse, error) {
 	return uc.repo.DeleteRaspberryPI(token, request)
 }
 
+func (uc Usecase) RotateRaspberryPICredential(token string, request *entities.RaspberryPICredentialRequest) (*entities.RotateRaspberryPICredentialResponse, error) {
+	return uc.repo.RotateRaspberryPICredential(token, request)
+}
+
+func (uc Usecase) RevokeRaspberryPICredential(token string, request *entities.RaspberryPICredentialRequest) (*entities.RevokeRaspberryPICredentialResponse, error) {
+	return uc.repo.RevokeRaspberryPICredential(token, request)
+}
+
+func (uc Usecase) ImportRaspberryPIBundle(token string, request *entities.ImportRaspberryPIBundleRequest) (*entities.ImportRaspberryPIBundleResponse, error) {
+	return uc.repo.ImportRaspberryPIBundle(token, request)
+}
+
+func (uc Usecase) GetRaspberryPIDirectives(token, rspUUID string) (*entities.ReturnRaspberryPIDirectivesResponse, error) {
+	return uc.repo.GetRaspberryPIDirectives(token, rspUUID)
+}
+
+func (uc Usecase) GetRaspberryPIDevice(token, rspUUID string, page int) (*entities.ReturnRaspberryPIResponse, error) {
+	return uc.repo.GetRaspberryPIDevice(token, rspUUID, page)
+}
+
+func (uc Usecase) RenameRaspberryPI(token string, request *entities.RenameRaspberryPIRequest) (*entities.RenameRaspberryPIResponse, error) {
+	return uc.repo.RenameRaspberryPI(token, request)
+}
+
+func (uc Usecase) CreateRaspberryPIDirective(token string, request *entities.CreateRaspberryPIDirectiveRequest) (*entities.CreateRaspberryPIDirectiveResponse, error) {
+	return uc.repo.CreateRaspberryPIDirective(token, request)
+}
+
+func (uc Usecase) CancelRaspberryPIDirective(token string, request *entities.CancelRaspberryPIDirectiveRequest) (*entities.CancelRaspberryPIDirectiveResponse, error) {
+	return uc.repo.CancelRaspberryPIDirective(token, request)
+}
+
+func (uc Usecase) GetUserRevocations(token string, page int) (*entities.ReturnRevocationsResponse, error) {
+	return uc.repo.GetUserRevocations(token, page)
+}
+
+func (uc Usecase) ApproveRevocation(token string, request *entities.ApproveRevocationRequest) (*entities.ApproveRevocationResponse, error) {
+	return uc.repo.ApproveRevocation(token, request)
+}
+
+func (uc Usecase) GetPersonalAccessTokens(token string, page int) (*entities.ReturnPersonalAccessTokensResponse, error) {
+	return uc.repo.GetPersonalAccessTokens(token, page)
+}
+
+func (uc Usecase) CreatePersonalAccessToken(token string, request *entities.CreatePersonalAccessTokenRequest) (*entities.CreatePersonalAccessTokenResponse, error) {
+	return uc.repo.CreatePersonalAccessToken(token, request)
+}
+
+func (uc Usecase) RevokePersonalAccessToken(token string, request *entities.RevokePersonalAccessTokenRequest) (*entities.RevokePersonalAccessTokenResponse, error) {
+	return uc.repo.RevokePersonalAccessToken(token, request)
+}
+
 func (uc Usecase) DeleteHandshakeRequest(token string, request *entities.DeleteHandshakesRequest) (*entities.DeleteHandshakesResponse, error) {
 	return uc.repo.DeleteHandshake(token, request)
 }
@@ -162,3 +312,111 @@ func (uc Usecase) DeleteHandshakeRequest(token string, request *entities.DeleteH
 func (uc Usecase) CreateHandshake(token string, request *entities.CreateHandshakeRequest) (*entities.CreateHandshakeResponse, error) {
 	return uc.repo.CreateHandshake(token, request)
 }
+
+func (uc Usecase) ExportHandshakeLocations(token, format string) ([]byte, error) {
+	return uc.repo.ExportHandshakeLocations(token, format)
+}
+
+func (uc Usecase) GetHandshakeCapture(token, handshakeUUID string, request *entities.CaptureRequest) ([]byte, error) {
+	return uc.repo.GetHandshakeCapture(token, handshakeUUID, request)
+}
+
+func (uc Usecase) ExportHandshakes(token string, request *entities.HandshakeExportRequest) ([]byte, error) {
+	return uc.repo.ExportHandshakes(token, request)
+}
+
+func (uc Usecase) ExportCrackedResults(token string, request *entities.CrackedResultsRequest) ([]byte, error) {
+	return uc.repo.ExportCrackedResults(token, request)
+}
+
+func (uc Usecase) ImportHandshakes(token string, request *entities.ImportHandshakesRequest) (*entities.ImportHandshakesResponse, error) {
+	return uc.repo.ImportHandshakes(token, request)
+}
+
+func (uc Usecase) GetHandshakeLogs(token, handshakeUUID string) ([]byte, error) {
+	return uc.repo.GetHandshakeLogs(token, handshakeUUID)
+}
+
+func (uc Usecase) GetUsers(token string, page int) (*entities.ReturnUsersResponse, error) {
+	return uc.repo.GetUsers(token, page)
+}
+
+func (uc Usecase) GetAllClients(token string, page int) (*entities.ReturnClientsInstalledResponse, error) {
+	return uc.repo.GetAllClients(token, page)
+}
+
+func (uc Usecase) GetAllDevices(token string, page int) (*entities.ReturnRaspberryPiDevicesResponse, error) {
+	return uc.repo.GetAllDevices(token, page)
+}
+
+func (uc Usecase) GetTaskQueue(token string, page int) (*entities.GetHandshakeResponse, error) {
+	return uc.repo.GetTaskQueue(token, page)
+}
+
+func (uc Usecase) CreateUser(token string, request *entities.CreateUserRequest) (*entities.UniformResponse, error) {
+	return uc.repo.CreateUser(token, request)
+}
+
+func (uc Usecase) DeleteUser(token string, request *entities.DeleteUserRequest) (*entities.UserOperationResponse, error) {
+	return uc.repo.DeleteUser(token, request)
+}
+
+func (uc Usecase) DisableUser(token string, request *entities.DisableUserRequest) (*entities.UserOperationResponse, error) {
+	return uc.repo.DisableUser(token, request)
+}
+
+func (uc Usecase) ResetUserPassword(token string, request *entities.ResetUserPasswordRequest) (*entities.UserOperationResponse, error) {
+	return uc.repo.ResetUserPassword(token, request)
+}
+
+func (uc Usecase) UpdateUserRole(token string, request *entities.UpdateUserRoleRequest) (*entities.UserOperationResponse, error) {
+	return uc.repo.UpdateUserRole(token, request)
+}
+
+func (uc Usecase) GetRegistrations(token string) (*entities.RegistrationsResponse, error) {
+	return uc.repo.GetRegistrations(token)
+}
+
+func (uc Usecase) UpdateRegistrations(token string, request *entities.RegistrationsRequest) (*entities.RegistrationsResponse, error) {
+	return uc.repo.UpdateRegistrations(token, request)
+}
+
+func (uc Usecase) GetTeams(token string) (*entities.ReturnTeamsResponse, error) {
+	return uc.repo.GetTeams(token)
+}
+
+func (uc Usecase) GetTeamMembers(token, teamUUID string) (*entities.ReturnTeamMembersResponse, error) {
+	return uc.repo.GetTeamMembers(token, teamUUID)
+}
+
+func (uc Usecase) CreateTeam(token string, request *entities.CreateTeamRequest) (*entities.CreateTeamResponse, error) {
+	return uc.repo.CreateTeam(token, request)
+}
+
+func (uc Usecase) DeleteTeam(token string, request *entities.DeleteTeamRequest) (*entities.TeamOperationResponse, error) {
+	return uc.repo.DeleteTeam(token, request)
+}
+
+func (uc Usecase) AddTeamMember(token string, request *entities.AddTeamMemberRequest) (*entities.TeamOperationResponse, error) {
+	return uc.repo.AddTeamMember(token, request)
+}
+
+func (uc Usecase) RemoveTeamMember(token string, request *entities.RemoveTeamMemberRequest) (*entities.TeamOperationResponse, error) {
+	return uc.repo.RemoveTeamMember(token, request)
+}
+
+func (uc Usecase) ShareResource(token string, request *entities.ShareResourceRequest) (*entities.TeamOperationResponse, error) {
+	return uc.repo.ShareResource(token, request)
+}
+
+func (uc Usecase) GetWordlists(token string) (*entities.ReturnWordlistsResponse, error) {
+	return uc.repo.GetWordlists(token)
+}
+
+func (uc Usecase) CreateWordlist(token string, request *entities.CreateWordlistRequest) (*entities.CreateWordlistResponse, error) {
+	return uc.repo.CreateWordlist(token, request)
+}
+
+func (uc Usecase) DeleteWordlist(token string, request *entities.DeleteWordlistRequest) (*entities.DeleteWordlistResponse, error) {
+	return uc.repo.DeleteWordlist(token, request)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 )
@@ -35,6 +36,23 @@ func ValidateQueryParameters(obj any, r *http.Request) error {
 	return bindAndValidate(obj, r.URL.Query(), "query")
 }
 
+// EncodeQueryParameters the reverse of ValidateQueryParameters, fields left to their zero value are omitted.
+func EncodeQueryParameters(obj any) url.Values {
+	val := reflect.Indirect(reflect.ValueOf(obj))
+	typ := val.Type()
+	values := url.Values{}
+
+	for i := 0; i < val.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("query")
+		if tag == "" || val.Field(i).IsZero() {
+			continue
+		}
+		values.Set(tag, fmt.Sprint(val.Field(i).Interface()))
+	}
+
+	return values
+}
+
 // ensurePointer verifies that the given object is a pointer.
 func ensurePointer(obj any) error {
 	if reflect.TypeOf(obj).Kind() != reflect.Ptr {
@@ -496,17 +496,43 @@ $(function () {
         $("#deleteConfirmModalRsp").modal("show");
     });
 
+    // rotate rsp credential modal
+    $(document).on("click", ".rotate-btn-rsp", function () {
+        const uuid = $(this).data("uuid");
+        $("#rotateUUIDRsp").val(uuid);
+        $("#rotateConfirmModalRsp").modal("show");
+    });
+
+    // revoke rsp credential modal
+    $(document).on("click", ".revoke-btn-rsp", function () {
+        const uuid = $(this).data("uuid");
+        $("#revokeUUIDRsp").val(uuid);
+        $("#revokeConfirmModalRsp").modal("show");
+    });
+
     $(document).on("click", ".hashcat-options-btn", function () {
         const options = $(this).data("options");
         $("#hashcatOptionsContent").text(options !== "<nil>" ? options : "No scan run");
         $("#hashcatOptionsModal").modal("show");
     });
 
+    // the logs are not part of the handshakes page, they are loaded when opened
     $(document).on("click", ".hashcat-logs-btn", function () {
-        const logs = $(this).data("logs");
-        // Note: using .html here; ensure logs are sanitized to avoid XSS
-        $("#hashcatLogsContent").html(logs.replace(/\n/g, "<br>") || "No scan run");
+        const uuid = $(this).data("uuid");
+        $("#hashcatLogsContent").text("Loading...");
         $("#hashcatLogsModal").modal("show");
+
+        $.ajax({
+            url: "/handshake-logs",
+            data: { uuid: uuid },
+            dataType: "text",
+            success: function (logs) {
+                $("#hashcatLogsContent").text(logs || "No scan run");
+            },
+            error: function (xhr) {
+                $("#hashcatLogsContent").text(`Logs not available: ${xhr.responseText}`);
+            },
+        });
     });
 
     // ------------------------------------------------
@@ -613,6 +639,15 @@ $(function () {
         // Submit the form automatically
         this.form.submit();
     });
+
+    // Share the resource with the team selected, or make it personal again
+    $(document).on("change", ".share-select", function () {
+        this.form.submit();
+    });
+
+    $(document).on("change", "#selectAllHandshakes", function () {
+        $(".handshake-select").prop("checked", this.checked);
+    });
 });
 
 document.getElementById("settingsLink").addEventListener("click", (e) => {